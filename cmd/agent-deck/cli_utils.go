@@ -108,12 +108,19 @@ func (c *CLIOutput) Print(humanOutput string, jsonData interface{}) {
 	fmt.Print(humanOutput)
 }
 
+// exit ends the process with code once the lifecycle hooks the command
+// fired have finished; os.Exit alone skips main's deferred wait for them.
+func exit(code int) {
+	session.WaitSessionHooks()
+	os.Exit(code)
+}
+
 // printJSON marshals and prints JSON data
 func (c *CLIOutput) printJSON(data interface{}) {
 	output, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: failed to format JSON: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	fmt.Println(string(output))
 }
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -210,3 +215,41 @@ func TestNormalizeArgsIntegration(t *testing.T) {
 		})
 	}
 }
+
+// TestLaunchFailureRunsQueuedHooks launches a session that fails after
+// session.created fired, in a child process, and checks the hook still ran
+// before the CLI exited.
+func TestLaunchFailureRunsQueuedHooks(t *testing.T) {
+	if project := os.Getenv("AGENTDECK_TEST_LAUNCH_PROJECT"); project != "" {
+		handleLaunch("_test", []string{project, "--mcp", "no-such-mcp"})
+		return
+	}
+
+	home := t.TempDir()
+	project := filepath.Join(home, "proj")
+	marker := filepath.Join(home, "created")
+	if err := os.MkdirAll(filepath.Join(home, ".agent-deck"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(project, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := "[session_hooks.events]\n\"session.created\" = [\"sleep 0.5; touch " + marker + "\"]\n"
+	if err := os.WriteFile(filepath.Join(home, ".agent-deck", "config.toml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLaunchFailureRunsQueuedHooks$")
+	cmd.Env = append(os.Environ(), "HOME="+home, "AGENTDECK_TEST_LAUNCH_PROJECT="+project)
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Fatalf("expected launch to exit with 1, got %v:\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "no-such-mcp") {
+		t.Fatalf("expected the MCP error, got:\n%s", output)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("session.created hook did not run before exit: %v\n%s", err, output)
+	}
+}
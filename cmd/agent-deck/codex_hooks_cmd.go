@@ -191,7 +191,7 @@ func handleCodexNotify() {
 func handleCodexHooks(args []string) {
 	if len(args) == 0 {
 		printCodexHooksUsage(os.Stderr)
-		os.Exit(1)
+		exit(1)
 	}
 
 	switch args[0] {
@@ -206,7 +206,7 @@ func handleCodexHooks(args []string) {
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown codex-hooks subcommand: %s\n", args[0])
 		printCodexHooksUsage(os.Stderr)
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -238,11 +238,11 @@ func handleCodexHooksInstall() {
 			updated = prependCodexNotifyBlock(block, updated)
 			if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 				fmt.Fprintf(os.Stderr, "Error creating codex config dir: %v\n", err)
-				os.Exit(1)
+				exit(1)
 			}
 			if err := os.WriteFile(configPath, []byte(updated), 0644); err != nil {
 				fmt.Fprintf(os.Stderr, "Error writing codex config: %v\n", err)
-				os.Exit(1)
+				exit(1)
 			}
 			fmt.Println("Codex notify hook upgraded successfully.")
 			fmt.Printf("Config: %s\n", configPath)
@@ -254,11 +254,11 @@ func handleCodexHooksInstall() {
 		updated = prependCodexNotifyBlock(block, strings.TrimSpace(updated))
 		if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 			fmt.Fprintf(os.Stderr, "Error creating codex config dir: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		if err := os.WriteFile(configPath, []byte(updated), 0644); err != nil {
 			fmt.Fprintf(os.Stderr, "Error writing codex config: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println("Codex notify hook upgraded successfully.")
 		fmt.Printf("Config: %s\n", configPath)
@@ -275,18 +275,18 @@ func handleCodexHooksInstall() {
 		fmt.Fprintf(os.Stderr, "Error: existing notify setting found in %s\n", configPath)
 		fmt.Fprintln(os.Stderr, "Please merge manually by setting:")
 		fmt.Fprintln(os.Stderr, `  notify = ["agent-deck", "codex-notify"]`)
-		os.Exit(1)
+		exit(1)
 	}
 
 	newContent := prependCodexNotifyBlock(block, content)
 
 	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating codex config dir: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing codex config: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Println("Codex notify hook installed successfully.")
@@ -298,7 +298,7 @@ func handleCodexHooksUninstall() {
 	content, err := readFileOrEmpty(configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading codex config: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	begin := strings.Index(content, codexNotifyMarkerBegin)
@@ -306,7 +306,7 @@ func handleCodexHooksUninstall() {
 		endRel := strings.Index(content[begin:], codexNotifyMarkerEnd)
 		if endRel == -1 {
 			fmt.Fprintln(os.Stderr, "Error: malformed agent-deck Codex hook block in config.")
-			os.Exit(1)
+			exit(1)
 		}
 		end := begin + endRel + len(codexNotifyMarkerEnd)
 		updated := content[:begin] + content[end:]
@@ -317,7 +317,7 @@ func handleCodexHooksUninstall() {
 
 		if err := os.WriteFile(configPath, []byte(updated), 0644); err != nil {
 			fmt.Fprintf(os.Stderr, "Error writing codex config: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println("Codex notify hook removed successfully.")
 		return
@@ -326,7 +326,7 @@ func handleCodexHooksUninstall() {
 	if updated, removed := removeLegacyCodexNotifyTable(content); removed {
 		if err := os.WriteFile(configPath, []byte(updated), 0644); err != nil {
 			fmt.Fprintf(os.Stderr, "Error writing codex config: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println("Codex notify hook removed successfully.")
 		return
@@ -335,7 +335,7 @@ func handleCodexHooksUninstall() {
 	if updated, removed := removeExactCodexNotifyLine(content); removed {
 		if err := os.WriteFile(configPath, []byte(updated), 0644); err != nil {
 			fmt.Fprintf(os.Stderr, "Error writing codex config: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println("Codex notify hook removed successfully.")
 		return
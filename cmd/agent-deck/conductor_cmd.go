@@ -36,7 +36,7 @@ func handleConductor(profile string, args []string) {
 		fmt.Fprintf(os.Stderr, "Unknown conductor command: %s\n", args[0])
 		fmt.Fprintln(os.Stderr)
 		printConductorHelp()
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -122,22 +122,22 @@ func handleConductorSetup(profile string, args []string) {
 
 	name, extras, err := parseConductorSetupArgs(fs, args)
 	if err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	if name == "" {
 		fmt.Fprintln(os.Stderr, "Error: conductor name is required")
 		fmt.Fprintln(os.Stderr, "Usage: agent-deck [-p profile] conductor setup <name>")
-		os.Exit(1)
+		exit(1)
 	}
 	if len(extras) > 0 {
 		fmt.Fprintf(os.Stderr, "Error: unexpected arguments: %s\n", strings.Join(extras, " "))
-		os.Exit(1)
+		exit(1)
 	}
 
 	if err := session.ValidateConductorName(name); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	resolvedProfile := session.GetEffectiveProfile(profile)
 
@@ -156,7 +156,7 @@ func handleConductorSetup(profile string, args []string) {
 	config, err := session.LoadUserConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	settings := config.Conductor
@@ -191,7 +191,7 @@ func handleConductorSetup(profile string, args []string) {
 			token = strings.TrimSpace(token)
 			if token == "" {
 				fmt.Fprintln(os.Stderr, "Error: token is required")
-				os.Exit(1)
+				exit(1)
 			}
 
 			fmt.Print("Your Telegram user ID: ")
@@ -200,7 +200,7 @@ func handleConductorSetup(profile string, args []string) {
 			userID, err := strconv.ParseInt(userIDStr, 10, 64)
 			if err != nil || userID == 0 {
 				fmt.Fprintln(os.Stderr, "Error: valid user ID is required")
-				os.Exit(1)
+				exit(1)
 			}
 
 			telegram = session.TelegramSettings{Token: token, UserID: userID}
@@ -228,7 +228,7 @@ func handleConductorSetup(profile string, args []string) {
 			botToken = strings.TrimSpace(botToken)
 			if botToken == "" {
 				fmt.Fprintln(os.Stderr, "Error: bot token is required")
-				os.Exit(1)
+				exit(1)
 			}
 
 			fmt.Print("Slack app token (xapp-...): ")
@@ -236,7 +236,7 @@ func handleConductorSetup(profile string, args []string) {
 			appToken = strings.TrimSpace(appToken)
 			if appToken == "" {
 				fmt.Fprintln(os.Stderr, "Error: app token is required")
-				os.Exit(1)
+				exit(1)
 			}
 
 			fmt.Print("Slack channel ID (C01234...): ")
@@ -244,7 +244,7 @@ func handleConductorSetup(profile string, args []string) {
 			channelID = strings.TrimSpace(channelID)
 			if channelID == "" {
 				fmt.Fprintln(os.Stderr, "Error: channel ID is required")
-				os.Exit(1)
+				exit(1)
 			}
 
 			slack = session.SlackSettings{BotToken: botToken, AppToken: appToken, ChannelID: channelID}
@@ -262,7 +262,7 @@ func handleConductorSetup(profile string, args []string) {
 
 		if err := session.SaveUserConfig(config); err != nil {
 			fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println()
 		fmt.Println("[ok] Conductor config saved to config.toml")
@@ -271,7 +271,7 @@ func handleConductorSetup(profile string, args []string) {
 	// Step 3: Install/update shared CLAUDE.md
 	if err := session.InstallSharedClaudeMD(*sharedClaudeMD); err != nil {
 		fmt.Fprintf(os.Stderr, "Error installing shared CLAUDE.md: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	if !*jsonOutput {
 		fmt.Println("[ok] Shared CLAUDE.md installed/updated")
@@ -280,7 +280,7 @@ func handleConductorSetup(profile string, args []string) {
 	// Step 3b: Install/update shared POLICY.md
 	if err := session.InstallPolicyMD(*sharedPolicyMD); err != nil {
 		fmt.Fprintf(os.Stderr, "Error installing POLICY.md: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	if !*jsonOutput {
 		fmt.Println("[ok] Shared POLICY.md installed/updated")
@@ -293,7 +293,7 @@ func handleConductorSetup(profile string, args []string) {
 
 	if err := session.SetupConductor(name, resolvedProfile, heartbeatEnabled, *description, *claudeMD, *policyMD); err != nil {
 		fmt.Fprintf(os.Stderr, "Error setting up conductor %s: %v\n", name, err)
-		os.Exit(1)
+		exit(1)
 	}
 	if !*jsonOutput {
 		fmt.Printf("  [ok] Directory, CLAUDE.md, and meta.json created\n")
@@ -304,13 +304,13 @@ func handleConductorSetup(profile string, args []string) {
 	storage, err := session.NewStorageWithProfile(resolvedProfile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading storage for %s: %v\n", resolvedProfile, err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	instances, groups, err := storage.LoadWithGroups()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading sessions for %s: %v\n", resolvedProfile, err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Check if session already exists
@@ -349,7 +349,7 @@ func handleConductorSetup(profile string, args []string) {
 
 	if err := storage.SaveWithGroups(instances, groupTree); err != nil {
 		fmt.Fprintf(os.Stderr, "Error saving session for %s: %v\n", resolvedProfile, err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Step 6: Install heartbeat timer (if heartbeat enabled)
@@ -376,7 +376,7 @@ func handleConductorSetup(profile string, args []string) {
 
 		if err := session.InstallBridgeScript(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error installing bridge.py: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		if !*jsonOutput {
 			fmt.Println("[ok] bridge.py installed")
@@ -479,13 +479,13 @@ func handleConductorTeardown(_ string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, flagArgs)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	if !*allConductors && name == "" {
 		fmt.Fprintln(os.Stderr, "Error: conductor name or --all is required")
 		fmt.Fprintln(os.Stderr, "Usage: agent-deck conductor teardown <name> or --all")
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Auto-migrate before teardown so we can find legacy conductors
@@ -498,7 +498,7 @@ func handleConductorTeardown(_ string, args []string) {
 		targets, err = session.ListConductors()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error listing conductors: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		if len(targets) == 0 {
 			if *jsonOutput {
@@ -512,7 +512,7 @@ func handleConductorTeardown(_ string, args []string) {
 		meta, err := session.LoadConductorMeta(name)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: conductor %q not found: %v\n", name, err)
-			os.Exit(1)
+			exit(1)
 		}
 		targets = []session.ConductorMeta{*meta}
 	}
@@ -661,7 +661,7 @@ func handleConductorStatus(_ string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, flagArgs)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	settings := session.GetConductorSettings()
@@ -684,7 +684,7 @@ func handleConductorStatus(_ string, args []string) {
 		meta, err := session.LoadConductorMeta(name)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: conductor %q not found: %v\n", name, err)
-			os.Exit(1)
+			exit(1)
 		}
 		conductors = []session.ConductorMeta{*meta}
 	} else {
@@ -692,7 +692,7 @@ func handleConductorStatus(_ string, args []string) {
 		conductors, err = session.ListConductors()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error listing conductors: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -822,7 +822,7 @@ func handleConductorList(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Auto-migrate
@@ -840,7 +840,7 @@ func handleConductorList(profile string, args []string) {
 	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error listing conductors: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if *jsonOutput {
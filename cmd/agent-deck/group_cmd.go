@@ -3,7 +3,6 @@ package main
 import (
 	"flag"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/asheshgoplani/agent-deck/internal/session"
@@ -35,7 +34,7 @@ func handleGroup(profile string, args []string) {
 		fmt.Printf("Unknown group command: %s\n", args[0])
 		fmt.Println()
 		printGroupHelp()
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -78,7 +77,7 @@ func handleGroupList(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	out := NewCLIOutput(*jsonOutput, *quiet || *quietShort)
@@ -87,13 +86,13 @@ func handleGroupList(profile string, args []string) {
 	storage, err := session.NewStorageWithProfile(profile)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to initialize storage: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	instances, groups, err := storage.LoadWithGroups()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Build group tree
@@ -319,7 +318,7 @@ func handleGroupCreate(profile string, args []string) {
 	args = reorderGroupArgs(args)
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	out := NewCLIOutput(*jsonOutput, *quiet || *quietShort)
@@ -328,20 +327,20 @@ func handleGroupCreate(profile string, args []string) {
 	if name == "" {
 		out.Error("group name is required", ErrCodeNotFound)
 		fmt.Println("Usage: agent-deck group create <name> [--parent <group>]")
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Load sessions and groups
 	storage, err := session.NewStorageWithProfile(profile)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to initialize storage: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	instances, groups, err := storage.LoadWithGroups()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Build group tree
@@ -355,7 +354,7 @@ func handleGroupCreate(profile string, args []string) {
 		parentPath := normalizeGroupPath(*parent)
 		if _, exists := groupTree.Groups[parentPath]; !exists {
 			out.Error(fmt.Sprintf("parent group '%s' not found", *parent), ErrCodeNotFound)
-			os.Exit(2)
+			exit(2)
 		}
 		newGroup = groupTree.CreateSubgroup(parentPath, name)
 		fullPath = newGroup.Path
@@ -380,7 +379,7 @@ func handleGroupCreate(profile string, args []string) {
 	// Save
 	if err := storage.SaveWithGroups(instances, groupTree); err != nil {
 		out.Error(fmt.Sprintf("failed to save: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if existingGroup {
@@ -426,7 +425,7 @@ func handleGroupUpdate(profile string, args []string) {
 	args = reorderGroupArgs(args)
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	out := NewCLIOutput(*jsonOutput, *quiet || *quietShort)
@@ -435,24 +434,24 @@ func handleGroupUpdate(profile string, args []string) {
 	if name == "" {
 		out.Error("group name is required", ErrCodeNotFound)
 		fmt.Println("Usage: agent-deck group update <name> [--default-path <path>|--clear-default-path]")
-		os.Exit(1)
+		exit(1)
 	}
 
 	if (*defaultPath == "" && !*clearDefaultPath) || (*defaultPath != "" && *clearDefaultPath) {
 		out.Error("specify exactly one of --default-path or --clear-default-path", ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	storage, err := session.NewStorageWithProfile(profile)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to initialize storage: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	instances, groups, err := storage.LoadWithGroups()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	groupTree := session.NewGroupTreeWithGroups(instances, groups)
@@ -470,7 +469,7 @@ func handleGroupUpdate(profile string, args []string) {
 	}
 	if !exists {
 		out.Error(fmt.Sprintf("group '%s' not found", name), ErrCodeNotFound)
-		os.Exit(2)
+		exit(2)
 	}
 
 	if *clearDefaultPath {
@@ -481,7 +480,7 @@ func handleGroupUpdate(profile string, args []string) {
 
 	if err := storage.SaveWithGroups(instances, groupTree); err != nil {
 		out.Error(fmt.Sprintf("failed to save: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	currentDefaultPath := groupTree.DefaultPathForGroup(groupPath)
@@ -524,7 +523,7 @@ func handleGroupDelete(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	out := NewCLIOutput(*jsonOutput, *quiet || *quietShort)
@@ -533,20 +532,20 @@ func handleGroupDelete(profile string, args []string) {
 	if name == "" {
 		out.Error("group name is required", ErrCodeNotFound)
 		fmt.Println("Usage: agent-deck group delete <name> [--force]")
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Load sessions and groups
 	storage, err := session.NewStorageWithProfile(profile)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to initialize storage: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	instances, groups, err := storage.LoadWithGroups()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Build group tree
@@ -569,13 +568,13 @@ func handleGroupDelete(profile string, args []string) {
 
 	if !exists {
 		out.Error(fmt.Sprintf("group '%s' not found", name), ErrCodeNotFound)
-		os.Exit(2)
+		exit(2)
 	}
 
 	// Check if group is protected (default group)
 	if groupPath == session.DefaultGroupPath {
 		out.Error("cannot delete the default group", ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Count sessions in group and subgroups
@@ -589,7 +588,7 @@ func handleGroupDelete(profile string, args []string) {
 	// Check if group has sessions and --force not specified
 	if sessionCount > 0 && !*force {
 		out.Error(fmt.Sprintf("group '%s' has %d sessions. Use --force to move them to parent.", name, sessionCount), ErrCodeGroupNotEmpty)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Determine where sessions will be moved
@@ -615,7 +614,7 @@ func handleGroupDelete(profile string, args []string) {
 	// Save
 	if err := storage.SaveWithGroups(groupTree.GetAllInstances(), groupTree); err != nil {
 		out.Error(fmt.Sprintf("failed to save: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	out.Success(fmt.Sprintf("Deleted group: %s", name), map[string]interface{}{
@@ -652,7 +651,7 @@ func handleGroupMove(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	out := NewCLIOutput(*jsonOutput, *quiet || *quietShort)
@@ -663,26 +662,26 @@ func handleGroupMove(profile string, args []string) {
 	if sessionID == "" {
 		out.Error("session identifier is required", ErrCodeNotFound)
 		fmt.Println("Usage: agent-deck group move <session-id> <group>")
-		os.Exit(1)
+		exit(1)
 	}
 
 	if fs.NArg() < 2 {
 		out.Error("target group is required", ErrCodeNotFound)
 		fmt.Println("Usage: agent-deck group move <session-id> <group>")
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Load sessions and groups
 	storage, err := session.NewStorageWithProfile(profile)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to initialize storage: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	instances, groups, err := storage.LoadWithGroups()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Find the session
@@ -690,9 +689,9 @@ func handleGroupMove(profile string, args []string) {
 	if inst == nil {
 		out.Error(errMsg, errCode)
 		if errCode == ErrCodeNotFound {
-			os.Exit(2)
+			exit(2)
 		}
-		os.Exit(1)
+		exit(1)
 		return // unreachable, satisfies staticcheck SA5011
 	}
 
@@ -726,7 +725,7 @@ func handleGroupMove(profile string, args []string) {
 	// Save
 	if err := storage.SaveWithGroups(groupTree.GetAllInstances(), groupTree); err != nil {
 		out.Error(fmt.Sprintf("failed to save: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	toGroup := targetGroupPath
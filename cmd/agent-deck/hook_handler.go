@@ -162,11 +162,12 @@ func cleanStaleHookFiles() {
 	}
 }
 
-// handleHooks handles the "hooks" CLI subcommand for manual hook management.
-func handleHooks(args []string) {
+// handleHooks handles the "hooks" CLI subcommand for manual hook management
+// and for trying [session_hooks] commands.
+func handleHooks(profile string, args []string) {
 	if len(args) == 0 {
-		fmt.Fprintln(os.Stderr, "Usage: agent-deck hooks <install|uninstall|status>")
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, "Usage: agent-deck hooks <install|uninstall|status|test>")
+		exit(1)
 	}
 
 	switch args[0] {
@@ -176,10 +177,12 @@ func handleHooks(args []string) {
 		handleHooksUninstall()
 	case "status":
 		handleHooksStatus()
+	case "test":
+		handleHooksTest(profile, args[1:])
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown hooks subcommand: %s\n", args[0])
-		fmt.Fprintln(os.Stderr, "Usage: agent-deck hooks <install|uninstall|status>")
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, "Usage: agent-deck hooks <install|uninstall|status|test>")
+		exit(1)
 	}
 }
 
@@ -188,7 +191,7 @@ func handleHooksInstall() {
 	installed, err := session.InjectClaudeHooks(configDir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error installing hooks: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	if installed {
 		fmt.Println("Claude Code hooks installed successfully.")
@@ -203,7 +206,7 @@ func handleHooksUninstall() {
 	removed, err := session.RemoveClaudeHooks(configDir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error removing hooks: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	if removed {
 		fmt.Println("Claude Code hooks removed successfully.")
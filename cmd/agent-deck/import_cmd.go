@@ -0,0 +1,221 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/searchdb"
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+// handleImport dispatches `agent-deck import <what>`
+func handleImport(profile string, args []string) {
+	if len(args) == 0 || isHelpArg(args[0]) {
+		printImportHelp()
+		return
+	}
+	switch args[0] {
+	case "conversations", "convos":
+		handleImportConversations(profile, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown import source: %s\n\n", args[0])
+		printImportHelp()
+		exit(1)
+	}
+}
+
+func printImportHelp() {
+	fmt.Println("Usage: agent-deck import <source> [options]")
+	fmt.Println()
+	fmt.Println("Sources:")
+	fmt.Println("  conversations   Turn Claude, Codex, Gemini and OpenCode conversations on disk into sessions")
+	fmt.Println()
+	fmt.Println("See 'agent-deck session import' for session bundles.")
+}
+
+// handleImportConversations creates stopped sessions that resume tool
+// conversations not yet managed by agent-deck.
+func handleImportConversations(profile string, args []string) {
+	fs := flag.NewFlagSet("import conversations", flag.ExitOnError)
+	tools := fs.String("tool", "", "Only these tools (comma-separated: claude,codex,gemini,opencode)")
+	since := fs.String("since", "", "Only conversations active since (YYYY-MM-DD or age like 7d)")
+	project := fs.String("project", "", "Only conversations in this directory or below it")
+	group := fs.String("group", "", "Group for imported sessions (default: derived from each project path)")
+	groupShort := fs.String("g", "", "Group for imported sessions (short)")
+	dryRun := fs.Bool("dry-run", false, "List what would be imported without changing anything")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	quiet := fs.Bool("quiet", false, "Minimal output")
+	quietShort := fs.Bool("q", false, "Minimal output (short)")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck import conversations [options]")
+		fmt.Println()
+		fmt.Println("Discover conversations the tools saved on disk and add each one as a stopped")
+		fmt.Println("session that resumes the conversation when started. Conversations already")
+		fmt.Println("linked to a session are skipped. Titles come from the first prompt.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  agent-deck import conversations --since 7d --dry-run")
+		fmt.Println("  agent-deck import conversations --tool claude --project ~/src/api -g api")
+	}
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+	out := NewCLIOutput(*jsonOutput, *quiet || *quietShort)
+
+	opts := session.ConversationDiscoveryOptions{}
+	if *tools != "" {
+		for _, tool := range strings.Split(*tools, ",") {
+			tool = strings.ToLower(strings.TrimSpace(tool))
+			switch tool {
+			case session.TranscriptToolClaude, session.TranscriptToolCodex, session.TranscriptToolGemini, session.TranscriptToolOpenCode:
+				opts.Tools = append(opts.Tools, tool)
+			case "":
+			default:
+				out.Error(fmt.Sprintf("unsupported tool %q (use claude, codex, gemini or opencode)", tool), ErrCodeInvalidOperation)
+				exit(1)
+			}
+		}
+	}
+	if *since != "" {
+		t, err := searchdb.ParseDate(*since)
+		if err != nil {
+			out.Error(fmt.Sprintf("invalid --since: %v", err), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		opts.Since = t
+	}
+	if *project != "" {
+		abs, err := filepath.Abs(session.ExpandPath(*project))
+		if err != nil {
+			out.Error(fmt.Sprintf("invalid --project: %v", err), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		opts.ProjectPath = abs
+	}
+	groupPath := firstNonEmpty(*group, *groupShort)
+
+	storage, instances, groupsData, err := loadSessionData(profile)
+	if err != nil {
+		out.Error(err.Error(), ErrCodeNotFound)
+		exit(1)
+	}
+
+	found, err := session.DiscoverConversations(instances, opts)
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to scan conversations: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	var toImport, linked, skipped []*session.DiscoveredConversation
+	for _, c := range found {
+		switch {
+		case c.InstanceID != "":
+			linked = append(linked, c)
+		case c.Skip != "":
+			skipped = append(skipped, c)
+		default:
+			toImport = append(toImport, c)
+		}
+	}
+
+	if *dryRun {
+		var b strings.Builder
+		if len(found) == 0 {
+			b.WriteString("No conversations found\n")
+		} else {
+			tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ACTION\tTOOL\tLAST ACTIVE\tPROJECT\tTITLE")
+			for _, c := range found {
+				action := "import"
+				switch {
+				case c.InstanceID != "":
+					action = "linked"
+				case c.Skip != "":
+					action = "skip: " + c.Skip
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", action, c.Tool, formatImportAge(c.LastActivity), c.ProjectPath, c.Title)
+			}
+			_ = tw.Flush()
+			fmt.Fprintf(&b, "\n%d to import, %d already linked, %d skipped (dry run)\n", len(toImport), len(linked), len(skipped))
+		}
+		out.Print(b.String(), map[string]interface{}{
+			"dry_run":       true,
+			"import":        nonNilConversations(toImport),
+			"linked":        nonNilConversations(linked),
+			"skipped":       nonNilConversations(skipped),
+			"conversations": len(found),
+		})
+		return
+	}
+
+	imported := make([]map[string]interface{}, 0, len(toImport))
+	for _, c := range toImport {
+		title := session.UniqueSessionTitle(c.Title, c.ProjectPath, instances)
+		inst := c.NewInstance(title, groupPath)
+		instances = append(instances, inst)
+		imported = append(imported, map[string]interface{}{
+			"id":         inst.ID,
+			"title":      inst.Title,
+			"tool":       inst.Tool,
+			"session_id": c.SessionID,
+			"path":       inst.ProjectPath,
+			"group":      inst.GroupPath,
+		})
+	}
+
+	if len(toImport) > 0 {
+		groupTree := session.NewGroupTreeWithGroups(instances, groupsData)
+		if groupPath != "" {
+			groupTree.CreateGroup(groupPath)
+		}
+		if err := storage.SaveWithGroups(instances, groupTree); err != nil {
+			out.Error(fmt.Sprintf("failed to save sessions: %v", err), ErrCodeInvalidOperation)
+			exit(1)
+		}
+	}
+
+	var b strings.Builder
+	for _, m := range imported {
+		fmt.Fprintf(&b, "  + %s (%s) %s\n", m["title"], m["tool"], m["path"])
+	}
+	fmt.Fprintf(&b, "Imported %d conversations (%d already linked, %d skipped)", len(imported), len(linked), len(skipped))
+	if len(imported) > 0 {
+		b.WriteString("; start them with 'agent-deck session start <title>'")
+	}
+	out.Success(b.String(), map[string]interface{}{
+		"success":  true,
+		"imported": imported,
+		"linked":   len(linked),
+		"skipped":  nonNilConversations(skipped),
+	})
+}
+
+func nonNilConversations(list []*session.DiscoveredConversation) []*session.DiscoveredConversation {
+	if list == nil {
+		return []*session.DiscoveredConversation{}
+	}
+	return list
+}
+
+// formatImportAge renders a timestamp as a compact age ("3h ago", "2025-01-04")
+func formatImportAge(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 7*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+	return t.Local().Format("2006-01-02")
+}
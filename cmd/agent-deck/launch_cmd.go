@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -8,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/asheshgoplani/agent-deck/internal/forge"
 	"github.com/asheshgoplani/agent-deck/internal/git"
 	"github.com/asheshgoplani/agent-deck/internal/session"
 )
@@ -38,6 +41,10 @@ func handleLaunch(profile string, args []string) {
 	newBranch := fs.Bool("b", false, "Create new branch (use with --worktree)")
 	newBranchLong := fs.Bool("new-branch", false, "Create new branch")
 	worktreeLocation := fs.String("location", "", "Worktree location: sibling, subdirectory, or custom path")
+	worktreeFrom := fs.String("worktree-from", "", "Create worktree from a remote branch (e.g. origin/feature-x), fetched first")
+	prNumber := fs.Int("pr", 0, "Create worktree on pull request N's branch (see [forge] in config.toml)")
+	prComments := fs.Bool("pr-comments", false, "Send the pull request's review comments as the initial message (with --pr)")
+	workspaceName := fs.String("workspace", "", "Create the session from a [workspaces] definition in config.toml (-w/-b apply to every repo)")
 
 	// MCP flag
 	var mcpFlags []string
@@ -67,18 +74,26 @@ func handleLaunch(profile string, args []string) {
 		fmt.Println("  agent-deck launch /path/to/project -t \"My Agent\" -c claude -g work")
 		fmt.Println("  agent-deck launch . -c claude --mcp memory -m \"Research topic X\"")
 		fmt.Println("  agent-deck launch . -c claude -m \"Fix bug\" --no-wait")
+		fmt.Println("  agent-deck launch . -c claude --worktree-from origin/feature-x")
+		fmt.Println("  agent-deck launch . -c claude --pr 123 --pr-comments")
+		fmt.Println("  agent-deck launch --workspace shop -c claude -w feat/cart -b")
 	}
 
 	// Reorder args: move path to end so flags are parsed correctly
 	args = reorderArgsForFlagParsing(args)
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	quietMode := *quiet || *quietShort
 	out := NewCLIOutput(*jsonOutput, quietMode)
 
+	if *workspaceName != "" && fs.Arg(0) != "" {
+		out.Error("--workspace sets the project path (its first repo); omit the path argument", ErrCodeInvalidOperation)
+		exit(1)
+	}
+
 	// Resolve path
 	path := strings.Trim(fs.Arg(0), "'\"")
 	if path == "" || path == "." {
@@ -86,14 +101,14 @@ func handleLaunch(profile string, args []string) {
 		path, err = os.Getwd()
 		if err != nil {
 			out.Error(fmt.Sprintf("failed to get current directory: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	} else {
 		var err error
 		path, err = filepath.Abs(path)
 		if err != nil {
 			out.Error(fmt.Sprintf("failed to resolve path: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -101,11 +116,11 @@ func handleLaunch(profile string, args []string) {
 	info, err := os.Stat(path)
 	if err != nil {
 		out.Error(fmt.Sprintf("path does not exist: %s", path), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 	if !info.IsDir() {
 		out.Error(fmt.Sprintf("path is not a directory: %s", path), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Merge flags
@@ -121,39 +136,126 @@ func handleLaunch(profile string, args []string) {
 		wtBranch = *worktreeBranchLong
 	}
 	createNewBranch := *newBranch || *newBranchLong
+	if *worktreeFrom != "" && *prNumber > 0 {
+		out.Error("--worktree-from and --pr cannot be combined", ErrCodeInvalidOperation)
+		exit(1)
+	}
+	fromSource := *worktreeFrom != "" || *prNumber > 0
+	if fromSource && createNewBranch {
+		out.Error("-b cannot be used with --worktree-from or --pr (the branch comes from the remote)", ErrCodeInvalidOperation)
+		exit(1)
+	}
+	if *workspaceName != "" && fromSource {
+		out.Error("--workspace cannot be combined with --worktree-from or --pr", ErrCodeInvalidOperation)
+		exit(1)
+	}
+	if *prComments && *prNumber <= 0 {
+		out.Error("--pr-comments requires --pr", ErrCodeInvalidOperation)
+		exit(1)
+	}
 
 	// Validate --resume-session requires Claude
 	if *resumeSession != "" {
 		tool := detectTool(sessionCommand)
 		if tool != "claude" {
 			out.Error("--resume-session only works with Claude sessions (-c claude)", ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
-	// Handle worktree creation
+	// Handle worktree creation (a workspace creates one per repo)
 	var worktreePath, worktreeRepoRoot string
-	if wtBranch != "" {
+	var pullRequest *forge.PullRequest
+	var workspace *session.WorkspaceState
+	if *workspaceName != "" {
+		def := session.GetWorkspaceDef(*workspaceName)
+		if def == nil {
+			out.Error(fmt.Sprintf("workspace '%s' not found in config.toml (see: agent-deck workspace list)", *workspaceName), ErrCodeNotFound)
+			exit(1)
+		}
+		workspace, err = session.CreateWorkspace(*workspaceName, def, session.WorkspaceOptions{
+			Branch:    wtBranch,
+			NewBranch: createNewBranch,
+			Location:  *worktreeLocation,
+		}, worktreeProgress(*jsonOutput))
+		if err != nil {
+			out.Error(fmt.Sprintf("failed to create workspace: %v", err), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		path = workspace.Primary().Dir()
+		if sessionGroup == "" {
+			sessionGroup = def.Group
+		}
+	} else if wtBranch != "" || fromSource {
 		if !git.IsGitRepo(path) {
 			out.Error(fmt.Sprintf("%s is not a git repository", path), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 
 		repoRoot, err := git.GetWorktreeBaseRoot(path)
 		if err != nil {
 			out.Error(fmt.Sprintf("failed to get repo root: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 
-		if err := git.ValidateBranchName(wtBranch); err != nil {
-			out.Error(fmt.Sprintf("invalid branch name: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+		if wtBranch != "" {
+			if err := git.ValidateBranchName(wtBranch); err != nil {
+				out.Error(fmt.Sprintf("invalid branch name: %v", err), ErrCodeInvalidOperation)
+				exit(1)
+			}
 		}
 
 		branchExists := git.BranchExists(repoRoot, wtBranch)
 		if createNewBranch && branchExists {
 			out.Error(fmt.Sprintf("branch '%s' already exists (remove -b flag to use existing branch)", wtBranch), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
+		}
+
+		// Resolve --worktree-from / --pr to a local branch (-w names it)
+		var source *worktreeSource
+		if *worktreeFrom != "" {
+			source, err = resolveRemoteBranch(repoRoot, *worktreeFrom, wtBranch)
+			if err != nil {
+				out.Error(err.Error(), ErrCodeInvalidOperation)
+				exit(1)
+			}
+		} else if *prNumber > 0 {
+			provider, remote, err := newForgeProvider(repoRoot)
+			if err != nil {
+				out.Error(err.Error(), ErrCodeInvalidOperation)
+				exit(1)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			source, err = resolvePullRequest(ctx, provider, remote, repoRoot, *prNumber, wtBranch)
+			if err == nil && *prComments {
+				var comments []forge.ReviewComment
+				if comments, err = provider.ReviewComments(ctx, *prNumber); err == nil {
+					if len(comments) == 0 {
+						fmt.Fprintf(os.Stderr, "Pull request #%d has no review comments\n", *prNumber)
+					} else {
+						initialMessage = strings.TrimSpace(initialMessage + "\n\n" + forge.ReviewPrompt(source.PR, comments))
+					}
+				}
+			}
+			cancel()
+			if err != nil {
+				code := ErrCodeInvalidOperation
+				if errors.Is(err, forge.ErrNotFound) {
+					code = ErrCodeNotFound
+				}
+				out.Error(err.Error(), code)
+				exit(1)
+			}
+			pullRequest = source.PR
+		}
+		if source != nil {
+			wtBranch = source.Branch
+		}
+		// discardSourceBranch deletes a branch created above when the worktree fails
+		discardSourceBranch := func() {
+			if source != nil && source.BranchCreated {
+				_ = git.DeleteBranch(repoRoot, source.Branch, true)
+			}
 		}
 
 		wtSettings := session.GetWorktreeSettings()
@@ -171,18 +273,21 @@ func handleLaunch(profile string, args []string) {
 		})
 
 		if err := os.MkdirAll(filepath.Dir(worktreePath), 0o755); err != nil {
+			discardSourceBranch()
 			out.Error(fmt.Sprintf("failed to create parent directory: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 
 		if _, err := os.Stat(worktreePath); err == nil {
+			discardSourceBranch()
 			out.Error(fmt.Sprintf("worktree already exists at %s", worktreePath), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 
-		if err := git.CreateWorktree(repoRoot, worktreePath, wtBranch); err != nil {
+		if err := session.CreateWorktree(repoRoot, worktreePath, wtBranch, worktreeProgress(*jsonOutput)); err != nil {
+			discardSourceBranch()
 			out.Error(fmt.Sprintf("failed to create worktree: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 
 		worktreeRepoRoot = repoRoot
@@ -193,7 +298,7 @@ func handleLaunch(profile string, args []string) {
 	storage, instances, groups, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(err.Error(), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Resolve parent session if specified
@@ -203,16 +308,19 @@ func handleLaunch(profile string, args []string) {
 		parentInstance, errMsg, _ = ResolveSession(sessionParent, instances)
 		if parentInstance == nil {
 			out.Error(errMsg, ErrCodeNotFound)
-			os.Exit(1)
+			exit(1)
 		}
 		if parentInstance.IsSubSession() {
 			out.Error("cannot create sub-session of a sub-session (single level only)", ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 		sessionGroup = parentInstance.GroupPath
 	}
 
-	// Default title to folder name
+	// Default title to the workspace or folder name
+	if sessionTitle == "" && workspace != nil {
+		sessionTitle = workspace.Name
+	}
 	if sessionTitle == "" {
 		sessionTitle = filepath.Base(path)
 	}
@@ -227,7 +335,7 @@ func handleLaunch(profile string, args []string) {
 				fmt.Sprintf("session already exists: %s (%s)", existingInst.Title, existingInst.ID),
 				ErrCodeAlreadyExists,
 			)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -261,6 +369,9 @@ func handleLaunch(profile string, args []string) {
 		newInstance.WorktreeRepoRoot = worktreeRepoRoot
 		newInstance.WorktreeBranch = wtBranch
 	}
+	if workspace != nil {
+		workspace.ApplyTo(newInstance)
+	}
 
 	if *resumeSession != "" {
 		newInstance.ClaudeSessionID = *resumeSession
@@ -286,8 +397,9 @@ func handleLaunch(profile string, args []string) {
 
 	if err := storage.SaveWithGroups(instances, groupTree); err != nil {
 		out.Error(fmt.Sprintf("failed to save session: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
+	session.FireSessionHook(session.HookSessionCreated, newInstance, nil)
 
 	// Attach MCPs if specified
 	if len(mcpFlags) > 0 {
@@ -295,12 +407,12 @@ func handleLaunch(profile string, args []string) {
 		for _, mcpName := range mcpFlags {
 			if _, exists := availableMCPs[mcpName]; !exists {
 				out.Error(fmt.Sprintf("MCP '%s' not found in config.toml", mcpName), ErrCodeNotFound)
-				os.Exit(1)
+				exit(1)
 			}
 		}
 		if err := session.WriteMCPJsonFromConfig(path, mcpFlags); err != nil {
 			out.Error(fmt.Sprintf("failed to write MCPs: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -308,12 +420,12 @@ func handleLaunch(profile string, args []string) {
 	if initialMessage != "" {
 		if err := newInstance.StartWithMessage(initialMessage); err != nil {
 			out.Error(fmt.Sprintf("failed to start session: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	} else {
 		if err := newInstance.Start(); err != nil {
 			out.Error(fmt.Sprintf("failed to start session: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -323,7 +435,7 @@ func handleLaunch(profile string, args []string) {
 	// Save again with updated state (session ID, tmux name)
 	if err := saveSessionData(storage, instances); err != nil {
 		out.Error(fmt.Sprintf("failed to save session state: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Send message if provided and StartWithMessage wasn't used
@@ -360,8 +472,28 @@ func handleLaunch(profile string, args []string) {
 		jsonData["worktree_path"] = worktreePath
 		jsonData["worktree_branch"] = wtBranch
 	}
+	if pullRequest != nil {
+		jsonData["pull_request"] = pullRequest
+	}
+	if workspace != nil {
+		jsonData["workspace"] = workspace
+		if workspace.Branch != "" {
+			jsonData["worktree_path"] = newInstance.WorktreePath
+			jsonData["worktree_branch"] = workspace.Branch
+		}
+	}
 
 	msg := fmt.Sprintf("Launched session: %s", newInstance.Title)
+	if pullRequest != nil {
+		msg += fmt.Sprintf(" on pull request #%d (%s)", pullRequest.Number, pullRequest.Title)
+	}
+	if workspace != nil {
+		msg += fmt.Sprintf(" in workspace %s (%d repos", workspace.Name, len(workspace.Repos))
+		if workspace.Branch != "" {
+			msg += " on " + workspace.Branch
+		}
+		msg += ")"
+	}
 	if initialMessage != "" {
 		msg += " (message pending)"
 	}
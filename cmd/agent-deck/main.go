@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -183,6 +185,9 @@ func initColorProfile() {
 }
 
 func main() {
+	// Let lifecycle hooks fired by a command finish before the process exits
+	defer session.WaitSessionHooks()
+
 	// Extract global -p/--profile flag before subcommand dispatch
 	profile, args := extractProfileFlag(os.Args[1:])
 	if profile != "" {
@@ -219,7 +224,7 @@ func main() {
 			handleStatus(profile, args[1:])
 			return
 		case "profile":
-			handleProfile(args[1:])
+			handleProfile(profile, args[1:])
 			return
 		case "update":
 			handleUpdate(args[1:])
@@ -236,7 +241,7 @@ func main() {
 		case "mcp-proxy":
 			if len(args) < 2 {
 				fmt.Fprintln(os.Stderr, "Usage: agent-deck mcp-proxy <socket-path>")
-				os.Exit(1)
+				exit(1)
 			}
 			runMCPProxy(args[1])
 			return
@@ -252,10 +257,37 @@ func main() {
 		case "conductor":
 			handleConductor(profile, args[1:])
 			return
+		case "search":
+			handleSearch(args[1:])
+			return
+		case "report":
+			handleReport(profile, args[1:])
+			return
+		case "import":
+			handleImport(profile, args[1:])
+			return
+		case "models":
+			handleModels(args[1:])
+			return
 		case "worktree", "wt":
 			handleWorktree(profile, args[1:])
 			return
+		case "workspace", "ws":
+			handleWorkspace(profile, args[1:])
+			return
 		case "web":
+			if len(args) > 1 && args[1] == "token" {
+				handleWebToken(profile, args[2:])
+				return
+			}
+			if len(args) > 1 && args[1] == "cert" {
+				handleWebCert(profile, args[2:])
+				return
+			}
+			if len(args) > 1 && args[1] == "federation" {
+				handleWebFederation(profile, args[2:])
+				return
+			}
 			webEnabled = true
 			webArgs = append(webArgs, args[1:]...)
 			// fall through to TUI launch below
@@ -269,7 +301,7 @@ func main() {
 			handleCodexNotify()
 			return
 		case "hooks":
-			handleHooks(args[1:])
+			handleHooks(profile, args[1:])
 			return
 		case "codex-hooks":
 			handleCodexHooks(args[1:])
@@ -290,7 +322,7 @@ func main() {
 		fmt.Fprintln(os.Stderr, "  agent-deck list                    # List sessions")
 		fmt.Fprintln(os.Stderr, "")
 		fmt.Fprintln(os.Stderr, "To open the TUI, detach first with Ctrl+Q.")
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Set version for UI update checking
@@ -311,7 +343,7 @@ func main() {
 		fmt.Println("Error: tmux not found in PATH")
 		fmt.Println("\nAgent Deck requires tmux. Install with:")
 		fmt.Println("  brew install tmux")
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Create storage early to register instance via SQLite
@@ -331,7 +363,7 @@ func main() {
 			if electErr == nil && !isFirst {
 				fmt.Println("Error: agent-deck is already running for this profile")
 				fmt.Println("Set [instances] allow_multiple = true in config.toml to allow multiple instances")
-				os.Exit(1)
+				exit(1)
 			}
 		}
 	}
@@ -345,7 +377,7 @@ func main() {
 			_ = db.ResignPrimary()
 			_ = db.UnregisterInstance()
 		}
-		os.Exit(0)
+		exit(0)
 	}()
 
 	// Set up structured logging (JSONL format with rotation)
@@ -436,7 +468,7 @@ func main() {
 		server, err := buildWebServer(effectiveProfile, webArgs, liveMenuData)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: web server setup failed: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		go func() {
 			if err := server.Start(); err != nil {
@@ -444,12 +476,36 @@ func main() {
 					slog.String("error", err.Error()))
 			}
 		}()
-		fmt.Printf("Web server: http://%s\n", server.Addr())
+		fmt.Printf("Web server: %s\n", server.URL())
 		defer func() {
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 			_ = server.Shutdown(ctx)
 		}()
+	} else if metricsSettings := session.GetMetricsSettings(); metricsSettings.Listen != "" {
+		// No web server: serve /metrics on its own listener ([metrics] listen)
+		addr := metricsSettings.Listen
+		effectiveProfile := session.GetEffectiveProfile(profile)
+		liveMenuData := web.NewMemoryMenuData(web.NewSessionDataService(effectiveProfile))
+		homeModel.SetWebMenuData(liveMenuData)
+
+		metricsServer, err := web.NewMetricsServer(metricsSettings, liveMenuData)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logging.ForComponent(logging.CompWeb).Error("metrics_server_error",
+					slog.String("addr", addr),
+					slog.String("error", err.Error()))
+			}
+		}()
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = metricsServer.Shutdown(ctx)
+		}()
 	}
 
 	p := tea.NewProgram(
@@ -467,7 +523,7 @@ func main() {
 
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -527,6 +583,9 @@ func reorderArgsForFlagParsing(args []string) []string {
 		"-w":        true, "--worktree": true,
 		"--location":       true,
 		"--resume-session": true,
+		"--worktree-from":  true,
+		"--pr":             true,
+		"--workspace":      true,
 	}
 
 	var flags []string
@@ -702,7 +761,7 @@ func handleAdd(profile string, args []string) {
 	args = reorderArgsForFlagParsing(args)
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Path argument is optional; if omitted with -g/--group, we'll try group default_path.
@@ -729,7 +788,7 @@ func handleAdd(profile string, args []string) {
 		tool := detectTool(sessionCommand)
 		if tool != "claude" {
 			fmt.Println("Error: --resume-session only works with Claude sessions (-c claude)")
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -737,13 +796,13 @@ func handleAdd(profile string, args []string) {
 	storage, err := session.NewStorageWithProfile(profile)
 	if err != nil {
 		fmt.Printf("Error: failed to initialize storage: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	instances, groups, err := storage.LoadWithGroups()
 	if err != nil {
 		fmt.Printf("Error: failed to load sessions: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	groupTree := session.NewGroupTreeWithGroups(instances, groups)
@@ -755,13 +814,13 @@ func handleAdd(profile string, args []string) {
 		parentInstance, errMsg, _ = ResolveSession(sessionParent, instances)
 		if parentInstance == nil {
 			fmt.Printf("Error: %s\n", errMsg)
-			os.Exit(1)
+			exit(1)
 			return // unreachable, satisfies staticcheck SA5011
 		}
 		// Sub-sessions cannot have sub-sessions (single level only)
 		if parentInstance.IsSubSession() {
 			fmt.Printf("Error: cannot create sub-session of a sub-session (single level only)\n")
-			os.Exit(1)
+			exit(1)
 		}
 		// Inherit group from parent
 		sessionGroup = parentInstance.GroupPath
@@ -777,13 +836,13 @@ func handleAdd(profile string, args []string) {
 			path, err = os.Getwd()
 			if err != nil {
 				fmt.Printf("Error: failed to get current directory: %v\n", err)
-				os.Exit(1)
+				exit(1)
 			}
 		} else {
 			path, err = filepath.Abs(rawPathArg)
 			if err != nil {
 				fmt.Printf("Error: failed to resolve path: %v\n", err)
-				os.Exit(1)
+				exit(1)
 			}
 		}
 	} else {
@@ -795,7 +854,7 @@ func handleAdd(profile string, args []string) {
 			path, err = os.Getwd()
 			if err != nil {
 				fmt.Printf("Error: failed to get current directory: %v\n", err)
-				os.Exit(1)
+				exit(1)
 			}
 		}
 	}
@@ -804,11 +863,11 @@ func handleAdd(profile string, args []string) {
 	info, err := os.Stat(path)
 	if err != nil {
 		fmt.Printf("Error: path does not exist: %s\n", path)
-		os.Exit(1)
+		exit(1)
 	}
 	if !info.IsDir() {
 		fmt.Printf("Error: path is not a directory: %s\n", path)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Handle worktree creation
@@ -817,20 +876,20 @@ func handleAdd(profile string, args []string) {
 		// Validate path is a git repo
 		if !git.IsGitRepo(path) {
 			fmt.Fprintf(os.Stderr, "Error: %s is not a git repository\n", path)
-			os.Exit(1)
+			exit(1)
 		}
 
 		// Get repo root (resolve through worktrees to prevent nesting)
 		repoRoot, err := git.GetWorktreeBaseRoot(path)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: failed to get repo root: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 
 		// Pre-validate branch name for better error messages
 		if err := git.ValidateBranchName(wtBranch); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: invalid branch name: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 
 		// Check -b flag logic: if -b is passed, branch must NOT exist (user wants new branch)
@@ -841,7 +900,7 @@ func handleAdd(profile string, args []string) {
 				"Error: branch '%s' already exists (remove -b flag to use existing branch)\n",
 				wtBranch,
 			)
-			os.Exit(1)
+			exit(1)
 		}
 
 		// Determine worktree location: CLI flag overrides config
@@ -863,20 +922,20 @@ func handleAdd(profile string, args []string) {
 		// Ensure parent directory exists (needed for subdirectory mode)
 		if err := os.MkdirAll(filepath.Dir(worktreePath), 0o755); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: failed to create parent directory: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 
 		// Check if worktree already exists
 		if _, err := os.Stat(worktreePath); err == nil {
 			fmt.Fprintf(os.Stderr, "Error: worktree already exists at %s\n", worktreePath)
 			fmt.Fprintf(os.Stderr, "Tip: Use 'agent-deck add %s' to add the existing worktree\n", worktreePath)
-			os.Exit(1)
+			exit(1)
 		}
 
 		// Create worktree
-		if err := git.CreateWorktree(repoRoot, worktreePath, wtBranch); err != nil {
+		if err := session.CreateWorktree(repoRoot, worktreePath, wtBranch, worktreeProgress(*jsonOutput)); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: failed to create worktree: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 
 		fmt.Printf("Created worktree at: %s\n", worktreePath)
@@ -904,7 +963,7 @@ func handleAdd(profile string, args []string) {
 		// User provided explicit title - check for exact duplicate (same title AND path)
 		if isDupe, existingInst := isDuplicateSession(instances, sessionTitle, path); isDupe {
 			fmt.Printf("Session already exists with same title and path: %s (%s)\n", existingInst.Title, existingInst.ID)
-			os.Exit(0)
+			exit(0)
 		}
 	}
 
@@ -973,8 +1032,9 @@ func handleAdd(profile string, args []string) {
 
 	if err := storage.SaveWithGroups(instances, groupTree); err != nil {
 		fmt.Printf("Error: failed to save session: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
+	session.FireSessionHook(session.HookSessionCreated, newInstance, nil)
 
 	// Attach MCPs if specified
 	if len(mcpFlags) > 0 {
@@ -987,14 +1047,14 @@ func handleAdd(profile string, args []string) {
 				for name := range availableMCPs {
 					fmt.Printf("  • %s\n", name)
 				}
-				os.Exit(1)
+				exit(1)
 			}
 		}
 
 		// Write MCPs to .mcp.json
 		if err := session.WriteMCPJsonFromConfig(path, mcpFlags); err != nil {
 			fmt.Printf("Error: failed to write MCPs: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -1087,7 +1147,7 @@ func handleList(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	if *allProfiles {
@@ -1098,13 +1158,13 @@ func handleList(profile string, args []string) {
 	storage, err := session.NewStorageWithProfile(profile)
 	if err != nil {
 		fmt.Printf("Error: failed to initialize storage: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	instances, _, err := storage.LoadWithGroups()
 	if err != nil {
 		fmt.Printf("Error: failed to load sessions: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if len(instances) == 0 {
@@ -1143,7 +1203,7 @@ func handleList(profile string, args []string) {
 		output, err := json.MarshalIndent(sessions, "", "  ")
 		if err != nil {
 			fmt.Printf("Error: failed to format JSON output: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println(string(output))
 		return
@@ -1175,7 +1235,7 @@ func handleListAllProfiles(jsonOutput bool) {
 	profiles, err := session.ListProfiles()
 	if err != nil {
 		fmt.Printf("Error: failed to list profiles: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if len(profiles) == 0 {
@@ -1222,7 +1282,7 @@ func handleListAllProfiles(jsonOutput bool) {
 		output, err := json.MarshalIndent(allSessions, "", "  ")
 		if err != nil {
 			fmt.Printf("Error: failed to format JSON output: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println(string(output))
 		return
@@ -1285,7 +1345,7 @@ func handleRemove(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	quietMode := *quiet || *quietShort
@@ -1297,13 +1357,13 @@ func handleRemove(profile string, args []string) {
 		if !*jsonOutput {
 			fs.Usage()
 		}
-		os.Exit(1)
+		exit(1)
 	}
 
 	storage, instances, groups, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(err.Error(), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Use shared ResolveSession for consistent matching (ambiguity detection, min prefix length)
@@ -1311,9 +1371,9 @@ func handleRemove(profile string, args []string) {
 	if inst == nil {
 		out.Error(fmt.Sprintf("%s (profile '%s')", errMsg, storage.Profile()), errCode)
 		if errCode == ErrCodeNotFound {
-			os.Exit(2)
+			exit(2)
 		}
-		os.Exit(1)
+		exit(1)
 	}
 
 	removedID := inst.ID
@@ -1339,6 +1399,14 @@ func handleRemove(profile string, args []string) {
 		}
 		_ = git.PruneWorktrees(inst.WorktreeRepoRoot)
 	}
+	if inst.Workspace != nil {
+		for _, repo := range inst.Workspace.OtherWorktrees() {
+			if err := git.RemoveWorktree(repo.RepoRoot, repo.WorktreePath, false); err != nil && !*jsonOutput {
+				fmt.Printf("Warning: failed to remove worktree %s: %v\n", repo.WorktreePath, err)
+			}
+			_ = git.PruneWorktrees(repo.RepoRoot)
+		}
+	}
 
 	// Direct SQL DELETE first to prevent resurrection by concurrent TUI force saves.
 	// The TUI's forceSaveInstances() can race with CLI deletion and re-insert the session.
@@ -1360,8 +1428,9 @@ func handleRemove(profile string, args []string) {
 
 	if err := storage.SaveWithGroups(newInstances, groupTree); err != nil {
 		out.Error(fmt.Sprintf("failed to save: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
+	session.FireSessionHook(session.HookSessionDeleted, inst, nil)
 
 	out.Success(
 		fmt.Sprintf("Removed session: %s (from profile '%s')", removedTitle, storage.Profile()),
@@ -1393,7 +1462,7 @@ func handleRename(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	quietMode := *quiet || *quietShort
@@ -1406,22 +1475,22 @@ func handleRename(profile string, args []string) {
 		if !*jsonOutput {
 			fs.Usage()
 		}
-		os.Exit(1)
+		exit(1)
 	}
 
 	storage, instances, groups, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(err.Error(), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	inst, errMsg, errCode := ResolveSession(identifier, instances)
 	if inst == nil {
 		out.Error(fmt.Sprintf("%s (profile '%s')", errMsg, storage.Profile()), errCode)
 		if errCode == ErrCodeNotFound {
-			os.Exit(2)
+			exit(2)
 		}
-		os.Exit(1)
+		exit(1)
 	}
 
 	oldTitle := inst.Title
@@ -1433,7 +1502,7 @@ func handleRename(profile string, args []string) {
 				fmt.Sprintf("session with title %q already exists at path %q (id: %s)", newTitle, inst.ProjectPath, existing.ID),
 				ErrCodeInvalidOperation,
 			)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -1443,7 +1512,7 @@ func handleRename(profile string, args []string) {
 	groupTree := session.NewGroupTreeWithGroups(instances, groups)
 	if err := storage.SaveWithGroups(instances, groupTree); err != nil {
 		out.Error(fmt.Sprintf("failed to save: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	out.Success(
@@ -1512,20 +1581,20 @@ func handleStatus(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Load sessions
 	storage, err := session.NewStorageWithProfile(profile)
 	if err != nil {
 		fmt.Printf("Error: failed to initialize storage: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	instances, _, err := storage.LoadWithGroups()
 	if err != nil {
 		fmt.Printf("Error: failed to load sessions: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if len(instances) == 0 {
@@ -1603,8 +1672,23 @@ func handleStatus(profile string, args []string) {
 	}
 }
 
-// handleProfile manages profiles (list, create, delete, default)
-func handleProfile(args []string) {
+// handleProfile manages profiles (list, create, delete, default, backup, restore, dump)
+func handleProfile(profile string, args []string) {
+	// Snapshot commands parse their own flags and act on the -p profile
+	if len(args) > 0 {
+		switch args[0] {
+		case "backup":
+			handleProfileBackup(profile, args[1:])
+			return
+		case "restore":
+			handleProfileRestore(profile, args[1:])
+			return
+		case "dump":
+			handleProfileDump(profile, args[1:])
+			return
+		}
+	}
+
 	// Extract --json and -q/--quiet flags from anywhere in args
 	var jsonMode, quietMode bool
 	var filteredArgs []string
@@ -1644,7 +1728,7 @@ func handleProfile(args []string) {
 			if !jsonMode {
 				printProfileCreateHelp()
 			}
-			os.Exit(1)
+			exit(1)
 		}
 		handleProfileCreate(out, filteredArgs[1])
 	case "delete", "rm":
@@ -1657,7 +1741,7 @@ func handleProfile(args []string) {
 			if !jsonMode {
 				printProfileDeleteHelp()
 			}
-			os.Exit(1)
+			exit(1)
 		}
 		handleProfileDelete(out, jsonMode, filteredArgs[1])
 	case "default":
@@ -1670,7 +1754,7 @@ func handleProfile(args []string) {
 			config, err := session.LoadConfig()
 			if err != nil {
 				out.Error(fmt.Sprintf("failed to load config: %v", err), ErrCodeInvalidOperation)
-				os.Exit(1)
+				exit(1)
 			}
 			out.Success(fmt.Sprintf("Default profile: %s", config.DefaultProfile), map[string]interface{}{
 				"success":         true,
@@ -1685,7 +1769,7 @@ func handleProfile(args []string) {
 			fmt.Println()
 			printProfileHelp()
 		}
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -1699,6 +1783,11 @@ func printProfileHelp() {
 	fmt.Println("  create <name>     Create a new profile")
 	fmt.Println("  delete <name>     Delete a profile")
 	fmt.Println("  default [name]    Show or set default profile")
+	fmt.Println("  backup            Snapshot the profile's state.db (safe while the TUI runs)")
+	fmt.Println("  restore <file>    Restore a snapshot (or 'latest')")
+	fmt.Println("  dump              Print sessions, groups and metadata as JSON")
+	fmt.Println()
+	fmt.Println("backup, restore and dump act on the -p profile (default profile if omitted).")
 }
 
 func printProfileCreateHelp() {
@@ -1721,7 +1810,7 @@ func handleProfileList(out *CLIOutput, jsonMode bool) {
 	profiles, err := session.ListProfiles()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to list profiles: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	config, _ := session.LoadConfig()
@@ -1767,7 +1856,7 @@ func handleProfileList(out *CLIOutput, jsonMode bool) {
 func handleProfileCreate(out *CLIOutput, name string) {
 	if err := session.CreateProfile(name); err != nil {
 		out.Error(fmt.Sprintf("%v", err), ErrCodeAlreadyExists)
-		os.Exit(1)
+		exit(1)
 	}
 	out.Success(fmt.Sprintf("Created profile: %s", name), map[string]interface{}{
 		"success": true,
@@ -1793,7 +1882,7 @@ func handleProfileDelete(out *CLIOutput, jsonMode bool, name string) {
 
 	if err := session.DeleteProfile(name); err != nil {
 		out.Error(fmt.Sprintf("%v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 	out.Success(fmt.Sprintf("Deleted profile: %s", name), map[string]interface{}{
 		"success": true,
@@ -1805,7 +1894,7 @@ func handleProfileDelete(out *CLIOutput, jsonMode bool, name string) {
 func handleProfileSetDefault(out *CLIOutput, name string) {
 	if err := session.SetDefaultProfile(name); err != nil {
 		out.Error(fmt.Sprintf("%v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 	out.Success(fmt.Sprintf("Default profile set to: %s", name), map[string]interface{}{
 		"success":         true,
@@ -1833,7 +1922,7 @@ func handleUpdate(args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Printf("Agent Deck v%s\n", Version)
@@ -1844,7 +1933,7 @@ func handleUpdate(args []string) {
 	info, err := update.CheckForUpdate(Version, true)
 	if err != nil {
 		fmt.Printf("Error checking for updates: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if !info.Available {
@@ -1878,7 +1967,7 @@ func handleUpdate(args []string) {
 	fmt.Println()
 	if err := update.PerformUpdate(info.DownloadURL); err != nil {
 		fmt.Printf("Error installing update: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Update bridge.py if conductor is installed
@@ -1953,13 +2042,19 @@ func printHelp() {
 	fmt.Println("  remove, rm       Remove a session")
 	fmt.Println("  rename, mv       Rename a session")
 	fmt.Println("  status           Show session status summary")
+	fmt.Println("  search <query>   Search conversation history (full-text index)")
+	fmt.Println("  report           Usage and cost report across sessions")
+	fmt.Println("  import           Import existing tool conversations as sessions")
+	fmt.Println("  models           Show model context limits and pricing")
 	fmt.Println("  session          Manage session lifecycle")
 	fmt.Println("  mcp              Manage MCP servers")
 	fmt.Println("  skill            Manage Claude skills")
+	fmt.Println("  hooks            Manage Claude Code hooks, test session lifecycle hooks")
 	fmt.Println("  codex-hooks      Manage Codex notify hook integration")
 	fmt.Println("  group            Manage groups")
 	fmt.Println("  worktree, wt     Manage git worktrees")
-	fmt.Println("  web              Start TUI with web UI server running alongside")
+	fmt.Println("  workspace, ws    Multi-repo workspaces (list, finish)")
+	fmt.Println("  web              Start TUI with web UI server running alongside, manage web tokens/certs")
 	fmt.Println("  conductor        Manage conductor meta-agent orchestration")
 	fmt.Println("  profile          Manage profiles")
 	fmt.Println("  update           Check for and install updates")
@@ -1988,6 +2083,12 @@ func printHelp() {
 	fmt.Println("  skill detach <id> <name>  Detach skill from session project")
 	fmt.Println("  skill source list         List global skill sources")
 	fmt.Println()
+	fmt.Println("Hook Commands:")
+	fmt.Println("  hooks install             Install Claude Code status hooks")
+	fmt.Println("  hooks uninstall           Remove Claude Code status hooks")
+	fmt.Println("  hooks status              Show Claude Code hook install status")
+	fmt.Println("  hooks test <event> [id]   Run the [session_hooks] commands for an event")
+	fmt.Println()
 	fmt.Println("Codex Hook Commands:")
 	fmt.Println("  codex-hooks install       Install or upgrade Codex notify hook")
 	fmt.Println("  codex-hooks uninstall     Remove Codex notify hook")
@@ -2010,6 +2111,10 @@ func printHelp() {
 	fmt.Println("  worktree info <session>   Show worktree info for a session")
 	fmt.Println("  worktree cleanup          Find and remove orphaned worktrees/sessions")
 	fmt.Println()
+	fmt.Println("Workspace Commands:")
+	fmt.Println("  workspace list            List workspaces from config.toml")
+	fmt.Println("  workspace finish <id>     Merge or open PRs in every repo of a session")
+	fmt.Println()
 	fmt.Println("Profile Commands:")
 	fmt.Println("  profile list              List all profiles")
 	fmt.Println("  profile create <name>     Create a new profile")
@@ -2031,6 +2136,8 @@ func printHelp() {
 	fmt.Println("  agent-deck web --listen :9000         # TUI + web on custom port")
 	fmt.Println("  agent-deck web --read-only            # TUI + web in read-only mode")
 	fmt.Println("  agent-deck web --token secret         # TUI + web with auth token")
+	fmt.Println("  agent-deck web token create <name>    # Create a scoped web access token")
+	fmt.Println("  agent-deck web --tls                  # TUI + web over HTTPS (per-profile CA)")
 	fmt.Println("  agent-deck web --help                 # Show web command flags")
 	fmt.Println()
 	fmt.Println("Environment Variables:")
@@ -2123,7 +2230,7 @@ func handleUninstall(args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Println("╔════════════════════════════════════════╗")
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+// handleModels dispatches model table subcommands
+func handleModels(args []string) {
+	if len(args) == 0 {
+		handleModelsList(nil)
+		return
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		handleModelsList(args[1:])
+	case "help", "--help", "-h":
+		printModelsHelp()
+	default:
+		fmt.Printf("Unknown models command: %s\n", args[0])
+		fmt.Println()
+		printModelsHelp()
+		exit(1)
+	}
+}
+
+// printModelsHelp prints usage for models commands
+func printModelsHelp() {
+	fmt.Println("Usage: agent-deck models <command> [options]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  list [model]      Show the effective model table (context limits and pricing)")
+	fmt.Println()
+	fmt.Println("The built-in table can be overridden in ~/.agent-deck/models.toml or in")
+	fmt.Println("[models] in config.toml (config.toml wins).")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  agent-deck models list")
+	fmt.Println("  agent-deck models list claude-sonnet-4-5-20250929")
+	fmt.Println("  agent-deck models list --json")
+}
+
+// modelsListJSON is the JSON output of models list
+type modelsListJSON struct {
+	Version   string                    `json:"version"`
+	Model     string                    `json:"model,omitempty"`
+	Effective *session.ModelDef         `json:"effective,omitempty"`
+	Models    []session.ModelTableEntry `json:"models"`
+}
+
+// handleModelsList prints the effective model table. With a model name, only
+// the entries matching it are listed, followed by the merged result.
+func handleModelsList(args []string) {
+	fs := flag.NewFlagSet("models list", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck models list [model] [options]")
+		fmt.Println()
+		fmt.Println("Show context limits and prices (USD per million tokens) from the built-in")
+		fmt.Println("table, ~/.agent-deck/models.toml and [models] in config.toml. With a model")
+		fmt.Println("name, show the entries that match it and the effective result.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+	model := fs.Arg(0)
+
+	version, entries := session.ModelTable()
+	if model != "" {
+		var matched []session.ModelTableEntry
+		for _, e := range entries {
+			if e.Matches(model) {
+				matched = append(matched, e)
+			}
+		}
+		entries = matched
+	}
+
+	if *jsonOutput {
+		result := modelsListJSON{Version: version, Model: model, Models: entries}
+		if model != "" {
+			def := session.LookupModel(model)
+			result.Effective = &def
+		}
+		if result.Models == nil {
+			result.Models = []session.ModelTableEntry{}
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to format JSON output: %v\n", err)
+			exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Printf("Model table version %s (prices in USD per million tokens)\n\n", version)
+	writeModelTable(os.Stdout, entries)
+	if model != "" {
+		def := session.LookupModel(model)
+		fmt.Printf("\nEffective for %s:\n", model)
+		writeModelTable(os.Stdout, []session.ModelTableEntry{{Key: model, ModelDef: def}})
+	}
+}
+
+// formatModelPrice formats a per-million price, "-" when unset
+func formatModelPrice(p float64) string {
+	if p == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("$%g", p)
+}
+
+// formatModelTokens formats a context size compactly (200k, 1.05M)
+func formatModelTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.3gM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.4gk", float64(n)/1_000)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+// formatLongContext summarizes a long-context tier as ">200k: $6/$22.5"
+func formatLongContext(lc *session.LongContextPricing) string {
+	if lc == nil || lc.Threshold == 0 {
+		return "-"
+	}
+	return fmt.Sprintf(">%s: %s/%s", formatModelTokens(lc.Threshold), formatModelPrice(lc.Input), formatModelPrice(lc.Output))
+}
+
+func writeModelTable(w io.Writer, entries []session.ModelTableEntry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No matching models.")
+		return
+	}
+	header := fmt.Sprintf("%-28s %9s %7s %7s %7s %7s %7s  %-18s %s",
+		"MODEL", "CONTEXT", "INPUT", "OUTPUT", "CACHE R", "CACHE W", "W 1H", "LONG CONTEXT", "SOURCE")
+	fmt.Fprintln(w, header)
+	fmt.Fprintln(w, strings.Repeat("-", len(header)))
+	for _, e := range entries {
+		source := e.Source
+		if e.PricingFrom != "" {
+			source += " (price: " + e.PricingFrom + ")"
+		}
+		cw1h := e.CacheWrite1h
+		if cw1h == 0 {
+			cw1h = e.CacheWrite
+		}
+		fmt.Fprintf(w, "%-28s %9s %7s %7s %7s %7s %7s  %-18s %s\n",
+			truncate(e.Key, 28), formatModelTokens(e.ContextLimit),
+			formatModelPrice(e.Input), formatModelPrice(e.Output),
+			formatModelPrice(e.CacheRead), formatModelPrice(e.CacheWrite), formatModelPrice(cw1h),
+			formatLongContext(e.LongContext), source)
+	}
+}
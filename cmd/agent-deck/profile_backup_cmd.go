@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+// profileName resolves the profile a backup command operates on.
+func profileName(profile string) string {
+	if profile != "" {
+		return profile
+	}
+	if config, err := session.LoadConfig(); err == nil && config.DefaultProfile != "" {
+		return config.DefaultProfile
+	}
+	return session.DefaultProfile
+}
+
+// handleProfileBackup snapshots a profile's state.db, or lists snapshots.
+func handleProfileBackup(profile string, args []string) {
+	fs := flag.NewFlagSet("profile backup", flag.ExitOnError)
+	output := fs.String("output", "", "Snapshot file to write (default: profiles/<name>/backups/state-<time>.db)")
+	outputShort := fs.String("o", "", "Snapshot file to write (short)")
+	list := fs.Bool("list", false, "List existing snapshots instead of taking one")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	quiet := fs.Bool("quiet", false, "Minimal output")
+	quietShort := fs.Bool("q", false, "Minimal output (short)")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck [-p profile] profile backup [-o file.db] [--list]")
+		fmt.Println()
+		fmt.Println("Take a consistent snapshot of the profile's state.db. Uses SQLite's online")
+		fmt.Println("backup API, so it is safe while the TUI is running.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+	out := NewCLIOutput(*jsonOutput, *quiet || *quietShort)
+	name := profileName(profile)
+
+	if *list {
+		backups, err := session.ListProfileBackups(name)
+		if err != nil {
+			out.Error(fmt.Sprintf("failed to list backups: %v", err), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		if backups == nil {
+			backups = []session.ProfileBackup{}
+		}
+		var b strings.Builder
+		if len(backups) == 0 {
+			fmt.Fprintf(&b, "No backups for profile %s\n", name)
+		}
+		for _, bk := range backups {
+			fmt.Fprintf(&b, "%s  %8s  %s\n", bk.CreatedAt.Format("2006-01-02 15:04:05"), formatSize(bk.Size), bk.Path)
+		}
+		out.Print(b.String(), map[string]interface{}{"profile": name, "backups": backups})
+		return
+	}
+
+	path, err := session.BackupProfile(name, firstNonEmpty(*output, *outputShort))
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to back up profile %s: %v", name, err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	out.Success(fmt.Sprintf("Backed up profile %s to %s", name, path), map[string]interface{}{
+		"success": true,
+		"profile": name,
+		"path":    path,
+	})
+}
+
+// handleProfileRestore replaces a profile's state with a snapshot.
+func handleProfileRestore(profile string, args []string) {
+	fs := flag.NewFlagSet("profile restore", flag.ExitOnError)
+	force := fs.Bool("force", false, "Restore even while a TUI has the profile open")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	quiet := fs.Bool("quiet", false, "Minimal output")
+	quietShort := fs.Bool("q", false, "Minimal output (short)")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck [-p profile] profile restore <file.db|latest> [--force]")
+		fmt.Println()
+		fmt.Println("Replace the profile's sessions, groups and metadata with a snapshot taken by")
+		fmt.Println("'profile backup'. Snapshots from a newer agent-deck are refused. The current")
+		fmt.Println("state is saved to the backups dir first.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		exit(1)
+	}
+	out := NewCLIOutput(*jsonOutput, *quiet || *quietShort)
+	name := profileName(profile)
+
+	src := fs.Arg(0)
+	if src == "latest" {
+		backups, err := session.ListProfileBackups(name)
+		if err != nil || len(backups) == 0 {
+			out.Error(fmt.Sprintf("no backups found for profile %s", name), ErrCodeNotFound)
+			exit(1)
+		}
+		src = backups[0].Path
+	} else if abs, err := filepath.Abs(session.ExpandPath(src)); err == nil {
+		src = abs
+	}
+
+	if !*force {
+		if n, err := session.RunningProfileInstances(name); err == nil && n > 0 {
+			out.Error(fmt.Sprintf("profile %s is open in %d agent-deck TUI(s); quit them or use --force (they reload the restored state)", name, n), ErrCodeInvalidOperation)
+			exit(1)
+		}
+	}
+
+	safety, err := session.RestoreProfile(name, src)
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to restore profile %s: %v", name, err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	msg := fmt.Sprintf("Restored profile %s from %s", name, src)
+	if safety != "" {
+		msg += fmt.Sprintf(" (previous state saved to %s)", safety)
+	}
+	out.Success(msg, map[string]interface{}{
+		"success":       true,
+		"profile":       name,
+		"source":        src,
+		"safety_backup": safety,
+	})
+}
+
+// handleProfileDump prints a profile's state (or a snapshot) as JSON.
+func handleProfileDump(profile string, args []string) {
+	fs := flag.NewFlagSet("profile dump", flag.ExitOnError)
+	from := fs.String("from", "", "Dump a snapshot file instead of the live state.db")
+	output := fs.String("output", "", "Write the dump to a file instead of stdout")
+	outputShort := fs.String("o", "", "Write the dump to a file (short)")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck [-p profile] profile dump [--from file.db] [-o dump.json]")
+		fmt.Println()
+		fmt.Println("Print the profile's sessions, groups and metadata as indented JSON. Dump two")
+		fmt.Println("snapshots and diff them to see what changed.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+	out := NewCLIOutput(false, false)
+
+	path := *from
+	if path == "" && fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	if path == "" {
+		dbPath, err := session.GetDBPathForProfile(profileName(profile))
+		if err != nil {
+			out.Error(err.Error(), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		path = dbPath
+	} else {
+		path = session.ExpandPath(path)
+	}
+
+	dump, err := session.DumpStateDB(path)
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to dump %s: %v", path, err), ErrCodeNotFound)
+		exit(1)
+	}
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to encode dump: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	data = append(data, '\n')
+
+	if dst := firstNonEmpty(*output, *outputShort); dst != "" {
+		if err := os.WriteFile(dst, data, 0600); err != nil {
+			out.Error(fmt.Sprintf("failed to write dump: %v", err), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d sessions and %d groups to %s\n", len(dump.Instances), len(dump.Groups), dst)
+		return
+	}
+	_, _ = os.Stdout.Write(data)
+}
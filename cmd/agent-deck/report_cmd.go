@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/searchdb"
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+// handleReport prints token, cost and time usage aggregated across sessions
+func handleReport(profile string, args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	since := fs.String("since", "", "Only usage on/after this date (YYYY-MM-DD or age like 7d)")
+	until := fs.String("until", "", "Only usage before this date (YYYY-MM-DD or age like 7d)")
+	by := fs.String("by", session.UsageBySession, "Group by: session, group, tool, model, day")
+	format := fs.String("format", "table", "Output format: table, json, csv, markdown")
+	jsonOutput := fs.Bool("json", false, "Output as JSON (same as --format json)")
+	refresh := fs.Bool("refresh", false, "Re-parse all transcripts instead of using cached usage")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck report [options]")
+		fmt.Println()
+		fmt.Println("Report tokens, estimated cost, turns, tool calls, active and waiting time")
+		fmt.Println("from session transcripts (Claude, Codex, Gemini, OpenCode). Covers all")
+		fmt.Println("profiles unless -p is given. Usage is cached per session in the profile's")
+		fmt.Println("state.db and re-parsed only when a transcript changes.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  agent-deck report --since 7d --by group")
+		fmt.Println("  agent-deck report --since 2025-01-01 --until 2025-02-01 --by day --format csv")
+		fmt.Println("  agent-deck -p work report --by model --format markdown")
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+	if *jsonOutput {
+		*format = "json"
+	}
+	out := NewCLIOutput(*format == "json", false)
+
+	switch *format {
+	case "table", "json", "csv", "markdown", "md":
+	default:
+		out.Error(fmt.Sprintf("invalid format %q (expected table, json, csv or markdown)", *format), ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	var sinceTime, untilTime time.Time
+	var err error
+	if *since != "" {
+		if sinceTime, err = searchdb.ParseDate(*since); err != nil {
+			out.Error(err.Error(), ErrCodeInvalidOperation)
+			exit(1)
+		}
+	}
+	if *until != "" {
+		if untilTime, err = searchdb.ParseDate(*until); err != nil {
+			out.Error(err.Error(), ErrCodeInvalidOperation)
+			exit(1)
+		}
+	}
+
+	profiles := []string{session.GetEffectiveProfile(profile)}
+	if profile == "" {
+		if all, err := session.ListProfiles(); err == nil && len(all) > 0 {
+			profiles = all
+		}
+	}
+
+	sessions := collectReportSessions(profiles, *refresh)
+	report, err := session.BuildUsageReport(sessions, *by, sinceTime, untilTime)
+	if err != nil {
+		out.Error(err.Error(), ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	switch *format {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			out.Error(fmt.Sprintf("failed to format JSON output: %v", err), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		fmt.Println(string(data))
+	case "csv":
+		if err := writeReportCSV(os.Stdout, report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+	case "markdown", "md":
+		writeReportMarkdown(os.Stdout, report, len(profiles) > 1)
+	default:
+		writeReportTable(os.Stdout, report, len(profiles) > 1)
+	}
+}
+
+// collectReportSessions loads every session of the given profiles with its
+// usage, using and refreshing each profile's usage cache.
+func collectReportSessions(profiles []string, refresh bool) []session.UsageReportSession {
+	var sessions []session.UsageReportSession
+	for _, p := range profiles {
+		storage, err := session.NewStorageWithProfile(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: profile %s: %v\n", p, err)
+			continue
+		}
+		instances, _, err := storage.LoadWithGroups()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: profile %s: failed to load sessions: %v\n", p, err)
+			storage.Close()
+			continue
+		}
+		db := storage.GetDB()
+		if db != nil {
+			if err := session.ResetStaleUsageCache(db); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: profile %s: usage cache: %v\n", p, err)
+			}
+		}
+		ids := make([]string, 0, len(instances))
+		for _, inst := range instances {
+			ids = append(ids, inst.ID)
+			rows, err := session.SessionUsage(db, inst, refresh)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", inst.Title, err)
+				continue
+			}
+			if len(rows) > 0 {
+				sessions = append(sessions, session.UsageReportSession{Profile: storage.Profile(), Instance: inst, Rows: rows})
+			}
+		}
+		if db != nil {
+			_ = db.PruneUsage(ids)
+		}
+		storage.Close()
+	}
+	return sessions
+}
+
+// reportLabel returns the display name of a report row
+func reportLabel(r session.UsageReportRow, showProfile bool) string {
+	label := r.Key
+	if label == "" {
+		label = "(unknown)"
+	}
+	if showProfile && r.Profile != "" {
+		label = r.Profile + ":" + label
+	}
+	return label
+}
+
+// formatReportTokens abbreviates token counts (12.3k, 4.56M)
+func formatReportTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.2fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	}
+	return strconv.Itoa(n)
+}
+
+// formatReportDuration formats seconds as "2h 05m", "12m" or "40s"
+func formatReportDuration(seconds int64) string {
+	d := time.Duration(seconds) * time.Second
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
+
+// reportWindow describes the report's time window for headers
+func reportWindow(r *session.UsageReport) string {
+	from, to := "start", "now"
+	if r.Since != nil {
+		from = r.Since.Local().Format("2006-01-02")
+	}
+	if r.Until != nil {
+		to = r.Until.Local().Format("2006-01-02")
+	}
+	return from + " to " + to
+}
+
+const reportColKey = 36
+
+func writeReportTable(w io.Writer, r *session.UsageReport, showProfile bool) {
+	fmt.Fprintf(w, "Usage by %s, %s\n\n", r.By, reportWindow(r))
+	if len(r.Rows) == 0 {
+		fmt.Fprintln(w, "No usage found.")
+		return
+	}
+	header := fmt.Sprintf("%-*s %8s %9s %10s %6s %6s %8s %8s", reportColKey, strings.ToUpper(r.By),
+		"SESSIONS", "TOKENS", "COST", "TURNS", "TOOLS", "ACTIVE", "WAITING")
+	fmt.Fprintln(w, header)
+	fmt.Fprintln(w, strings.Repeat("-", len(header)))
+	line := func(label string, t session.UsageTotals) {
+		fmt.Fprintf(w, "%-*s %8d %9s %10s %6d %6d %8s %8s\n", reportColKey, truncate(label, reportColKey),
+			t.Sessions, formatReportTokens(t.TotalTokens), fmt.Sprintf("$%.2f", t.EstimatedCost),
+			t.Turns, t.ToolCalls, formatReportDuration(t.ActiveSeconds), formatReportDuration(t.WaitingSeconds))
+	}
+	for _, row := range r.Rows {
+		line(reportLabel(row, showProfile), row.UsageTotals)
+	}
+	fmt.Fprintln(w, strings.Repeat("-", len(header)))
+	line("TOTAL", r.Total)
+}
+
+func writeReportMarkdown(w io.Writer, r *session.UsageReport, showProfile bool) {
+	fmt.Fprintf(w, "## Usage by %s (%s)\n\n", r.By, reportWindow(r))
+	fmt.Fprintf(w, "| %s | Sessions | Tokens | Cost | Turns | Tool calls | Active | Waiting |\n", strings.ToUpper(r.By[:1])+r.By[1:])
+	fmt.Fprintln(w, "|---|---:|---:|---:|---:|---:|---:|---:|")
+	line := func(label string, t session.UsageTotals) {
+		fmt.Fprintf(w, "| %s | %d | %s | $%.2f | %d | %d | %s | %s |\n",
+			strings.ReplaceAll(label, "|", `\|`), t.Sessions, formatReportTokens(t.TotalTokens), t.EstimatedCost,
+			t.Turns, t.ToolCalls, formatReportDuration(t.ActiveSeconds), formatReportDuration(t.WaitingSeconds))
+	}
+	for _, row := range r.Rows {
+		line(reportLabel(row, showProfile), row.UsageTotals)
+	}
+	line("**Total**", r.Total)
+}
+
+func writeReportCSV(w io.Writer, r *session.UsageReport) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{r.By, "profile", "session_id", "sessions", "input_tokens", "output_tokens",
+		"cache_read_input_tokens", "cache_creation_input_tokens", "total_tokens", "estimated_cost",
+		"turns", "tool_calls", "active_seconds", "waiting_seconds"})
+	for _, row := range r.Rows {
+		_ = cw.Write([]string{
+			row.Key, row.Profile, row.SessionID,
+			strconv.Itoa(row.Sessions), strconv.Itoa(row.InputTokens), strconv.Itoa(row.OutputTokens),
+			strconv.Itoa(row.CacheReadTokens), strconv.Itoa(row.CacheWriteTokens), strconv.Itoa(row.TotalTokens),
+			strconv.FormatFloat(row.EstimatedCost, 'f', 4, 64),
+			strconv.Itoa(row.Turns), strconv.Itoa(row.ToolCalls),
+			strconv.FormatInt(row.ActiveSeconds, 10), strconv.FormatInt(row.WaitingSeconds, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+func testUsageReport() *session.UsageReport {
+	return &session.UsageReport{
+		By: session.UsageByGroup,
+		Rows: []session.UsageReportRow{
+			{Key: "work|api", Profile: "default", UsageTotals: session.UsageTotals{
+				Sessions: 2, InputTokens: 1000, OutputTokens: 500, TotalTokens: 1500,
+				EstimatedCost: 1.5, Turns: 4, ToolCalls: 3, ActiveSeconds: 3900, WaitingSeconds: 40,
+			}},
+		},
+		Total: session.UsageTotals{Sessions: 2, TotalTokens: 1500, EstimatedCost: 1.5, Turns: 4, ToolCalls: 3},
+	}
+}
+
+func TestWriteReportCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeReportCSV(&buf, testUsageReport()); err != nil {
+		t.Fatalf("writeReportCSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected header and one row, got %q", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "group,profile,session_id,sessions,") {
+		t.Errorf("header = %q", lines[0])
+	}
+	if want := "work|api,default,,2,1000,500,0,0,1500,1.5000,4,3,3900,40"; lines[1] != want {
+		t.Errorf("row = %q, want %q", lines[1], want)
+	}
+}
+
+func TestWriteReportMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	writeReportMarkdown(&buf, testUsageReport(), true)
+	out := buf.String()
+	for _, want := range []string{
+		"| Group | Sessions |",
+		`| default:work\|api | 2 | 1.5k | $1.50 | 4 | 3 | 1h 05m | 40s |`,
+		"| **Total** |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/asheshgoplani/agent-deck/internal/searchdb"
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+// handleSearch searches past conversations using the persistent search index
+func handleSearch(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	project := fs.String("project", "", "Only sessions whose project path contains this text")
+	role := fs.String("role", "", "Only messages from this role (user or assistant)")
+	tool := fs.String("tool", "", "Only transcripts from this tool (claude, codex, gemini, opencode)")
+	since := fs.String("since", "", "Only messages on/after this date (YYYY-MM-DD or age like 7d)")
+	until := fs.String("until", "", "Only messages before this date (YYYY-MM-DD or age like 7d)")
+	limit := fs.Int("limit", 20, "Maximum number of sessions to show")
+	noSync := fs.Bool("no-sync", false, "Search the index as-is without indexing new transcripts")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	quiet := fs.Bool("quiet", false, "Minimal output")
+	quietShort := fs.Bool("q", false, "Minimal output (short)")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck search <query> [options]")
+		fmt.Println()
+		fmt.Println("Search Claude, Codex, Gemini and OpenCode conversation history using the")
+		fmt.Println("persistent full-text index")
+		fmt.Println("(~/.agent-deck/search.db). New transcript content is indexed before searching.")
+		fmt.Println()
+		fmt.Println("Query syntax:")
+		fmt.Println("  foo bar              Messages containing both words")
+		fmt.Println("  \"exact phrase\"       Phrase match")
+		fmt.Println("  conn*                Prefix match")
+		fmt.Println("  a OR b, a NOT b      Boolean operators (uppercase), with ( ) grouping")
+		fmt.Println("  project:api role:user tool:codex since:7d until:2025-01-31   Inline filters")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  agent-deck search \"connection refused\"")
+		fmt.Println("  agent-deck search 'migrat* NOT sqlite' --project api --since 30d")
+		fmt.Println("  agent-deck search deploy role:user --json")
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+
+	out := NewCLIOutput(*jsonOutput, *quiet || *quietShort)
+
+	text := strings.Join(fs.Args(), " ")
+	q, err := searchdb.ParseQuery(text)
+	if err != nil {
+		out.Error(err.Error(), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	if *project != "" {
+		q.Project = *project
+	}
+	if *role != "" {
+		q.Role = strings.ToLower(*role)
+		if q.Role != "user" && q.Role != "assistant" {
+			out.Error(fmt.Sprintf("invalid role %q (expected user or assistant)", *role), ErrCodeInvalidOperation)
+			exit(1)
+		}
+	}
+	if *tool != "" {
+		q.Tool = strings.ToLower(*tool)
+	}
+	if *since != "" {
+		if q.Since, err = searchdb.ParseDate(*since); err != nil {
+			out.Error(err.Error(), ErrCodeInvalidOperation)
+			exit(1)
+		}
+	}
+	if *until != "" {
+		if q.Until, err = searchdb.ParseDate(*until); err != nil {
+			out.Error(err.Error(), ErrCodeInvalidOperation)
+			exit(1)
+		}
+	}
+	if strings.TrimSpace(q.Text) == "" {
+		out.Error("search query is required", ErrCodeInvalidOperation)
+		fs.Usage()
+		exit(1)
+	}
+
+	store, err := session.OpenSearchStore()
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to open search index: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	defer store.Close()
+
+	if !*noSync {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		var settings session.GlobalSearchSettings
+		if cfg, _ := session.LoadUserConfig(); cfg != nil {
+			settings = cfg.GlobalSearch
+		}
+		sources := session.NewTranscriptSources(session.GetClaudeConfigDir(), settings.Tools)
+		if _, err := session.SyncSearchStore(ctx, store, sources, settings.RecentDays, nil); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to update search index: %v\n", err)
+		}
+		stop()
+	}
+
+	results, err := session.SearchStore(store, q, *limit)
+	if err != nil {
+		out.Error(fmt.Sprintf("search failed: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	if results == nil {
+		results = []*session.StoreSearchResult{}
+	}
+
+	var b strings.Builder
+	if len(results) == 0 {
+		b.WriteString("No matches found.\n")
+	}
+	for _, r := range results {
+		date := "          "
+		if !r.Timestamp.IsZero() {
+			date = r.Timestamp.Local().Format("2006-01-02")
+		}
+		fmt.Fprintf(&b, "%s  %-8s  %-8s  %-30s  %3d hit(s)\n", date, r.Tool, TruncateID(r.SessionID), truncateSearchField(FormatPath(r.Project), 30), r.Hits)
+		snippet := session.StripSnippetMarkers(strings.Join(strings.Fields(r.Snippet), " "))
+		fmt.Fprintf(&b, "            %s: %s\n", r.Role, snippet)
+	}
+	out.Print(b.String(), results)
+}
+
+// truncateSearchField shortens s to max runes, keeping the tail (the most
+// specific part of a path).
+func truncateSearchField(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return "..." + string(runes[len(runes)-max+3:])
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+// handleSessionExport writes a session bundle (metadata, attachments and
+// transcript files) for moving a session to another machine.
+func handleSessionExport(profile string, args []string) {
+	fs := flag.NewFlagSet("session export", flag.ExitOnError)
+	output := fs.String("output", "", "Bundle file to write (default: <title>.agent-deck.tar.gz)")
+	outputShort := fs.String("o", "", "Bundle file to write (short)")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	quiet := fs.Bool("quiet", false, "Minimal output")
+	quietShort := fs.Bool("q", false, "Minimal output (short)")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck session export <id|title> [-o bundle.tar.gz]")
+		fmt.Println()
+		fmt.Println("Export a session with its tool transcript files, group, MCP and skill")
+		fmt.Println("attachments into a portable bundle. Secrets in MCP env/headers and inline")
+		fmt.Println("command env vars are replaced with " + session.RedactedValue + ".")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		exit(1)
+	}
+	out := NewCLIOutput(*jsonOutput, *quiet || *quietShort)
+
+	_, instances, groupsData, err := loadSessionData(profile)
+	if err != nil {
+		out.Error(err.Error(), ErrCodeNotFound)
+		exit(1)
+	}
+	inst, errMsg, errCode := ResolveSession(fs.Arg(0), instances)
+	if inst == nil {
+		out.Error(errMsg, errCode)
+		exit(2)
+		return // unreachable, satisfies staticcheck SA5011
+	}
+
+	path := *output
+	if path == "" {
+		path = *outputShort
+	}
+	if path == "" {
+		path = bundleFileName(inst.Title)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to create bundle: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	manifest, err := session.ExportSessionBundle(f, inst, groupsData)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		out.Error(fmt.Sprintf("failed to export session: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	if len(manifest.Files) == 0 && !*jsonOutput {
+		fmt.Fprintf(os.Stderr, "Warning: no %s transcript found; the bundle holds metadata only\n", inst.Tool)
+	}
+	msg := fmt.Sprintf("Exported session %s to %s (%d transcript files", inst.Title, path, len(manifest.Files))
+	if n := len(manifest.Redacted); n > 0 {
+		msg += fmt.Sprintf(", %d secrets redacted", n)
+	}
+	out.Success(msg+")", map[string]interface{}{
+		"success":  true,
+		"id":       inst.ID,
+		"title":    inst.Title,
+		"bundle":   path,
+		"files":    manifest.Files,
+		"redacted": manifest.Redacted,
+	})
+}
+
+// bundleFileName derives a default bundle name from a session title
+func bundleFileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' || r == ':' {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" {
+		name = "session"
+	}
+	return name + ".agent-deck.tar.gz"
+}
+
+// handleSessionImport restores a session bundle into the current profile
+func handleSessionImport(profile string, args []string) {
+	fs := flag.NewFlagSet("session import", flag.ExitOnError)
+	projectPath := fs.String("path", "", "Project directory on this machine (default: the exported path)")
+	group := fs.String("group", "", "Group for the session (default: the exported group)")
+	groupShort := fs.String("g", "", "Group for the session (short)")
+	title := fs.String("title", "", "Session title (default: the exported title)")
+	titleShort := fs.String("t", "", "Session title (short)")
+	force := fs.Bool("force", false, "Overwrite differing transcript files and allow importing a session twice")
+	dryRun := fs.Bool("dry-run", false, "Show the bundle contents without importing")
+	trustCommands := fs.Bool("trust-commands", false, "Keep the bundle's command, wrapper and tool options (default: run the tool's normal command)")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	quiet := fs.Bool("quiet", false, "Minimal output")
+	quietShort := fs.Bool("q", false, "Minimal output (short)")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck session import <bundle.tar.gz> [options]")
+		fmt.Println()
+		fmt.Println("Import a session bundle created by 'agent-deck session export'. Transcript")
+		fmt.Println("files are placed where the tool looks for them on this machine, with the")
+		fmt.Println("project path rewritten, so the session resumes the conversation on start.")
+		fmt.Println("The session runs the tool's normal command: the bundle's command and")
+		fmt.Println("wrapper run only with --trust-commands, so only use it for bundles you trust.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		exit(1)
+	}
+	out := NewCLIOutput(*jsonOutput, *quiet || *quietShort)
+	bundlePath := fs.Arg(0)
+
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to open bundle: %v", err), ErrCodeNotFound)
+		exit(1)
+	}
+	defer f.Close()
+
+	if *dryRun {
+		manifest, err := session.ReadSessionBundleManifest(f)
+		if err != nil {
+			out.Error(err.Error(), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "Session:  %s (%s)\n", manifest.Instance.Title, manifest.Instance.Tool)
+		fmt.Fprintf(&b, "Project:  %s\n", manifest.Instance.ProjectPath)
+		fmt.Fprintf(&b, "Group:    %s\n", manifest.Instance.GroupPath)
+		fmt.Fprintf(&b, "Exported: %s\n", manifest.ExportedAt.Local().Format("2006-01-02 15:04"))
+		if manifest.Instance.Command != "" {
+			fmt.Fprintf(&b, "Command:  %s\n", manifest.Instance.Command)
+		}
+		if manifest.Instance.Wrapper != "" {
+			fmt.Fprintf(&b, "Wrapper:  %s\n", manifest.Instance.Wrapper)
+		}
+		if len(manifest.Instance.LoadedMCPNames) > 0 {
+			fmt.Fprintf(&b, "MCPs:     %s\n", strings.Join(manifest.Instance.LoadedMCPNames, ", "))
+		}
+		if len(manifest.Skills) > 0 {
+			names := make([]string, len(manifest.Skills))
+			for i, s := range manifest.Skills {
+				names[i] = s.ID
+			}
+			fmt.Fprintf(&b, "Skills:   %s\n", strings.Join(names, ", "))
+		}
+		fmt.Fprintf(&b, "Files:    %d\n", len(manifest.Files))
+		for _, file := range manifest.Files {
+			fmt.Fprintf(&b, "  %s/%s (%d bytes)\n", file.Root, file.Path, file.Size)
+		}
+		out.Print(b.String(), manifest)
+		return
+	}
+
+	storage, instances, groupsData, err := loadSessionData(profile)
+	if err != nil {
+		out.Error(err.Error(), ErrCodeNotFound)
+		exit(1)
+	}
+
+	// Refuse a second copy of the same conversation unless forced
+	if !*force {
+		manifest, err := session.ReadSessionBundleManifest(f)
+		if err != nil {
+			out.Error(err.Error(), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		if existing := findImportedSession(instances, &manifest.Instance); existing != nil {
+			out.Error(fmt.Sprintf("session already imported as %q (%s); use --force to import another copy", existing.Title, existing.ID), ErrCodeAlreadyExists)
+			exit(1)
+		}
+		if _, err := f.Seek(0, 0); err != nil {
+			out.Error(err.Error(), ErrCodeInvalidOperation)
+			exit(1)
+		}
+	}
+
+	opts := session.SessionImportOptions{
+		ProjectPath:   *projectPath,
+		GroupPath:     firstNonEmpty(*group, *groupShort),
+		Title:         firstNonEmpty(*title, *titleShort),
+		Overwrite:     *force,
+		TrustCommands: *trustCommands,
+	}
+	if opts.ProjectPath != "" {
+		if abs, err := filepath.Abs(session.ExpandPath(opts.ProjectPath)); err == nil {
+			opts.ProjectPath = abs
+		}
+	}
+	result, err := session.ImportSessionBundle(f, opts)
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to import session: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	inst := result.Instance
+	for i := range result.Groups {
+		g := result.Groups[i]
+		if !hasGroupData(groupsData, g.Path) {
+			g.DefaultPath = ""
+			groupsData = append(groupsData, &g)
+		}
+	}
+	instances = append(instances, inst)
+	groupTree := session.NewGroupTreeWithGroups(instances, groupsData)
+	if err := storage.SaveWithGroups(instances, groupTree); err != nil {
+		out.Error(fmt.Sprintf("failed to save session state: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	if !*jsonOutput {
+		for _, w := range result.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+	}
+	out.Success(fmt.Sprintf("Imported session %s (%s) into %s with %d transcript files", inst.Title, inst.ID, inst.ProjectPath, len(result.Files)), map[string]interface{}{
+		"success":  true,
+		"id":       inst.ID,
+		"title":    inst.Title,
+		"path":     inst.ProjectPath,
+		"group":    inst.GroupPath,
+		"files":    result.Files,
+		"warnings": result.Warnings,
+	})
+}
+
+// findImportedSession returns an existing session continuing the same tool
+// conversation as an exported one.
+func findImportedSession(instances []*session.Instance, d *session.InstanceData) *session.Instance {
+	for _, inst := range instances {
+		switch {
+		case d.ClaudeSessionID != "" && inst.ClaudeSessionID == d.ClaudeSessionID,
+			d.GeminiSessionID != "" && inst.GeminiSessionID == d.GeminiSessionID,
+			d.CodexSessionID != "" && inst.CodexSessionID == d.CodexSessionID,
+			d.OpenCodeSessionID != "" && inst.OpenCodeSessionID == d.OpenCodeSessionID:
+			return inst
+		}
+	}
+	return nil
+}
+
+func hasGroupData(groups []*session.GroupData, path string) bool {
+	for _, g := range groups {
+		if g.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
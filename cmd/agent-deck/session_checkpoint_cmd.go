@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+// handleSessionCheckpoints lists, diffs, restores and manages a session's
+// per-turn git checkpoints (see [checkpoints] in config.toml).
+func handleSessionCheckpoints(profile string, args []string) {
+	fs := flag.NewFlagSet("session checkpoints", flag.ExitOnError)
+	stat := fs.Bool("stat", false, "diff: show a diffstat instead of the full diff")
+	message := fs.String("message", "", "create: checkpoint message")
+	messageShort := fs.String("m", "", "create: checkpoint message (short)")
+	keep := fs.Int("keep", -1, "prune: checkpoints to keep (default: [checkpoints] keep)")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	quiet := fs.Bool("quiet", false, "Minimal output")
+	quietShort := fs.Bool("q", false, "Minimal output (short)")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck session checkpoints <id|title> [command] [options]")
+		fmt.Println()
+		fmt.Println("Work tree snapshots taken at the end of each agent turn. Checkpoints are")
+		fmt.Println("stored as refs/agent-deck/checkpoints/<session>/<n>; the branch and index")
+		fmt.Println("are never touched.")
+		fmt.Println()
+		fmt.Println("Commands:")
+		fmt.Println("  (none)        List checkpoints")
+		fmt.Println("  diff <n>      Show changes since checkpoint n (--stat for a summary)")
+		fmt.Println("  restore <n>   Restore the work tree to checkpoint n (current state is checkpointed first)")
+		fmt.Println("  create        Take a checkpoint now (-m message)")
+		fmt.Println("  prune         Apply retention limits now (--keep to override)")
+		fmt.Println("  clear         Delete all of the session's checkpoints")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  agent-deck session checkpoints my-project")
+		fmt.Println("  agent-deck session checkpoints my-project diff 3 --stat")
+		fmt.Println("  agent-deck session checkpoints my-project restore 3")
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		exit(1)
+	}
+	out := NewCLIOutput(*jsonOutput, *quiet || *quietShort)
+
+	_, instances, _, err := loadSessionData(profile)
+	if err != nil {
+		out.Error(err.Error(), ErrCodeNotFound)
+		exit(1)
+	}
+	inst, errMsg, errCode := ResolveSession(fs.Arg(0), instances)
+	if inst == nil {
+		out.Error(errMsg, errCode)
+		exit(2)
+		return // unreachable, satisfies staticcheck SA5011
+	}
+	root, err := git.GetRepoRoot(inst.ReviewDir())
+	if err != nil {
+		out.Error(fmt.Sprintf("session %s is not in a git repository", inst.Title), ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	// checkpointArg parses the <n> argument of diff and restore
+	checkpointArg := func() int {
+		if fs.NArg() < 3 {
+			out.Error(fmt.Sprintf("%s requires a checkpoint number", fs.Arg(1)), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(fs.Arg(2), "#"))
+		if err != nil || n < 1 {
+			out.Error(fmt.Sprintf("invalid checkpoint number: %s", fs.Arg(2)), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		return n
+	}
+	// fail reports a checkpoint error, mapping unknown numbers to NOT_FOUND
+	fail := func(err error) {
+		code := ErrCodeInvalidOperation
+		if errors.Is(err, git.ErrCheckpointNotFound) {
+			code = ErrCodeNotFound
+		}
+		out.Error(err.Error(), code)
+		exit(1)
+	}
+
+	switch fs.Arg(1) {
+	case "", "list":
+		checkpoints, err := git.ListCheckpoints(root, inst.ID)
+		if err != nil {
+			fail(err)
+		}
+		if checkpoints == nil {
+			checkpoints = []git.Checkpoint{}
+		}
+		var b strings.Builder
+		if len(checkpoints) == 0 {
+			b.WriteString("No checkpoints")
+			if !session.GetCheckpointSettings().Enabled {
+				b.WriteString(" (enable [checkpoints] in config.toml to snapshot each turn)")
+			}
+			b.WriteString("\n")
+		}
+		for i := len(checkpoints) - 1; i >= 0; i-- {
+			cp := checkpoints[i]
+			fmt.Fprintf(&b, "#%-4d %-10s  %.7s  %s\n", cp.N, formatImportAge(cp.CreatedAt), cp.Commit, cp.Message)
+		}
+		out.Print(b.String(), map[string]interface{}{
+			"session_id":  inst.ID,
+			"title":       inst.Title,
+			"repo":        root,
+			"checkpoints": checkpoints,
+		})
+
+	case "diff":
+		n := checkpointArg()
+		diff, err := git.CheckpointDiff(root, inst.ID, n, *stat)
+		if err != nil {
+			fail(err)
+		}
+		out.Print(diff, map[string]interface{}{
+			"session_id": inst.ID,
+			"n":          n,
+			"diff":       diff,
+		})
+
+	case "restore":
+		n := checkpointArg()
+		safety, err := git.RestoreCheckpoint(root, inst.ID, n)
+		if err != nil {
+			fail(err)
+		}
+		msg := fmt.Sprintf("Restored %s to checkpoint #%d", inst.Title, n)
+		data := map[string]interface{}{"success": true, "session_id": inst.ID, "n": n}
+		if safety != nil {
+			msg += fmt.Sprintf(" (previous state saved as #%d)", safety.N)
+			data["saved_as"] = safety.N
+		}
+		out.Success(msg, data)
+
+	case "create":
+		msg := *message
+		if msg == "" {
+			msg = *messageShort
+		}
+		if msg == "" {
+			msg = "Manual checkpoint"
+		}
+		cp, err := git.CreateCheckpoint(root, inst.ID, msg)
+		if err != nil {
+			fail(err)
+		}
+		if cp == nil {
+			out.Success("Work tree unchanged since the last checkpoint", map[string]interface{}{
+				"success": true, "session_id": inst.ID, "created": false,
+			})
+			return
+		}
+		out.Success(fmt.Sprintf("Created checkpoint #%d", cp.N), map[string]interface{}{
+			"success": true, "session_id": inst.ID, "created": true, "checkpoint": cp,
+		})
+
+	case "prune":
+		settings := session.GetCheckpointSettings()
+		if *keep >= 0 {
+			settings.Keep = *keep
+		}
+		removed, err := git.PruneCheckpoints(root, inst.ID, settings.Keep, settings.MaxAge())
+		if err != nil {
+			fail(err)
+		}
+		out.Success(fmt.Sprintf("Pruned %d checkpoint(s)", removed), map[string]interface{}{
+			"success": true, "session_id": inst.ID, "removed": removed,
+		})
+
+	case "clear":
+		removed, err := git.DeleteCheckpoints(root, inst.ID)
+		if err != nil {
+			fail(err)
+		}
+		out.Success(fmt.Sprintf("Deleted %d checkpoint(s)", removed), map[string]interface{}{
+			"success": true, "session_id": inst.ID, "removed": removed,
+		})
+
+	default:
+		out.Error(fmt.Sprintf("unknown checkpoints command: %s", fs.Arg(1)), ErrCodeInvalidOperation)
+		exit(1)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/asheshgoplani/agent-deck/internal/git"
 	"github.com/asheshgoplani/agent-deck/internal/profile"
 	"github.com/asheshgoplani/agent-deck/internal/session"
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
 	"github.com/asheshgoplani/agent-deck/internal/tmux"
 )
 
@@ -21,7 +23,7 @@ import (
 func handleSession(profile string, args []string) {
 	if len(args) == 0 {
 		printSessionHelp()
-		os.Exit(1)
+		exit(1)
 	}
 
 	switch args[0] {
@@ -49,12 +51,20 @@ func handleSession(profile string, args []string) {
 		handleSessionSend(profile, args[1:])
 	case "output":
 		handleSessionOutput(profile, args[1:])
+	case "export":
+		handleSessionExport(profile, args[1:])
+	case "import":
+		handleSessionImport(profile, args[1:])
+	case "checkpoints":
+		handleSessionCheckpoints(profile, args[1:])
+	case "share":
+		handleSessionShare(profile, args[1:])
 	case "help", "--help", "-h":
 		printSessionHelp()
 	default:
 		fmt.Fprintf(os.Stderr, "Error: unknown session command: %s\n", args[0])
 		printSessionHelp()
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -77,6 +87,10 @@ func printSessionHelp() {
 	fmt.Println("  output <id>             Get the last response from a session")
 	fmt.Println("  set-parent <id> <parent>  Link session as sub-session of parent")
 	fmt.Println("  unset-parent <id>       Remove sub-session link")
+	fmt.Println("  export <id> [-o file]   Export session and transcripts to a portable bundle")
+	fmt.Println("  import <bundle>         Import a session bundle (--path to relocate)")
+	fmt.Println("  checkpoints <id>        List per-turn git checkpoints (diff <n>, restore <n>)")
+	fmt.Println("  share <id>              Create an expiring web link to the terminal (list, revoke)")
 	fmt.Println()
 	fmt.Println("Global Options:")
 	fmt.Println("  -p, --profile <name>   Use specific profile")
@@ -95,6 +109,10 @@ func printSessionHelp() {
 	fmt.Println("  agent-deck session unset-parent sub-task             # Remove sub-session link")
 	fmt.Println("  agent-deck session output my-project                 # Get last response from session")
 	fmt.Println("  agent-deck session output my-project --json          # Get response as JSON")
+	fmt.Println("  agent-deck session export my-project -o my-project.tar.gz")
+	fmt.Println("  agent-deck session import my-project.tar.gz --path ~/code/my-project")
+	fmt.Println("  agent-deck session checkpoints my-project restore 3")
+	fmt.Println("  agent-deck session share my-project --ttl 1h")
 	fmt.Println()
 	fmt.Println("Set command fields:")
 	fmt.Println("  title              Session title")
@@ -136,7 +154,7 @@ func handleSessionStart(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	identifier := fs.Arg(0)
@@ -150,7 +168,7 @@ func handleSessionStart(profile string, args []string) {
 	storage, instances, _, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(err.Error(), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Resolve session
@@ -158,28 +176,28 @@ func handleSessionStart(profile string, args []string) {
 	if inst == nil {
 		out.Error(errMsg, errCode)
 		if errCode == ErrCodeNotFound {
-			os.Exit(2)
+			exit(2)
 		}
-		os.Exit(1)
+		exit(1)
 		return // unreachable, satisfies staticcheck SA5011
 	}
 
 	// Check if already running
 	if inst.Exists() {
 		out.Error(fmt.Sprintf("session '%s' is already running", inst.Title), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Start the session (with or without initial message)
 	if initialMessage != "" {
 		if err := inst.StartWithMessage(initialMessage); err != nil {
 			out.Error(fmt.Sprintf("failed to start session: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	} else {
 		if err := inst.Start(); err != nil {
 			out.Error(fmt.Sprintf("failed to start session: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -190,7 +208,7 @@ func handleSessionStart(profile string, args []string) {
 	// Save updated state
 	if err := saveSessionData(storage, instances); err != nil {
 		out.Error(fmt.Sprintf("failed to save session state: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Output success
@@ -231,7 +249,7 @@ func handleSessionStop(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	identifier := fs.Arg(0)
@@ -242,7 +260,7 @@ func handleSessionStop(profile string, args []string) {
 	storage, instances, _, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(err.Error(), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Resolve session
@@ -250,28 +268,28 @@ func handleSessionStop(profile string, args []string) {
 	if inst == nil {
 		out.Error(errMsg, errCode)
 		if errCode == ErrCodeNotFound {
-			os.Exit(2)
+			exit(2)
 		}
-		os.Exit(1)
+		exit(1)
 		return // unreachable, satisfies staticcheck SA5011
 	}
 
 	// Check if not running
 	if !inst.Exists() {
 		out.Error(fmt.Sprintf("session '%s' is not running", inst.Title), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Stop the session by killing the tmux session
 	if err := inst.Kill(); err != nil {
 		out.Error(fmt.Sprintf("failed to stop session: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Save updated state
 	if err := saveSessionData(storage, instances); err != nil {
 		out.Error(fmt.Sprintf("failed to save session state: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Output success
@@ -299,7 +317,7 @@ func handleSessionRestart(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	identifier := fs.Arg(0)
@@ -310,7 +328,7 @@ func handleSessionRestart(profile string, args []string) {
 	storage, instances, _, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(err.Error(), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Resolve session
@@ -318,16 +336,16 @@ func handleSessionRestart(profile string, args []string) {
 	if inst == nil {
 		out.Error(errMsg, errCode)
 		if errCode == ErrCodeNotFound {
-			os.Exit(2)
+			exit(2)
 		}
-		os.Exit(1)
+		exit(1)
 		return // unreachable, satisfies staticcheck SA5011
 	}
 
 	// Restart the session
 	if err := inst.Restart(); err != nil {
 		out.Error(fmt.Sprintf("failed to restart session: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// If restart created a fresh session (no prior ID), capture the new ID
@@ -338,7 +356,7 @@ func handleSessionRestart(profile string, args []string) {
 	// Save updated state
 	if err := saveSessionData(storage, instances); err != nil {
 		out.Error(fmt.Sprintf("failed to save session state: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Output success
@@ -381,7 +399,7 @@ func handleSessionFork(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	identifier := fs.Arg(0)
@@ -396,7 +414,7 @@ func handleSessionFork(profile string, args []string) {
 	storage, instances, groupsData, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(err.Error(), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Resolve session
@@ -404,9 +422,9 @@ func handleSessionFork(profile string, args []string) {
 	if inst == nil {
 		out.Error(errMsg, errCode)
 		if errCode == ErrCodeNotFound {
-			os.Exit(2)
+			exit(2)
 		}
-		os.Exit(1)
+		exit(1)
 		return // unreachable, satisfies staticcheck SA5011
 	}
 
@@ -416,7 +434,7 @@ func handleSessionFork(profile string, args []string) {
 			fmt.Sprintf("session '%s' is not a Claude session (tool: %s)", inst.Title, inst.Tool),
 			ErrCodeInvalidOperation,
 		)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Try to capture session ID from tmux if missing (handles pre-fix sessions)
@@ -430,7 +448,7 @@ func handleSessionFork(profile string, args []string) {
 			fmt.Sprintf("session '%s' cannot be forked: no active Claude session ID", inst.Title),
 			ErrCodeInvalidOperation,
 		)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Default title if not provided
@@ -455,17 +473,17 @@ func handleSessionFork(profile string, args []string) {
 	if wtBranch != "" {
 		if !git.IsGitRepo(inst.ProjectPath) {
 			out.Error("session path is not a git repository", ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 		repoRoot, err := git.GetWorktreeBaseRoot(inst.ProjectPath)
 		if err != nil {
 			out.Error(fmt.Sprintf("failed to get repo root: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 
 		if !createNewBranch && !git.BranchExists(repoRoot, wtBranch) {
 			out.Error(fmt.Sprintf("branch '%s' does not exist (use -b to create)", wtBranch), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 
 		wtSettings := session.GetWorktreeSettings()
@@ -479,17 +497,17 @@ func handleSessionFork(profile string, args []string) {
 
 		if _, statErr := os.Stat(worktreePath); statErr == nil {
 			out.Error(fmt.Sprintf("worktree path already exists: %s", worktreePath), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 
 		if err := os.MkdirAll(filepath.Dir(worktreePath), 0o755); err != nil {
 			out.Error(fmt.Sprintf("failed to create directory: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 
-		if err := git.CreateWorktree(repoRoot, worktreePath, wtBranch); err != nil {
+		if err := session.CreateWorktree(repoRoot, worktreePath, wtBranch, worktreeProgress(*jsonOutput)); err != nil {
 			out.Error(fmt.Sprintf("worktree creation failed: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 
 		userConfig, _ := session.LoadUserConfig()
@@ -504,13 +522,13 @@ func handleSessionFork(profile string, args []string) {
 	forkedInst, _, err := inst.CreateForkedInstanceWithOptions(forkTitle, forkGroup, opts)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to create fork: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Start the forked session
 	if err := forkedInst.Start(); err != nil {
 		out.Error(fmt.Sprintf("failed to start forked session: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Capture forked session's new session ID
@@ -528,8 +546,9 @@ func handleSessionFork(profile string, args []string) {
 	// Save
 	if err := storage.SaveWithGroups(instances, groupTree); err != nil {
 		out.Error(fmt.Sprintf("failed to save: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
+	session.FireForkHooks(forkedInst, inst.ID)
 
 	// Output success
 	out.Success(
@@ -555,7 +574,7 @@ func handleSessionAttach(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	identifier := fs.Arg(0)
@@ -564,7 +583,7 @@ func handleSessionAttach(profile string, args []string) {
 	_, instances, _, err := loadSessionData(profile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Resolve session (allow current session detection)
@@ -572,23 +591,23 @@ func handleSessionAttach(profile string, args []string) {
 	if inst == nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", errMsg)
 		if errCode == ErrCodeNotFound {
-			os.Exit(2)
+			exit(2)
 		}
-		os.Exit(1)
+		exit(1)
 		return // unreachable, satisfies staticcheck SA5011
 	}
 
 	// Check if session exists
 	if !inst.Exists() {
 		fmt.Fprintf(os.Stderr, "Error: session '%s' is not running\n", inst.Title)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Attach to the session
 	tmuxSession := inst.GetTmuxSession()
 	if tmuxSession == nil {
 		fmt.Fprintf(os.Stderr, "Error: no tmux session for '%s'\n", inst.Title)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Create context for attach
@@ -596,7 +615,7 @@ func handleSessionAttach(profile string, args []string) {
 
 	if err := tmuxSession.Attach(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: failed to attach: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -617,7 +636,7 @@ func handleSessionShow(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	identifier := fs.Arg(0)
@@ -625,10 +644,10 @@ func handleSessionShow(profile string, args []string) {
 	out := NewCLIOutput(*jsonOutput, quietMode)
 
 	// Load sessions
-	_, instances, _, err := loadSessionData(profile)
+	storage, instances, _, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(err.Error(), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Resolve session (allow current session detection)
@@ -646,6 +665,7 @@ func handleSessionShow(profile string, args []string) {
 					// Found in a different profile - show which profile
 					// (jsonData will include the profile info)
 					profile = foundProfile
+					storage = nil // History lives in the other profile's database
 				}
 			}
 			if inst == nil {
@@ -656,9 +676,9 @@ func handleSessionShow(profile string, args []string) {
 		} else {
 			out.Error(errMsg, errCode)
 			if errCode == ErrCodeNotFound {
-				os.Exit(2)
+				exit(2)
 			}
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -704,6 +724,39 @@ func handleSessionShow(profile string, args []string) {
 		}
 	}
 
+	contextPolicy := session.ResolveContextPolicy(inst)
+	jsonData["context_policy"] = contextPolicy
+
+	usage := sessionUsageForShow(inst)
+	if usage != nil {
+		jsonData["analytics"] = usage
+	}
+
+	// Live git status of the session's working directory (nil outside a repo)
+	gitStatus, _ := git.GetStatus(inst.GetActualWorkDir())
+	if gitStatus != nil {
+		jsonData["git"] = gitStatus
+	}
+
+	if inst.Workspace != nil {
+		jsonData["workspace"] = inst.Workspace
+	}
+
+	// Leased [ports], each checked for a listener
+	ports := showPorts(inst.GetPorts())
+	if len(ports) > 0 {
+		jsonData["ports"] = ports
+	}
+
+	// History is only available for sessions in the current profile's database
+	var history []session.HistoryEntry
+	if storage != nil {
+		history, _ = storage.LoadHistory(inst.ID, 20)
+	}
+	if len(history) > 0 {
+		jsonData["history"] = history
+	}
+
 	// Build human-readable output
 	var sb strings.Builder
 
@@ -766,9 +819,169 @@ func handleSessionShow(profile string, args []string) {
 		}
 	}
 
+	if usage != nil {
+		sb.WriteString(fmt.Sprintf("Usage:   %d tokens, %d turns, ~$%.4f (context %.0f%%)\n",
+			usage.TotalTokens, usage.Turns, usage.EstimatedCost, usage.ContextPercent))
+	}
+
+	if contextPolicy.Action != session.ContextActionOff {
+		sb.WriteString(fmt.Sprintf("Context: %s at %d%% (idle %ds)\n",
+			contextPolicy.Action, contextPolicy.Threshold, contextPolicy.IdleSeconds))
+	}
+
+	if gitStatus != nil {
+		sb.WriteString(fmt.Sprintf("Git:     %s\n", formatGitStatusLine(gitStatus)))
+	}
+
+	if ws := inst.Workspace; ws != nil {
+		sb.WriteString(fmt.Sprintf("Workspace: %s", ws.Name))
+		if ws.Branch != "" {
+			sb.WriteString(fmt.Sprintf(" (branch %s)", ws.Branch))
+		}
+		sb.WriteString("\n")
+		for _, repo := range ws.Repos {
+			sb.WriteString(fmt.Sprintf("  repo:  %s\n", FormatPath(repo.Dir())))
+		}
+		for _, path := range ws.Paths {
+			sb.WriteString(fmt.Sprintf("  path:  %s\n", FormatPath(path)))
+		}
+	}
+
+	if len(ports) > 0 {
+		parts := make([]string, len(ports))
+		for n, p := range ports {
+			parts[n] = strconv.Itoa(p.Port)
+			if p.Listening {
+				parts[n] += " (listening)"
+			}
+		}
+		sb.WriteString(fmt.Sprintf("Ports:   %s\n", strings.Join(parts, ", ")))
+	}
+
+	if len(history) > 0 {
+		sb.WriteString("\nHistory:\n")
+		for _, h := range history {
+			sb.WriteString(fmt.Sprintf("  %s  %-16s %s\n", h.Time.Format("2006-01-02 15:04:05"), h.Kind, h.Message))
+		}
+	}
+
 	out.Print(sb.String(), jsonData)
 }
 
+// showPort is a leased port included in session show output
+type showPort struct {
+	Port      int    `json:"port"`
+	Env       string `json:"env"`
+	Listening bool   `json:"listening"`
+}
+
+// showPorts pairs leased ports with their env var and listener state.
+func showPorts(ports []int) []showPort {
+	result := make([]showPort, 0, len(ports))
+	for n, port := range ports {
+		result = append(result, showPort{
+			Port:      port,
+			Env:       fmt.Sprintf("%s%d", session.PortEnvPrefix, n+1),
+			Listening: session.PortListening(port),
+		})
+	}
+	return result
+}
+
+// formatGitStatusLine summarizes a git status on one line, e.g.
+// "feature/x ↑2↓0 vs origin/feature/x, 3 changed (+120 -4), origin/main ↑5↓1"
+func formatGitStatusLine(st *git.Status) string {
+	var b strings.Builder
+	if st.Branch != "" {
+		b.WriteString(st.Branch)
+	} else {
+		b.WriteString("(detached)")
+	}
+	if st.Upstream != "" {
+		fmt.Fprintf(&b, " ↑%d↓%d vs %s", st.Ahead, st.Behind, st.Upstream)
+	}
+	if changed := st.Staged + st.Unstaged + st.Untracked + st.Conflicted; changed > 0 {
+		fmt.Fprintf(&b, ", %d changed (+%d -%d)", changed, st.LinesAdded, st.LinesDeleted)
+	} else {
+		b.WriteString(", clean")
+	}
+	if st.DefaultBranch != "" {
+		fmt.Fprintf(&b, ", %s ↑%d↓%d", st.DefaultBranch, st.AheadDefault, st.BehindDefault)
+	}
+	return b.String()
+}
+
+// showUsage is the analytics summary included in session show output
+type showUsage struct {
+	Model          string             `json:"model,omitempty"`
+	InputTokens    int                `json:"input_tokens"`
+	OutputTokens   int                `json:"output_tokens"`
+	CacheRead      int                `json:"cache_read_input_tokens"`
+	CacheWrite     int                `json:"cache_creation_input_tokens"`
+	TotalTokens    int                `json:"total_tokens"`
+	ContextTokens  int                `json:"current_context_tokens"`
+	ContextPercent float64            `json:"context_percent"`
+	Turns          int                `json:"total_turns"`
+	ToolCalls      []session.ToolCall `json:"tool_calls,omitempty"`
+	EstimatedCost  float64            `json:"estimated_cost"`
+	LastActive     *time.Time         `json:"last_active,omitempty"`
+}
+
+// sessionUsageForShow returns token, turn and cost analytics for the
+// session's tool, or nil if none are available.
+func sessionUsageForShow(inst *session.Instance) *showUsage {
+	if inst.Tool == "gemini" {
+		a := inst.GeminiAnalytics
+		if a == nil || a.TotalTurns == 0 {
+			return nil
+		}
+		model := a.Model
+		if model == "" {
+			model = "default"
+		}
+		u := &showUsage{
+			Model:          a.Model,
+			InputTokens:    a.InputTokens,
+			OutputTokens:   a.OutputTokens,
+			TotalTokens:    a.TotalTokens(),
+			ContextTokens:  a.CurrentContextTokens,
+			ContextPercent: a.ContextPercent(0),
+			Turns:          a.TotalTurns,
+			EstimatedCost:  a.CalculateCost(model),
+		}
+		if !a.LastActive.IsZero() {
+			u.LastActive = &a.LastActive
+		}
+		return u
+	}
+
+	a, err := session.GetSessionAnalytics(inst)
+	if err != nil || a == nil || a.TotalTurns == 0 {
+		return nil
+	}
+	cost := a.EstimatedCost
+	if cost == 0 {
+		cost = a.CalculateCost(a.Model)
+	}
+	u := &showUsage{
+		Model:          a.Model,
+		InputTokens:    a.InputTokens,
+		OutputTokens:   a.OutputTokens,
+		CacheRead:      a.CacheReadTokens,
+		CacheWrite:     a.CacheWriteTokens,
+		TotalTokens:    a.TotalTokens(),
+		ContextTokens:  a.CurrentContextTokens,
+		ContextPercent: a.ContextPercent(0),
+		Turns:          a.TotalTurns,
+		ToolCalls:      a.ToolCalls,
+		EstimatedCost:  cost,
+	}
+	if !a.LastActive.IsZero() {
+		u.LastActive = &a.LastActive
+	}
+	return u
+}
+
 func mcpInfoForJSON(mcpInfo *session.MCPInfo) map[string]interface{} {
 	if mcpInfo == nil || !mcpInfo.HasAny() {
 		return nil
@@ -800,6 +1013,8 @@ func handleSessionSet(profile string, args []string) {
 		fmt.Println("  wrapper            Wrapper command (use {command} to include tool command)")
 		fmt.Println("  claude-session-id  Claude conversation ID")
 		fmt.Println("  gemini-session-id  Gemini conversation ID")
+		fmt.Println("  context-action     Context-window action: off, compact, fork (default = inherit)")
+		fmt.Println("  context-threshold  Context usage percentage that triggers the action (0 = inherit)")
 		fmt.Println()
 		fmt.Println("Options:")
 		fs.PrintDefaults()
@@ -809,15 +1024,16 @@ func handleSessionSet(profile string, args []string) {
 		fmt.Println("  agent-deck session set my-project claude-session-id \"abc123-def456\"")
 		fmt.Println("  agent-deck session set my-project path /new/path/to/project")
 		fmt.Println("  agent-deck session set my-project wrapper \"nvim +'terminal {command}'\"")
+		fmt.Println("  agent-deck session set my-project context-action fork")
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	if fs.NArg() < 3 {
 		fs.Usage()
-		os.Exit(1)
+		exit(1)
 	}
 
 	identifier := fs.Arg(0)
@@ -835,24 +1051,26 @@ func handleSessionSet(profile string, args []string) {
 		"wrapper":           true,
 		"claude-session-id": true,
 		"gemini-session-id": true,
+		"context-action":    true,
+		"context-threshold": true,
 	}
 
 	if !validFields[field] {
 		out.Error(
 			fmt.Sprintf(
-				"invalid field: %s\nValid fields: title, path, command, tool, wrapper, claude-session-id, gemini-session-id",
+				"invalid field: %s\nValid fields: title, path, command, tool, wrapper, claude-session-id, gemini-session-id, context-action, context-threshold",
 				field,
 			),
 			ErrCodeInvalidOperation,
 		)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Load sessions
 	storage, instances, groupsData, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(err.Error(), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Resolve session
@@ -860,9 +1078,9 @@ func handleSessionSet(profile string, args []string) {
 	if inst == nil {
 		out.Error(errMsg, errCode)
 		if errCode == ErrCodeNotFound {
-			os.Exit(2)
+			exit(2)
 		}
-		os.Exit(1)
+		exit(1)
 		return // unreachable, satisfies staticcheck SA5011
 	}
 
@@ -903,13 +1121,41 @@ func handleSessionSet(profile string, args []string) {
 		if tmuxSess := inst.GetTmuxSession(); tmuxSess != nil && tmuxSess.Exists() {
 			_ = exec.Command("tmux", "set-environment", "-t", tmuxSess.Name, "GEMINI_SESSION_ID", value).Run()
 		}
+	case "context-action":
+		if value != "default" && !session.IsValidContextAction(value) {
+			out.Error(fmt.Sprintf("invalid context-action: %s (valid: off, compact, fork, default)", value), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		policy := session.ContextPolicy{}
+		if inst.ContextPolicy != nil {
+			policy = *inst.ContextPolicy
+		}
+		oldValue = policy.Action
+		policy.Action = value
+		if value == "default" {
+			policy.Action = ""
+		}
+		inst.ContextPolicy = contextPolicyOrNil(policy)
+	case "context-threshold":
+		threshold, err := strconv.Atoi(value)
+		if err != nil || threshold < 0 || threshold > 100 {
+			out.Error(fmt.Sprintf("invalid context-threshold: %s (expected 0-100)", value), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		policy := session.ContextPolicy{}
+		if inst.ContextPolicy != nil {
+			policy = *inst.ContextPolicy
+		}
+		oldValue = strconv.Itoa(policy.Threshold)
+		policy.Threshold = threshold
+		inst.ContextPolicy = contextPolicyOrNil(policy)
 	}
 
 	// Save
 	groupTree := session.NewGroupTreeWithGroups(instances, groupsData)
 	if err := storage.SaveWithGroups(instances, groupTree); err != nil {
 		out.Error(fmt.Sprintf("failed to save: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Output success
@@ -923,6 +1169,14 @@ func handleSessionSet(profile string, args []string) {
 	})
 }
 
+// contextPolicyOrNil returns nil for an all-inherit policy so it isn't persisted.
+func contextPolicyOrNil(p session.ContextPolicy) *session.ContextPolicy {
+	if p == (session.ContextPolicy{}) {
+		return nil
+	}
+	return &p
+}
+
 // loadSessionData loads storage and session data for a profile
 // The Storage.LoadWithGroups() method already handles tmux reconnection internally
 func loadSessionData(profile string) (*session.Storage, []*session.Instance, []*session.GroupData, error) {
@@ -939,6 +1193,14 @@ func loadSessionData(profile string) (*session.Storage, []*session.Instance, []*
 	// LoadWithGroups reconnects tmux sessions with lazy loading.
 	// Status uses cached values from JSON; session IDs are not synced at load time.
 
+	// Sessions started from the CLI lease [ports] and record history through
+	// the global state database, which only the TUI sets up otherwise.
+	if statedb.GetGlobal() == nil {
+		if db := storage.GetDB(); db != nil {
+			statedb.SetGlobal(db)
+		}
+	}
+
 	return storage, instances, groupsData, nil
 }
 
@@ -1033,7 +1295,7 @@ func showTmuxSessionInfo(out *CLIOutput, jsonOutput bool) {
 	output, err := cmd.Output()
 	if err != nil {
 		out.Error("failed to get tmux session info", ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	parts := strings.Split(strings.TrimSpace(string(output)), "\t")
@@ -1108,12 +1370,12 @@ func handleSessionSetParent(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	if fs.NArg() < 2 {
 		fs.Usage()
-		os.Exit(1)
+		exit(1)
 	}
 
 	sessionID := fs.Arg(0)
@@ -1125,14 +1387,14 @@ func handleSessionSetParent(profile string, args []string) {
 	storage, instances, groupsData, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(err.Error(), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Resolve the session to be linked
 	inst, errMsg, errCode := ResolveSession(sessionID, instances)
 	if inst == nil {
 		out.Error(errMsg, errCode)
-		os.Exit(2)
+		exit(2)
 		return // unreachable, satisfies staticcheck SA5011
 	}
 
@@ -1140,20 +1402,20 @@ func handleSessionSetParent(profile string, args []string) {
 	parentInst, errMsg, errCode := ResolveSession(parentID, instances)
 	if parentInst == nil {
 		out.Error(errMsg, errCode)
-		os.Exit(2)
+		exit(2)
 		return // unreachable, satisfies staticcheck SA5011
 	}
 
 	// Validate: can't set self as parent
 	if inst.ID == parentInst.ID {
 		out.Error("cannot set session as its own parent", ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Validate: parent can't be a sub-session (single level only)
 	if parentInst.IsSubSession() {
 		out.Error("cannot set parent to a sub-session (single level only)", ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Validate: session can't already have sub-sessions
@@ -1163,7 +1425,7 @@ func handleSessionSetParent(profile string, args []string) {
 				fmt.Sprintf("session '%s' already has sub-sessions, cannot become a sub-session", inst.Title),
 				ErrCodeInvalidOperation,
 			)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -1175,7 +1437,7 @@ func handleSessionSetParent(profile string, args []string) {
 	groupTree := session.NewGroupTreeWithGroups(instances, groupsData)
 	if err := storage.SaveWithGroups(instances, groupTree); err != nil {
 		out.Error(fmt.Sprintf("failed to save: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	out.Success(fmt.Sprintf("Linked '%s' as sub-session of '%s'", inst.Title, parentInst.Title), map[string]interface{}{
@@ -1206,12 +1468,12 @@ func handleSessionUnsetParent(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	if fs.NArg() < 1 {
 		fs.Usage()
-		os.Exit(1)
+		exit(1)
 	}
 
 	sessionID := fs.Arg(0)
@@ -1222,21 +1484,21 @@ func handleSessionUnsetParent(profile string, args []string) {
 	storage, instances, groupsData, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(err.Error(), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Resolve the session
 	inst, errMsg, errCode := ResolveSession(sessionID, instances)
 	if inst == nil {
 		out.Error(errMsg, errCode)
-		os.Exit(2)
+		exit(2)
 		return // unreachable, satisfies staticcheck SA5011
 	}
 
 	// Check if it's actually a sub-session
 	if !inst.IsSubSession() {
 		out.Error(fmt.Sprintf("session '%s' is not a sub-session", inst.Title), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Get parent title for output
@@ -1255,7 +1517,7 @@ func handleSessionUnsetParent(profile string, args []string) {
 	groupTree := session.NewGroupTreeWithGroups(instances, groupsData)
 	if err := storage.SaveWithGroups(instances, groupTree); err != nil {
 		out.Error(fmt.Sprintf("failed to save: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	out.Success(
@@ -1295,7 +1557,7 @@ func handleSessionSend(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 	remaining := fs.Args()
 
@@ -1304,7 +1566,7 @@ func handleSessionSend(profile string, args []string) {
 	if len(remaining) < 2 {
 		fs.Usage()
 		out.Error("session and message are required", ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	sessionRef := remaining[0]
@@ -1314,7 +1576,7 @@ func handleSessionSend(profile string, args []string) {
 	_, instances, _, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(err.Error(), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Resolve session
@@ -1322,30 +1584,30 @@ func handleSessionSend(profile string, args []string) {
 	if inst == nil {
 		out.Error(errMsg, errCode)
 		if errCode == ErrCodeNotFound {
-			os.Exit(2)
+			exit(2)
 		}
-		os.Exit(1)
+		exit(1)
 		return // unreachable, satisfies staticcheck SA5011
 	}
 
 	// Check if session is running
 	if !inst.Exists() {
 		out.Error(fmt.Sprintf("session '%s' is not running", inst.Title), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Get tmux session
 	tmuxSess := inst.GetTmuxSession()
 	if tmuxSess == nil {
 		out.Error("could not determine tmux session", ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Wait for agent to be ready (unless --no-wait is specified)
 	if !*noWait {
 		if err := waitForAgentReady(tmuxSess, inst.Tool); err != nil {
 			out.Error(fmt.Sprintf("timeout waiting for agent: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -1355,12 +1617,12 @@ func handleSessionSend(profile string, args []string) {
 	if *noWait {
 		if err := tmuxSess.SendKeysAndEnter(message); err != nil {
 			out.Error(fmt.Sprintf("failed to send message: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	} else {
 		if err := sendWithRetry(tmuxSess, message, false); err != nil {
 			out.Error(fmt.Sprintf("failed to send message: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -1376,7 +1638,7 @@ func handleSessionSend(profile string, args []string) {
 		finalStatus, err := waitForCompletion(tmuxSess, *timeout)
 		if err != nil {
 			out.Error(fmt.Sprintf("timeout waiting for completion: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 
 		// Refresh session ID: the instance was loaded before sending the message,
@@ -1403,13 +1665,13 @@ func handleSessionSend(profile string, args []string) {
 		}
 		if err != nil {
 			out.Error(fmt.Sprintf("failed to get response: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println(response.Content)
 
 		// Exit 1 for error/inactive status
 		if finalStatus == "inactive" || finalStatus == "error" {
-			os.Exit(1)
+			exit(1)
 		}
 	}
 }
@@ -1597,7 +1859,7 @@ func handleSessionOutput(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	identifier := fs.Arg(0)
@@ -1608,7 +1870,7 @@ func handleSessionOutput(profile string, args []string) {
 	_, instances, _, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Resolve session (allow current session detection)
@@ -1616,9 +1878,9 @@ func handleSessionOutput(profile string, args []string) {
 	if inst == nil {
 		out.Error(errMsg, errCode)
 		if errCode == ErrCodeNotFound {
-			os.Exit(2)
+			exit(2)
 		}
-		os.Exit(1)
+		exit(1)
 		return // unreachable, satisfies staticcheck SA5011
 	}
 
@@ -1626,7 +1888,7 @@ func handleSessionOutput(profile string, args []string) {
 	response, err := inst.GetLastResponse()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to get response: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Copy to clipboard mode
@@ -1635,7 +1897,7 @@ func handleSessionOutput(profile string, args []string) {
 		result, err := clipboard.Copy(response.Content, termInfo.SupportsOSC52)
 		if err != nil {
 			out.Error(fmt.Sprintf("clipboard: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 		jsonData := map[string]interface{}{
 			"success":       true,
@@ -1710,7 +1972,7 @@ func handleSessionCurrent(profileArg string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	quietMode := *quiet || *quietShort
@@ -1719,7 +1981,7 @@ func handleSessionCurrent(profileArg string, args []string) {
 	// Check if we're in a tmux session
 	if os.Getenv("TMUX") == "" {
 		out.Error("not in a tmux session", ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// ═══════════════════════════════════════════════════════════════════
@@ -1729,7 +1991,7 @@ func handleSessionCurrent(profileArg string, args []string) {
 	tmuxSessionName, err := getCurrentTmuxSessionName()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to get current tmux session: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Detect profile: use explicit arg if provided, otherwise auto-detect
@@ -1746,7 +2008,7 @@ func handleSessionCurrent(profileArg string, args []string) {
 			"current tmux session is not an agent-deck session\nHint: Run 'agent-deck list' to see available sessions",
 			ErrCodeNotFound,
 		)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if foundProfile != "" {
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+// handleHooksTest runs the [session_hooks] commands for an event right away,
+// for a real session or a sample one, and reports how each command did.
+func handleHooksTest(profile string, args []string) {
+	fs := flag.NewFlagSet("hooks test", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	group := fs.String("group", "", "Group of the sample session (without a session argument)")
+	tool := fs.String("tool", "", "Tool of the sample session (without a session argument)")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck hooks test <event> [session] [options]")
+		fmt.Println()
+		fmt.Println("Run the [session_hooks] commands for an event now and show the results.")
+		fmt.Println("Without a session, a sample session in the current directory is used.")
+		fmt.Println("The payload's details contain \"test\": \"true\".")
+		fmt.Println()
+		fmt.Println("Events:")
+		for _, event := range session.SessionHookEvents {
+			fmt.Printf("  %s\n", event)
+		}
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  agent-deck hooks test session.created")
+		fmt.Println("  agent-deck hooks test session.status_changed my-project")
+		fmt.Println("  agent-deck hooks test session.deleted --group work --tool claude")
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+
+	out := NewCLIOutput(*jsonOutput, false)
+
+	event := fs.Arg(0)
+	if event == "" {
+		fs.Usage()
+		exit(1)
+	}
+	if !session.IsSessionHookEvent(event) {
+		out.Error(fmt.Sprintf("unknown event '%s' (one of: %s)", event, strings.Join(session.SessionHookEvents, ", ")), ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	var inst *session.Instance
+	if identifier := fs.Arg(1); identifier != "" {
+		_, instances, _, err := loadSessionData(profile)
+		if err != nil {
+			out.Error(err.Error(), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		var errMsg, errCode string
+		inst, errMsg, errCode = ResolveSession(identifier, instances)
+		if inst == nil {
+			out.Error(errMsg, errCode)
+			if errCode == ErrCodeNotFound {
+				exit(2)
+			}
+			exit(1)
+		}
+	} else {
+		cwd, err := os.Getwd()
+		if err != nil {
+			out.Error(fmt.Sprintf("failed to get current directory: %v", err), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		sampleTool := *tool
+		if sampleTool == "" {
+			sampleTool = session.GetDefaultTool()
+		}
+		if sampleTool == "" {
+			sampleTool = "shell"
+		}
+		inst = session.NewInstanceWithTool("hooks-test", cwd, sampleTool)
+		if *group != "" {
+			inst.GroupPath = *group
+		}
+	}
+
+	payload := session.NewSessionHookPayload(event, inst)
+	payload.Details = map[string]string{"test": "true"}
+	if event == session.HookSessionStatusChanged {
+		payload.PrevStatus = session.StatusRunning
+	}
+
+	settings := session.GetSessionHookSettings()
+	commands := settings.Commands(event, inst.GroupPath, inst.Tool)
+	if len(commands) == 0 {
+		out.Print(
+			fmt.Sprintf("No hooks configured for %s (group '%s', tool '%s')\n", event, inst.GroupPath, inst.Tool),
+			map[string]interface{}{"event": event, "session_id": inst.ID, "results": []interface{}{}},
+		)
+		return
+	}
+
+	if !*jsonOutput {
+		fmt.Printf("Running %d hook(s) for %s (session '%s')...\n", len(commands), event, inst.Title)
+	}
+	results := session.RunSessionHooks(settings, payload)
+
+	type hookResult struct {
+		Command    string   `json:"command"`
+		OK         bool     `json:"ok"`
+		DurationMS int64    `json:"duration_ms"`
+		Error      string   `json:"error,omitempty"`
+		Output     []string `json:"output,omitempty"`
+	}
+	failed := 0
+	report := make([]hookResult, 0, len(results))
+	for _, res := range results {
+		r := hookResult{Command: res.Command, OK: res.Err == nil, DurationMS: res.Duration.Milliseconds(), Output: res.Output}
+		if res.Err != nil {
+			r.Error = res.Err.Error()
+			failed++
+		}
+		report = append(report, r)
+	}
+
+	if *jsonOutput {
+		out.Print("", map[string]interface{}{
+			"event":      event,
+			"session_id": inst.ID,
+			"payload":    payload,
+			"results":    report,
+		})
+	} else {
+		for _, r := range report {
+			duration := (time.Duration(r.DurationMS) * time.Millisecond).Round(10 * time.Millisecond)
+			if r.OK {
+				fmt.Printf("  %s %s (%s)\n", successSymbol, r.Command, duration)
+			} else {
+				fmt.Printf("  %s %s (%s): %s\n", errorSymbol, r.Command, duration, r.Error)
+			}
+			for _, line := range r.Output {
+				fmt.Printf("      %s\n", line)
+			}
+		}
+	}
+	if failed > 0 {
+		exit(1)
+	}
+}
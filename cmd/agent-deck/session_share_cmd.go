@@ -0,0 +1,266 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+	"github.com/asheshgoplani/agent-deck/internal/web"
+)
+
+// defaultShareBaseURL is used when the web server never recorded its URL.
+const defaultShareBaseURL = "http://127.0.0.1:8420"
+
+// handleSessionShare creates, lists and revokes expiring links to one
+// session's terminal in the web UI.
+func handleSessionShare(profile string, args []string) {
+	fs := flag.NewFlagSet("session share", flag.ExitOnError)
+	ttl := fs.Duration("ttl", time.Hour, "How long the link works (e.g. 30m, 4h)")
+	allowInput := fs.Bool("allow-input", false, "Let viewers type into the terminal")
+	baseURL := fs.String("url", "", "Web server URL viewers open (default: where 'agent-deck web' last ran)")
+	all := fs.Bool("all", false, "list: include expired and revoked links")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	quiet := fs.Bool("quiet", false, "Minimal output")
+	quietShort := fs.Bool("q", false, "Minimal output (short)")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck session share <id|title> [command] [options]")
+		fmt.Println()
+		fmt.Println("Signed, expiring links to one session's terminal in the web UI, for")
+		fmt.Println("people without an access token. Links are read-only unless created with")
+		fmt.Println("--allow-input, and work while 'agent-deck web' runs for this profile.")
+		fmt.Println()
+		fmt.Println("Commands:")
+		fmt.Println("  (none)              Create a link and print it")
+		fmt.Println("  list                List the session's links and who is watching")
+		fmt.Println("  revoke [share-id]   Revoke one link, or all of the session's links")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  agent-deck session share my-project --ttl 1h")
+		fmt.Println("  agent-deck session share my-project --ttl 30m --allow-input")
+		fmt.Println("  agent-deck session share my-project list")
+		fmt.Println("  agent-deck session share my-project revoke")
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		exit(1)
+	}
+	out := NewCLIOutput(*jsonOutput, *quiet || *quietShort)
+
+	storage, instances, _, err := loadSessionData(profile)
+	if err != nil {
+		out.Error(err.Error(), ErrCodeNotFound)
+		exit(1)
+	}
+	defer storage.Close()
+	inst, errMsg, errCode := ResolveSession(fs.Arg(0), instances)
+	if inst == nil {
+		out.Error(errMsg, errCode)
+		exit(2)
+		return // unreachable, satisfies staticcheck SA5011
+	}
+	db := storage.GetDB()
+	if db == nil {
+		out.Error("profile has no state database", ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	switch fs.Arg(1) {
+	case "":
+		if *ttl <= 0 {
+			out.Error("--ttl must be positive", ErrCodeInvalidOperation)
+			exit(1)
+		}
+		base := *baseURL
+		if base == "" {
+			if base, _ = db.GetMeta(web.ShareBaseURLMeta); base == "" {
+				base = defaultShareBaseURL
+			}
+		}
+		if u, err := url.Parse(base); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			out.Error(fmt.Sprintf("invalid --url %q: want http(s)://host:port", base), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		// Links that ended a while ago are only noise in 'list'
+		_ = db.PruneWebShares(time.Now().Add(-7 * 24 * time.Hour))
+
+		token, share, err := web.CreateShare(db, inst.ID, *ttl, *allowInput)
+		if err != nil {
+			out.Error(err.Error(), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		link := web.ShareURL(base, token)
+		_ = db.AppendWebAudit(&statedb.WebAuditRow{
+			TokenName: "share:" + share.ID,
+			Action:    "share.create",
+			SessionID: inst.ID,
+			Detail:    describeShare(share),
+		})
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s Shared '%s' (%s)\n", successSymbol, inst.Title, describeShare(share))
+		fmt.Fprintln(&b)
+		fmt.Fprintf(&b, "  %s\n", link)
+		fmt.Fprintln(&b)
+		fmt.Fprintf(&b, "Revoke with: agent-deck session share %s revoke %s\n", fs.Arg(0), share.ID)
+		data := shareJSON(share, nil)
+		data["url"] = link
+		data["session_title"] = inst.Title
+		out.Print(b.String(), data)
+
+	case "list", "ls":
+		shares, err := db.LoadWebShares(inst.ID)
+		if err != nil {
+			out.Error(fmt.Sprintf("failed to load share links: %v", err), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		viewers, err := db.LoadActiveWebShareViewers(time.Now().Add(-web.ShareViewerActiveWindow))
+		if err != nil {
+			out.Error(fmt.Sprintf("failed to load viewers: %v", err), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		byShare := make(map[string][]*statedb.WebShareViewerRow)
+		for _, v := range viewers {
+			byShare[v.ShareID] = append(byShare[v.ShareID], v)
+		}
+
+		now := time.Now()
+		list := make([]map[string]interface{}, 0, len(shares))
+		var b strings.Builder
+		tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tACCESS\tEXPIRES\tWATCHING")
+		for _, share := range shares {
+			if !*all && !share.Active(now) {
+				continue
+			}
+			list = append(list, shareJSON(share, byShare[share.ID]))
+			watching := make([]string, 0, len(byShare[share.ID]))
+			for _, v := range byShare[share.ID] {
+				watching = append(watching, describeShareViewer(v))
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", share.ID, shareAccess(share), formatShareState(share, now),
+				dashIfEmpty(strings.Join(watching, ", ")))
+		}
+		_ = tw.Flush()
+		if len(list) == 0 {
+			b.Reset()
+			fmt.Fprintf(&b, "No active share links for '%s'. Create one with: agent-deck session share %s\n", inst.Title, fs.Arg(0))
+		}
+		out.Print(b.String(), map[string]interface{}{"session_id": inst.ID, "shares": list})
+
+	case "revoke":
+		now := time.Now()
+		revoked := []string{}
+		if id := fs.Arg(2); id != "" {
+			share, err := db.WebShare(id)
+			if err != nil {
+				out.Error(fmt.Sprintf("failed to load share link: %v", err), ErrCodeInvalidOperation)
+				exit(1)
+			}
+			if share == nil || share.SessionID != inst.ID {
+				out.Error(fmt.Sprintf("share link '%s' not found for '%s'", id, inst.Title), ErrCodeNotFound)
+				exit(2)
+			}
+			if ok, err := db.RevokeWebShare(id, now); err != nil {
+				out.Error(fmt.Sprintf("failed to revoke share link: %v", err), ErrCodeInvalidOperation)
+				exit(1)
+			} else if ok {
+				revoked = append(revoked, id)
+			}
+		} else {
+			shares, err := db.LoadWebShares(inst.ID)
+			if err != nil {
+				out.Error(fmt.Sprintf("failed to load share links: %v", err), ErrCodeInvalidOperation)
+				exit(1)
+			}
+			for _, share := range shares {
+				if !share.Active(now) {
+					continue
+				}
+				if ok, err := db.RevokeWebShare(share.ID, now); err != nil {
+					out.Error(fmt.Sprintf("failed to revoke share link: %v", err), ErrCodeInvalidOperation)
+					exit(1)
+				} else if ok {
+					revoked = append(revoked, share.ID)
+				}
+			}
+		}
+		for _, id := range revoked {
+			_ = db.AppendWebAudit(&statedb.WebAuditRow{TokenName: "share:" + id, Action: "share.revoke", SessionID: inst.ID})
+		}
+		out.Success(fmt.Sprintf("Revoked %d share link(s) for '%s'", len(revoked), inst.Title),
+			map[string]interface{}{"session_id": inst.ID, "revoked": revoked})
+
+	default:
+		out.Error(fmt.Sprintf("unknown share command: %s (use list or revoke)", fs.Arg(1)), ErrCodeInvalidOperation)
+		exit(1)
+	}
+}
+
+// describeShare summarizes a share's access and expiry.
+func describeShare(share *statedb.WebShareRow) string {
+	return shareAccess(share) + ", expires " + share.ExpiresAt.Format("2006-01-02 15:04")
+}
+
+func shareAccess(share *statedb.WebShareRow) string {
+	if share.AllowInput {
+		return "view+input"
+	}
+	return "view"
+}
+
+func formatShareState(share *statedb.WebShareRow, now time.Time) string {
+	switch {
+	case !share.RevokedAt.IsZero():
+		return "revoked"
+	case !now.Before(share.ExpiresAt):
+		return "expired"
+	default:
+		return share.ExpiresAt.Format("2006-01-02 15:04")
+	}
+}
+
+// describeShareViewer names a viewer by the name they gave, falling back to
+// their address.
+func describeShareViewer(v *statedb.WebShareViewerRow) string {
+	if v.Name != "" {
+		return fmt.Sprintf("%s (%s)", v.Name, v.RemoteAddr)
+	}
+	return v.RemoteAddr
+}
+
+func shareJSON(share *statedb.WebShareRow, viewers []*statedb.WebShareViewerRow) map[string]interface{} {
+	watching := make([]map[string]interface{}, 0, len(viewers))
+	for _, v := range viewers {
+		watching = append(watching, map[string]interface{}{
+			"name":         v.Name,
+			"remote_addr":  v.RemoteAddr,
+			"user_agent":   v.UserAgent,
+			"connected_at": v.ConnectedAt,
+		})
+	}
+	data := map[string]interface{}{
+		"id":          share.ID,
+		"session_id":  share.SessionID,
+		"allow_input": share.AllowInput,
+		"created_at":  share.CreatedAt,
+		"expires_at":  share.ExpiresAt,
+		"active":      share.Active(time.Now()),
+		"viewers":     watching,
+	}
+	if !share.RevokedAt.IsZero() {
+		data["revoked_at"] = share.RevokedAt
+	}
+	return data
+}
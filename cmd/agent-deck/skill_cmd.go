@@ -16,7 +16,7 @@ import (
 func handleSkill(profile string, args []string) {
 	if len(args) == 0 {
 		printSkillHelp()
-		os.Exit(1)
+		exit(1)
 	}
 
 	switch args[0] {
@@ -35,7 +35,7 @@ func handleSkill(profile string, args []string) {
 	default:
 		fmt.Fprintf(os.Stderr, "Error: unknown skill command '%s'\n", args[0])
 		printSkillHelp()
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -78,7 +78,7 @@ func handleSkillList(args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	quietMode := *quiet || *quietShort
@@ -87,7 +87,7 @@ func handleSkillList(args []string) {
 	skills, err := session.ListAvailableSkills()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to list skills: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if strings.TrimSpace(*source) != "" {
@@ -156,7 +156,7 @@ func handleSkillAttached(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	quietMode := *quiet || *quietShort
@@ -165,33 +165,33 @@ func handleSkillAttached(profile string, args []string) {
 	storage, err := session.NewStorageWithProfile(profile)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to initialize storage: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	instances, _, err := storage.LoadWithGroups()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	identifier := fs.Arg(0)
 	inst, errMsg, errCode := ResolveSessionOrCurrent(identifier, instances)
 	if inst == nil {
 		out.Error(errMsg, errCode)
-		os.Exit(2)
+		exit(2)
 		return
 	}
 
 	attached, err := session.GetAttachedProjectSkills(inst.ProjectPath)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to load attached skills: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	materialized, err := session.ListMaterializedProjectSkills(inst.ProjectPath)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to read project skills directory: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	managedTargets := make(map[string]bool)
@@ -273,7 +273,7 @@ func handleSkillAttach(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	quietMode := *quiet || *quietShort
@@ -284,7 +284,7 @@ func handleSkillAttach(profile string, args []string) {
 		if !*jsonOutput {
 			fmt.Println("\nUsage: agent-deck skill attach <session-id> <skill> [options]")
 		}
-		os.Exit(1)
+		exit(1)
 	}
 
 	sessionID := fs.Arg(0)
@@ -293,24 +293,24 @@ func handleSkillAttach(profile string, args []string) {
 	storage, err := session.NewStorageWithProfile(profile)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to initialize storage: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	instances, _, err := storage.LoadWithGroups()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	inst, errMsg, errCode := ResolveSession(sessionID, instances)
 	if inst == nil {
 		out.Error(errMsg, errCode)
-		os.Exit(2)
+		exit(2)
 		return
 	}
 	if inst.Tool != "claude" {
 		out.Error("skills are currently supported for Claude sessions only", ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	attachment, err := session.AttachSkillToProject(inst.ProjectPath, skillRef, *sourceName)
@@ -318,16 +318,16 @@ func handleSkillAttach(profile string, args []string) {
 		switch {
 		case errors.Is(err, session.ErrSkillNotFound):
 			out.Error(err.Error(), ErrCodeNotFound)
-			os.Exit(2)
+			exit(2)
 		case errors.Is(err, session.ErrSkillAmbiguous):
 			out.Error(err.Error(), ErrCodeAmbiguous)
-			os.Exit(2)
+			exit(2)
 		case errors.Is(err, session.ErrSkillAlreadyAttached):
 			out.Error(err.Error(), ErrCodeAlreadyExists)
-			os.Exit(1)
+			exit(1)
 		default:
 			out.Error(fmt.Sprintf("failed to attach skill: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -382,7 +382,7 @@ func handleSkillDetach(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	quietMode := *quiet || *quietShort
@@ -393,7 +393,7 @@ func handleSkillDetach(profile string, args []string) {
 		if !*jsonOutput {
 			fmt.Println("\nUsage: agent-deck skill detach <session-id> <skill> [options]")
 		}
-		os.Exit(1)
+		exit(1)
 	}
 
 	sessionID := fs.Arg(0)
@@ -402,24 +402,24 @@ func handleSkillDetach(profile string, args []string) {
 	storage, err := session.NewStorageWithProfile(profile)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to initialize storage: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	instances, _, err := storage.LoadWithGroups()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	inst, errMsg, errCode := ResolveSession(sessionID, instances)
 	if inst == nil {
 		out.Error(errMsg, errCode)
-		os.Exit(2)
+		exit(2)
 		return
 	}
 	if inst.Tool != "claude" {
 		out.Error("skills are currently supported for Claude sessions only", ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	removed, err := session.DetachSkillFromProject(inst.ProjectPath, skillRef, *sourceName)
@@ -427,13 +427,13 @@ func handleSkillDetach(profile string, args []string) {
 		switch {
 		case errors.Is(err, session.ErrSkillNotAttached):
 			out.Error(err.Error(), ErrCodeNotFound)
-			os.Exit(2)
+			exit(2)
 		case errors.Is(err, session.ErrSkillAmbiguous):
 			out.Error(err.Error(), ErrCodeAmbiguous)
-			os.Exit(2)
+			exit(2)
 		default:
 			out.Error(fmt.Sprintf("failed to detach skill: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -473,7 +473,7 @@ func handleSkillDetach(profile string, args []string) {
 func handleSkillSource(args []string) {
 	if len(args) == 0 {
 		printSkillSourceHelp()
-		os.Exit(1)
+		exit(1)
 	}
 
 	switch args[0] {
@@ -488,7 +488,7 @@ func handleSkillSource(args []string) {
 	default:
 		fmt.Fprintf(os.Stderr, "Error: unknown skill source command '%s'\n", args[0])
 		printSkillSourceHelp()
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -510,7 +510,7 @@ func handleSkillSourceList(args []string) {
 	quietShort := fs.Bool("q", false, "Minimal output (short)")
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	quietMode := *quiet || *quietShort
@@ -519,7 +519,7 @@ func handleSkillSourceList(args []string) {
 	sources, err := session.ListSkillSources()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to list skill sources: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if *jsonOutput {
@@ -564,7 +564,7 @@ func handleSkillSourceAdd(args []string) {
 	description := fs.String("description", "", "Optional source description")
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	quietMode := *quiet || *quietShort
@@ -572,7 +572,7 @@ func handleSkillSourceAdd(args []string) {
 
 	if fs.NArg() < 2 {
 		out.Error("source name and path are required", ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	name := fs.Arg(0)
@@ -581,10 +581,10 @@ func handleSkillSourceAdd(args []string) {
 	if err := session.AddSkillSource(name, path, *description); err != nil {
 		if errors.Is(err, session.ErrSkillSourceExists) {
 			out.Error(err.Error(), ErrCodeAlreadyExists)
-			os.Exit(1)
+			exit(1)
 		}
 		out.Error(fmt.Sprintf("failed to add source: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if *jsonOutput {
@@ -601,7 +601,7 @@ func handleSkillSourceRemove(args []string) {
 	quietShort := fs.Bool("q", false, "Minimal output (short)")
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	quietMode := *quiet || *quietShort
@@ -609,17 +609,17 @@ func handleSkillSourceRemove(args []string) {
 
 	if fs.NArg() < 1 {
 		out.Error("source name is required", ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	name := fs.Arg(0)
 	if err := session.RemoveSkillSource(name); err != nil {
 		if errors.Is(err, session.ErrSkillSourceNotFound) {
 			out.Error(err.Error(), ErrCodeNotFound)
-			os.Exit(2)
+			exit(2)
 		}
 		out.Error(fmt.Sprintf("failed to remove source: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if *jsonOutput {
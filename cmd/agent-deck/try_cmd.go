@@ -55,7 +55,7 @@ func handleTry(profile string, args []string) {
 	args = reorderArgsForTryCommand(args)
 
 	if err := fs.Parse(args); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Get settings
@@ -82,14 +82,14 @@ func handleTry(profile string, args []string) {
 	name := fs.Arg(0)
 	if name == "" {
 		fs.Usage()
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Find or create experiment
 	exp, created, err := experiments.FindOrCreate(settings.Directory, name, settings.DatePrefix)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if *noSession {
@@ -112,7 +112,7 @@ func handleTry(profile string, args []string) {
 	storage, instances, _, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(err.Error(), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Check if session already exists for this path
@@ -122,7 +122,7 @@ func handleTry(profile string, args []string) {
 			if !inst.Exists() {
 				if err := inst.Start(); err != nil {
 					out.Error(fmt.Sprintf("starting session: %v", err), ErrCodeInvalidOperation)
-					os.Exit(1)
+					exit(1)
 				}
 				inst.PostStartSync(3 * time.Second)
 				// Save updated state with session ID
@@ -152,13 +152,14 @@ func handleTry(profile string, args []string) {
 	// Save using helper (rebuilds group tree including "experiments" group from instance)
 	if err := saveSessionData(storage, instances); err != nil {
 		out.Error(err.Error(), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
+	session.FireSessionHook(session.HookSessionCreated, newInst, nil)
 
 	// Start the session
 	if err := newInst.Start(); err != nil {
 		out.Error(fmt.Sprintf("starting session: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Capture session ID and re-save (first save at line above was before Start)
@@ -188,7 +189,7 @@ func handleTryList(dir, query string, jsonOutput bool) {
 	exps, err := experiments.ListExperiments(dir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if query != "" {
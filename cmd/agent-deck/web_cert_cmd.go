@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/web"
+)
+
+// handleWebCert dispatches web cert subcommands
+func handleWebCert(profile string, args []string) {
+	if len(args) == 0 {
+		printWebCertUsage()
+		return
+	}
+
+	switch args[0] {
+	case "export":
+		handleWebCertExport(profile, args[1:])
+	case "client":
+		handleWebCertClient(profile, args[1:])
+	case "help", "-h", "--help":
+		printWebCertUsage()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown web cert command: %s\n", args[0])
+		printWebCertUsage()
+		exit(1)
+	}
+}
+
+// printWebCertUsage prints help for web cert commands
+func printWebCertUsage() {
+	fmt.Println("Usage: agent-deck web cert <command> [options]")
+	fmt.Println()
+	fmt.Println("Manage the profile's web CA, used by 'agent-deck web --tls'. Install the CA")
+	fmt.Println("on phones and other browsers so they trust the web server (needed for the")
+	fmt.Println("PWA and web push over the network).")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  export          Write the CA certificate (stdout or --out)")
+	fmt.Println("  client <name>   Issue a client certificate for --tls-client-auth")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  agent-deck web cert export --out agent-deck-ca.crt")
+	fmt.Println("  agent-deck web cert export --der --out agent-deck-ca.cer")
+	fmt.Println("  agent-deck web cert client my-phone --out ./certs")
+}
+
+func handleWebCertExport(profile string, args []string) {
+	fs := flag.NewFlagSet("web cert export", flag.ExitOnError)
+	outPath := fs.String("out", "", "Write the certificate to this file instead of stdout")
+	der := fs.Bool("der", false, "Write binary DER instead of PEM (for devices that need it)")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck web cert export [options]")
+		fmt.Println()
+		fmt.Println("Export the profile's web CA certificate, creating the CA if needed.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+
+	out := NewCLIOutput(*jsonOutput, false)
+
+	ca, _, err := web.EnsureWebCA(profile)
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to prepare web CA: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	data := ca.CertPEM
+	if *der {
+		data = ca.Cert.Raw
+	}
+	sum := sha256.Sum256(ca.Cert.Raw)
+	fingerprint := formatCertFingerprint(sum[:])
+
+	if *outPath == "" && !*jsonOutput {
+		_, _ = os.Stdout.Write(data)
+		return
+	}
+	if *outPath != "" {
+		if err := os.WriteFile(*outPath, data, 0o644); err != nil {
+			out.Error(fmt.Sprintf("failed to write certificate: %v", err), ErrCodeInvalidOperation)
+			exit(1)
+		}
+	}
+
+	result := map[string]interface{}{
+		"ca_path":     ca.Path,
+		"subject":     ca.Cert.Subject.CommonName,
+		"fingerprint": fingerprint,
+		"expires_at":  ca.Cert.NotAfter,
+	}
+	if *outPath != "" {
+		result["path"] = *outPath
+	} else {
+		result["pem"] = string(ca.CertPEM)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s Wrote %s to %s\n", successSymbol, ca.Cert.Subject.CommonName, *outPath)
+	fmt.Fprintf(&b, "  SHA-256: %s\n", fingerprint)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "Install it as a trusted CA on each device:")
+	fmt.Fprintln(&b, "  iOS:     open the file, install the profile, then enable full trust in")
+	fmt.Fprintln(&b, "           Settings > General > About > Certificate Trust Settings")
+	fmt.Fprintln(&b, "  Android: Settings > Security > Encryption & credentials > Install a certificate > CA certificate")
+	out.Print(b.String(), result)
+}
+
+func handleWebCertClient(profile string, args []string) {
+	fs := flag.NewFlagSet("web cert client", flag.ExitOnError)
+	outDir := fs.String("out", ".", "Directory to write <name>.crt and <name>.key to")
+	days := fs.Int("days", 365, "Days the certificate is valid")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck web cert client <name> [options]")
+		fmt.Println()
+		fmt.Println("Issue a client certificate from the profile's web CA. Servers started with")
+		fmt.Println("--tls-client-auth only accept clients presenting such a certificate.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+
+	out := NewCLIOutput(*jsonOutput, false)
+
+	name := strings.TrimSpace(fs.Arg(0))
+	if name == "" {
+		fs.Usage()
+		exit(1)
+	}
+	if strings.ContainsAny(name, `/\`) {
+		out.Error("client name must not contain path separators", ErrCodeInvalidOperation)
+		exit(1)
+	}
+	if *days <= 0 {
+		out.Error("--days must be > 0", ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	certPEM, keyPEM, err := web.IssueWebClientCert(profile, name, time.Duration(*days)*24*time.Hour)
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to issue client certificate: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	if err := os.MkdirAll(*outDir, 0o700); err != nil {
+		out.Error(fmt.Sprintf("failed to create %s: %v", *outDir, err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	certPath := filepath.Join(*outDir, name+".crt")
+	keyPath := filepath.Join(*outDir, name+".key")
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		out.Error(fmt.Sprintf("failed to write key: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	if err := os.WriteFile(certPath, certPEM, 0o644); err != nil {
+		out.Error(fmt.Sprintf("failed to write certificate: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s Issued client certificate '%s' (valid %d days)\n", successSymbol, name, *days)
+	fmt.Fprintf(&b, "  Certificate: %s\n", certPath)
+	fmt.Fprintf(&b, "  Key:         %s\n", keyPath)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "Browsers and phones import a PKCS#12 bundle:")
+	fmt.Fprintf(&b, "  openssl pkcs12 -export -in %s -inkey %s -out %s\n", certPath, keyPath, filepath.Join(*outDir, name+".p12"))
+	out.Print(b.String(), map[string]interface{}{
+		"name":      name,
+		"cert_path": certPath,
+		"key_path":  keyPath,
+		"days":      *days,
+	})
+}
+
+// formatCertFingerprint renders a digest as colon-separated hex pairs.
+func formatCertFingerprint(sum []byte) string {
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
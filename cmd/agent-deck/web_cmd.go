@@ -1,12 +1,15 @@
 package main
 
 import (
+	"crypto/tls"
 	"errors"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 
 	"github.com/asheshgoplani/agent-deck/internal/session"
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
 	"github.com/asheshgoplani/agent-deck/internal/web"
 )
 
@@ -16,10 +19,18 @@ func buildWebServer(profile string, args []string, menuData web.MenuDataLoader)
 	fs := flag.NewFlagSet("web", flag.ContinueOnError)
 	listenAddr := fs.String("listen", "127.0.0.1:8420", "Listen address for web server")
 	readOnly := fs.Bool("read-only", false, "Run in read-only mode (input disabled)")
-	token := fs.String("token", "", "Bearer token for API/WS access")
+	token := fs.String("token", "", "Bearer token for API/WS access with every scope")
 	pushEnabled := fs.Bool("push", false, "Enable web push notifications (auto-generates VAPID keys per profile)")
 	pushVAPIDSubject := fs.String("push-vapid-subject", "mailto:agentdeck@localhost", "VAPID subject used for web push notifications")
 	pushTestEvery := fs.Duration("push-test-every", 0, "Send periodic push test notifications at this interval (e.g. 10s, 1m); 0 disables")
+	tlsEnabled := fs.Bool("tls", false, "Serve HTTPS with a certificate from the profile's CA (generated on first use)")
+	tlsCert := fs.String("tls-cert", "", "Serve HTTPS with this certificate file (PEM) instead; needs --tls-key")
+	tlsKey := fs.String("tls-key", "", "Private key file (PEM) for --tls-cert")
+	tlsHosts := fs.String("tls-host", "", "Extra comma-separated host names or IPs for the generated certificate")
+	tlsClientAuth := fs.Bool("tls-client-auth", false, "Require client certificates issued with 'web cert client' (implies --tls)")
+	tlsClientCA := fs.String("tls-client-ca", "", "Require client certificates signed by this CA bundle (PEM) instead (implies --tls)")
+	noFederation := fs.Bool("no-federation", false, "Serve only this profile, ignoring [federation] in config.toml")
+	terminalSize := fs.String("terminal-size", "smallest", "Size of a terminal several browsers watch: smallest, largest or owner (the input lock holder)")
 
 	fs.Usage = func() {
 		fmt.Println("Usage: agent-deck web [options]")
@@ -35,11 +46,26 @@ func buildWebServer(profile string, args []string, menuData web.MenuDataLoader)
 		fmt.Println("  agent-deck web --read-only")
 		fmt.Println("  agent-deck web --push")
 		fmt.Println("  agent-deck web --push --push-test-every 10s")
+		fmt.Println("  agent-deck web --listen 0.0.0.0:8420 --tls")
+		fmt.Println("  agent-deck web --tls-cert cert.pem --tls-key key.pem")
+		fmt.Println("  agent-deck web --tls --tls-client-auth")
+		fmt.Println("  agent-deck web --terminal-size owner")
+		fmt.Println()
+		fmt.Println("Access tokens (scoped, per profile):")
+		fmt.Println("  agent-deck web token create alice --scopes read --groups work")
+		fmt.Println("  agent-deck web token list|revoke|audit")
+		fmt.Println()
+		fmt.Println("Certificates (for --tls):")
+		fmt.Println("  agent-deck web cert export --out agent-deck-ca.crt")
+		fmt.Println("  agent-deck web cert client my-phone --out ./certs")
+		fmt.Println()
+		fmt.Println("Federation ([federation] in config.toml):")
+		fmt.Println("  agent-deck web federation             # check upstreams and profiles")
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(0)
+			exit(0)
 		}
 		return nil, fmt.Errorf("flag parsing: %w", err)
 	}
@@ -52,9 +78,35 @@ func buildWebServer(profile string, args []string, menuData web.MenuDataLoader)
 	if *pushTestEvery > 0 && !*pushEnabled {
 		return nil, fmt.Errorf("--push-test-every requires --push")
 	}
+	sizePolicy, err := web.ParseTerminalSizePolicy(*terminalSize)
+	if err != nil {
+		return nil, fmt.Errorf("--terminal-size: %w", err)
+	}
 
 	effectiveProfile := session.GetEffectiveProfile(profile)
 
+	var tlsConfig *tls.Config
+	if *tlsEnabled || *tlsCert != "" || *tlsKey != "" || *tlsClientAuth || *tlsClientCA != "" {
+		var generated bool
+		var err error
+		tlsConfig, generated, err = web.BuildTLSConfig(effectiveProfile, web.TLSOptions{
+			CertFile:     *tlsCert,
+			KeyFile:      *tlsKey,
+			Hosts:        append(splitCSV(*tlsHosts), webListenHosts(*listenAddr)...),
+			ClientAuth:   *tlsClientAuth,
+			ClientCAFile: *tlsClientCA,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare TLS: %w", err)
+		}
+		if generated {
+			fmt.Println("TLS: generated new certificates for profile; install the CA on devices with 'agent-deck web cert export'")
+		}
+		if tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert {
+			fmt.Println("TLS: client certificates required")
+		}
+	}
+
 	resolvedPushSubject := *pushVAPIDSubject
 	resolvedPushPublic := ""
 	resolvedPushPrivate := ""
@@ -72,17 +124,87 @@ func buildWebServer(profile string, args []string, menuData web.MenuDataLoader)
 		}
 	}
 
+	if !*noFederation {
+		fed, err := web.FederationFromSettings(effectiveProfile, menuData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid [federation] config: %w", err)
+		}
+		if fed != nil {
+			menuData = fed
+			fmt.Printf("Federation: %d source(s); check them with 'agent-deck web federation'\n", fed.SourceCount())
+		}
+	}
+
+	// Named tokens and share links live in the profile's state database,
+	// opened by the TUI
+	var tokens web.TokenStore
+	var shares web.ShareStore
+	if db := statedb.GetGlobal(); db != nil {
+		tokens = web.NewTokenStore(db)
+		shares = web.NewShareStore(db)
+		_ = db.SetMeta(web.ShareBaseURLMeta, webShareBaseURL(*listenAddr, tlsConfig != nil))
+	}
+
 	server := web.NewServer(web.Config{
 		ListenAddr:          *listenAddr,
 		Profile:             effectiveProfile,
 		ReadOnly:            *readOnly,
 		Token:               *token,
+		Tokens:              tokens,
+		Shares:              shares,
+		TerminalSize:        sizePolicy,
 		MenuData:            menuData,
 		PushVAPIDPublicKey:  resolvedPushPublic,
 		PushVAPIDPrivateKey: resolvedPushPrivate,
 		PushVAPIDSubject:    resolvedPushSubject,
 		PushTestInterval:    *pushTestEvery,
+		TLS:                 tlsConfig,
 	})
 
 	return server, nil
 }
+
+// webShareBaseURL returns the URL share links point at for a listen
+// address, naming the machine when listening on all interfaces.
+func webShareBaseURL(listenAddr string, tls bool) string {
+	scheme := "http"
+	if tls {
+		scheme = "https"
+	}
+	host, port, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		return scheme + "://" + listenAddr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		if name, err := os.Hostname(); err == nil && name != "" {
+			host = name
+		} else {
+			host = "127.0.0.1"
+		}
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
+}
+
+// webListenHosts returns the names the generated certificate must cover
+// for a listen address: its host, or every local interface address when
+// listening on all interfaces.
+func webListenHosts(listenAddr string) []string {
+	host, _, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		return nil
+	}
+	if ip := net.ParseIP(host); host != "" && (ip == nil || !ip.IsUnspecified()) {
+		return []string{host}
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var hosts []string
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && !ipNet.IP.IsLinkLocalUnicast() {
+			hosts = append(hosts, ipNet.IP.String())
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/session"
+	"github.com/asheshgoplani/agent-deck/internal/web"
+)
+
+// handleWebFederation shows the reachability of every federated source.
+func handleWebFederation(profile string, args []string) {
+	fs := flag.NewFlagSet("web federation", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	timeout := fs.Duration("timeout", 10*time.Second, "How long to wait for upstreams")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck web federation [options]")
+		fmt.Println()
+		fmt.Println("Check the local profiles and upstream web servers listed in [federation]")
+		fmt.Println("and show how many sessions each serves.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+
+	out := NewCLIOutput(*jsonOutput, false)
+
+	effectiveProfile := session.GetEffectiveProfile(profile)
+	fed, err := web.FederationFromSettings(effectiveProfile, web.NewSessionDataService(effectiveProfile))
+	if err != nil {
+		out.Error(fmt.Sprintf("invalid [federation] config: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	if fed == nil {
+		out.Error("no [federation] profiles or upstreams configured", ErrCodeNotFound)
+		exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	fed.Refresh(ctx)
+	statuses := fed.Statuses()
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tPROFILE\tSTATUS\tSESSIONS\tURL")
+	unreachable := 0
+	for _, st := range statuses {
+		state := successSymbol + " reachable"
+		if !st.Reachable {
+			state = errorSymbol + " " + st.Error
+			unreachable++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", st.Host, dashIfEmpty(st.Profile), state, st.Sessions, dashIfEmpty(st.URL))
+	}
+	_ = w.Flush()
+
+	out.Print(b.String(), map[string]interface{}{
+		"sources":     statuses,
+		"unreachable": unreachable,
+	})
+	if unreachable > 0 {
+		exit(1)
+	}
+}
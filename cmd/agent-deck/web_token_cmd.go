@@ -0,0 +1,399 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/session"
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+	"github.com/asheshgoplani/agent-deck/internal/web"
+)
+
+// handleWebToken dispatches web token subcommands
+func handleWebToken(profile string, args []string) {
+	if len(args) == 0 {
+		printWebTokenUsage()
+		return
+	}
+
+	switch args[0] {
+	case "create":
+		handleWebTokenCreate(profile, args[1:])
+	case "list", "ls":
+		handleWebTokenList(profile, args[1:])
+	case "revoke":
+		handleWebTokenRevoke(profile, args[1:])
+	case "audit":
+		handleWebTokenAudit(profile, args[1:])
+	case "help", "-h", "--help":
+		printWebTokenUsage()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown web token command: %s\n", args[0])
+		printWebTokenUsage()
+		exit(1)
+	}
+}
+
+// printWebTokenUsage prints help for web token commands
+func printWebTokenUsage() {
+	fmt.Println("Usage: agent-deck web token <command> [options]")
+	fmt.Println()
+	fmt.Println("Manage named access tokens for the web server. Once a profile has a token,")
+	fmt.Println("the web server refuses requests without a valid one. Tokens are stored hashed.")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  create <name>   Create a token and print it (shown only once)")
+	fmt.Println("  list            List tokens")
+	fmt.Println("  revoke <name>   Delete a token")
+	fmt.Println("  audit [name]    Show the audit log of changes and terminal input")
+	fmt.Println()
+	fmt.Printf("Scopes: %s\n", strings.Join(web.TokenScopes, ", "))
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  agent-deck web token create alice --scopes read --groups work --expires 72h")
+	fmt.Println("  agent-deck web token create me --scopes admin")
+	fmt.Println("  agent-deck web token list")
+	fmt.Println("  agent-deck web token revoke alice")
+	fmt.Println("  agent-deck web token audit alice --limit 20")
+}
+
+// openWebTokenDB opens the profile's state database, which holds its web
+// tokens and audit log. Close the returned storage when done.
+func openWebTokenDB(profile string) (*session.Storage, *statedb.StateDB, error) {
+	storage, err := session.NewStorageWithProfile(profile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open profile: %w", err)
+	}
+	db := storage.GetDB()
+	if db == nil {
+		storage.Close()
+		return nil, nil, errors.New("profile has no state database")
+	}
+	return storage, db, nil
+}
+
+// splitCSV splits a comma-separated flag value, dropping empty entries.
+func splitCSV(value string) []string {
+	var result []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+func handleWebTokenCreate(profile string, args []string) {
+	fs := flag.NewFlagSet("web token create", flag.ExitOnError)
+	scopes := fs.String("scopes", web.ScopeRead, "Comma-separated scopes ("+strings.Join(web.TokenScopes, ", ")+")")
+	groups := fs.String("groups", "", "Comma-separated groups the token is limited to (default: all)")
+	expires := fs.Duration("expires", 0, "Expire the token after this long (e.g. 24h); 0 never expires")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck web token create <name> [options]")
+		fmt.Println()
+		fmt.Println("Create a named web access token. The token is printed once; only its hash")
+		fmt.Println("is stored. Groups include their subgroups.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+
+	out := NewCLIOutput(*jsonOutput, false)
+
+	name := strings.TrimSpace(fs.Arg(0))
+	if name == "" {
+		fs.Usage()
+		exit(1)
+	}
+	scopeList := splitCSV(*scopes)
+	if len(scopeList) == 0 {
+		out.Error("at least one scope is required", ErrCodeInvalidOperation)
+		exit(1)
+	}
+	for _, scope := range scopeList {
+		if !web.IsTokenScope(scope) {
+			out.Error(fmt.Sprintf("unknown scope '%s' (one of: %s)", scope, strings.Join(web.TokenScopes, ", ")), ErrCodeInvalidOperation)
+			exit(1)
+		}
+	}
+	if *expires < 0 {
+		out.Error("--expires must be >= 0", ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	storage, db, err := openWebTokenDB(profile)
+	if err != nil {
+		out.Error(err.Error(), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	defer storage.Close()
+
+	secret, err := web.GenerateToken()
+	if err != nil {
+		out.Error(err.Error(), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	row := &statedb.WebTokenRow{
+		Name:      name,
+		Hash:      web.HashToken(secret),
+		Scopes:    scopeList,
+		Groups:    splitCSV(*groups),
+		CreatedAt: time.Now(),
+	}
+	if *expires > 0 {
+		row.ExpiresAt = row.CreatedAt.Add(*expires)
+	}
+	if err := db.CreateWebToken(row); err != nil {
+		if errors.Is(err, statedb.ErrWebTokenExists) {
+			out.Error(fmt.Sprintf("token '%s' already exists; revoke it first", name), ErrCodeAlreadyExists)
+		} else {
+			out.Error(fmt.Sprintf("failed to save token: %v", err), ErrCodeInvalidOperation)
+		}
+		exit(1)
+	}
+	_ = db.AppendWebAudit(&statedb.WebAuditRow{TokenName: name, Action: "token.create", Detail: "scopes=" + strings.Join(scopeList, ",")})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s Created token '%s' (%s)\n", successSymbol, name, describeWebToken(row))
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "  %s\n", secret)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "Store it now; it can't be shown again. Use it as ?token=... or an")
+	fmt.Fprintln(&b, "'Authorization: Bearer' header.")
+	data := webTokenJSON(row)
+	data["token"] = secret
+	out.Print(b.String(), data)
+}
+
+func handleWebTokenList(profile string, args []string) {
+	fs := flag.NewFlagSet("web token list", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck web token list [options]")
+		fmt.Println()
+		fmt.Println("List the profile's web access tokens.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+
+	out := NewCLIOutput(*jsonOutput, false)
+
+	storage, db, err := openWebTokenDB(profile)
+	if err != nil {
+		out.Error(err.Error(), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	defer storage.Close()
+
+	tokens, err := db.LoadWebTokens()
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to load tokens: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	list := make([]map[string]interface{}, 0, len(tokens))
+	var b strings.Builder
+	if len(tokens) == 0 {
+		b.WriteString("No web tokens. Create one with: agent-deck web token create <name>\n")
+	} else {
+		tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tSCOPES\tGROUPS\tEXPIRES\tLAST USED")
+		for _, tok := range tokens {
+			list = append(list, webTokenJSON(tok))
+			groups := "all"
+			if len(tok.Groups) > 0 {
+				groups = strings.Join(tok.Groups, ",")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", tok.Name, strings.Join(tok.Scopes, ","), groups,
+				formatWebTokenExpiry(tok.ExpiresAt), formatWebTokenTime(tok.LastUsedAt))
+		}
+		_ = tw.Flush()
+	}
+	out.Print(b.String(), map[string]interface{}{"tokens": list})
+}
+
+func handleWebTokenRevoke(profile string, args []string) {
+	fs := flag.NewFlagSet("web token revoke", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck web token revoke <name> [options]")
+		fmt.Println()
+		fmt.Println("Delete a web access token. Requests using it are refused right away.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+
+	out := NewCLIOutput(*jsonOutput, false)
+
+	name := strings.TrimSpace(fs.Arg(0))
+	if name == "" {
+		fs.Usage()
+		exit(1)
+	}
+
+	storage, db, err := openWebTokenDB(profile)
+	if err != nil {
+		out.Error(err.Error(), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	defer storage.Close()
+
+	found, err := db.DeleteWebToken(name)
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to revoke token: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	if !found {
+		out.Error(fmt.Sprintf("token '%s' not found", name), ErrCodeNotFound)
+		exit(2)
+	}
+	_ = db.AppendWebAudit(&statedb.WebAuditRow{TokenName: name, Action: "token.revoke"})
+
+	out.Success(fmt.Sprintf("Revoked token '%s'", name), map[string]interface{}{"name": name, "revoked": true})
+}
+
+func handleWebTokenAudit(profile string, args []string) {
+	fs := flag.NewFlagSet("web token audit", flag.ExitOnError)
+	limit := fs.Int("limit", 50, "Show at most this many entries (0 for all)")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck web token audit [name] [options]")
+		fmt.Println()
+		fmt.Println("Show the web audit log, oldest first: every request that changed state,")
+		fmt.Println("every terminal input session and token changes, with the token used.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+
+	out := NewCLIOutput(*jsonOutput, false)
+
+	storage, db, err := openWebTokenDB(profile)
+	if err != nil {
+		out.Error(err.Error(), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	defer storage.Close()
+
+	entries, err := db.LoadWebAudit(fs.Arg(0), *limit)
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to load audit log: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	type auditEntry struct {
+		Time       time.Time `json:"time"`
+		Token      string    `json:"token"`
+		Action     string    `json:"action"`
+		Method     string    `json:"method,omitempty"`
+		Path       string    `json:"path,omitempty"`
+		SessionID  string    `json:"session_id,omitempty"`
+		RemoteAddr string    `json:"remote_addr,omitempty"`
+		Status     int       `json:"status,omitempty"`
+		Detail     string    `json:"detail,omitempty"`
+	}
+	list := make([]auditEntry, 0, len(entries))
+	var b strings.Builder
+	if len(entries) == 0 {
+		b.WriteString("No audit entries\n")
+	} else {
+		tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TIME\tTOKEN\tACTION\tTARGET\tFROM\tDETAIL")
+		for _, e := range entries {
+			list = append(list, auditEntry{
+				Time: e.Timestamp, Token: e.TokenName, Action: e.Action, Method: e.Method, Path: e.Path,
+				SessionID: e.SessionID, RemoteAddr: e.RemoteAddr, Status: e.Status, Detail: e.Detail,
+			})
+			target := e.SessionID
+			if e.Action == "request" {
+				target = fmt.Sprintf("%s %s -> %d", e.Method, e.Path, e.Status)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Timestamp.Format("2006-01-02 15:04:05"), e.TokenName, e.Action,
+				dashIfEmpty(target), dashIfEmpty(e.RemoteAddr), e.Detail)
+		}
+		_ = tw.Flush()
+	}
+	out.Print(b.String(), map[string]interface{}{"entries": list})
+}
+
+// describeWebToken summarizes a token's scopes, groups and expiry.
+func describeWebToken(tok *statedb.WebTokenRow) string {
+	parts := []string{"scopes: " + strings.Join(tok.Scopes, ",")}
+	if len(tok.Groups) > 0 {
+		parts = append(parts, "groups: "+strings.Join(tok.Groups, ","))
+	}
+	if !tok.ExpiresAt.IsZero() {
+		parts = append(parts, "expires "+tok.ExpiresAt.Format("2006-01-02 15:04"))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func webTokenJSON(tok *statedb.WebTokenRow) map[string]interface{} {
+	data := map[string]interface{}{
+		"name":       tok.Name,
+		"scopes":     tok.Scopes,
+		"groups":     append([]string{}, tok.Groups...),
+		"created_at": tok.CreatedAt,
+		"expired":    !tok.ExpiresAt.IsZero() && time.Now().After(tok.ExpiresAt),
+	}
+	if !tok.ExpiresAt.IsZero() {
+		data["expires_at"] = tok.ExpiresAt
+	}
+	if !tok.LastUsedAt.IsZero() {
+		data["last_used_at"] = tok.LastUsedAt
+	}
+	return data
+}
+
+func formatWebTokenExpiry(t time.Time) string {
+	switch {
+	case t.IsZero():
+		return "never"
+	case time.Now().After(t):
+		return "expired"
+	default:
+		return t.Format("2006-01-02 15:04")
+	}
+}
+
+func formatWebTokenTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/forge"
+	"github.com/asheshgoplani/agent-deck/internal/git"
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+// handleWorkspace dispatches workspace subcommands
+func handleWorkspace(profile string, args []string) {
+	if len(args) == 0 {
+		printWorkspaceUsage()
+		return
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		handleWorkspaceList(profile, args[1:])
+	case "finish":
+		handleWorkspaceFinish(profile, args[1:])
+	case "help", "-h", "--help":
+		printWorkspaceUsage()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown workspace command: %s\n", args[0])
+		printWorkspaceUsage()
+		exit(1)
+	}
+}
+
+// printWorkspaceUsage prints help for workspace commands
+func printWorkspaceUsage() {
+	fmt.Println("Usage: agent-deck workspace <command> [options]")
+	fmt.Println()
+	fmt.Println("Work on several repositories in one session. Workspaces are defined in")
+	fmt.Println("config.toml under [workspaces.<name>] and started with launch --workspace.")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  list              List workspaces and their sessions")
+	fmt.Println("  finish <session>  Merge (or open PRs for) every repo, then remove worktrees and session")
+	fmt.Println()
+	fmt.Println("Global Options:")
+	fmt.Println("  -p, --profile <name>   Use specific profile")
+	fmt.Println("  --json                 Output as JSON")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  agent-deck launch --workspace shop -c claude -w feat/cart -b")
+	fmt.Println("  agent-deck workspace list")
+	fmt.Println("  agent-deck workspace finish shop")
+	fmt.Println("  agent-deck workspace finish shop --pr")
+}
+
+// handleWorkspaceList lists the [workspaces] definitions and their sessions
+func handleWorkspaceList(profile string, args []string) {
+	fs := flag.NewFlagSet("workspace list", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck workspace list [options]")
+		fmt.Println()
+		fmt.Println("List the workspaces defined in config.toml and the sessions using them.")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+
+	out := NewCLIOutput(*jsonOutput, false)
+
+	_, instances, _, err := loadSessionData(profile)
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	type workspaceInfo struct {
+		Name     string   `json:"name"`
+		Repos    []string `json:"repos"`
+		Paths    []string `json:"paths,omitempty"`
+		Group    string   `json:"group,omitempty"`
+		Sessions []string `json:"sessions,omitempty"`
+	}
+
+	results := []workspaceInfo{}
+	for _, name := range session.GetWorkspaceNames() {
+		def := session.GetWorkspaceDef(name)
+		info := workspaceInfo{Name: name, Repos: def.Repos, Paths: def.Paths, Group: def.Group}
+		for _, inst := range instances {
+			if inst.Workspace != nil && inst.Workspace.Name == name {
+				info.Sessions = append(info.Sessions, inst.Title)
+			}
+		}
+		results = append(results, info)
+	}
+
+	if *jsonOutput {
+		out.Print("", map[string]interface{}{"workspaces": results})
+		return
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No workspaces defined. Add one to config.toml:")
+		fmt.Println()
+		fmt.Println("  [workspaces.shop]")
+		fmt.Println("  repos = [\"~/src/shop-api\", \"~/src/shop-web\"]")
+		return
+	}
+	for i, ws := range results {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Print(ws.Name)
+		if ws.Group != "" {
+			fmt.Printf("  (group: %s)", ws.Group)
+		}
+		fmt.Println()
+		for _, repo := range ws.Repos {
+			fmt.Printf("  repo:     %s\n", FormatPath(repo))
+		}
+		for _, path := range ws.Paths {
+			fmt.Printf("  path:     %s\n", FormatPath(path))
+		}
+		if len(ws.Sessions) > 0 {
+			fmt.Printf("  sessions: %s\n", strings.Join(ws.Sessions, ", "))
+		}
+	}
+}
+
+// workspaceRepoPlan is what finishing one repo of a workspace will do,
+// worked out before anything is changed.
+type workspaceRepoPlan struct {
+	Repo     session.WorkspaceRepo
+	Target   string                       // Branch to merge into / open the PR against
+	Hooks    session.WorktreeHookSettings // pre_finish / post_finish
+	Provider forge.Provider               // Set with --pr
+	Remote   string                       // Remote the branch is pushed to with --pr
+}
+
+// workspaceRepoResult reports how finishing one repo went.
+type workspaceRepoResult struct {
+	Repo    string `json:"repo"`
+	Path    string `json:"path"`
+	Branch  string `json:"branch"`
+	Target  string `json:"target,omitempty"`
+	Action  string `json:"action"` // merged, pr, unchanged or failed
+	Commits int    `json:"commits"`
+	PRURL   string `json:"pr_url,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// finishWorkspaceRepo merges the workspace branch into the plan's target, or
+// with a provider pushes it and opens a pull request. Repos without new
+// commits are left alone. Worktrees and branches are not touched here.
+func finishWorkspaceRepo(ctx context.Context, plan workspaceRepoPlan, branch, prTitle, prBody string) workspaceRepoResult {
+	repo := plan.Repo
+	res := workspaceRepoResult{Repo: repo.Name(), Path: repo.RepoRoot, Branch: branch, Target: plan.Target}
+	fail := func(err error) workspaceRepoResult {
+		res.Action, res.Error = "failed", err.Error()
+		return res
+	}
+
+	ahead, err := git.CommitsAhead(repo.RepoRoot, plan.Target, branch)
+	if err != nil {
+		return fail(err)
+	}
+	res.Commits = ahead
+	if ahead == 0 {
+		res.Action = "unchanged"
+		return res
+	}
+
+	if plan.Provider != nil {
+		if err := git.PushBranch(repo.RepoRoot, plan.Remote, branch); err != nil {
+			return fail(err)
+		}
+		pr, err := plan.Provider.CreatePullRequest(ctx, forge.NewPullRequest{
+			Title: prTitle,
+			Body:  prBody,
+			Head:  branch,
+			Base:  plan.Target,
+		})
+		if err != nil {
+			return fail(err)
+		}
+		res.Action, res.PRURL = "pr", pr.URL
+		return res
+	}
+
+	checkout := exec.Command("git", "-C", repo.RepoRoot, "checkout", plan.Target)
+	if output, err := checkout.CombinedOutput(); err != nil {
+		return fail(fmt.Errorf("failed to checkout %s: %s", plan.Target, strings.TrimSpace(string(output))))
+	}
+	if err := git.MergeBranch(repo.RepoRoot, branch); err != nil {
+		// Abort the merge to leave things clean
+		_ = exec.Command("git", "-C", repo.RepoRoot, "merge", "--abort").Run()
+		return fail(fmt.Errorf("merge failed (aborted): %w", err))
+	}
+	res.Action = "merged"
+	return res
+}
+
+// workspacePRBody describes a pull request that is one part of a change
+// spanning the workspace's repos.
+func workspacePRBody(ws *session.WorkspaceState) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Part of a change across the repositories of workspace %s (branch %s):\n", ws.Name, ws.Branch)
+	for _, repo := range ws.Repos {
+		fmt.Fprintf(&b, "\n- %s", repo.Name())
+		if repo.PRURL != "" {
+			fmt.Fprintf(&b, ": %s", repo.PRURL)
+		}
+	}
+	return b.String()
+}
+
+// handleWorkspaceFinish merges or opens pull requests for the branch of a
+// workspace session in each repo, then removes the worktrees and the
+// session. Every repo is checked first; if a merge or PR fails, the repos
+// that succeeded are recorded and everything is kept for a retry.
+func handleWorkspaceFinish(profile string, args []string) {
+	fs := flag.NewFlagSet("workspace finish", flag.ExitOnError)
+	into := fs.String("into", "", "Target branch in every repo (default: each repo's default branch)")
+	openPR := fs.Bool("pr", false, "Push the branch and open a pull request per repo instead of merging (see [forge])")
+	keepBranch := fs.Bool("keep-branch", false, "Don't delete local branches after finish")
+	force := fs.Bool("force", false, "Skip safety checks and force branch deletion")
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+
+	fs.Usage = func() {
+		fmt.Println("Usage: agent-deck workspace finish <session> [options]")
+		fmt.Println()
+		fmt.Println("Merge the workspace branch in every repo (or open a pull request per repo")
+		fmt.Println("with --pr), remove the worktrees, and delete the session. Repos without")
+		fmt.Println("new commits are skipped. Results are reported per repo.")
+		fmt.Println()
+		fmt.Println("Arguments:")
+		fmt.Println("  session    Session title, ID prefix, or path")
+		fmt.Println()
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  agent-deck workspace finish shop")
+		fmt.Println("  agent-deck workspace finish shop --into develop")
+		fmt.Println("  agent-deck workspace finish shop --pr --json")
+	}
+
+	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
+		exit(1)
+	}
+
+	identifier := fs.Arg(0)
+	out := NewCLIOutput(*jsonOutput, false)
+
+	if identifier == "" {
+		out.Error("session identifier is required", ErrCodeNotFound)
+		fmt.Println()
+		fs.Usage()
+		exit(1)
+	}
+
+	storage, instances, _, err := loadSessionData(profile)
+	if err != nil {
+		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	inst, errMsg, errCode := ResolveSessionOrCurrent(identifier, instances)
+	if inst == nil {
+		out.Error(errMsg, errCode)
+		exit(1)
+		return
+	}
+
+	ws := inst.Workspace
+	if ws == nil {
+		out.Error(fmt.Sprintf("session '%s' was not created from a workspace (use: agent-deck worktree finish)", inst.Title), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	if ws.Branch == "" {
+		out.Error(fmt.Sprintf("session '%s' uses the workspace repos in place; there is no branch to finish (use: agent-deck remove)", inst.Title), ErrCodeInvalidOperation)
+		exit(1)
+	}
+
+	// Plan every repo before changing any of them
+	var plans []workspaceRepoPlan
+	for _, repo := range ws.Repos {
+		plan := workspaceRepoPlan{Repo: repo, Target: *into}
+		fail := func(msg string) {
+			out.Error(fmt.Sprintf("%s: %s", repo.Name(), msg), ErrCodeInvalidOperation)
+			exit(1)
+		}
+		if plan.Hooks, err = session.LoadWorktreeHooks(repo.RepoRoot); err != nil {
+			fail(err.Error())
+		}
+		if repo.Finished != "" {
+			plans = append(plans, plan)
+			continue
+		}
+		if _, statErr := os.Stat(repo.WorktreePath); statErr == nil && !*force {
+			dirty, err := git.HasUncommittedChanges(repo.WorktreePath)
+			if err != nil {
+				fail(fmt.Sprintf("failed to check worktree status: %v", err))
+			}
+			if dirty {
+				fail("worktree has uncommitted changes (use --force to override)")
+			}
+		}
+		if plan.Target == "" {
+			if plan.Target, err = git.GetDefaultBranch(repo.RepoRoot); err != nil {
+				fail(fmt.Sprintf("could not determine target branch: %v\nUse --into <branch> to specify", err))
+			}
+		}
+		if plan.Target == ws.Branch {
+			fail(fmt.Sprintf("cannot merge branch '%s' into itself", ws.Branch))
+		}
+		if *openPR {
+			if plan.Provider, plan.Remote, err = newForgeProvider(repo.RepoRoot); err != nil {
+				fail(err.Error())
+			}
+		}
+		plans = append(plans, plan)
+	}
+
+	// Show summary and confirm
+	if !*force && !*jsonOutput {
+		fmt.Printf("Session:   %s\n", inst.Title)
+		fmt.Printf("Workspace: %s (branch %s)\n", ws.Name, ws.Branch)
+		for _, plan := range plans {
+			switch {
+			case plan.Repo.Finished != "":
+				fmt.Printf("  %-12s already %s\n", plan.Repo.Name(), plan.Repo.Finished)
+			case *openPR:
+				fmt.Printf("  %-12s pull request %s → %s (%s)\n", plan.Repo.Name(), ws.Branch, plan.Target, plan.Provider.Name())
+			default:
+				fmt.Printf("  %-12s merge %s → %s\n", plan.Repo.Name(), ws.Branch, plan.Target)
+			}
+		}
+		if *keepBranch || *openPR {
+			fmt.Printf("Branch:    kept\n")
+		} else {
+			fmt.Printf("Delete:    branch '%s' will be deleted in every repo\n", ws.Branch)
+		}
+		fmt.Println()
+		fmt.Print("Proceed? [y/N]: ")
+
+		reader := bufio.NewReader(os.Stdin)
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			fmt.Println("Aborted.")
+			return
+		}
+		fmt.Println()
+	}
+
+	// Step 0: pre_finish hooks (a failure aborts before anything is merged)
+	for _, plan := range plans {
+		if plan.Repo.Finished != "" || len(plan.Hooks.PreFinish) == 0 {
+			continue
+		}
+		if _, statErr := os.Stat(plan.Repo.WorktreePath); statErr != nil {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Running pre-finish hooks in %s...\n", plan.Repo.Name())
+		env := session.WorktreeHookEnv{RepoRoot: plan.Repo.RepoRoot, WorktreePath: plan.Repo.WorktreePath, Branch: ws.Branch, SessionID: inst.ID}
+		if err := session.RunPreFinishHooks(plan.Hooks, env, worktreeProgress(*jsonOutput)); err != nil {
+			out.Error(fmt.Sprintf("%s: %v\nFix the problem or remove the hook, then finish again", plan.Repo.Name(), err), ErrCodeInvalidOperation)
+			exit(1)
+		}
+	}
+
+	// Step 1: merge or open a pull request in each repo
+	results := make([]workspaceRepoResult, 0, len(plans))
+	failed := false
+	for n, plan := range plans {
+		repo := plan.Repo
+		if repo.Finished != "" {
+			results = append(results, workspaceRepoResult{
+				Repo: repo.Name(), Path: repo.RepoRoot, Branch: ws.Branch,
+				Action: repo.Finished, PRURL: repo.PRURL,
+			})
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		res := finishWorkspaceRepo(ctx, plan, ws.Branch, inst.Title, workspacePRBody(ws))
+		cancel()
+		results = append(results, res)
+		if !*jsonOutput {
+			printWorkspaceRepoResult(res)
+		}
+		switch res.Action {
+		case "failed":
+			failed = true
+		case "merged", "pr":
+			ws.Repos[n].Finished, ws.Repos[n].PRURL = res.Action, res.PRURL
+		}
+	}
+
+	if failed {
+		// Keep the session and every worktree; finished repos are skipped next time
+		if err := saveSessionData(storage, instances); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save session data: %v\n", err)
+		}
+		if *jsonOutput {
+			out.Print("", map[string]interface{}{
+				"success":    false,
+				"session":    inst.Title,
+				"session_id": inst.ID,
+				"workspace":  ws.Name,
+				"branch":     ws.Branch,
+				"repos":      results,
+			})
+		} else {
+			fmt.Printf("\n%s Not all repos finished; worktrees and session kept. Fix the failures and run finish again.\n", errorSymbol)
+		}
+		exit(1)
+	}
+
+	// Step 2: remove worktrees, checkpoints and branches
+	deleteBranches := !*keepBranch && !*openPR
+	for _, plan := range plans {
+		repo := plan.Repo
+		if _, statErr := os.Stat(repo.WorktreePath); !os.IsNotExist(statErr) {
+			if err := git.RemoveWorktree(repo.RepoRoot, repo.WorktreePath, *force); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %s: failed to remove worktree: %v\n", repo.Name(), err)
+			}
+		}
+		_ = git.PruneWorktrees(repo.RepoRoot)
+		if _, err := git.DeleteCheckpoints(repo.RepoRoot, inst.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: failed to delete checkpoints: %v\n", repo.Name(), err)
+		}
+		if deleteBranches {
+			if err := git.DeleteBranch(repo.RepoRoot, ws.Branch, *force); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %s: failed to delete branch: %v\n", repo.Name(), err)
+			}
+		}
+	}
+
+	// Step 3: Kill tmux session
+	if inst.Exists() {
+		if err := inst.Kill(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to kill tmux session: %v\n", err)
+		}
+	}
+
+	// Step 4: post_finish hooks (teardown; failures are only reported)
+	var postFinishErrs []string
+	for _, plan := range plans {
+		if len(plan.Hooks.PostFinish) == 0 {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Running post-finish hooks in %s...\n", plan.Repo.Name())
+		env := session.WorktreeHookEnv{RepoRoot: plan.Repo.RepoRoot, WorktreePath: plan.Repo.WorktreePath, Branch: ws.Branch, SessionID: inst.ID}
+		if err := session.RunPostFinishHooks(plan.Hooks, env, worktreeProgress(*jsonOutput)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", plan.Repo.Name(), err)
+			postFinishErrs = append(postFinishErrs, fmt.Sprintf("%s: %v", plan.Repo.Name(), err))
+		}
+	}
+
+	// Step 5: Remove session from agent-deck
+	var remaining []*session.Instance
+	for _, i := range instances {
+		if i.ID != inst.ID {
+			remaining = append(remaining, i)
+		}
+	}
+	if err := saveSessionData(storage, remaining); err != nil {
+		out.Error(fmt.Sprintf("failed to save session data: %v", err), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	session.FireSessionHook(session.HookWorktreeFinished, inst, map[string]string{
+		"branch":         ws.Branch,
+		"workspace":      ws.Name,
+		"pull_requests":  strconv.FormatBool(*openPR),
+		"branch_deleted": strconv.FormatBool(deleteBranches),
+	})
+	session.FireSessionHook(session.HookSessionDeleted, inst, nil)
+
+	if *jsonOutput {
+		data := map[string]interface{}{
+			"success":          true,
+			"session":          inst.Title,
+			"session_id":       inst.ID,
+			"workspace":        ws.Name,
+			"branch":           ws.Branch,
+			"branches_deleted": deleteBranches,
+			"repos":            results,
+		}
+		if len(postFinishErrs) > 0 {
+			data["post_finish_errors"] = postFinishErrs
+		}
+		out.Print("", data)
+	} else {
+		fmt.Printf("\n%s Finished: session '%s' removed, %d worktree(s) cleaned up\n", successSymbol, inst.Title, len(plans))
+	}
+}
+
+// printWorkspaceRepoResult prints one repo's finish result
+func printWorkspaceRepoResult(res workspaceRepoResult) {
+	switch res.Action {
+	case "merged":
+		fmt.Printf("  %s %-12s merged %d commit(s) into %s\n", successSymbol, res.Repo, res.Commits, res.Target)
+	case "pr":
+		fmt.Printf("  %s %-12s pull request opened: %s\n", successSymbol, res.Repo, res.PRURL)
+	case "unchanged":
+		fmt.Printf("  %s %-12s no new commits\n", successSymbol, res.Repo)
+	default:
+		fmt.Printf("  %s %-12s %s\n", errorSymbol, res.Repo, res.Error)
+	}
+}
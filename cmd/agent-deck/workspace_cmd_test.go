@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asheshgoplani/agent-deck/internal/forge"
+	"github.com/asheshgoplani/agent-deck/internal/git"
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+// workspaceRepo creates a repo on main with a feat branch checked out in a
+// worktree, and commits files (name → content) to feat.
+func workspaceRepo(t *testing.T, files map[string]string) session.WorkspaceRepo {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "repo")
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, root, "init", "-q", "-b", "main")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "init")
+	wt := root + "-feat"
+	runGit(t, root, "worktree", "add", "-q", "-b", "feat", wt)
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(wt, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, wt, "add", name)
+		runGit(t, wt, "commit", "-q", "-m", "add "+name)
+	}
+	return session.WorkspaceRepo{RepoRoot: root, WorktreePath: wt}
+}
+
+func TestFinishWorkspaceRepo_Merge(t *testing.T) {
+	ctx := context.Background()
+
+	changed := workspaceRepo(t, map[string]string{"api.go": "package api\n"})
+	res := finishWorkspaceRepo(ctx, workspaceRepoPlan{Repo: changed, Target: "main"}, "feat", "", "")
+	if res.Action != "merged" || res.Commits != 1 {
+		t.Fatalf("changed repo = %+v", res)
+	}
+	if _, err := os.Stat(filepath.Join(changed.RepoRoot, "api.go")); err != nil {
+		t.Errorf("merged file missing: %v", err)
+	}
+
+	untouched := workspaceRepo(t, nil)
+	res = finishWorkspaceRepo(ctx, workspaceRepoPlan{Repo: untouched, Target: "main"}, "feat", "", "")
+	if res.Action != "unchanged" {
+		t.Errorf("untouched repo = %+v", res)
+	}
+
+	// A conflicting change on main makes the merge fail and abort cleanly
+	conflict := workspaceRepo(t, map[string]string{"web.txt": "feat\n"})
+	if err := os.WriteFile(filepath.Join(conflict.RepoRoot, "web.txt"), []byte("main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, conflict.RepoRoot, "add", "web.txt")
+	runGit(t, conflict.RepoRoot, "commit", "-q", "-m", "main change")
+	res = finishWorkspaceRepo(ctx, workspaceRepoPlan{Repo: conflict, Target: "main"}, "feat", "", "")
+	if res.Action != "failed" || !strings.Contains(res.Error, "merge failed") {
+		t.Errorf("conflicting repo = %+v", res)
+	}
+	if dirty, _ := git.HasUncommittedChanges(conflict.RepoRoot); dirty {
+		t.Error("aborted merge left changes behind")
+	}
+}
+
+func TestFinishWorkspaceRepo_PullRequest(t *testing.T) {
+	var opened map[string]string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/acme/web/pulls" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&opened)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"number":5,"html_url":"https://github.com/acme/web/pull/5"}`))
+	}))
+	t.Cleanup(api.Close)
+	provider, err := forge.New("https://github.com/acme/web.git", forge.Options{APIURL: api.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := workspaceRepo(t, map[string]string{"web.txt": "feat\n"})
+	remote := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("init remote: %v: %s", err, out)
+	}
+	runGit(t, repo.RepoRoot, "remote", "add", "origin", remote)
+
+	ws := &session.WorkspaceState{Name: "shop", Branch: "feat", Repos: []session.WorkspaceRepo{
+		{RepoRoot: "/src/api", PRURL: "https://github.com/acme/api/pull/4"}, repo,
+	}}
+	plan := workspaceRepoPlan{Repo: repo, Target: "main", Provider: provider, Remote: "origin"}
+	res := finishWorkspaceRepo(context.Background(), plan, "feat", "Cart", workspacePRBody(ws))
+	if res.Action != "pr" || res.PRURL != "https://github.com/acme/web/pull/5" {
+		t.Fatalf("result = %+v", res)
+	}
+	if !git.BranchExists(remote, "feat") {
+		t.Error("branch was not pushed")
+	}
+	if opened["head"] != "feat" || opened["base"] != "main" || opened["title"] != "Cart" {
+		t.Errorf("opened = %v", opened)
+	}
+	if !strings.Contains(opened["body"], "- api: https://github.com/acme/api/pull/4") {
+		t.Errorf("body does not link the other repos: %q", opened["body"])
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/asheshgoplani/agent-deck/internal/git"
@@ -33,7 +34,7 @@ func handleWorktree(profile string, args []string) {
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown worktree command: %s\n", args[0])
 		printWorktreeUsage()
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -79,7 +80,7 @@ func handleWorktreeList(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	out := NewCLIOutput(*jsonOutput, false)
@@ -88,34 +89,34 @@ func handleWorktreeList(profile string, args []string) {
 	cwd, err := os.Getwd()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to get current directory: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Check if in a git repo
 	if !git.IsGitRepo(cwd) {
 		out.Error("not in a git repository", ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Get repo root (resolve through worktrees to prevent nesting)
 	repoRoot, err := git.GetWorktreeBaseRoot(cwd)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to get repo root: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// List worktrees
 	worktrees, err := git.ListWorktrees(repoRoot)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to list worktrees: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Load sessions
 	_, instances, _, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Build session map: path -> session title
@@ -210,7 +211,7 @@ func handleWorktreeInfo(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	identifier := fs.Arg(0)
@@ -220,28 +221,28 @@ func handleWorktreeInfo(profile string, args []string) {
 		out.Error("session identifier is required", ErrCodeNotFound)
 		fmt.Println()
 		fs.Usage()
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Load sessions
 	_, instances, _, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Resolve session
 	inst, errMsg, errCode := ResolveSession(identifier, instances)
 	if inst == nil {
 		out.Error(errMsg, errCode)
-		os.Exit(1)
+		exit(1)
 		return // unreachable, satisfies staticcheck SA5011
 	}
 
 	// Check if session has worktree info
 	if !inst.IsWorktree() {
 		out.Error(fmt.Sprintf("session '%s' is not in a worktree", inst.Title), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Check if worktree still exists
@@ -298,7 +299,7 @@ func handleWorktreeCleanup(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	out := NewCLIOutput(*jsonOutput, false)
@@ -307,7 +308,7 @@ func handleWorktreeCleanup(profile string, args []string) {
 	storage, instances, _, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeNotFound)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Find orphaned sessions (WorktreePath set but directory doesn't exist)
@@ -324,7 +325,7 @@ func handleWorktreeCleanup(profile string, args []string) {
 	cwd, err := os.Getwd()
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to get current directory: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Find orphaned worktrees (exist but no session points to them)
@@ -462,7 +463,7 @@ func handleWorktreeCleanup(profile string, args []string) {
 		// Save updated session data (uses existing saveSessionData which rebuilds GroupTree)
 		if err := saveSessionData(storage, remaining); err != nil {
 			out.Error(fmt.Sprintf("failed to save session data: %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -509,7 +510,7 @@ func handleWorktreeFinish(profile string, args []string) {
 	}
 
 	if err := fs.Parse(normalizeArgs(fs, args)); err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 
 	identifier := fs.Arg(0)
@@ -519,28 +520,32 @@ func handleWorktreeFinish(profile string, args []string) {
 		out.Error("session identifier is required", ErrCodeNotFound)
 		fmt.Println()
 		fs.Usage()
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Load sessions
 	storage, instances, _, err := loadSessionData(profile)
 	if err != nil {
 		out.Error(fmt.Sprintf("failed to load sessions: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Resolve session
 	inst, errMsg, errCode := ResolveSessionOrCurrent(identifier, instances)
 	if inst == nil {
 		out.Error(errMsg, errCode)
-		os.Exit(1)
+		exit(1)
 		return
 	}
 
 	// Validate it's a worktree session
 	if !inst.IsWorktree() {
 		out.Error(fmt.Sprintf("session '%s' is not in a worktree", inst.Title), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
+	}
+	if inst.Workspace != nil {
+		out.Error(fmt.Sprintf("session '%s' spans the repos of workspace %s; finish it with: agent-deck workspace finish", inst.Title, inst.Workspace.Name), ErrCodeInvalidOperation)
+		exit(1)
 	}
 
 	repoRoot := inst.WorktreeRepoRoot
@@ -557,12 +562,12 @@ func handleWorktreeFinish(profile string, args []string) {
 				dirty = false
 			} else {
 				out.Error(fmt.Sprintf("failed to check worktree status: %v", err), ErrCodeInvalidOperation)
-				os.Exit(1)
+				exit(1)
 			}
 		}
 		if dirty {
 			out.Error("worktree has uncommitted changes (use --force to override)", ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
@@ -572,14 +577,14 @@ func handleWorktreeFinish(profile string, args []string) {
 		targetBranch, err = git.GetDefaultBranch(repoRoot)
 		if err != nil {
 			out.Error(fmt.Sprintf("could not determine target branch: %v\nUse --into <branch> to specify", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 	}
 
 	// Validate target != source
 	if !*noMerge && targetBranch == worktreeBranch {
 		out.Error(fmt.Sprintf("cannot merge branch '%s' into itself", worktreeBranch), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Show summary and confirm
@@ -610,6 +615,23 @@ func handleWorktreeFinish(profile string, args []string) {
 		fmt.Println()
 	}
 
+	// Worktree hooks from [worktree] / .agent-deck/worktree.toml
+	hooks, err := session.LoadWorktreeHooks(repoRoot)
+	if err != nil {
+		out.Error(err.Error(), ErrCodeInvalidOperation)
+		exit(1)
+	}
+	hookEnv := session.WorktreeHookEnv{RepoRoot: repoRoot, WorktreePath: worktreePath, Branch: worktreeBranch, SessionID: inst.ID}
+
+	// Step 0: pre_finish hooks (a failure aborts before anything is changed)
+	if _, statErr := os.Stat(worktreePath); statErr == nil && len(hooks.PreFinish) > 0 {
+		fmt.Fprintln(os.Stderr, "Running pre-finish hooks...")
+		if err := session.RunPreFinishHooks(hooks, hookEnv, worktreeProgress(*jsonOutput)); err != nil {
+			out.Error(fmt.Sprintf("%v\nFix the problem or remove the hook, then finish again", err), ErrCodeInvalidOperation)
+			exit(1)
+		}
+	}
+
 	// Step 1: Merge (if requested)
 	if !*noMerge {
 		fmt.Printf("Merging %s into %s...\n", worktreeBranch, targetBranch)
@@ -619,7 +641,7 @@ func handleWorktreeFinish(profile string, args []string) {
 		checkoutOutput, err := cmd.CombinedOutput()
 		if err != nil {
 			out.Error(fmt.Sprintf("failed to checkout %s: %s", targetBranch, strings.TrimSpace(string(checkoutOutput))), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 
 		// Merge the worktree branch
@@ -628,7 +650,7 @@ func handleWorktreeFinish(profile string, args []string) {
 			abortCmd := exec.Command("git", "-C", repoRoot, "merge", "--abort")
 			_ = abortCmd.Run()
 			out.Error(fmt.Sprintf("merge failed (aborted): %v", err), ErrCodeInvalidOperation)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Printf("  %s Merged successfully\n", successSymbol)
 	}
@@ -643,6 +665,11 @@ func handleWorktreeFinish(profile string, args []string) {
 		}
 	}
 	_ = git.PruneWorktrees(repoRoot)
+	if n, err := git.DeleteCheckpoints(repoRoot, inst.ID); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to delete checkpoints: %v\n", err)
+	} else if n > 0 {
+		fmt.Printf("  %s %d checkpoint(s) removed\n", successSymbol, n)
+	}
 
 	// Step 3: Delete branch (if not --keep-branch)
 	if !*keepBranch {
@@ -661,7 +688,16 @@ func handleWorktreeFinish(profile string, args []string) {
 		}
 	}
 
-	// Step 5: Remove session from agent-deck
+	// Step 5: post_finish hooks (teardown; failures are only reported)
+	var postFinishErr error
+	if len(hooks.PostFinish) > 0 {
+		fmt.Fprintln(os.Stderr, "Running post-finish hooks...")
+		if postFinishErr = session.RunPostFinishHooks(hooks, hookEnv, worktreeProgress(*jsonOutput)); postFinishErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", postFinishErr)
+		}
+	}
+
+	// Step 6: Remove session from agent-deck
 	var remaining []*session.Instance
 	for _, i := range instances {
 		if i.ID != inst.ID {
@@ -670,11 +706,18 @@ func handleWorktreeFinish(profile string, args []string) {
 	}
 	if err := saveSessionData(storage, remaining); err != nil {
 		out.Error(fmt.Sprintf("failed to save session data: %v", err), ErrCodeInvalidOperation)
-		os.Exit(1)
+		exit(1)
 	}
+	session.FireSessionHook(session.HookWorktreeFinished, inst, map[string]string{
+		"branch":         worktreeBranch,
+		"target_branch":  targetBranch,
+		"merged":         strconv.FormatBool(!*noMerge),
+		"branch_deleted": strconv.FormatBool(!*keepBranch),
+	})
+	session.FireSessionHook(session.HookSessionDeleted, inst, nil)
 
 	if *jsonOutput {
-		out.Print("", map[string]interface{}{
+		data := map[string]interface{}{
 			"success":        true,
 			"session":        inst.Title,
 			"session_id":     inst.ID,
@@ -682,7 +725,11 @@ func handleWorktreeFinish(profile string, args []string) {
 			"merged_into":    targetBranch,
 			"merged":         !*noMerge,
 			"branch_deleted": !*keepBranch,
-		})
+		}
+		if postFinishErr != nil {
+			data["post_finish_error"] = postFinishErr.Error()
+		}
+		out.Print("", data)
 	} else {
 		fmt.Printf("\n%s Finished: session '%s' removed, worktree cleaned up", successSymbol, inst.Title)
 		if !*noMerge {
@@ -692,6 +739,17 @@ func handleWorktreeFinish(profile string, args []string) {
 	}
 }
 
+// worktreeProgress prints worktree hook progress (steps and command output)
+// to stderr, keeping stdout clean for --json. Nothing is printed in JSON mode.
+func worktreeProgress(jsonMode bool) func(string) {
+	if jsonMode {
+		return nil
+	}
+	return func(line string) {
+		fmt.Fprintf(os.Stderr, "  %s\n", line)
+	}
+}
+
 // truncateString truncates a string to maxLen, adding "..." if truncated
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
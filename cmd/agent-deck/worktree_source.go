@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/asheshgoplani/agent-deck/internal/forge"
+	"github.com/asheshgoplani/agent-deck/internal/git"
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+// worktreeSource is the local branch a new worktree checks out when it is
+// created from a remote branch (--worktree-from) or a pull request (--pr).
+type worktreeSource struct {
+	Branch        string             // Local branch for the worktree
+	BranchCreated bool               // Created here; delete it if the worktree can't be created
+	PR            *forge.PullRequest // Set for --pr
+}
+
+// resolveRemoteBranch fetches a remote branch such as origin/feature-x and
+// returns a local branch tracking it, named localName or after the remote
+// branch. An existing local branch of that name is used as is.
+func resolveRemoteBranch(repoRoot, ref, localName string) (*worktreeSource, error) {
+	remote, branch, err := git.SplitRemoteBranch(repoRoot, ref)
+	if err != nil {
+		return nil, err
+	}
+	return trackRemoteBranch(repoRoot, remote, branch, localName)
+}
+
+func trackRemoteBranch(repoRoot, remote, branch, localName string) (*worktreeSource, error) {
+	if localName == "" {
+		localName = branch
+	}
+	if err := git.FetchBranch(repoRoot, remote, branch); err != nil {
+		return nil, err
+	}
+	src := &worktreeSource{Branch: localName}
+	if !git.BranchExists(repoRoot, localName) {
+		if err := git.CreateTrackingBranch(repoRoot, localName, remote, branch); err != nil {
+			return nil, err
+		}
+		src.BranchCreated = true
+	}
+	return src, nil
+}
+
+// newForgeProvider returns the forge provider for the repository's [forge]
+// remote (default origin).
+func newForgeProvider(repoRoot string) (forge.Provider, string, error) {
+	settings := session.GetForgeSettings()
+	remoteURL, err := git.RemoteURL(repoRoot, settings.Remote)
+	if err != nil {
+		return nil, "", err
+	}
+	opts := forge.Options{Provider: settings.Provider, APIURL: settings.APIURL}
+	if settings.TokenEnv != "" {
+		opts.Token = os.Getenv(settings.TokenEnv)
+	}
+	provider, err := forge.New(remoteURL, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	return provider, settings.Remote, nil
+}
+
+// resolvePullRequest looks up pull request n and prepares a local branch for
+// its head. Branches in the base repository are tracked like --worktree-from;
+// fork branches are fetched through the forge's PR ref into pr-<n>, which is
+// fast-forwarded when it already exists.
+func resolvePullRequest(ctx context.Context, provider forge.Provider, remote, repoRoot string, n int, localName string) (*worktreeSource, error) {
+	pr, err := provider.PullRequest(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+
+	var src *worktreeSource
+	if !pr.FromFork {
+		src, err = trackRemoteBranch(repoRoot, remote, pr.HeadBranch, localName)
+	} else {
+		if localName == "" {
+			localName = fmt.Sprintf("pr-%d", pr.Number)
+		}
+		// Always fetch: a branch left by an earlier checkout is fast-forwarded
+		// to the current head, never silently reused when stale
+		existed := git.BranchExists(repoRoot, localName)
+		src = &worktreeSource{Branch: localName}
+		err = git.FetchRefToBranch(repoRoot, remote, pr.HeadRef, localName)
+		if errors.Is(err, git.ErrBranchDiverged) {
+			err = fmt.Errorf("pull request #%d: %w; delete %s or pick another branch name with -w", pr.Number, err, localName)
+		}
+		src.BranchCreated = err == nil && !existed
+	}
+	if err != nil {
+		return nil, err
+	}
+	src.PR = pr
+	return src, nil
+}
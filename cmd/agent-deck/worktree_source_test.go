@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@test.com",
+		"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@test.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// setupForgeRepos creates an upstream repo with a review branch and a fork
+// PR ref, a clone of it, and a config.toml pointing [forge] at a local
+// stand-in for the GitHub API.
+func setupForgeRepos(t *testing.T) (upstream, clone string) {
+	t.Helper()
+	upstream = t.TempDir()
+	runGit(t, upstream, "init", "-q", "-b", "main")
+	runGit(t, upstream, "commit", "-q", "--allow-empty", "-m", "init")
+	runGit(t, upstream, "branch", "feature/login")
+	runGit(t, upstream, "commit", "-q", "--allow-empty", "-m", "fork work")
+	runGit(t, upstream, "update-ref", "refs/pull/8/head", "HEAD")
+	runGit(t, upstream, "reset", "-q", "--hard", "HEAD~1")
+
+	clone = filepath.Join(t.TempDir(), "clone")
+	if out, err := exec.Command("git", "clone", "-q", upstream, clone).CombinedOutput(); err != nil {
+		t.Fatalf("clone: %v: %s", err, out)
+	}
+	// [forge] remote "hub" has a GitHub URL so the provider can be derived
+	// from it; tests repoint it at upstream before fetching
+	runGit(t, clone, "remote", "add", "hub", "https://github.com/acme/app.git")
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/app/pulls/7":
+			_, _ = w.Write([]byte(`{"number":7,"title":"Add login","head":{"ref":"feature/login","repo":{"full_name":"acme/app"}},"base":{"ref":"main","repo":{"full_name":"acme/app"}}}`))
+		case "/repos/acme/app/pulls/8":
+			_, _ = w.Write([]byte(`{"number":8,"title":"Fork fix","head":{"ref":"main","repo":{"full_name":"someone/app"}},"base":{"ref":"main","repo":{"full_name":"acme/app"}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(api.Close)
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".agent-deck"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := "[forge]\napi_url = \"" + api.URL + "\"\nremote = \"hub\"\n"
+	if err := os.WriteFile(filepath.Join(home, ".agent-deck", "config.toml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	session.ClearUserConfigCache()
+	t.Cleanup(session.ClearUserConfigCache)
+	return upstream, clone
+}
+
+func TestResolveRemoteBranch(t *testing.T) {
+	_, clone := setupForgeRepos(t)
+
+	src, err := resolveRemoteBranch(clone, "origin/feature/login", "")
+	if err != nil {
+		t.Fatalf("resolveRemoteBranch: %v", err)
+	}
+	if src.Branch != "feature/login" || !src.BranchCreated {
+		t.Errorf("source = %+v", src)
+	}
+	if got := runGit(t, clone, "rev-parse", "--abbrev-ref", "feature/login@{upstream}"); got != "origin/feature/login" {
+		t.Errorf("upstream = %q", got)
+	}
+
+	// A second resolve reuses the branch; -w renames the local branch
+	src, err = resolveRemoteBranch(clone, "origin/feature/login", "")
+	if err != nil || src.BranchCreated {
+		t.Errorf("existing branch: %+v, %v", src, err)
+	}
+	src, err = resolveRemoteBranch(clone, "origin/feature/login", "review-login")
+	if err != nil || src.Branch != "review-login" {
+		t.Errorf("renamed: %+v, %v", src, err)
+	}
+	if _, err := resolveRemoteBranch(clone, "origin/missing", ""); err == nil {
+		t.Error("missing remote branch should fail")
+	}
+}
+
+func TestResolvePullRequest(t *testing.T) {
+	upstream, clone := setupForgeRepos(t)
+	provider, remote, err := newForgeProvider(clone)
+	if err != nil {
+		t.Fatalf("newForgeProvider: %v", err)
+	}
+	if provider.Name() != "github" || remote != "hub" {
+		t.Fatalf("provider = %s, remote = %s", provider.Name(), remote)
+	}
+	runGit(t, clone, "remote", "set-url", "hub", upstream)
+	ctx := context.Background()
+
+	src, err := resolvePullRequest(ctx, provider, remote, clone, 7, "")
+	if err != nil {
+		t.Fatalf("same-repo PR: %v", err)
+	}
+	if src.Branch != "feature/login" || src.PR.Title != "Add login" {
+		t.Errorf("same-repo PR source = %+v", src)
+	}
+	if got := runGit(t, clone, "rev-parse", "--abbrev-ref", "feature/login@{upstream}"); got != "hub/feature/login" {
+		t.Errorf("upstream = %q", got)
+	}
+
+	src, err = resolvePullRequest(ctx, provider, remote, clone, 8, "")
+	if err != nil {
+		t.Fatalf("fork PR: %v", err)
+	}
+	if src.Branch != "pr-8" || !src.BranchCreated {
+		t.Errorf("fork PR source = %+v", src)
+	}
+	if got, want := runGit(t, clone, "rev-parse", "pr-8"), runGit(t, upstream, "rev-parse", "refs/pull/8/head"); got != want {
+		t.Errorf("pr-8 = %s, want %s", got, want)
+	}
+	if !git.BranchExists(clone, "pr-8") {
+		t.Error("pr-8 should exist")
+	}
+
+	// Checking the PR out again picks up new pushes to it
+	pushed := runGit(t, upstream, "commit-tree", "refs/pull/8/head^{tree}", "-p", "refs/pull/8/head", "-m", "more fork work")
+	runGit(t, upstream, "update-ref", "refs/pull/8/head", pushed)
+	src, err = resolvePullRequest(ctx, provider, remote, clone, 8, "")
+	if err != nil {
+		t.Fatalf("fork PR again: %v", err)
+	}
+	if src.BranchCreated {
+		t.Error("an existing pr-8 was not created here")
+	}
+	if got := runGit(t, clone, "rev-parse", "pr-8"); got != pushed {
+		t.Errorf("pr-8 = %s, want the new head %s", got, pushed)
+	}
+
+	// A local commit the PR doesn't have is never thrown away
+	local := runGit(t, clone, "commit-tree", "pr-8^{tree}", "-p", "pr-8", "-m", "local work")
+	runGit(t, clone, "update-ref", "refs/heads/pr-8", local)
+	force := runGit(t, upstream, "commit-tree", "refs/pull/8/head^{tree}", "-p", "refs/pull/8/head", "-m", "force-pushed")
+	runGit(t, upstream, "update-ref", "refs/pull/8/head", force)
+	if _, err := resolvePullRequest(ctx, provider, remote, clone, 8, ""); !errors.Is(err, git.ErrBranchDiverged) {
+		t.Errorf("diverged pr-8: err = %v, want ErrBranchDiverged", err)
+	}
+	if got := runGit(t, clone, "rev-parse", "pr-8"); got != local {
+		t.Errorf("diverged pr-8 moved to %s", got)
+	}
+
+	if _, err := resolvePullRequest(ctx, provider, remote, clone, 99, ""); err == nil {
+		t.Error("unknown PR should fail")
+	}
+}
@@ -0,0 +1,285 @@
+// Package forge looks up and opens pull requests on code hosting services
+// (GitHub, GitLab, ...) so sessions can be started on a pull request's
+// branch with its review comments as the first prompt, and finished by
+// opening one.
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a pull request does not exist.
+var ErrNotFound = errors.New("pull request not found")
+
+// PullRequest is a pull (or merge) request as seen from the base repository.
+type PullRequest struct {
+	Number     int    `json:"number"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	Author     string `json:"author,omitempty"`
+	State      string `json:"state,omitempty"`
+	BaseBranch string `json:"base_branch"`
+	HeadBranch string `json:"head_branch"`
+	HeadSHA    string `json:"head_sha,omitempty"`
+	// FromFork is set when the head branch lives in another repository and
+	// can only be fetched through HeadRef.
+	FromFork bool `json:"from_fork"`
+	// HeadRef is a ref on the base remote that points at the head commit,
+	// e.g. refs/pull/123/head.
+	HeadRef string `json:"head_ref"`
+}
+
+// ReviewComment is a review remark on a pull request: an inline comment on
+// a line (Path set) or a review summary.
+type ReviewComment struct {
+	Author string `json:"author"`
+	Body   string `json:"body"`
+	Path   string `json:"path,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+// Provider resolves pull requests for one repository.
+type Provider interface {
+	// Name returns the provider name, e.g. "github".
+	Name() string
+	// PullRequest returns pull request number n.
+	PullRequest(ctx context.Context, n int) (*PullRequest, error)
+	// ReviewComments returns the review comments of pull request n, oldest first.
+	ReviewComments(ctx context.Context, n int) ([]ReviewComment, error)
+	// CreatePullRequest opens a pull request from a branch that has already
+	// been pushed to the repository.
+	CreatePullRequest(ctx context.Context, req NewPullRequest) (*PullRequest, error)
+}
+
+// NewPullRequest describes a pull request to open.
+type NewPullRequest struct {
+	Title string
+	Body  string
+	Head  string // Branch with the changes
+	Base  string // Branch to merge into
+}
+
+// Repo identifies a repository on a forge.
+type Repo struct {
+	Scheme string // https or http, used to derive API URLs
+	Host   string // e.g. github.com
+	Path   string // owner/name, may have more segments (GitLab subgroups)
+}
+
+// ParseRemoteURL extracts the host and repository path from a git remote
+// URL: https://host/owner/repo.git, ssh://git@host:22/owner/repo.git or the
+// scp-like git@host:owner/repo.git.
+func ParseRemoteURL(remote string) (Repo, error) {
+	remote = strings.TrimSpace(remote)
+	var repo Repo
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return Repo{}, fmt.Errorf("invalid remote URL %q: %w", remote, err)
+		}
+		// Web URLs keep their port; ssh:// ports are not the API's
+		repo.Scheme, repo.Host, repo.Path = "https", u.Hostname(), u.Path
+		if u.Scheme == "http" || u.Scheme == "https" {
+			repo.Scheme, repo.Host = u.Scheme, u.Host
+		}
+	} else if at := strings.Index(remote, ":"); at > 0 && !strings.HasPrefix(remote, "/") {
+		host := remote[:at]
+		if i := strings.LastIndex(host, "@"); i >= 0 {
+			host = host[i+1:]
+		}
+		repo.Scheme, repo.Host, repo.Path = "https", host, remote[at+1:]
+	}
+	repo.Path = strings.TrimSuffix(strings.Trim(repo.Path, "/"), ".git")
+	if repo.Host == "" || !strings.Contains(repo.Path, "/") {
+		return Repo{}, fmt.Errorf("cannot determine the forge repository from remote %q", remote)
+	}
+	return repo, nil
+}
+
+// Options configures a provider.
+type Options struct {
+	// Provider names a registered provider; empty detects it from the host.
+	Provider string
+	// APIURL overrides the API base URL. Required for self-hosted
+	// instances; only github.com and gitlab.com have a default.
+	APIURL string
+	// Token authenticates API requests; empty uses the provider's default
+	// environment variables, which are only sent to the public service.
+	Token string
+	// HTTPClient defaults to a client with a 30s timeout.
+	HTTPClient *http.Client
+}
+
+// Factory creates a provider for a repository.
+type Factory func(repo Repo, opts Options) (Provider, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes a provider available by name. Registering a name twice
+// replaces the earlier factory.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// Providers returns the registered provider names, sorted.
+func Providers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns the provider for the repository behind a git remote URL.
+func New(remoteURL string, opts Options) (Provider, error) {
+	repo, err := ParseRemoteURL(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+	name := opts.Provider
+	if name == "" {
+		name = detectProvider(repo.Host)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("unknown forge host %s: set [forge] provider (one of %s)",
+			repo.Host, strings.Join(Providers(), ", "))
+	}
+	registryMu.RLock()
+	factory := registry[name]
+	registryMu.RUnlock()
+	if factory == nil {
+		return nil, fmt.Errorf("unknown forge provider %q (one of %s)", name, strings.Join(Providers(), ", "))
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return factory(repo, opts)
+}
+
+// detectProvider guesses the provider from well-known host names.
+func detectProvider(host string) string {
+	host = strings.ToLower(host)
+	switch {
+	case host == "github.com" || strings.HasPrefix(host, "github."):
+		return "github"
+	case host == "gitlab.com" || strings.HasPrefix(host, "gitlab."):
+		return "gitlab"
+	}
+	return ""
+}
+
+// apiToken returns the token to send to apiURL. The provider's default
+// environment variables (envVars) are only sent to its public API
+// (publicAPI): any other host needs [forge] token_env, so a remote named
+// like a forge can't collect a GitHub or GitLab token. Tokens never go
+// over plain http.
+func apiToken(apiURL, publicAPI, token string, envVars ...string) (string, error) {
+	if token == "" && strings.EqualFold(apiURL, publicAPI) {
+		for _, name := range envVars {
+			if token = os.Getenv(name); token != "" {
+				break
+			}
+		}
+	}
+	if token != "" && !strings.HasPrefix(strings.ToLower(apiURL), "https://") {
+		return "", fmt.Errorf("refusing to send the forge token to %s over plain http: use an https api_url", apiURL)
+	}
+	return token, nil
+}
+
+// getJSON fetches an API URL into v, mapping 404 to ErrNotFound.
+func getJSON(ctx context.Context, client *http.Client, apiURL string, header http.Header, v any) error {
+	return doJSON(ctx, client, http.MethodGet, apiURL, header, nil, v)
+}
+
+// postJSON sends body as JSON to an API URL and decodes the response into v.
+func postJSON(ctx context.Context, client *http.Client, apiURL string, header http.Header, body, v any) error {
+	return doJSON(ctx, client, http.MethodPost, apiURL, header, body, v)
+}
+
+func doJSON(ctx context.Context, client *http.Client, method, apiURL string, header http.Header, body, v any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, vals := range header {
+		for _, val := range vals {
+			req.Header.Add(k, val)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", req.URL.Path, err)
+	}
+	return nil
+}
+
+// ReviewPrompt turns a pull request's review comments into an instruction
+// for an agent working on the PR's branch.
+func ReviewPrompt(pr *PullRequest, comments []ReviewComment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Address the review comments on pull request #%d", pr.Number)
+	if pr.Title != "" {
+		fmt.Fprintf(&b, " (%s)", pr.Title)
+	}
+	b.WriteString(". You are on its branch; make the requested changes and commit them.\n")
+	for _, c := range comments {
+		b.WriteString("\n- ")
+		if c.Path != "" {
+			b.WriteString(c.Path)
+			if c.Line > 0 {
+				fmt.Fprintf(&b, ":%d", c.Line)
+			}
+			b.WriteString(" ")
+		}
+		if c.Author != "" {
+			fmt.Fprintf(&b, "(%s) ", c.Author)
+		}
+		b.WriteString(strings.ReplaceAll(strings.TrimSpace(c.Body), "\n", "\n  "))
+	}
+	return b.String()
+}
@@ -0,0 +1,227 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRemoteURL(t *testing.T) {
+	tests := []struct {
+		remote string
+		want   Repo
+	}{
+		{"https://github.com/acme/app.git", Repo{Scheme: "https", Host: "github.com", Path: "acme/app"}},
+		{"git@github.com:acme/app.git", Repo{Scheme: "https", Host: "github.com", Path: "acme/app"}},
+		{"ssh://git@gitlab.example.com:2222/group/sub/app.git", Repo{Scheme: "https", Host: "gitlab.example.com", Path: "group/sub/app"}},
+		{"http://127.0.0.1:8080/acme/app", Repo{Scheme: "http", Host: "127.0.0.1:8080", Path: "acme/app"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRemoteURL(tt.remote)
+		require.NoError(t, err, tt.remote)
+		assert.Equal(t, tt.want, got, tt.remote)
+	}
+	for _, bad := range []string{"/srv/git/app.git", "https://github.com/", "app"} {
+		_, err := ParseRemoteURL(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestNew_ProviderSelection(t *testing.T) {
+	p, err := New("git@github.com:acme/app.git", Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "github", p.Name())
+	p, err = New("https://gitlab.com/acme/app.git", Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "gitlab", p.Name())
+
+	_, err = New("https://git.example.com/acme/app.git", Options{})
+	assert.ErrorContains(t, err, "set [forge] provider")
+	p, err = New("https://git.example.com/acme/app.git", Options{Provider: "gitlab", APIURL: "https://git.example.com/api/v4"})
+	require.NoError(t, err)
+	assert.Equal(t, "gitlab", p.Name())
+	_, err = New("https://git.example.com/acme/app.git", Options{Provider: "nope"})
+	assert.Error(t, err)
+
+	// Self-hosted instances have no API URL default
+	_, err = New("https://github.example.com/acme/app.git", Options{})
+	assert.ErrorContains(t, err, "set [forge] api_url")
+	_, err = New("https://gitlab.example.com/acme/app.git", Options{})
+	assert.ErrorContains(t, err, "set [forge] api_url")
+}
+
+func TestNew_TokenOnlyGoesToTrustedAPIs(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "gh-env")
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GITLAB_TOKEN", "gl-env")
+
+	p, err := New("git@github.com:acme/app.git", Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "gh-env", p.(*gitHub).token)
+	p, err = New("https://gitlab.com/acme/app.git", Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "gl-env", p.(*gitLab).token)
+
+	// The environment tokens are not sent to other API URLs
+	srv, header := fakeForge(t, map[string]string{"/repos/acme/app/pulls/1": `{"number":1}`})
+	p, err = New("https://github.example.com/acme/app.git", Options{APIURL: srv.URL, HTTPClient: srv.Client()})
+	require.NoError(t, err)
+	_, err = p.PullRequest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, header.Get("Authorization"))
+	p, err = New("https://gitlab.example.com/acme/app.git", Options{APIURL: "https://gitlab.example.com/api/v4"})
+	require.NoError(t, err)
+	assert.Empty(t, p.(*gitLab).token)
+
+	// An explicit token is, but never over plain http
+	p, err = New("https://github.example.com/acme/app.git", Options{APIURL: "https://github.example.com/api/v3", Token: "ghe"})
+	require.NoError(t, err)
+	assert.Equal(t, "ghe", p.(*gitHub).token)
+	_, err = New("http://github.example.com/acme/app.git", Options{APIURL: "http://github.example.com/api/v3", Token: "ghe"})
+	assert.ErrorContains(t, err, "plain http")
+	_, err = New("http://gitlab.example.com/acme/app.git", Options{Provider: "gitlab", APIURL: "http://gitlab.example.com/api/v4", Token: "tok"})
+	assert.ErrorContains(t, err, "plain http")
+	_, err = New("http://gitlab.example.com/acme/app.git", Options{Provider: "gitlab", APIURL: "http://gitlab.example.com/api/v4"})
+	assert.NoError(t, err, "plain http is fine without a token")
+}
+
+// fakeForge serves canned JSON per request path over TLS and records headers.
+// Use srv.Client() as the provider's HTTP client.
+func fakeForge(t *testing.T, routes map[string]string) (*httptest.Server, *http.Header) {
+	t.Helper()
+	var last http.Header
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = r.Header.Clone()
+		body, ok := routes[r.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &last
+}
+
+func TestGitHub(t *testing.T) {
+	srv, header := fakeForge(t, map[string]string{
+		"/repos/acme/app/pulls/7": `{"number":7,"title":"Add login","html_url":"https://github.com/acme/app/pull/7",
+			"state":"open","user":{"login":"dev"},
+			"head":{"ref":"feature/login","sha":"abc123","repo":{"full_name":"acme/app"}},
+			"base":{"ref":"main","repo":{"full_name":"acme/app"}}}`,
+		"/repos/acme/app/pulls/8": `{"number":8,"title":"Fork fix","head":{"ref":"main","sha":"def","repo":{"full_name":"someone/app"}},
+			"base":{"ref":"main","repo":{"full_name":"acme/app"}}}`,
+		"/repos/acme/app/pulls/7/reviews": `[{"user":{"login":"rev"},"body":"Please add tests","state":"CHANGES_REQUESTED"},
+			{"user":{"login":"rev2"},"body":"LGTM","state":"APPROVED"},{"user":{"login":"rev"},"body":"","state":"COMMENTED"}]`,
+		"/repos/acme/app/pulls/7/comments": `[{"user":{"login":"rev"},"body":"Handle the error","path":"auth.go","line":42},
+			{"user":{"login":"rev"},"body":"Outdated","path":"old.go","line":0,"original_line":3}]`,
+	})
+	p, err := New("git@github.com:acme/app.git", Options{APIURL: srv.URL, Token: "secret", HTTPClient: srv.Client()})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	pr, err := p.PullRequest(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer secret", header.Get("Authorization"))
+	assert.Equal(t, &PullRequest{
+		Number: 7, Title: "Add login", URL: "https://github.com/acme/app/pull/7", Author: "dev", State: "open",
+		BaseBranch: "main", HeadBranch: "feature/login", HeadSHA: "abc123", HeadRef: "refs/pull/7/head",
+	}, pr)
+
+	fork, err := p.PullRequest(ctx, 8)
+	require.NoError(t, err)
+	assert.True(t, fork.FromFork)
+
+	_, err = p.PullRequest(ctx, 9)
+	assert.True(t, errors.Is(err, ErrNotFound), "err = %v", err)
+
+	comments, err := p.ReviewComments(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, []ReviewComment{
+		{Author: "rev", Body: "Please add tests"},
+		{Author: "rev", Body: "Handle the error", Path: "auth.go", Line: 42},
+		{Author: "rev", Body: "Outdated", Path: "old.go", Line: 3},
+	}, comments)
+}
+
+func TestGitLab(t *testing.T) {
+	srv, header := fakeForge(t, map[string]string{
+		"/api/v4/projects/group%2Fapp/merge_requests/3": `{"iid":3,"title":"Fix","web_url":"https://gitlab.example.com/group/app/-/merge_requests/3",
+			"author":{"username":"dev"},"source_branch":"fix","target_branch":"main","sha":"abc",
+			"source_project_id":1,"target_project_id":1}`,
+		"/api/v4/projects/group%2Fapp/merge_requests/3/notes": `[{"author":{"username":"bot"},"body":"added 1 commit","system":true},
+			{"author":{"username":"rev"},"body":"Rename this","position":{"new_path":"a.go","new_line":5}},
+			{"author":{"username":"rev"},"body":"Overall fine"}]`,
+	})
+	p, err := New("https://gitlab.example.com/group/app.git", Options{Provider: "gitlab", APIURL: srv.URL + "/api/v4", Token: "tok", HTTPClient: srv.Client()})
+	require.NoError(t, err)
+
+	pr, err := p.PullRequest(context.Background(), 3)
+	require.NoError(t, err)
+	assert.Equal(t, "tok", header.Get("PRIVATE-TOKEN"))
+	assert.Equal(t, "fix", pr.HeadBranch)
+	assert.False(t, pr.FromFork)
+	assert.Equal(t, "refs/merge-requests/3/head", pr.HeadRef)
+
+	comments, err := p.ReviewComments(context.Background(), 3)
+	require.NoError(t, err)
+	assert.Equal(t, []ReviewComment{
+		{Author: "rev", Body: "Rename this", Path: "a.go", Line: 5},
+		{Author: "rev", Body: "Overall fine"},
+	}, comments)
+}
+
+func TestReviewPrompt(t *testing.T) {
+	prompt := ReviewPrompt(&PullRequest{Number: 7, Title: "Add login"}, []ReviewComment{
+		{Author: "rev", Body: "Please add tests"},
+		{Author: "rev", Body: "Handle the error\nand log it", Path: "auth.go", Line: 42},
+	})
+	assert.True(t, strings.HasPrefix(prompt, "Address the review comments on pull request #7 (Add login)."), prompt)
+	assert.Contains(t, prompt, "\n- (rev) Please add tests")
+	assert.Contains(t, prompt, "\n- auth.go:42 (rev) Handle the error\n  and log it")
+}
+
+func TestCreatePullRequest(t *testing.T) {
+	var method string
+	var sent map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&sent))
+		w.WriteHeader(http.StatusCreated)
+		switch r.URL.EscapedPath() {
+		case "/repos/acme/app/pulls":
+			_, _ = w.Write([]byte(`{"number":12,"html_url":"https://github.com/acme/app/pull/12","head":{"ref":"feat","repo":{"full_name":"acme/app"}},"base":{"ref":"main","repo":{"full_name":"acme/app"}}}`))
+		case "/api/v4/projects/group%2Fapp/merge_requests":
+			_, _ = w.Write([]byte(`{"iid":4,"web_url":"https://gitlab.example.com/group/app/-/merge_requests/4","source_branch":"feat","target_branch":"main"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	req := NewPullRequest{Title: "Feat", Body: "Details", Head: "feat", Base: "main"}
+
+	gh, err := New("git@github.com:acme/app.git", Options{APIURL: srv.URL})
+	require.NoError(t, err)
+	pr, err := gh.CreatePullRequest(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPost, method)
+	assert.Equal(t, map[string]string{"title": "Feat", "body": "Details", "head": "feat", "base": "main"}, sent)
+	assert.Equal(t, 12, pr.Number)
+	assert.Equal(t, "https://github.com/acme/app/pull/12", pr.URL)
+
+	gl, err := New("https://gitlab.example.com/group/app.git", Options{Provider: "gitlab", APIURL: srv.URL + "/api/v4"})
+	require.NoError(t, err)
+	mr, err := gl.CreatePullRequest(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "feat", sent["source_branch"])
+	assert.Equal(t, "Details", sent["description"])
+	assert.Equal(t, 4, mr.Number)
+}
@@ -0,0 +1,159 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func init() {
+	Register("github", newGitHub)
+}
+
+// gitHub talks to the GitHub REST API (github.com or Enterprise Server).
+type gitHub struct {
+	repo   Repo
+	apiURL string
+	token  string
+	client *http.Client
+}
+
+// gitHubAPI is the API of github.com, the only one that gets $GITHUB_TOKEN.
+const gitHubAPI = "https://api.github.com"
+
+func newGitHub(repo Repo, opts Options) (Provider, error) {
+	g := &gitHub{repo: repo, apiURL: strings.TrimSuffix(opts.APIURL, "/"), client: opts.HTTPClient}
+	if g.apiURL == "" {
+		if !strings.EqualFold(repo.Host, "github.com") {
+			return nil, fmt.Errorf("set [forge] api_url for GitHub Enterprise host %s (e.g. https://%s/api/v3)", repo.Host, repo.Host)
+		}
+		g.apiURL = gitHubAPI
+	}
+	token, err := apiToken(g.apiURL, gitHubAPI, opts.Token, "GITHUB_TOKEN", "GH_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	g.token = token
+	return g, nil
+}
+
+func (g *gitHub) Name() string { return "github" }
+
+func (g *gitHub) header() http.Header {
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.github+json")
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if g.token != "" {
+		header.Set("Authorization", "Bearer "+g.token)
+	}
+	return header
+}
+
+func (g *gitHub) url(path string) string {
+	return fmt.Sprintf("%s/repos/%s%s", g.apiURL, g.repo.Path, path)
+}
+
+func (g *gitHub) get(ctx context.Context, path string, v any) error {
+	return getJSON(ctx, g.client, g.url(path), g.header(), v)
+}
+
+type gitHubUser struct {
+	Login string `json:"login"`
+}
+
+// gitHubPull is the API's pull request object.
+type gitHubPull struct {
+	Number  int        `json:"number"`
+	Title   string     `json:"title"`
+	HTMLURL string     `json:"html_url"`
+	State   string     `json:"state"`
+	User    gitHubUser `json:"user"`
+	Head    struct {
+		Ref  string `json:"ref"`
+		SHA  string `json:"sha"`
+		Repo *struct {
+			FullName string `json:"full_name"`
+		} `json:"repo"`
+	} `json:"head"`
+	Base struct {
+		Ref  string `json:"ref"`
+		Repo struct {
+			FullName string `json:"full_name"`
+		} `json:"repo"`
+	} `json:"base"`
+}
+
+func (pr *gitHubPull) toPullRequest() *PullRequest {
+	return &PullRequest{
+		Number:     pr.Number,
+		Title:      pr.Title,
+		URL:        pr.HTMLURL,
+		Author:     pr.User.Login,
+		State:      pr.State,
+		BaseBranch: pr.Base.Ref,
+		HeadBranch: pr.Head.Ref,
+		HeadSHA:    pr.Head.SHA,
+		// A deleted fork has no head repo; its commits are still on refs/pull
+		FromFork: pr.Head.Repo == nil || !strings.EqualFold(pr.Head.Repo.FullName, pr.Base.Repo.FullName),
+		HeadRef:  fmt.Sprintf("refs/pull/%d/head", pr.Number),
+	}
+}
+
+func (g *gitHub) PullRequest(ctx context.Context, n int) (*PullRequest, error) {
+	var pr gitHubPull
+	if err := g.get(ctx, fmt.Sprintf("/pulls/%d", n), &pr); err != nil {
+		return nil, fmt.Errorf("pull request #%d: %w", n, err)
+	}
+	return pr.toPullRequest(), nil
+}
+
+func (g *gitHub) CreatePullRequest(ctx context.Context, req NewPullRequest) (*PullRequest, error) {
+	body := map[string]string{"title": req.Title, "body": req.Body, "head": req.Head, "base": req.Base}
+	var pr gitHubPull
+	if err := postJSON(ctx, g.client, g.url("/pulls"), g.header(), body, &pr); err != nil {
+		return nil, fmt.Errorf("create pull request %s → %s: %w", req.Head, req.Base, err)
+	}
+	return pr.toPullRequest(), nil
+}
+
+func (g *gitHub) ReviewComments(ctx context.Context, n int) ([]ReviewComment, error) {
+	// Review summaries ("Request changes" with a body) come first, then the
+	// inline comments. Only the first 100 of each are read.
+	var reviews []struct {
+		User    gitHubUser `json:"user"`
+		Body    string     `json:"body"`
+		State   string     `json:"state"`
+		HTMLURL string     `json:"html_url"`
+	}
+	if err := g.get(ctx, fmt.Sprintf("/pulls/%d/reviews?per_page=100", n), &reviews); err != nil {
+		return nil, fmt.Errorf("reviews of pull request #%d: %w", n, err)
+	}
+	var inline []struct {
+		User         gitHubUser `json:"user"`
+		Body         string     `json:"body"`
+		Path         string     `json:"path"`
+		Line         int        `json:"line"`
+		OriginalLine int        `json:"original_line"`
+		HTMLURL      string     `json:"html_url"`
+	}
+	if err := g.get(ctx, fmt.Sprintf("/pulls/%d/comments?per_page=100", n), &inline); err != nil {
+		return nil, fmt.Errorf("review comments of pull request #%d: %w", n, err)
+	}
+
+	var comments []ReviewComment
+	for _, r := range reviews {
+		if strings.TrimSpace(r.Body) == "" || r.State == "APPROVED" {
+			continue
+		}
+		comments = append(comments, ReviewComment{Author: r.User.Login, Body: r.Body, URL: r.HTMLURL})
+	}
+	for _, c := range inline {
+		line := c.Line
+		if line == 0 {
+			line = c.OriginalLine // Outdated comment
+		}
+		comments = append(comments, ReviewComment{Author: c.User.Login, Body: c.Body, Path: c.Path, Line: line, URL: c.HTMLURL})
+	}
+	return comments, nil
+}
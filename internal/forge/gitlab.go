@@ -0,0 +1,146 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	Register("gitlab", newGitLab)
+}
+
+// gitLab talks to the GitLab REST API (v4). Pull requests are merge
+// requests there; numbers are the project-scoped IIDs shown in the UI.
+type gitLab struct {
+	repo   Repo
+	apiURL string
+	token  string
+	client *http.Client
+}
+
+// gitLabAPI is the API of gitlab.com, the only one that gets $GITLAB_TOKEN.
+const gitLabAPI = "https://gitlab.com/api/v4"
+
+func newGitLab(repo Repo, opts Options) (Provider, error) {
+	g := &gitLab{repo: repo, apiURL: strings.TrimSuffix(opts.APIURL, "/"), client: opts.HTTPClient}
+	if g.apiURL == "" {
+		if !strings.EqualFold(repo.Host, "gitlab.com") {
+			return nil, fmt.Errorf("set [forge] api_url for self-hosted GitLab host %s (e.g. https://%s/api/v4)", repo.Host, repo.Host)
+		}
+		g.apiURL = gitLabAPI
+	}
+	token, err := apiToken(g.apiURL, gitLabAPI, opts.Token, "GITLAB_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	g.token = token
+	return g, nil
+}
+
+func (g *gitLab) Name() string { return "gitlab" }
+
+func (g *gitLab) header() http.Header {
+	header := http.Header{}
+	if g.token != "" {
+		header.Set("PRIVATE-TOKEN", g.token)
+	}
+	return header
+}
+
+func (g *gitLab) url(path string) string {
+	return fmt.Sprintf("%s/projects/%s%s", g.apiURL, url.PathEscape(g.repo.Path), path)
+}
+
+func (g *gitLab) get(ctx context.Context, path string, v any) error {
+	return getJSON(ctx, g.client, g.url(path), g.header(), v)
+}
+
+type gitLabUser struct {
+	Username string `json:"username"`
+}
+
+// gitLabMR is the API's merge request object.
+type gitLabMR struct {
+	IID             int        `json:"iid"`
+	Title           string     `json:"title"`
+	WebURL          string     `json:"web_url"`
+	State           string     `json:"state"`
+	Author          gitLabUser `json:"author"`
+	SourceBranch    string     `json:"source_branch"`
+	TargetBranch    string     `json:"target_branch"`
+	SHA             string     `json:"sha"`
+	SourceProjectID int        `json:"source_project_id"`
+	TargetProjectID int        `json:"target_project_id"`
+}
+
+func (mr *gitLabMR) toPullRequest() *PullRequest {
+	return &PullRequest{
+		Number:     mr.IID,
+		Title:      mr.Title,
+		URL:        mr.WebURL,
+		Author:     mr.Author.Username,
+		State:      mr.State,
+		BaseBranch: mr.TargetBranch,
+		HeadBranch: mr.SourceBranch,
+		HeadSHA:    mr.SHA,
+		FromFork:   mr.SourceProjectID != mr.TargetProjectID,
+		HeadRef:    fmt.Sprintf("refs/merge-requests/%d/head", mr.IID),
+	}
+}
+
+func (g *gitLab) PullRequest(ctx context.Context, n int) (*PullRequest, error) {
+	var mr gitLabMR
+	if err := g.get(ctx, fmt.Sprintf("/merge_requests/%d", n), &mr); err != nil {
+		return nil, fmt.Errorf("merge request !%d: %w", n, err)
+	}
+	return mr.toPullRequest(), nil
+}
+
+func (g *gitLab) CreatePullRequest(ctx context.Context, req NewPullRequest) (*PullRequest, error) {
+	body := map[string]string{
+		"title":         req.Title,
+		"description":   req.Body,
+		"source_branch": req.Head,
+		"target_branch": req.Base,
+	}
+	var mr gitLabMR
+	if err := postJSON(ctx, g.client, g.url("/merge_requests"), g.header(), body, &mr); err != nil {
+		return nil, fmt.Errorf("create merge request %s → %s: %w", req.Head, req.Base, err)
+	}
+	return mr.toPullRequest(), nil
+}
+
+func (g *gitLab) ReviewComments(ctx context.Context, n int) ([]ReviewComment, error) {
+	var notes []struct {
+		Author   gitLabUser `json:"author"`
+		Body     string     `json:"body"`
+		System   bool       `json:"system"`
+		Position *struct {
+			NewPath string `json:"new_path"`
+			NewLine int    `json:"new_line"`
+			OldLine int    `json:"old_line"`
+		} `json:"position"`
+	}
+	if err := g.get(ctx, fmt.Sprintf("/merge_requests/%d/notes?sort=asc&per_page=100", n), &notes); err != nil {
+		return nil, fmt.Errorf("notes of merge request !%d: %w", n, err)
+	}
+
+	var comments []ReviewComment
+	for _, note := range notes {
+		if note.System || strings.TrimSpace(note.Body) == "" {
+			continue // "added 1 commit", "approved this merge request", ...
+		}
+		c := ReviewComment{Author: note.Author.Username, Body: note.Body}
+		if p := note.Position; p != nil {
+			c.Path, c.Line = p.NewPath, p.NewLine
+			if c.Line == 0 {
+				c.Line = p.OldLine
+			}
+		}
+		comments = append(comments, c)
+	}
+	return comments, nil
+}
@@ -0,0 +1,312 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CheckpointRefPrefix is the namespace for work tree snapshots. Each session
+// gets refs/agent-deck/checkpoints/<session>/<n>, numbered from 1.
+const CheckpointRefPrefix = "refs/agent-deck/checkpoints/"
+
+// checkpointIdent authors checkpoint commits, so snapshots work in
+// repositories without a configured user.
+var checkpointIdent = []string{
+	"GIT_AUTHOR_NAME=agent-deck", "GIT_AUTHOR_EMAIL=agent-deck@localhost",
+	"GIT_COMMITTER_NAME=agent-deck", "GIT_COMMITTER_EMAIL=agent-deck@localhost",
+}
+
+// ErrCheckpointNotFound is returned for checkpoint numbers that don't exist.
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// Checkpoint is a snapshot of a work tree (tracked and untracked files,
+// ignored files excluded) stored as a commit under CheckpointRefPrefix.
+// The commit's parent is the HEAD at the time, so the branch is untouched.
+type Checkpoint struct {
+	N         int       `json:"n"`
+	Ref       string    `json:"ref"`
+	Commit    string    `json:"commit"`
+	Tree      string    `json:"tree"`
+	Head      string    `json:"head,omitempty"` // HEAD when the snapshot was taken
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CheckpointRef returns the ref name of a session's checkpoint n.
+func CheckpointRef(sessionID string, n int) string {
+	return fmt.Sprintf("%s%s/%d", CheckpointRefPrefix, sessionID, n)
+}
+
+// CreateCheckpoint snapshots the work tree containing dir as the session's
+// next checkpoint. The user's index, HEAD and branch are not touched: files
+// are staged into a temporary index. It returns nil (and no error) when the
+// work tree matches the newest checkpoint.
+func CreateCheckpoint(dir, sessionID, message string) (*Checkpoint, error) {
+	root, err := GetRepoRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := snapshotTree(root)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := ListCheckpoints(root, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	n := 1
+	if len(existing) > 0 {
+		latest := existing[len(existing)-1]
+		if latest.Tree == tree {
+			return nil, nil
+		}
+		n = latest.N + 1
+	}
+
+	args := []string{"commit-tree", tree, "-m", message}
+	head, _ := statusGit(root, "rev-parse", "--verify", "--quiet", "HEAD")
+	head = strings.TrimSpace(head)
+	if head != "" {
+		args = append(args, "-p", head)
+	}
+	cmd := exec.Command("git", append([]string{"-C", root}, args...)...)
+	cmd.Env = append(os.Environ(), checkpointIdent...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write checkpoint commit: %w", err)
+	}
+	commit := strings.TrimSpace(string(out))
+
+	ref := CheckpointRef(sessionID, n)
+	if err := runGitCmd(root, "update-ref", ref, commit, ""); err != nil {
+		return nil, err
+	}
+	return &Checkpoint{N: n, Ref: ref, Commit: commit, Tree: tree, Head: head, Message: message, CreatedAt: time.Now()}, nil
+}
+
+// snapshotTree writes the work tree at root (tracked and untracked, ignored
+// files excluded) as a tree object using a throwaway copy of the index.
+func snapshotTree(root string) (string, error) {
+	tmp, err := os.CreateTemp("", "agent-deck-index-*")
+	if err != nil {
+		return "", err
+	}
+	indexPath := tmp.Name()
+	defer os.Remove(indexPath)
+
+	// Starting from the real index lets `git add` skip unchanged files by
+	// their cached stat data. Without one, start from HEAD (if any).
+	copied := false
+	if gitDir, err := GetGitDir(root); err == nil {
+		if src, err := os.Open(filepath.Join(gitDir, "index")); err == nil {
+			_, err = io.Copy(tmp, src)
+			src.Close()
+			copied = err == nil
+		}
+	}
+	tmp.Close()
+	if !copied {
+		_ = os.Remove(indexPath) // A missing index file reads as empty
+	}
+
+	env := append(os.Environ(), "GIT_INDEX_FILE="+indexPath, "GIT_OPTIONAL_LOCKS=0")
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command("git", append([]string{"-C", root}, args...)...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("git %s failed: %s: %w", args[0], strings.TrimSpace(string(out)), err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+	if !copied {
+		if _, err := statusGit(root, "rev-parse", "--verify", "--quiet", "HEAD"); err == nil {
+			if _, err := run("read-tree", "HEAD"); err != nil {
+				return "", err
+			}
+		}
+	}
+	if _, err := run("add", "-A", "--", "."); err != nil {
+		return "", err
+	}
+	return run("write-tree")
+}
+
+// ListCheckpoints returns a session's checkpoints, oldest first.
+func ListCheckpoints(dir, sessionID string) ([]Checkpoint, error) {
+	prefix := CheckpointRefPrefix + sessionID + "/"
+	out, err := statusGit(dir, "for-each-ref",
+		"--format=%(refname)%09%(objectname)%09%(tree)%09%(parent)%09%(creatordate:unix)%09%(subject)", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	var checkpoints []Checkpoint
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.SplitN(line, "\t", 6)
+		if len(fields) != 6 {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(fields[0], prefix))
+		if err != nil {
+			continue
+		}
+		unix, _ := strconv.ParseInt(fields[4], 10, 64)
+		checkpoints = append(checkpoints, Checkpoint{
+			N:         n,
+			Ref:       fields[0],
+			Commit:    fields[1],
+			Tree:      fields[2],
+			Head:      fields[3],
+			Message:   fields[5],
+			CreatedAt: time.Unix(unix, 0),
+		})
+	}
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].N < checkpoints[j].N })
+	return checkpoints, nil
+}
+
+// GetCheckpoint returns a session's checkpoint n.
+func GetCheckpoint(dir, sessionID string, n int) (*Checkpoint, error) {
+	checkpoints, err := ListCheckpoints(dir, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range checkpoints {
+		if checkpoints[i].N == n {
+			return &checkpoints[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: #%d", ErrCheckpointNotFound, n)
+}
+
+// CheckpointDiff returns the changes made to the work tree since checkpoint
+// n, as a unified diff (or a diffstat when stat is set). Untracked files
+// are included.
+func CheckpointDiff(dir, sessionID string, n int, stat bool) (string, error) {
+	root, err := GetRepoRoot(dir)
+	if err != nil {
+		return "", err
+	}
+	cp, err := GetCheckpoint(root, sessionID, n)
+	if err != nil {
+		return "", err
+	}
+	current, err := snapshotTree(root)
+	if err != nil {
+		return "", err
+	}
+	args := []string{"diff", "--no-color", "--no-ext-diff", "-M"}
+	if stat {
+		args = append(args, "--stat")
+	}
+	out, err := statusGit(root, append(args, cp.Tree, current)...)
+	if err != nil {
+		return "", fmt.Errorf("failed to diff checkpoint: %w", err)
+	}
+	return out, nil
+}
+
+// RestoreCheckpoint makes the work tree match checkpoint n: files are
+// rewritten, and files created since are deleted. HEAD, the branch and the
+// index stay as they are, so the restore shows up as uncommitted changes.
+// The current state is checkpointed first so the restore can be undone;
+// that checkpoint is returned (nil when it matched the newest one).
+func RestoreCheckpoint(dir, sessionID string, n int) (*Checkpoint, error) {
+	root, err := GetRepoRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	cp, err := GetCheckpoint(root, sessionID, n)
+	if err != nil {
+		return nil, err
+	}
+	safety, err := CreateCheckpoint(root, sessionID, fmt.Sprintf("Before restoring checkpoint #%d", n))
+	if err != nil {
+		return nil, fmt.Errorf("failed to checkpoint current state: %w", err)
+	}
+	current, err := snapshotTree(root)
+	if err != nil {
+		return safety, err
+	}
+
+	added, err := statusGit(root, "diff", "--name-only", "--no-renames", "--diff-filter=A", "-z", cp.Tree, current)
+	if err != nil {
+		return safety, fmt.Errorf("failed to list files created since checkpoint: %w", err)
+	}
+	for _, path := range strings.Split(added, "\x00") {
+		if path == "" {
+			continue
+		}
+		if err := removeWorkTreeFile(root, path); err != nil {
+			return safety, err
+		}
+	}
+	if err := runGitCmd(root, "restore", "--source="+cp.Commit, "--worktree", "--", "."); err != nil {
+		return safety, err
+	}
+	return safety, nil
+}
+
+// PruneCheckpoints deletes a session's oldest checkpoints beyond keep
+// (0 = no count limit) and those older than maxAge (0 = no age limit). The
+// newest checkpoint is always kept. It returns how many were deleted.
+func PruneCheckpoints(dir, sessionID string, keep int, maxAge time.Duration) (int, error) {
+	checkpoints, err := ListCheckpoints(dir, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	if len(checkpoints) <= 1 {
+		return 0, nil
+	}
+	cutoff := time.Time{}
+	if maxAge > 0 {
+		cutoff = time.Now().Add(-maxAge)
+	}
+	var doomed []string
+	for i, cp := range checkpoints[:len(checkpoints)-1] {
+		tooMany := keep > 0 && len(checkpoints)-i > keep
+		tooOld := !cutoff.IsZero() && cp.CreatedAt.Before(cutoff)
+		if tooMany || tooOld {
+			doomed = append(doomed, cp.Ref)
+		}
+	}
+	return len(doomed), deleteRefs(dir, doomed)
+}
+
+// DeleteCheckpoints removes all of a session's checkpoints and returns how
+// many there were.
+func DeleteCheckpoints(dir, sessionID string) (int, error) {
+	checkpoints, err := ListCheckpoints(dir, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	refs := make([]string, len(checkpoints))
+	for i, cp := range checkpoints {
+		refs[i] = cp.Ref
+	}
+	return len(refs), deleteRefs(dir, refs)
+}
+
+func deleteRefs(dir string, refs []string) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	for _, ref := range refs {
+		b.WriteString("delete " + ref + "\n")
+	}
+	cmd := exec.Command("git", "-C", dir, "update-ref", "--stdin")
+	cmd.Stdin = strings.NewReader(b.String())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to delete checkpoints: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestCreateCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	createTestRepo(t, dir)
+	head := gitOutput(t, dir, "rev-parse", "HEAD")
+
+	writeFile(t, dir, "README.md", "# Changed\n")
+	writeFile(t, dir, "new.txt", "untracked\n")
+	writeFile(t, dir, "staged.txt", "staged\n")
+	runGit(t, dir, "add", "staged.txt")
+	indexBefore := gitOutput(t, dir, "diff", "--cached", "--name-only")
+
+	cp, err := CreateCheckpoint(dir, "sess-1", "Turn 1")
+	if err != nil {
+		t.Fatalf("CreateCheckpoint: %v", err)
+	}
+	if cp == nil || cp.N != 1 || cp.Ref != "refs/agent-deck/checkpoints/sess-1/1" || cp.Head != head {
+		t.Fatalf("checkpoint = %+v", cp)
+	}
+
+	// The snapshot holds every non-ignored file; the user's state is untouched
+	files := gitOutput(t, dir, "ls-tree", "-r", "--name-only", cp.Commit)
+	for _, want := range []string{"README.md", "new.txt", "staged.txt"} {
+		if !strings.Contains(files, want) {
+			t.Errorf("snapshot missing %s: %s", want, files)
+		}
+	}
+	if got := gitOutput(t, dir, "show", cp.Commit+":README.md"); got != "# Changed" {
+		t.Errorf("snapshot README = %q", got)
+	}
+	if gitOutput(t, dir, "rev-parse", "HEAD") != head {
+		t.Error("HEAD moved")
+	}
+	if got := gitOutput(t, dir, "diff", "--cached", "--name-only"); got != indexBefore {
+		t.Errorf("index changed: %q, was %q", got, indexBefore)
+	}
+	if status := gitOutput(t, dir, "status", "--porcelain"); !strings.Contains(status, "?? new.txt") {
+		t.Errorf("new.txt should still be untracked:\n%s", status)
+	}
+
+	// Unchanged work tree: no new checkpoint
+	if again, err := CreateCheckpoint(dir, "sess-1", "Turn 2"); err != nil || again != nil {
+		t.Errorf("unchanged checkpoint = %+v, %v; want nil, nil", again, err)
+	}
+
+	writeFile(t, dir, "new.txt", "edited\n")
+	cp2, err := CreateCheckpoint(dir, "sess-1", "Turn 3")
+	if err != nil || cp2 == nil || cp2.N != 2 {
+		t.Fatalf("second checkpoint = %+v, %v", cp2, err)
+	}
+
+	list, err := ListCheckpoints(dir, "sess-1")
+	if err != nil {
+		t.Fatalf("ListCheckpoints: %v", err)
+	}
+	if len(list) != 2 || list[0].Message != "Turn 1" || list[1].Message != "Turn 3" || list[1].Tree != cp2.Tree {
+		t.Errorf("list = %+v", list)
+	}
+	if other, _ := ListCheckpoints(dir, "sess-10"); len(other) != 0 {
+		t.Errorf("other session's checkpoints = %+v", other)
+	}
+}
+
+func TestCheckpointDiffAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	createTestRepo(t, dir)
+	writeFile(t, dir, "keep.txt", "good\n")
+	if _, err := CreateCheckpoint(dir, "s", "good state"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The agent wrecks things: edits, deletes and creates files
+	writeFile(t, dir, "keep.txt", "broken\n")
+	if err := os.Remove(filepath.Join(dir, "README.md")); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "junk.txt", "junk\n")
+
+	stat, err := CheckpointDiff(dir, "s", 1, true)
+	if err != nil {
+		t.Fatalf("CheckpointDiff: %v", err)
+	}
+	for _, want := range []string{"keep.txt", "README.md", "junk.txt"} {
+		if !strings.Contains(stat, want) {
+			t.Errorf("diffstat missing %s:\n%s", want, stat)
+		}
+	}
+	patch, err := CheckpointDiff(dir, "s", 1, false)
+	if err != nil || !strings.Contains(patch, "-good") || !strings.Contains(patch, "+broken") {
+		t.Errorf("diff = %q, %v", patch, err)
+	}
+	if _, err := CheckpointDiff(dir, "s", 9, false); err == nil {
+		t.Error("diff of a missing checkpoint should fail")
+	}
+
+	safety, err := RestoreCheckpoint(dir, "s", 1)
+	if err != nil {
+		t.Fatalf("RestoreCheckpoint: %v", err)
+	}
+	if safety == nil || safety.N != 2 {
+		t.Errorf("safety checkpoint = %+v, want #2", safety)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "keep.txt")); string(data) != "good\n" {
+		t.Errorf("keep.txt = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "README.md")); err != nil {
+		t.Errorf("README.md not restored: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "junk.txt")); !os.IsNotExist(err) {
+		t.Error("junk.txt should be removed")
+	}
+
+	// The safety checkpoint undoes the restore
+	if _, err := RestoreCheckpoint(dir, "s", 2); err != nil {
+		t.Fatalf("undo restore: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "junk.txt")); string(data) != "junk\n" {
+		t.Errorf("junk.txt after undo = %q", data)
+	}
+}
+
+func TestPruneAndDeleteCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	createTestRepo(t, dir)
+	for i := 0; i < 5; i++ {
+		writeFile(t, dir, "f.txt", strings.Repeat("x", i+1))
+		if _, err := CreateCheckpoint(dir, "s", "turn"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := PruneCheckpoints(dir, "s", 3, 0)
+	if err != nil || removed != 2 {
+		t.Fatalf("PruneCheckpoints = %d, %v; want 2", removed, err)
+	}
+	list, _ := ListCheckpoints(dir, "s")
+	if len(list) != 3 || list[0].N != 3 || list[2].N != 5 {
+		t.Errorf("after prune = %+v", list)
+	}
+
+	// Age limit: everything is "old", but the newest survives
+	if removed, err := PruneCheckpoints(dir, "s", 0, time.Nanosecond); err != nil || removed != 2 {
+		t.Errorf("age prune = %d, %v; want 2", removed, err)
+	}
+
+	// Numbering continues after pruning
+	writeFile(t, dir, "f.txt", "new")
+	if cp, err := CreateCheckpoint(dir, "s", "turn"); err != nil || cp.N != 6 {
+		t.Errorf("checkpoint after prune = %+v, %v; want #6", cp, err)
+	}
+
+	if n, err := DeleteCheckpoints(dir, "s"); err != nil || n != 2 {
+		t.Errorf("DeleteCheckpoints = %d, %v; want 2", n, err)
+	}
+	if list, _ := ListCheckpoints(dir, "s"); len(list) != 0 {
+		t.Errorf("after delete = %+v", list)
+	}
+}
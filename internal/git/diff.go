@@ -0,0 +1,325 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// diffUntrackedMax caps how many untracked files are included in a Diff, so a
+// forgotten build directory can't stall the review screen.
+const diffUntrackedMax = 200
+
+// ErrInvalidBase is returned by GetDiff for a base that is not a commit.
+var ErrInvalidBase = errors.New("invalid diff base")
+
+// Diff is everything a branch would bring into its base: committed, staged,
+// unstaged and untracked changes, compared against the merge base with the
+// base branch.
+type Diff struct {
+	Base      string     `json:"base"`       // Branch compared against, e.g. "main"
+	MergeBase string     `json:"merge_base"` // Common ancestor commit of Base and HEAD
+	Branch    string     `json:"branch,omitempty"`
+	Added     int        `json:"added"`
+	Deleted   int        `json:"deleted"`
+	Files     []FileDiff `json:"files"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// FileDiff is one changed file in a Diff.
+type FileDiff struct {
+	Path    string `json:"path"`
+	OldPath string `json:"old_path,omitempty"` // Set for renames
+	// Status is "M" (modified), "A" (added), "D" (deleted), "R" (renamed)
+	// or "?" (untracked).
+	Status  string `json:"status"`
+	Added   int    `json:"added"`
+	Deleted int    `json:"deleted"`
+	Binary  bool   `json:"binary,omitempty"`
+	Hunks   []Hunk `json:"hunks,omitempty"`
+
+	header []string // Raw patch header lines, reused to build per-hunk patches
+}
+
+// Hunk is one "@@" section of a file's unified diff. Lines keep their
+// leading ' ', '+', '-' or '\' marker.
+type Hunk struct {
+	Header string   `json:"header"`
+	Lines  []string `json:"lines"`
+}
+
+// GetDiff collects the changes in the work tree at dir relative to its merge
+// base with base. An empty base means the repository's default branch. Paths
+// are relative to the work tree root.
+func GetDiff(dir, base string) (*Diff, error) {
+	dir, err := GetRepoRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	if base == "" {
+		detected, err := GetDefaultBranch(dir)
+		if err != nil {
+			return nil, err
+		}
+		base = detected
+	}
+	// base may come from a web query: never let it be parsed as an option
+	if strings.HasPrefix(base, "-") {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidBase, base)
+	}
+	if _, err := statusGit(dir, "rev-parse", "--verify", "--quiet", "--end-of-options", base+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("%w: %q is not a commit", ErrInvalidBase, base)
+	}
+	mergeBase, err := statusGit(dir, "merge-base", base, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find merge base with %s: %w", base, err)
+	}
+	d := &Diff{Base: base, MergeBase: strings.TrimSpace(mergeBase), UpdatedAt: time.Now()}
+	if branch, err := GetCurrentBranch(dir); err == nil && branch != "HEAD" {
+		d.Branch = branch
+	}
+
+	out, err := statusGit(dir, "-c", "core.quotepath=off", "diff", "--no-color", "--no-ext-diff", "-M", d.MergeBase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff against %s: %w", base, err)
+	}
+	d.Files = parseUnifiedDiff(out)
+
+	untracked, err := statusGit(dir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list untracked files: %w", err)
+	}
+	paths := strings.Split(strings.TrimRight(untracked, "\x00"), "\x00")
+	if len(paths) > diffUntrackedMax {
+		paths = paths[:diffUntrackedMax]
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		files := parseUnifiedDiff(untrackedDiff(dir, path))
+		if len(files) == 0 {
+			// Empty files produce no patch
+			files = []FileDiff{{Path: path}}
+		}
+		f := files[0]
+		f.Path = path
+		f.Status = "?"
+		d.Files = append(d.Files, f)
+	}
+
+	for _, f := range d.Files {
+		d.Added += f.Added
+		d.Deleted += f.Deleted
+	}
+	return d, nil
+}
+
+// untrackedDiff renders an untracked file as a new-file patch. `git diff
+// --no-index` exits 1 when the inputs differ, which is always the case here.
+func untrackedDiff(dir, path string) string {
+	cmd := exec.Command("git", "-C", dir, "-c", "core.quotepath=off", "diff", "--no-color", "--no-ext-diff", "--no-index", "--", os.DevNull, path)
+	cmd.Env = append(os.Environ(), "GIT_OPTIONAL_LOCKS=0")
+	out, _ := cmd.Output()
+	return string(out)
+}
+
+// parseUnifiedDiff splits `git diff` output into files and hunks.
+func parseUnifiedDiff(output string) []FileDiff {
+	var files []FileDiff
+	var file *FileDiff
+	var hunk *Hunk
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "diff --git ") {
+			files = append(files, FileDiff{Status: "M"})
+			file = &files[len(files)-1]
+			hunk = nil
+			file.Path = diffGitPath(strings.TrimPrefix(line, "diff --git "))
+			file.header = append(file.header, line)
+			continue
+		}
+		if file == nil {
+			continue
+		}
+		if strings.HasPrefix(line, "@@") {
+			file.Hunks = append(file.Hunks, Hunk{Header: line})
+			hunk = &file.Hunks[len(file.Hunks)-1]
+			continue
+		}
+		if hunk != nil {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				file.Added++
+			case strings.HasPrefix(line, "-"):
+				file.Deleted++
+			case strings.HasPrefix(line, " "), strings.HasPrefix(line, `\`), line == "":
+			default:
+				continue
+			}
+			hunk.Lines = append(hunk.Lines, line)
+			continue
+		}
+
+		file.header = append(file.header, line)
+		switch {
+		case strings.HasPrefix(line, "new file mode"):
+			file.Status = "A"
+		case strings.HasPrefix(line, "deleted file mode"):
+			file.Status = "D"
+		case strings.HasPrefix(line, "rename from "):
+			file.Status = "R"
+			file.OldPath = unquoteDiffPath(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			file.Path = unquoteDiffPath(strings.TrimPrefix(line, "rename to "))
+		case strings.HasPrefix(line, "Binary files "), strings.HasPrefix(line, "GIT binary patch"):
+			file.Binary = true
+		case strings.HasPrefix(line, "+++ "):
+			if p := strings.TrimPrefix(line, "+++ "); p != "/dev/null" {
+				file.Path = strings.TrimPrefix(unquoteDiffPath(strings.TrimSuffix(p, "\t")), "b/")
+			}
+		}
+	}
+	return files
+}
+
+// diffGitPath extracts the path from the "a/X b/X" part of a "diff --git"
+// line. Both halves name the same file unless it was renamed, in which case
+// the "rename to" line supplies the path instead.
+func diffGitPath(rest string) string {
+	if strings.HasPrefix(rest, `"`) {
+		// Quoted: "a/x" "b/x" (or a mix); take the second token
+		if i := strings.LastIndex(rest, ` "b/`); i >= 0 {
+			return strings.TrimPrefix(unquoteDiffPath(rest[i+1:]), "b/")
+		}
+		if i := strings.LastIndex(rest, " b/"); i >= 0 {
+			return rest[i+3:]
+		}
+		return rest
+	}
+	if n := (len(rest) - 5) / 2; n > 0 && len(rest) == 2*n+5 && rest[:2+n] == "a/"+rest[n+5:] {
+		return rest[n+5:]
+	}
+	if i := strings.LastIndex(rest, " b/"); i >= 0 {
+		return unquoteDiffPath(rest[i+1:])[2:]
+	}
+	return rest
+}
+
+// unquoteDiffPath undoes git's C-style quoting of unusual path names.
+func unquoteDiffPath(p string) string {
+	if len(p) >= 2 && p[0] == '"' && p[len(p)-1] == '"' {
+		if s, err := strconv.Unquote(p); err == nil {
+			return s
+		}
+	}
+	return p
+}
+
+// Patch returns a patch containing only hunk i of the file, suitable for
+// `git apply`.
+func (f *FileDiff) Patch(i int) (string, error) {
+	if i < 0 || i >= len(f.Hunks) {
+		return "", fmt.Errorf("hunk %d out of range", i)
+	}
+	if f.Binary || len(f.header) == 0 {
+		return "", errors.New("file has no text hunks")
+	}
+	var b strings.Builder
+	for _, l := range f.header {
+		b.WriteString(l)
+		b.WriteString("\n")
+	}
+	b.WriteString(f.Hunks[i].Header)
+	b.WriteString("\n")
+	for _, l := range f.Hunks[i].Lines {
+		b.WriteString(l)
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// RevertFile restores a file in the work tree at dir to its state at the
+// merge base: added and untracked files are deleted, modified and deleted
+// files are checked out from the merge base, and renames are undone.
+func RevertFile(dir, mergeBase string, f FileDiff) error {
+	dir, err := GetRepoRoot(dir)
+	if err != nil {
+		return err
+	}
+	switch f.Status {
+	case "?":
+		return removeWorkTreeFile(dir, f.Path)
+	case "A":
+		if err := runGitCmd(dir, "rm", "-q", "--cached", "--ignore-unmatch", "--", f.Path); err != nil {
+			return err
+		}
+		return removeWorkTreeFile(dir, f.Path)
+	case "R":
+		if err := runGitCmd(dir, "rm", "-q", "--cached", "--ignore-unmatch", "--", f.Path); err != nil {
+			return err
+		}
+		if err := removeWorkTreeFile(dir, f.Path); err != nil {
+			return err
+		}
+		return runGitCmd(dir, "checkout", mergeBase, "--", f.OldPath)
+	default:
+		return runGitCmd(dir, "checkout", mergeBase, "--", f.Path)
+	}
+}
+
+// RevertHunk undoes hunk i of a file in the work tree at dir by applying the
+// hunk in reverse. The index is left alone.
+func RevertHunk(dir string, f FileDiff, i int) error {
+	patch, err := f.Patch(i)
+	if err != nil {
+		return err
+	}
+	dir, err = GetRepoRoot(dir)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("git", "-C", dir, "apply", "-R", "--recount", "-")
+	cmd.Stdin = strings.NewReader(patch)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to revert hunk: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// CommitAll stages every change in the work tree at dir (including new and
+// deleted files) and commits it with message.
+func CommitAll(dir, message string) error {
+	if strings.TrimSpace(message) == "" {
+		return errors.New("commit message is required")
+	}
+	if err := runGitCmd(dir, "add", "-A"); err != nil {
+		return err
+	}
+	return runGitCmd(dir, "commit", "-q", "-m", message)
+}
+
+func removeWorkTreeFile(dir, path string) error {
+	if err := os.Remove(filepath.Join(dir, path)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
+
+// runGitCmd runs a mutating git command, folding its output into the error.
+func runGitCmd(dir string, args ...string) error {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s failed: %s: %w", args[0], strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseUnifiedDiff(t *testing.T) {
+	output := strings.Join([]string{
+		"diff --git a/main.go b/main.go",
+		"index 1111111..2222222 100644",
+		"--- a/main.go",
+		"+++ b/main.go",
+		"@@ -1,3 +1,3 @@",
+		" package main",
+		"-var a = 1",
+		"+var a = 2",
+		" ",
+		"@@ -10,2 +10,3 @@ func main() {",
+		" \tx()",
+		"+\ty()",
+		" }",
+		"diff --git a/docs/old name.md b/docs/new name.md",
+		"similarity index 90%",
+		"rename from docs/old name.md",
+		"rename to docs/new name.md",
+		"diff --git a/gone.txt b/gone.txt",
+		"deleted file mode 100644",
+		"--- a/gone.txt",
+		"+++ /dev/null",
+		"@@ -1 +0,0 @@",
+		"-bye",
+		"\\ No newline at end of file",
+		"diff --git a/logo.png b/logo.png",
+		"new file mode 100644",
+		"Binary files /dev/null and b/logo.png differ",
+	}, "\n")
+
+	files := parseUnifiedDiff(output)
+	if len(files) != 4 {
+		t.Fatalf("files = %d, want 4: %+v", len(files), files)
+	}
+
+	m := files[0]
+	if m.Path != "main.go" || m.Status != "M" || m.Added != 2 || m.Deleted != 1 || len(m.Hunks) != 2 {
+		t.Errorf("modified = %+v", m)
+	}
+	if len(m.Hunks[0].Lines) != 4 || m.Hunks[1].Header != "@@ -10,2 +10,3 @@ func main() {" {
+		t.Errorf("hunks = %+v", m.Hunks)
+	}
+	if r := files[1]; r.Path != "docs/new name.md" || r.OldPath != "docs/old name.md" || r.Status != "R" {
+		t.Errorf("rename = %+v", r)
+	}
+	if d := files[2]; d.Path != "gone.txt" || d.Status != "D" || d.Deleted != 1 || len(d.Hunks[0].Lines) != 2 {
+		t.Errorf("delete = %+v", d)
+	}
+	if b := files[3]; b.Path != "logo.png" || b.Status != "A" || !b.Binary {
+		t.Errorf("binary = %+v", b)
+	}
+
+	patch, err := m.Patch(1)
+	if err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	if !strings.HasPrefix(patch, "diff --git a/main.go b/main.go\n") || strings.Contains(patch, "var a") || !strings.Contains(patch, "+\ty()\n") {
+		t.Errorf("patch for hunk 1 =\n%s", patch)
+	}
+	if _, err := files[3].Patch(0); err == nil {
+		t.Error("Patch on a binary file should fail")
+	}
+}
+
+func TestDiffGitPath(t *testing.T) {
+	tests := map[string]string{
+		"a/x.go b/x.go":               "x.go",
+		"a/a b/c.txt b/a b/c.txt":     "a b/c.txt",
+		`"a/tab\there" "b/tab\there"`: "tab\there",
+		"a/old.go b/new.go":           "new.go",
+		"a/dir/ b/x b/dir/ b/x":       "dir/ b/x",
+	}
+	for in, want := range tests {
+		if got := diffGitPath(in); got != want {
+			t.Errorf("diffGitPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// setupDiffRepo creates a repo on main with a feature branch carrying one
+// commit plus uncommitted and untracked changes.
+func setupDiffRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	createTestRepo(t, dir)
+	runGit(t, dir, "branch", "-M", "main")
+	writeFile(t, dir, "lib.go", "package lib\n\nfunc A() int { return 1 }\n\nfunc B() int { return 2 }\n")
+	runGit(t, dir, "add", "lib.go")
+	runGit(t, dir, "commit", "-q", "-m", "lib")
+
+	runGit(t, dir, "checkout", "-q", "-b", "feature")
+	writeFile(t, dir, "feature.go", "package lib\n")
+	runGit(t, dir, "add", "feature.go")
+	runGit(t, dir, "commit", "-q", "-m", "feature")
+
+	// Main moves on; its changes must not show up in the feature's diff
+	runGit(t, dir, "checkout", "-q", "main")
+	writeFile(t, dir, "main-only.txt", "main\n")
+	runGit(t, dir, "add", "main-only.txt")
+	runGit(t, dir, "commit", "-q", "-m", "main only")
+	runGit(t, dir, "checkout", "-q", "feature")
+
+	writeFile(t, dir, "lib.go", "package lib\n\nfunc A() int { return 10 }\n\nfunc B() int { return 2 }\n\nfunc C() int { return 3 }\n")
+	writeFile(t, dir, "notes.txt", "todo\n")
+	return dir
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func findFileDiff(d *Diff, path string) *FileDiff {
+	for i := range d.Files {
+		if d.Files[i].Path == path {
+			return &d.Files[i]
+		}
+	}
+	return nil
+}
+
+func TestGetDiff(t *testing.T) {
+	dir := setupDiffRepo(t)
+
+	d, err := GetDiff(dir, "")
+	if err != nil {
+		t.Fatalf("GetDiff: %v", err)
+	}
+	if d.Base != "main" || d.Branch != "feature" || len(d.MergeBase) != 40 {
+		t.Errorf("diff = base %q branch %q merge base %q", d.Base, d.Branch, d.MergeBase)
+	}
+	if len(d.Files) != 3 {
+		t.Fatalf("files = %+v, want feature.go, lib.go, notes.txt", d.Files)
+	}
+	if f := findFileDiff(d, "feature.go"); f == nil || f.Status != "A" || f.Added != 1 {
+		t.Errorf("feature.go = %+v", f)
+	}
+	if f := findFileDiff(d, "lib.go"); f == nil || f.Status != "M" || f.Added != 3 || f.Deleted != 1 {
+		t.Errorf("lib.go = %+v", f)
+	}
+	if f := findFileDiff(d, "notes.txt"); f == nil || f.Status != "?" || f.Added != 1 {
+		t.Errorf("notes.txt = %+v", f)
+	}
+	if findFileDiff(d, "main-only.txt") != nil {
+		t.Error("changes made on the base after branching should be excluded")
+	}
+	if d.Added != 5 || d.Deleted != 1 {
+		t.Errorf("totals = +%d -%d, want +5 -1", d.Added, d.Deleted)
+	}
+
+	for _, base := range []string{"no-such-branch", "--output=" + filepath.Join(dir, "out"), "-p"} {
+		if _, err := GetDiff(dir, base); !errors.Is(err, ErrInvalidBase) {
+			t.Errorf("GetDiff(%q) err = %v, want ErrInvalidBase", base, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out")); err == nil {
+		t.Error("an option-shaped base must not reach git as an option")
+	}
+}
+
+func TestRevertHunk(t *testing.T) {
+	dir := setupDiffRepo(t)
+	d, err := GetDiff(dir, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib := findFileDiff(d, "lib.go")
+	if lib == nil || len(lib.Hunks) != 1 {
+		t.Fatalf("lib.go = %+v", lib)
+	}
+	if err := RevertHunk(dir, *lib, 0); err != nil {
+		t.Fatalf("RevertHunk: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "lib.go"))
+	if !strings.Contains(string(data), "return 1 }") || strings.Contains(string(data), "func C") {
+		t.Errorf("lib.go after revert =\n%s", data)
+	}
+	if err := RevertHunk(dir, *lib, 0); err == nil {
+		t.Error("reverting an already reverted hunk should fail")
+	}
+}
+
+func TestRevertFile(t *testing.T) {
+	dir := setupDiffRepo(t)
+	d, err := GetDiff(dir, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"feature.go", "lib.go", "notes.txt"} {
+		if err := RevertFile(dir, d.MergeBase, *findFileDiff(d, path)); err != nil {
+			t.Fatalf("RevertFile(%s): %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); !os.IsNotExist(err) {
+		t.Error("untracked file should be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "feature.go")); !os.IsNotExist(err) {
+		t.Error("added file should be deleted")
+	}
+
+	// With everything reverted, committing leaves nothing to merge
+	if err := CommitAll(dir, "revert feature"); err != nil {
+		t.Fatalf("CommitAll: %v", err)
+	}
+	after, err := GetDiff(dir, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after.Files) != 0 {
+		t.Errorf("files after revert = %+v, want none", after.Files)
+	}
+}
+
+func TestCommitAll(t *testing.T) {
+	dir := setupDiffRepo(t)
+	if err := CommitAll(dir, "  "); err == nil {
+		t.Error("CommitAll without a message should fail")
+	}
+	if err := CommitAll(dir, "wip"); err != nil {
+		t.Fatalf("CommitAll: %v", err)
+	}
+	if dirty, err := HasUncommittedChanges(dir); err != nil || dirty {
+		t.Errorf("dirty = %v (%v) after CommitAll", dirty, err)
+	}
+	d, err := GetDiff(dir, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := findFileDiff(d, "notes.txt"); f == nil || f.Status != "A" {
+		t.Errorf("committed untracked file = %+v, want status A", f)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -393,6 +394,20 @@ func GetDefaultBranch(repoDir string) (string, error) {
 	return "", errors.New("could not determine default branch (no origin/HEAD, no main or master branch)")
 }
 
+// CommitsAhead returns the number of commits on branch that are not on base
+func CommitsAhead(repoDir, base, branch string) (int, error) {
+	cmd := exec.Command("git", "-C", repoDir, "rev-list", "--count", base+".."+branch)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("failed to compare %s with %s: %s: %w", branch, base, strings.TrimSpace(string(output)), err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected rev-list output %q", strings.TrimSpace(string(output)))
+	}
+	return n, nil
+}
+
 // MergeBranch merges the given branch into the current branch of the repository
 func MergeBranch(repoDir, branchName string) error {
 	cmd := exec.Command("git", "-C", repoDir, "merge", branchName)
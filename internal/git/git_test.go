@@ -917,6 +917,31 @@ func TestMergeBranch(t *testing.T) {
 	})
 }
 
+func TestCommitsAhead(t *testing.T) {
+	dir := t.TempDir()
+	createTestRepo(t, dir)
+	base, err := GetCurrentBranch(dir)
+	if err != nil {
+		t.Fatalf("GetCurrentBranch: %v", err)
+	}
+	createBranch(t, dir, "feature-ahead")
+
+	if n, err := CommitsAhead(dir, base, "feature-ahead"); err != nil || n != 0 {
+		t.Errorf("CommitsAhead = %d, %v; want 0", n, err)
+	}
+	cmd := exec.Command("git", "commit", "-q", "--allow-empty", "-m", "more")
+	cmd.Dir = dir
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if n, err := CommitsAhead(dir, "feature-ahead", base); err != nil || n != 1 {
+		t.Errorf("CommitsAhead = %d, %v; want 1", n, err)
+	}
+	if _, err := CommitsAhead(dir, base, "missing"); err == nil {
+		t.Error("unknown branch should fail")
+	}
+}
+
 func TestPruneWorktrees(t *testing.T) {
 	t.Run("prune after manually removing worktree dir", func(t *testing.T) {
 		dir := t.TempDir()
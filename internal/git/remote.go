@@ -0,0 +1,103 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrBranchDiverged is returned by FetchRefToBranch when the local branch
+// can't be fast-forwarded to the fetched ref.
+var ErrBranchDiverged = errors.New("branch has diverged")
+
+// ListRemotes returns the names of the repository's remotes.
+func ListRemotes(repoDir string) ([]string, error) {
+	output, err := exec.Command("git", "-C", repoDir, "remote").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remotes: %w", err)
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// RemoteURL returns the fetch URL of a remote.
+func RemoteURL(repoDir, remote string) (string, error) {
+	output, err := exec.Command("git", "-C", repoDir, "remote", "get-url", remote).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("unknown remote %q: %s", remote, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// SplitRemoteBranch splits a remote branch reference such as
+// "origin/feature/x" (or "refs/remotes/origin/feature/x") into the remote
+// and branch names. The longest matching remote name wins, so remotes that
+// contain slashes work too.
+func SplitRemoteBranch(repoDir, ref string) (remote, branch string, err error) {
+	ref = strings.TrimPrefix(ref, "refs/remotes/")
+	remotes, err := ListRemotes(repoDir)
+	if err != nil {
+		return "", "", err
+	}
+	for _, r := range remotes {
+		if strings.HasPrefix(ref, r+"/") && len(r) > len(remote) {
+			remote = r
+		}
+	}
+	if remote == "" {
+		return "", "", fmt.Errorf("%q does not name a remote branch (expected <remote>/<branch>; remotes: %s)",
+			ref, strings.Join(remotes, ", "))
+	}
+	branch = strings.TrimPrefix(ref, remote+"/")
+	if err := ValidateBranchName(branch); err != nil {
+		return "", "", fmt.Errorf("invalid branch name: %w", err)
+	}
+	return remote, branch, nil
+}
+
+// FetchBranch fetches a branch from a remote, updating its remote-tracking
+// ref (refs/remotes/<remote>/<branch>).
+func FetchBranch(repoDir, remote, branch string) error {
+	refspec := fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", branch, remote, branch)
+	output, err := exec.Command("git", "-C", repoDir, "fetch", remote, refspec).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s/%s: %s: %w", remote, branch, strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// CreateTrackingBranch creates a local branch from remote/branch with
+// upstream tracking set, so pull and push go to the remote branch.
+func CreateTrackingBranch(repoDir, localBranch, remote, branch string) error {
+	output, err := exec.Command("git", "-C", repoDir, "branch", "--track", localBranch, remote+"/"+branch).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to create branch %s: %s: %w", localBranch, strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// FetchRefToBranch fetches an arbitrary ref from a remote (e.g. a pull
+// request head such as refs/pull/123/head) into a local branch, creating it
+// or fast-forwarding it. A branch that can't be fast-forwarded is left alone
+// and ErrBranchDiverged returned.
+func FetchRefToBranch(repoDir, remote, ref, localBranch string) error {
+	refspec := fmt.Sprintf("%s:refs/heads/%s", ref, localBranch)
+	output, err := exec.Command("git", "-C", repoDir, "fetch", remote, refspec).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(output), "non-fast-forward") {
+			return fmt.Errorf("%w: %s has commits that are not in %s on %s", ErrBranchDiverged, localBranch, ref, remote)
+		}
+		return fmt.Errorf("failed to fetch %s from %s: %s: %w", ref, remote, strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// PushBranch pushes a local branch to remote/branch and sets it as the
+// branch's upstream.
+func PushBranch(repoDir, remote, branch string) error {
+	output, err := exec.Command("git", "-C", repoDir, "push", "--set-upstream", remote, branch).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to push %s to %s: %s: %w", branch, remote, strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
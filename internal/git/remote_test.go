@@ -0,0 +1,122 @@
+package git
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// createRemotePair creates an upstream repo with a feature branch and a clone
+// of it whose remote is named origin.
+func createRemotePair(t *testing.T) (upstream, clone string) {
+	t.Helper()
+	upstream = t.TempDir()
+	createTestRepo(t, upstream)
+	gitOutput(t, upstream, "checkout", "-q", "-b", "feature/x")
+	gitOutput(t, upstream, "commit", "-q", "--allow-empty", "-m", "feature work")
+	gitOutput(t, upstream, "checkout", "-q", "-")
+
+	clone = filepath.Join(t.TempDir(), "clone")
+	if out, err := exec.Command("git", "clone", "-q", upstream, clone).CombinedOutput(); err != nil {
+		t.Fatalf("clone: %v: %s", err, out)
+	}
+	return upstream, clone
+}
+
+func TestSplitRemoteBranch(t *testing.T) {
+	_, clone := createRemotePair(t)
+	gitOutput(t, clone, "remote", "add", "origin/mirror", "https://example.com/mirror.git")
+
+	tests := []struct {
+		ref, remote, branch string
+	}{
+		{"origin/feature/x", "origin", "feature/x"},
+		{"refs/remotes/origin/main", "origin", "main"},
+		{"origin/mirror/dev", "origin/mirror", "dev"},
+	}
+	for _, tt := range tests {
+		remote, branch, err := SplitRemoteBranch(clone, tt.ref)
+		if err != nil || remote != tt.remote || branch != tt.branch {
+			t.Errorf("SplitRemoteBranch(%q) = %q, %q, %v; want %q, %q", tt.ref, remote, branch, err, tt.remote, tt.branch)
+		}
+	}
+	if _, _, err := SplitRemoteBranch(clone, "upstream/main"); err == nil {
+		t.Error("unknown remote should fail")
+	}
+}
+
+func TestFetchBranchAndTrack(t *testing.T) {
+	upstream, clone := createRemotePair(t)
+	gitOutput(t, upstream, "commit", "-q", "--allow-empty", "-m", "more")
+	gitOutput(t, upstream, "branch", "-q", "late")
+
+	if err := FetchBranch(clone, "origin", "late"); err != nil {
+		t.Fatalf("FetchBranch: %v", err)
+	}
+	if err := CreateTrackingBranch(clone, "late", "origin", "late"); err != nil {
+		t.Fatalf("CreateTrackingBranch: %v", err)
+	}
+	if got := gitOutput(t, clone, "rev-parse", "--abbrev-ref", "late@{upstream}"); got != "origin/late" {
+		t.Errorf("upstream = %q, want origin/late", got)
+	}
+	if err := FetchBranch(clone, "origin", "missing"); err == nil {
+		t.Error("fetching a missing branch should fail")
+	}
+
+	if url, err := RemoteURL(clone, "origin"); err != nil || url != upstream {
+		t.Errorf("RemoteURL = %q, %v; want %q", url, err, upstream)
+	}
+}
+
+func TestFetchRefToBranch(t *testing.T) {
+	upstream, clone := createRemotePair(t)
+	// Simulate a forge's pull request ref
+	gitOutput(t, upstream, "update-ref", "refs/pull/7/head", "feature/x")
+
+	if err := FetchRefToBranch(clone, "origin", "refs/pull/7/head", "pr-7"); err != nil {
+		t.Fatalf("FetchRefToBranch: %v", err)
+	}
+	if got, want := gitOutput(t, clone, "rev-parse", "pr-7"), gitOutput(t, upstream, "rev-parse", "feature/x"); got != want {
+		t.Errorf("pr-7 = %s, want %s", got, want)
+	}
+
+	// An existing branch is fast-forwarded to the new head
+	gitOutput(t, upstream, "checkout", "-q", "feature/x")
+	gitOutput(t, upstream, "commit", "-q", "--allow-empty", "-m", "pushed again")
+	gitOutput(t, upstream, "update-ref", "refs/pull/7/head", "feature/x")
+	if err := FetchRefToBranch(clone, "origin", "refs/pull/7/head", "pr-7"); err != nil {
+		t.Fatalf("FetchRefToBranch fast-forward: %v", err)
+	}
+	if got, want := gitOutput(t, clone, "rev-parse", "pr-7"), gitOutput(t, upstream, "rev-parse", "feature/x"); got != want {
+		t.Errorf("pr-7 = %s, want %s", got, want)
+	}
+
+	// ...but not moved off commits the ref doesn't have
+	gitOutput(t, upstream, "commit", "-q", "--amend", "--allow-empty", "-m", "rewritten")
+	gitOutput(t, upstream, "update-ref", "refs/pull/7/head", "feature/x")
+	if err := FetchRefToBranch(clone, "origin", "refs/pull/7/head", "pr-7"); !errors.Is(err, ErrBranchDiverged) {
+		t.Errorf("diverged branch: err = %v, want ErrBranchDiverged", err)
+	}
+}
+
+func TestPushBranch(t *testing.T) {
+	upstream, clone := createRemotePair(t)
+	gitOutput(t, clone, "config", "user.email", "test@test.com")
+	gitOutput(t, clone, "config", "user.name", "Test User")
+	gitOutput(t, clone, "checkout", "-q", "-b", "pushed")
+	gitOutput(t, clone, "commit", "-q", "--allow-empty", "-m", "local work")
+
+	if err := PushBranch(clone, "origin", "pushed"); err != nil {
+		t.Fatalf("PushBranch: %v", err)
+	}
+	if !BranchExists(upstream, "pushed") {
+		t.Error("branch should exist upstream")
+	}
+	if got := gitOutput(t, clone, "rev-parse", "--abbrev-ref", "pushed@{upstream}"); got != "origin/pushed" {
+		t.Errorf("upstream = %q, want origin/pushed", got)
+	}
+	if err := PushBranch(clone, "nowhere", "pushed"); err == nil {
+		t.Error("pushing to an unknown remote should fail")
+	}
+}
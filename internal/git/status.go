@@ -0,0 +1,232 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Status is a snapshot of a working tree: where the branch stands relative to
+// its upstream and the repository's default branch, and what has changed
+// since the last commit.
+type Status struct {
+	Branch   string `json:"branch,omitempty"` // Empty when HEAD is detached
+	Commit   string `json:"commit,omitempty"` // HEAD commit SHA (empty before the first commit)
+	Upstream string `json:"upstream,omitempty"`
+	Ahead    int    `json:"ahead"`  // Commits on HEAD not on the upstream
+	Behind   int    `json:"behind"` // Commits on the upstream not on HEAD
+
+	DefaultBranch string `json:"default_branch,omitempty"` // Ref compared against, e.g. "origin/main"
+	AheadDefault  int    `json:"ahead_default"`
+	BehindDefault int    `json:"behind_default"`
+
+	Staged     int `json:"staged"`     // Files with changes in the index
+	Unstaged   int `json:"unstaged"`   // Tracked files with changes not yet staged
+	Untracked  int `json:"untracked"`  // Files git doesn't track (ignored files excluded)
+	Conflicted int `json:"conflicted"` // Files with unresolved merge conflicts
+
+	LinesAdded   int `json:"lines_added"` // Working tree vs HEAD, staged and unstaged
+	LinesDeleted int `json:"lines_deleted"`
+
+	Files     []FileChange `json:"files,omitempty"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// FileChange is one changed path in a Status.
+type FileChange struct {
+	Path    string `json:"path"`
+	Code    string `json:"code"` // Two-letter index/worktree code as in `git status --short`, e.g. "M ", " M", "??"
+	Added   int    `json:"added"`
+	Deleted int    `json:"deleted"`
+	Binary  bool   `json:"binary,omitempty"`
+}
+
+// Dirty reports whether the working tree has any uncommitted changes.
+func (s *Status) Dirty() bool {
+	return s.Staged+s.Unstaged+s.Untracked+s.Conflicted > 0
+}
+
+// GetStatus collects the Status of the working tree containing dir.
+func GetStatus(dir string) (*Status, error) {
+	out, err := statusGit(dir, "status", "--porcelain=v2", "--branch", "--untracked-files=normal")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git status: %w", err)
+	}
+	s := parseStatusV2(out)
+	s.UpdatedAt = time.Now()
+
+	// Line stats against HEAD cover staged and unstaged changes together.
+	// Before the first commit there is no HEAD, so only the index counts.
+	numstatArgs := []string{"diff", "--numstat", "--no-renames", "HEAD"}
+	if s.Commit == "" {
+		numstatArgs = []string{"diff", "--numstat", "--no-renames", "--cached"}
+	}
+	if numstat, err := statusGit(dir, numstatArgs...); err == nil {
+		applyNumstat(s, numstat)
+	}
+
+	if s.Commit != "" {
+		// On the default branch itself the upstream counts already say it all
+		if ref := defaultBranchRef(dir); ref != "" && ref != s.Branch && ref != s.Upstream {
+			if ahead, behind, ok := aheadBehind(dir, ref); ok {
+				s.DefaultBranch = ref
+				s.AheadDefault = ahead
+				s.BehindDefault = behind
+			}
+		}
+	}
+	return s, nil
+}
+
+// statusGit runs a read-only git command. GIT_OPTIONAL_LOCKS=0 stops
+// `git status` from rewriting the index, so polling never races the agent's
+// own git commands or wakes file watchers.
+func statusGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_OPTIONAL_LOCKS=0")
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%s: %w", strings.TrimSpace(string(exitErr.Stderr)), err)
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+// parseStatusV2 parses `git status --porcelain=v2 --branch`.
+func parseStatusV2(output string) *Status {
+	s := &Status{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "# branch.oid "):
+			if oid := strings.TrimPrefix(line, "# branch.oid "); oid != "(initial)" {
+				s.Commit = oid
+			}
+		case strings.HasPrefix(line, "# branch.head "):
+			if head := strings.TrimPrefix(line, "# branch.head "); head != "(detached)" {
+				s.Branch = head
+			}
+		case strings.HasPrefix(line, "# branch.upstream "):
+			s.Upstream = strings.TrimPrefix(line, "# branch.upstream ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			var ahead, behind int
+			if _, err := fmt.Sscanf(strings.TrimPrefix(line, "# branch.ab "), "+%d -%d", &ahead, &behind); err == nil {
+				s.Ahead, s.Behind = ahead, behind
+			}
+		case strings.HasPrefix(line, "1 "), strings.HasPrefix(line, "2 "):
+			// 1 XY sub mH mI mW hH hI path
+			// 2 XY sub mH mI mW hH hI Xscore path<TAB>origPath
+			fieldCount := 9
+			if line[0] == '2' {
+				fieldCount = 10
+			}
+			fields := strings.SplitN(line, " ", fieldCount)
+			if len(fields) < fieldCount {
+				continue
+			}
+			path, _, _ := strings.Cut(fields[fieldCount-1], "\t")
+			xy := fields[1]
+			if xy[0] != '.' {
+				s.Staged++
+			}
+			if xy[1] != '.' {
+				s.Unstaged++
+			}
+			s.Files = append(s.Files, FileChange{Path: path, Code: strings.ReplaceAll(xy, ".", " ")})
+		case strings.HasPrefix(line, "u "):
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			fields := strings.SplitN(line, " ", 11)
+			if len(fields) < 11 {
+				continue
+			}
+			s.Conflicted++
+			s.Files = append(s.Files, FileChange{Path: fields[10], Code: fields[1]})
+		case strings.HasPrefix(line, "? "):
+			s.Untracked++
+			s.Files = append(s.Files, FileChange{Path: strings.TrimPrefix(line, "? "), Code: "??"})
+		}
+	}
+	return s
+}
+
+// applyNumstat adds `git diff --numstat` line counts to s and its files.
+func applyNumstat(s *Status, output string) {
+	byPath := make(map[string]int, len(s.Files))
+	for i, f := range s.Files {
+		byPath[f.Path] = i
+	}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		added, errA := strconv.Atoi(fields[0])
+		deleted, errD := strconv.Atoi(fields[1])
+		binary := errA != nil || errD != nil
+		s.LinesAdded += added
+		s.LinesDeleted += deleted
+
+		i, ok := byPath[fields[2]]
+		if !ok {
+			// Renames appear as an add and a delete with --no-renames; the
+			// old path has no status entry of its own.
+			continue
+		}
+		s.Files[i].Added = added
+		s.Files[i].Deleted = deleted
+		s.Files[i].Binary = binary
+	}
+	sort.SliceStable(s.Files, func(i, j int) bool {
+		ci, cj := s.Files[i].Added+s.Files[i].Deleted, s.Files[j].Added+s.Files[j].Deleted
+		if ci != cj {
+			return ci > cj
+		}
+		return s.Files[i].Path < s.Files[j].Path
+	})
+}
+
+// defaultBranchRef returns the ref to measure a branch against: the remote's
+// default branch when there is one, otherwise the local default branch.
+func defaultBranchRef(dir string) string {
+	branch, err := GetDefaultBranch(dir)
+	if err != nil {
+		return ""
+	}
+	if _, err := statusGit(dir, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch); err == nil {
+		return "origin/" + branch
+	}
+	return branch
+}
+
+// aheadBehind counts commits on HEAD but not ref, and on ref but not HEAD.
+func aheadBehind(dir, ref string) (ahead, behind int, ok bool) {
+	out, err := statusGit(dir, "rev-list", "--left-right", "--count", ref+"...HEAD")
+	if err != nil {
+		return 0, 0, false
+	}
+	if _, err := fmt.Sscanf(strings.TrimSpace(out), "%d %d", &behind, &ahead); err != nil {
+		return 0, 0, false
+	}
+	return ahead, behind, true
+}
+
+// GetGitDir returns the absolute path of the git directory for the working
+// tree containing dir (for a linked worktree, its .git/worktrees/<name>).
+func GetGitDir(dir string) (string, error) {
+	out, err := statusGit(dir, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", fmt.Errorf("not a git repository: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
@@ -0,0 +1,148 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func TestParseStatusV2(t *testing.T) {
+	output := strings.Join([]string{
+		"# branch.oid 1234567890abcdef1234567890abcdef12345678",
+		"# branch.head feature/login",
+		"# branch.upstream origin/feature/login",
+		"# branch.ab +2 -1",
+		"1 M. N... 100644 100644 100644 aaa bbb internal/auth.go",
+		"1 .M N... 100644 100644 100644 aaa bbb README.md",
+		"1 MM N... 100644 100644 100644 aaa bbb go.mod",
+		"2 R. N... 100644 100644 100644 aaa bbb R100 docs/new name.md\tdocs/old.md",
+		"u UU N... 100644 100644 100644 100644 aaa bbb ccc main.go",
+		"? scratch.txt",
+		"! ignored.log",
+	}, "\n")
+
+	s := parseStatusV2(output)
+	if s.Branch != "feature/login" || s.Upstream != "origin/feature/login" || s.Commit == "" {
+		t.Errorf("branch info = %q %q %q", s.Branch, s.Upstream, s.Commit)
+	}
+	if s.Ahead != 2 || s.Behind != 1 {
+		t.Errorf("ahead/behind = %d/%d, want 2/1", s.Ahead, s.Behind)
+	}
+	if s.Staged != 3 || s.Unstaged != 2 || s.Untracked != 1 || s.Conflicted != 1 {
+		t.Errorf("counts staged=%d unstaged=%d untracked=%d conflicted=%d, want 3/2/1/1",
+			s.Staged, s.Unstaged, s.Untracked, s.Conflicted)
+	}
+	if len(s.Files) != 6 {
+		t.Fatalf("files = %+v, want 6", s.Files)
+	}
+	if s.Files[3].Path != "docs/new name.md" || s.Files[3].Code != "R " {
+		t.Errorf("rename = %+v", s.Files[3])
+	}
+	if s.Files[1].Code != " M" || s.Files[5].Code != "??" {
+		t.Errorf("codes = %q %q", s.Files[1].Code, s.Files[5].Code)
+	}
+	if !s.Dirty() {
+		t.Error("Dirty() = false, want true")
+	}
+
+	detached := parseStatusV2("# branch.oid (initial)\n# branch.head (detached)\n")
+	if detached.Branch != "" || detached.Commit != "" || detached.Dirty() {
+		t.Errorf("detached/initial = %+v", detached)
+	}
+}
+
+func TestApplyNumstat(t *testing.T) {
+	s := &Status{Files: []FileChange{{Path: "a.go", Code: " M"}, {Path: "logo.png", Code: "M "}, {Path: "b.go", Code: " M"}}}
+	applyNumstat(s, "3\t1\ta.go\n-\t-\tlogo.png\n40\t2\tb.go\n5\t0\tgone.go\n")
+	if s.LinesAdded != 48 || s.LinesDeleted != 3 {
+		t.Errorf("lines = +%d -%d, want +48 -3", s.LinesAdded, s.LinesDeleted)
+	}
+	if s.Files[0].Path != "b.go" || s.Files[1].Path != "a.go" {
+		t.Errorf("files should sort by size: %+v", s.Files)
+	}
+	if !s.Files[2].Binary {
+		t.Errorf("logo.png should be binary: %+v", s.Files[2])
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	dir := t.TempDir()
+	createTestRepo(t, dir)
+	runGit(t, dir, "branch", "-M", "main")
+
+	t.Run("clean default branch", func(t *testing.T) {
+		s, err := GetStatus(dir)
+		if err != nil {
+			t.Fatalf("GetStatus: %v", err)
+		}
+		if s.Branch != "main" || s.Dirty() || s.DefaultBranch != "" {
+			t.Errorf("status = %+v, want clean main with no base comparison", s)
+		}
+	})
+
+	runGit(t, dir, "checkout", "-q", "-b", "feature")
+	if err := os.WriteFile(filepath.Join(dir, "feature.go"), []byte("package x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", "feature.go")
+	runGit(t, dir, "commit", "-q", "-m", "feature")
+
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Test Repo\nmore\nlines\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "staged.go"), []byte("package x\n\nfunc f() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", "staged.go")
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("todo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := GetStatus(dir)
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	if s.Branch != "feature" {
+		t.Errorf("Branch = %q, want feature", s.Branch)
+	}
+	if s.DefaultBranch != "main" || s.AheadDefault != 1 || s.BehindDefault != 0 {
+		t.Errorf("default = %q ↑%d↓%d, want main ↑1↓0", s.DefaultBranch, s.AheadDefault, s.BehindDefault)
+	}
+	if s.Staged != 1 || s.Unstaged != 1 || s.Untracked != 1 {
+		t.Errorf("counts = %d/%d/%d, want 1/1/1", s.Staged, s.Unstaged, s.Untracked)
+	}
+	// README: 1 line replaced by 3 (+3 -1); staged.go: +3
+	if s.LinesAdded != 6 || s.LinesDeleted != 1 {
+		t.Errorf("lines = +%d -%d, want +6 -1", s.LinesAdded, s.LinesDeleted)
+	}
+	if s.UpdatedAt.IsZero() {
+		t.Error("UpdatedAt not set")
+	}
+
+	if _, err := GetStatus(t.TempDir()); err == nil {
+		t.Error("GetStatus outside a repository should fail")
+	}
+}
+
+func TestGetGitDir(t *testing.T) {
+	dir := t.TempDir()
+	createTestRepo(t, dir)
+	gitDir, err := GetGitDir(dir)
+	if err != nil {
+		t.Fatalf("GetGitDir: %v", err)
+	}
+	if filepath.Base(gitDir) != ".git" || !filepath.IsAbs(gitDir) {
+		t.Errorf("GetGitDir = %q", gitDir)
+	}
+}
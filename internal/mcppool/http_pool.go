@@ -196,8 +196,10 @@ func (p *HTTPPool) restartFailedServers() {
 		if server != nil {
 			httpPoolLog.Info("auto_restart", slog.String("mcp", name))
 			if err := server.Restart(); err != nil {
+				poolRestarts.Inc(name, transportHTTP, "failed")
 				httpPoolLog.Error("restart_failed", slog.String("mcp", name), slog.String("error", err.Error()))
 			} else {
+				poolRestarts.Inc(name, transportHTTP, "ok")
 				httpPoolLog.Info("restart_success", slog.String("mcp", name))
 			}
 		}
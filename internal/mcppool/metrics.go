@@ -0,0 +1,36 @@
+package mcppool
+
+import "github.com/asheshgoplani/agent-deck/internal/metrics"
+
+// Transport label values for pool metrics.
+const (
+	transportSocket = "socket"
+	transportHTTP   = "http"
+)
+
+// poolRestarts counts MCP server restarts by server, transport and result
+// (ok, failed, rate_limited).
+var poolRestarts = metrics.NewCounterVec("agentdeck_mcp_pool_restarts",
+	"MCP pool server restarts by server, transport and result.", "server", "transport", "result")
+
+// CollectMetrics reports the servers of both pools with their status and
+// client counts. Either pool may be nil.
+func CollectMetrics(pool *Pool, httpPool *HTTPPool) []*metrics.Gauge {
+	servers := metrics.NewGauge("agentdeck_mcp_pool_servers",
+		"MCP pool servers; always 1, labelled with the server's current status.", "server", "transport", "status")
+	clients := metrics.NewGauge("agentdeck_mcp_pool_clients",
+		"Clients connected to a socket pool MCP server.", "server")
+
+	if pool != nil {
+		for _, info := range pool.ListServers() {
+			servers.Set(1, info.Name, transportSocket, info.Status)
+			clients.Set(float64(info.Clients), info.Name)
+		}
+	}
+	if httpPool != nil {
+		for _, info := range httpPool.ListServers() {
+			servers.Set(1, info.Name, transportHTTP, info.Status)
+		}
+	}
+	return []*metrics.Gauge{servers, clients}
+}
@@ -144,16 +144,19 @@ func (p *Pool) RestartProxy(name string) error {
 	// Create and start new proxy
 	newProxy, err := NewSocketProxy(p.ctx, name, proxy.command, proxy.args, proxy.env)
 	if err != nil {
+		poolRestarts.Inc(name, transportSocket, "failed")
 		return fmt.Errorf("failed to create proxy: %w", err)
 	}
 
 	if err := newProxy.Start(); err != nil {
 		// Clean up the failed proxy to avoid leaking its context/goroutines
 		_ = newProxy.Stop()
+		poolRestarts.Inc(name, transportSocket, "failed")
 		return fmt.Errorf("failed to start proxy: %w", err)
 	}
 
 	p.proxies[name] = newProxy
+	poolRestarts.Inc(name, transportSocket, "ok")
 	return nil
 }
 
@@ -295,9 +298,11 @@ func (p *Pool) RestartProxyWithRateLimit(name string) error {
 
 	// Rate limit: minimum 5 seconds between restarts, max 3 per minute
 	if time.Since(proxy.lastRestart) < 5*time.Second {
+		poolRestarts.Inc(name, transportSocket, "rate_limited")
 		return fmt.Errorf("rate limited: last restart was %v ago", time.Since(proxy.lastRestart))
 	}
 	if proxy.restartCount >= 3 && time.Since(proxy.lastRestart) < time.Minute {
+		poolRestarts.Inc(name, transportSocket, "rate_limited")
 		return fmt.Errorf("rate limited: %d restarts in last minute", proxy.restartCount)
 	}
 
@@ -318,6 +323,7 @@ func (p *Pool) RestartProxyWithRateLimit(name string) error {
 	// Create and start new proxy
 	newProxy, err := NewSocketProxy(p.ctx, name, command, args, env)
 	if err != nil {
+		poolRestarts.Inc(name, transportSocket, "failed")
 		return fmt.Errorf("failed to create proxy: %w", err)
 	}
 
@@ -337,6 +343,7 @@ func (p *Pool) RestartProxyWithRateLimit(name string) error {
 			newProxy.SetStatus(StatusFailed)
 		}
 		p.proxies[name] = newProxy
+		poolRestarts.Inc(name, transportSocket, "failed")
 
 		return fmt.Errorf("failed to start proxy: %w", err)
 	}
@@ -347,6 +354,7 @@ func (p *Pool) RestartProxyWithRateLimit(name string) error {
 	newProxy.lastRestart = time.Now()
 
 	p.proxies[name] = newProxy
+	poolRestarts.Inc(name, transportSocket, "ok")
 	poolLog.Info("restart_complete", slog.String("mcp", name), slog.Int("restart_num", newProxy.restartCount))
 
 	return nil
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Exposition content types.
+const (
+	ContentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Format selects the exposition format.
+type Format int
+
+const (
+	// FormatText is the Prometheus text format, version 0.0.4.
+	FormatText Format = iota
+	// FormatOpenMetrics is OpenMetrics 1.0.
+	FormatOpenMetrics
+)
+
+// ContentType returns the Content-Type header for the format.
+func (f Format) ContentType() string {
+	if f == FormatOpenMetrics {
+		return ContentTypeOpenMetrics
+	}
+	return ContentTypeText
+}
+
+// NegotiateFormat picks OpenMetrics when the Accept header asks for it and
+// the Prometheus text format otherwise.
+func NegotiateFormat(accept string) Format {
+	if strings.Contains(accept, "application/openmetrics-text") {
+		return FormatOpenMetrics
+	}
+	return FormatText
+}
+
+// Write renders every registered family, the collectors' gauges and extra
+// in the given format, ordered by metric name.
+func (r *Registry) Write(w io.Writer, format Format, extra ...*Gauge) error {
+	r.mu.Lock()
+	families := make([]family, 0, len(r.families)+len(extra))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	// Collectors run outside the lock; they may be slow or take other locks.
+	for _, c := range collectors {
+		for _, g := range c() {
+			families = append(families, g)
+		}
+	}
+	for _, g := range extra {
+		families = append(families, g)
+	}
+	sort.SliceStable(families, func(i, j int) bool {
+		return families[i].describe().name < families[j].describe().name
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		writeFamily(bw, f, format)
+	}
+	if format == FormatOpenMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP writes the registry in the format the request's Accept header
+// negotiates, followed by extra.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request, extra ...*Gauge) {
+	format := NegotiateFormat(req.Header.Get("Accept"))
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Cache-Control", "no-store")
+	_ = r.Write(w, format, extra...)
+}
+
+func writeFamily(w *bufio.Writer, f family, format Format) {
+	d := f.describe()
+	// The text format names a counter family after its samples; OpenMetrics
+	// uses the bare name.
+	headerName := d.name
+	if d.typ == typeCounter && format == FormatText {
+		headerName += "_total"
+	}
+	w.WriteString("# HELP " + headerName + " " + escapeHelp(d.help) + "\n")
+	w.WriteString("# TYPE " + headerName + " " + d.typ + "\n")
+
+	for _, s := range f.samples() {
+		w.WriteString(d.name + s.suffix)
+		if len(d.labels) > 0 || s.extraName != "" {
+			w.WriteByte('{')
+			for i, name := range d.labels {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				w.WriteString(name + `="` + escapeLabelValue(s.labels[i]) + `"`)
+			}
+			if s.extraName != "" {
+				if len(d.labels) > 0 {
+					w.WriteByte(',')
+				}
+				w.WriteString(s.extraName + `="` + s.extraValue + `"`)
+			}
+			w.WriteByte('}')
+		}
+		w.WriteString(" " + formatFloat(s.value) + "\n")
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,347 @@
+// Package metrics is a small, dependency-free Prometheus instrumentation
+// library. Packages declare counters and histograms as package variables,
+// collectors add gauges computed at scrape time, and Write renders
+// everything in the Prometheus text or OpenMetrics exposition format.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DurationBuckets are histogram upper bounds, in seconds, suited to
+// in-process work from sub-millisecond polls to multi-second stalls.
+var DurationBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metric types as written in # TYPE lines.
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// family is one named metric with its samples, ready to be written.
+type family interface {
+	describe() *desc
+	samples() []sample
+}
+
+// desc describes a metric family.
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d *desc) describe() *desc { return d }
+
+// sample is one exposition line. suffix is appended to the family name
+// ("_total", "_bucket", ...) and extra is an additional label such as le.
+type sample struct {
+	suffix     string
+	labels     []string
+	extraName  string
+	extraValue string
+	value      float64
+}
+
+// Registry holds metric families and scrape-time collectors.
+type Registry struct {
+	mu         sync.Mutex
+	families   map[string]family
+	collectors []Collector
+}
+
+// Collector returns gauges computed at scrape time.
+type Collector func() []*Gauge
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]family)}
+}
+
+// Default is the process-wide registry the New* helpers register with.
+var Default = NewRegistry()
+
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name := f.describe().name
+	if _, exists := r.families[name]; exists {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.families[name] = f
+}
+
+// RegisterCollector adds a collector run on every scrape.
+func (r *Registry) RegisterCollector(c Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// RegisterCollector adds a collector to the Default registry.
+func RegisterCollector(c Collector) {
+	Default.RegisterCollector(c)
+}
+
+// labelKey joins label values into a map key.
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// sortedKeys returns m's keys in order, so exposition is stable.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func checkLabels(d *desc, values []string) {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+}
+
+// --- Counter ---
+
+// CounterVec is a monotonically increasing counter partitioned by labels.
+// Its samples are exposed as <name>_total.
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+// NewCounterVec creates a counter and registers it with Default. name must
+// not end in _total; the suffix is added on exposition.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := newCounterVec(name, help, labels...)
+	Default.register(c)
+	return c
+}
+
+func newCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{
+		desc:   desc{name: name, help: help, typ: typeCounter, labels: labels},
+		values: make(map[string]*counterValue),
+	}
+}
+
+// Inc adds one to the counter with the given label values.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v (which must not be negative) to the counter.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	checkLabels(&c.desc, labelValues)
+	key := labelKey(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cv, ok := c.values[key]
+	if !ok {
+		cv = &counterValue{labels: append([]string(nil), labelValues...)}
+		c.values[key] = cv
+	}
+	cv.value += v
+}
+
+// Value returns the current count for the given label values.
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cv, ok := c.values[labelKey(labelValues)]; ok {
+		return cv.value
+	}
+	return 0
+}
+
+func (c *CounterVec) samples() []sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]sample, 0, len(c.values))
+	for _, key := range sortedKeys(c.values) {
+		cv := c.values[key]
+		out = append(out, sample{suffix: "_total", labels: cv.labels, value: cv.value})
+	}
+	return out
+}
+
+// --- Histogram ---
+
+// HistogramVec counts observations into cumulative buckets, partitioned
+// by labels.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec creates a histogram with the given bucket upper bounds
+// and registers it with Default.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := newHistogramVec(name, help, buckets, labels...)
+	Default.register(h)
+	return h
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &HistogramVec{
+		desc:    desc{name: name, help: help, typ: typeHistogram, labels: labels},
+		buckets: sorted,
+		values:  make(map[string]*histogramValue),
+	}
+}
+
+// Observe records v for the given label values.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	checkLabels(&h.desc, labelValues)
+	key := labelKey(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{
+			labels: append([]string(nil), labelValues...),
+			counts: make([]uint64, len(h.buckets)),
+		}
+		h.values[key] = hv
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		hv.counts[i]++
+	}
+	hv.count++
+	hv.sum += v
+}
+
+// ObserveSince records the seconds elapsed since start.
+func (h *HistogramVec) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Count returns how many observations were made for the label values.
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hv, ok := h.values[labelKey(labelValues)]; ok {
+		return hv.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) samples() []sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]sample, 0, len(h.values)*(len(h.buckets)+3))
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += hv.counts[i]
+			out = append(out, sample{suffix: "_bucket", labels: hv.labels, extraName: "le", extraValue: formatFloat(upper), value: float64(cumulative)})
+		}
+		out = append(out,
+			sample{suffix: "_bucket", labels: hv.labels, extraName: "le", extraValue: "+Inf", value: float64(hv.count)},
+			sample{suffix: "_sum", labels: hv.labels, value: hv.sum},
+			sample{suffix: "_count", labels: hv.labels, value: float64(hv.count)},
+		)
+	}
+	return out
+}
+
+// --- Gauge ---
+
+// Gauge is a set of values computed for a single scrape. Collectors and
+// handlers build one, fill it with Set or Add, and hand it to Write.
+type Gauge struct {
+	desc
+	order  []string
+	values map[string]*counterValue
+}
+
+// NewGauge returns an empty gauge. It is not registered anywhere.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{
+		desc:   desc{name: name, help: help, typ: typeGauge, labels: labels},
+		values: make(map[string]*counterValue),
+	}
+}
+
+func (g *Gauge) value(labelValues []string) *counterValue {
+	checkLabels(&g.desc, labelValues)
+	key := labelKey(labelValues)
+	gv, ok := g.values[key]
+	if !ok {
+		gv = &counterValue{labels: append([]string(nil), labelValues...)}
+		g.values[key] = gv
+		g.order = append(g.order, key)
+	}
+	return gv
+}
+
+// Set sets the value for the given label values.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.value(labelValues).value = v
+}
+
+// Add adds v to the value for the given label values.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.value(labelValues).value += v
+}
+
+// Value returns the value for the given label values.
+func (g *Gauge) Value(labelValues ...string) float64 {
+	if gv, ok := g.values[labelKey(labelValues)]; ok {
+		return gv.value
+	}
+	return 0
+}
+
+func (g *Gauge) samples() []sample {
+	out := make([]sample, 0, len(g.order))
+	for _, key := range g.order {
+		gv := g.values[key]
+		out = append(out, sample{labels: gv.labels, value: gv.value})
+	}
+	return out
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestRegistry() (*Registry, *CounterVec, *HistogramVec) {
+	r := NewRegistry()
+	c := newCounterVec("test_requests", "Requests handled.", "result")
+	h := newHistogramVec("test_duration_seconds", "Time spent.", []float64{0.1, 1}, "op")
+	r.register(c)
+	r.register(h)
+	return r, c, h
+}
+
+func TestWriteTextFormat(t *testing.T) {
+	r, c, h := newTestRegistry()
+	c.Inc("ok")
+	c.Add(2, "ok")
+	c.Inc(`bad "quote"`)
+	h.Observe(0.05, "save")
+	h.Observe(0.5, "save")
+	h.Observe(3, "save")
+
+	g := NewGauge("test_sessions", "Sessions by status.", "status")
+	g.Add(1, "running")
+	g.Add(1, "running")
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf, FormatText, g); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_duration_seconds Time spent.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{op="save",le="0.1"} 1
+test_duration_seconds_bucket{op="save",le="1"} 2
+test_duration_seconds_bucket{op="save",le="+Inf"} 3
+test_duration_seconds_sum{op="save"} 3.55
+test_duration_seconds_count{op="save"} 3
+# HELP test_requests_total Requests handled.
+# TYPE test_requests_total counter
+test_requests_total{result="bad \"quote\""} 1
+test_requests_total{result="ok"} 3
+# HELP test_sessions Sessions by status.
+# TYPE test_sessions gauge
+test_sessions{status="running"} 2
+`
+	if got := buf.String(); got != want {
+		t.Errorf("text exposition mismatch:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteOpenMetricsFormat(t *testing.T) {
+	r, c, _ := newTestRegistry()
+	c.Inc("ok")
+	r.RegisterCollector(func() []*Gauge {
+		g := NewGauge("test_up", "Always one.")
+		g.Set(1)
+		return []*Gauge{g}
+	})
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text;version=1.0.0,text/plain;q=0.5")
+	r.ServeHTTP(rr, req)
+
+	if ct := rr.Header().Get("Content-Type"); ct != ContentTypeOpenMetrics {
+		t.Fatalf("content type = %q", ct)
+	}
+	body := rr.Body.String()
+	for _, line := range []string{
+		"# TYPE test_requests counter\n",
+		"test_requests_total{result=\"ok\"} 1\n",
+		"# TYPE test_up gauge\ntest_up 1\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Errorf("OpenMetrics output must end with # EOF:\n%s", body)
+	}
+}
+
+func TestDuplicateRegistrationPanics(t *testing.T) {
+	r := NewRegistry()
+	r.register(newCounterVec("dup", "first"))
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic on duplicate metric name")
+		}
+	}()
+	r.register(newCounterVec("dup", "second"))
+}
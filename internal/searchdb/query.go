@@ -0,0 +1,230 @@
+package searchdb
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Query is a parsed search request.
+type Query struct {
+	Text    string    // Search text (see BuildMatch)
+	Project string    // Substring of the transcript's working directory
+	Role    string    // "user" or "assistant"
+	Tool    string    // Tool that wrote the transcript ("claude", "codex", ...)
+	Since   time.Time // Messages at or after this time
+	Until   time.Time // Messages before this time
+	Limit   int       // Max matching messages (default 500)
+}
+
+// ParseQuery splits inline filters out of a search string.
+//
+// Recognized filters: project:<substr>, role:<user|assistant>, tool:<name>,
+// since:<date> (alias after:), until:<date> (alias before:).
+// Dates are YYYY-MM-DD or a relative age such as 7d or 12h.
+// Everything else is kept as search text.
+func ParseQuery(input string) (Query, error) {
+	var q Query
+	var text []string
+	for _, tok := range splitTokens(input) {
+		key, value, ok := strings.Cut(tok, ":")
+		if !ok || value == "" || strings.HasPrefix(tok, `"`) {
+			text = append(text, tok)
+			continue
+		}
+		var err error
+		switch strings.ToLower(key) {
+		case "project":
+			q.Project = strings.Trim(value, `"`)
+		case "role":
+			q.Role = strings.ToLower(value)
+			if q.Role != "user" && q.Role != "assistant" {
+				return q, fmt.Errorf("invalid role %q (expected user or assistant)", value)
+			}
+		case "tool":
+			q.Tool = strings.ToLower(value)
+		case "since", "after":
+			q.Since, err = ParseDate(value)
+		case "until", "before":
+			q.Until, err = ParseDate(value)
+		default:
+			text = append(text, tok)
+		}
+		if err != nil {
+			return q, err
+		}
+	}
+	q.Text = strings.Join(text, " ")
+	return q, nil
+}
+
+// ParseDate parses an absolute date (YYYY-MM-DD, or RFC3339) or a relative age
+// (e.g. 30m, 12h, 7d, 2w) measured back from now.
+func ParseDate(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if len(s) >= 2 {
+		n := 0
+		if _, err := fmt.Sscanf(s[:len(s)-1], "%d", &n); err == nil && n >= 0 {
+			var unit time.Duration
+			switch s[len(s)-1] {
+			case 'm':
+				unit = time.Minute
+			case 'h':
+				unit = time.Hour
+			case 'd':
+				unit = 24 * time.Hour
+			case 'w':
+				unit = 7 * 24 * time.Hour
+			}
+			if unit > 0 {
+				return time.Now().Add(-time.Duration(n) * unit), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (expected YYYY-MM-DD or an age like 7d)", s)
+}
+
+// BuildMatch converts user search text into an FTS5 MATCH expression.
+//
+// Supported syntax:
+//   - words are ANDed: foo bar
+//   - "quoted phrases": "connection refused"
+//   - prefix matching: conn*
+//   - boolean operators (uppercase): AND, OR, NOT, and parentheses
+//
+// NOT is binary in FTS5: "a NOT b" (or "a AND NOT b") matches a without b.
+// A NOT with no term before it cannot be expressed and is an error.
+// Any other punctuation is treated as literal text, so arbitrary input never
+// produces an FTS5 syntax error.
+func BuildMatch(text string) (string, error) {
+	var out []string
+	depth := 0
+	lastWasTerm := false
+	for _, tok := range splitTokens(text) {
+		switch {
+		case tok == "NOT" && !lastWasTerm:
+			if len(out) == 0 || out[len(out)-1] != "AND" {
+				return "", fmt.Errorf("NOT needs a term before it (e.g. foo NOT bar)")
+			}
+			out[len(out)-1] = "NOT" // AND NOT: FTS5's binary NOT
+		case tok == "AND" || tok == "OR" || tok == "NOT":
+			if !lastWasTerm {
+				continue // Leading or doubled operator: drop
+			}
+			out = append(out, tok)
+			lastWasTerm = false
+		case tok == "(":
+			if lastWasTerm {
+				out = append(out, "AND") // FTS5 has no implicit AND before a group
+			}
+			depth++
+			out = append(out, tok)
+			lastWasTerm = false
+		case tok == ")":
+			if depth == 0 {
+				continue
+			}
+			if out[len(out)-1] == "(" {
+				// Empty group: drop it
+				out = out[:len(out)-1]
+				depth--
+				lastWasTerm = len(out) > 0 && out[len(out)-1] != "AND" && out[len(out)-1] != "OR" && out[len(out)-1] != "NOT" && out[len(out)-1] != "("
+				continue
+			}
+			// Drop dangling operator before a close paren
+			if !lastWasTerm && len(out) > 0 && out[len(out)-1] != "(" {
+				out = out[:len(out)-1]
+			}
+			depth--
+			out = append(out, tok)
+			lastWasTerm = true
+		default:
+			term := quoteTerm(tok)
+			if term == "" {
+				continue
+			}
+			if lastWasTerm {
+				out = append(out, "AND")
+			}
+			out = append(out, term)
+			lastWasTerm = true
+		}
+	}
+	// Trailing operator
+	for len(out) > 0 {
+		last := out[len(out)-1]
+		if last == "AND" || last == "OR" || last == "NOT" || last == "(" {
+			out = out[:len(out)-1]
+			if last == "(" {
+				depth--
+			}
+			continue
+		}
+		break
+	}
+	for ; depth > 0; depth-- {
+		out = append(out, ")")
+	}
+	if len(out) == 0 {
+		return "", nil
+	}
+	return strings.Join(out, " "), nil
+}
+
+// quoteTerm turns a raw token into a quoted FTS5 string, keeping a trailing * as prefix.
+func quoteTerm(tok string) string {
+	prefix := false
+	if strings.HasSuffix(tok, "*") {
+		prefix = true
+		tok = strings.TrimRight(tok, "*")
+	}
+	if strings.HasPrefix(tok, `"`) {
+		tok = strings.Trim(tok, `"`)
+	}
+	if strings.IndexFunc(tok, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) < 0 {
+		return "" // Nothing the tokenizer would index
+	}
+	term := `"` + strings.ReplaceAll(tok, `"`, `""`) + `"`
+	if prefix {
+		term += "*"
+	}
+	return term
+}
+
+// splitTokens splits on whitespace and parentheses outside of double quotes,
+// so "quoted phrases" and key:"quoted values" stay single tokens.
+func splitTokens(s string) []string {
+	var tokens []string
+	var cur strings.Builder
+	inQuote := false
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case r == '"':
+			cur.WriteRune(r)
+			inQuote = !inQuote
+		case inQuote:
+			cur.WriteRune(r)
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,448 @@
+// Package searchdb is a persistent SQLite FTS5 index of conversation transcripts.
+//
+// The index lives outside any profile (~/.agent-deck/search.db) because the
+// transcripts it indexes are shared by all profiles. Files are indexed
+// incrementally: each file row records the byte offset up to which the file has
+// been consumed, so appends only cost the new bytes.
+package searchdb
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SchemaVersion tracks the current index schema version.
+// Bump this when adding migrations.
+//
+//	1: files, messages, messages_fts
+//	2: files.tool (transcripts from tools other than Claude)
+const SchemaVersion = 2
+
+// Snippet highlight markers used in Hit.Snippet.
+const (
+	SnippetOpen  = "**"
+	SnippetClose = "**"
+)
+
+// SearchDB wraps the FTS5 transcript index.
+// Safe for concurrent use; multiple processes share it via WAL mode + busy timeout.
+type SearchDB struct {
+	db *sql.DB
+}
+
+// FileRow tracks indexing progress for one transcript file.
+type FileRow struct {
+	Path      string
+	Tool      string // Tool that wrote the transcript ("claude", "codex", ...)
+	SessionID string
+	Project   string // Working directory recorded in the transcript
+	Summary   string
+	Offset    int64 // Bytes consumed so far (always ends on a line boundary)
+	Size      int64 // File size when last indexed
+	ModTime   time.Time
+}
+
+// MessageRow is a single indexed message.
+type MessageRow struct {
+	ID        int64
+	Role      string
+	Timestamp time.Time
+	Content   string
+}
+
+// Hit is a single matching message.
+type Hit struct {
+	MessageID int64
+	FilePath  string
+	Tool      string
+	SessionID string
+	Project   string
+	Role      string
+	Timestamp time.Time
+	Snippet   string
+	Rank      float64 // bm25 rank: lower is better
+}
+
+// Open creates or opens the index at dbPath with WAL mode and busy timeout.
+func Open(dbPath string) (*SearchDB, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0700); err != nil {
+		return nil, fmt.Errorf("searchdb: mkdir: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("searchdb: open: %w", err)
+	}
+
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("searchdb: wal mode: %w", err)
+	}
+	if _, err := db.Exec("PRAGMA busy_timeout=5000"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("searchdb: busy timeout: %w", err)
+	}
+
+	return &SearchDB{db: db}, nil
+}
+
+// Close checkpoints WAL and closes the database.
+func (s *SearchDB) Close() error {
+	_, _ = s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	return s.db.Close()
+}
+
+// Migrate creates tables if they don't exist.
+func (s *SearchDB) Migrate() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("searchdb: begin migration: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmts := []struct {
+		name string
+		sql  string
+	}{
+		{"metadata", `
+			CREATE TABLE IF NOT EXISTS metadata (
+				key   TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			)`},
+		{"files", `
+			CREATE TABLE IF NOT EXISTS files (
+				path       TEXT PRIMARY KEY,
+				tool       TEXT NOT NULL DEFAULT 'claude',
+				session_id TEXT NOT NULL DEFAULT '',
+				project    TEXT NOT NULL DEFAULT '',
+				summary    TEXT NOT NULL DEFAULT '',
+				offset     INTEGER NOT NULL DEFAULT 0,
+				size       INTEGER NOT NULL DEFAULT 0,
+				mod_time   INTEGER NOT NULL DEFAULT 0
+			)`},
+		{"messages", `
+			CREATE TABLE IF NOT EXISTS messages (
+				id         INTEGER PRIMARY KEY AUTOINCREMENT,
+				file_path  TEXT NOT NULL,
+				session_id TEXT NOT NULL DEFAULT '',
+				project    TEXT NOT NULL DEFAULT '',
+				role       TEXT NOT NULL DEFAULT '',
+				ts         INTEGER NOT NULL DEFAULT 0,
+				content    TEXT NOT NULL
+			)`},
+		{"messages index", `
+			CREATE INDEX IF NOT EXISTS idx_messages_file ON messages (file_path, id)`},
+		{"messages_fts", `
+			CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
+				content,
+				content='messages',
+				content_rowid='id',
+				tokenize='unicode61 remove_diacritics 2'
+			)`},
+		{"insert trigger", `
+			CREATE TRIGGER IF NOT EXISTS messages_ai AFTER INSERT ON messages BEGIN
+				INSERT INTO messages_fts(rowid, content) VALUES (new.id, new.content);
+			END`},
+		{"delete trigger", `
+			CREATE TRIGGER IF NOT EXISTS messages_ad AFTER DELETE ON messages BEGIN
+				INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+			END`},
+	}
+	for _, st := range stmts {
+		if _, err := tx.Exec(st.sql); err != nil {
+			return fmt.Errorf("searchdb: create %s: %w", st.name, err)
+		}
+	}
+
+	// v2: indexes created by v1 predate the tool column; their rows are all Claude
+	if err := addColumnIfMissing(tx, "files", "tool", "TEXT NOT NULL DEFAULT 'claude'"); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		"INSERT OR REPLACE INTO metadata (key, value) VALUES ('schema_version', ?)",
+		fmt.Sprintf("%d", SchemaVersion),
+	); err != nil {
+		return fmt.Errorf("searchdb: set schema version: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// addColumnIfMissing adds a column to a table created by an older schema version.
+func addColumnIfMissing(tx *sql.Tx, table, column, def string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("searchdb: inspect %s: %w", table, err)
+	}
+	found := false
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, typ        string
+			dflt             sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("searchdb: inspect %s: %w", table, err)
+		}
+		if name == column {
+			found = true
+		}
+	}
+	rows.Close()
+	if found {
+		return nil
+	}
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def)); err != nil {
+		return fmt.Errorf("searchdb: add %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// GetFile returns the tracking row for a file, or nil if the file isn't indexed.
+func (s *SearchDB) GetFile(path string) (*FileRow, error) {
+	r := &FileRow{}
+	var mod int64
+	err := s.db.QueryRow(
+		"SELECT path, tool, session_id, project, summary, offset, size, mod_time FROM files WHERE path = ?", path,
+	).Scan(&r.Path, &r.Tool, &r.SessionID, &r.Project, &r.Summary, &r.Offset, &r.Size, &mod)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.ModTime = time.Unix(0, mod)
+	return r, nil
+}
+
+// ListFiles returns all indexed files, most recently modified first.
+func (s *SearchDB) ListFiles() ([]*FileRow, error) {
+	rows, err := s.db.Query(
+		"SELECT path, tool, session_id, project, summary, offset, size, mod_time FROM files ORDER BY mod_time DESC",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*FileRow
+	for rows.Next() {
+		r := &FileRow{}
+		var mod int64
+		if err := rows.Scan(&r.Path, &r.Tool, &r.SessionID, &r.Project, &r.Summary, &r.Offset, &r.Size, &mod); err != nil {
+			return nil, err
+		}
+		r.ModTime = time.Unix(0, mod)
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// AppendMessages inserts messages for a file and records its new offset in one
+// transaction, so an interrupted index run never double-indexes a range.
+func (s *SearchDB) AppendMessages(file *FileRow, msgs []MessageRow) error {
+	return s.writeFile(file, msgs, false)
+}
+
+// ReplaceFile replaces all of a file's messages in one transaction. Used for
+// transcripts that are rewritten rather than appended to.
+func (s *SearchDB) ReplaceFile(file *FileRow, msgs []MessageRow) error {
+	return s.writeFile(file, msgs, true)
+}
+
+func (s *SearchDB) writeFile(file *FileRow, msgs []MessageRow, replace bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("searchdb: begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if replace {
+		if _, err := tx.Exec("DELETE FROM messages WHERE file_path = ?", file.Path); err != nil {
+			return fmt.Errorf("searchdb: delete messages: %w", err)
+		}
+	}
+
+	if len(msgs) > 0 {
+		stmt, err := tx.Prepare(`
+			INSERT INTO messages (file_path, session_id, project, role, ts, content)
+			VALUES (?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return fmt.Errorf("searchdb: prepare insert: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, m := range msgs {
+			var ts int64
+			if !m.Timestamp.IsZero() {
+				ts = m.Timestamp.Unix()
+			}
+			if _, err := stmt.Exec(file.Path, file.SessionID, file.Project, m.Role, ts, m.Content); err != nil {
+				return fmt.Errorf("searchdb: insert message: %w", err)
+			}
+		}
+	}
+
+	tool := file.Tool
+	if tool == "" {
+		tool = "claude"
+	}
+	if _, err := tx.Exec(`
+		INSERT OR REPLACE INTO files (path, tool, session_id, project, summary, offset, size, mod_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		file.Path, tool, file.SessionID, file.Project, file.Summary, file.Offset, file.Size, file.ModTime.UnixNano(),
+	); err != nil {
+		return fmt.Errorf("searchdb: save file: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ResetFile removes a file and all its messages (used when a file shrinks or is deleted).
+func (s *SearchDB) ResetFile(path string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("searchdb: begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec("DELETE FROM messages WHERE file_path = ?", path); err != nil {
+		return fmt.Errorf("searchdb: delete messages: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM files WHERE path = ?", path); err != nil {
+		return fmt.Errorf("searchdb: delete file: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Search runs a query and returns matching messages, best first.
+// Query.Text uses the syntax described in BuildMatch.
+func (s *SearchDB) Search(q Query) ([]Hit, error) {
+	match, err := BuildMatch(q.Text)
+	if err != nil {
+		return nil, err
+	}
+	if match == "" {
+		return nil, nil
+	}
+
+	var where []string
+	args := []any{SnippetOpen, SnippetClose, match}
+	if q.Project != "" {
+		where = append(where, "m.project LIKE ? ESCAPE '\\'")
+		args = append(args, "%"+escapeLike(q.Project)+"%")
+	}
+	if q.Role != "" {
+		where = append(where, "m.role = ?")
+		args = append(args, q.Role)
+	}
+	if q.Tool != "" {
+		where = append(where, "f.tool = ?")
+		args = append(args, q.Tool)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "m.ts >= ?")
+		args = append(args, q.Since.Unix())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "m.ts < ?")
+		args = append(args, q.Until.Unix())
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 500
+	}
+	args = append(args, limit)
+
+	query := `
+		SELECT m.id, m.file_path, COALESCE(f.tool, 'claude'), m.session_id, m.project, m.role, m.ts,
+		       snippet(messages_fts, 0, ?, ?, '...', 16), bm25(messages_fts)
+		FROM messages_fts
+		JOIN messages m ON m.id = messages_fts.rowid
+		LEFT JOIN files f ON f.path = m.file_path
+		WHERE messages_fts MATCH ?`
+	for _, w := range where {
+		query += " AND " + w
+	}
+	query += " ORDER BY bm25(messages_fts) LIMIT ?"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("searchdb: search: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []Hit
+	for rows.Next() {
+		var h Hit
+		var ts int64
+		if err := rows.Scan(&h.MessageID, &h.FilePath, &h.Tool, &h.SessionID, &h.Project, &h.Role, &ts, &h.Snippet, &h.Rank); err != nil {
+			return nil, err
+		}
+		if ts > 0 {
+			h.Timestamp = time.Unix(ts, 0)
+		}
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
+
+// MessagesAround returns up to radius messages before and after messageID in
+// the same file, in transcript order. Used to build previews around a hit.
+func (s *SearchDB) MessagesAround(filePath string, messageID int64, radius int) ([]MessageRow, error) {
+	rows, err := s.db.Query(`
+		SELECT id, role, ts, content FROM (
+			SELECT id, role, ts, content FROM messages
+			WHERE file_path = ? AND id < ? ORDER BY id DESC LIMIT ?
+		)
+		UNION ALL
+		SELECT id, role, ts, content FROM (
+			SELECT id, role, ts, content FROM messages
+			WHERE file_path = ? AND id >= ? ORDER BY id ASC LIMIT ?
+		)
+		ORDER BY id`,
+		filePath, messageID, radius, filePath, messageID, radius+1,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []MessageRow
+	for rows.Next() {
+		var m MessageRow
+		var ts int64
+		if err := rows.Scan(&m.ID, &m.Role, &ts, &m.Content); err != nil {
+			return nil, err
+		}
+		if ts > 0 {
+			m.Timestamp = time.Unix(ts, 0)
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// Stats returns the number of indexed files and messages.
+func (s *SearchDB) Stats() (files, messages int, err error) {
+	if err = s.db.QueryRow("SELECT COUNT(*) FROM files").Scan(&files); err != nil {
+		return 0, 0, err
+	}
+	if err = s.db.QueryRow("SELECT COUNT(*) FROM messages").Scan(&messages); err != nil {
+		return 0, 0, err
+	}
+	return files, messages, nil
+}
+
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
@@ -0,0 +1,285 @@
+package searchdb
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestDB(t *testing.T) *SearchDB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "search.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func seed(t *testing.T, db *SearchDB) {
+	t.Helper()
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 12, 0, 0, 0, time.UTC) }
+	files := []struct {
+		row  FileRow
+		msgs []MessageRow
+	}{
+		{
+			FileRow{Path: "/p/a.jsonl", SessionID: "a", Project: "/home/me/api", Offset: 100, Size: 100},
+			[]MessageRow{
+				{Role: "user", Timestamp: day(1), Content: "why does the connection get refused"},
+				{Role: "assistant", Timestamp: day(1), Content: "The connection is refused because the port is closed"},
+			},
+		},
+		{
+			FileRow{Path: "/p/b.jsonl", SessionID: "b", Project: "/home/me/web", Offset: 50, Size: 50},
+			[]MessageRow{
+				{Role: "user", Timestamp: day(10), Content: "refactor the connector module"},
+			},
+		},
+	}
+	for _, f := range files {
+		row := f.row
+		if err := db.AppendMessages(&row, f.msgs); err != nil {
+			t.Fatalf("AppendMessages: %v", err)
+		}
+	}
+}
+
+func TestSearchQueries(t *testing.T) {
+	db := newTestDB(t)
+	seed(t, db)
+
+	tests := []struct {
+		name  string
+		query Query
+		want  int
+	}{
+		{"word", Query{Text: "refused"}, 2},
+		{"phrase", Query{Text: `"connection is refused"`}, 1},
+		{"prefix", Query{Text: "connect*"}, 3},
+		{"and", Query{Text: "connection port"}, 1},
+		{"or", Query{Text: "port OR connector"}, 2},
+		{"not", Query{Text: "connect* NOT refused"}, 1},
+		{"and not", Query{Text: "connect* AND NOT refused"}, 1},
+		{"project filter", Query{Text: "connect*", Project: "web"}, 1},
+		{"role filter", Query{Text: "connect*", Role: "assistant"}, 1},
+		{"since filter", Query{Text: "connect*", Since: time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)}, 1},
+		{"until filter", Query{Text: "connect*", Until: time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)}, 2},
+		{"punctuation is literal", Query{Text: "port, (closed"}, 1},
+		{"empty", Query{Text: "  "}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := db.Search(tt.query)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if len(hits) != tt.want {
+				t.Errorf("got %d hits, want %d: %+v", len(hits), tt.want, hits)
+			}
+		})
+	}
+}
+
+func TestSearchSnippet(t *testing.T) {
+	db := newTestDB(t)
+	seed(t, db)
+
+	hits, err := db.Search(Query{Text: "port"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 1 {
+		t.Fatalf("got %d hits", len(hits))
+	}
+	if want := SnippetOpen + "port" + SnippetClose; !strings.Contains(hits[0].Snippet, want) {
+		t.Errorf("snippet %q missing highlighted term", hits[0].Snippet)
+	}
+	if hits[0].SessionID != "a" || hits[0].Role != "assistant" {
+		t.Errorf("unexpected hit metadata: %+v", hits[0])
+	}
+}
+
+func TestResetFileAndOffsets(t *testing.T) {
+	db := newTestDB(t)
+	seed(t, db)
+
+	f, err := db.GetFile("/p/a.jsonl")
+	if err != nil || f == nil {
+		t.Fatalf("GetFile: %v %v", f, err)
+	}
+	if f.Offset != 100 {
+		t.Errorf("offset = %d, want 100", f.Offset)
+	}
+
+	if err := db.ResetFile("/p/a.jsonl"); err != nil {
+		t.Fatalf("ResetFile: %v", err)
+	}
+	if f, _ := db.GetFile("/p/a.jsonl"); f != nil {
+		t.Error("expected file row removed")
+	}
+	hits, _ := db.Search(Query{Text: "refused"})
+	if len(hits) != 0 {
+		t.Errorf("expected messages removed from FTS index, got %d hits", len(hits))
+	}
+	files, messages, _ := db.Stats()
+	if files != 1 || messages != 1 {
+		t.Errorf("stats = %d files, %d messages", files, messages)
+	}
+}
+
+func TestMessagesAround(t *testing.T) {
+	db := newTestDB(t)
+	seed(t, db)
+
+	hits, _ := db.Search(Query{Text: "port"})
+	msgs, err := db.MessagesAround("/p/a.jsonl", hits[0].MessageID, 5)
+	if err != nil {
+		t.Fatalf("MessagesAround: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].Role != "user" || msgs[1].Role != "assistant" {
+		t.Errorf("unexpected context: %+v", msgs)
+	}
+}
+
+func TestBuildMatch(t *testing.T) {
+	tests := map[string]string{
+		"foo bar":             `"foo" AND "bar"`,
+		`"exact phrase"`:      `"exact phrase"`,
+		"conn*":               `"conn"*`,
+		"a OR b":              `"a" OR "b"`,
+		"a NOT b":             `"a" NOT "b"`,
+		"foo AND NOT bar":     `"foo" NOT "bar"`,
+		"a b AND NOT c":       `"a" AND "b" NOT "c"`,
+		"a AND":               `"a"`,
+		"(a OR b) c":          `( "a" OR "b" ) AND "c"`,
+		"a (b OR c)":          `"a" AND ( "b" OR "c" )`,
+		"(a":                  `( "a" )`,
+		"a )":                 `"a"`,
+		"() a":                `"a"`,
+		`say "hi`:             `"say" AND "hi"`,
+		"foo.bar --force":     `"foo.bar" AND "--force"`,
+		"***":                 ``,
+		`he said "ok"*`:       `"he" AND "said" AND "ok"*`,
+		`quote"inside`:        `"quote""inside"`,
+		"lower and or not ok": `"lower" AND "and" AND "or" AND "not" AND "ok"`,
+	}
+	for in, want := range tests {
+		got, err := BuildMatch(in)
+		if err != nil {
+			t.Errorf("BuildMatch(%q) error: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("BuildMatch(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBuildMatchRejectsLeadingNot(t *testing.T) {
+	for _, in := range []string{"NOT a", "a OR NOT b", "(NOT a)", "a AND NOT NOT b"} {
+		if got, err := BuildMatch(in); err == nil {
+			t.Errorf("BuildMatch(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery(`deploy project:"my api" role:user tool:Codex since:2025-01-02 until:2025-02-01 https://x.io`)
+	if err != nil {
+		t.Fatalf("ParseQuery: %v", err)
+	}
+	if q.Text != "deploy https://x.io" {
+		t.Errorf("Text = %q", q.Text)
+	}
+	if q.Project != "my api" || q.Role != "user" || q.Tool != "codex" {
+		t.Errorf("filters = %+v", q)
+	}
+	if q.Since.Format("2006-01-02") != "2025-01-02" || q.Until.Format("2006-01-02") != "2025-02-01" {
+		t.Errorf("dates = %v %v", q.Since, q.Until)
+	}
+
+	q, err = ParseQuery("x after:7d")
+	if err != nil {
+		t.Fatalf("ParseQuery: %v", err)
+	}
+	if d := time.Since(q.Since); d < 7*24*time.Hour-time.Minute || d > 7*24*time.Hour+time.Minute {
+		t.Errorf("relative since = %v", q.Since)
+	}
+
+	if _, err := ParseQuery("role:robot"); err == nil {
+		t.Error("expected invalid role error")
+	}
+	if _, err := ParseQuery("since:yesterday"); err == nil {
+		t.Error("expected invalid date error")
+	}
+}
+
+func TestToolFilterAndReplaceFile(t *testing.T) {
+	db := newTestDB(t)
+	seed(t, db)
+
+	codex := FileRow{Path: "/c/rollout.jsonl", Tool: "codex", SessionID: "c", Project: "/home/me/api"}
+	if err := db.ReplaceFile(&codex, []MessageRow{{Role: "user", Content: "connection pool exhausted"}}); err != nil {
+		t.Fatalf("ReplaceFile: %v", err)
+	}
+
+	hits, err := db.Search(Query{Text: "connection", Tool: "codex"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Tool != "codex" {
+		t.Errorf("codex hits = %+v", hits)
+	}
+	if hits, _ := db.Search(Query{Text: "connection", Tool: "claude"}); len(hits) != 2 {
+		t.Errorf("claude hits = %d, want 2 (rows default to claude)", len(hits))
+	}
+
+	if err := db.ReplaceFile(&codex, []MessageRow{{Role: "user", Content: "pool resized"}}); err != nil {
+		t.Fatalf("ReplaceFile: %v", err)
+	}
+	if hits, _ := db.Search(Query{Text: "exhausted"}); len(hits) != 0 {
+		t.Errorf("replaced messages still indexed: %+v", hits)
+	}
+	if f, _ := db.GetFile(codex.Path); f == nil || f.Tool != "codex" {
+		t.Errorf("file row = %+v", f)
+	}
+}
+
+func TestMigrateFromV1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "search.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	// v1 files table had no tool column
+	if _, err := db.db.Exec(`CREATE TABLE files (
+		path TEXT PRIMARY KEY, session_id TEXT NOT NULL DEFAULT '', project TEXT NOT NULL DEFAULT '',
+		summary TEXT NOT NULL DEFAULT '', offset INTEGER NOT NULL DEFAULT 0,
+		size INTEGER NOT NULL DEFAULT 0, mod_time INTEGER NOT NULL DEFAULT 0)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.db.Exec(`INSERT INTO files (path, session_id) VALUES ('/p/old.jsonl', 'old')`); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	defer db.Close()
+
+	f, err := db.GetFile("/p/old.jsonl")
+	if err != nil || f == nil {
+		t.Fatalf("GetFile: %v %v", f, err)
+	}
+	if f.Tool != "claude" {
+		t.Errorf("Tool = %q, want claude", f.Tool)
+	}
+	// Migrating again is a no-op
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("second Migrate: %v", err)
+	}
+}
@@ -15,11 +15,17 @@ type SessionAnalytics struct {
 	OutputTokens     int `json:"output_tokens"`
 	CacheReadTokens  int `json:"cache_read_input_tokens"`
 	CacheWriteTokens int `json:"cache_creation_input_tokens"`
+	// Part of CacheWriteTokens written with a 1-hour cache TTL
+	CacheWrite1hTokens int `json:"cache_creation_1h_input_tokens,omitempty"`
 
 	// Current context size (last turn's input + cache read tokens)
 	// This represents the actual context window usage, not cumulative totals
 	CurrentContextTokens int `json:"current_context_tokens"`
 
+	// Context window size reported by the tool itself (Codex records it in
+	// the transcript); 0 means look up Model in the model table
+	ContextWindow int `json:"context_window,omitempty"`
+
 	// Session metrics
 	TotalTurns int           `json:"total_turns"`
 	Duration   time.Duration `json:"duration"`
@@ -37,6 +43,9 @@ type SessionAnalytics struct {
 
 	// 5-hour billing blocks
 	BillingBlocks []BillingBlock `json:"billing_blocks"`
+
+	// Model detected from the most recent assistant message
+	Model string `json:"model,omitempty"`
 }
 
 // ToolCall represents a tool and its usage count
@@ -67,49 +76,29 @@ func (a *SessionAnalytics) TotalTokens() int {
 
 // ContextPercent returns the percentage of context window used
 // Uses CurrentContextTokens (last turn's input + cache) for accurate context usage
-// modelLimit is the model's context window size (defaults to 200000 for Claude)
+// modelLimit is the model's context window size (0 = use the window reported by
+// the tool, else look up a.Model in the model table)
 func (a *SessionAnalytics) ContextPercent(modelLimit int) float64 {
 	if modelLimit == 0 {
-		modelLimit = 200000 // Default Claude limit
+		modelLimit = a.ContextWindow
+	}
+	if modelLimit == 0 {
+		modelLimit = ModelContextLimit(a.Model)
 	}
 	return float64(a.CurrentContextTokens) / float64(modelLimit) * 100
 }
 
-// ModelPricing holds pricing per million tokens for a model
-type ModelPricing struct {
-	Input      float64
-	Output     float64
-	CacheRead  float64
-	CacheWrite float64
-}
-
-// modelPricing contains pricing per million tokens for each model (as of Jan 2025)
-var modelPricing = map[string]ModelPricing{
-	"claude-sonnet-4-20250514": {Input: 3.0, Output: 15.0, CacheRead: 0.30, CacheWrite: 3.75},
-	"claude-opus-4-20250514":   {Input: 15.0, Output: 75.0, CacheRead: 1.50, CacheWrite: 18.75},
-	"claude-3-5-sonnet":        {Input: 3.0, Output: 15.0, CacheRead: 0.30, CacheWrite: 3.75},
-	"claude-3-5-haiku":         {Input: 0.80, Output: 4.0, CacheRead: 0.08, CacheWrite: 1.0},
-	// Default fallback uses Sonnet pricing
-	"default": {Input: 3.0, Output: 15.0, CacheRead: 0.30, CacheWrite: 3.75},
-}
-
-// CalculateCost estimates session cost based on token usage and model pricing
+// CalculateCost estimates session cost from token usage using the model
+// table (see models.go). Long-context tiers need per-request prompt sizes, so
+// session totals are priced at base rates.
 func (a *SessionAnalytics) CalculateCost(model string) float64 {
-	pricing, ok := modelPricing[model]
-	if !ok {
-		pricing = modelPricing["default"]
-	}
-
-	// Convert to millions
-	inputM := float64(a.InputTokens) / 1_000_000
-	outputM := float64(a.OutputTokens) / 1_000_000
-	cacheReadM := float64(a.CacheReadTokens) / 1_000_000
-	cacheWriteM := float64(a.CacheWriteTokens) / 1_000_000
-
-	return inputM*pricing.Input +
-		outputM*pricing.Output +
-		cacheReadM*pricing.CacheRead +
-		cacheWriteM*pricing.CacheWrite
+	return LookupModel(model).Cost(TokenUsage{
+		Input:        a.InputTokens,
+		Output:       a.OutputTokens,
+		CacheRead:    a.CacheReadTokens,
+		CacheWrite:   a.CacheWriteTokens,
+		CacheWrite1h: a.CacheWrite1hTokens,
+	})
 }
 
 // jsonlEntry represents a single line in a Claude session JSONL file
@@ -117,11 +106,15 @@ type jsonlEntry struct {
 	Type      string    `json:"type"`
 	Timestamp time.Time `json:"timestamp"`
 	Message   struct {
+		Model string `json:"model"`
 		Usage struct {
 			InputTokens              int `json:"input_tokens"`
 			OutputTokens             int `json:"output_tokens"`
 			CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
 			CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+			CacheCreation            struct {
+				Ephemeral1hInputTokens int `json:"ephemeral_1h_input_tokens"`
+			} `json:"cache_creation"`
 		} `json:"usage"`
 		Content []struct {
 			Type string `json:"type"`
@@ -176,12 +169,17 @@ func ParseSessionJSONL(path string) (*SessionAnalytics, error) {
 		analytics.OutputTokens += entry.Message.Usage.OutputTokens
 		analytics.CacheReadTokens += entry.Message.Usage.CacheReadInputTokens
 		analytics.CacheWriteTokens += entry.Message.Usage.CacheCreationInputTokens
+		analytics.CacheWrite1hTokens += entry.Message.Usage.CacheCreation.Ephemeral1hInputTokens
 
 		// Track current context size (last turn's input + cache read)
 		// This represents the actual context window usage
 		analytics.CurrentContextTokens = entry.Message.Usage.InputTokens +
 			entry.Message.Usage.CacheReadInputTokens
 
+		if entry.Message.Model != "" && entry.Message.Model != "<synthetic>" {
+			analytics.Model = entry.Message.Model
+		}
+
 		// Count turn
 		analytics.TotalTurns++
 
@@ -211,6 +209,31 @@ func ParseSessionJSONL(path string) (*SessionAnalytics, error) {
 	return analytics, scanner.Err()
 }
 
+// GetSessionAnalytics parses the transcript of a Claude, Codex or OpenCode
+// instance. Returns nil, nil when the tool has no transcript yet (or, like
+// Gemini, tracks analytics separately in GeminiAnalytics).
+func GetSessionAnalytics(inst *Instance) (*SessionAnalytics, error) {
+	switch inst.GetToolThreadSafe() {
+	case "claude":
+		if path := inst.GetJSONLPath(); path != "" {
+			return ParseSessionJSONL(path)
+		}
+	case "codex":
+		if path := findCodexRollout(inst.CodexSessionID); path != "" {
+			return ParseCodexRollout(path)
+		}
+	case "opencode":
+		if inst.OpenCodeSessionID != "" {
+			a, err := ParseOpenCodeSession(inst.OpenCodeSessionID)
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return a, err
+		}
+	}
+	return nil, nil
+}
+
 // CalculateBillingBlocks groups timestamps into billing windows.
 // Claude Code API bills in 5-hour windows. Each block represents a billing period.
 // Timestamps are sorted chronologically and grouped - a new block starts when
@@ -130,6 +130,23 @@ func TestParseJSONL(t *testing.T) {
 	assert.Equal(t, 1, editCalls)
 }
 
+func TestParseJSONL_Model(t *testing.T) {
+	dir := t.TempDir()
+	jsonlPath := filepath.Join(dir, "session.jsonl")
+
+	jsonl := `{"type":"assistant","message":{"model":"claude-sonnet-4-20250514","usage":{"input_tokens":100,"output_tokens":50}}}
+{"type":"assistant","message":{"model":"claude-opus-4-20250514","usage":{"input_tokens":200,"output_tokens":100}}}
+{"type":"assistant","message":{"model":"<synthetic>","usage":{"input_tokens":0,"output_tokens":0}}}`
+
+	require.NoError(t, os.WriteFile(jsonlPath, []byte(jsonl), 0644))
+
+	analytics, err := ParseSessionJSONL(jsonlPath)
+	require.NoError(t, err)
+
+	// Latest real model wins; synthetic entries are ignored
+	assert.Equal(t, "claude-opus-4-20250514", analytics.Model)
+}
+
 func TestParseJSONL_WithTimestamps(t *testing.T) {
 	dir := t.TempDir()
 	jsonlPath := filepath.Join(dir, "session.jsonl")
@@ -0,0 +1,785 @@
+package session
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SessionBundleFormat is the version of the export bundle layout. Import
+// refuses bundles with a newer format.
+const SessionBundleFormat = 1
+
+// bundleManifestName is the first entry of every bundle
+const bundleManifestName = "manifest.json"
+
+// RedactedValue replaces secrets in exported bundles
+const RedactedValue = "<redacted>"
+
+// Bundle file roots: where a transcript file lives relative to its tool's
+// storage, so import can place it on a machine with different paths.
+const (
+	BundleRootClaudeProject = "claude-project" // <claude config>/projects/<project dir>/
+	BundleRootGeminiChats   = "gemini-chats"   // <gemini config>/tmp/<project hash>/chats/
+	BundleRootCodex         = "codex"          // $CODEX_HOME/
+	BundleRootOpenCode      = "opencode"       // OpenCode data dir
+)
+
+// BundleFile is a transcript file carried in a bundle.
+type BundleFile struct {
+	Root string `json:"root"`
+	Path string `json:"path"` // Slash-separated, relative to Root
+	Size int64  `json:"size"`
+}
+
+// SessionBundleManifest describes an exported session.
+type SessionBundleManifest struct {
+	Format     int       `json:"format"`
+	ExportedAt time.Time `json:"exported_at"`
+
+	Instance InstanceData `json:"instance"`
+	// SourceProjectPath is the symlink-resolved project path on the exporting
+	// machine, as recorded in transcripts
+	SourceProjectPath string `json:"source_project_path"`
+	// Groups holds the session's group and its parents
+	Groups []GroupData `json:"groups,omitempty"`
+
+	// MCPs are the config.toml definitions of the session's loaded MCPs
+	MCPs map[string]MCPDef `json:"mcps,omitempty"`
+	// ProjectMCPJSON is the project's .mcp.json
+	ProjectMCPJSON json.RawMessage `json:"project_mcp_json,omitempty"`
+	// Skills are the project's attached skills (.agent-deck/skills.toml)
+	Skills []ProjectSkillAttachment `json:"skills,omitempty"`
+
+	Files []BundleFile `json:"files"`
+	// Redacted lists the values replaced by RedactedValue
+	Redacted []string `json:"redacted,omitempty"`
+}
+
+// --- Redaction ---
+
+// secretNamePattern matches env var, header and flag names that hold secrets
+var secretNamePattern = regexp.MustCompile(`(?i)(token|secret|passw(or)?d|api[-_]?key|auth|credential|private[-_]?key|session[-_]?key|cookie)`)
+
+// inlineEnvPattern matches NAME=value assignments in shell commands
+var inlineEnvPattern = regexp.MustCompile(`\b([A-Za-z_][A-Za-z0-9_]*)=("[^"]*"|'[^']*'|\S+)`)
+
+// redactor replaces secrets and records where it did so.
+type redactor struct {
+	redacted []string
+}
+
+func (r *redactor) note(where string) {
+	r.redacted = append(r.redacted, where)
+}
+
+// values redacts every value of a map (env vars, HTTP headers).
+func (r *redactor) values(where string, m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return m
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if v == "" {
+			out[k] = v
+			continue
+		}
+		out[k] = RedactedValue
+		r.note(where + "." + k)
+	}
+	return out
+}
+
+// args redacts the values of secret-looking flags ("--api-key=x", "--token x").
+func (r *redactor) args(where string, args []string) []string {
+	if len(args) == 0 {
+		return args
+	}
+	out := make([]string, len(args))
+	copy(out, args)
+	for i, a := range out {
+		if !strings.HasPrefix(a, "-") {
+			continue
+		}
+		name, _, hasValue := strings.Cut(a, "=")
+		if !secretNamePattern.MatchString(name) {
+			continue
+		}
+		if hasValue {
+			out[i] = name + "=" + RedactedValue
+			r.note(fmt.Sprintf("%s[%d]", where, i))
+		} else if i+1 < len(out) && !strings.HasPrefix(out[i+1], "-") {
+			out[i+1] = RedactedValue
+			r.note(fmt.Sprintf("%s[%d]", where, i+1))
+		}
+	}
+	return out
+}
+
+// command redacts inline secret env assignments in a shell command.
+func (r *redactor) command(where, cmd string) string {
+	return inlineEnvPattern.ReplaceAllStringFunc(cmd, func(m string) string {
+		name, _, _ := strings.Cut(m, "=")
+		if !secretNamePattern.MatchString(name) {
+			return m
+		}
+		r.note(where + "." + name)
+		return name + "=" + RedactedValue
+	})
+}
+
+func (r *redactor) mcpDef(name string, def MCPDef) MCPDef {
+	where := "mcps." + name
+	def.Env = r.values(where+".env", def.Env)
+	def.Headers = r.values(where+".headers", def.Headers)
+	def.Args = r.args(where+".args", def.Args)
+	return def
+}
+
+// mcpJSON redacts env, headers and secret flags of every server in a
+// .mcp.json document, leaving unknown fields untouched.
+func (r *redactor) mcpJSON(data []byte) (json.RawMessage, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var servers map[string]map[string]json.RawMessage
+	if raw, ok := doc["mcpServers"]; ok {
+		if err := json.Unmarshal(raw, &servers); err != nil {
+			return nil, err
+		}
+	}
+	for name, server := range servers {
+		where := "project_mcp_json." + name
+		for _, field := range []string{"env", "headers"} {
+			var m map[string]string
+			if raw, ok := server[field]; ok && json.Unmarshal(raw, &m) == nil {
+				server[field], _ = json.Marshal(r.values(where+"."+field, m))
+			}
+		}
+		var args []string
+		if raw, ok := server["args"]; ok && json.Unmarshal(raw, &args) == nil {
+			server["args"], _ = json.Marshal(r.args(where+".args", args))
+		}
+	}
+	if servers != nil {
+		doc["mcpServers"], _ = json.Marshal(servers)
+	}
+	return json.Marshal(doc)
+}
+
+// --- Export ---
+
+// bundleSource is a transcript file to be exported
+type bundleSource struct {
+	file BundleFile
+	abs  string
+}
+
+// sessionBundleSources lists the transcript files of an instance.
+func sessionBundleSources(inst *Instance) ([]bundleSource, error) {
+	transcript := TranscriptPath(inst)
+	if transcript == "" {
+		return nil, nil
+	}
+	var out []bundleSource
+	add := func(root, base, abs string) error {
+		info, err := os.Stat(abs)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, abs)
+		if err != nil {
+			return err
+		}
+		out = append(out, bundleSource{file: BundleFile{Root: root, Path: filepath.ToSlash(rel), Size: info.Size()}, abs: abs})
+		return nil
+	}
+	addTree := func(root, base, dir string) error {
+		err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			return add(root, base, p)
+		})
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	switch inst.GetToolThreadSafe() {
+	case "claude":
+		base := filepath.Dir(transcript)
+		if err := add(BundleRootClaudeProject, base, transcript); err != nil {
+			return nil, err
+		}
+		// Subagent transcripts live in <session>/subagents/
+		if err := addTree(BundleRootClaudeProject, base, filepath.Join(strings.TrimSuffix(transcript, ".jsonl"), "subagents")); err != nil {
+			return nil, err
+		}
+	case "gemini":
+		if err := add(BundleRootGeminiChats, filepath.Dir(transcript), transcript); err != nil {
+			return nil, err
+		}
+	case "codex":
+		if err := add(BundleRootCodex, getCodexHomeDir(), transcript); err != nil {
+			return nil, err
+		}
+	case "opencode":
+		dataDir := getOpenCodeDataDir()
+		storage := openCodeTranscriptSource{dir: dataDir}.storage()
+		if err := add(BundleRootOpenCode, dataDir, transcript); err != nil {
+			return nil, err
+		}
+		msgDir := filepath.Join(storage, "message", inst.OpenCodeSessionID)
+		entries, _ := os.ReadDir(msgDir)
+		if err := addTree(BundleRootOpenCode, dataDir, msgDir); err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			msgID := strings.TrimSuffix(e.Name(), ".json")
+			if err := addTree(BundleRootOpenCode, dataDir, filepath.Join(storage, "part", msgID)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// bundleGroups returns the stored data of a group and its parents.
+func bundleGroups(groupPath string, groups []*GroupData) []GroupData {
+	byPath := make(map[string]*GroupData, len(groups))
+	for _, g := range groups {
+		byPath[g.Path] = g
+	}
+	var out []GroupData
+	parts := strings.Split(groupPath, "/")
+	for i := range parts {
+		p := strings.Join(parts[:i+1], "/")
+		if g, ok := byPath[p]; ok {
+			out = append(out, *g)
+		}
+	}
+	return out
+}
+
+// ExportSessionBundle writes a gzipped tar bundle of a session: its metadata,
+// group, MCP and skill attachments, and transcript files. Secrets in MCP
+// definitions and inline command env vars are redacted.
+func ExportSessionBundle(w io.Writer, inst *Instance, groups []*GroupData) (*SessionBundleManifest, error) {
+	sources, err := sessionBundleSources(inst)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect transcript files: %w", err)
+	}
+
+	r := &redactor{}
+	resolved := inst.ProjectPath
+	if p, err := filepath.EvalSymlinks(inst.ProjectPath); err == nil {
+		resolved = p
+	}
+	m := &SessionBundleManifest{
+		Format:            SessionBundleFormat,
+		ExportedAt:        time.Now().UTC(),
+		SourceProjectPath: resolved,
+		Groups:            bundleGroups(inst.GroupPath, groups),
+		Instance: InstanceData{
+			ID:                 inst.ID,
+			Title:              inst.Title,
+			ProjectPath:        inst.ProjectPath,
+			GroupPath:          inst.GroupPath,
+			Command:            r.command("instance.command", inst.Command),
+			Wrapper:            r.command("instance.wrapper", inst.Wrapper),
+			Tool:               inst.Tool,
+			Status:             inst.Status,
+			CreatedAt:          inst.CreatedAt,
+			LastAccessedAt:     inst.LastAccessedAt,
+			WorktreePath:       inst.WorktreePath,
+			WorktreeRepoRoot:   inst.WorktreeRepoRoot,
+			WorktreeBranch:     inst.WorktreeBranch,
+			ClaudeSessionID:    inst.ClaudeSessionID,
+			ClaudeDetectedAt:   inst.ClaudeDetectedAt,
+			GeminiSessionID:    inst.GeminiSessionID,
+			GeminiDetectedAt:   inst.GeminiDetectedAt,
+			GeminiYoloMode:     inst.GeminiYoloMode,
+			GeminiModel:        inst.GeminiModel,
+			OpenCodeSessionID:  inst.OpenCodeSessionID,
+			OpenCodeDetectedAt: inst.OpenCodeDetectedAt,
+			CodexSessionID:     inst.CodexSessionID,
+			CodexDetectedAt:    inst.CodexDetectedAt,
+			LatestPrompt:       inst.LatestPrompt,
+			ToolOptionsJSON:    inst.ToolOptionsJSON,
+			ContextPolicy:      inst.ContextPolicy,
+			LoadedMCPNames:     inst.LoadedMCPNames,
+		},
+	}
+
+	if len(inst.LoadedMCPNames) > 0 {
+		available := GetAvailableMCPs()
+		m.MCPs = make(map[string]MCPDef)
+		for _, name := range inst.LoadedMCPNames {
+			if def, ok := available[name]; ok {
+				m.MCPs[name] = r.mcpDef(name, def)
+			}
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(inst.ProjectPath, ".mcp.json")); err == nil {
+		if m.ProjectMCPJSON, err = r.mcpJSON(data); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(inst.ProjectPath, ".mcp.json"), err)
+		}
+	}
+	if skills, err := GetAttachedProjectSkills(inst.ProjectPath); err == nil && len(skills) > 0 {
+		m.Skills = skills
+	}
+
+	m.Files = make([]BundleFile, 0, len(sources))
+	for _, s := range sources {
+		m.Files = append(m.Files, s.file)
+	}
+	m.Redacted = r.redacted
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeTarFile(tw, bundleManifestName, manifest, m.ExportedAt); err != nil {
+		return nil, err
+	}
+	for _, s := range sources {
+		data, err := os.ReadFile(s.abs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", s.abs, err)
+		}
+		if err := writeTarFile(tw, path.Join("files", s.file.Root, s.file.Path), data, m.ExportedAt); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// --- Import ---
+
+// SessionImportOptions controls where an imported session goes.
+type SessionImportOptions struct {
+	// ProjectPath is the project directory on this machine (default: the
+	// exported path)
+	ProjectPath string
+	// GroupPath overrides the bundle's group
+	GroupPath string
+	// Title overrides the bundle's title
+	Title string
+	// Overwrite replaces transcript files that already exist with different content
+	Overwrite bool
+	// TrustCommands keeps the bundle's command, wrapper and tool options.
+	// By default the session runs the tool's normal launch command, since
+	// whatever a bundle specifies runs as a shell command on start.
+	TrustCommands bool
+}
+
+// SessionImportResult describes an imported session.
+type SessionImportResult struct {
+	Manifest *SessionBundleManifest
+	Instance *Instance
+	// Groups are the bundle's group definitions, for creating missing groups
+	Groups   []GroupData
+	Files    []string // Transcript files written
+	Warnings []string
+}
+
+// ReadSessionBundleManifest reads only the manifest of a bundle.
+func ReadSessionBundleManifest(r io.Reader) (*SessionBundleManifest, error) {
+	tr, closeFn, err := openBundle(r)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+	return readBundleManifest(tr)
+}
+
+func openBundle(r io.Reader) (*tar.Reader, func(), error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a session bundle: %w", err)
+	}
+	return tar.NewReader(gz), func() { _ = gz.Close() }, nil
+}
+
+func readBundleManifest(tr *tar.Reader) (*SessionBundleManifest, error) {
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != bundleManifestName {
+		return nil, fmt.Errorf("not a session bundle: missing %s", bundleManifestName)
+	}
+	var m SessionBundleManifest
+	if err := json.NewDecoder(tr).Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	if m.Format > SessionBundleFormat {
+		return nil, fmt.Errorf("bundle format %d is newer than supported (%d); upgrade agent-deck", m.Format, SessionBundleFormat)
+	}
+	for _, f := range m.Files {
+		if !filepath.IsLocal(filepath.FromSlash(f.Path)) || !validBundleFilePath(f.Root, f.Path) {
+			return nil, fmt.Errorf("invalid bundle: unexpected %s file path %q", f.Root, f.Path)
+		}
+	}
+	return &m, nil
+}
+
+// validBundleFilePath reports whether path has the shape an export writes
+// for root, so a crafted bundle can only create transcript files and never,
+// say, $CODEX_HOME/config.toml.
+func validBundleFilePath(root, path string) bool {
+	parts := strings.Split(path, "/")
+	for _, part := range parts {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	name := parts[len(parts)-1]
+	switch root {
+	case BundleRootClaudeProject:
+		// <session>.jsonl, or <session>/subagents/<agent>.jsonl
+		return strings.HasSuffix(name, ".jsonl") &&
+			(len(parts) == 1 || (len(parts) == 3 && parts[1] == "subagents"))
+	case BundleRootGeminiChats:
+		return len(parts) == 1 && strings.HasPrefix(name, "session-") && strings.HasSuffix(name, ".json")
+	case BundleRootCodex:
+		// sessions/<yyyy>/<mm>/<dd>/rollout-*.jsonl
+		return len(parts) >= 2 && parts[0] == "sessions" &&
+			strings.HasPrefix(name, "rollout-") && strings.HasSuffix(name, ".jsonl")
+	case BundleRootOpenCode:
+		// storage/{session,message,part}/<parent id>/<id>.json
+		return len(parts) == 4 && parts[0] == "storage" &&
+			(parts[1] == "session" || parts[1] == "message" || parts[1] == "part") &&
+			strings.HasSuffix(name, ".json")
+	}
+	return false
+}
+
+// bundleRewriter rewrites machine-specific paths inside transcripts.
+type bundleRewriter struct {
+	pathPatterns []*regexp.Regexp
+	newPath      string // JSON-escaped
+	oldHash      string
+	newHash      string
+}
+
+func newBundleRewriter(m *SessionBundleManifest, newProjectPath string) *bundleRewriter {
+	rw := &bundleRewriter{newPath: jsonEscape(newProjectPath)}
+	seen := make(map[string]bool)
+	for _, old := range []string{m.SourceProjectPath, m.Instance.ProjectPath} {
+		if old == "" || old == newProjectPath || seen[old] {
+			continue
+		}
+		seen[old] = true
+		// Only whole paths: followed by a separator or the end of the JSON string
+		rw.pathPatterns = append(rw.pathPatterns, regexp.MustCompile(regexp.QuoteMeta(jsonEscape(old))+`([/"]|\\\\)`))
+	}
+	if m.Instance.Tool == "gemini" {
+		// Gemini stores chats under the sha256 of the resolved project path
+		sum := sha256.Sum256([]byte(m.SourceProjectPath))
+		rw.oldHash = hex.EncodeToString(sum[:])
+		rw.newHash = HashProjectPath(newProjectPath)
+	}
+	return rw
+}
+
+func (rw *bundleRewriter) rewrite(data []byte) []byte {
+	for _, p := range rw.pathPatterns {
+		data = p.ReplaceAllFunc(data, func(match []byte) []byte {
+			// Keep the trailing separator or quote the pattern consumed
+			sep := p.FindSubmatch(match)[1]
+			return append([]byte(rw.newPath), sep...)
+		})
+	}
+	if rw.oldHash != "" && rw.newHash != "" && rw.oldHash != rw.newHash {
+		data = bytes.ReplaceAll(data, []byte(rw.oldHash), []byte(rw.newHash))
+	}
+	return data
+}
+
+func jsonEscape(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
+
+// bundleDestination returns where a bundle file goes on this machine.
+func bundleDestination(f BundleFile, projectPath string) (string, error) {
+	rel := filepath.FromSlash(f.Path)
+	switch f.Root {
+	case BundleRootClaudeProject:
+		resolved := projectPath
+		if p, err := filepath.EvalSymlinks(projectPath); err == nil {
+			resolved = p
+		}
+		return filepath.Join(GetClaudeConfigDir(), "projects", ConvertToClaudeDirName(resolved), rel), nil
+	case BundleRootGeminiChats:
+		dir := GetGeminiSessionsDir(projectPath)
+		if dir == "" {
+			return "", fmt.Errorf("cannot determine Gemini chats directory for %s", projectPath)
+		}
+		return filepath.Join(dir, rel), nil
+	case BundleRootCodex:
+		return filepath.Join(getCodexHomeDir(), rel), nil
+	case BundleRootOpenCode:
+		return filepath.Join(getOpenCodeDataDir(), rel), nil
+	}
+	return "", fmt.Errorf("unknown bundle root %q", f.Root)
+}
+
+// ImportSessionBundle restores a bundle's transcript files with paths
+// rewritten for this machine, reattaches MCPs and skills that are available
+// locally, and returns the new instance. The caller adds it to storage.
+func ImportSessionBundle(r io.Reader, opts SessionImportOptions) (*SessionImportResult, error) {
+	tr, closeFn, err := openBundle(r)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+	m, err := readBundleManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	projectPath := opts.ProjectPath
+	if projectPath == "" {
+		projectPath = m.Instance.ProjectPath
+	}
+	projectPath = ExpandPath(projectPath)
+	if abs, err := filepath.Abs(projectPath); err == nil {
+		projectPath = abs
+	}
+	if info, err := os.Stat(projectPath); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("project path %s does not exist on this machine (use --path)", projectPath)
+	}
+
+	res := &SessionImportResult{Manifest: m, Groups: m.Groups}
+
+	// Resolve destinations and check for conflicts before writing anything
+	dests := make(map[string]string, len(m.Files))
+	for _, f := range m.Files {
+		dest, err := bundleDestination(f, projectPath)
+		if err != nil {
+			return nil, err
+		}
+		dests[f.Root+"/"+f.Path] = dest
+	}
+
+	rw := newBundleRewriter(m, projectPath)
+	type pendingFile struct {
+		dest string
+		data []byte
+	}
+	var pending []pendingFile
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		dest, ok := dests[strings.TrimPrefix(hdr.Name, "files/")]
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			continue // Not listed in the manifest
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from bundle: %w", hdr.Name, err)
+		}
+		data = rw.rewrite(data)
+		if existing, err := os.ReadFile(dest); err == nil && !bytes.Equal(existing, data) && !opts.Overwrite {
+			return nil, fmt.Errorf("%s already exists with different content (use --force to overwrite)", dest)
+		}
+		pending = append(pending, pendingFile{dest, data})
+	}
+	if len(pending) < len(m.Files) {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("bundle is missing %d of %d transcript files", len(m.Files)-len(pending), len(m.Files)))
+	}
+	for _, f := range pending {
+		if err := os.MkdirAll(filepath.Dir(f.dest), 0o700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(f.dest, f.data, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.dest, err)
+		}
+		res.Files = append(res.Files, f.dest)
+	}
+
+	res.Instance = instanceFromBundle(m, projectPath, opts, res)
+	res.Warnings = append(res.Warnings, restoreBundleAttachments(m, projectPath)...)
+	return res, nil
+}
+
+// bundleTokenPattern matches the tool session IDs and model names that are
+// safe to put on a command line unquoted.
+var bundleTokenPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]+$`)
+
+// instanceFromBundle builds a new instance (fresh ID and tmux session) that
+// resumes the bundle's conversation from projectPath.
+func instanceFromBundle(m *SessionBundleManifest, projectPath string, opts SessionImportOptions, res *SessionImportResult) *Instance {
+	d := m.Instance
+	title := d.Title
+	if opts.Title != "" {
+		title = opts.Title
+	}
+	group := d.GroupPath
+	if opts.GroupPath != "" {
+		group = opts.GroupPath
+		res.Groups = nil
+	}
+	inst := NewInstanceWithGroupAndTool(title, projectPath, group, d.Tool)
+	inst.CreatedAt = d.CreatedAt
+	inst.ClaudeDetectedAt = d.ClaudeDetectedAt
+	inst.GeminiDetectedAt = d.GeminiDetectedAt
+	inst.GeminiYoloMode = d.GeminiYoloMode
+	inst.OpenCodeDetectedAt = d.OpenCodeDetectedAt
+	inst.CodexDetectedAt = d.CodexDetectedAt
+	inst.LatestPrompt = d.LatestPrompt
+	inst.ContextPolicy = d.ContextPolicy
+	inst.LoadedMCPNames = d.LoadedMCPNames
+
+	// Values that end up on the session's command line
+	token := func(name, value string) string {
+		if value == "" || opts.TrustCommands || bundleTokenPattern.MatchString(value) {
+			return value
+		}
+		res.Warnings = append(res.Warnings, fmt.Sprintf("dropped the bundle's %s %q: not a valid identifier", name, value))
+		return ""
+	}
+	inst.ClaudeSessionID = token("Claude session ID", d.ClaudeSessionID)
+	inst.GeminiSessionID = token("Gemini session ID", d.GeminiSessionID)
+	inst.GeminiModel = token("Gemini model", d.GeminiModel)
+	inst.OpenCodeSessionID = token("OpenCode session ID", d.OpenCodeSessionID)
+	inst.CodexSessionID = token("Codex session ID", d.CodexSessionID)
+
+	if opts.TrustCommands {
+		inst.Command = d.Command
+		inst.Wrapper = d.Wrapper
+		inst.ToolOptionsJSON = d.ToolOptionsJSON
+		if strings.Contains(d.Command, RedactedValue) || strings.Contains(d.Wrapper, RedactedValue) {
+			res.Warnings = append(res.Warnings, "command contains redacted secrets; fix it with 'agent-deck session set <id> command ...'")
+		}
+	} else {
+		inst.Command = launchCommandForTool(d.Tool)
+		if d.Command != inst.Command {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("dropped the bundle's command %q; the session runs %q (import with --trust-commands to keep it)", d.Command, inst.Command))
+		}
+		if d.Wrapper != "" {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("dropped the bundle's wrapper %q (import with --trust-commands to keep it)", d.Wrapper))
+		}
+		if len(d.ToolOptionsJSON) > 0 {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("dropped the bundle's tool options %s (import with --trust-commands to keep them)", d.ToolOptionsJSON))
+		}
+	}
+	if d.WorktreePath != "" {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("worktree %s (branch %s) was not recreated; the session uses %s", d.WorktreePath, d.WorktreeBranch, projectPath))
+	}
+	return inst
+}
+
+// launchCommandForTool returns the command a new session of tool runs on
+// this machine: the command of a custom tool from config.toml, the name of
+// a built-in tool, and nothing (a shell) otherwise.
+func launchCommandForTool(tool string) string {
+	if def := GetToolDef(tool); def != nil {
+		return def.Command
+	}
+	switch tool {
+	case "claude", "gemini", "opencode", "codex", "cursor", "aider":
+		return tool
+	}
+	return ""
+}
+
+// restoreBundleAttachments reattaches the bundle's MCPs and skills to the
+// project where that can be done with local definitions, and returns
+// warnings for the rest. Existing project attachments are left alone.
+func restoreBundleAttachments(m *SessionBundleManifest, projectPath string) []string {
+	var warnings []string
+
+	// MCPs: only names defined in the local config are written, so no
+	// redacted value ever reaches a config file
+	names := make(map[string]bool)
+	for _, n := range m.Instance.LoadedMCPNames {
+		names[n] = true
+	}
+	if len(m.ProjectMCPJSON) > 0 {
+		var doc struct {
+			MCPServers map[string]json.RawMessage `json:"mcpServers"`
+		}
+		if json.Unmarshal(m.ProjectMCPJSON, &doc) == nil {
+			for n := range doc.MCPServers {
+				names[n] = true
+			}
+		}
+	}
+	available := GetAvailableMCPs()
+	var local, missing []string
+	for n := range names {
+		if _, ok := available[n]; ok {
+			local = append(local, n)
+		} else {
+			missing = append(missing, n)
+		}
+	}
+	sort.Strings(local)
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		warnings = append(warnings, fmt.Sprintf("MCPs not defined in this machine's config.toml: %s (definitions in the bundle have secrets redacted)", strings.Join(missing, ", ")))
+	}
+	mcpFile := filepath.Join(projectPath, ".mcp.json")
+	if _, err := os.Stat(mcpFile); os.IsNotExist(err) && len(local) > 0 {
+		if err := WriteMCPJsonFromConfig(projectPath, local); err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to write %s: %v", mcpFile, err))
+		}
+	}
+
+	// Skills: attach from local skill sources
+	if len(m.Skills) > 0 {
+		attached, _ := GetAttachedProjectSkills(projectPath)
+		have := make(map[string]bool, len(attached))
+		for _, a := range attached {
+			have[a.ID] = true
+		}
+		for _, s := range m.Skills {
+			if have[s.ID] {
+				continue
+			}
+			if _, err := AttachSkillToProject(projectPath, s.Name, s.Source); err != nil {
+				warnings = append(warnings, fmt.Sprintf("skill %s not attached: %v", s.ID, err))
+			}
+		}
+	}
+	return warnings
+}
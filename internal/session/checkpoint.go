@@ -0,0 +1,182 @@
+package session
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+	"github.com/asheshgoplani/agent-deck/internal/logging"
+)
+
+var checkpointLog = logging.ForComponent(logging.CompSession)
+
+// checkpointMinInterval collapses the signals one turn can produce (a Stop
+// hook and a running→waiting transition a tick later) into one checkpoint.
+const checkpointMinInterval = 10 * time.Second
+
+// Checkpointer snapshots a session's work tree into a git checkpoint ref each
+// time its agent finishes a turn: a Claude Stop hook, a Codex turn-complete
+// notification, or (for tools without hooks) a running→waiting transition.
+// Snapshots are taken on a background worker and pruned to the configured
+// retention.
+type Checkpointer struct {
+	settings CheckpointSettings
+
+	mu       sync.Mutex
+	sessions map[string]*checkpointState
+
+	queue  chan checkpointJob
+	create func(dir, sessionID, message string) (*git.Checkpoint, error)
+	prune  func(dir, sessionID string, keep int, maxAge time.Duration) (int, error)
+	now    func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type checkpointState struct {
+	lastHook   time.Time // UpdatedAt of the last turn-end hook handled
+	lastQueued time.Time
+}
+
+type checkpointJob struct {
+	sessionID string
+	dir       string
+	message   string
+}
+
+// NewCheckpointer creates a checkpointer. It does nothing unless
+// settings.Enabled is set. Call Start to begin taking snapshots.
+func NewCheckpointer(settings CheckpointSettings) *Checkpointer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Checkpointer{
+		settings: settings,
+		sessions: make(map[string]*checkpointState),
+		queue:    make(chan checkpointJob, 64),
+		create:   git.CreateCheckpoint,
+		prune:    git.PruneCheckpoints,
+		now:      time.Now,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Enabled reports whether checkpoints are being taken.
+func (c *Checkpointer) Enabled() bool {
+	return c != nil && c.settings.Enabled
+}
+
+// Start runs the snapshot worker in a background goroutine.
+func (c *Checkpointer) Start() {
+	if !c.Enabled() {
+		return
+	}
+	go func() {
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case job := <-c.queue:
+				c.run(job)
+			}
+		}
+	}()
+}
+
+// Stop shuts down the worker. Queued snapshots are dropped.
+func (c *Checkpointer) Stop() {
+	c.cancel()
+}
+
+// NoteHookStatus checkpoints a session when its hook reports a new
+// turn-end event.
+func (c *Checkpointer) NoteHookStatus(inst *Instance, hs *HookStatus) {
+	if !c.Enabled() || inst == nil || !isTurnEndHook(hs) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stateLocked(inst.ID)
+	if !hs.UpdatedAt.After(s.lastHook) {
+		return
+	}
+	s.lastHook = hs.UpdatedAt
+	c.enqueueLocked(inst, s, "Turn ended ("+hs.Event+")")
+}
+
+// NoteStatusChange checkpoints a session whose status went from running to
+// waiting, which is how turn ends show up for tools without hooks.
+func (c *Checkpointer) NoteStatusChange(inst *Instance, old, new Status) {
+	if !c.Enabled() || inst == nil || old != StatusRunning || new != StatusWaiting {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enqueueLocked(inst, c.stateLocked(inst.ID), "Turn ended")
+}
+
+func (c *Checkpointer) stateLocked(id string) *checkpointState {
+	s := c.sessions[id]
+	if s == nil {
+		s = &checkpointState{}
+		c.sessions[id] = s
+	}
+	return s
+}
+
+func (c *Checkpointer) enqueueLocked(inst *Instance, s *checkpointState, reason string) {
+	now := c.now()
+	if now.Sub(s.lastQueued) < checkpointMinInterval {
+		return
+	}
+	dir := inst.ReviewDir()
+	if dir == "" {
+		return
+	}
+	select {
+	case c.queue <- checkpointJob{sessionID: inst.ID, dir: dir, message: reason}:
+		s.lastQueued = now
+	default:
+		checkpointLog.Debug("checkpoint_queue_full", slog.String("session_id", inst.ID))
+	}
+}
+
+// run takes one snapshot and applies retention.
+func (c *Checkpointer) run(job checkpointJob) {
+	cp, err := c.create(job.dir, job.sessionID, job.message)
+	if err != nil {
+		// Usually not a git repository; nothing to snapshot
+		checkpointLog.Debug("checkpoint_failed",
+			slog.String("session_id", job.sessionID),
+			slog.String("dir", job.dir),
+			slog.String("error", err.Error()))
+		return
+	}
+	if cp == nil {
+		return // Nothing changed since the last checkpoint
+	}
+	checkpointLog.Info("checkpoint_created",
+		slog.String("session_id", job.sessionID),
+		slog.Int("n", cp.N),
+		slog.String("commit", cp.Commit))
+	if removed, err := c.prune(job.dir, job.sessionID, c.settings.Keep, c.settings.MaxAge()); err != nil {
+		checkpointLog.Warn("checkpoint_prune_failed", slog.String("session_id", job.sessionID), slog.String("error", err.Error()))
+	} else if removed > 0 {
+		checkpointLog.Debug("checkpoints_pruned", slog.String("session_id", job.sessionID), slog.Int("removed", removed))
+	}
+}
+
+// isTurnEndHook reports whether a hook event marks the end of an agent turn:
+// Claude's Stop, or a Codex notification such as "agent-turn-complete".
+func isTurnEndHook(hs *HookStatus) bool {
+	if hs == nil {
+		return false
+	}
+	if hs.Event == "Stop" {
+		return true
+	}
+	return hs.Status == "waiting" && strings.Contains(strings.ToLower(hs.Event), "turn")
+}
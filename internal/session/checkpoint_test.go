@@ -0,0 +1,104 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+)
+
+// newTestCheckpointer returns an enabled, unstarted checkpointer whose
+// snapshots are recorded instead of written; tests drive the worker with
+// drainCheckpoints.
+func newTestCheckpointer(t *testing.T) (*Checkpointer, *[]checkpointJob, *time.Time) {
+	t.Helper()
+	c := NewCheckpointer(CheckpointSettings{Enabled: true, Keep: 5})
+	t.Cleanup(c.Stop)
+	var created []checkpointJob
+	c.create = func(dir, sessionID, message string) (*git.Checkpoint, error) {
+		created = append(created, checkpointJob{sessionID: sessionID, dir: dir, message: message})
+		return &git.Checkpoint{N: len(created)}, nil
+	}
+	c.prune = func(string, string, int, time.Duration) (int, error) { return 0, nil }
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	return c, &created, &now
+}
+
+func drainCheckpoints(c *Checkpointer) {
+	for {
+		select {
+		case job := <-c.queue:
+			c.run(job)
+		default:
+			return
+		}
+	}
+}
+
+func TestCheckpointer_HookTurnEnds(t *testing.T) {
+	c, created, now := newTestCheckpointer(t)
+	inst := &Instance{ID: "a", Title: "demo", ProjectPath: "/repo"}
+
+	c.NoteHookStatus(inst, &HookStatus{Status: "running", Event: "UserPromptSubmit", UpdatedAt: *now})
+	drainCheckpoints(c)
+	assert.Empty(t, *created, "only turn ends are checkpointed")
+
+	stop := &HookStatus{Status: "waiting", Event: "Stop", UpdatedAt: *now}
+	c.NoteHookStatus(inst, stop)
+	drainCheckpoints(c)
+	assert.Equal(t, []checkpointJob{{sessionID: "a", dir: "/repo", message: "Turn ended (Stop)"}}, *created)
+
+	// The same event and the status transition it causes don't checkpoint again
+	*now = now.Add(time.Minute)
+	c.NoteHookStatus(inst, stop)
+	*now = now.Add(time.Second)
+	c.NoteStatusChange(inst, StatusRunning, StatusWaiting)
+	drainCheckpoints(c)
+	assert.Len(t, *created, 2, "repeated hook is ignored; the transition a minute later is a new turn")
+
+	c.NoteStatusChange(inst, StatusRunning, StatusWaiting)
+	drainCheckpoints(c)
+	assert.Len(t, *created, 2, "signals within the minimum interval collapse")
+
+	// Codex notify
+	*now = now.Add(time.Minute)
+	c.NoteHookStatus(inst, &HookStatus{Status: "waiting", Event: "agent-turn-complete", UpdatedAt: *now})
+	drainCheckpoints(c)
+	assert.Len(t, *created, 3)
+}
+
+func TestCheckpointer_WorktreeDirAndTransitions(t *testing.T) {
+	c, created, _ := newTestCheckpointer(t)
+	inst := &Instance{ID: "w", ProjectPath: "/repo", WorktreePath: "/repo-wt"}
+
+	c.NoteStatusChange(inst, StatusWaiting, StatusRunning)
+	c.NoteStatusChange(inst, StatusIdle, StatusWaiting)
+	drainCheckpoints(c)
+	assert.Empty(t, *created)
+
+	c.NoteStatusChange(inst, StatusRunning, StatusWaiting)
+	drainCheckpoints(c)
+	if assert.Len(t, *created, 1) {
+		assert.Equal(t, "/repo-wt", (*created)[0].dir)
+	}
+}
+
+func TestCheckpointer_Disabled(t *testing.T) {
+	c := NewCheckpointer(CheckpointSettings{})
+	t.Cleanup(c.Stop)
+	c.NoteStatusChange(&Instance{ID: "a", ProjectPath: "/repo"}, StatusRunning, StatusWaiting)
+	assert.Empty(t, c.queue)
+	assert.False(t, c.Enabled())
+	assert.False(t, (*Checkpointer)(nil).Enabled())
+}
+
+func TestIsTurnEndHook(t *testing.T) {
+	assert.True(t, isTurnEndHook(&HookStatus{Event: "Stop", Status: "waiting"}))
+	assert.True(t, isTurnEndHook(&HookStatus{Event: "turn/completed", Status: "waiting"}))
+	assert.False(t, isTurnEndHook(&HookStatus{Event: "agent-turn-start", Status: "running"}))
+	assert.False(t, isTurnEndHook(&HookStatus{Event: "Notification", Status: "waiting"}))
+	assert.False(t, isTurnEndHook(nil))
+}
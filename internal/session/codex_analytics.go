@@ -0,0 +1,183 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// codexTokenUsage mirrors Codex's TokenUsage. input_tokens includes
+// cached_input_tokens, and output_tokens includes reasoning_output_tokens.
+type codexTokenUsage struct {
+	InputTokens           int `json:"input_tokens"`
+	CachedInputTokens     int `json:"cached_input_tokens"`
+	OutputTokens          int `json:"output_tokens"`
+	ReasoningOutputTokens int `json:"reasoning_output_tokens"`
+	TotalTokens           int `json:"total_tokens"`
+}
+
+// codexAnalyticsPayload covers the rollout payloads that carry analytics:
+// event_msg token_count, turn_context, and response_item tool calls.
+type codexAnalyticsPayload struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Model string `json:"model"`
+	Info  *struct {
+		TotalTokenUsage    codexTokenUsage `json:"total_token_usage"`
+		LastTokenUsage     codexTokenUsage `json:"last_token_usage"`
+		ModelContextWindow int             `json:"model_context_window"`
+	} `json:"info"`
+}
+
+// codexToolCallNames maps response_item types that invoke a tool without a
+// name field to the tool name shown in analytics.
+var codexToolCallNames = map[string]string{
+	"local_shell_call": "shell",
+	"web_search_call":  "web_search",
+}
+
+// ParseCodexRollout parses a Codex rollout file and returns analytics.
+// Token totals come from the last token_count event, which Codex records
+// cumulatively; each change in the running total counts as one turn.
+func ParseCodexRollout(path string) (*SessionAnalytics, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	analytics := &SessionAnalytics{
+		ToolCalls: []ToolCall{},
+	}
+	toolCounts := make(map[string]int)
+	var firstTime, lastTime time.Time
+	var total codexTokenUsage
+
+	scanner := bufio.NewScanner(file)
+	buf := make([]byte, 0, 1024*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+
+	for scanner.Scan() {
+		var record codexRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || len(record.Payload) == 0 {
+			continue
+		}
+		var p codexAnalyticsPayload
+		if err := json.Unmarshal(record.Payload, &p); err != nil {
+			continue
+		}
+
+		if ts := parseTranscriptTime(record.Timestamp); !ts.IsZero() {
+			if firstTime.IsZero() || ts.Before(firstTime) {
+				firstTime = ts
+			}
+			if ts.After(lastTime) {
+				lastTime = ts
+			}
+		}
+
+		switch {
+		case record.Type == "turn_context":
+			if p.Model != "" {
+				analytics.Model = p.Model
+			}
+		case record.Type == "event_msg" && p.Type == "token_count":
+			if p.Info == nil {
+				break // Rate-limit-only update
+			}
+			if p.Info.TotalTokenUsage.TotalTokens != total.TotalTokens {
+				analytics.TotalTurns++
+			}
+			total = p.Info.TotalTokenUsage
+			last := p.Info.LastTokenUsage
+			analytics.CurrentContextTokens = last.TotalTokens
+			if analytics.CurrentContextTokens == 0 {
+				analytics.CurrentContextTokens = last.InputTokens + last.OutputTokens
+			}
+			if p.Info.ModelContextWindow > 0 {
+				analytics.ContextWindow = p.Info.ModelContextWindow
+			}
+		case record.Type == "response_item":
+			var name string
+			switch p.Type {
+			case "function_call", "custom_tool_call":
+				name = p.Name
+			default:
+				name = codexToolCallNames[p.Type]
+			}
+			if name != "" {
+				toolCounts[name]++
+			}
+		}
+	}
+
+	analytics.InputTokens = total.InputTokens - total.CachedInputTokens
+	analytics.CacheReadTokens = total.CachedInputTokens
+	analytics.OutputTokens = total.OutputTokens
+
+	for name, count := range toolCounts {
+		analytics.ToolCalls = append(analytics.ToolCalls, ToolCall{
+			Name:  name,
+			Count: count,
+		})
+	}
+
+	analytics.StartTime = firstTime
+	analytics.LastActive = lastTime
+	if !firstTime.IsZero() && !lastTime.IsZero() {
+		analytics.Duration = lastTime.Sub(firstTime)
+	}
+	analytics.EstimatedCost = analytics.CalculateCost(analytics.Model)
+
+	return analytics, scanner.Err()
+}
+
+// codexRolloutCache remembers resolved rollout paths by $CODEX_HOME and
+// session ID, so periodic analytics refreshes don't walk the sessions tree.
+var codexRolloutCache = struct {
+	sync.Mutex
+	paths map[string]string
+}{paths: make(map[string]string)}
+
+// findCodexRollout returns the rollout file for a Codex session ID under
+// $CODEX_HOME/sessions, preferring the most recently modified match. Found
+// paths are cached while the file exists; misses walk the tree again.
+func findCodexRollout(sessionID string) string {
+	if sessionID == "" {
+		return ""
+	}
+	home := getCodexHomeDir()
+	key := home + "\x00" + sessionID
+	codexRolloutCache.Lock()
+	cached := codexRolloutCache.paths[key]
+	codexRolloutCache.Unlock()
+	if cached != "" {
+		if _, err := os.Stat(cached); err == nil {
+			return cached
+		}
+	}
+
+	var best string
+	var bestTime time.Time
+	_ = walkTranscripts(codexTranscriptSource{home: home}, func(path string, d os.DirEntry) error {
+		if !strings.Contains(d.Name(), sessionID) {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && (best == "" || info.ModTime().After(bestTime)) {
+			best, bestTime = path, info.ModTime()
+		}
+		return nil
+	})
+
+	codexRolloutCache.Lock()
+	if best != "" {
+		codexRolloutCache.paths[key] = best
+	} else {
+		delete(codexRolloutCache.paths, key)
+	}
+	codexRolloutCache.Unlock()
+	return best
+}
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const codexAnalyticsRollout = `{"timestamp":"2025-10-18T10:00:00Z","type":"session_meta","payload":{"id":"0199f5a1-2b3c-7d4e-8f90-123456789abc","cwd":"/home/me/api"}}
+{"timestamp":"2025-10-18T10:00:01Z","type":"turn_context","payload":{"cwd":"/home/me/api","model":"gpt-5-codex"}}
+{"timestamp":"2025-10-18T10:00:02Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"fix the flaky test"}]}}
+{"timestamp":"2025-10-18T10:00:03Z","type":"event_msg","payload":{"type":"token_count","info":null,"rate_limits":{}}}
+{"timestamp":"2025-10-18T10:00:04Z","type":"response_item","payload":{"type":"function_call","name":"shell","arguments":"{}"}}
+{"timestamp":"2025-10-18T10:00:05Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":10000,"cached_input_tokens":4000,"output_tokens":500,"reasoning_output_tokens":200,"total_tokens":10500},"last_token_usage":{"input_tokens":10000,"cached_input_tokens":4000,"output_tokens":500,"reasoning_output_tokens":200,"total_tokens":10500},"model_context_window":272000}}}
+{"timestamp":"2025-10-18T10:00:06Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":10000,"cached_input_tokens":4000,"output_tokens":500,"reasoning_output_tokens":200,"total_tokens":10500},"last_token_usage":{"input_tokens":10000,"cached_input_tokens":4000,"output_tokens":500,"reasoning_output_tokens":200,"total_tokens":10500},"model_context_window":272000}}}
+{"timestamp":"2025-10-18T10:00:07Z","type":"response_item","payload":{"type":"custom_tool_call","name":"apply_patch","input":"*** Begin Patch"}}
+{"timestamp":"2025-10-18T10:00:08Z","type":"response_item","payload":{"type":"function_call","name":"shell","arguments":"{}"}}
+{"timestamp":"2025-10-18T10:00:09Z","type":"response_item","payload":{"type":"web_search_call","status":"completed"}}
+{"timestamp":"2025-10-18T10:01:00Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":25000,"cached_input_tokens":14000,"output_tokens":1300,"reasoning_output_tokens":600,"total_tokens":26300},"last_token_usage":{"input_tokens":15000,"cached_input_tokens":10000,"output_tokens":800,"reasoning_output_tokens":400,"total_tokens":15800},"model_context_window":272000}}}
+`
+
+func TestParseCodexRollout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rollout.jsonl")
+	writeTestFile(t, path, codexAnalyticsRollout)
+
+	a, err := ParseCodexRollout(path)
+	if err != nil {
+		t.Fatalf("ParseCodexRollout: %v", err)
+	}
+	if a.Model != "gpt-5-codex" {
+		t.Errorf("Model = %q, want gpt-5-codex", a.Model)
+	}
+	// The duplicate token_count event must not count as a turn or double the totals
+	if a.TotalTurns != 2 {
+		t.Errorf("TotalTurns = %d, want 2", a.TotalTurns)
+	}
+	if a.InputTokens != 11000 || a.CacheReadTokens != 14000 || a.OutputTokens != 1300 {
+		t.Errorf("tokens = in %d, cache %d, out %d; want 11000, 14000, 1300", a.InputTokens, a.CacheReadTokens, a.OutputTokens)
+	}
+	if a.CurrentContextTokens != 15800 {
+		t.Errorf("CurrentContextTokens = %d, want 15800", a.CurrentContextTokens)
+	}
+	if got := a.ContextPercent(0); got < 5.8 || got > 5.81 {
+		t.Errorf("ContextPercent = %.3f, want ~5.81 (reported 272k window)", got)
+	}
+
+	counts := map[string]int{}
+	for _, tc := range a.ToolCalls {
+		counts[tc.Name] = tc.Count
+	}
+	if counts["shell"] != 2 || counts["apply_patch"] != 1 || counts["web_search"] != 1 || len(counts) != 3 {
+		t.Errorf("ToolCalls = %v", a.ToolCalls)
+	}
+
+	if a.Duration.Seconds() != 60 {
+		t.Errorf("Duration = %v, want 60s", a.Duration)
+	}
+	if want := a.CalculateCost("gpt-5-codex"); a.EstimatedCost != want || want == 0 {
+		t.Errorf("EstimatedCost = %f, want %f", a.EstimatedCost, want)
+	}
+}
+
+func TestFindCodexRollout(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("CODEX_HOME", home)
+	id := "0199f5a1-2b3c-7d4e-8f90-123456789abc"
+	path := filepath.Join(home, "sessions", "2025", "10", "18", "rollout-2025-10-18T10-00-00-"+id+".jsonl")
+	writeTestFile(t, path, codexAnalyticsRollout)
+
+	if got := findCodexRollout(id); got != path {
+		t.Errorf("findCodexRollout = %q, want %q", got, path)
+	}
+	if got := findCodexRollout("1b2c3d4e-5f60-4718-9a0b-c1d2e3f4a5b6"); got != "" {
+		t.Errorf("findCodexRollout(unknown) = %q, want empty", got)
+	}
+
+	// Later lookups use the cached path without walking the tree: a newer
+	// file for the same ID is only picked up once the cached one is gone
+	newer := filepath.Join(home, "sessions", "2025", "10", "19", "rollout-2025-10-19T10-00-00-"+id+".jsonl")
+	writeTestFile(t, newer, codexAnalyticsRollout)
+	if got := findCodexRollout(id); got != path {
+		t.Errorf("findCodexRollout (cached) = %q, want %q", got, path)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := findCodexRollout(id); got != newer {
+		t.Errorf("findCodexRollout after removal = %q, want %q", got, newer)
+	}
+
+	inst := &Instance{Tool: "codex", CodexSessionID: id}
+	a, err := GetSessionAnalytics(inst)
+	if err != nil || a == nil || a.TotalTurns != 2 {
+		t.Fatalf("GetSessionAnalytics = %+v, %v", a, err)
+	}
+	usage, ok := GetContextUsage(inst)
+	if !ok || usage.Tokens != 15800 || usage.Model != "gpt-5-codex" {
+		t.Errorf("GetContextUsage = %+v, %v", usage, ok)
+	}
+}
@@ -0,0 +1,398 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/asheshgoplani/agent-deck/internal/logging"
+)
+
+var contextLog = logging.ForComponent(logging.CompSession)
+
+// Context-window actions
+const (
+	ContextActionOff     = "off"
+	ContextActionCompact = "compact"
+	ContextActionFork    = "fork"
+)
+
+const (
+	defaultContextThreshold   = 80
+	defaultContextIdleSeconds = 60
+
+	// contextActionCooldown prevents repeated actions on the same session while
+	// a compaction is still in flight or the tool hasn't written new usage yet.
+	contextActionCooldown = 5 * time.Minute
+
+	// contextSummaryMaxResponse caps the last-response excerpt in fork summaries
+	contextSummaryMaxResponse = 2000
+)
+
+// ContextPolicy is an automatic context-window policy.
+// Empty/zero fields inherit from the next level (global -> group -> session).
+type ContextPolicy struct {
+	Action      string `toml:"action" json:"action,omitempty"`
+	Threshold   int    `toml:"threshold" json:"threshold,omitempty"`
+	IdleSeconds int    `toml:"idle_seconds" json:"idle_seconds,omitempty"`
+}
+
+// IsValidContextAction reports whether action is a recognized context action.
+func IsValidContextAction(action string) bool {
+	switch action {
+	case ContextActionOff, ContextActionCompact, ContextActionFork:
+		return true
+	}
+	return false
+}
+
+// merge overlays the non-empty fields of o onto p.
+func (p ContextPolicy) merge(o ContextPolicy) ContextPolicy {
+	if o.Action != "" {
+		p.Action = o.Action
+	}
+	if o.Threshold > 0 {
+		p.Threshold = o.Threshold
+	}
+	if o.IdleSeconds > 0 {
+		p.IdleSeconds = o.IdleSeconds
+	}
+	return p
+}
+
+// ResolveContextPolicy returns the effective context policy for an instance.
+// Precedence (lowest to highest): defaults, [context], [context.groups] from the
+// root group down to the instance's group, then the per-session override.
+func ResolveContextPolicy(inst *Instance) ContextPolicy {
+	return resolveContextPolicy(GetContextSettings(), inst.GroupPath, inst.ContextPolicy)
+}
+
+func resolveContextPolicy(settings ContextSettings, groupPath string, override *ContextPolicy) ContextPolicy {
+	policy := ContextPolicy{
+		Action:      ContextActionOff,
+		Threshold:   defaultContextThreshold,
+		IdleSeconds: defaultContextIdleSeconds,
+	}
+	policy = policy.merge(ContextPolicy{
+		Action:      settings.Action,
+		Threshold:   settings.Threshold,
+		IdleSeconds: settings.IdleSeconds,
+	})
+
+	if groupPath != "" && len(settings.Groups) > 0 {
+		parts := strings.Split(groupPath, "/")
+		for i := range parts {
+			if g, ok := settings.Groups[strings.Join(parts[:i+1], "/")]; ok {
+				policy = policy.merge(g)
+			}
+		}
+	}
+
+	if override != nil {
+		policy = policy.merge(*override)
+	}
+	if !IsValidContextAction(policy.Action) {
+		policy.Action = ContextActionOff
+	}
+	return policy
+}
+
+// GetToolCompactCommand returns the in-session command that compacts the
+// conversation for a tool, or "" if the tool has no known compact command.
+func GetToolCompactCommand(tool string) string {
+	if def := GetToolDef(tool); def != nil && def.CompactCommand != "" {
+		return def.CompactCommand
+	}
+	switch tool {
+	case "claude", "codex", "opencode":
+		return "/compact"
+	case "gemini":
+		return "/compress"
+	}
+	return ""
+}
+
+// ContextUsage is a point-in-time view of a session's context window usage.
+type ContextUsage struct {
+	Model      string
+	Tokens     int
+	Percent    float64
+	LastActive time.Time // Last transcript activity (zero if unknown)
+}
+
+// GetContextUsage returns the current context usage for an instance, or false
+// if the tool's usage can't be determined.
+func GetContextUsage(inst *Instance) (ContextUsage, bool) {
+	switch inst.Tool {
+	case "claude", "codex", "opencode":
+		a, err := GetSessionAnalytics(inst)
+		if err != nil || a == nil || a.TotalTurns == 0 {
+			return ContextUsage{}, false
+		}
+		return ContextUsage{
+			Model:      a.Model,
+			Tokens:     a.CurrentContextTokens,
+			Percent:    a.ContextPercent(0),
+			LastActive: a.LastActive,
+		}, true
+	case "gemini":
+		a := inst.GeminiAnalytics
+		if a == nil || a.CurrentContextTokens == 0 {
+			return ContextUsage{}, false
+		}
+		return ContextUsage{
+			Model:      a.Model,
+			Tokens:     a.CurrentContextTokens,
+			Percent:    a.ContextPercent(0),
+			LastActive: a.LastActive,
+		}, true
+	}
+	return ContextUsage{}, false
+}
+
+// ContextActionResult describes an action taken by the ContextManager.
+type ContextActionResult struct {
+	Instance *Instance
+	Action   string
+	Percent  float64
+	Forked   *Instance // New session (fork action only)
+	Err      error
+}
+
+// ContextManager watches idle sessions and compacts or forks them when their
+// context window usage crosses the configured threshold.
+type ContextManager struct {
+	mu sync.Mutex
+
+	// lastAction tracks when each instance was last acted on
+	lastAction map[string]time.Time
+
+	// cache avoids re-parsing unchanged transcripts
+	cache map[string]contextCacheEntry
+
+	now func() time.Time
+}
+
+type contextCacheEntry struct {
+	size  int64
+	mtime time.Time
+	usage ContextUsage
+	ok    bool
+}
+
+// NewContextManager creates a ContextManager.
+func NewContextManager() *ContextManager {
+	return &ContextManager{
+		lastAction: make(map[string]time.Time),
+		cache:      make(map[string]contextCacheEntry),
+		now:        time.Now,
+	}
+}
+
+// usage returns context usage for an instance, reusing the cached parse when
+// the Claude transcript hasn't changed since the last check.
+func (m *ContextManager) usage(inst *Instance) (ContextUsage, bool) {
+	if inst.Tool != "claude" {
+		return GetContextUsage(inst)
+	}
+	path := inst.GetJSONLPath()
+	if path == "" {
+		return ContextUsage{}, false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return ContextUsage{}, false
+	}
+	m.mu.Lock()
+	entry, hit := m.cache[path]
+	m.mu.Unlock()
+	if hit && entry.size == info.Size() && entry.mtime.Equal(info.ModTime()) {
+		return entry.usage, entry.ok
+	}
+	usage, ok := GetContextUsage(inst)
+	m.mu.Lock()
+	m.cache[path] = contextCacheEntry{size: info.Size(), mtime: info.ModTime(), usage: usage, ok: ok}
+	m.mu.Unlock()
+	return usage, ok
+}
+
+// Check evaluates every instance against its policy and acts on those that
+// qualify. skipID excludes a session (e.g. the one the user is attached to).
+// Forked sessions are started but not persisted; the caller adds them to its
+// instance list and saves.
+func (m *ContextManager) Check(instances []*Instance, skipID string) []ContextActionResult {
+	var results []ContextActionResult
+	now := m.now()
+
+	for _, inst := range instances {
+		if inst.ID == skipID {
+			continue
+		}
+		policy := ResolveContextPolicy(inst)
+		if policy.Action == ContextActionOff {
+			continue
+		}
+		status := inst.GetStatusThreadSafe()
+		if status != StatusWaiting && status != StatusIdle {
+			continue
+		}
+		if now.Sub(inst.GetLastActivityTime()) < time.Duration(policy.IdleSeconds)*time.Second {
+			continue
+		}
+
+		m.mu.Lock()
+		last := m.lastAction[inst.ID]
+		m.mu.Unlock()
+		if !last.IsZero() && now.Sub(last) < contextActionCooldown {
+			continue
+		}
+
+		usage, ok := m.usage(inst)
+		if !ok || usage.Percent < float64(policy.Threshold) {
+			continue
+		}
+		// Transcript hasn't moved since we last acted: the previous action's
+		// usage numbers are stale, wait for the tool to report fresh ones.
+		if !last.IsZero() && !usage.LastActive.IsZero() && !usage.LastActive.After(last) {
+			continue
+		}
+
+		m.mu.Lock()
+		m.lastAction[inst.ID] = now
+		m.mu.Unlock()
+
+		result := ContextActionResult{Instance: inst, Action: policy.Action, Percent: usage.Percent}
+		switch policy.Action {
+		case ContextActionCompact:
+			result.Err = compactInstance(inst)
+		case ContextActionFork:
+			result.Forked, result.Err = forkInstanceWithSummary(inst, usage)
+		}
+		recordContextAction(result)
+		results = append(results, result)
+	}
+	return results
+}
+
+// Forget drops tracked state for an instance (e.g. after deletion).
+func (m *ContextManager) Forget(instanceID string) {
+	m.mu.Lock()
+	delete(m.lastAction, instanceID)
+	m.mu.Unlock()
+}
+
+func compactInstance(inst *Instance) error {
+	cmd := GetToolCompactCommand(inst.Tool)
+	if cmd == "" {
+		return fmt.Errorf("tool %q has no compact command (set compact_command in [tools.%s])", inst.Tool, inst.Tool)
+	}
+	tmuxSess := inst.GetTmuxSession()
+	if tmuxSess == nil {
+		return fmt.Errorf("tmux session not initialized")
+	}
+	return tmuxSess.SendKeysAndEnter(cmd)
+}
+
+// forkInstanceWithSummary starts a fresh session in the same project and group,
+// seeded with a summary of the source session.
+func forkInstanceWithSummary(inst *Instance, usage ContextUsage) (*Instance, error) {
+	forked := NewInstanceWithGroupAndTool(inst.Title+" (cont)", inst.ProjectPath, inst.GroupPath, inst.Tool)
+	forked.Command = inst.Command
+	forked.Wrapper = inst.Wrapper
+	forked.ToolOptionsJSON = inst.ToolOptionsJSON
+	forked.GeminiYoloMode = inst.GeminiYoloMode
+	forked.GeminiModel = inst.GeminiModel
+	if inst.ContextPolicy != nil {
+		p := *inst.ContextPolicy
+		forked.ContextPolicy = &p
+	}
+
+	if err := forked.StartWithMessage(BuildContextSummary(inst, usage)); err != nil {
+		return nil, err
+	}
+	return forked, nil
+}
+
+// BuildContextSummary builds the seed message for a session forked because its
+// predecessor ran low on context.
+func BuildContextSummary(inst *Instance, usage ContextUsage) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "This session continues %q, which was forked after reaching %.0f%% of its context window.", inst.Title, usage.Percent)
+	if inst.LatestPrompt != "" {
+		fmt.Fprintf(&b, " The last request there was: %s", strings.TrimSpace(inst.LatestPrompt))
+	}
+	if resp, err := inst.GetLastResponse(); err == nil && resp != nil && resp.Content != "" {
+		content := strings.TrimSpace(resp.Content)
+		if len(content) > contextSummaryMaxResponse {
+			content = "..." + tailOnRuneBoundary(content, contextSummaryMaxResponse)
+		}
+		fmt.Fprintf(&b, " The last response was: %s", content)
+	}
+	b.WriteString(" Review the project state and continue from there.")
+	// Keep the seed on one line so it is sent as a single prompt
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// tailOnRuneBoundary returns at most the last max bytes of s without
+// splitting a multi-byte rune.
+func tailOnRuneBoundary(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := len(s) - max
+	for cut < len(s) && !utf8.RuneStart(s[cut]) {
+		cut++
+	}
+	return s[cut:]
+}
+
+// Describe summarizes the action for history entries and notifications.
+func (r ContextActionResult) Describe() string {
+	switch {
+	case r.Err != nil:
+		return fmt.Sprintf("%s at %.0f%% failed: %v", r.Action, r.Percent, r.Err)
+	case r.Forked != nil:
+		return fmt.Sprintf("forked to %q (%s) at %.0f%%", r.Forked.Title, r.Forked.ID, r.Percent)
+	default:
+		return fmt.Sprintf("compacted at %.0f%%", r.Percent)
+	}
+}
+
+func recordContextAction(r ContextActionResult) {
+	contextLog.Info("context_action",
+		slog.String("instance_id", r.Instance.ID),
+		slog.String("action", r.Action),
+		slog.Float64("percent", r.Percent),
+		slog.Bool("failed", r.Err != nil))
+
+	RecordHistory(r.Instance.ID, HistoryKindContext+r.Action, r.Describe())
+}
+
+// marshalContextPolicy encodes a per-session policy for the tool_data blob.
+func marshalContextPolicy(p *ContextPolicy) json.RawMessage {
+	if p == nil {
+		return nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// unmarshalContextPolicy decodes a per-session policy from the tool_data blob.
+func unmarshalContextPolicy(data json.RawMessage) *ContextPolicy {
+	if len(data) == 0 {
+		return nil
+	}
+	var p ContextPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil
+	}
+	return &p
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveContextPolicy_Defaults(t *testing.T) {
+	p := resolveContextPolicy(ContextSettings{}, "", nil)
+	assert.Equal(t, ContextPolicy{Action: ContextActionOff, Threshold: 80, IdleSeconds: 60}, p)
+}
+
+func TestResolveContextPolicy_Precedence(t *testing.T) {
+	settings := ContextSettings{
+		Action:    ContextActionCompact,
+		Threshold: 90,
+		Groups: map[string]ContextPolicy{
+			"work":     {Threshold: 70},
+			"work/api": {Action: ContextActionFork},
+			"personal": {Action: ContextActionOff},
+		},
+	}
+
+	// Global only
+	p := resolveContextPolicy(settings, "other", nil)
+	assert.Equal(t, ContextActionCompact, p.Action)
+	assert.Equal(t, 90, p.Threshold)
+
+	// Parent group applies to subgroups, child group overrides
+	p = resolveContextPolicy(settings, "work/api/v2", nil)
+	assert.Equal(t, ContextActionFork, p.Action)
+	assert.Equal(t, 70, p.Threshold)
+
+	// Per-session override wins
+	p = resolveContextPolicy(settings, "work/api", &ContextPolicy{Action: ContextActionCompact, IdleSeconds: 5})
+	assert.Equal(t, ContextActionCompact, p.Action)
+	assert.Equal(t, 70, p.Threshold)
+	assert.Equal(t, 5, p.IdleSeconds)
+
+	p = resolveContextPolicy(settings, "personal", nil)
+	assert.Equal(t, ContextActionOff, p.Action)
+}
+
+func TestResolveContextPolicy_InvalidAction(t *testing.T) {
+	p := resolveContextPolicy(ContextSettings{Action: "explode"}, "", nil)
+	assert.Equal(t, ContextActionOff, p.Action)
+}
+
+func TestGetToolCompactCommand(t *testing.T) {
+	assert.Equal(t, "/compact", GetToolCompactCommand("claude"))
+	assert.Equal(t, "/compact", GetToolCompactCommand("codex"))
+	assert.Equal(t, "/compress", GetToolCompactCommand("gemini"))
+	assert.Equal(t, "", GetToolCompactCommand("shell"))
+}
+
+func TestModelContextLimit(t *testing.T) {
+	assert.Equal(t, 200000, ModelContextLimit("claude-sonnet-4-20250514"))
+	assert.Equal(t, 1048576, ModelContextLimit("gemini-2.5-pro"))
+	assert.Equal(t, DefaultContextLimit, ModelContextLimit("unknown-model"))
+	assert.Equal(t, DefaultContextLimit, ModelContextLimit(""))
+}
+
+func TestContextPolicyRoundTrip(t *testing.T) {
+	assert.Nil(t, marshalContextPolicy(nil))
+	assert.Nil(t, unmarshalContextPolicy(nil))
+
+	in := &ContextPolicy{Action: ContextActionFork, Threshold: 75}
+	out := unmarshalContextPolicy(marshalContextPolicy(in))
+	assert.Equal(t, in, out)
+}
+
+func TestTailOnRuneBoundary(t *testing.T) {
+	assert.Equal(t, "abc", tailOnRuneBoundary("abc", 5))
+	assert.Equal(t, "bc", tailOnRuneBoundary("abc", 2))
+	// "é" is two bytes; cutting into it drops the partial rune
+	assert.Equal(t, "éyz", tailOnRuneBoundary("xéyz", 4))
+	assert.Equal(t, "yz", tailOnRuneBoundary("xéyz", 3))
+	assert.True(t, utf8.ValidString(tailOnRuneBoundary("日本語テキスト", 7)))
+}
+
+func TestContextActionResultDescribe(t *testing.T) {
+	inst := &Instance{ID: "a", Title: "api"}
+	assert.Equal(t, "compacted at 85%", ContextActionResult{Instance: inst, Action: ContextActionCompact, Percent: 85}.Describe())
+	forked := ContextActionResult{Instance: inst, Action: ContextActionFork, Percent: 90, Forked: &Instance{ID: "b", Title: "api (cont)"}}
+	assert.Equal(t, `forked to "api (cont)" (b) at 90%`, forked.Describe())
+}
@@ -0,0 +1,229 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// conversationTitleMax caps titles derived from a conversation's first prompt
+const conversationTitleMax = 48
+
+// ConversationDiscoveryOptions filters which on-disk conversations
+// DiscoverConversations returns.
+type ConversationDiscoveryOptions struct {
+	Tools       []string  // Tools to scan (empty = claude, codex, gemini and opencode)
+	Since       time.Time // Only conversations active at or after this time (zero = all)
+	ProjectPath string    // Only conversations whose working directory is this path or below it
+}
+
+// DiscoveredConversation is a tool conversation found on disk that can be
+// turned into an agent-deck session.
+type DiscoveredConversation struct {
+	Tool         string    `json:"tool"`
+	SessionID    string    `json:"session_id"`
+	ProjectPath  string    `json:"project_path"`
+	Title        string    `json:"title"`
+	Path         string    `json:"path"`
+	LastActivity time.Time `json:"last_activity"`
+	Messages     int       `json:"messages"`
+	// Skip explains why the conversation can't be imported (empty = importable)
+	Skip string `json:"skip,omitempty"`
+	// InstanceID is set when an existing session already resumes this conversation
+	InstanceID string `json:"instance_id,omitempty"`
+}
+
+// DiscoverConversations scans the tools' transcript directories for
+// conversations, newest first. Conversations already linked to one of
+// existing are marked with InstanceID; ones that can't be resumed carry a
+// Skip reason.
+func DiscoverConversations(existing []*Instance, opts ConversationDiscoveryOptions) ([]*DiscoveredConversation, error) {
+	linked := make(map[string]string, len(existing))
+	for _, inst := range existing {
+		if id := inst.ToolSessionID(); id != "" {
+			linked[inst.Tool+":"+id] = inst.ID
+		}
+	}
+	projectFilter := ""
+	if opts.ProjectPath != "" {
+		projectFilter = filepath.Clean(ExpandPath(opts.ProjectPath))
+	}
+
+	seen := make(map[string]*DiscoveredConversation)
+	var result []*DiscoveredConversation
+	for _, src := range NewTranscriptSources(GetClaudeConfigDir(), opts.Tools) {
+		err := walkTranscripts(src, func(path string, d os.DirEntry) error {
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if !opts.Since.IsZero() && info.ModTime().Before(opts.Since) {
+				return nil
+			}
+			t, err := src.Parse(path)
+			if err != nil || t == nil || t.SessionID == "" {
+				return nil
+			}
+			if projectFilter != "" && !pathWithin(t.CWD, projectFilter) {
+				return nil
+			}
+
+			c := &DiscoveredConversation{
+				Tool:         src.Tool(),
+				SessionID:    t.SessionID,
+				ProjectPath:  t.CWD,
+				Title:        conversationTitle(t),
+				Path:         path,
+				LastActivity: info.ModTime(),
+				Messages:     len(t.Messages),
+			}
+			c.InstanceID = linked[c.Tool+":"+c.SessionID]
+			c.Skip = conversationSkipReason(c)
+
+			// A conversation can span several files (e.g. a resumed Codex
+			// rollout); keep the most recent one.
+			key := c.Tool + ":" + c.SessionID
+			if prev, ok := seen[key]; ok {
+				if prev.LastActivity.After(c.LastActivity) {
+					return nil
+				}
+				*prev = *c
+				return nil
+			}
+			seen[key] = c
+			result = append(result, c)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].LastActivity.After(result[j].LastActivity)
+	})
+	return result, nil
+}
+
+// conversationSkipReason explains why c can't become a session.
+func conversationSkipReason(c *DiscoveredConversation) string {
+	if _, ok := GeminiCheckpointTag(c.SessionID); ok {
+		return "gemini checkpoint (resume with /chat resume inside a session)"
+	}
+	if c.Messages == 0 {
+		return "no messages"
+	}
+	if c.ProjectPath == "" {
+		return "unknown project directory"
+	}
+	if info, err := os.Stat(c.ProjectPath); err != nil || !info.IsDir() {
+		return "project directory no longer exists"
+	}
+	return ""
+}
+
+// NewInstance builds a stopped session that resumes the conversation on
+// start. An empty groupPath derives the group from the project path, as
+// `agent-deck add` does.
+func (c *DiscoveredConversation) NewInstance(title, groupPath string) *Instance {
+	if title == "" {
+		title = c.Title
+	}
+	var inst *Instance
+	if groupPath == "" {
+		inst = NewInstanceWithTool(title, c.ProjectPath, c.Tool)
+	} else {
+		inst = NewInstanceWithGroupAndTool(title, c.ProjectPath, groupPath, c.Tool)
+	}
+	inst.Command = c.Tool
+
+	switch c.Tool {
+	case TranscriptToolClaude:
+		inst.ClaudeSessionID = c.SessionID
+		inst.ClaudeDetectedAt = time.Now()
+		userConfig, _ := LoadUserConfig()
+		opts := NewClaudeOptions(userConfig)
+		opts.SessionMode = "resume"
+		opts.ResumeSessionID = c.SessionID
+		_ = inst.SetClaudeOptions(opts)
+	case TranscriptToolCodex:
+		inst.CodexSessionID = c.SessionID
+	case TranscriptToolGemini:
+		inst.GeminiSessionID = c.SessionID
+	case TranscriptToolOpenCode:
+		inst.OpenCodeSessionID = c.SessionID
+	}
+	return inst
+}
+
+// UniqueSessionTitle returns title, or title with a " (n)" suffix if a
+// session in the same project already uses it.
+func UniqueSessionTitle(title, projectPath string, instances []*Instance) string {
+	taken := make(map[string]bool)
+	for _, inst := range instances {
+		if inst.ProjectPath == projectPath {
+			taken[inst.Title] = true
+		}
+	}
+	candidate := title
+	for n := 2; taken[candidate]; n++ {
+		candidate = title + " (" + strconv.Itoa(n) + ")"
+	}
+	return candidate
+}
+
+// conversationTitle derives a session title from the first prompt the user
+// typed, falling back to the tool's summary and then the project name.
+func conversationTitle(t *Transcript) string {
+	for _, m := range t.Messages {
+		if m.Role != "user" || isInjectedPrompt(m.Text) {
+			continue
+		}
+		if title := shortenTitle(m.Text); title != "" {
+			return title
+		}
+	}
+	if title := shortenTitle(t.Summary); title != "" && !isInjectedPrompt(t.Summary) {
+		return title
+	}
+	if t.CWD != "" {
+		return filepath.Base(t.CWD)
+	}
+	return t.Tool + " session"
+}
+
+// isInjectedPrompt reports user-role text the tool wrote itself (slash
+// command wrappers, caveats, context blocks) rather than a typed prompt.
+func isInjectedPrompt(text string) bool {
+	text = strings.TrimSpace(text)
+	return strings.HasPrefix(text, "<") || strings.HasPrefix(text, "Caveat:")
+}
+
+// shortenTitle takes the first line of text, collapses whitespace and cuts
+// it at a word boundary.
+func shortenTitle(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	line = strings.Join(strings.Fields(line), " ")
+	if utf8.RuneCountInString(line) <= conversationTitleMax {
+		return line
+	}
+	runes := []rune(line)[:conversationTitleMax]
+	cut := string(runes)
+	if i := strings.LastIndex(cut, " "); i > conversationTitleMax/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:-") + "…"
+}
+
+// pathWithin reports whether path is dir or inside it.
+func pathWithin(path, dir string) bool {
+	if path == "" {
+		return false
+	}
+	rel, err := filepath.Rel(dir, filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,141 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	importClaudeID  = "11111111-2222-4333-8444-555555555555"
+	importLinkedID  = "22222222-3333-4444-8555-666666666666"
+	importMissingID = "33333333-4444-4555-8666-777777777777"
+	importCodexID   = "0199f5a1-2b3c-7d4e-8f90-123456789abc"
+)
+
+// setupImportEnv writes Claude and Codex conversations under a temp HOME:
+// an importable one, one already linked, one whose project is gone, and an
+// old Codex rollout.
+func setupImportEnv(t *testing.T) (home, proj string) {
+	home, claudeDir := setupBundleEnv(t)
+	t.Setenv("CODEX_HOME", filepath.Join(home, ".codex"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	proj = filepath.Join(home, "src", "api")
+	require.NoError(t, os.MkdirAll(proj, 0o755))
+
+	projDir := filepath.Join(claudeDir, "projects", ConvertToClaudeDirName(proj))
+	writeTestFile(t, filepath.Join(projDir, importClaudeID+".jsonl"),
+		`{"type":"user","sessionId":"`+importClaudeID+`","cwd":"`+proj+`","message":{"role":"user","content":"<command-name>/clear</command-name>"}}`+"\n"+
+			`{"type":"user","sessionId":"`+importClaudeID+`","cwd":"`+proj+`","message":{"role":"user","content":"Fix the flaky login test in the auth package before the release\nthanks"}}`+"\n"+
+			`{"type":"assistant","sessionId":"`+importClaudeID+`","cwd":"`+proj+`","message":{"role":"assistant","content":[{"type":"text","text":"On it."}]}}`+"\n")
+	writeTestFile(t, filepath.Join(projDir, importLinkedID+".jsonl"),
+		`{"type":"user","sessionId":"`+importLinkedID+`","cwd":"`+proj+`","message":{"role":"user","content":"already managed"}}`+"\n")
+
+	gone := filepath.Join(home, "src", "deleted")
+	writeTestFile(t, filepath.Join(claudeDir, "projects", ConvertToClaudeDirName(gone), importMissingID+".jsonl"),
+		`{"type":"user","sessionId":"`+importMissingID+`","cwd":"`+gone+`","message":{"role":"user","content":"hello"}}`+"\n")
+
+	rollout := filepath.Join(home, ".codex", "sessions", "2025", "10", "18", "rollout-2025-10-18T10-00-00-"+importCodexID+".jsonl")
+	writeTestFile(t, rollout, `{"timestamp":"2025-10-18T10:00:00Z","type":"session_meta","payload":{"id":"`+importCodexID+`","cwd":"`+proj+`"}}
+{"timestamp":"2025-10-18T10:00:02Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"add rate limiting"}]}}
+`)
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	require.NoError(t, os.Chtimes(rollout, old, old))
+	return home, proj
+}
+
+func findConversation(list []*DiscoveredConversation, id string) *DiscoveredConversation {
+	for _, c := range list {
+		if c.SessionID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestDiscoverConversations(t *testing.T) {
+	_, proj := setupImportEnv(t)
+	existing := []*Instance{{ID: "inst-1", Tool: "claude", ClaudeSessionID: importLinkedID}}
+
+	found, err := DiscoverConversations(existing, ConversationDiscoveryOptions{})
+	require.NoError(t, err)
+	require.Len(t, found, 4)
+	assert.Equal(t, TranscriptToolCodex, found[len(found)-1].Tool, "oldest conversation sorts last")
+
+	c := findConversation(found, importClaudeID)
+	require.NotNil(t, c)
+	assert.Equal(t, proj, c.ProjectPath)
+	assert.Equal(t, "Fix the flaky login test in the auth package…", c.Title)
+	assert.Empty(t, c.Skip)
+	assert.Empty(t, c.InstanceID)
+
+	assert.Equal(t, "inst-1", findConversation(found, importLinkedID).InstanceID)
+	assert.Equal(t, "project directory no longer exists", findConversation(found, importMissingID).Skip)
+
+	codex := findConversation(found, importCodexID)
+	require.NotNil(t, codex)
+	assert.Equal(t, "add rate limiting", codex.Title)
+	assert.Empty(t, codex.Skip)
+}
+
+func TestDiscoverConversations_Filters(t *testing.T) {
+	home, proj := setupImportEnv(t)
+
+	recent, err := DiscoverConversations(nil, ConversationDiscoveryOptions{Since: time.Now().Add(-7 * 24 * time.Hour)})
+	require.NoError(t, err)
+	assert.Nil(t, findConversation(recent, importCodexID), "--since drops the old rollout")
+	assert.Len(t, recent, 3)
+
+	codexOnly, err := DiscoverConversations(nil, ConversationDiscoveryOptions{Tools: []string{TranscriptToolCodex}})
+	require.NoError(t, err)
+	require.Len(t, codexOnly, 1)
+	assert.Equal(t, importCodexID, codexOnly[0].SessionID)
+
+	inProject, err := DiscoverConversations(nil, ConversationDiscoveryOptions{ProjectPath: proj})
+	require.NoError(t, err)
+	assert.Len(t, inProject, 3)
+	assert.Nil(t, findConversation(inProject, importMissingID))
+
+	none, err := DiscoverConversations(nil, ConversationDiscoveryOptions{ProjectPath: filepath.Join(home, "src", "ap")})
+	require.NoError(t, err)
+	assert.Empty(t, none, "a sibling directory sharing a prefix is not within the project")
+}
+
+func TestDiscoveredConversation_NewInstance(t *testing.T) {
+	_, proj := setupImportEnv(t)
+
+	claude := (&DiscoveredConversation{Tool: TranscriptToolClaude, SessionID: importClaudeID, ProjectPath: proj, Title: "fix tests"}).NewInstance("", "")
+	assert.Equal(t, "fix tests", claude.Title)
+	assert.Equal(t, "claude", claude.Tool)
+	assert.Equal(t, importClaudeID, claude.ClaudeSessionID)
+	assert.Equal(t, StatusIdle, claude.Status)
+	opts := claude.GetClaudeOptions()
+	require.NotNil(t, opts)
+	assert.Equal(t, "resume", opts.SessionMode)
+	assert.Equal(t, importClaudeID, opts.ResumeSessionID)
+
+	codex := (&DiscoveredConversation{Tool: TranscriptToolCodex, SessionID: importCodexID, ProjectPath: proj}).NewInstance("api", "work/api")
+	assert.Equal(t, importCodexID, codex.CodexSessionID)
+	assert.Equal(t, "work/api", codex.GroupPath)
+	assert.Equal(t, "codex", codex.Command)
+}
+
+func TestUniqueSessionTitle(t *testing.T) {
+	instances := []*Instance{
+		{Title: "api", ProjectPath: "/src/api"},
+		{Title: "api (2)", ProjectPath: "/src/api"},
+		{Title: "web", ProjectPath: "/src/web"},
+	}
+	assert.Equal(t, "api (3)", UniqueSessionTitle("api", "/src/api", instances))
+	assert.Equal(t, "web", UniqueSessionTitle("web", "/src/api", instances))
+}
+
+func TestShortenTitle(t *testing.T) {
+	assert.Equal(t, "short prompt", shortenTitle("  short   prompt \nsecond line"))
+	assert.Equal(t, "Refactor the storage layer so that every write…", shortenTitle("Refactor the storage layer so that every write goes through one transaction"))
+	assert.Equal(t, "", shortenTitle("   "))
+}
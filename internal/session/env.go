@@ -14,7 +14,8 @@ import (
 //  1. Global [shell].env_files (in order)
 //  2. [shell].init_script (for direnv, nvm, etc.)
 //  3. Tool-specific env_file ([claude].env_file, [gemini].env_file, [tools.X].env_file)
-//  4. Inline env vars from [tools.X].env (highest priority)
+//  4. Leased ports from [ports] (PORT, AGENTDECK_PORT_1..N)
+//  5. Inline env vars from [tools.X].env (highest priority)
 func (i *Instance) buildEnvSourceCommand() string {
 	var sources []string
 	config, _ := LoadUserConfig()
@@ -49,7 +50,12 @@ func (i *Instance) buildEnvSourceCommand() string {
 		sources = append(sources, buildSourceCmd(resolved, ignoreMissing))
 	}
 
-	// 4. Inline env vars from [tools.X].env (highest priority)
+	// 4. Leased ports
+	if portEnv := i.getPortEnv(); portEnv != "" {
+		sources = append(sources, portEnv)
+	}
+
+	// 5. Inline env vars from [tools.X].env (highest priority)
 	if inlineEnv := i.getToolInlineEnv(); inlineEnv != "" {
 		sources = append(sources, inlineEnv)
 	}
@@ -175,13 +175,10 @@ func findGeminiSessionInAllProjects(sessionID string) string {
 	return bestPath
 }
 
-// UpdateGeminiAnalyticsFromDisk updates the analytics struct from the session file on disk.
-// Uses mtime caching to skip re-parsing unchanged files (important for 40MB+ session files).
-func UpdateGeminiAnalyticsFromDisk(projectPath, sessionID string, analytics *GeminiSessionAnalytics) error {
-	if sessionID == "" || len(sessionID) < 8 {
-		return fmt.Errorf("invalid session ID")
-	}
-
+// findGeminiSessionFile returns the chat file of a Gemini session and its
+// modification time, looking in the project's chats directory first and then
+// across all projects. sessionID must be at least 8 characters.
+func findGeminiSessionFile(projectPath, sessionID string) (string, time.Time) {
 	sessionsDir := GetGeminiSessionsDir(projectPath)
 	// Find file matching session ID prefix (first 8 chars)
 	// Filename format: session-YYYY-MM-DDTHH-MM-<uuid8>.json
@@ -197,6 +194,17 @@ func UpdateGeminiAnalyticsFromDisk(projectPath, sessionID string, analytics *Gem
 			}
 		}
 	}
+	return filePath, fileMtime
+}
+
+// UpdateGeminiAnalyticsFromDisk updates the analytics struct from the session file on disk.
+// Uses mtime caching to skip re-parsing unchanged files (important for 40MB+ session files).
+func UpdateGeminiAnalyticsFromDisk(projectPath, sessionID string, analytics *GeminiSessionAnalytics) error {
+	if sessionID == "" || len(sessionID) < 8 {
+		return fmt.Errorf("invalid session ID")
+	}
+
+	filePath, fileMtime := findGeminiSessionFile(projectPath, sessionID)
 
 	if filePath == "" {
 		return fmt.Errorf("session file not found")
@@ -34,32 +34,21 @@ func (a *GeminiSessionAnalytics) TotalTokens() int {
 	return a.InputTokens + a.OutputTokens
 }
 
-// GeminiModelPricing holds pricing per million tokens
-type GeminiModelPricing struct {
-	Input  float64
-	Output float64
-}
-
-// geminiPricing contains pricing per million tokens for each model (as of Jan 2025)
-var geminiPricing = map[string]GeminiModelPricing{
-	"gemini-1.5-flash": {Input: 0.075, Output: 0.30},
-	"gemini-1.5-pro":   {Input: 3.50, Output: 10.50},
-	"gemini-2.0-flash": {Input: 0.10, Output: 0.40},
-	"gemini-2.5-flash": {Input: 0.15, Output: 0.60},
-	"gemini-2.5-pro":   {Input: 1.25, Output: 10.00},
-	// Fallback
-	"default": {Input: 0.15, Output: 0.60},
+// ContextPercent returns the percentage of the model's context window used.
+// modelLimit of 0 looks up a.Model in the model table.
+func (a *GeminiSessionAnalytics) ContextPercent(modelLimit int) float64 {
+	if modelLimit == 0 {
+		modelLimit = ModelContextLimit(a.Model)
+	}
+	return float64(a.CurrentContextTokens) / float64(modelLimit) * 100
 }
 
-// CalculateCost estimates session cost based on token usage and model pricing
+// CalculateCost estimates session cost from token usage using the model
+// table. Unknown models are priced with the gemini* entry rather than the
+// global default.
 func (a *GeminiSessionAnalytics) CalculateCost(model string) float64 {
-	pricing, ok := geminiPricing[model]
-	if !ok {
-		pricing = geminiPricing["default"]
+	if _, ok := GetModelDef(model); !ok {
+		model = "gemini"
 	}
-
-	inputM := float64(a.InputTokens) / 1_000_000
-	outputM := float64(a.OutputTokens) / 1_000_000
-
-	return inputM*pricing.Input + outputM*pricing.Output
+	return LookupModel(model).Cost(TokenUsage{Input: a.InputTokens, Output: a.OutputTokens})
 }
@@ -0,0 +1,428 @@
+package session
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+	"github.com/asheshgoplani/agent-deck/internal/logging"
+)
+
+var gitStatusLog = logging.ForComponent(logging.CompSession)
+
+const (
+	// gitStatusTTL re-checks every tracked directory this often, catching
+	// edits in subdirectories that the (non-recursive) watcher can't see.
+	gitStatusTTL = 30 * time.Second
+	// gitWorkDirTTL is how long a session's resolved working directory is
+	// trusted before asking tmux again.
+	gitWorkDirTTL = 30 * time.Second
+	// gitStatusDebounce coalesces bursts of file events into one refresh.
+	gitStatusDebounce = 500 * time.Millisecond
+)
+
+// gitMetaFiles are the files in a git dir whose changes mean the status may
+// have moved (commit, checkout, stage, merge, fetch).
+var gitMetaFiles = map[string]bool{
+	"HEAD":       true,
+	"index":      true,
+	"ORIG_HEAD":  true,
+	"MERGE_HEAD": true,
+	"FETCH_HEAD": true,
+}
+
+// GitStatusCollector keeps a cached git.Status for every session's working
+// directory. Statuses are refreshed in the background on a timer, when
+// fsnotify sees the work tree or git dir change, and when a hook reports
+// that an agent finished a turn (Stop). Track attaches the cached status to
+// instances, where the UI reads it with GetGitStatus.
+type GitStatusCollector struct {
+	watcher *fsnotify.Watcher // nil when fsnotify is unavailable; polling still works
+
+	mu       sync.Mutex
+	sessions map[string]*gitSessionState // instance ID -> state
+	repos    map[string]*gitRepoState    // work dir -> state
+	watched  map[string][]string         // watched dir -> work dirs it belongs to
+	pending  map[string]bool             // work dirs waiting for the debounce timer
+	debounce *time.Timer
+
+	queue    chan string
+	onChange func()
+	collect  func(dir string) (*git.Status, error)
+	now      func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type gitSessionState struct {
+	inst          *Instance
+	dir           string
+	dirResolvedAt time.Time
+	lastStop      time.Time
+}
+
+type gitRepoState struct {
+	status    *git.Status
+	checkedAt time.Time
+	queued    bool
+	watches   []string
+}
+
+// NewGitStatusCollector creates a collector. onChange (optional) is called
+// after a refresh changes a status. Call Start to begin collecting.
+func NewGitStatusCollector(onChange func()) *GitStatusCollector {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &GitStatusCollector{
+		sessions: make(map[string]*gitSessionState),
+		repos:    make(map[string]*gitRepoState),
+		watched:  make(map[string][]string),
+		pending:  make(map[string]bool),
+		queue:    make(chan string, 256),
+		onChange: onChange,
+		collect:  git.GetStatus,
+		now:      time.Now,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	if w, err := fsnotify.NewWatcher(); err != nil {
+		gitStatusLog.Warn("git_status_watcher_init_failed", slog.String("error", err.Error()))
+	} else {
+		c.watcher = w
+	}
+	return c
+}
+
+// Start runs the refresh worker and file watcher in background goroutines.
+func (c *GitStatusCollector) Start() {
+	go c.refreshLoop()
+	if c.watcher != nil {
+		go c.watchLoop()
+	}
+}
+
+// Stop shuts down the collector.
+func (c *GitStatusCollector) Stop() {
+	c.cancel()
+	if c.watcher != nil {
+		_ = c.watcher.Close()
+	}
+}
+
+// Track registers instances for collection, attaches each one's cached
+// status and queues refreshes for stale directories. Sessions and
+// directories missing from instances are forgotten. It may run tmux to find
+// working directories, so call it off the UI goroutine.
+func (c *GitStatusCollector) Track(instances []*Instance) {
+	now := c.now()
+
+	// Resolve working directories outside the lock (tmux subprocesses)
+	type resolved struct {
+		inst   *Instance
+		dir    string
+		cached bool
+	}
+	c.mu.Lock()
+	work := make([]resolved, 0, len(instances))
+	for _, inst := range instances {
+		if inst == nil {
+			continue
+		}
+		r := resolved{inst: inst}
+		if s := c.sessions[inst.ID]; s != nil && now.Sub(s.dirResolvedAt) < gitWorkDirTTL {
+			r.dir, r.cached = s.dir, true
+		}
+		work = append(work, r)
+	}
+	c.mu.Unlock()
+	for i := range work {
+		if !work[i].cached {
+			work[i].dir = work[i].inst.GetActualWorkDir()
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seen := make(map[string]bool, len(work))
+	used := make(map[string]bool, len(work))
+	for _, r := range work {
+		seen[r.inst.ID] = true
+		s := c.sessions[r.inst.ID]
+		if s == nil {
+			s = &gitSessionState{}
+			c.sessions[r.inst.ID] = s
+		}
+		if !r.cached {
+			s.dirResolvedAt = now
+		}
+		s.inst = r.inst
+		s.dir = r.dir
+		if r.dir == "" {
+			r.inst.SetGitStatus(nil)
+			continue
+		}
+		used[r.dir] = true
+
+		repo := c.repos[r.dir]
+		if repo == nil {
+			repo = &gitRepoState{}
+			c.repos[r.dir] = repo
+		}
+		r.inst.SetGitStatus(repo.status)
+		if now.Sub(repo.checkedAt) >= gitStatusTTL {
+			c.enqueueLocked(r.dir, repo)
+		}
+	}
+
+	for id := range c.sessions {
+		if !seen[id] {
+			delete(c.sessions, id)
+		}
+	}
+	for dir, repo := range c.repos {
+		if !used[dir] {
+			c.unwatchLocked(dir, repo)
+			delete(c.repos, dir)
+		}
+	}
+}
+
+// NoteHookStatus refreshes a session's status when its hook reports a new
+// Stop event, i.e. the agent just finished a turn and likely changed files.
+func (c *GitStatusCollector) NoteHookStatus(instanceID string, hs *HookStatus) {
+	if hs == nil || hs.Event != "Stop" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.sessions[instanceID]
+	if s == nil || !hs.UpdatedAt.After(s.lastStop) {
+		return
+	}
+	s.lastStop = hs.UpdatedAt
+	if repo := c.repos[s.dir]; repo != nil {
+		c.enqueueLocked(s.dir, repo)
+	}
+}
+
+// Refresh queues an immediate refresh of a session's directory.
+func (c *GitStatusCollector) Refresh(instanceID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s := c.sessions[instanceID]; s != nil {
+		if repo := c.repos[s.dir]; repo != nil {
+			c.enqueueLocked(s.dir, repo)
+		}
+	}
+}
+
+// StatusFor returns the cached status of a session's directory, or nil when
+// it isn't a git work tree or hasn't been collected yet.
+func (c *GitStatusCollector) StatusFor(instanceID string) *git.Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s := c.sessions[instanceID]; s != nil {
+		if repo := c.repos[s.dir]; repo != nil {
+			return repo.status
+		}
+	}
+	return nil
+}
+
+func (c *GitStatusCollector) enqueueLocked(dir string, repo *gitRepoState) {
+	if repo.queued {
+		return
+	}
+	select {
+	case c.queue <- dir:
+		repo.queued = true
+	default:
+		// Queue full: the next Track picks it up again
+	}
+}
+
+func (c *GitStatusCollector) refreshLoop() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case dir := <-c.queue:
+			c.refresh(dir)
+		}
+	}
+}
+
+// refresh collects dir's status, stores it and pushes it to every session
+// in that directory.
+func (c *GitStatusCollector) refresh(dir string) {
+	status, err := c.collect(dir)
+	if err != nil {
+		// Not a repository (or git missing): remember that until the TTL
+		// expires rather than retrying on every event.
+		status = nil
+	}
+
+	c.mu.Lock()
+	repo := c.repos[dir]
+	if repo == nil {
+		c.mu.Unlock()
+		return
+	}
+	changed := !gitStatusEqual(repo.status, status)
+	repo.status = status
+	repo.checkedAt = c.now()
+	repo.queued = false
+	if status != nil && repo.watches == nil {
+		c.watchLocked(dir, repo)
+	}
+	for _, s := range c.sessions {
+		if s.dir == dir && s.inst != nil {
+			s.inst.SetGitStatus(status)
+		}
+	}
+	onChange := c.onChange
+	c.mu.Unlock()
+
+	if changed && onChange != nil {
+		onChange()
+	}
+}
+
+// watchLocked watches dir's top level and its git dir.
+func (c *GitStatusCollector) watchLocked(dir string, repo *gitRepoState) {
+	repo.watches = []string{}
+	if c.watcher == nil {
+		return
+	}
+	dirs := []string{dir}
+	if gitDir, err := git.GetGitDir(dir); err == nil {
+		dirs = append(dirs, gitDir)
+	}
+	for _, d := range dirs {
+		if len(c.watched[d]) == 0 {
+			if err := c.watcher.Add(d); err != nil {
+				gitStatusLog.Debug("git_status_watch_failed", slog.String("dir", d), slog.String("error", err.Error()))
+				continue
+			}
+		}
+		c.watched[d] = append(c.watched[d], dir)
+		repo.watches = append(repo.watches, d)
+	}
+}
+
+func (c *GitStatusCollector) unwatchLocked(dir string, repo *gitRepoState) {
+	for _, d := range repo.watches {
+		owners := c.watched[d][:0]
+		for _, o := range c.watched[d] {
+			if o != dir {
+				owners = append(owners, o)
+			}
+		}
+		if len(owners) == 0 {
+			delete(c.watched, d)
+			if c.watcher != nil {
+				_ = c.watcher.Remove(d)
+			}
+			continue
+		}
+		c.watched[d] = owners
+	}
+	repo.watches = nil
+}
+
+func (c *GitStatusCollector) watchLoop() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case event, ok := <-c.watcher.Events:
+			if !ok {
+				return
+			}
+			c.handleEvent(event.Name)
+		case err, ok := <-c.watcher.Errors:
+			if !ok {
+				return
+			}
+			gitStatusLog.Warn("git_status_watcher_error", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// handleEvent maps a changed path to the work dirs it affects and schedules
+// a debounced refresh.
+func (c *GitStatusCollector) handleEvent(path string) {
+	parent, name := filepath.Dir(path), filepath.Base(path)
+	if strings.HasSuffix(name, ".lock") {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	owners := c.watched[parent]
+	if len(owners) == 0 {
+		return
+	}
+	for _, dir := range owners {
+		if dir == parent {
+			// Work tree top level: anything but the .git entry itself
+			if name == ".git" {
+				continue
+			}
+		} else if !gitMetaFiles[name] {
+			continue
+		}
+		c.pending[dir] = true
+	}
+	if len(c.pending) == 0 {
+		return
+	}
+	if c.debounce != nil {
+		c.debounce.Stop()
+	}
+	c.debounce = time.AfterFunc(gitStatusDebounce, c.flushPending)
+}
+
+func (c *GitStatusCollector) flushPending() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for dir := range c.pending {
+		if repo := c.repos[dir]; repo != nil {
+			c.enqueueLocked(dir, repo)
+		}
+	}
+	c.pending = make(map[string]bool)
+}
+
+// gitStatusEqual compares two statuses, ignoring when they were collected.
+func gitStatusEqual(a, b *git.Status) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ac, bc := *a, *b
+	ac.UpdatedAt, bc.UpdatedAt = time.Time{}, time.Time{}
+	return reflect.DeepEqual(ac, bc)
+}
+
+// GetGitStatus returns the last collected git status of the session's
+// working directory, or nil when unknown or not a git work tree.
+func (i *Instance) GetGitStatus() *git.Status {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.gitStatus
+}
+
+// SetGitStatus stores the session's git status (called by GitStatusCollector).
+func (i *Instance) SetGitStatus(status *git.Status) {
+	i.mu.Lock()
+	i.gitStatus = status
+	i.mu.Unlock()
+}
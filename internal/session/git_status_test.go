@@ -0,0 +1,133 @@
+package session
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+)
+
+// newTestGitCollector returns an unstarted collector whose statuses come
+// from collect; tests drive refreshes with drainGitQueue.
+func newTestGitCollector(t *testing.T, collect func(dir string) (*git.Status, error)) (*GitStatusCollector, *atomic.Int32) {
+	t.Helper()
+	var changes atomic.Int32
+	c := NewGitStatusCollector(func() { changes.Add(1) })
+	c.collect = collect
+	t.Cleanup(c.Stop)
+	return c, &changes
+}
+
+// drainGitQueue runs every queued refresh and returns the directories.
+func drainGitQueue(c *GitStatusCollector) []string {
+	var dirs []string
+	for {
+		select {
+		case dir := <-c.queue:
+			c.refresh(dir)
+			dirs = append(dirs, dir)
+		default:
+			return dirs
+		}
+	}
+}
+
+func TestGitStatusCollector_TrackAndRefresh(t *testing.T) {
+	dir := t.TempDir()
+	calls := 0
+	c, changes := newTestGitCollector(t, func(d string) (*git.Status, error) {
+		calls++
+		return &git.Status{Branch: "main", Unstaged: calls}, nil
+	})
+
+	a := &Instance{ID: "a", ProjectPath: dir}
+	b := &Instance{ID: "b", ProjectPath: dir}
+	c.Track([]*Instance{a, b})
+	assert.Nil(t, a.GetGitStatus(), "nothing collected yet")
+
+	assert.Equal(t, []string{dir}, drainGitQueue(c), "sessions sharing a directory share one refresh")
+	require.NotNil(t, a.GetGitStatus())
+	assert.Same(t, a.GetGitStatus(), b.GetGitStatus())
+	assert.Equal(t, int32(1), changes.Load())
+
+	// Fresh statuses aren't re-queued; Refresh forces it
+	c.Track([]*Instance{a, b})
+	assert.Empty(t, drainGitQueue(c))
+	c.Refresh("a")
+	drainGitQueue(c)
+	assert.Equal(t, 2, c.StatusFor("b").Unstaged)
+	assert.Equal(t, int32(2), changes.Load())
+
+	// Stale statuses are re-queued on Track
+	c.now = func() time.Time { return time.Now().Add(gitStatusTTL + time.Second) }
+	c.Track([]*Instance{a})
+	assert.Equal(t, []string{dir}, drainGitQueue(c))
+
+	// Sessions that disappear are forgotten
+	c.Track(nil)
+	assert.Nil(t, c.StatusFor("a"))
+	assert.Empty(t, c.repos)
+}
+
+func TestGitStatusCollector_NotARepo(t *testing.T) {
+	c, changes := newTestGitCollector(t, func(string) (*git.Status, error) {
+		return nil, assert.AnError
+	})
+	inst := &Instance{ID: "a", ProjectPath: t.TempDir()}
+	c.Track([]*Instance{inst})
+	drainGitQueue(c)
+	assert.Nil(t, inst.GetGitStatus())
+	assert.Zero(t, changes.Load())
+}
+
+func TestGitStatusCollector_HookStop(t *testing.T) {
+	dir := t.TempDir()
+	c, _ := newTestGitCollector(t, func(string) (*git.Status, error) { return &git.Status{}, nil })
+	c.Track([]*Instance{{ID: "a", ProjectPath: dir}})
+	drainGitQueue(c)
+
+	stop := &HookStatus{Event: "Stop", UpdatedAt: time.Now()}
+	c.NoteHookStatus("a", &HookStatus{Event: "UserPromptSubmit", UpdatedAt: time.Now()})
+	assert.Empty(t, drainGitQueue(c), "only Stop triggers a refresh")
+
+	c.NoteHookStatus("a", stop)
+	assert.Equal(t, []string{dir}, drainGitQueue(c))
+
+	c.NoteHookStatus("a", stop)
+	assert.Empty(t, drainGitQueue(c), "the same Stop event is handled once")
+}
+
+func TestGitStatusCollector_FileEvents(t *testing.T) {
+	dir := t.TempDir()
+	c, _ := newTestGitCollector(t, func(string) (*git.Status, error) { return &git.Status{}, nil })
+	if c.watcher == nil {
+		t.Skip("fsnotify unavailable")
+	}
+	c.Track([]*Instance{{ID: "a", ProjectPath: dir}})
+	drainGitQueue(c)
+	require.Contains(t, c.watched, dir)
+
+	c.handleEvent(filepath.Join(dir, ".git"))
+	c.handleEvent(filepath.Join(dir, "main.go.lock"))
+	assert.Empty(t, c.pending)
+
+	c.handleEvent(filepath.Join(dir, "main.go"))
+	assert.True(t, c.pending[dir])
+	c.flushPending()
+	assert.Equal(t, []string{dir}, drainGitQueue(c))
+}
+
+func TestGitStatusEqual(t *testing.T) {
+	a := &git.Status{Branch: "main", Files: []git.FileChange{{Path: "x", Code: " M"}}, UpdatedAt: time.Now()}
+	b := &git.Status{Branch: "main", Files: []git.FileChange{{Path: "x", Code: " M"}}, UpdatedAt: time.Now().Add(time.Minute)}
+	assert.True(t, gitStatusEqual(a, b), "collection time is ignored")
+	b.Files[0].Added = 3
+	assert.False(t, gitStatusEqual(a, b))
+	assert.False(t, gitStatusEqual(a, nil))
+	assert.True(t, gitStatusEqual(nil, nil))
+}
@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/asheshgoplani/agent-deck/internal/logging"
+	"github.com/asheshgoplani/agent-deck/internal/searchdb"
 	"github.com/fsnotify/fsnotify"
 	"github.com/sahilm/fuzzy"
 	"golang.org/x/time/rate"
@@ -28,8 +29,9 @@ var searchLog = logging.ForComponent(logging.CompSession)
 type SearchTier int
 
 const (
-	TierInstant  SearchTier = iota // < 100MB, full in-memory
-	TierBalanced                   // 100MB-500MB, on-demand scan to cap memory
+	TierInstant    SearchTier = iota // < 100MB, full in-memory
+	TierBalanced                     // 100MB-500MB, on-demand scan to cap memory
+	TierPersistent                   // > 500MB, SQLite FTS5 index in ~/.agent-deck/search.db
 )
 
 // TierThresholdInstant is the max size for instant tier (100MB)
@@ -38,10 +40,11 @@ const TierThresholdInstant = 100 * 1024 * 1024
 // TierThresholdBalanced is the max size for balanced tier (500MB)
 const TierThresholdBalanced = 500 * 1024 * 1024
 
-// SearchEntry represents a searchable Claude session
+// SearchEntry represents a searchable conversation session
 type SearchEntry struct {
-	SessionID string    // Claude session UUID
-	FilePath  string    // Path to .jsonl file
+	Tool      string    // Tool that wrote the transcript ("claude", "codex", "gemini", "opencode")
+	SessionID string    // Tool session ID
+	FilePath  string    // Path to the transcript file
 	CWD       string    // Project working directory
 	Summary   string    // First user message or summary
 	ModTime   time.Time // File modification time
@@ -441,7 +444,10 @@ func DetectTier(totalSize int64) SearchTier {
 	if totalSize < TierThresholdInstant {
 		return TierInstant
 	}
-	return TierBalanced
+	if totalSize < TierThresholdBalanced {
+		return TierBalanced
+	}
+	return TierPersistent
 }
 
 // TierName returns a human-readable name for the tier
@@ -451,6 +457,8 @@ func TierName(tier SearchTier) string {
 		return "instant"
 	case TierBalanced:
 		return "balanced"
+	case TierPersistent:
+		return "persistent"
 	default:
 		return "unknown"
 	}
@@ -462,6 +470,7 @@ type SearchResult struct {
 	Matches []MatchRange
 	Score   int
 	Snippet string
+	Hits    int // Matching messages (persistent tier only; 0 = unknown)
 }
 
 // GlobalSearchIndex manages the searchable session index
@@ -469,6 +478,7 @@ type GlobalSearchIndex struct {
 	// Configuration
 	config    GlobalSearchSettings
 	claudeDir string
+	sources   []TranscriptSource
 
 	// Index data (protected by atomic pointer for lock-free reads)
 	entries atomic.Pointer[[]SearchEntry]
@@ -486,6 +496,9 @@ type GlobalSearchIndex struct {
 	// Tier
 	tier SearchTier
 
+	// Persistent FTS5 index (TierPersistent only)
+	store *searchdb.SearchDB
+
 	// Loading state
 	loading atomic.Bool
 
@@ -537,6 +550,7 @@ func NewGlobalSearchIndex(claudeDir string, config GlobalSearchSettings) (*Globa
 	idx := &GlobalSearchIndex{
 		config:           config,
 		claudeDir:        claudeDir,
+		sources:          NewTranscriptSources(claudeDir, config.Tools),
 		fileTrackers:     make(map[string]*FileTracker),
 		limiter:          rate.NewLimiter(rate.Limit(config.IndexRateLimit), 5),
 		memoryLimitBytes: memLimitBytes,
@@ -549,14 +563,10 @@ func NewGlobalSearchIndex(claudeDir string, config GlobalSearchSettings) (*Globa
 	idx.entries.Store(&emptyEntries)
 
 	// Measure data size and determine tier
-	projectsDir := filepath.Join(claudeDir, "projects")
-	totalSize, err := measureDataSize(projectsDir, config.RecentDays)
+	totalSize, err := measureDataSize(idx.sources, config.RecentDays)
 	if err != nil {
-		// Don't fail if projects dir doesn't exist, just use empty index
-		if !os.IsNotExist(err) {
-			cancel()
-			return nil, err
-		}
+		cancel()
+		return nil, err
 	}
 
 	// Determine tier (respect config override)
@@ -565,6 +575,8 @@ func NewGlobalSearchIndex(claudeDir string, config GlobalSearchSettings) (*Globa
 		idx.tier = TierInstant
 	case "balanced":
 		idx.tier = TierBalanced
+	case "persistent":
+		idx.tier = TierPersistent
 	case "disabled":
 		cancel()
 		return nil, nil
@@ -572,29 +584,41 @@ func NewGlobalSearchIndex(claudeDir string, config GlobalSearchSettings) (*Globa
 		idx.tier = DetectTier(totalSize)
 	}
 
+	if idx.tier == TierPersistent {
+		store, err := OpenSearchStore()
+		if err != nil {
+			// Fall back to on-disk scanning rather than disabling search
+			searchLog.Warn("search_store_open_failed", slog.String("error", err.Error()))
+			idx.tier = TierBalanced
+		} else {
+			idx.store = store
+		}
+	}
+
 	// Start file watcher
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		cancel()
+		if idx.store != nil {
+			_ = idx.store.Close()
+		}
 		return nil, err
 	}
 	idx.watcher = watcher
 
-	// Watch the projects directory (create if doesn't exist check)
-	if _, err := os.Stat(projectsDir); err == nil {
-		if err := watcher.Add(projectsDir); err != nil {
-			searchLog.Warn("global_search_watch_failed", slog.String("error", err.Error()))
+	// Watch only the directories that hold transcripts (see WatchDirs).
+	// Previously watched ALL Claude subdirectories (884 dirs including tool-results/,
+	// subagents/, etc.) which leaked ~7000 kqueue file descriptors and caused
+	// agent-deck to balloon to 6+ GB RSS until macOS killed it.
+	for _, src := range idx.sources {
+		if _, err := os.Stat(src.Root()); err != nil {
+			continue
 		}
-
-		// Only watch project-level directories (depth 1 under projects/).
-		// Previously watched ALL subdirectories (884 dirs including tool-results/,
-		// subagents/, etc.) which leaked ~7000 kqueue file descriptors and caused
-		// agent-deck to balloon to 6+ GB RSS until macOS killed it.
-		// JSONL files only exist at the project level, not in subdirectories.
-		dirEntries, _ := os.ReadDir(projectsDir)
-		for _, de := range dirEntries {
-			if de.IsDir() {
-				_ = watcher.Add(filepath.Join(projectsDir, de.Name()))
+		for _, dir := range src.WatchDirs(idx.recentCutoff()) {
+			if err := watcher.Add(dir); err != nil {
+				searchLog.Warn("global_search_watch_failed",
+					slog.String("tool", src.Tool()),
+					slog.String("error", err.Error()))
 			}
 		}
 	}
@@ -610,133 +634,103 @@ func NewGlobalSearchIndex(claudeDir string, config GlobalSearchSettings) (*Globa
 	return idx, nil
 }
 
-// measureDataSize calculates total size of JSONL files
-func measureDataSize(projectsDir string, recentDays int) (int64, error) {
+// measureDataSize calculates total size of transcript files
+func measureDataSize(sources []TranscriptSource, recentDays int) (int64, error) {
 	var totalSize int64
 	cutoff := time.Time{}
 	if recentDays > 0 {
 		cutoff = time.Now().AddDate(0, 0, -recentDays)
 	}
 
-	err := filepath.WalkDir(projectsDir, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-		if d.IsDir() {
-			name := d.Name()
-			if name == "tool-results" || name == "subagents" {
-				return filepath.SkipDir
+	for _, src := range sources {
+		err := walkTranscripts(src, func(path string, d os.DirEntry) error {
+			info, err := d.Info()
+			if err != nil {
+				return nil
 			}
+			if !cutoff.IsZero() && info.ModTime().Before(cutoff) {
+				return nil
+			}
+			totalSize += info.Size()
 			return nil
+		})
+		// Don't fail if a tool's directory doesn't exist, just skip it
+		if err != nil && !os.IsNotExist(err) {
+			return totalSize, err
 		}
-		if !strings.HasSuffix(path, ".jsonl") {
-			return nil
-		}
-		info, err := d.Info()
-		if err != nil {
-			return nil
-		}
-		if !cutoff.IsZero() && info.ModTime().Before(cutoff) {
-			return nil
-		}
-		totalSize += info.Size()
-		return nil
-	})
+	}
+	return totalSize, nil
+}
 
-	return totalSize, err
+// recentCutoff returns the oldest modification time that is still indexed.
+func (idx *GlobalSearchIndex) recentCutoff() time.Time {
+	if idx.config.RecentDays > 0 {
+		return time.Now().AddDate(0, 0, -idx.config.RecentDays)
+	}
+	return time.Time{}
 }
 
 // initialLoad loads all session files on startup
 func (idx *GlobalSearchIndex) initialLoad() {
 	defer idx.wg.Done()
 
-	projectsDir := filepath.Join(idx.claudeDir, "projects")
-	cutoff := time.Time{}
-	if idx.config.RecentDays > 0 {
-		cutoff = time.Now().AddDate(0, 0, -idx.config.RecentDays)
+	if idx.tier == TierPersistent {
+		idx.persistentLoad()
+		return
 	}
 
+	cutoff := idx.recentCutoff()
+
 	var entries []SearchEntry
 	includeContent := idx.tier == TierInstant
 
-	_ = filepath.WalkDir(projectsDir, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-		if d.IsDir() {
-			// Skip subdirectories that never contain JSONL files.
-			// This avoids traversing thousands of tool-results/ and subagents/ dirs.
-			name := d.Name()
-			if name == "tool-results" || name == "subagents" {
-				return filepath.SkipDir
+	for _, src := range idx.sources {
+		_ = walkTranscripts(src, func(path string, d os.DirEntry) error {
+			// Check cancellation
+			select {
+			case <-idx.ctx.Done():
+				return filepath.SkipAll
+			default:
 			}
-			return nil
-		}
-		if !strings.HasSuffix(path, ".jsonl") {
-			return nil
-		}
-
-		// Check cancellation
-		select {
-		case <-idx.ctx.Done():
-			return filepath.SkipAll
-		default:
-		}
-
-		info, err := d.Info()
-		if err != nil {
-			return nil
-		}
 
-		// Check recency
-		if !cutoff.IsZero() && info.ModTime().Before(cutoff) {
-			return nil
-		}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
 
-		// Only UUID-named files (skip agent-*.jsonl)
-		baseName := filepath.Base(path)
-		if !isUUIDFileName(baseName) {
-			return nil
-		}
+			// Check recency
+			if !cutoff.IsZero() && info.ModTime().Before(cutoff) {
+				return nil
+			}
 
-		// Parse file: for metadata-only mode, read just the head (first 32KB)
-		var entry *SearchEntry
-		if !includeContent {
-			entry, err = parseClaudeJSONLHead(path)
-		} else {
-			var data []byte
-			data, err = os.ReadFile(path)
-			if err != nil {
+			entry, err := loadSearchEntry(src, path, includeContent)
+			if err != nil || entry == nil || entry.SessionID == "" {
 				return nil
 			}
-			entry, err = parseClaudeJSONL(path, data, true)
-		}
-		if err != nil || entry == nil || entry.SessionID == "" {
-			return nil
-		}
 
-		entry.ModTime = info.ModTime()
-		entry.FileSize = info.Size()
+			entry.ModTime = info.ModTime()
+			entry.FileSize = info.Size()
 
-		// Track content memory usage
-		if entry.hasContent() {
-			idx.currentMemoryBytes.Add(entry.content.Size())
-		}
+			// Track content memory usage
+			if entry.hasContent() {
+				idx.currentMemoryBytes.Add(entry.content.Size())
+			}
 
-		entries = append(entries, *entry)
+			entries = append(entries, *entry)
 
-		// Track file for incremental updates
-		idx.trackerMu.Lock()
-		idx.fileTrackers[path] = &FileTracker{
-			Path:       path,
-			LastOffset: info.Size(),
-			LastSize:   info.Size(),
-			LastMod:    info.ModTime(),
-		}
-		idx.trackerMu.Unlock()
+			// Track file for incremental updates
+			idx.trackerMu.Lock()
+			idx.fileTrackers[path] = &FileTracker{
+				Path:       path,
+				LastOffset: info.Size(),
+				LastSize:   info.Size(),
+				LastMod:    info.ModTime(),
+			}
+			idx.trackerMu.Unlock()
 
-		return nil
-	})
+			return nil
+		})
+	}
 
 	// Store entries and mark loading complete
 	idx.entries.Store(&entries)
@@ -748,6 +742,46 @@ func (idx *GlobalSearchIndex) initialLoad() {
 	}
 }
 
+// loadSearchEntry parses a transcript for the in-memory tiers. Claude files
+// use the streaming parsers (head-only in metadata mode); other tools are
+// parsed whole and content is dropped when includeContent is false.
+func loadSearchEntry(src TranscriptSource, path string, includeContent bool) (*SearchEntry, error) {
+	if src.Tool() == TranscriptToolClaude {
+		var entry *SearchEntry
+		var err error
+		if !includeContent {
+			entry, err = parseClaudeJSONLHead(path)
+		} else {
+			var data []byte
+			data, err = os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			entry, err = parseClaudeJSONL(path, data, true)
+		}
+		if entry != nil {
+			entry.Tool = TranscriptToolClaude
+		}
+		return entry, err
+	}
+
+	t, err := src.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	entry := &SearchEntry{
+		Tool:      t.Tool,
+		SessionID: t.SessionID,
+		FilePath:  path,
+		CWD:       t.CWD,
+		Summary:   t.Summary,
+	}
+	if includeContent && len(t.Messages) > 0 {
+		entry.setContent([]byte(t.Content()))
+	}
+	return entry, nil
+}
+
 // isUUIDFileName checks if filename matches UUID pattern
 var uuidFilePattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\.jsonl$`)
 
@@ -778,11 +812,19 @@ func (idx *GlobalSearchIndex) watcherLoop() {
 				return
 			}
 
-			// Only care about writes and creates for .jsonl files
-			if !strings.HasSuffix(event.Name, ".jsonl") {
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
 				continue
 			}
-			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+			// New transcript directories (a new Claude project, a new Codex
+			// day, ...) must be watched to see the files created in them
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					idx.watchNewDir(event.Name)
+					continue
+				}
+			}
+			// Only care about transcript files
+			if transcriptSourceFor(idx.sources, event.Name) == nil {
 				continue
 			}
 
@@ -808,9 +850,35 @@ func (idx *GlobalSearchIndex) watcherLoop() {
 	}
 }
 
+// watchNewDir starts watching a directory created under a source root if
+// transcripts can live at its depth.
+func (idx *GlobalSearchIndex) watchNewDir(dir string) {
+	for _, src := range idx.sources {
+		if depth := pathDepth(src.Root(), dir); depth > 0 && depth <= src.Depth() {
+			if err := idx.watcher.Add(dir); err != nil {
+				searchLog.Warn("global_search_watch_failed",
+					slog.String("tool", src.Tool()),
+					slog.String("error", err.Error()))
+			}
+			return
+		}
+	}
+}
+
 // updateFile handles incremental update for a single file
 func (idx *GlobalSearchIndex) updateFile(path string) {
-	if !isUUIDFileName(filepath.Base(path)) {
+	src := transcriptSourceFor(idx.sources, path)
+	if src == nil {
+		return
+	}
+
+	if idx.tier == TierPersistent {
+		idx.updateFilePersistent(src, path)
+		return
+	}
+
+	if src.Tool() != TranscriptToolClaude {
+		idx.replaceTranscriptEntry(src, path)
 		return
 	}
 
@@ -887,6 +955,7 @@ func (idx *GlobalSearchIndex) updateFile(path string) {
 	if err != nil || entry.SessionID == "" {
 		return
 	}
+	entry.Tool = TranscriptToolClaude
 	entry.ModTime = info.ModTime()
 	entry.FileSize = info.Size()
 
@@ -943,6 +1012,52 @@ func (idx *GlobalSearchIndex) updateFile(path string) {
 	idx.trackerMu.Unlock()
 }
 
+// replaceTranscriptEntry re-parses a whole non-Claude transcript and swaps its
+// entry. These transcripts are either rewritten in place (Gemini, OpenCode)
+// or small enough per session (Codex) that incremental reads aren't worth it.
+func (idx *GlobalSearchIndex) replaceTranscriptEntry(src TranscriptSource, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	entry, err := loadSearchEntry(src, path, idx.tier == TierInstant)
+	if err != nil || entry == nil || entry.SessionID == "" {
+		return
+	}
+	entry.ModTime = info.ModTime()
+	entry.FileSize = info.Size()
+
+	oldEntries := idx.entries.Load()
+	newEntries := make([]SearchEntry, 0, len(*oldEntries)+1)
+	for _, e := range *oldEntries {
+		if e.FilePath == path {
+			if e.hasContent() {
+				idx.currentMemoryBytes.Add(-e.content.Size())
+			}
+			continue
+		}
+		newEntries = append(newEntries, e)
+	}
+	if entry.hasContent() {
+		idx.currentMemoryBytes.Add(entry.content.Size())
+	}
+	newEntries = append(newEntries, *entry)
+	idx.entries.Store(&newEntries)
+
+	if idx.currentMemoryBytes.Load() > idx.memoryLimitBytes {
+		idx.evictOldestEntries()
+	}
+
+	idx.trackerMu.Lock()
+	idx.fileTrackers[path] = &FileTracker{
+		Path:       path,
+		LastOffset: info.Size(),
+		LastSize:   info.Size(),
+		LastMod:    info.ModTime(),
+	}
+	idx.trackerMu.Unlock()
+}
+
 // evictOldestEntries frees memory by nil-ing content on the oldest 25% of entries.
 // Evicted entries retain metadata (Summary, CWD, SessionID) so they still appear
 // in results; search just falls back to on-disk scanning for them.
@@ -1006,8 +1121,11 @@ func (idx *GlobalSearchIndex) Search(query string) []*SearchResult {
 		return nil
 	}
 
-	if idx.tier == TierBalanced {
+	switch idx.tier {
+	case TierBalanced:
 		return idx.searchOnDisk(query)
+	case TierPersistent:
+		return idx.searchPersistent(query)
 	}
 
 	entries := idx.entries.Load()
@@ -1135,6 +1253,11 @@ func (idx *GlobalSearchIndex) Close() {
 
 	// Clear query cache
 	idx.resetQueryCache()
+
+	if idx.store != nil {
+		_ = idx.store.Close()
+		idx.store = nil
+	}
 }
 
 func matchRanges(lower []byte, queryLower []byte) []MatchRange {
@@ -1235,7 +1358,7 @@ func (idx *GlobalSearchIndex) searchOnDisk(query string) []*SearchResult {
 		go func() {
 			defer wg.Done()
 			for entry := range jobs {
-				matchCount, snippet := scanFileForQuery(entry.FilePath, queryLower, 60)
+				matchCount, snippet := idx.scanEntryForQuery(entry, queryLower, 60)
 				if matchCount > 0 {
 					hits <- searchHit{entry: entry, count: matchCount, snippet: snippet}
 				}
@@ -1306,6 +1429,35 @@ func (idx *GlobalSearchIndex) resetQueryCache() {
 	idx.lastQueryMu.Unlock()
 }
 
+// scanEntryForQuery counts query matches in an entry's transcript on disk.
+func (idx *GlobalSearchIndex) scanEntryForQuery(entry *SearchEntry, queryLower string, windowSize int) (int, string) {
+	if entry.Tool == "" || entry.Tool == TranscriptToolClaude {
+		return scanFileForQuery(entry.FilePath, queryLower, windowSize)
+	}
+	src := transcriptSourceFor(idx.sources, entry.FilePath)
+	if src == nil {
+		return 0, ""
+	}
+	t, err := src.Parse(entry.FilePath)
+	if err != nil {
+		return 0, ""
+	}
+	matchCount := 0
+	snippet := ""
+	for _, m := range t.Messages {
+		content := formatTranscriptMessage(m)
+		contentLower := strings.ToLower(content)
+		if !strings.Contains(contentLower, queryLower) {
+			continue
+		}
+		matchCount += strings.Count(contentLower, queryLower)
+		if snippet == "" {
+			snippet = snippetFromText(content, queryLower, windowSize)
+		}
+	}
+	return matchCount, snippet
+}
+
 func scanFileForQuery(path string, queryLower string, windowSize int) (int, string) {
 	file, err := os.Open(path)
 	if err != nil {
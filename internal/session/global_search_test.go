@@ -127,10 +127,11 @@ func TestDetectTier(t *testing.T) {
 		size     int64
 		expected SearchTier
 	}{
-		{50 * 1024 * 1024, TierInstant},   // 50MB -> instant
-		{99 * 1024 * 1024, TierInstant},   // 99MB -> instant
-		{100 * 1024 * 1024, TierBalanced}, // 100MB -> balanced
-		{200 * 1024 * 1024, TierBalanced}, // 200MB -> balanced
+		{50 * 1024 * 1024, TierInstant},     // 50MB -> instant
+		{99 * 1024 * 1024, TierInstant},     // 99MB -> instant
+		{100 * 1024 * 1024, TierBalanced},   // 100MB -> balanced
+		{200 * 1024 * 1024, TierBalanced},   // 200MB -> balanced
+		{500 * 1024 * 1024, TierPersistent}, // 500MB -> persistent
 	}
 
 	for _, tc := range tests {
@@ -148,6 +149,9 @@ func TestTierName(t *testing.T) {
 	if TierName(TierBalanced) != "balanced" {
 		t.Errorf("TierName(TierBalanced) = %q, want 'balanced'", TierName(TierBalanced))
 	}
+	if TierName(TierPersistent) != "persistent" {
+		t.Errorf("TierName(TierPersistent) = %q, want 'persistent'", TierName(TierPersistent))
+	}
 }
 
 func TestGlobalSearchIndexInstantTier(t *testing.T) {
@@ -181,6 +185,7 @@ func TestGlobalSearchIndexInstantTier(t *testing.T) {
 		MemoryLimitMB:  100,
 		RecentDays:     0, // All sessions
 		IndexRateLimit: 100,
+		Tools:          []string{"claude"},
 	}
 
 	index, err := NewGlobalSearchIndex(tmpDir, config)
@@ -221,7 +226,7 @@ func TestGlobalSearchIndexFuzzyMatch(t *testing.T) {
 	jsonl := `{"sessionId":"c3d4e5f6-a7b8-9012-cdef-345678901234","type":"user","message":{"role":"user","content":"authentication system implementation"}}`
 	_ = os.WriteFile(filepath.Join(projectDir, "c3d4e5f6-a7b8-9012-cdef-345678901234.jsonl"), []byte(jsonl), 0644)
 
-	config := GlobalSearchSettings{Enabled: true, Tier: "auto", MemoryLimitMB: 100, IndexRateLimit: 100}
+	config := GlobalSearchSettings{Enabled: true, Tier: "auto", MemoryLimitMB: 100, IndexRateLimit: 100, Tools: []string{"claude"}}
 	index, err := NewGlobalSearchIndex(tmpDir, config)
 	if err != nil {
 		t.Fatalf("Failed to create index: %v", err)
@@ -252,7 +257,7 @@ func TestGlobalSearchIndexEmptyQuery(t *testing.T) {
 	projectDir := filepath.Join(tmpDir, "projects", "-Users-test-project")
 	_ = os.MkdirAll(projectDir, 0755)
 
-	config := GlobalSearchSettings{Enabled: true, Tier: "auto", MemoryLimitMB: 100, IndexRateLimit: 100}
+	config := GlobalSearchSettings{Enabled: true, Tier: "auto", MemoryLimitMB: 100, IndexRateLimit: 100, Tools: []string{"claude"}}
 	index, _ := NewGlobalSearchIndex(tmpDir, config)
 	if index == nil {
 		t.Fatal("Index should not be nil")
@@ -284,6 +289,7 @@ func TestGlobalSearchIndexBalancedTier(t *testing.T) {
 		MemoryLimitMB:  1, // Very low limit
 		RecentDays:     0,
 		IndexRateLimit: 100,
+		Tools:          []string{"claude"},
 	}
 
 	index, err := NewGlobalSearchIndex(tmpDir, config)
@@ -321,6 +327,7 @@ func TestGlobalSearchIndexTierAutoDetect(t *testing.T) {
 		Tier:           "auto",
 		MemoryLimitMB:  100,
 		IndexRateLimit: 100,
+		Tools:          []string{"claude"},
 	}
 
 	index, _ := NewGlobalSearchIndex(tmpDir, config)
@@ -0,0 +1,62 @@
+package session
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+)
+
+// History event kinds. Context actions are recorded as HistoryKindContext + action
+// (e.g. "context.compact", "context.fork").
+const (
+	HistoryKindContext = "context."
+)
+
+// HistoryEntry is a notable event in a session's lifetime.
+type HistoryEntry struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	Message string    `json:"message"`
+}
+
+// RecordHistory appends an event to a session's history in the global state database.
+// Failures are logged, not returned: history is informational.
+func RecordHistory(instanceID, kind, message string) {
+	db := statedb.GetGlobal()
+	if db == nil {
+		return
+	}
+	if err := db.AppendHistory(&statedb.HistoryRow{
+		InstanceID: instanceID,
+		Timestamp:  time.Now(),
+		Kind:       kind,
+		Message:    message,
+	}); err != nil {
+		storageLog.Warn("history_append_failed",
+			slog.String("instance_id", instanceID),
+			slog.String("kind", kind),
+			slog.String("error", err.Error()))
+	}
+}
+
+// LoadHistory returns the most recent history entries for a session, oldest first.
+// limit <= 0 returns all entries.
+func (s *Storage) LoadHistory(instanceID string, limit int) ([]HistoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db == nil {
+		return nil, fmt.Errorf("storage database not initialized")
+	}
+	rows, err := s.db.LoadHistory(instanceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load history: %w", err)
+	}
+	entries := make([]HistoryEntry, len(rows))
+	for i, r := range rows {
+		entries[i] = HistoryEntry{Time: r.Timestamp, Kind: r.Kind, Message: r.Message}
+	}
+	return entries, nil
+}
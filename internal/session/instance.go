@@ -19,6 +19,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/asheshgoplani/agent-deck/internal/git"
 	"github.com/asheshgoplani/agent-deck/internal/logging"
 	"github.com/asheshgoplani/agent-deck/internal/tmux"
 )
@@ -108,6 +109,13 @@ type Instance struct {
 	// JSON structure: {"tool": "claude", "options": {...}}
 	ToolOptionsJSON json.RawMessage `json:"tool_options,omitempty"`
 
+	// ContextPolicy overrides the group/global automatic context-window policy (nil = inherit)
+	ContextPolicy *ContextPolicy `json:"context_policy,omitempty"`
+
+	// Workspace is set for sessions created from a [workspaces] definition
+	// and lists the other repos and directories the session works in
+	Workspace *WorkspaceState `json:"workspace,omitempty"`
+
 	tmuxSession *tmux.Session // Internal tmux session
 
 	// Hook-based status detection (set by StatusFileWatcher from Claude Code hooks)
@@ -115,6 +123,12 @@ type Instance struct {
 	hookSessionID  string    // Session ID from hook payload
 	hookLastUpdate time.Time // When hook status was last received
 
+	// Live git status of the working directory (set by GitStatusCollector)
+	gitStatus *git.Status
+
+	// Ports leased from [ports] (set at start and by Storage on load)
+	ports []int
+
 	// mu protects fields written by backgroundStatusUpdate and read by the TUI goroutine.
 	// Use GetStatus()/SetStatus() and GetTool()/SetTool() for thread-safe access.
 	// UpdateStatus() acquires the write lock internally.
@@ -134,6 +148,10 @@ type Instance struct {
 	// Not serialized - only relevant for current TUI session
 	lastStartTime time.Time
 
+	// waitingEnteredAt is when UpdateStatus last saw the session become
+	// waiting, for the time-in-waiting metric. Not serialized.
+	waitingEnteredAt time.Time
+
 	// SkipMCPRegenerate skips .mcp.json regeneration on next Restart()
 	// Set by MCP dialog Apply() to avoid race condition where Apply writes
 	// config then Restart immediately overwrites it with different pool state
@@ -222,6 +240,15 @@ func (inst *Instance) IsWorktree() bool {
 	return inst.WorktreePath != ""
 }
 
+// ReviewDir returns the directory whose changes a diff review covers: the
+// session's worktree when it has one, otherwise its project directory.
+func (inst *Instance) ReviewDir() string {
+	if inst.WorktreePath != "" {
+		return inst.WorktreePath
+	}
+	return inst.ProjectPath
+}
+
 // SetParent sets the parent session ID
 func (inst *Instance) SetParent(parentID string) {
 	inst.ParentSessionID = parentID
@@ -450,14 +477,15 @@ func (i *Instance) buildClaudeCommandWithMessage(baseCommand, message string) st
 }
 
 // buildClaudeExtraFlags builds extra command-line flags string from ClaudeOptions
-// Also handles instance-level flags like --add-dir for subagent access
+// Also handles instance-level flags like --add-dir for subagents and workspaces
 func (i *Instance) buildClaudeExtraFlags(opts *ClaudeOptions) string {
 	var flags []string
 
 	// Instance-level flags (not from ClaudeOptions)
-	// --add-dir: Grant subagent access to parent's project directory (for worktrees, etc.)
-	if i.ParentProjectPath != "" {
-		flags = append(flags, fmt.Sprintf("--add-dir %s", i.ParentProjectPath))
+	// --add-dir: Grant access to the parent's project directory (subagents)
+	// and to the other repos and paths of a workspace session
+	if addDirs := i.extraDirFlags("--add-dir"); addDirs != "" {
+		flags = append(flags, strings.TrimPrefix(addDirs, " "))
 	}
 
 	// Options-level flags
@@ -525,17 +553,24 @@ func (i *Instance) buildGeminiCommand(baseCommand string) string {
 		}
 	}
 
+	// Extra directories (parent project, workspace repos) go in one
+	// comma-separated --include-directories
+	includeFlag := ""
+	if dirs := i.ExtraDirs(); len(dirs) > 0 {
+		includeFlag = " --include-directories " + shellArg(strings.Join(dirs, ","))
+	}
+
 	// If baseCommand is just "gemini", handle specially
 	if baseCommand == "gemini" {
 		// If we already have a session ID, use simple resume
 		if i.GeminiSessionID != "" {
-			return envPrefix + fmt.Sprintf("tmux set-environment GEMINI_YOLO_MODE %s; tmux set-environment GEMINI_SESSION_ID %s; gemini --resume %s%s%s", yoloEnv, i.GeminiSessionID, i.GeminiSessionID, yoloFlag, modelFlag)
+			return envPrefix + fmt.Sprintf("tmux set-environment GEMINI_YOLO_MODE %s; tmux set-environment GEMINI_SESSION_ID %s; gemini --resume %s%s%s%s", yoloEnv, i.GeminiSessionID, i.GeminiSessionID, yoloFlag, modelFlag, includeFlag)
 		}
 
 		// Start Gemini fresh - session ID will be captured when user interacts
 		// The previous capture-resume approach (gemini --output-format json ".") would hang
 		// because Gemini processes the "." prompt which takes too long
-		return envPrefix + fmt.Sprintf(`tmux set-environment GEMINI_YOLO_MODE %s; gemini%s%s`, yoloEnv, yoloFlag, modelFlag)
+		return envPrefix + fmt.Sprintf(`tmux set-environment GEMINI_YOLO_MODE %s; gemini%s%s%s`, yoloEnv, yoloFlag, modelFlag, includeFlag)
 	}
 
 	// For custom commands (e.g., resume commands), return as-is
@@ -637,7 +672,8 @@ func (i *Instance) buildCodexCommand(baseCommand string) string {
 		i.ID, i.Title, i.Tool)
 	envPrefix += agentdeckEnvPrefix
 
-	yoloFlag := i.resolveCodexYoloFlag()
+	// Global flags go before the resume subcommand
+	yoloFlag := i.resolveCodexYoloFlag() + i.extraDirFlags("--add-dir")
 
 	// If baseCommand is just "codex", handle specially
 	if baseCommand == "codex" {
@@ -1156,6 +1192,7 @@ func (i *Instance) UpdateCodexSession(excludeIDs map[string]bool) {
 //   - output_format_flag: flag to get JSON output (e.g., "--output-format json")
 //   - dangerous_flag: flag to skip confirmations (e.g., "--auto-approve")
 //   - dangerous_mode: whether to enable dangerous flag by default
+//   - add_dir_flag: flag repeated per extra directory (e.g., "--add-dir")
 //   - env_file: .env file to source for this tool
 func (i *Instance) buildGenericCommand(baseCommand string) string {
 	envPrefix := i.buildEnvSourceCommand()
@@ -1164,6 +1201,9 @@ func (i *Instance) buildGenericCommand(baseCommand string) string {
 	if toolDef == nil {
 		return envPrefix + baseCommand // No custom config, return with env prefix
 	}
+	if toolDef.AddDirFlag != "" {
+		baseCommand += i.extraDirFlags(toolDef.AddDirFlag)
+	}
 
 	// Check if tool supports session resume (needs both resume_flag and session_id_env)
 	if toolDef.ResumeFlag == "" || toolDef.SessionIDEnv == "" {
@@ -1241,6 +1281,22 @@ func (i *Instance) GetGenericSessionID() string {
 	return sessionID
 }
 
+// ToolSessionID returns the session ID of the instance's built-in tool
+// (Claude, Gemini, OpenCode or Codex), or "" for other tools.
+func (i *Instance) ToolSessionID() string {
+	switch i.Tool {
+	case "claude":
+		return i.ClaudeSessionID
+	case "gemini":
+		return i.GeminiSessionID
+	case "opencode":
+		return i.OpenCodeSessionID
+	case "codex":
+		return i.CodexSessionID
+	}
+	return ""
+}
+
 // CanRestartGeneric returns true if a custom tool can be restarted with session resume
 func (i *Instance) CanRestartGeneric() bool {
 	toolDef := GetToolDef(i.Tool)
@@ -1323,7 +1379,7 @@ func (i *Instance) Start() error {
 		if toolDef := GetToolDef(i.Tool); toolDef != nil {
 			command = i.buildGenericCommand(i.Command)
 		} else {
-			command = i.Command
+			command = i.rawCommandWithPorts()
 		}
 	}
 
@@ -1384,6 +1440,7 @@ func (i *Instance) Start() error {
 		go i.detectCodexSessionAsync()
 	}
 
+	FireSessionHook(HookSessionStarted, i, nil)
 	return nil
 }
 
@@ -1415,7 +1472,7 @@ func (i *Instance) StartWithMessage(message string) error {
 		if toolDef := GetToolDef(i.Tool); toolDef != nil {
 			command = i.buildGenericCommand(i.Command)
 		} else {
-			command = i.Command
+			command = i.rawCommandWithPorts()
 		}
 	}
 
@@ -1469,6 +1526,8 @@ func (i *Instance) StartWithMessage(message string) error {
 		go i.detectCodexSessionAsync()
 	}
 
+	FireSessionHook(HookSessionStarted, i, nil)
+
 	// Send message synchronously (CLI will wait)
 	if message != "" {
 		return i.sendMessageWhenReady(message)
@@ -1569,6 +1628,10 @@ func (i *Instance) UpdateStatus() error {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
+	pollStart := time.Now()
+	prevStatus := i.Status
+	defer func() { i.recordStatusPoll(prevStatus, pollStart) }()
+
 	// Short grace period for tmux initialization (not Claude startup)
 	// Use lastStartTime for accuracy on restarts, fallback to CreatedAt
 	graceTime := i.lastStartTime
@@ -2785,6 +2848,7 @@ func (i *Instance) Kill() error {
 		return fmt.Errorf("failed to kill tmux session: %w", err)
 	}
 	i.Status = StatusError
+	FireSessionHook(HookSessionStopped, i, nil)
 	return nil
 }
 
@@ -2961,15 +3025,19 @@ func (i *Instance) Restart() error {
 		sessionID := i.GetGenericSessionID()
 
 		// Build resume command for custom tool
+		toolCmd := i.Command
+		if toolDef.AddDirFlag != "" {
+			toolCmd += i.extraDirFlags(toolDef.AddDirFlag)
+		}
 		var resumeCmd string
 		if toolDef.DangerousMode && toolDef.DangerousFlag != "" {
 			resumeCmd = fmt.Sprintf("tmux set-environment %s %s && %s %s %s %s",
 				toolDef.SessionIDEnv, sessionID,
-				i.Command, toolDef.ResumeFlag, sessionID, toolDef.DangerousFlag)
+				toolCmd, toolDef.ResumeFlag, sessionID, toolDef.DangerousFlag)
 		} else {
 			resumeCmd = fmt.Sprintf("tmux set-environment %s %s && %s %s %s",
 				toolDef.SessionIDEnv, sessionID,
-				i.Command, toolDef.ResumeFlag, sessionID)
+				toolCmd, toolDef.ResumeFlag, sessionID)
 		}
 		resumeCmd, err := i.applyWrapper(resumeCmd)
 		if err != nil {
@@ -3035,7 +3103,7 @@ func (i *Instance) Restart() error {
 			if toolDef := GetToolDef(i.Tool); toolDef != nil {
 				command = i.buildGenericCommand(i.Command)
 			} else {
-				command = i.Command
+				command = i.rawCommandWithPorts()
 			}
 		}
 	}
@@ -0,0 +1,334 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session lifecycle events that [session_hooks] commands run on.
+const (
+	HookSessionCreated       = "session.created"
+	HookSessionStarted       = "session.started"
+	HookSessionStatusChanged = "session.status_changed"
+	HookSessionStopped       = "session.stopped"
+	HookSessionForked        = "session.forked"
+	HookSessionDeleted       = "session.deleted"
+	HookWorktreeFinished     = "worktree.finished"
+)
+
+// SessionHookEvents lists the lifecycle events in the order a session
+// usually goes through them.
+var SessionHookEvents = []string{
+	HookSessionCreated,
+	HookSessionForked,
+	HookSessionStarted,
+	HookSessionStatusChanged,
+	HookSessionStopped,
+	HookWorktreeFinished,
+	HookSessionDeleted,
+}
+
+// IsSessionHookEvent reports whether event is a known lifecycle event.
+func IsSessionHookEvent(event string) bool {
+	for _, e := range SessionHookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultSessionHookTimeout bounds each lifecycle hook command when
+// timeout_seconds is unset. Hooks notify other systems; they should be quick.
+const defaultSessionHookTimeout = 30 * time.Second
+
+// sessionHookOutputTail is how many output lines a hook result keeps.
+const sessionHookOutputTail = 10
+
+// SessionHookSettings maps lifecycle events to commands, run with sh -c.
+// Commands from all matching scopes run: global ones first, then those of
+// the session's group (and its parent groups), then those of its tool.
+type SessionHookSettings struct {
+	// TimeoutSeconds limits each command (default: 30)
+	TimeoutSeconds int `toml:"timeout_seconds"`
+
+	// Events maps an event to the commands run for every session
+	Events map[string][]string `toml:"events"`
+
+	// Groups maps a group path to event commands for sessions in that group
+	// or any of its subgroups
+	Groups map[string]map[string][]string `toml:"groups"`
+
+	// Tools maps a tool name to event commands for sessions of that tool
+	Tools map[string]map[string][]string `toml:"tools"`
+}
+
+// Timeout returns the per-command timeout.
+func (s SessionHookSettings) Timeout() time.Duration {
+	if s.TimeoutSeconds <= 0 {
+		return defaultSessionHookTimeout
+	}
+	return time.Duration(s.TimeoutSeconds) * time.Second
+}
+
+// Commands returns the commands configured for event in a session of the
+// given group and tool, in run order.
+func (s SessionHookSettings) Commands(event, groupPath, tool string) []string {
+	commands := append([]string(nil), s.Events[event]...)
+
+	groups := make([]string, 0, len(s.Groups))
+	for group := range s.Groups {
+		if groupPath == group || strings.HasPrefix(groupPath, group+"/") {
+			groups = append(groups, group)
+		}
+	}
+	// Parent groups before subgroups
+	sort.Strings(groups)
+	for _, group := range groups {
+		commands = append(commands, s.Groups[group][event]...)
+	}
+
+	if tool != "" {
+		commands = append(commands, s.Tools[tool][event]...)
+	}
+	return commands
+}
+
+// SessionHookSession is the session a lifecycle hook runs for, as passed in
+// the payload.
+type SessionHookSession struct {
+	ID               string    `json:"id"`
+	Title            string    `json:"title"`
+	ProjectPath      string    `json:"project_path"`
+	GroupPath        string    `json:"group_path"`
+	Tool             string    `json:"tool"`
+	Command          string    `json:"command,omitempty"`
+	Status           Status    `json:"status"`
+	TmuxSession      string    `json:"tmux_session,omitempty"`
+	ParentSessionID  string    `json:"parent_session_id,omitempty"`
+	WorktreePath     string    `json:"worktree_path,omitempty"`
+	WorktreeRepoRoot string    `json:"worktree_repo_root,omitempty"`
+	WorktreeBranch   string    `json:"worktree_branch,omitempty"`
+	Workspace        string    `json:"workspace,omitempty"`
+	Ports            []int     `json:"ports,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// SessionHookPayload is the JSON document lifecycle hooks read on stdin.
+type SessionHookPayload struct {
+	Event     string             `json:"event"`
+	Timestamp time.Time          `json:"timestamp"`
+	Profile   string             `json:"profile"`
+	Session   SessionHookSession `json:"session"`
+	// PrevStatus is set for session.status_changed and for session.stopped
+	// fired by a status change
+	PrevStatus Status `json:"prev_status,omitempty"`
+	// Details carries event specifics, e.g. source_id for session.forked or
+	// target_branch and merged for worktree.finished
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// NewSessionHookPayload describes inst for an event.
+func NewSessionHookPayload(event string, inst *Instance) SessionHookPayload {
+	p := SessionHookPayload{
+		Event:     event,
+		Timestamp: time.Now(),
+		Profile:   GetEffectiveProfile(""),
+		Session: SessionHookSession{
+			ID:               inst.ID,
+			Title:            inst.Title,
+			ProjectPath:      inst.ProjectPath,
+			GroupPath:        inst.GroupPath,
+			Tool:             inst.Tool,
+			Command:          inst.Command,
+			Status:           inst.GetStatusThreadSafe(),
+			ParentSessionID:  inst.ParentSessionID,
+			WorktreePath:     inst.WorktreePath,
+			WorktreeRepoRoot: inst.WorktreeRepoRoot,
+			WorktreeBranch:   inst.WorktreeBranch,
+			Ports:            inst.GetPorts(),
+			CreatedAt:        inst.CreatedAt,
+		},
+	}
+	if ts := inst.GetTmuxSession(); ts != nil {
+		p.Session.TmuxSession = ts.Name
+	}
+	if inst.Workspace != nil {
+		p.Session.Workspace = inst.Workspace.Name
+	}
+	return p
+}
+
+// SessionHookResult is the outcome of one lifecycle hook command.
+type SessionHookResult struct {
+	Command  string
+	Duration time.Duration
+	Err      error
+	// Output holds the command's last lines of output
+	Output []string
+}
+
+// sessionHookQueue holds fired hooks until a single background worker runs
+// them, so a session's events reach the commands in the order they happened
+// (session.created before session.started, ...).
+var sessionHookQueue struct {
+	sync.Mutex
+	jobs    []sessionHookJob
+	running bool
+	pending sync.WaitGroup
+}
+
+type sessionHookJob struct {
+	settings SessionHookSettings
+	payload  SessionHookPayload
+}
+
+// FireSessionHook runs the hooks configured for event in the background.
+// details (may be nil) is passed as the payload's details.
+func FireSessionHook(event string, inst *Instance, details map[string]string) {
+	fireSessionHook(event, inst, func(p *SessionHookPayload) {
+		p.Details = details
+	})
+}
+
+// FireForkHooks runs the hooks for a new fork of the session sourceID: it is
+// a new session (session.created) and a fork (session.forked).
+func FireForkHooks(inst *Instance, sourceID string) {
+	FireSessionHook(HookSessionCreated, inst, nil)
+	FireSessionHook(HookSessionForked, inst, map[string]string{"source_id": sourceID})
+}
+
+// FireStatusChangedHook runs the session.status_changed hooks in the
+// background after inst went from prev to its current status. A session
+// that went to error has stopped, so session.stopped runs too.
+func FireStatusChangedHook(inst *Instance, prev Status) {
+	fill := func(p *SessionHookPayload) {
+		p.PrevStatus = prev
+	}
+	fireSessionHook(HookSessionStatusChanged, inst, fill)
+	if prev != StatusError && inst.GetStatusThreadSafe() == StatusError {
+		fireSessionHook(HookSessionStopped, inst, fill)
+	}
+}
+
+func fireSessionHook(event string, inst *Instance, fill func(*SessionHookPayload)) {
+	settings := GetSessionHookSettings()
+	if len(settings.Commands(event, inst.GroupPath, inst.Tool)) == 0 {
+		return
+	}
+	// Describe the session now; it may change or be gone once hooks run
+	p := NewSessionHookPayload(event, inst)
+	fill(&p)
+
+	q := &sessionHookQueue
+	q.pending.Add(1)
+	q.Lock()
+	q.jobs = append(q.jobs, sessionHookJob{settings: settings, payload: p})
+	if !q.running {
+		q.running = true
+		go drainSessionHooks()
+	}
+	q.Unlock()
+}
+
+func drainSessionHooks() {
+	q := &sessionHookQueue
+	for {
+		q.Lock()
+		if len(q.jobs) == 0 {
+			q.running = false
+			q.Unlock()
+			return
+		}
+		job := q.jobs[0]
+		q.jobs = q.jobs[1:]
+		q.Unlock()
+
+		RunSessionHooks(job.settings, job.payload)
+		q.pending.Done()
+	}
+}
+
+// WaitSessionHooks blocks until the hooks fired so far have finished, so a
+// CLI command's hooks are not cut off when it exits. Each command is bounded
+// by its timeout.
+func WaitSessionHooks() {
+	sessionHookQueue.pending.Wait()
+}
+
+// RunSessionHooks runs the commands configured for the payload's event one
+// after another and returns their results. A failing command is logged and
+// does not stop the ones after it.
+func RunSessionHooks(settings SessionHookSettings, p SessionHookPayload) []SessionHookResult {
+	commands := settings.Commands(p.Event, p.Session.GroupPath, p.Session.Tool)
+	if len(commands) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(p)
+	if err != nil {
+		sessionLog.Warn("session_hook_payload_failed", slog.String("event", p.Event), slog.String("error", err.Error()))
+		return nil
+	}
+	dir := p.Session.ProjectPath
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		// The project may be gone, e.g. a finished worktree
+		dir = ""
+	}
+
+	var results []SessionHookResult
+	for _, command := range commands {
+		if strings.TrimSpace(command) == "" {
+			continue
+		}
+		start := time.Now()
+		output, err := runSessionHook(command, dir, p, payload, settings.Timeout())
+		res := SessionHookResult{Command: command, Duration: time.Since(start), Err: err, Output: output}
+		attrs := []any{
+			slog.String("event", p.Event),
+			slog.String("instance_id", p.Session.ID),
+			slog.String("command", command),
+			slog.Duration("duration", res.Duration),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+			if len(output) > 0 {
+				attrs = append(attrs, slog.String("output", strings.Join(output, "\n")))
+			}
+			sessionLog.Warn("session_hook_failed", attrs...)
+		} else {
+			sessionLog.Info("session_hook", attrs...)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func runSessionHook(command, dir string, p SessionHookPayload, payload []byte, timeout time.Duration) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	env := append(os.Environ(),
+		"AGENT_DECK_HOOK="+p.Event,
+		"AGENT_DECK_SESSION_ID="+p.Session.ID,
+		"AGENT_DECK_SESSION_TITLE="+p.Session.Title,
+	)
+	var tail []string
+	err := runShellHook(ctx, command, dir, env, bytes.NewReader(payload), func(line string) {
+		tail = append(tail, line)
+		if len(tail) > sessionHookOutputTail {
+			tail = tail[1:]
+		}
+	})
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	return tail, err
+}
@@ -0,0 +1,154 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupSessionHooks points HOME at a temp dir whose config.toml holds config.
+func setupSessionHooks(t *testing.T, config string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".agent-deck"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".agent-deck", "config.toml"), []byte(config), 0o644))
+	ClearUserConfigCache()
+	t.Cleanup(ClearUserConfigCache)
+}
+
+func TestSessionHookSettings_Commands(t *testing.T) {
+	setupSessionHooks(t, `
+[session_hooks]
+timeout_seconds = 5
+
+[session_hooks.events]
+"session.created" = ["global"]
+
+[session_hooks.groups.work]
+"session.created" = ["work"]
+
+[session_hooks.groups."work/api"]
+"session.created" = ["work-api"]
+
+[session_hooks.groups.workshop]
+"session.created" = ["workshop"]
+
+[session_hooks.tools.claude]
+"session.created" = ["claude"]
+"session.deleted" = ["claude-deleted"]
+`)
+	settings := GetSessionHookSettings()
+	assert.Equal(t, 5*time.Second, settings.Timeout())
+
+	assert.Equal(t, []string{"global", "work", "work-api", "claude"}, settings.Commands(HookSessionCreated, "work/api", "claude"),
+		"parent groups run before subgroups, tools last; workshop is not a parent of work")
+	assert.Equal(t, []string{"global"}, settings.Commands(HookSessionCreated, "personal", "shell"))
+	assert.Equal(t, []string{"claude-deleted"}, settings.Commands(HookSessionDeleted, "", "claude"))
+	assert.Empty(t, settings.Commands(HookSessionStarted, "work", "claude"))
+
+	assert.Equal(t, defaultSessionHookTimeout, SessionHookSettings{}.Timeout())
+}
+
+func TestRunSessionHooks_Payload(t *testing.T) {
+	setupSessionHooks(t, "")
+	dir := t.TempDir()
+	out := filepath.Join(dir, "payload.json")
+
+	inst := NewInstanceWithGroupAndTool("api", dir, "work", "claude")
+	inst.WorktreeBranch = "feat/x"
+	p := NewSessionHookPayload(HookSessionStatusChanged, inst)
+	p.PrevStatus = StatusRunning
+	settings := SessionHookSettings{Events: map[string][]string{
+		HookSessionStatusChanged: {
+			`cat > payload.json && echo "$AGENT_DECK_HOOK $AGENT_DECK_SESSION_TITLE"`,
+			"echo broken >&2; exit 3",
+			"true",
+		},
+	}}
+
+	results := RunSessionHooks(settings, p)
+	require.Len(t, results, 3, "a failing command doesn't stop the others")
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, []string{"session.status_changed api"}, results[0].Output)
+	assert.Error(t, results[1].Err)
+	assert.Equal(t, []string{"broken"}, results[1].Output)
+	assert.NoError(t, results[2].Err)
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err, "commands run in the session's project directory")
+	var got SessionHookPayload
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, HookSessionStatusChanged, got.Event)
+	assert.Equal(t, StatusRunning, got.PrevStatus)
+	assert.Equal(t, inst.ID, got.Session.ID)
+	assert.Equal(t, "work", got.Session.GroupPath)
+	assert.Equal(t, "claude", got.Session.Tool)
+	assert.Equal(t, "feat/x", got.Session.WorktreeBranch)
+	assert.Equal(t, "_test", got.Profile)
+}
+
+func TestRunSessionHooks_Timeout(t *testing.T) {
+	setupSessionHooks(t, "")
+	inst := NewInstance("slow", t.TempDir())
+	settings := SessionHookSettings{
+		TimeoutSeconds: 1,
+		Events:         map[string][]string{HookSessionStopped: {"sleep 30"}},
+	}
+
+	start := time.Now()
+	results := RunSessionHooks(settings, NewSessionHookPayload(HookSessionStopped, inst))
+	require.Len(t, results, 1)
+	assert.ErrorContains(t, results[0].Err, "timed out after 1s")
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestFireSessionHook_RunsInOrder(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "events.log")
+	setupSessionHooks(t, `
+[session_hooks.events]
+"session.created" = ["sleep 0.2; echo created >> `+log+`"]
+"session.started" = ["echo started >> `+log+`"]
+"session.deleted" = ["grep -o cleanup >> `+log+`"]
+`)
+	// The project directory is gone by the time session.deleted runs
+	inst := NewInstance("ordered", filepath.Join(t.TempDir(), "gone"))
+
+	start := time.Now()
+	FireSessionHook(HookSessionCreated, inst, nil)
+	FireSessionHook(HookSessionStarted, inst, nil)
+	FireSessionHook(HookSessionStopped, inst, nil) // No commands: nothing queued
+	FireSessionHook(HookSessionDeleted, inst, map[string]string{"reason": "cleanup"})
+	assert.Less(t, time.Since(start), 150*time.Millisecond, "hooks run in the background")
+
+	WaitSessionHooks()
+	data, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"created", "started", "cleanup"}, strings.Fields(string(data)))
+}
+
+func TestFireStatusChangedHook_StoppedWhenSessionExits(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "events.log")
+	setupSessionHooks(t, `
+[session_hooks.events]
+"session.status_changed" = ["echo changed >> `+log+`"]
+"session.stopped" = ["grep -o 'prev_status\":\"[a-z]*' >> `+log+`"]
+`)
+	inst := NewInstance("exits", t.TempDir())
+
+	inst.Status = StatusWaiting
+	FireStatusChangedHook(inst, StatusRunning)
+	inst.Status = StatusError
+	FireStatusChangedHook(inst, StatusWaiting)
+
+	WaitSessionHooks()
+	data, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"changed", "changed", `prev_status":"waiting`}, strings.Fields(string(data)))
+}
@@ -19,6 +19,7 @@ var maintLog = logging.ForComponent(logging.CompSession)
 type MaintenanceResult struct {
 	PrunedLogs       int
 	PrunedBackups    int
+	ProfileBackups   int
 	ArchivedSessions int
 	Duration         time.Duration
 }
@@ -38,9 +39,18 @@ func RunMaintenance() MaintenanceResult {
 	prunedBackups := cleanupDeckBackups(filepath.Join(deckDir, "profiles"))
 	archivedSessions := archiveBloatedSessions(deckDir)
 
+	var profileBackups int
+	if settings := GetMaintenanceSettings(); settings.BackupIntervalHours > 0 {
+		created, pruned := backupProfiles(filepath.Join(deckDir, "profiles"),
+			time.Duration(settings.BackupIntervalHours)*time.Hour, settings.BackupRetention, time.Now())
+		profileBackups = created
+		prunedBackups += pruned
+	}
+
 	return MaintenanceResult{
 		PrunedLogs:       prunedLogs,
 		PrunedBackups:    prunedBackups,
+		ProfileBackups:   profileBackups,
 		ArchivedSessions: archivedSessions,
 		Duration:         time.Since(start),
 	}
@@ -0,0 +1,53 @@
+package session
+
+import (
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/mcppool"
+	"github.com/asheshgoplani/agent-deck/internal/metrics"
+)
+
+var (
+	// statusPollDuration times Instance.UpdateStatus by tool.
+	statusPollDuration = metrics.NewHistogramVec("agentdeck_status_poll_duration_seconds",
+		"Time spent in one session status poll.", metrics.DurationBuckets, "tool")
+
+	// statusTransitions counts status changes seen by UpdateStatus.
+	statusTransitions = metrics.NewCounterVec("agentdeck_status_transitions",
+		"Session status changes by tool, previous and new status.", "tool", "from", "to")
+
+	// waitingDuration records how long sessions stayed waiting before
+	// moving to another status.
+	waitingDuration = metrics.NewHistogramVec("agentdeck_session_waiting_duration_seconds",
+		"Time sessions spent waiting for input before leaving the waiting status.",
+		[]float64{5, 15, 30, 60, 120, 300, 600, 1800, 3600, 7200, 14400}, "tool")
+)
+
+func init() {
+	metrics.RegisterCollector(func() []*metrics.Gauge {
+		return mcppool.CollectMetrics(GetGlobalPool(), GetGlobalHTTPPool())
+	})
+}
+
+// recordStatusPoll updates the status metrics after a poll. Called with
+// i.mu held.
+func (i *Instance) recordStatusPoll(prev Status, start time.Time) {
+	tool := i.Tool
+	if tool == "" {
+		tool = "shell"
+	}
+	statusPollDuration.ObserveSince(start, tool)
+	if i.Status == prev {
+		return
+	}
+	statusTransitions.Inc(tool, string(prev), string(i.Status))
+
+	now := time.Now()
+	if prev == StatusWaiting && !i.waitingEnteredAt.IsZero() {
+		waitingDuration.Observe(now.Sub(i.waitingEnteredAt).Seconds(), tool)
+		i.waitingEnteredAt = time.Time{}
+	}
+	if i.Status == StatusWaiting {
+		i.waitingEnteredAt = now
+	}
+}
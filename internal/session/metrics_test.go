@@ -0,0 +1,29 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordStatusPollTracksWaitingTime(t *testing.T) {
+	inst := &Instance{Tool: "metrics-test", Status: StatusWaiting}
+	inst.recordStatusPoll(StatusRunning, time.Now())
+
+	assert.Equal(t, float64(1), statusTransitions.Value("metrics-test", "running", "waiting"))
+	assert.False(t, inst.waitingEnteredAt.IsZero(), "entering waiting should start the clock")
+	assert.Equal(t, uint64(1), statusPollDuration.Count("metrics-test"))
+
+	inst.waitingEnteredAt = time.Now().Add(-time.Minute)
+	inst.Status = StatusRunning
+	inst.recordStatusPoll(StatusWaiting, time.Now())
+
+	assert.Equal(t, uint64(1), waitingDuration.Count("metrics-test"))
+	assert.True(t, inst.waitingEnteredAt.IsZero())
+
+	// Unchanged status: the poll is timed but no transition is counted
+	inst.recordStatusPoll(StatusRunning, time.Now())
+	assert.Equal(t, uint64(3), statusPollDuration.Count("metrics-test"))
+	assert.Equal(t, float64(1), statusTransitions.Value("metrics-test", "waiting", "running"))
+}
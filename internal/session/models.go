@@ -0,0 +1,355 @@
+package session
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DefaultContextLimit is the context window size assumed for models that are
+// not listed in the model table (matches current Claude models).
+const DefaultContextLimit = 200000
+
+// ModelPricing holds pricing per million tokens for a model.
+// CacheWrite1h prices cache writes with a 1-hour TTL (0 = same as CacheWrite).
+type ModelPricing struct {
+	Input        float64 `toml:"input" json:"input"`
+	Output       float64 `toml:"output" json:"output"`
+	CacheRead    float64 `toml:"cache_read" json:"cache_read"`
+	CacheWrite   float64 `toml:"cache_write" json:"cache_write"`
+	CacheWrite1h float64 `toml:"cache_write_1h" json:"cache_write_1h,omitempty"`
+}
+
+// IsZero reports whether no price is set.
+func (p ModelPricing) IsZero() bool {
+	return p == ModelPricing{}
+}
+
+// LongContextPricing replaces the base pricing for requests whose prompt
+// (input plus cached tokens) exceeds Threshold tokens.
+type LongContextPricing struct {
+	Threshold int `toml:"threshold" json:"threshold"`
+	ModelPricing
+}
+
+// ModelDef describes a model's properties that agent-deck needs for analytics.
+type ModelDef struct {
+	// ContextLimit is the model's context window size in tokens
+	ContextLimit int `toml:"context_limit" json:"context_limit"`
+	ModelPricing
+	LongContext *LongContextPricing `toml:"long_context" json:"long_context,omitempty"`
+}
+
+// TokenUsage is a token count to be priced. CacheWrite1h is the part of
+// CacheWrite written with a 1-hour TTL. Prompt is the prompt size of a single
+// request, used to pick the long-context tier; 0 prices at base rates.
+type TokenUsage struct {
+	Input        int
+	Output       int
+	CacheRead    int
+	CacheWrite   int
+	CacheWrite1h int
+	Prompt       int
+}
+
+// Cost prices usage with the model's rates.
+func (d ModelDef) Cost(u TokenUsage) float64 {
+	p := d.ModelPricing
+	if lc := d.LongContext; lc != nil && lc.Threshold > 0 && u.Prompt > lc.Threshold {
+		p = lc.ModelPricing
+	}
+	cw1h := p.CacheWrite1h
+	if cw1h == 0 {
+		cw1h = p.CacheWrite
+	}
+
+	// Convert to millions
+	inputM := float64(u.Input) / 1_000_000
+	outputM := float64(u.Output) / 1_000_000
+	cacheReadM := float64(u.CacheRead) / 1_000_000
+	cacheWriteM := float64(u.CacheWrite-u.CacheWrite1h) / 1_000_000
+	cacheWrite1hM := float64(u.CacheWrite1h) / 1_000_000
+
+	return inputM*p.Input +
+		outputM*p.Output +
+		cacheReadM*p.CacheRead +
+		cacheWriteM*p.CacheWrite +
+		cacheWrite1hM*cw1h
+}
+
+// modelTableFile is the format of the embedded table and ~/.agent-deck/models.toml
+type modelTableFile struct {
+	Version string              `toml:"version"`
+	Models  map[string]ModelDef `toml:"models"`
+}
+
+//go:embed models.toml
+var builtinModelsTOML []byte
+
+var (
+	builtinModelsOnce     sync.Once
+	builtinModels         modelTableFile
+	modelOverridesCache   *modelTableFile
+	modelOverridesCacheMu sync.Mutex
+)
+
+// builtinModelTable returns the embedded model table.
+func builtinModelTable() modelTableFile {
+	builtinModelsOnce.Do(func() {
+		if _, err := toml.Decode(string(builtinModelsTOML), &builtinModels); err != nil {
+			panic(fmt.Sprintf("invalid embedded models.toml: %v", err))
+		}
+	})
+	return builtinModels
+}
+
+// GetModelsFilePath returns the path of the user's model table overrides.
+func GetModelsFilePath() (string, error) {
+	dir, err := GetAgentDeckDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "models.toml"), nil
+}
+
+// loadModelOverrides reads ~/.agent-deck/models.toml once; a missing or
+// invalid file yields an empty table. ReloadUserConfig clears the cache.
+func loadModelOverrides() *modelTableFile {
+	modelOverridesCacheMu.Lock()
+	defer modelOverridesCacheMu.Unlock()
+	if modelOverridesCache != nil {
+		return modelOverridesCache
+	}
+	table := &modelTableFile{}
+	if path, err := GetModelsFilePath(); err == nil {
+		if _, err := toml.DecodeFile(path, table); err != nil && !os.IsNotExist(err) {
+			sessionLog.Warn("models_file_parse_failed", slog.String("path", path), slog.String("error", err.Error()))
+			table = &modelTableFile{}
+		}
+	}
+	modelOverridesCache = table
+	return table
+}
+
+func clearModelOverridesCache() {
+	modelOverridesCacheMu.Lock()
+	modelOverridesCache = nil
+	modelOverridesCacheMu.Unlock()
+}
+
+// Model table sources, highest precedence first
+const (
+	ModelSourceConfig  = "config.toml"
+	ModelSourceFile    = "models.toml"
+	ModelSourceBuiltin = "builtin"
+)
+
+// modelLayer is one source of model definitions
+type modelLayer struct {
+	source string
+	models map[string]ModelDef
+}
+
+// modelLayers returns the model table sources, highest precedence first.
+func modelLayers() []modelLayer {
+	var layers []modelLayer
+	if config, err := LoadUserConfig(); err == nil && config != nil && len(config.Models) > 0 {
+		layers = append(layers, modelLayer{ModelSourceConfig, config.Models})
+	}
+	if overrides := loadModelOverrides(); len(overrides.Models) > 0 {
+		layers = append(layers, modelLayer{ModelSourceFile, overrides.Models})
+	}
+	return append(layers, modelLayer{ModelSourceBuiltin, builtinModelTable().Models})
+}
+
+// matchModelPattern reports whether a model name matches a table key, where
+// "*" matches any run of characters. Matching is case-insensitive.
+func matchModelPattern(pattern, name string) bool {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	last := len(parts) - 1
+	for _, part := range parts[1:last] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return strings.HasSuffix(name, parts[last])
+}
+
+// modelCandidate is a table entry matching a model name
+type modelCandidate struct {
+	key    string
+	source string
+	def    ModelDef
+	rank   int // Higher is more specific
+	layer  int // Lower takes precedence
+}
+
+// matchModel returns the entries matching a model name, most specific first.
+// An exact key beats any pattern, and patterns with more literal characters
+// beat shorter ones; ties go to the higher-precedence source.
+func matchModel(model string, layers []modelLayer) []modelCandidate {
+	var out []modelCandidate
+	for i, l := range layers {
+		for key, def := range l.models {
+			if key == "default" || !matchModelPattern(key, model) {
+				continue
+			}
+			rank := len(strings.ReplaceAll(key, "*", ""))
+			if !strings.Contains(key, "*") {
+				rank = 1 << 30
+			}
+			out = append(out, modelCandidate{key: key, source: l.source, def: def, rank: rank, layer: i})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].rank != out[j].rank {
+			return out[i].rank > out[j].rank
+		}
+		if out[i].layer != out[j].layer {
+			return out[i].layer < out[j].layer
+		}
+		return out[i].key < out[j].key
+	})
+	return out
+}
+
+// resolveModel merges the matching entries of a model: each of the context
+// limit and the pricing (with its long-context tier) comes from the most
+// specific entry that sets it.
+func resolveModel(candidates []modelCandidate) (def ModelDef, pricingKey string) {
+	for _, c := range candidates {
+		if def.ContextLimit == 0 && c.def.ContextLimit > 0 {
+			def.ContextLimit = c.def.ContextLimit
+		}
+		if pricingKey == "" && !c.def.ModelPricing.IsZero() {
+			def.ModelPricing = c.def.ModelPricing
+			def.LongContext = c.def.LongContext
+			pricingKey = c.key
+		}
+	}
+	return def, pricingKey
+}
+
+// defaultCandidates returns the "default" entries of each layer.
+func defaultCandidates(layers []modelLayer) []modelCandidate {
+	var out []modelCandidate
+	for i, l := range layers {
+		if def, ok := l.models["default"]; ok {
+			out = append(out, modelCandidate{key: "default", source: l.source, def: def, layer: i})
+		}
+	}
+	return out
+}
+
+// GetModelDef returns the effective definition for a model, merged from
+// [models] in config.toml, ~/.agent-deck/models.toml and the built-in table.
+// Returns false if no entry matches the model.
+func GetModelDef(model string) (ModelDef, bool) {
+	if model == "" {
+		return ModelDef{}, false
+	}
+	candidates := matchModel(model, modelLayers())
+	if len(candidates) == 0 {
+		return ModelDef{}, false
+	}
+	def, _ := resolveModel(candidates)
+	return def, true
+}
+
+// LookupModel returns the definition for a model with unset fields filled in
+// from the "default" entry, so it always has a context limit and pricing.
+func LookupModel(model string) ModelDef {
+	layers := modelLayers()
+	var candidates []modelCandidate
+	if model != "" {
+		candidates = matchModel(model, layers)
+	}
+	def, _ := resolveModel(append(candidates, defaultCandidates(layers)...))
+	if def.ContextLimit == 0 {
+		def.ContextLimit = DefaultContextLimit
+	}
+	return def
+}
+
+// ModelContextLimit returns the context window size for a model.
+// Falls back to the [models.default] entry, then to DefaultContextLimit.
+func ModelContextLimit(model string) int {
+	return LookupModel(model).ContextLimit
+}
+
+// ModelTableFingerprint identifies the effective model table, so caches of
+// computed costs can be dropped when prices change.
+func ModelTableFingerprint() string {
+	h := sha256.New()
+	for _, l := range modelLayers() {
+		data, _ := json.Marshal(l.models) // Map keys marshal sorted
+		fmt.Fprintf(h, "%s\x00%s\x00", l.source, data)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// ModelTableEntry is one row of the effective model table.
+type ModelTableEntry struct {
+	Key    string `json:"model"`
+	Source string `json:"source"`
+	ModelDef
+	// PricingFrom names the entry the pricing was inherited from, if not Key
+	PricingFrom string `json:"pricing_from,omitempty"`
+}
+
+// Matches reports whether the entry applies to a model name.
+func (e ModelTableEntry) Matches(model string) bool {
+	return e.Key != "default" && matchModelPattern(e.Key, model)
+}
+
+// ModelTable lists every key of the effective model table with its merged
+// definition, sorted by key with "default" first. Version is the built-in
+// table's version.
+func ModelTable() (version string, entries []ModelTableEntry) {
+	layers := modelLayers()
+	keys := make(map[string]string) // key -> source of highest-precedence layer
+	for i := len(layers) - 1; i >= 0; i-- {
+		for key := range layers[i].models {
+			keys[key] = layers[i].source
+		}
+	}
+	for key, source := range keys {
+		var candidates []modelCandidate
+		if key != "default" {
+			candidates = matchModel(key, layers)
+		}
+		def, pricingKey := resolveModel(append(candidates, defaultCandidates(layers)...))
+		e := ModelTableEntry{Key: key, Source: source, ModelDef: def}
+		if pricingKey != key {
+			e.PricingFrom = pricingKey
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if (entries[i].Key == "default") != (entries[j].Key == "default") {
+			return entries[i].Key == "default"
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return builtinModelTable().Version, entries
+}
@@ -0,0 +1,135 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withModelOverrides points HOME at a temp dir holding the given
+// ~/.agent-deck/models.toml and config.toml contents ("" = no file).
+func withModelOverrides(t *testing.T, modelsTOML, configTOML string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if modelsTOML != "" {
+		writeTestFile(t, filepath.Join(home, ".agent-deck", "models.toml"), modelsTOML)
+	}
+	if configTOML != "" {
+		writeTestFile(t, filepath.Join(home, ".agent-deck", "config.toml"), configTOML)
+	}
+	ClearUserConfigCache()
+	t.Cleanup(ClearUserConfigCache)
+}
+
+func TestMatchModelPattern(t *testing.T) {
+	assert.True(t, matchModelPattern("o3", "o3"))
+	assert.False(t, matchModelPattern("o3", "o3-mini"))
+	assert.True(t, matchModelPattern("claude-sonnet-4*", "claude-sonnet-4-5-20250929"))
+	assert.True(t, matchModelPattern("claude-sonnet-4*", "claude-sonnet-4"))
+	assert.True(t, matchModelPattern("*sonnet*", "anthropic/Claude-Sonnet-4"))
+	assert.True(t, matchModelPattern("claude-*-2025*", "claude-opus-4-20250514"))
+	assert.False(t, matchModelPattern("claude-*-2024*", "claude-opus-4-20250514"))
+	assert.False(t, matchModelPattern("gpt-5*", "claude-sonnet-4"))
+}
+
+func TestLookupModel_Builtin(t *testing.T) {
+	withModelOverrides(t, "", "")
+
+	// The most specific pattern supplies pricing, a broader one the context limit
+	def := LookupModel("claude-sonnet-4-5-20250929")
+	assert.Equal(t, 200000, def.ContextLimit)
+	assert.Equal(t, 3.0, def.Input)
+	assert.NotNil(t, def.LongContext)
+
+	mini := LookupModel("gpt-5-mini-2025-08-07")
+	assert.Equal(t, 0.25, mini.Input)
+	assert.Equal(t, 400000, mini.ContextLimit)
+
+	unknown := LookupModel("some-local-model")
+	assert.Equal(t, LookupModel("default"), unknown)
+	_, ok := GetModelDef("some-local-model")
+	assert.False(t, ok)
+}
+
+func TestModelDefCost_Tiers(t *testing.T) {
+	def := ModelDef{
+		ModelPricing: ModelPricing{Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75, CacheWrite1h: 6},
+		LongContext: &LongContextPricing{Threshold: 200000,
+			ModelPricing: ModelPricing{Input: 6, Output: 22.5, CacheRead: 0.6, CacheWrite: 7.5}},
+	}
+
+	// 1M cache writes, a quarter of them with the 1-hour TTL
+	assert.InDelta(t, 0.75*3.75+0.25*6, def.Cost(TokenUsage{CacheWrite: 1_000_000, CacheWrite1h: 250_000}), 1e-9)
+
+	base := TokenUsage{Input: 1_000_000, Output: 100_000, Prompt: 150_000}
+	assert.InDelta(t, 3+1.5, def.Cost(base), 1e-9)
+
+	long := base
+	long.Prompt = 250_000
+	assert.InDelta(t, 6+2.25, def.Cost(long), 1e-9)
+
+	// Long-context tier without a 1h price falls back to its cache_write
+	assert.InDelta(t, 7.5, def.Cost(TokenUsage{CacheWrite: 1_000_000, CacheWrite1h: 1_000_000, Prompt: 300_000}), 1e-9)
+}
+
+func TestLookupModel_Overrides(t *testing.T) {
+	withModelOverrides(t, `
+version = "local"
+
+[models."claude-sonnet-4*"]
+input = 2.0
+output = 10.0
+
+[models."my-local-*"]
+context_limit = 32768
+input = 0.01
+output = 0.01
+
+[models.default]
+context_limit = 128000
+`, `
+[models."claude-sonnet-4-5-20250929"]
+context_limit = 1000000
+
+[models."my-local-7b"]
+output = 0.02
+`)
+
+	// models.toml replaces the built-in pricing of the same key, dropping its
+	// long-context tier; config.toml's exact entry sets only the context limit
+	sonnet := LookupModel("claude-sonnet-4-5-20250929")
+	assert.Equal(t, 1000000, sonnet.ContextLimit)
+	assert.Equal(t, 2.0, sonnet.Input)
+	assert.Nil(t, sonnet.LongContext)
+	assert.Equal(t, 200000, ModelContextLimit("claude-sonnet-4-20250514"))
+
+	// Pricing comes from the most specific entry that sets any price
+	local := LookupModel("my-local-7b")
+	assert.Equal(t, 32768, local.ContextLimit)
+	assert.Equal(t, 0.0, local.Input)
+	assert.Equal(t, 0.02, local.Output)
+
+	// [models.default] in models.toml only overrides the context limit
+	assert.Equal(t, 128000, ModelContextLimit("unknown-model"))
+	assert.Equal(t, 3.0, LookupModel("unknown-model").Input)
+
+	version, entries := ModelTable()
+	assert.Equal(t, builtinModelTable().Version, version)
+	sources := map[string]string{}
+	for _, e := range entries {
+		sources[e.Key] = e.Source
+	}
+	assert.Equal(t, ModelSourceConfig, sources["my-local-7b"])
+	assert.Equal(t, ModelSourceFile, sources["claude-sonnet-4*"])
+	assert.Equal(t, ModelSourceBuiltin, sources["gpt-5*"])
+	assert.Equal(t, "default", entries[0].Key)
+}
+
+func TestGeminiCalculateCost_UnknownModelUsesGeminiFallback(t *testing.T) {
+	withModelOverrides(t, "", "")
+	a := &GeminiSessionAnalytics{InputTokens: 1_000_000, OutputTokens: 1_000_000}
+	assert.InDelta(t, 0.75, a.CalculateCost("gemini-9-ultra"), 1e-9)
+	assert.InDelta(t, 0.75, a.CalculateCost(""), 1e-9)
+}
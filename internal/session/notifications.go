@@ -18,9 +18,19 @@ type NotificationEntry struct {
 	Status       Status // For icon rendering when show_all enabled
 }
 
+// NotificationNotice is a one-off message about a session (e.g. an automatic
+// context compaction) shown in the notification bar until it expires
+type NotificationNotice struct {
+	SessionID string
+	Title     string
+	Text      string
+	ExpiresAt time.Time
+}
+
 // NotificationManager tracks waiting sessions for the notification bar
 type NotificationManager struct {
-	entries  []*NotificationEntry // Ordered: newest first
+	entries  []*NotificationEntry  // Ordered: newest first
+	notices  []*NotificationNotice // Ordered: oldest first
 	maxShown int
 	showAll  bool // Show all sessions vs only waiting
 	mu       sync.RWMutex
@@ -149,14 +159,61 @@ func (nm *NotificationManager) Has(sessionID string) bool {
 	return false
 }
 
+// Notify shows a notice about a session in the bar for ttl, replacing an
+// earlier notice for the same session
+func (nm *NotificationManager) Notify(sessionID, title, text string, ttl time.Duration) {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+
+	now := time.Now()
+	notices := make([]*NotificationNotice, 0, len(nm.notices)+1)
+	for _, n := range nm.notices {
+		if n.SessionID != sessionID && now.Before(n.ExpiresAt) {
+			notices = append(notices, n)
+		}
+	}
+	nm.notices = append(notices, &NotificationNotice{
+		SessionID: sessionID,
+		Title:     title,
+		Text:      text,
+		ExpiresAt: now.Add(ttl),
+	})
+}
+
+// Notices returns the notices that have not expired yet (oldest first)
+func (nm *NotificationManager) Notices() []*NotificationNotice {
+	nm.mu.RLock()
+	defer nm.mu.RUnlock()
+	return nm.activeNotices(time.Now())
+}
+
+// activeNotices filters out expired notices; nm.mu must be held
+func (nm *NotificationManager) activeNotices(now time.Time) []*NotificationNotice {
+	var active []*NotificationNotice
+	for _, n := range nm.notices {
+		if now.Before(n.ExpiresAt) {
+			active = append(active, n)
+		}
+	}
+	return active
+}
+
 // FormatBar returns the formatted status bar text
 func (nm *NotificationManager) FormatBar() string {
 	nm.mu.RLock()
 	defer nm.mu.RUnlock()
 
-	if len(nm.entries) == 0 {
+	notices := nm.activeNotices(time.Now())
+	if len(nm.entries) == 0 && len(notices) == 0 {
 		return ""
 	}
+	var noticeParts []string
+	for _, n := range notices {
+		noticeParts = append(noticeParts, fmt.Sprintf("%s: %s", n.Title, n.Text))
+	}
+	if len(nm.entries) == 0 {
+		return "ℹ " + strings.Join(noticeParts, " · ")
+	}
 
 	var parts []string
 	for _, e := range nm.entries {
@@ -172,7 +229,11 @@ func (nm *NotificationManager) FormatBar() string {
 		parts = append(parts, formatted)
 	}
 
-	return "⚡ " + strings.Join(parts, " ")
+	bar := "⚡ " + strings.Join(parts, " ")
+	if len(noticeParts) > 0 {
+		bar += " ℹ " + strings.Join(noticeParts, " · ")
+	}
+	return bar
 }
 
 // statusIcon returns the Unicode icon for a given session status
@@ -111,6 +111,29 @@ func TestNotificationManager_FormatBar(t *testing.T) {
 	assert.Contains(t, bar, "[2]")
 }
 
+func TestNotificationManager_Notices(t *testing.T) {
+	nm := NewNotificationManager(6, false)
+
+	nm.Notify("a", "frontend", "compacted at 85%", time.Minute)
+	assert.Equal(t, "ℹ frontend: compacted at 85%", nm.FormatBar())
+
+	// A newer notice replaces the session's earlier one
+	nm.Notify("a", "frontend", "forked to \"frontend (cont)\"", time.Minute)
+	require.Len(t, nm.Notices(), 1)
+	assert.Contains(t, nm.FormatBar(), "forked")
+
+	// Notices follow the waiting sessions
+	_ = nm.Add(&Instance{ID: "b", Title: "api", Status: StatusWaiting})
+	bar := nm.FormatBar()
+	assert.Contains(t, bar, "[1] api")
+	assert.Contains(t, bar, "frontend: forked")
+
+	// Expired notices disappear
+	nm.Notify("c", "docs", "compacted at 90%", -time.Second)
+	assert.Len(t, nm.Notices(), 1)
+	assert.NotContains(t, nm.FormatBar(), "docs")
+}
+
 func TestNotificationManager_FullTitles(t *testing.T) {
 	nm := NewNotificationManager(6, false)
 
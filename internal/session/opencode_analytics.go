@@ -0,0 +1,136 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// openCodeUsageMessage holds the usage fields OpenCode records on each
+// message (zero for user messages). Cost is OpenCode's own estimate in USD.
+type openCodeUsageMessage struct {
+	ID      string `json:"id"`
+	Role    string `json:"role"`
+	ModelID string `json:"modelID"`
+	Time    struct {
+		Created   int64 `json:"created"`   // Unix millis
+		Completed int64 `json:"completed"` // Unix millis
+	} `json:"time"`
+	Cost   float64 `json:"cost"`
+	Tokens struct {
+		Input     int `json:"input"`
+		Output    int `json:"output"`
+		Reasoning int `json:"reasoning"`
+		Cache     struct {
+			Read  int `json:"read"`
+			Write int `json:"write"`
+		} `json:"cache"`
+	} `json:"tokens"`
+}
+
+// openCodeToolPart is a message part recording a tool invocation
+type openCodeToolPart struct {
+	Type string `json:"type"`
+	Tool string `json:"tool"`
+}
+
+// ParseOpenCodeSession returns analytics for an OpenCode session by reading
+// its per-message usage from OpenCode's storage directory.
+func ParseOpenCodeSession(sessionID string) (*SessionAnalytics, error) {
+	return parseOpenCodeSession(getOpenCodeDataDir(), sessionID)
+}
+
+func parseOpenCodeSession(dataDir, sessionID string) (*SessionAnalytics, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("no OpenCode session ID")
+	}
+	storage := openCodeTranscriptSource{dir: dataDir}.storage()
+	msgDir := filepath.Join(storage, "message", sessionID)
+	if _, err := os.Stat(msgDir); err != nil {
+		return nil, err
+	}
+
+	var msgs []openCodeUsageMessage
+	for _, f := range readJSONDir(msgDir) {
+		var m openCodeUsageMessage
+		if json.Unmarshal(f, &m) == nil && m.ID != "" && m.Role == "assistant" {
+			msgs = append(msgs, m)
+		}
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		if msgs[i].Time.Created != msgs[j].Time.Created {
+			return msgs[i].Time.Created < msgs[j].Time.Created
+		}
+		return msgs[i].ID < msgs[j].ID
+	})
+
+	analytics := &SessionAnalytics{
+		ToolCalls: []ToolCall{},
+	}
+	toolCounts := make(map[string]int)
+	var firstTime, lastTime time.Time
+	var reportedCost float64
+
+	for _, m := range msgs {
+		for _, ms := range []int64{m.Time.Created, m.Time.Completed} {
+			if ms <= 0 {
+				continue
+			}
+			ts := time.UnixMilli(ms)
+			if firstTime.IsZero() || ts.Before(firstTime) {
+				firstTime = ts
+			}
+			if ts.After(lastTime) {
+				lastTime = ts
+			}
+		}
+
+		analytics.InputTokens += m.Tokens.Input
+		analytics.OutputTokens += m.Tokens.Output + m.Tokens.Reasoning
+		analytics.CacheReadTokens += m.Tokens.Cache.Read
+		analytics.CacheWriteTokens += m.Tokens.Cache.Write
+		reportedCost += m.Cost
+
+		// Messages that errored before the model replied carry no usage and
+		// must not reset the context size
+		if ctx := m.Tokens.Input + m.Tokens.Cache.Read + m.Tokens.Cache.Write; ctx > 0 {
+			analytics.CurrentContextTokens = ctx
+		}
+		if m.ModelID != "" {
+			analytics.Model = m.ModelID
+		}
+		analytics.TotalTurns++
+
+		for _, f := range readJSONDir(filepath.Join(storage, "part", m.ID)) {
+			var p openCodeToolPart
+			if json.Unmarshal(f, &p) == nil && p.Type == "tool" && p.Tool != "" {
+				toolCounts[p.Tool]++
+			}
+		}
+	}
+
+	for name, count := range toolCounts {
+		analytics.ToolCalls = append(analytics.ToolCalls, ToolCall{
+			Name:  name,
+			Count: count,
+		})
+	}
+
+	analytics.StartTime = firstTime
+	analytics.LastActive = lastTime
+	if !firstTime.IsZero() && !lastTime.IsZero() {
+		analytics.Duration = lastTime.Sub(firstTime)
+	}
+
+	// Prefer OpenCode's own cost (it knows the provider's pricing); it is
+	// zero for subscription providers, so estimate from tokens instead
+	analytics.EstimatedCost = reportedCost
+	if analytics.EstimatedCost == 0 {
+		analytics.EstimatedCost = analytics.CalculateCost(analytics.Model)
+	}
+
+	return analytics, nil
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseOpenCodeSession(t *testing.T) {
+	dir := t.TempDir()
+	storage := filepath.Join(dir, "storage")
+	sesID := "ses_abc123"
+	writeTestFile(t, filepath.Join(storage, "message", sesID, "msg_001.json"),
+		`{"id":"msg_001","sessionID":"ses_abc123","role":"user","time":{"created":1760781600000}}`)
+	writeTestFile(t, filepath.Join(storage, "message", sesID, "msg_002.json"),
+		`{"id":"msg_002","sessionID":"ses_abc123","role":"assistant","modelID":"claude-sonnet-4-20250514","providerID":"anthropic","time":{"created":1760781601000,"completed":1760781610000},"cost":0.02,"tokens":{"input":1200,"output":300,"reasoning":0,"cache":{"read":8000,"write":500}}}`)
+	writeTestFile(t, filepath.Join(storage, "message", sesID, "msg_003.json"),
+		`{"id":"msg_003","sessionID":"ses_abc123","role":"assistant","modelID":"claude-sonnet-4-20250514","providerID":"anthropic","time":{"created":1760781620000,"completed":1760781660000},"cost":0.03,"tokens":{"input":400,"output":700,"reasoning":100,"cache":{"read":9500,"write":0}}}`)
+	// Aborted reply: no usage, must not reset the context size
+	writeTestFile(t, filepath.Join(storage, "message", sesID, "msg_004.json"),
+		`{"id":"msg_004","sessionID":"ses_abc123","role":"assistant","modelID":"claude-sonnet-4-20250514","time":{"created":1760781670000},"cost":0,"tokens":{"input":0,"output":0,"reasoning":0,"cache":{"read":0,"write":0}}}`)
+	writeTestFile(t, filepath.Join(storage, "part", "msg_002", "prt_001.json"), `{"id":"prt_001","type":"tool","tool":"bash"}`)
+	writeTestFile(t, filepath.Join(storage, "part", "msg_002", "prt_002.json"), `{"id":"prt_002","type":"text","text":"done"}`)
+	writeTestFile(t, filepath.Join(storage, "part", "msg_003", "prt_003.json"), `{"id":"prt_003","type":"tool","tool":"edit"}`)
+	writeTestFile(t, filepath.Join(storage, "part", "msg_003", "prt_004.json"), `{"id":"prt_004","type":"tool","tool":"bash"}`)
+
+	a, err := parseOpenCodeSession(dir, sesID)
+	if err != nil {
+		t.Fatalf("parseOpenCodeSession: %v", err)
+	}
+	if a.TotalTurns != 3 {
+		t.Errorf("TotalTurns = %d, want 3", a.TotalTurns)
+	}
+	if a.InputTokens != 1600 || a.OutputTokens != 1100 || a.CacheReadTokens != 17500 || a.CacheWriteTokens != 500 {
+		t.Errorf("tokens = %+v", a)
+	}
+	if a.CurrentContextTokens != 9900 {
+		t.Errorf("CurrentContextTokens = %d, want 9900", a.CurrentContextTokens)
+	}
+	if a.Model != "claude-sonnet-4-20250514" {
+		t.Errorf("Model = %q", a.Model)
+	}
+	if a.EstimatedCost < 0.0499 || a.EstimatedCost > 0.0501 {
+		t.Errorf("EstimatedCost = %f, want OpenCode's reported 0.05", a.EstimatedCost)
+	}
+	counts := map[string]int{}
+	for _, tc := range a.ToolCalls {
+		counts[tc.Name] = tc.Count
+	}
+	if counts["bash"] != 2 || counts["edit"] != 1 || len(counts) != 2 {
+		t.Errorf("ToolCalls = %v", a.ToolCalls)
+	}
+	if a.Duration.Seconds() != 69 {
+		t.Errorf("Duration = %v, want 69s", a.Duration)
+	}
+}
+
+func TestParseOpenCodeSessionEstimatesCost(t *testing.T) {
+	dir := t.TempDir()
+	sesID := "ses_free"
+	writeTestFile(t, filepath.Join(dir, "storage", "message", sesID, "msg_001.json"),
+		`{"id":"msg_001","role":"assistant","modelID":"gpt-5","time":{"created":1760781601000},"cost":0,"tokens":{"input":1000000,"output":0,"reasoning":0,"cache":{"read":0,"write":0}}}`)
+
+	a, err := parseOpenCodeSession(dir, sesID)
+	if err != nil {
+		t.Fatalf("parseOpenCodeSession: %v", err)
+	}
+	if a.EstimatedCost != 1.25 {
+		t.Errorf("EstimatedCost = %f, want 1.25 (gpt-5 input pricing)", a.EstimatedCost)
+	}
+	if _, err := parseOpenCodeSession(dir, "ses_missing"); err == nil {
+		t.Error("expected error for missing session")
+	}
+}
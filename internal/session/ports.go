@@ -0,0 +1,127 @@
+package session
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+)
+
+// PortEnvPrefix names the per-slot port variables: AGENTDECK_PORT_1..N.
+// PORT is always the first leased port.
+const PortEnvPrefix = "AGENTDECK_PORT_"
+
+// GetPorts returns the ports leased to this session, in slot order.
+func (i *Instance) GetPorts() []int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.ports
+}
+
+// SetPorts records the ports leased to this session.
+func (i *Instance) SetPorts(ports []int) {
+	i.mu.Lock()
+	i.ports = ports
+	i.mu.Unlock()
+}
+
+// wantsPorts reports whether [ports] allocates for this session.
+func (i *Instance) wantsPorts(settings PortSettings) bool {
+	return settings.Enabled && (settings.AllSessions || i.IsWorktree())
+}
+
+// leasePorts reserves the session's ports in the state database, reusing an
+// existing lease, and stores them on the instance. Failures are logged and
+// leave the session without ports rather than blocking its start.
+func (i *Instance) leasePorts() []int {
+	settings := GetPortSettings()
+	if !i.wantsPorts(settings) {
+		return nil
+	}
+	db := statedb.GetGlobal()
+	if db == nil {
+		return i.GetPorts()
+	}
+	ports, err := db.LeasePorts(i.ID, settings.PerSession, settings.RangeStart, settings.RangeEnd, isPortFree)
+	if err != nil {
+		sessionLog.Warn("port_lease_failed",
+			slog.String("instance_id", i.ID),
+			slog.String("error", err.Error()))
+		return nil
+	}
+	i.SetPorts(ports)
+	return ports
+}
+
+// getPortEnv returns shell exports for the session's leased ports:
+// PORT and AGENTDECK_PORT_1..N. Returns empty string when none are leased.
+func (i *Instance) getPortEnv() string {
+	return portExports(i.leasePorts())
+}
+
+// rawCommandWithPorts returns the command for a session without a tool
+// definition, which gets no env files. Leased ports are still provided: a
+// raw command gets the exports prefixed, a plain shell gets them in its tmux
+// environment.
+func (i *Instance) rawCommandWithPorts() string {
+	ports := i.leasePorts()
+	if len(ports) == 0 {
+		return i.Command
+	}
+	if i.Command != "" {
+		return portExports(ports) + " && " + i.Command
+	}
+	if i.tmuxSession != nil {
+		i.tmuxSession.Environment = portEnvVars(ports)
+	}
+	return ""
+}
+
+// portEnvVars returns the port variables as KEY=VALUE pairs.
+func portEnvVars(ports []int) []string {
+	if len(ports) == 0 {
+		return nil
+	}
+	vars := []string{fmt.Sprintf("PORT=%d", ports[0])}
+	for n, port := range ports {
+		vars = append(vars, fmt.Sprintf("%s%d=%d", PortEnvPrefix, n+1, port))
+	}
+	return vars
+}
+
+// portExports formats ports as && joined export commands.
+func portExports(ports []int) string {
+	if len(ports) == 0 {
+		return ""
+	}
+	exports := []string{fmt.Sprintf("export PORT='%d'", ports[0])}
+	for n, port := range ports {
+		exports = append(exports, fmt.Sprintf("export %s%d='%d'", PortEnvPrefix, n+1, port))
+	}
+	return strings.Join(exports, " && ")
+}
+
+// isPortFree reports whether nothing is listening on the port locally.
+func isPortFree(port int) bool {
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	_ = ln.Close()
+	return true
+}
+
+// PortListening reports whether something accepts connections on the port
+// locally (typically the session's dev server).
+func PortListening(port int) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), 200*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
@@ -0,0 +1,85 @@
+package session
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+)
+
+// setupPortTest writes config to a temp HOME and installs a fresh global
+// state database.
+func setupPortTest(t *testing.T, config string) *statedb.StateDB {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".agent-deck"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".agent-deck", "config.toml"), []byte(config), 0o644))
+	ClearUserConfigCache()
+	t.Cleanup(ClearUserConfigCache)
+
+	db, err := statedb.Open(filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.Migrate())
+	prev := statedb.GetGlobal()
+	statedb.SetGlobal(db)
+	t.Cleanup(func() {
+		statedb.SetGlobal(prev)
+		db.Close()
+	})
+	return db
+}
+
+func TestPortExports(t *testing.T) {
+	assert.Equal(t, "", portExports(nil))
+	assert.Equal(t,
+		"export PORT='4100' && export AGENTDECK_PORT_1='4100' && export AGENTDECK_PORT_2='4102'",
+		portExports([]int{4100, 4102}))
+	assert.Equal(t, []string{"PORT=4100", "AGENTDECK_PORT_1=4100"}, portEnvVars([]int{4100}))
+}
+
+func TestGetPortEnv_LeasesWorktreeSessions(t *testing.T) {
+	// Occupy a port in the range so allocation has to skip it
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	busy := ln.Addr().(*net.TCPAddr).Port
+	if busy >= 65534 {
+		t.Skip("listener port too close to the top of the range")
+	}
+	db := setupPortTest(t, "[ports]\nenabled = true\nrange_start = "+strconv.Itoa(busy)+
+		"\nrange_end = "+strconv.Itoa(busy+20)+"\nper_session = 2\n")
+
+	plain := &Instance{ID: "plain", Tool: "shell"}
+	assert.Empty(t, plain.getPortEnv(), "non-worktree sessions get no ports by default")
+
+	wt := &Instance{ID: "wt", Tool: "shell", WorktreePath: "/tmp/wt"}
+	env := wt.getPortEnv()
+	ports := wt.GetPorts()
+	require.Len(t, ports, 2)
+	assert.NotContains(t, ports, busy, "a port with a listener is never leased")
+	assert.True(t, strings.HasPrefix(env, "export PORT='"+strconv.Itoa(ports[0])+"'"), env)
+	assert.Contains(t, env, "AGENTDECK_PORT_2='"+strconv.Itoa(ports[1])+"'")
+	assert.Equal(t, env, wt.getPortEnv(), "restarts keep the same ports")
+
+	leases, err := db.LoadPortLeases()
+	require.NoError(t, err)
+	assert.Equal(t, ports, leases["wt"])
+	assert.True(t, PortListening(busy))
+}
+
+func TestGetPortSettings_Defaults(t *testing.T) {
+	setupPortTest(t, "[ports]\nenabled = true\n")
+	s := GetPortSettings()
+	assert.Equal(t, DefaultPortRangeStart, s.RangeStart)
+	assert.Equal(t, DefaultPortRangeEnd, s.RangeEnd)
+	assert.Equal(t, DefaultPortsPerSession, s.PerSession)
+	assert.False(t, s.AllSessions)
+}
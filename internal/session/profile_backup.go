@@ -0,0 +1,271 @@
+package session
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+)
+
+const (
+	// profileBackupsDirName is the per-profile directory holding state.db
+	// snapshots.
+	profileBackupsDirName = "backups"
+	// profileBackupPrefix and profileBackupTimeFormat name snapshots
+	// state-20060102-150405.db so they sort chronologically.
+	profileBackupPrefix     = "state-"
+	profileBackupTimeFormat = "20060102-150405"
+	// DefaultBackupRetention is how many snapshots are kept per profile when
+	// maintenance.backup_retention is unset.
+	DefaultBackupRetention = 7
+)
+
+// ProfileBackup describes one state.db snapshot in a profile's backups dir.
+type ProfileBackup struct {
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+}
+
+// GetProfileBackupsDir returns ~/.agent-deck/profiles/<profile>/backups.
+func GetProfileBackupsDir(profile string) (string, error) {
+	profileDir, err := GetProfileDir(profile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(profileDir, profileBackupsDirName), nil
+}
+
+// BackupProfile snapshots a profile's state.db with SQLite's online backup
+// API, so it is safe while the TUI is running. With dstPath empty the
+// snapshot goes to the profile's backups dir under a timestamped name.
+// Returns the path written.
+func BackupProfile(profile, dstPath string) (string, error) {
+	dbPath, err := GetDBPathForProfile(profile)
+	if err != nil {
+		return "", err
+	}
+	if dstPath == "" {
+		dir, err := GetProfileBackupsDir(profile)
+		if err != nil {
+			return "", err
+		}
+		dstPath = nextBackupPath(dir, time.Now(), "")
+	}
+	if err := backupStateDB(dbPath, dstPath); err != nil {
+		return "", err
+	}
+	return dstPath, nil
+}
+
+// RestoreProfile replaces a profile's state with the snapshot at srcPath.
+// The snapshot's schema version must not be newer than this binary's. The
+// current state is first saved as a "pre-restore" snapshot in the backups
+// dir, whose path is returned (empty when the profile had no state yet).
+// Running TUIs pick up the restored state through the usual change
+// detection.
+func RestoreProfile(profile, srcPath string) (string, error) {
+	version, err := statedb.ReadSchemaVersion(srcPath)
+	if err != nil {
+		return "", err
+	}
+	if version > statedb.SchemaVersion {
+		return "", &statedb.SchemaTooNewError{Version: version, Supported: statedb.SchemaVersion}
+	}
+
+	dbPath, err := GetDBPathForProfile(profile)
+	if err != nil {
+		return "", err
+	}
+
+	var safetyPath string
+	if _, err := os.Stat(dbPath); err == nil {
+		dir, err := GetProfileBackupsDir(profile)
+		if err != nil {
+			return "", err
+		}
+		safetyPath = nextBackupPath(dir, time.Now(), "pre-restore")
+		if err := backupStateDB(dbPath, safetyPath); err != nil {
+			return "", fmt.Errorf("failed to save current state before restore: %w", err)
+		}
+	}
+
+	db, err := statedb.Open(dbPath)
+	if err != nil {
+		return safetyPath, err
+	}
+	defer db.Close()
+	if err := db.Restore(srcPath); err != nil {
+		return safetyPath, err
+	}
+	storageLog.Info("profile_restored",
+		slog.String("profile", profile),
+		slog.String("source", srcPath),
+		slog.String("safety_backup", safetyPath))
+	return safetyPath, nil
+}
+
+// RunningProfileInstances returns how many TUIs currently have the
+// profile's state.db open.
+func RunningProfileInstances(profile string) (int, error) {
+	dbPath, err := GetDBPathForProfile(profile)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		return 0, nil
+	}
+	db, err := statedb.OpenReadOnly(dbPath)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	return db.AliveInstanceCount()
+}
+
+// DumpStateDB reads instances, groups and metadata from a state.db or
+// snapshot without modifying it.
+func DumpStateDB(path string) (*statedb.Dump, error) {
+	db, err := statedb.OpenReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return db.Dump()
+}
+
+// ListProfileBackups returns a profile's snapshots, newest first.
+func ListProfileBackups(profile string) ([]ProfileBackup, error) {
+	dir, err := GetProfileBackupsDir(profile)
+	if err != nil {
+		return nil, err
+	}
+	return listBackupsIn(dir)
+}
+
+// PruneProfileBackups deletes all but the newest keep snapshots.
+func PruneProfileBackups(profile string, keep int) (int, error) {
+	dir, err := GetProfileBackupsDir(profile)
+	if err != nil {
+		return 0, err
+	}
+	return pruneBackupsIn(dir, keep), nil
+}
+
+// backupStateDB snapshots the database at dbPath into dstPath.
+func backupStateDB(dbPath, dstPath string) error {
+	if _, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("no state database at %s", dbPath)
+	}
+	db, err := statedb.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Backup(dstPath)
+}
+
+// nextBackupPath returns an unused snapshot path in dir for time t, with an
+// optional label (e.g. "pre-restore") after the timestamp.
+func nextBackupPath(dir string, t time.Time, label string) string {
+	base := profileBackupPrefix + t.Format(profileBackupTimeFormat)
+	if label != "" {
+		base += "-" + label
+	}
+	path := filepath.Join(dir, base+".db")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.db", base, i))
+	}
+}
+
+// listBackupsIn returns the snapshots in dir, newest first. The creation
+// time comes from the file name, falling back to the modification time.
+func listBackupsIn(dir string) ([]ProfileBackup, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []ProfileBackup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, profileBackupPrefix) || !strings.HasSuffix(name, ".db") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		b := ProfileBackup{Path: filepath.Join(dir, name), CreatedAt: info.ModTime(), Size: info.Size()}
+		stamp := strings.TrimPrefix(name, profileBackupPrefix)
+		if len(stamp) >= len(profileBackupTimeFormat) {
+			if t, err := time.ParseInLocation(profileBackupTimeFormat, stamp[:len(profileBackupTimeFormat)], time.Local); err == nil {
+				b.CreatedAt = t
+			}
+		}
+		backups = append(backups, b)
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		if !backups[i].CreatedAt.Equal(backups[j].CreatedAt) {
+			return backups[i].CreatedAt.After(backups[j].CreatedAt)
+		}
+		return backups[i].Path > backups[j].Path
+	})
+	return backups, nil
+}
+
+// pruneBackupsIn keeps the newest keep snapshots in dir and deletes the rest.
+func pruneBackupsIn(dir string, keep int) int {
+	if keep < 1 {
+		keep = 1
+	}
+	backups, err := listBackupsIn(dir)
+	if err != nil || len(backups) <= keep {
+		return 0
+	}
+	pruned := 0
+	for _, b := range backups[keep:] {
+		if err := os.Remove(b.Path); err != nil {
+			maintLog.Warn("maintenance_backup_remove_failed", slog.String("path", b.Path), slog.String("error", err.Error()))
+			continue
+		}
+		pruned++
+	}
+	return pruned
+}
+
+// backupProfiles snapshots every profile under profilesDir whose newest
+// snapshot is older than interval, then applies retention. Returns the
+// number of snapshots taken and pruned.
+func backupProfiles(profilesDir string, interval time.Duration, keep int, now time.Time) (created, pruned int) {
+	dbPaths, err := filepath.Glob(filepath.Join(profilesDir, "*", "state.db"))
+	if err != nil {
+		maintLog.Warn("profile_backups_glob_error", slog.String("error", err.Error()))
+		return 0, 0
+	}
+	for _, dbPath := range dbPaths {
+		dir := filepath.Join(filepath.Dir(dbPath), profileBackupsDirName)
+		backups, _ := listBackupsIn(dir)
+		if len(backups) > 0 && now.Sub(backups[0].CreatedAt) < interval {
+			continue
+		}
+		if err := backupStateDB(dbPath, nextBackupPath(dir, now, "")); err != nil {
+			maintLog.Warn("profile_backup_failed", slog.String("path", dbPath), slog.String("error", err.Error()))
+			continue
+		}
+		created++
+		pruned += pruneBackupsIn(dir, keep)
+	}
+	return created, pruned
+}
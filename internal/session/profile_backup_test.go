@@ -0,0 +1,97 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newProfileDB creates profiles/<name>/state.db under profilesDir with one
+// session.
+func newProfileDB(t *testing.T, profilesDir, name, sessionID string) string {
+	t.Helper()
+	dbPath := filepath.Join(profilesDir, name, "state.db")
+	db, err := statedb.Open(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, db.Migrate())
+	require.NoError(t, db.SaveInstance(&statedb.InstanceRow{ID: sessionID, Title: sessionID, ProjectPath: "/p", GroupPath: "g", Tool: "shell", CreatedAt: time.Now()}))
+	require.NoError(t, db.Close())
+	return dbPath
+}
+
+func TestBackupProfiles_IntervalAndRetention(t *testing.T) {
+	profilesDir := t.TempDir()
+	newProfileDB(t, profilesDir, "default", "s1")
+	newProfileDB(t, profilesDir, "work", "s2")
+	backupsDir := filepath.Join(profilesDir, "default", "backups")
+
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.Local)
+	created, pruned := backupProfiles(profilesDir, 24*time.Hour, 2, now)
+	assert.Equal(t, 2, created)
+	assert.Equal(t, 0, pruned)
+
+	// Within the interval nothing new is taken
+	created, _ = backupProfiles(profilesDir, 24*time.Hour, 2, now.Add(time.Hour))
+	assert.Equal(t, 0, created)
+
+	// Each later run adds one and retention keeps the newest two
+	backupProfiles(profilesDir, 24*time.Hour, 2, now.Add(25*time.Hour))
+	created, pruned = backupProfiles(profilesDir, 24*time.Hour, 2, now.Add(50*time.Hour))
+	assert.Equal(t, 2, created)
+	assert.Equal(t, 2, pruned)
+
+	backups, err := listBackupsIn(backupsDir)
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	assert.Equal(t, now.Add(50*time.Hour), backups[0].CreatedAt)
+	assert.Equal(t, now.Add(25*time.Hour), backups[1].CreatedAt)
+
+	d, err := DumpStateDB(backups[0].Path)
+	require.NoError(t, err)
+	require.Len(t, d.Instances, 1)
+	assert.Equal(t, "s1", d.Instances[0].ID)
+}
+
+func TestBackupRestoreProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	ClearUserConfigCache()
+	t.Cleanup(ClearUserConfigCache)
+
+	profilesDir, err := GetProfilesDir()
+	require.NoError(t, err)
+	dbPath := newProfileDB(t, profilesDir, "demo", "before")
+
+	snap, err := BackupProfile("demo", "")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(profilesDir, "demo", "backups"), filepath.Dir(snap))
+
+	// Replace the state, then roll back
+	require.NoError(t, os.Remove(dbPath))
+	newProfileDB(t, profilesDir, "demo", "after")
+
+	safety, err := RestoreProfile("demo", snap)
+	require.NoError(t, err)
+	assert.Contains(t, filepath.Base(safety), "pre-restore")
+
+	d, err := DumpStateDB(dbPath)
+	require.NoError(t, err)
+	require.Len(t, d.Instances, 1)
+	assert.Equal(t, "before", d.Instances[0].ID)
+
+	d, err = DumpStateDB(safety)
+	require.NoError(t, err)
+	assert.Equal(t, "after", d.Instances[0].ID, "pre-restore snapshot holds the replaced state")
+
+	backups, err := ListProfileBackups("demo")
+	require.NoError(t, err)
+	assert.Len(t, backups, 2)
+
+	_, err = RestoreProfile("demo", filepath.Join(home, "nope.db"))
+	assert.Error(t, err)
+}
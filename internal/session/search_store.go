@@ -0,0 +1,466 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/searchdb"
+	"golang.org/x/time/rate"
+)
+
+// SearchIndexFileName is the persistent search index under ~/.agent-deck
+const SearchIndexFileName = "search.db"
+
+// searchIndexBatchSize is the number of messages committed per transaction
+// while indexing. Offsets are committed with each batch so a large file that
+// is interrupted mid-way resumes where it stopped.
+const searchIndexBatchSize = 500
+
+// GetSearchIndexPath returns the path to the persistent search index.
+func GetSearchIndexPath() (string, error) {
+	dir, err := GetAgentDeckDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, SearchIndexFileName), nil
+}
+
+// OpenSearchStore opens (creating if needed) the persistent search index.
+func OpenSearchStore() (*searchdb.SearchDB, error) {
+	path, err := GetSearchIndexPath()
+	if err != nil {
+		return nil, err
+	}
+	store, err := searchdb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Migrate(); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// IndexTranscriptFile brings the index up to date for one transcript file.
+// JSONL transcripts are read incrementally: only bytes after the recorded
+// offset are parsed, a file that shrank is re-indexed from scratch, and
+// incomplete trailing lines are left for the next pass. Other transcripts are
+// re-parsed whole whenever their size or modification time changes.
+// Returns the number of messages added.
+func IndexTranscriptFile(store *searchdb.SearchDB, src TranscriptSource, path string) (int, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, store.ResetFile(path)
+		}
+		return 0, err
+	}
+
+	row, err := store.GetFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	lineSrc, ok := src.(lineTranscriptSource)
+	if !ok {
+		return indexWholeTranscript(store, src, path, info, row)
+	}
+
+	if row != nil && info.Size() < row.Offset {
+		// Truncated or replaced: start over
+		if err := store.ResetFile(path); err != nil {
+			return 0, err
+		}
+		row = nil
+	}
+	if row == nil {
+		row = &searchdb.FileRow{Path: path, Tool: src.Tool(), SessionID: lineSrc.sessionIDFromPath(path)}
+	}
+	if info.Size() == row.Offset {
+		return 0, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(row.Offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	t := &Transcript{Tool: row.Tool, SessionID: row.SessionID, CWD: row.Project, Summary: row.Summary}
+	reader := bufio.NewReaderSize(f, 64*1024)
+	var batch []searchdb.MessageRow
+	added := 0
+	offset := row.Offset
+
+	commit := func() error {
+		row.SessionID, row.Project, row.Summary = t.SessionID, t.CWD, t.Summary
+		row.Offset = offset
+		row.Size = info.Size()
+		row.ModTime = info.ModTime()
+		if err := store.AppendMessages(row, batch); err != nil {
+			return err
+		}
+		added += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			// EOF with a partial line: leave it for the next pass
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return added, err
+		}
+		offset += int64(len(line))
+
+		if msg, ok := lineSrc.parseLine(t, line); ok {
+			batch = append(batch, messageRow(msg))
+		}
+		if len(batch) >= searchIndexBatchSize {
+			if err := commit(); err != nil {
+				return added, err
+			}
+		}
+	}
+
+	if err := commit(); err != nil {
+		return added, err
+	}
+	return added, nil
+}
+
+// indexWholeTranscript re-indexes a transcript that is rewritten in place
+// (Gemini chats, OpenCode sessions).
+func indexWholeTranscript(store *searchdb.SearchDB, src TranscriptSource, path string, info os.FileInfo, row *searchdb.FileRow) (int, error) {
+	if row != nil && row.Size == info.Size() && row.ModTime.Equal(info.ModTime()) {
+		return 0, nil
+	}
+	t, err := src.Parse(path)
+	if err != nil {
+		return 0, err
+	}
+	msgs := make([]searchdb.MessageRow, len(t.Messages))
+	for i, m := range t.Messages {
+		msgs[i] = messageRow(m)
+	}
+	err = store.ReplaceFile(&searchdb.FileRow{
+		Path:      path,
+		Tool:      src.Tool(),
+		SessionID: t.SessionID,
+		Project:   t.CWD,
+		Summary:   t.Summary,
+		Offset:    info.Size(),
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+	}, msgs)
+	if err != nil {
+		return 0, err
+	}
+	return len(msgs), nil
+}
+
+func messageRow(m TranscriptMessage) searchdb.MessageRow {
+	return searchdb.MessageRow{Role: m.Role, Timestamp: m.Timestamp, Content: m.Text}
+}
+
+// SyncSearchStore indexes every transcript from sources that changed since
+// the last sync. Files older than recentDays (0 = all) are skipped.
+// limiter (optional) throttles how many files are indexed per second.
+// Returns the number of files that had new content.
+func SyncSearchStore(ctx context.Context, store *searchdb.SearchDB, sources []TranscriptSource, recentDays int, limiter *rate.Limiter) (int, error) {
+	cutoff := time.Time{}
+	if recentDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -recentDays)
+	}
+
+	known := make(map[string]*searchdb.FileRow)
+	if rows, err := store.ListFiles(); err == nil {
+		for _, r := range rows {
+			known[r.Path] = r
+		}
+	}
+
+	updated := 0
+	for _, src := range sources {
+		_, isLines := src.(lineTranscriptSource)
+		err := walkTranscripts(src, func(path string, d os.DirEntry) error {
+			if ctx.Err() != nil {
+				return filepath.SkipAll
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if !cutoff.IsZero() && info.ModTime().Before(cutoff) {
+				return nil
+			}
+			if r, ok := known[path]; ok && r.Offset == info.Size() && (isLines || r.ModTime.Equal(info.ModTime())) {
+				return nil // Up to date
+			}
+
+			if limiter != nil {
+				if err := limiter.Wait(ctx); err != nil {
+					return filepath.SkipAll
+				}
+			}
+			n, err := IndexTranscriptFile(store, src, path)
+			if err != nil {
+				searchLog.Warn("search_index_file_failed",
+					slog.String("tool", src.Tool()),
+					slog.String("path", path),
+					slog.String("error", err.Error()))
+				return nil
+			}
+			if n > 0 {
+				updated++
+			}
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return updated, err
+		}
+	}
+	return updated, ctx.Err()
+}
+
+// StoreSearchResult is a session-level result from the persistent index.
+type StoreSearchResult struct {
+	Tool          string    `json:"tool"`
+	SessionID     string    `json:"session_id"`
+	FilePath      string    `json:"file"`
+	Project       string    `json:"project"`
+	Summary       string    `json:"summary,omitempty"`
+	Role          string    `json:"role"`      // Role of the best-matching message
+	Timestamp     time.Time `json:"timestamp"` // Time of the best-matching message
+	Snippet       string    `json:"snippet"`
+	Hits          int       `json:"hits"` // Matching messages in this session
+	Rank          float64   `json:"rank"` // bm25 of the best match (lower is better)
+	BestMessageID int64     `json:"-"`
+}
+
+// SearchStore runs q against the persistent index and groups matching messages
+// by session, best match first. limit caps the number of sessions (0 = no cap).
+func SearchStore(store *searchdb.SearchDB, q searchdb.Query, limit int) ([]*StoreSearchResult, error) {
+	if q.Limit == 0 {
+		q.Limit = 2000
+	}
+	hits, err := store.Search(q)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[string]string)
+	if len(hits) > 0 {
+		if rows, err := store.ListFiles(); err == nil {
+			for _, r := range rows {
+				summaries[r.Path] = r.Summary
+			}
+		}
+	}
+
+	byFile := make(map[string]*StoreSearchResult)
+	var results []*StoreSearchResult
+	for _, h := range hits {
+		if r, ok := byFile[h.FilePath]; ok {
+			r.Hits++
+			continue
+		}
+		r := &StoreSearchResult{
+			Tool:          h.Tool,
+			SessionID:     h.SessionID,
+			FilePath:      h.FilePath,
+			Project:       h.Project,
+			Summary:       summaries[h.FilePath],
+			Role:          h.Role,
+			Timestamp:     h.Timestamp,
+			Snippet:       h.Snippet,
+			Hits:          1,
+			Rank:          h.Rank,
+			BestMessageID: h.MessageID,
+		}
+		byFile[h.FilePath] = r
+		results = append(results, r)
+	}
+
+	// Hits arrive ordered by rank, so results are already best-first.
+	// Sort stably by rank in case equal ranks came from different files.
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank < results[j].Rank })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// storePreviewRadius is how many messages either side of the best hit are
+// loaded for previews.
+const storePreviewRadius = 20
+
+// storePreviewContent formats the messages around a result's best hit in the
+// same "User: / Assistant:" layout used by the in-memory tiers.
+func storePreviewContent(store *searchdb.SearchDB, r *StoreSearchResult) string {
+	msgs, err := store.MessagesAround(r.FilePath, r.BestMessageID, storePreviewRadius)
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, m := range msgs {
+		b.WriteString(formatTranscriptMessage(TranscriptMessage{Role: m.Role, Text: m.Content}))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// persistentLoad catches the persistent index up with transcripts changed since
+// the last run. Unlike the in-memory tiers nothing is re-read on startup: files
+// whose size matches their recorded offset are skipped.
+func (idx *GlobalSearchIndex) persistentLoad() {
+	defer idx.loading.Store(false)
+
+	idx.refreshStoreEntries()
+	n, err := SyncSearchStore(idx.ctx, idx.store, idx.sources, idx.config.RecentDays, idx.limiter)
+	if err != nil && idx.ctx.Err() == nil {
+		searchLog.Warn("search_store_sync_failed", slog.String("error", err.Error()))
+	}
+	if n > 0 {
+		searchLog.Info("search_store_synced", slog.Int("files", n))
+	}
+	idx.refreshStoreEntries()
+}
+
+// refreshStoreEntries rebuilds the metadata-only entry list from the index.
+func (idx *GlobalSearchIndex) refreshStoreEntries() {
+	rows, err := idx.store.ListFiles()
+	if err != nil {
+		return
+	}
+	entries := make([]SearchEntry, 0, len(rows))
+	for _, r := range rows {
+		entries = append(entries, storeEntry(r))
+	}
+	idx.entries.Store(&entries)
+}
+
+// updateFilePersistent indexes new bytes of a changed file (called from watcherLoop).
+func (idx *GlobalSearchIndex) updateFilePersistent(src TranscriptSource, path string) {
+	if _, err := IndexTranscriptFile(idx.store, src, path); err != nil {
+		searchLog.Warn("search_index_file_failed",
+			slog.String("path", path),
+			slog.String("error", err.Error()))
+		return
+	}
+	row, err := idx.store.GetFile(path)
+	if err != nil || row == nil {
+		return
+	}
+
+	oldEntries := idx.entries.Load()
+	newEntries := make([]SearchEntry, 0, len(*oldEntries)+1)
+	found := false
+	for _, e := range *oldEntries {
+		if e.FilePath == path {
+			newEntries = append(newEntries, storeEntry(row))
+			found = true
+		} else {
+			newEntries = append(newEntries, e)
+		}
+	}
+	if !found {
+		newEntries = append(newEntries, storeEntry(row))
+	}
+	idx.entries.Store(&newEntries)
+}
+
+// searchPersistent queries the FTS5 index. The query may contain inline
+// filters (project:, role:, tool:, since:, until:) and FTS syntax (see searchdb.BuildMatch).
+func (idx *GlobalSearchIndex) searchPersistent(query string) []*SearchResult {
+	q, err := searchdb.ParseQuery(query)
+	if err != nil {
+		return nil
+	}
+	found, err := SearchStore(idx.store, q, persistentResultLimit)
+	if err != nil {
+		searchLog.Debug("search_store_query_failed", slog.String("error", err.Error()))
+		return nil
+	}
+
+	results := make([]*SearchResult, 0, len(found))
+	for i, r := range found {
+		entry := &SearchEntry{
+			Tool:      r.Tool,
+			SessionID: r.SessionID,
+			FilePath:  r.FilePath,
+			CWD:       r.Project,
+			Summary:   r.Summary,
+			ModTime:   r.Timestamp,
+		}
+		// Previews are only shown for the top results; load context for those
+		if i < persistentPreviewLimit {
+			entry.setContent([]byte(storePreviewContent(idx.store, r)))
+		}
+		results = append(results, &SearchResult{
+			Entry:   entry,
+			Score:   r.Hits*10 + int(-r.Rank*10),
+			Snippet: StripSnippetMarkers(r.Snippet),
+			Hits:    r.Hits,
+		})
+	}
+	return results
+}
+
+const (
+	persistentResultLimit  = 50
+	persistentPreviewLimit = 15
+)
+
+func storeEntry(r *searchdb.FileRow) SearchEntry {
+	return SearchEntry{
+		Tool:      r.Tool,
+		SessionID: r.SessionID,
+		FilePath:  r.Path,
+		CWD:       r.Project,
+		Summary:   r.Summary,
+		ModTime:   r.ModTime,
+		FileSize:  r.Size,
+	}
+}
+
+// StripSnippetMarkers removes the highlight markers from a persistent-index snippet.
+func StripSnippetMarkers(snippet string) string {
+	snippet = strings.ReplaceAll(snippet, searchdb.SnippetOpen, "")
+	return strings.ReplaceAll(snippet, searchdb.SnippetClose, "")
+}
+
+// SearchHighlightTerm returns the first plain search term in a query, with
+// filters and FTS syntax removed, for highlighting matches in previews.
+func SearchHighlightTerm(query string) string {
+	q, err := searchdb.ParseQuery(query)
+	if err != nil {
+		return ""
+	}
+	for _, tok := range strings.Fields(q.Text) {
+		switch tok {
+		case "AND", "OR", "NOT", "(", ")":
+			continue
+		}
+		tok = strings.Trim(tok, `"()*`)
+		if tok != "" {
+			return tok
+		}
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asheshgoplani/agent-deck/internal/searchdb"
+)
+
+func newTestSearchStore(t *testing.T) *searchdb.SearchDB {
+	t.Helper()
+	store, err := searchdb.Open(filepath.Join(t.TempDir(), "search.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestIndexTranscriptFileIncremental(t *testing.T) {
+	store := newTestSearchStore(t)
+	path := filepath.Join(t.TempDir(), "a1b2c3d4-e5f6-7890-abcd-ef1234567890.jsonl")
+
+	appendFile(t, path, `{"sessionId":"s1","cwd":"/home/me/api","message":{"role":"user","content":"deploy the api"},"timestamp":"2025-01-15T10:00:00Z"}`+"\n")
+	n, err := IndexTranscriptFile(store, claudeTranscriptSource{}, path)
+	if err != nil || n != 1 {
+		t.Fatalf("first pass: n=%d err=%v", n, err)
+	}
+
+	// A partial trailing line is not consumed until it is complete
+	partial := `{"sessionId":"s1","message":{"role":"assistant","content":"deployed to staging"}}`
+	appendFile(t, path, partial)
+	if n, _ := IndexTranscriptFile(store, claudeTranscriptSource{}, path); n != 0 {
+		t.Errorf("partial line indexed: n=%d", n)
+	}
+	appendFile(t, path, "\n")
+	if n, _ := IndexTranscriptFile(store, claudeTranscriptSource{}, path); n != 1 {
+		t.Errorf("completed line: n=%d, want 1", n)
+	}
+
+	row, err := store.GetFile(path)
+	if err != nil || row == nil {
+		t.Fatalf("GetFile: %v %v", row, err)
+	}
+	info, _ := os.Stat(path)
+	if row.Offset != info.Size() || row.SessionID != "s1" || row.Project != "/home/me/api" {
+		t.Errorf("file row = %+v (size %d)", row, info.Size())
+	}
+
+	// Truncation re-indexes from scratch
+	if err := os.WriteFile(path, []byte(`{"sessionId":"s1","message":{"role":"user","content":"rollback"}}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := IndexTranscriptFile(store, claudeTranscriptSource{}, path); n != 1 {
+		t.Errorf("after truncation: n=%d, want 1", n)
+	}
+	if hits, _ := store.Search(searchdb.Query{Text: "deploy*"}); len(hits) != 0 {
+		t.Errorf("stale messages remain after truncation: %d", len(hits))
+	}
+}
+
+func TestSyncAndSearchStore(t *testing.T) {
+	store := newTestSearchStore(t)
+	claudeDir := t.TempDir()
+	projectDir := filepath.Join(claudeDir, "projects", "-home-me-api")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, filepath.Join(projectDir, "a1b2c3d4-e5f6-7890-abcd-ef1234567890.jsonl"),
+		`{"sessionId":"s1","cwd":"/home/me/api","message":{"role":"user","content":"the websocket drops"}}`+"\n"+
+			`{"sessionId":"s1","message":{"role":"assistant","content":"the websocket ping interval is too long"}}`+"\n")
+	appendFile(t, filepath.Join(projectDir, "b2c3d4e5-f6a7-8901-bcde-f23456789012.jsonl"),
+		`{"sessionId":"s2","cwd":"/home/me/api","message":{"role":"user","content":"add a websocket test"}}`+"\n")
+
+	updated, err := SyncSearchStore(context.Background(), store, []TranscriptSource{claudeTranscriptSource{dir: claudeDir}}, 0, nil)
+	if err != nil || updated != 2 {
+		t.Fatalf("SyncSearchStore: updated=%d err=%v", updated, err)
+	}
+	if updated, _ := SyncSearchStore(context.Background(), store, []TranscriptSource{claudeTranscriptSource{dir: claudeDir}}, 0, nil); updated != 0 {
+		t.Errorf("second sync re-indexed %d files", updated)
+	}
+
+	results, err := SearchStore(store, searchdb.Query{Text: "websocket"}, 0)
+	if err != nil {
+		t.Fatalf("SearchStore: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(results))
+	}
+	hits := map[string]int{}
+	for _, r := range results {
+		hits[r.SessionID] = r.Hits
+	}
+	if hits["s1"] != 2 || hits["s2"] != 1 {
+		t.Errorf("hits per session = %v", hits)
+	}
+	if preview := storePreviewContent(store, results[0]); preview == "" {
+		t.Error("expected preview content")
+	}
+}
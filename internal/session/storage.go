@@ -79,6 +79,12 @@ type InstanceData struct {
 	// Tool-specific launch options (generic for all tools: claude, codex, etc.)
 	ToolOptionsJSON json.RawMessage `json:"tool_options,omitempty"`
 
+	// Automatic context-window policy override
+	ContextPolicy *ContextPolicy `json:"context_policy,omitempty"`
+
+	// Multi-repo workspace the session was created from
+	Workspace *WorkspaceState `json:"workspace,omitempty"`
+
 	// MCP tracking (persisted for sync status display)
 	LoadedMCPNames []string `json:"loaded_mcp_names,omitempty"`
 }
@@ -231,7 +237,8 @@ func (s *Storage) SaveWithGroups(instances []*Instance, groupTree *GroupTree) er
 			inst.OpenCodeSessionID, inst.OpenCodeDetectedAt,
 			inst.CodexSessionID, inst.CodexDetectedAt,
 			inst.LatestPrompt, inst.LoadedMCPNames,
-			inst.ToolOptionsJSON,
+			inst.ToolOptionsJSON, marshalContextPolicy(inst.ContextPolicy),
+			marshalWorkspace(inst.Workspace),
 		)
 
 		rows[i] = &statedb.InstanceRow{
@@ -371,7 +378,7 @@ func (s *Storage) LoadLite() ([]*InstanceData, []*GroupData, error) {
 			opencodeSID, opencodeAt,
 			codexSID, codexAt,
 			latestPrompt, loadedMCPs,
-			toolOpts := statedb.UnmarshalToolData(r.ToolData)
+			toolOpts, contextPolicy, workspace := statedb.UnmarshalToolData(r.ToolData)
 
 		instances[i] = &InstanceData{
 			ID:                 r.ID,
@@ -402,6 +409,8 @@ func (s *Storage) LoadLite() ([]*InstanceData, []*GroupData, error) {
 			CodexDetectedAt:    codexAt,
 			LatestPrompt:       latestPrompt,
 			ToolOptionsJSON:    toolOpts,
+			ContextPolicy:      unmarshalContextPolicy(contextPolicy),
+			Workspace:          unmarshalWorkspace(workspace),
 			LoadedMCPNames:     loadedMCPs,
 		}
 	}
@@ -453,7 +462,7 @@ func (s *Storage) LoadWithGroups() ([]*Instance, []*GroupData, error) {
 			opencodeSID, opencodeAt,
 			codexSID, codexAt,
 			latestPrompt, loadedMCPs,
-			toolOpts := statedb.UnmarshalToolData(r.ToolData)
+			toolOpts, contextPolicy, workspace := statedb.UnmarshalToolData(r.ToolData)
 
 		data.Instances[i] = &InstanceData{
 			ID:                 r.ID,
@@ -484,6 +493,8 @@ func (s *Storage) LoadWithGroups() ([]*Instance, []*GroupData, error) {
 			CodexDetectedAt:    codexAt,
 			LatestPrompt:       latestPrompt,
 			ToolOptionsJSON:    toolOpts,
+			ContextPolicy:      unmarshalContextPolicy(contextPolicy),
+			Workspace:          unmarshalWorkspace(workspace),
 			LoadedMCPNames:     loadedMCPs,
 		}
 	}
@@ -500,7 +511,23 @@ func (s *Storage) LoadWithGroups() ([]*Instance, []*GroupData, error) {
 		}
 	}
 
-	return s.convertToInstances(data)
+	instances, groups, err := s.convertToInstances(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Attach leased ports (informational; a failure leaves them unset)
+	if leases, err := s.db.LoadPortLeases(); err == nil {
+		for _, inst := range instances {
+			if ports, ok := leases[inst.ID]; ok {
+				inst.SetPorts(ports)
+			}
+		}
+	} else {
+		storageLog.Warn("load_port_leases_failed", slog.String("error", err.Error()))
+	}
+
+	return instances, groups, nil
 }
 
 // GetDBPathForProfile returns the path to the state.db file for a specific profile.
@@ -640,6 +667,8 @@ func (s *Storage) convertToInstances(data *StorageData) ([]*Instance, []*GroupDa
 			CodexSessionID:     instData.CodexSessionID,
 			CodexDetectedAt:    instData.CodexDetectedAt,
 			ToolOptionsJSON:    instData.ToolOptionsJSON,
+			ContextPolicy:      instData.ContextPolicy,
+			Workspace:          instData.Workspace,
 			LatestPrompt:       instData.LatestPrompt,
 			LoadedMCPNames:     instData.LoadedMCPNames,
 			tmuxSession:        tmuxSess,
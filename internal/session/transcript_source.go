@@ -0,0 +1,678 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Transcript is a tool-agnostic view of one conversation on disk.
+type Transcript struct {
+	Tool      string
+	SessionID string // Tool session ID (see GeminiCheckpointTag for Gemini checkpoints)
+	CWD       string
+	Summary   string
+	Messages  []TranscriptMessage
+}
+
+// TranscriptMessage is a single user or assistant message.
+type TranscriptMessage struct {
+	Role      string // "user" or "assistant"
+	Timestamp time.Time
+	Text      string
+}
+
+// TranscriptSource discovers and parses one tool's conversation transcripts.
+// Global search walks every source's Root down to Depth, indexes the files
+// IsTranscript accepts, and watches WatchDirs for changes.
+type TranscriptSource interface {
+	// Tool is the agent-deck tool name used to resume results ("claude", "codex", ...)
+	Tool() string
+	// Root is the directory that contains the tool's transcripts
+	Root() string
+	// Depth is how many directory levels below Root can contain transcripts
+	// (1 = transcripts live in Root's immediate subdirectories)
+	Depth() int
+	// WatchDirs returns the directories to watch for new or changed transcripts.
+	// Directories that can no longer receive transcripts modified after cutoff
+	// may be left out.
+	WatchDirs(cutoff time.Time) []string
+	// IsTranscript reports whether path is a transcript file of this source
+	IsTranscript(path string) bool
+	// Parse reads a whole transcript
+	Parse(path string) (*Transcript, error)
+}
+
+// lineTranscriptSource is implemented by sources whose transcripts are
+// append-only JSONL. These are indexed incrementally by byte offset.
+type lineTranscriptSource interface {
+	TranscriptSource
+	// sessionIDFromPath returns the session ID encoded in the file name, if any
+	sessionIDFromPath(path string) string
+	// parseLine extracts a message from one line, filling in transcript
+	// metadata (session ID, cwd, summary) as it is discovered
+	parseLine(t *Transcript, line []byte) (TranscriptMessage, bool)
+}
+
+// Transcript source tool names
+const (
+	TranscriptToolClaude   = "claude"
+	TranscriptToolCodex    = "codex"
+	TranscriptToolGemini   = "gemini"
+	TranscriptToolOpenCode = "opencode"
+)
+
+// geminiCheckpointPrefix marks session IDs of Gemini checkpoints, which have
+// no session ID of their own and are resumed with /chat resume <tag>.
+const geminiCheckpointPrefix = "checkpoint:"
+
+// GeminiCheckpointTag returns the /chat save tag if sessionID refers to a
+// Gemini checkpoint rather than an auto-saved chat.
+func GeminiCheckpointTag(sessionID string) (string, bool) {
+	tag, ok := strings.CutPrefix(sessionID, geminiCheckpointPrefix)
+	return tag, ok && tag != ""
+}
+
+// transcriptSummaryMax caps summaries derived from the first user message
+const transcriptSummaryMax = 200
+
+// NewTranscriptSources returns the transcript sources for the given tools.
+// An empty tools list enables every supported tool. Unknown names are ignored.
+func NewTranscriptSources(claudeDir string, tools []string) []TranscriptSource {
+	all := []TranscriptSource{
+		claudeTranscriptSource{dir: claudeDir},
+		codexTranscriptSource{home: getCodexHomeDir()},
+		geminiTranscriptSource{dir: GetGeminiConfigDir()},
+		openCodeTranscriptSource{dir: getOpenCodeDataDir()},
+	}
+	if len(tools) == 0 {
+		return all
+	}
+	var sources []TranscriptSource
+	for _, src := range all {
+		for _, tool := range tools {
+			if strings.EqualFold(strings.TrimSpace(tool), src.Tool()) {
+				sources = append(sources, src)
+				break
+			}
+		}
+	}
+	return sources
+}
+
+// transcriptSourceFor returns the source that owns path, or nil.
+func transcriptSourceFor(sources []TranscriptSource, path string) TranscriptSource {
+	for _, src := range sources {
+		if pathDepth(src.Root(), path) > 0 && src.IsTranscript(path) {
+			return src
+		}
+	}
+	return nil
+}
+
+// pathDepth returns how many levels below root path is (1 = direct child),
+// or -1 if path is not inside root.
+func pathDepth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return -1
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// walkTranscripts calls fn for every transcript file under src.Root().
+// Returning filepath.SkipAll from fn stops the walk.
+func walkTranscripts(src TranscriptSource, fn func(path string, d os.DirEntry) error) error {
+	root := src.Root()
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			// Transcripts only live down to Depth; skipping deeper directories
+			// avoids traversing e.g. thousands of Claude tool-results/ dirs.
+			if path != root && pathDepth(root, path) > src.Depth() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !src.IsTranscript(path) {
+			return nil
+		}
+		return fn(path, d)
+	})
+}
+
+// subdirs returns the immediate subdirectories of dir.
+func subdirs(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() {
+			dirs = append(dirs, filepath.Join(dir, e.Name()))
+		}
+	}
+	return dirs
+}
+
+// parseLineTranscript reads a whole JSONL transcript using src.parseLine.
+func parseLineTranscript(src lineTranscriptSource, path string) (*Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t := &Transcript{Tool: src.Tool(), SessionID: src.sessionIDFromPath(path)}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		if msg, ok := src.parseLine(t, scanner.Bytes()); ok {
+			t.Messages = append(t.Messages, msg)
+		}
+	}
+	return t, scanner.Err()
+}
+
+// noteMessage records msg's text as the summary if it is the first user message.
+func (t *Transcript) noteMessage(msg TranscriptMessage) {
+	if t.Summary == "" && msg.Role == "user" {
+		t.Summary = truncateSummary(msg.Text)
+	}
+}
+
+func truncateSummary(text string) string {
+	if len(text) > transcriptSummaryMax {
+		return text[:transcriptSummaryMax] + "..."
+	}
+	return text
+}
+
+// Content formats the transcript in the "User: / Assistant:" layout used for
+// in-memory search and previews.
+func (t *Transcript) Content() string {
+	var b strings.Builder
+	for _, m := range t.Messages {
+		b.WriteString(formatTranscriptMessage(m))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func formatTranscriptMessage(m TranscriptMessage) string {
+	switch m.Role {
+	case "user":
+		return "User: " + m.Text
+	case "assistant":
+		return "Assistant: " + m.Text
+	}
+	return m.Text
+}
+
+// parseTranscriptTime parses RFC3339 timestamps (with or without fractional seconds).
+func parseTranscriptTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// --- Claude ---
+
+// claudeTranscriptSource reads Claude Code transcripts:
+// <claudeDir>/projects/<encoded-path>/<uuid>.jsonl
+type claudeTranscriptSource struct {
+	dir string
+}
+
+func (s claudeTranscriptSource) Tool() string { return TranscriptToolClaude }
+func (s claudeTranscriptSource) Root() string { return filepath.Join(s.dir, "projects") }
+func (s claudeTranscriptSource) Depth() int   { return 1 }
+
+// WatchDirs watches only project-level directories. Watching every
+// subdirectory (tool-results/, subagents/, ...) leaked thousands of kqueue
+// file descriptors on macOS.
+func (s claudeTranscriptSource) WatchDirs(time.Time) []string {
+	return append([]string{s.Root()}, subdirs(s.Root())...)
+}
+
+func (s claudeTranscriptSource) IsTranscript(path string) bool {
+	return isUUIDFileName(filepath.Base(path))
+}
+
+func (s claudeTranscriptSource) Parse(path string) (*Transcript, error) {
+	return parseLineTranscript(s, path)
+}
+
+func (s claudeTranscriptSource) sessionIDFromPath(string) string { return "" }
+
+func (s claudeTranscriptSource) parseLine(t *Transcript, line []byte) (TranscriptMessage, bool) {
+	var record claudeJSONLRecord
+	if err := json.Unmarshal(line, &record); err != nil {
+		return TranscriptMessage{}, false
+	}
+	if t.SessionID == "" && record.SessionID != "" {
+		t.SessionID = record.SessionID
+	}
+	if t.CWD == "" && record.CWD != "" {
+		t.CWD = record.CWD
+	}
+	if t.Summary == "" && record.Summary != "" {
+		t.Summary = record.Summary
+	}
+	if len(record.Message) == 0 {
+		return TranscriptMessage{}, false
+	}
+
+	var msg claudeMessage
+	if err := json.Unmarshal(record.Message, &msg); err != nil {
+		return TranscriptMessage{}, false
+	}
+	text := strings.TrimSpace(extractContentText(msg.Content))
+	if text == "" {
+		return TranscriptMessage{}, false
+	}
+	m := TranscriptMessage{Role: msg.Role, Timestamp: parseTranscriptTime(record.Timestamp), Text: text}
+	t.noteMessage(m)
+	return m, true
+}
+
+// --- Codex ---
+
+// codexTranscriptSource reads Codex CLI rollout files:
+// $CODEX_HOME/sessions/YYYY/MM/DD/rollout-<timestamp>-<uuid>.jsonl
+type codexTranscriptSource struct {
+	home string
+}
+
+var codexRolloutIDPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+func (s codexTranscriptSource) Tool() string { return TranscriptToolCodex }
+func (s codexTranscriptSource) Root() string { return filepath.Join(s.home, "sessions") }
+func (s codexTranscriptSource) Depth() int   { return 3 }
+
+// WatchDirs skips day directories older than cutoff: rollouts are only ever
+// appended to in the directory of the day they started.
+func (s codexTranscriptSource) WatchDirs(cutoff time.Time) []string {
+	dirs := []string{s.Root()}
+	for _, year := range subdirs(s.Root()) {
+		dirs = append(dirs, year)
+		for _, month := range subdirs(year) {
+			dirs = append(dirs, month)
+			for _, day := range subdirs(month) {
+				if !cutoff.IsZero() {
+					if info, err := os.Stat(day); err == nil && info.ModTime().Before(cutoff) {
+						continue
+					}
+				}
+				dirs = append(dirs, day)
+			}
+		}
+	}
+	return dirs
+}
+
+func (s codexTranscriptSource) IsTranscript(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasPrefix(name, "rollout-") && strings.HasSuffix(name, ".jsonl")
+}
+
+func (s codexTranscriptSource) Parse(path string) (*Transcript, error) {
+	return parseLineTranscript(s, path)
+}
+
+func (s codexTranscriptSource) sessionIDFromPath(path string) string {
+	ids := codexRolloutIDPattern.FindAllString(filepath.Base(path), -1)
+	if len(ids) == 0 {
+		return ""
+	}
+	return ids[len(ids)-1]
+}
+
+// codexRecord covers both rollout layouts: the current envelope
+// ({"type":"response_item","payload":{...}}) and the legacy flat layout
+// where message objects are written directly.
+type codexRecord struct {
+	Timestamp string          `json:"timestamp"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	ID        string          `json:"id"`
+	Role      string          `json:"role"`
+	Content   json.RawMessage `json:"content"`
+}
+
+type codexPayload struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id"`
+	CWD     string          `json:"cwd"`
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// codexContextPrefixes mark user-role messages that Codex injects itself
+// (environment and instruction blocks) rather than prompts the user typed.
+var codexContextPrefixes = []string{"<environment_context>", "<user_instructions>", "# AGENTS.md instructions"}
+
+func (s codexTranscriptSource) parseLine(t *Transcript, line []byte) (TranscriptMessage, bool) {
+	var record codexRecord
+	if err := json.Unmarshal(line, &record); err != nil {
+		return TranscriptMessage{}, false
+	}
+	if t.CWD == "" && (record.Type == "session_meta" || record.Type == "turn_context" || record.Type == "") {
+		t.CWD = extractCodexCWDFromJSONLine(line)
+	}
+
+	var role string
+	var content json.RawMessage
+	switch record.Type {
+	case "session_meta":
+		var p codexPayload
+		if json.Unmarshal(record.Payload, &p) == nil && p.ID != "" {
+			t.SessionID = p.ID
+		}
+		return TranscriptMessage{}, false
+	case "response_item":
+		var p codexPayload
+		if json.Unmarshal(record.Payload, &p) != nil || p.Type != "message" {
+			return TranscriptMessage{}, false
+		}
+		role, content = p.Role, p.Content
+	case "message":
+		role, content = record.Role, record.Content
+	default:
+		// Legacy header line: {"id": ..., "timestamp": ..., "instructions": ...}
+		if record.Type == "" && record.ID != "" && t.SessionID == "" {
+			t.SessionID = record.ID
+		}
+		return TranscriptMessage{}, false
+	}
+	if role != "user" && role != "assistant" {
+		return TranscriptMessage{}, false
+	}
+
+	text := strings.TrimSpace(extractContentText(content))
+	if text == "" {
+		return TranscriptMessage{}, false
+	}
+	if role == "user" {
+		for _, prefix := range codexContextPrefixes {
+			if strings.HasPrefix(text, prefix) {
+				return TranscriptMessage{}, false
+			}
+		}
+	}
+	m := TranscriptMessage{Role: role, Timestamp: parseTranscriptTime(record.Timestamp), Text: text}
+	t.noteMessage(m)
+	return m, true
+}
+
+// --- Gemini ---
+
+// geminiTranscriptSource reads Gemini CLI chats and checkpoints:
+// ~/.gemini/tmp/<project_hash>/chats/session-*.json (auto-saved chats)
+// ~/.gemini/tmp/<project_hash>/checkpoint-<tag>.json (/chat save <tag>)
+type geminiTranscriptSource struct {
+	dir string
+}
+
+// geminiProjectRootFile records the project path in newer Gemini CLI
+// versions; older versions only store the path's hash.
+const geminiProjectRootFile = ".project_root"
+
+func (s geminiTranscriptSource) Tool() string { return TranscriptToolGemini }
+func (s geminiTranscriptSource) Root() string { return filepath.Join(s.dir, "tmp") }
+func (s geminiTranscriptSource) Depth() int   { return 2 }
+
+func (s geminiTranscriptSource) WatchDirs(time.Time) []string {
+	dirs := []string{s.Root()}
+	for _, project := range subdirs(s.Root()) {
+		dirs = append(dirs, project)
+		if chats := filepath.Join(project, "chats"); dirExists(chats) {
+			dirs = append(dirs, chats)
+		}
+	}
+	return dirs
+}
+
+func (s geminiTranscriptSource) IsTranscript(path string) bool {
+	name := filepath.Base(path)
+	if !strings.HasSuffix(name, ".json") {
+		return false
+	}
+	switch pathDepth(s.Root(), path) {
+	case 2:
+		return strings.HasPrefix(name, "checkpoint-")
+	case 3:
+		return strings.HasPrefix(name, "session-") && filepath.Base(filepath.Dir(path)) == "chats"
+	}
+	return false
+}
+
+type geminiChatFile struct {
+	SessionID string `json:"sessionId"`
+	Messages  []struct {
+		Type      string          `json:"type"`
+		Timestamp string          `json:"timestamp"`
+		Content   json.RawMessage `json:"content"`
+	} `json:"messages"`
+}
+
+type geminiCheckpointEntry struct {
+	Role  string          `json:"role"`
+	Parts json.RawMessage `json:"parts"`
+}
+
+func (s geminiTranscriptSource) Parse(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &Transcript{Tool: TranscriptToolGemini}
+
+	projectDir := filepath.Dir(path)
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, "checkpoint-") {
+		var entries []geminiCheckpointEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+		tag := strings.TrimSuffix(strings.TrimPrefix(name, "checkpoint-"), ".json")
+		if unescaped, err := url.PathUnescape(tag); err == nil {
+			tag = unescaped
+		}
+		t.SessionID = geminiCheckpointPrefix + tag
+		for _, e := range entries {
+			t.addGeminiMessage(e.Role, e.Parts, time.Time{})
+		}
+	} else {
+		projectDir = filepath.Dir(projectDir) // chats/ -> <project_hash>/
+		var chat geminiChatFile
+		if err := json.Unmarshal(data, &chat); err != nil {
+			return nil, err
+		}
+		t.SessionID = chat.SessionID
+		for _, m := range chat.Messages {
+			t.addGeminiMessage(m.Type, m.Content, parseTranscriptTime(m.Timestamp))
+		}
+	}
+
+	if root, err := os.ReadFile(filepath.Join(projectDir, geminiProjectRootFile)); err == nil {
+		t.CWD = strings.TrimSpace(string(root))
+	}
+	return t, nil
+}
+
+// addGeminiMessage appends a chat or checkpoint message. Gemini content is
+// either a plain string or a list of parts with "text" fields.
+func (t *Transcript) addGeminiMessage(kind string, content json.RawMessage, ts time.Time) {
+	var role string
+	switch kind {
+	case "user":
+		role = "user"
+	case "gemini", "model":
+		role = "assistant"
+	default:
+		return // info/error/tool entries
+	}
+	text := strings.TrimSpace(extractContentText(content))
+	if text == "" {
+		return
+	}
+	m := TranscriptMessage{Role: role, Timestamp: ts, Text: text}
+	t.noteMessage(m)
+	t.Messages = append(t.Messages, m)
+}
+
+// --- OpenCode ---
+
+// openCodeTranscriptSource reads OpenCode's session storage:
+// <data>/storage/session/<project_id>/ses_*.json (session metadata)
+// <data>/storage/message/<session_id>/msg_*.json (messages)
+// <data>/storage/part/<message_id>/prt_*.json    (message parts)
+// OpenCode bumps the session file's updated time on every message, so the
+// session file stands in for the whole transcript.
+type openCodeTranscriptSource struct {
+	dir string
+}
+
+// getOpenCodeDataDir returns OpenCode's data directory ($XDG_DATA_HOME/opencode,
+// defaulting to ~/.local/share/opencode).
+func getOpenCodeDataDir() string {
+	if xdg := strings.TrimSpace(os.Getenv("XDG_DATA_HOME")); xdg != "" {
+		return filepath.Join(xdg, "opencode")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "opencode")
+	}
+	return filepath.Join(home, ".local", "share", "opencode")
+}
+
+func (s openCodeTranscriptSource) Tool() string    { return TranscriptToolOpenCode }
+func (s openCodeTranscriptSource) storage() string { return filepath.Join(s.dir, "storage") }
+func (s openCodeTranscriptSource) Root() string    { return filepath.Join(s.storage(), "session") }
+func (s openCodeTranscriptSource) Depth() int      { return 1 }
+
+func (s openCodeTranscriptSource) WatchDirs(time.Time) []string {
+	return append([]string{s.Root()}, subdirs(s.Root())...)
+}
+
+func (s openCodeTranscriptSource) IsTranscript(path string) bool {
+	name := filepath.Base(path)
+	return pathDepth(s.Root(), path) == 2 && strings.HasPrefix(name, "ses_") && strings.HasSuffix(name, ".json")
+}
+
+type openCodeSessionFile struct {
+	ID        string `json:"id"`
+	Directory string `json:"directory"`
+	Title     string `json:"title"`
+}
+
+type openCodeMessageFile struct {
+	ID   string `json:"id"`
+	Role string `json:"role"`
+	Time struct {
+		Created int64 `json:"created"` // Unix millis
+	} `json:"time"`
+}
+
+type openCodePartFile struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Text      string `json:"text"`
+	Synthetic bool   `json:"synthetic"`
+}
+
+func (s openCodeTranscriptSource) Parse(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sess openCodeSessionFile
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return nil, err
+	}
+	if sess.ID == "" {
+		sess.ID = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	t := &Transcript{Tool: TranscriptToolOpenCode, SessionID: sess.ID, CWD: sess.Directory}
+
+	var msgs []openCodeMessageFile
+	for _, f := range readJSONDir(filepath.Join(s.storage(), "message", sess.ID)) {
+		var m openCodeMessageFile
+		if json.Unmarshal(f, &m) == nil && m.ID != "" {
+			msgs = append(msgs, m)
+		}
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		if msgs[i].Time.Created != msgs[j].Time.Created {
+			return msgs[i].Time.Created < msgs[j].Time.Created
+		}
+		return msgs[i].ID < msgs[j].ID
+	})
+
+	for _, m := range msgs {
+		if m.Role != "user" && m.Role != "assistant" {
+			continue
+		}
+		var parts []openCodePartFile
+		for _, f := range readJSONDir(filepath.Join(s.storage(), "part", m.ID)) {
+			var p openCodePartFile
+			if json.Unmarshal(f, &p) == nil && p.Type == "text" && !p.Synthetic && strings.TrimSpace(p.Text) != "" {
+				parts = append(parts, p)
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		sort.Slice(parts, func(i, j int) bool { return parts[i].ID < parts[j].ID })
+		texts := make([]string, len(parts))
+		for i, p := range parts {
+			texts[i] = strings.TrimSpace(p.Text)
+		}
+		msg := TranscriptMessage{Role: m.Role, Text: strings.Join(texts, "\n")}
+		if m.Time.Created > 0 {
+			msg.Timestamp = time.UnixMilli(m.Time.Created)
+		}
+		t.noteMessage(msg)
+		t.Messages = append(t.Messages, msg)
+	}
+
+	// The session title is a better summary than the first prompt
+	if sess.Title != "" {
+		t.Summary = truncateSummary(sess.Title)
+	}
+	return t, nil
+}
+
+// readJSONDir returns the contents of every .json file in dir.
+func readJSONDir(dir string) [][]byte {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files [][]byte
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, e.Name())); err == nil && len(bytes.TrimSpace(data)) > 0 {
+			files = append(files, data)
+		}
+	}
+	return files
+}
@@ -0,0 +1,171 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asheshgoplani/agent-deck/internal/searchdb"
+)
+
+func writeTestFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCodexTranscriptSource(t *testing.T) {
+	src := codexTranscriptSource{home: t.TempDir()}
+	path := filepath.Join(src.Root(), "2025", "10", "18", "rollout-2025-10-18T10-00-00-0199f5a1-2b3c-7d4e-8f90-123456789abc.jsonl")
+	writeTestFile(t, path, `{"timestamp":"2025-10-18T10:00:00.123Z","type":"session_meta","payload":{"id":"0199f5a1-2b3c-7d4e-8f90-123456789abc","cwd":"/home/me/api"}}
+{"timestamp":"2025-10-18T10:00:01Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"<environment_context>cwd</environment_context>"}]}}
+{"timestamp":"2025-10-18T10:00:02Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"fix the flaky test"}]}}
+{"timestamp":"2025-10-18T10:00:03Z","type":"event_msg","payload":{"type":"user_message","message":"fix the flaky test"}}
+{"timestamp":"2025-10-18T10:00:04Z","type":"response_item","payload":{"type":"function_call","name":"shell"}}
+{"timestamp":"2025-10-18T10:00:05Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"output_text","text":"The test races on a shared port."}]}}
+`)
+
+	if got := transcriptSourceFor([]TranscriptSource{src}, path); got == nil {
+		t.Fatal("rollout not recognized as a transcript")
+	}
+	tr, err := src.Parse(path)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if tr.SessionID != "0199f5a1-2b3c-7d4e-8f90-123456789abc" || tr.CWD != "/home/me/api" {
+		t.Errorf("metadata = %q %q", tr.SessionID, tr.CWD)
+	}
+	if tr.Summary != "fix the flaky test" {
+		t.Errorf("Summary = %q", tr.Summary)
+	}
+	if len(tr.Messages) != 2 || tr.Messages[1].Role != "assistant" || tr.Messages[0].Timestamp.IsZero() {
+		t.Errorf("Messages = %+v", tr.Messages)
+	}
+}
+
+func TestCodexTranscriptSourceLegacyLayout(t *testing.T) {
+	src := codexTranscriptSource{home: t.TempDir()}
+	path := filepath.Join(src.Root(), "2025", "05", "01", "rollout-2025-05-01T09-00-00-1b2c3d4e-5f60-4718-9a0b-c1d2e3f4a5b6.jsonl")
+	writeTestFile(t, path, `{"id":"1b2c3d4e-5f60-4718-9a0b-c1d2e3f4a5b6","timestamp":"2025-05-01T09:00:00Z","instructions":null}
+{"record_type":"state"}
+{"type":"message","role":"user","content":[{"type":"input_text","text":"hello codex"}]}
+`)
+	tr, err := src.Parse(path)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if tr.SessionID != "1b2c3d4e-5f60-4718-9a0b-c1d2e3f4a5b6" || len(tr.Messages) != 1 {
+		t.Errorf("got %+v", tr)
+	}
+}
+
+func TestGeminiTranscriptSource(t *testing.T) {
+	src := geminiTranscriptSource{dir: t.TempDir()}
+	project := filepath.Join(src.Root(), "abc123")
+	chat := filepath.Join(project, "chats", "session-2025-10-18T10-00-4d8fcb4d.json")
+	writeTestFile(t, chat, `{"sessionId":"4d8fcb4d-1111-2222-3333-444455556666","messages":[
+		{"type":"user","timestamp":"2025-10-18T10:00:00.000Z","content":"explain the cache"},
+		{"type":"info","content":"ignored"},
+		{"type":"gemini","timestamp":"2025-10-18T10:00:05.000Z","content":"The cache is write-through."}
+	]}`)
+	checkpoint := filepath.Join(project, "checkpoint-before%20refactor.json")
+	writeTestFile(t, checkpoint, `[{"role":"user","parts":[{"text":"plan the refactor"}]},{"role":"model","parts":[{"text":"Step one"},{"functionCall":{}}]}]`)
+	writeTestFile(t, filepath.Join(project, geminiProjectRootFile), "/home/me/cache\n")
+	writeTestFile(t, filepath.Join(project, "logs.json"), `[]`)
+
+	sources := []TranscriptSource{src}
+	if transcriptSourceFor(sources, chat) == nil || transcriptSourceFor(sources, checkpoint) == nil {
+		t.Fatal("chat/checkpoint not recognized")
+	}
+	if transcriptSourceFor(sources, filepath.Join(project, "logs.json")) != nil {
+		t.Error("logs.json recognized as a transcript")
+	}
+
+	tr, err := src.Parse(chat)
+	if err != nil {
+		t.Fatalf("Parse chat: %v", err)
+	}
+	if tr.SessionID != "4d8fcb4d-1111-2222-3333-444455556666" || tr.CWD != "/home/me/cache" || len(tr.Messages) != 2 {
+		t.Errorf("chat = %+v", tr)
+	}
+	if tr.Messages[1].Role != "assistant" {
+		t.Errorf("gemini role not mapped: %q", tr.Messages[1].Role)
+	}
+
+	tr, err = src.Parse(checkpoint)
+	if err != nil {
+		t.Fatalf("Parse checkpoint: %v", err)
+	}
+	if tag, ok := GeminiCheckpointTag(tr.SessionID); !ok || tag != "before refactor" {
+		t.Errorf("checkpoint tag = %q %v", tag, ok)
+	}
+	if len(tr.Messages) != 2 || tr.Messages[1].Text != "Step one" {
+		t.Errorf("checkpoint messages = %+v", tr.Messages)
+	}
+}
+
+func TestOpenCodeTranscriptSource(t *testing.T) {
+	src := openCodeTranscriptSource{dir: t.TempDir()}
+	sessionFile := filepath.Join(src.Root(), "proj1", "ses_abc.json")
+	writeTestFile(t, sessionFile, `{"id":"ses_abc","directory":"/home/me/web","title":"Add dark mode","time":{"created":1700000000000,"updated":1700000100000}}`)
+	storage := src.storage()
+	writeTestFile(t, filepath.Join(storage, "message", "ses_abc", "msg_2.json"), `{"id":"msg_2","role":"assistant","time":{"created":1700000002000}}`)
+	writeTestFile(t, filepath.Join(storage, "message", "ses_abc", "msg_1.json"), `{"id":"msg_1","role":"user","time":{"created":1700000001000}}`)
+	writeTestFile(t, filepath.Join(storage, "part", "msg_1", "prt_1.json"), `{"id":"prt_1","type":"text","text":"add a dark mode toggle"}`)
+	writeTestFile(t, filepath.Join(storage, "part", "msg_2", "prt_1.json"), `{"id":"prt_1","type":"text","text":"Added the toggle."}`)
+	writeTestFile(t, filepath.Join(storage, "part", "msg_2", "prt_2.json"), `{"id":"prt_2","type":"tool","tool":"edit"}`)
+	writeTestFile(t, filepath.Join(storage, "part", "msg_2", "prt_3.json"), `{"id":"prt_3","type":"text","text":"context","synthetic":true}`)
+
+	if transcriptSourceFor([]TranscriptSource{src}, sessionFile) == nil {
+		t.Fatal("session file not recognized")
+	}
+	tr, err := src.Parse(sessionFile)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if tr.SessionID != "ses_abc" || tr.CWD != "/home/me/web" || tr.Summary != "Add dark mode" {
+		t.Errorf("metadata = %+v", tr)
+	}
+	if len(tr.Messages) != 2 || tr.Messages[0].Role != "user" || tr.Messages[1].Text != "Added the toggle." {
+		t.Errorf("Messages = %+v", tr.Messages)
+	}
+}
+
+func TestSyncSearchStoreMultipleTools(t *testing.T) {
+	store := newTestSearchStore(t)
+	codex := codexTranscriptSource{home: t.TempDir()}
+	gemini := geminiTranscriptSource{dir: t.TempDir()}
+	writeTestFile(t, filepath.Join(codex.Root(), "2025", "10", "18", "rollout-2025-10-18T10-00-00-0199f5a1-2b3c-7d4e-8f90-123456789abc.jsonl"),
+		`{"type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"tune the kafka consumer"}]}}`+"\n")
+	chat := filepath.Join(gemini.Root(), "abc123", "chats", "session-2025-10-18T10-00-4d8fcb4d.json")
+	writeTestFile(t, chat, `{"sessionId":"4d8fcb4d","messages":[{"type":"user","content":"kafka lag alerts"}]}`)
+
+	sources := []TranscriptSource{codex, gemini}
+	if n, err := SyncSearchStore(context.Background(), store, sources, 0, nil); err != nil || n != 2 {
+		t.Fatalf("SyncSearchStore: n=%d err=%v", n, err)
+	}
+
+	results, err := SearchStore(store, searchdb.Query{Text: "kafka"}, 0)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("SearchStore: %d results, err=%v", len(results), err)
+	}
+	results, _ = SearchStore(store, searchdb.Query{Text: "kafka", Tool: "codex"}, 0)
+	if len(results) != 1 || results[0].Tool != "codex" || results[0].SessionID != "0199f5a1-2b3c-7d4e-8f90-123456789abc" {
+		t.Errorf("tool filter results = %+v", results)
+	}
+
+	// Rewritten Gemini chats replace their previous messages
+	writeTestFile(t, chat, `{"sessionId":"4d8fcb4d","messages":[{"type":"user","content":"zookeeper quorum"}]}`)
+	if _, err := IndexTranscriptFile(store, gemini, chat); err != nil {
+		t.Fatalf("IndexTranscriptFile: %v", err)
+	}
+	results, _ = SearchStore(store, searchdb.Query{Text: "kafka", Tool: "gemini"}, 0)
+	if len(results) != 0 {
+		t.Errorf("stale gemini messages after rewrite: %+v", results)
+	}
+}
@@ -0,0 +1,551 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Transcript entry kinds
+const (
+	TranscriptEntryUser      = "user"
+	TranscriptEntryAssistant = "assistant"
+	TranscriptEntryTool      = "tool"
+)
+
+// transcriptToolTextMax caps stored tool inputs and results; the viewer
+// shows them collapsed and nobody reads megabytes of tool output in a TUI.
+const transcriptToolTextMax = 8 * 1024
+
+// TranscriptToolCall is a tool invocation with its result.
+type TranscriptToolCall struct {
+	ID      string
+	Name    string
+	Summary string // One-line summary of the input (command, path, ...)
+	Input   string // Indented JSON or raw input
+	Result  string
+	IsError bool
+}
+
+// TranscriptEntry is one item of a session's conversation as shown by the
+// transcript viewer: a prompt, a reply, or a tool call.
+type TranscriptEntry struct {
+	Kind      string
+	Timestamp time.Time
+	Text      string
+	Tool      *TranscriptToolCall // Set for TranscriptEntryTool
+	// Subagent is the agent ID of Claude subagent (sidechain) entries
+	Subagent string
+	// Turn is the 1-based number of the user prompt the entry belongs to
+	// (0 before the first prompt)
+	Turn int
+
+	msgID string // Provider message ID, used to merge split messages
+}
+
+// SessionTranscript is the detailed conversation of one session.
+type SessionTranscript struct {
+	Tool      string
+	Path      string
+	ModTime   time.Time
+	Entries   []TranscriptEntry
+	Turns     int
+	Subagents []SubagentInfo
+}
+
+// TranscriptPath returns the transcript file of an instance, or "" if the
+// tool has none (yet).
+func TranscriptPath(inst *Instance) string {
+	switch inst.GetToolThreadSafe() {
+	case "claude":
+		return inst.GetJSONLPath()
+	case "codex":
+		return findCodexRollout(inst.CodexSessionID)
+	case "gemini":
+		if len(inst.GeminiSessionID) >= 8 {
+			path, _ := findGeminiSessionFile(inst.ProjectPath, inst.GeminiSessionID)
+			return path
+		}
+	case "opencode":
+		return findOpenCodeSessionFile(getOpenCodeDataDir(), inst.OpenCodeSessionID)
+	}
+	return ""
+}
+
+// LoadSessionTranscript parses an instance's transcript for display.
+// Returns nil, nil when the session has no transcript.
+func LoadSessionTranscript(inst *Instance) (*SessionTranscript, error) {
+	path := TranscriptPath(inst)
+	if path == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &SessionTranscript{Tool: inst.GetToolThreadSafe(), Path: path, ModTime: info.ModTime()}
+	switch t.Tool {
+	case "claude":
+		err = t.loadClaude(path)
+	case "codex":
+		err = t.loadCodex(path)
+	case "gemini":
+		err = t.loadGemini(path)
+	case "opencode":
+		err = t.loadOpenCode(getOpenCodeDataDir(), inst.OpenCodeSessionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.finish()
+	return t, nil
+}
+
+// finish numbers turns and collects subagent stats.
+func (t *SessionTranscript) finish() {
+	turn := 0
+	subagents := make(map[string]*SubagentInfo)
+	var order []string
+	for i := range t.Entries {
+		e := &t.Entries[i]
+		if e.Subagent != "" {
+			s, ok := subagents[e.Subagent]
+			if !ok {
+				s = &SubagentInfo{ID: e.Subagent, StartTime: e.Timestamp}
+				subagents[e.Subagent] = s
+				order = append(order, e.Subagent)
+			}
+			if e.Kind == TranscriptEntryAssistant {
+				s.Turns++
+			}
+		} else if e.Kind == TranscriptEntryUser {
+			turn++
+		}
+		e.Turn = turn
+	}
+	t.Turns = turn
+	for _, id := range order {
+		t.Subagents = append(t.Subagents, *subagents[id])
+	}
+}
+
+// addText appends a text entry, merging it into the previous entry when
+// both belong to the same provider message.
+func (t *SessionTranscript) addText(kind, text string, ts time.Time, subagent, msgID string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if n := len(t.Entries); n > 0 && msgID != "" {
+		last := &t.Entries[n-1]
+		if last.msgID == msgID && last.Kind == kind {
+			last.Text += "\n" + text
+			return
+		}
+	}
+	t.Entries = append(t.Entries, TranscriptEntry{Kind: kind, Timestamp: ts, Text: text, Subagent: subagent, msgID: msgID})
+}
+
+// addTool appends a tool call and returns its index for attaching the result.
+func (t *SessionTranscript) addTool(call TranscriptToolCall, ts time.Time, subagent string) int {
+	call.Input = capToolText(call.Input)
+	t.Entries = append(t.Entries, TranscriptEntry{Kind: TranscriptEntryTool, Timestamp: ts, Tool: &call, Subagent: subagent})
+	return len(t.Entries) - 1
+}
+
+// setToolResult fills in the result of the tool call at index i.
+func (t *SessionTranscript) setToolResult(i int, result string, isError bool) {
+	if i < 0 || i >= len(t.Entries) || t.Entries[i].Tool == nil {
+		return
+	}
+	t.Entries[i].Tool.Result = capToolText(strings.TrimSpace(result))
+	t.Entries[i].Tool.IsError = isError
+}
+
+func capToolText(s string) string {
+	if len(s) <= transcriptToolTextMax {
+		return s
+	}
+	return s[:transcriptToolTextMax] + "\n… (truncated)"
+}
+
+// toolInputSummaryKeys are input fields that best describe a tool call,
+// in order of preference.
+var toolInputSummaryKeys = []string{"command", "cmd", "file_path", "filePath", "path", "pattern", "url", "query", "description", "prompt"}
+
+// formatToolInput returns a one-line summary and the indented form of a
+// tool's JSON input. Non-JSON input is returned as-is.
+func formatToolInput(raw []byte) (summary, full string) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", ""
+	}
+	var obj map[string]any
+	if json.Unmarshal(raw, &obj) != nil {
+		s := string(raw)
+		return firstLine(s), s
+	}
+	var indented bytes.Buffer
+	if json.Indent(&indented, raw, "", "  ") == nil {
+		full = indented.String()
+	} else {
+		full = string(raw)
+	}
+	for _, key := range toolInputSummaryKeys {
+		switch v := obj[key].(type) {
+		case string:
+			if v != "" {
+				return firstLine(v), full
+			}
+		case []any: // Codex shell commands are argv lists
+			parts := make([]string, 0, len(v))
+			for _, p := range v {
+				if s, ok := p.(string); ok {
+					parts = append(parts, s)
+				}
+			}
+			if len(parts) > 0 {
+				return firstLine(strings.Join(parts, " ")), full
+			}
+		}
+	}
+	var compact bytes.Buffer
+	if json.Compact(&compact, raw) == nil {
+		return compact.String(), full
+	}
+	return firstLine(full), full
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i] + " …"
+	}
+	return s
+}
+
+// scanJSONLines calls fn for every line of a JSONL file.
+func scanJSONLines(path string, fn func(line []byte)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		fn(scanner.Bytes())
+	}
+	return scanner.Err()
+}
+
+// --- Claude ---
+
+// claudeViewRecord extends the search record with the fields the viewer
+// needs to skip injected messages and attribute subagent turns.
+type claudeViewRecord struct {
+	claudeJSONLRecord
+	IsMeta      bool   `json:"isMeta"`
+	IsSidechain bool   `json:"isSidechain"`
+	AgentID     string `json:"agentId"`
+}
+
+type claudeViewMessage struct {
+	ID      string          `json:"id"`
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type claudeContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	IsError   bool            `json:"is_error"`
+}
+
+// loadClaude reads a Claude transcript plus any subagent transcripts Claude
+// Code keeps beside it (<session>/subagents/agent-<id>.jsonl).
+func (t *SessionTranscript) loadClaude(path string) error {
+	tools := make(map[string]int)
+	if err := scanJSONLines(path, func(line []byte) { t.addClaudeLine(line, "", tools) }); err != nil {
+		return err
+	}
+
+	agentFiles, _ := filepath.Glob(filepath.Join(strings.TrimSuffix(path, ".jsonl"), "subagents", "*.jsonl"))
+	if len(agentFiles) == 0 {
+		return nil
+	}
+	for _, f := range agentFiles {
+		agent := strings.TrimPrefix(strings.TrimSuffix(filepath.Base(f), ".jsonl"), "agent-")
+		_ = scanJSONLines(f, func(line []byte) { t.addClaudeLine(line, agent, tools) })
+	}
+	// Interleave subagent turns with the main conversation
+	sort.SliceStable(t.Entries, func(i, j int) bool {
+		a, b := t.Entries[i].Timestamp, t.Entries[j].Timestamp
+		return !a.IsZero() && !b.IsZero() && a.Before(b)
+	})
+	return nil
+}
+
+func (t *SessionTranscript) addClaudeLine(line []byte, agent string, tools map[string]int) {
+	var record claudeViewRecord
+	if json.Unmarshal(line, &record) != nil || len(record.Message) == 0 || record.IsMeta {
+		return
+	}
+	if record.Type != "user" && record.Type != "assistant" {
+		return
+	}
+	var msg claudeViewMessage
+	if json.Unmarshal(record.Message, &msg) != nil {
+		return
+	}
+	subagent := agent
+	if subagent == "" && record.IsSidechain {
+		subagent = record.AgentID
+		if subagent == "" {
+			subagent = "sidechain"
+		}
+	}
+	ts := parseTranscriptTime(record.Timestamp)
+
+	var blocks []claudeContentBlock
+	if json.Unmarshal(msg.Content, &blocks) != nil {
+		// Plain string content
+		t.addText(record.Type, extractContentText(msg.Content), ts, subagent, msg.ID)
+		return
+	}
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			t.addText(record.Type, b.Text, ts, subagent, msg.ID)
+		case "tool_use":
+			summary, input := formatToolInput(b.Input)
+			tools[b.ID] = t.addTool(TranscriptToolCall{ID: b.ID, Name: b.Name, Summary: summary, Input: input}, ts, subagent)
+		case "tool_result":
+			if i, ok := tools[b.ToolUseID]; ok {
+				t.setToolResult(i, extractContentText(b.Content), b.IsError)
+			}
+		}
+	}
+}
+
+// --- Codex ---
+
+// codexToolPayload covers the rollout response_items that invoke tools and
+// carry their output.
+type codexToolPayload struct {
+	Type      string          `json:"type"`
+	Name      string          `json:"name"`
+	CallID    string          `json:"call_id"`
+	Arguments string          `json:"arguments"`
+	Input     string          `json:"input"`
+	Output    json.RawMessage `json:"output"`
+	Action    json.RawMessage `json:"action"`
+	Status    string          `json:"status"`
+}
+
+func (t *SessionTranscript) loadCodex(path string) error {
+	tools := make(map[string]int)
+	return scanJSONLines(path, func(line []byte) {
+		var record codexRecord
+		if json.Unmarshal(line, &record) != nil {
+			return
+		}
+		ts := parseTranscriptTime(record.Timestamp)
+		switch record.Type {
+		case "message": // Legacy flat layout
+			t.addCodexMessage(record.Role, record.Content, ts)
+		case "response_item":
+			var p codexToolPayload
+			if json.Unmarshal(record.Payload, &p) != nil {
+				return
+			}
+			switch p.Type {
+			case "message":
+				var m codexPayload
+				if json.Unmarshal(record.Payload, &m) == nil {
+					t.addCodexMessage(m.Role, m.Content, ts)
+				}
+			case "function_call":
+				summary, input := formatToolInput([]byte(p.Arguments))
+				tools[p.CallID] = t.addTool(TranscriptToolCall{ID: p.CallID, Name: p.Name, Summary: summary, Input: input}, ts, "")
+			case "custom_tool_call":
+				tools[p.CallID] = t.addTool(TranscriptToolCall{ID: p.CallID, Name: p.Name, Summary: firstLine(p.Input), Input: p.Input}, ts, "")
+			case "local_shell_call":
+				summary, input := formatToolInput(p.Action)
+				tools[p.CallID] = t.addTool(TranscriptToolCall{ID: p.CallID, Name: "shell", Summary: summary, Input: input}, ts, "")
+			case "web_search_call":
+				summary, input := formatToolInput(p.Action)
+				t.addTool(TranscriptToolCall{Name: "web_search", Summary: summary, Input: input}, ts, "")
+			case "function_call_output", "custom_tool_call_output":
+				if i, ok := tools[p.CallID]; ok {
+					out, failed := codexToolOutput(p.Output)
+					t.setToolResult(i, out, failed)
+				}
+			}
+		}
+	})
+}
+
+func (t *SessionTranscript) addCodexMessage(role string, content json.RawMessage, ts time.Time) {
+	if role != "user" && role != "assistant" {
+		return
+	}
+	text := strings.TrimSpace(extractContentText(content))
+	if role == "user" {
+		for _, prefix := range codexContextPrefixes {
+			if strings.HasPrefix(text, prefix) {
+				return
+			}
+		}
+	}
+	t.addText(role, text, ts, "", "")
+}
+
+// codexToolOutput unwraps a tool output, which is a string that may itself
+// hold JSON like {"output": "...", "metadata": {"exit_code": 1}}.
+func codexToolOutput(raw json.RawMessage) (string, bool) {
+	var s string
+	if json.Unmarshal(raw, &s) != nil {
+		return extractContentText(raw), false
+	}
+	var wrapped struct {
+		Output   *string `json:"output"`
+		Metadata struct {
+			ExitCode int `json:"exit_code"`
+		} `json:"metadata"`
+	}
+	if json.Unmarshal([]byte(s), &wrapped) == nil && wrapped.Output != nil {
+		return *wrapped.Output, wrapped.Metadata.ExitCode != 0
+	}
+	return s, false
+}
+
+// --- Gemini ---
+
+type geminiViewChat struct {
+	Messages []struct {
+		Type      string          `json:"type"`
+		Timestamp string          `json:"timestamp"`
+		Content   json.RawMessage `json:"content"`
+		ToolCalls []struct {
+			ID            string          `json:"id"`
+			Name          string          `json:"name"`
+			Args          json.RawMessage `json:"args"`
+			Status        string          `json:"status"`
+			ResultDisplay json.RawMessage `json:"resultDisplay"`
+		} `json:"toolCalls"`
+	} `json:"messages"`
+}
+
+func (t *SessionTranscript) loadGemini(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var chat geminiViewChat
+	if err := json.Unmarshal(data, &chat); err != nil {
+		return err
+	}
+	for _, m := range chat.Messages {
+		ts := parseTranscriptTime(m.Timestamp)
+		switch m.Type {
+		case "user":
+			t.addText(TranscriptEntryUser, extractContentText(m.Content), ts, "", "")
+		case "gemini":
+			t.addText(TranscriptEntryAssistant, extractContentText(m.Content), ts, "", "")
+			for _, tc := range m.ToolCalls {
+				summary, input := formatToolInput(tc.Args)
+				i := t.addTool(TranscriptToolCall{ID: tc.ID, Name: tc.Name, Summary: summary, Input: input}, ts, "")
+				var result string
+				if json.Unmarshal(tc.ResultDisplay, &result) != nil {
+					result = string(tc.ResultDisplay)
+				}
+				t.setToolResult(i, result, tc.Status == "error")
+			}
+		}
+	}
+	return nil
+}
+
+// --- OpenCode ---
+
+type openCodeToolPartFile struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	CallID string `json:"callID"`
+	Tool   string `json:"tool"`
+	State  struct {
+		Status string          `json:"status"`
+		Input  json.RawMessage `json:"input"`
+		Output string          `json:"output"`
+		Error  string          `json:"error"`
+	} `json:"state"`
+	Synthetic bool `json:"synthetic"`
+}
+
+func (t *SessionTranscript) loadOpenCode(dataDir, sessionID string) error {
+	storage := openCodeTranscriptSource{dir: dataDir}.storage()
+	msgDir := filepath.Join(storage, "message", sessionID)
+	if _, err := os.Stat(msgDir); err != nil {
+		return err
+	}
+
+	var msgs []openCodeMessageFile
+	for _, f := range readJSONDir(msgDir) {
+		var m openCodeMessageFile
+		if json.Unmarshal(f, &m) == nil && m.ID != "" {
+			msgs = append(msgs, m)
+		}
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		if msgs[i].Time.Created != msgs[j].Time.Created {
+			return msgs[i].Time.Created < msgs[j].Time.Created
+		}
+		return msgs[i].ID < msgs[j].ID
+	})
+
+	for _, m := range msgs {
+		if m.Role != "user" && m.Role != "assistant" {
+			continue
+		}
+		var ts time.Time
+		if m.Time.Created > 0 {
+			ts = time.UnixMilli(m.Time.Created)
+		}
+		var parts []openCodeToolPartFile
+		for _, f := range readJSONDir(filepath.Join(storage, "part", m.ID)) {
+			var p openCodeToolPartFile
+			if json.Unmarshal(f, &p) == nil {
+				parts = append(parts, p)
+			}
+		}
+		sort.Slice(parts, func(i, j int) bool { return parts[i].ID < parts[j].ID })
+		for _, p := range parts {
+			switch {
+			case p.Type == "text" && !p.Synthetic:
+				t.addText(m.Role, p.Text, ts, "", m.ID)
+			case p.Type == "tool" && p.Tool != "":
+				summary, input := formatToolInput(p.State.Input)
+				i := t.addTool(TranscriptToolCall{ID: p.CallID, Name: p.Tool, Summary: summary, Input: input}, ts, "")
+				if p.State.Status == "error" {
+					t.setToolResult(i, p.State.Error, true)
+				} else {
+					t.setToolResult(i, p.State.Output, false)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionTranscript_ClaudeToolsAndSubagents(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sess.jsonl")
+	writeTestFile(t, path, strings.Join([]string{
+		`{"type":"user","timestamp":"2025-10-01T10:00:00Z","message":{"role":"user","content":"fix the build"}}`,
+		`{"type":"user","isMeta":true,"timestamp":"2025-10-01T10:00:00Z","message":{"role":"user","content":"<command-name>ignored</command-name>"}}`,
+		`{"type":"assistant","timestamp":"2025-10-01T10:00:01Z","message":{"id":"m1","role":"assistant","content":[{"type":"text","text":"Looking."}]}}`,
+		`{"type":"assistant","timestamp":"2025-10-01T10:00:01Z","message":{"id":"m1","role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go build ./...","description":"Build"}}]}}`,
+		`{"type":"user","timestamp":"2025-10-01T10:00:03Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"undefined: foo","is_error":true}]}}`,
+		`{"type":"assistant","timestamp":"2025-10-01T10:00:10Z","message":{"id":"m2","role":"assistant","content":[{"type":"text","text":"Fixed."}]}}`,
+		`{"type":"user","timestamp":"2025-10-01T10:01:00Z","message":{"role":"user","content":"thanks"}}`,
+	}, "\n"))
+	writeTestFile(t, filepath.Join(dir, "sess", "subagents", "agent-a1.jsonl"), strings.Join([]string{
+		`{"type":"user","isSidechain":true,"timestamp":"2025-10-01T10:00:04Z","message":{"role":"user","content":"find foo"}}`,
+		`{"type":"assistant","isSidechain":true,"timestamp":"2025-10-01T10:00:05Z","message":{"id":"s1","role":"assistant","content":[{"type":"text","text":"foo is in bar.go"}]}}`,
+	}, "\n"))
+
+	tr := &SessionTranscript{}
+	require.NoError(t, tr.loadClaude(path))
+	tr.finish()
+
+	require.Len(t, tr.Entries, 7)
+	assert.Equal(t, 2, tr.Turns)
+
+	// Text split across records of one message merges; the tool call follows
+	assert.Equal(t, TranscriptEntryAssistant, tr.Entries[1].Kind)
+	assert.Equal(t, "Looking.", tr.Entries[1].Text)
+	tool := tr.Entries[2].Tool
+	require.NotNil(t, tool)
+	assert.Equal(t, "Bash", tool.Name)
+	assert.Equal(t, "go build ./...", tool.Summary)
+	assert.Equal(t, "undefined: foo", tool.Result)
+	assert.True(t, tool.IsError)
+
+	// Subagent turns are interleaved by time and don't start new turns
+	assert.Equal(t, "a1", tr.Entries[3].Subagent)
+	assert.Equal(t, "find foo", tr.Entries[3].Text)
+	assert.Equal(t, 1, tr.Entries[3].Turn)
+	assert.Equal(t, "a1", tr.Entries[4].Subagent)
+	require.Len(t, tr.Subagents, 1)
+	assert.Equal(t, "a1", tr.Subagents[0].ID)
+	assert.Equal(t, 1, tr.Subagents[0].Turns)
+
+	assert.Equal(t, "Fixed.", tr.Entries[5].Text)
+	assert.Equal(t, 2, tr.Entries[6].Turn)
+}
+
+func TestSessionTranscript_CodexToolCalls(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rollout.jsonl")
+	writeTestFile(t, path, strings.Join([]string{
+		`{"timestamp":"2025-10-01T10:00:00Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"<environment_context>cwd</environment_context>"}]}}`,
+		`{"timestamp":"2025-10-01T10:00:01Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"run tests"}]}}`,
+		`{"timestamp":"2025-10-01T10:00:02Z","type":"response_item","payload":{"type":"function_call","name":"shell","call_id":"c1","arguments":"{\"command\":[\"go\",\"test\",\"./...\"]}"}}`,
+		`{"timestamp":"2025-10-01T10:00:05Z","type":"response_item","payload":{"type":"function_call_output","call_id":"c1","output":"{\"output\":\"FAIL pkg\",\"metadata\":{\"exit_code\":1}}"}}`,
+		`{"timestamp":"2025-10-01T10:00:06Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"output_text","text":"One test fails."}]}}`,
+	}, "\n"))
+
+	tr := &SessionTranscript{}
+	require.NoError(t, tr.loadCodex(path))
+	tr.finish()
+
+	require.Len(t, tr.Entries, 3)
+	assert.Equal(t, 1, tr.Turns)
+	assert.Equal(t, "run tests", tr.Entries[0].Text)
+	tool := tr.Entries[1].Tool
+	require.NotNil(t, tool)
+	assert.Equal(t, "shell", tool.Name)
+	assert.Equal(t, "go test ./...", tool.Summary)
+	assert.Equal(t, "FAIL pkg", tool.Result)
+	assert.True(t, tool.IsError)
+	assert.Equal(t, "One test fails.", tr.Entries[2].Text)
+}
+
+func TestSessionTranscript_OpenCodeToolParts(t *testing.T) {
+	dataDir := t.TempDir()
+	storage := filepath.Join(dataDir, "storage")
+	writeTestFile(t, filepath.Join(storage, "message", "ses_1", "msg_1.json"),
+		`{"id":"msg_1","sessionID":"ses_1","role":"user","time":{"created":1759312800000}}`)
+	writeTestFile(t, filepath.Join(storage, "message", "ses_1", "msg_2.json"),
+		`{"id":"msg_2","sessionID":"ses_1","role":"assistant","time":{"created":1759312801000}}`)
+	writeTestFile(t, filepath.Join(storage, "part", "msg_1", "prt_1.json"),
+		`{"id":"prt_1","type":"text","text":"read main.go"}`)
+	writeTestFile(t, filepath.Join(storage, "part", "msg_2", "prt_1.json"),
+		`{"id":"prt_1","type":"tool","tool":"read","callID":"x","state":{"status":"completed","input":{"filePath":"main.go"},"output":"package main"}}`)
+	writeTestFile(t, filepath.Join(storage, "part", "msg_2", "prt_2.json"),
+		`{"id":"prt_2","type":"text","text":"It is the entry point."}`)
+
+	tr := &SessionTranscript{}
+	require.NoError(t, tr.loadOpenCode(dataDir, "ses_1"))
+	tr.finish()
+
+	require.Len(t, tr.Entries, 3)
+	assert.Equal(t, "read main.go", tr.Entries[0].Text)
+	require.NotNil(t, tr.Entries[1].Tool)
+	assert.Equal(t, "read", tr.Entries[1].Tool.Name)
+	assert.Equal(t, "main.go", tr.Entries[1].Tool.Summary)
+	assert.Equal(t, "package main", tr.Entries[1].Tool.Result)
+	assert.False(t, tr.Entries[1].Tool.IsError)
+	assert.Equal(t, "It is the entry point.", tr.Entries[2].Text)
+}
+
+func TestFormatToolInput(t *testing.T) {
+	summary, full := formatToolInput([]byte(`{"file_path":"/a/b.go","old_string":"x"}`))
+	assert.Equal(t, "/a/b.go", summary)
+	assert.Contains(t, full, "\n  \"old_string\"")
+
+	summary, _ = formatToolInput([]byte(`{"command":"line one\nline two"}`))
+	assert.Equal(t, "line one …", summary)
+
+	summary, _ = formatToolInput([]byte(`{"a":1}`))
+	assert.Equal(t, `{"a":1}`, summary)
+
+	summary, full = formatToolInput([]byte("*** Begin Patch"))
+	assert.Equal(t, "*** Begin Patch", summary)
+	assert.Equal(t, "*** Begin Patch", full)
+
+	summary, full = formatToolInput(nil)
+	assert.Empty(t, summary)
+	assert.Empty(t, full)
+}
+
+func TestLoadSessionTranscript_NoTranscript(t *testing.T) {
+	inst := &Instance{Tool: "shell", ProjectPath: os.TempDir()}
+	tr, err := LoadSessionTranscript(inst)
+	assert.NoError(t, err)
+	assert.Nil(t, tr)
+}
@@ -0,0 +1,490 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+)
+
+// usageIdleGap is the longest pause between two transcript events that still
+// counts toward active or waiting time. Longer pauses mean the session was
+// set aside and count toward neither.
+const usageIdleGap = 30 * time.Minute
+
+// usageEventKind classifies transcript events for usage accounting.
+type usageEventKind int
+
+const (
+	usagePrompt   usageEventKind = iota // The user sent a message
+	usageReply                          // A model response carrying token usage
+	usageActivity                       // Tool calls, tool results and other agent progress
+)
+
+// usageEvent is one timestamped transcript event. The pause before a prompt
+// is waiting time (the agent waited on the user); the pause before any other
+// event is active time.
+type usageEvent struct {
+	Time       time.Time
+	Kind       usageEventKind
+	Model      string // Model in use from this event on ("" = unchanged)
+	Input      int    // Uncached input tokens
+	Output     int
+	CacheRead  int
+	CacheWrite int
+	Cost       float64
+	Tools      []string
+}
+
+// estimateUsageCost prices one reply with the model table. The reply's
+// prompt size (input plus cached tokens) selects the long-context tier.
+func estimateUsageCost(model string, u TokenUsage) float64 {
+	u.Prompt = u.Input + u.CacheRead + u.CacheWrite
+	return LookupModel(model).Cost(u)
+}
+
+// aggregateUsage sums events into one row per local day and model.
+func aggregateUsage(events []usageEvent) []*statedb.UsageRow {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+
+	type key struct{ day, model string }
+	byKey := make(map[key]*statedb.UsageRow)
+	var order []key
+	row := func(t time.Time, model string) *statedb.UsageRow {
+		k := key{t.Local().Format("2006-01-02"), model}
+		r, ok := byKey[k]
+		if !ok {
+			r = &statedb.UsageRow{Day: k.day, Model: model, ToolCalls: map[string]int{}}
+			byKey[k] = r
+			order = append(order, k)
+		}
+		return r
+	}
+
+	// Prompts logged before the first reply belong to the session's first model
+	var model string
+	for _, e := range events {
+		if e.Model != "" {
+			model = e.Model
+			break
+		}
+	}
+	var prev time.Time
+	for _, e := range events {
+		if e.Time.IsZero() {
+			continue
+		}
+		if e.Model != "" {
+			model = e.Model
+		}
+		r := row(e.Time, model)
+		if !prev.IsZero() {
+			if gap := e.Time.Sub(prev); gap > 0 && gap <= usageIdleGap {
+				if e.Kind == usagePrompt {
+					r.Waiting += gap
+				} else {
+					r.Active += gap
+				}
+			}
+		}
+		prev = e.Time
+
+		if e.Kind == usageReply {
+			r.Turns++
+			r.InputTokens += e.Input
+			r.OutputTokens += e.Output
+			r.CacheReadTokens += e.CacheRead
+			r.CacheWriteTokens += e.CacheWrite
+			r.Cost += e.Cost
+		}
+		for _, tool := range e.Tools {
+			r.ToolCalls[tool]++
+		}
+	}
+
+	rows := make([]*statedb.UsageRow, 0, len(order))
+	for _, k := range order {
+		rows = append(rows, byKey[k])
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Day != rows[j].Day {
+			return rows[i].Day < rows[j].Day
+		}
+		return rows[i].Model < rows[j].Model
+	})
+	return rows
+}
+
+// usageSource locates the transcript an instance's usage is derived from.
+// fingerprint is the file whose size and mtime change whenever usage does.
+func usageSource(inst *Instance) (fingerprint string, parse func() ([]usageEvent, error)) {
+	path := TranscriptPath(inst)
+	if path == "" {
+		return "", nil
+	}
+	switch inst.GetToolThreadSafe() {
+	case "claude":
+		return path, func() ([]usageEvent, error) { return claudeUsageEvents(path) }
+	case "codex":
+		return path, func() ([]usageEvent, error) { return codexUsageEvents(path) }
+	case "gemini":
+		return path, func() ([]usageEvent, error) { return geminiUsageEvents(path) }
+	case "opencode":
+		dataDir, id := getOpenCodeDataDir(), inst.OpenCodeSessionID
+		return path, func() ([]usageEvent, error) { return openCodeUsageEvents(dataDir, id) }
+	}
+	return "", nil
+}
+
+// SessionUsage returns an instance's usage per day and model. Results are
+// cached in db (which may be nil) and reused while the transcript is
+// unchanged; refresh forces a re-parse. Sessions without a transcript return
+// no rows.
+func SessionUsage(db *statedb.StateDB, inst *Instance, refresh bool) ([]*statedb.UsageRow, error) {
+	path, parse := usageSource(inst)
+	if path == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if db != nil && !refresh {
+		cached, err := db.GetUsageSource(inst.ID)
+		if err == nil && cached != nil && cached.Path == path &&
+			cached.Size == info.Size() && cached.ModTime.Equal(info.ModTime()) {
+			return db.LoadUsage(inst.ID)
+		}
+	}
+
+	events, err := parse()
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	rows := aggregateUsage(events)
+	for _, r := range rows {
+		r.InstanceID = inst.ID
+	}
+	if db != nil {
+		src := &statedb.UsageSourceRow{InstanceID: inst.ID, Path: path, Size: info.Size(), ModTime: info.ModTime()}
+		if err := db.ReplaceUsage(src, rows); err != nil {
+			sessionLog.Warn("usage_cache_write_failed", slog.String("session_id", inst.ID), slog.String("error", err.Error()))
+		}
+	}
+	return rows, nil
+}
+
+// usagePricingMetaKey records the model table the cached costs were computed with
+const usagePricingMetaKey = "usage_pricing"
+
+// ResetStaleUsageCache drops a profile's cached usage when the model table
+// (and so the cached cost estimates) changed since it was computed. Call it
+// once before a batch of SessionUsage calls.
+func ResetStaleUsageCache(db *statedb.StateDB) error {
+	fingerprint := ModelTableFingerprint()
+	stored, err := db.GetMeta(usagePricingMetaKey)
+	if err != nil || stored == fingerprint {
+		return err
+	}
+	if err := db.PruneUsage(nil); err != nil { // Keep none
+		return err
+	}
+	return db.SetMeta(usagePricingMetaKey, fingerprint)
+}
+
+// --- Claude ---
+
+type claudeUsageEntry struct {
+	Type      string `json:"type"`
+	Timestamp string `json:"timestamp"`
+	IsMeta    bool   `json:"isMeta"`
+	Message   struct {
+		ID      string          `json:"id"`
+		Model   string          `json:"model"`
+		Content json.RawMessage `json:"content"`
+		Usage   struct {
+			InputTokens              int `json:"input_tokens"`
+			OutputTokens             int `json:"output_tokens"`
+			CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+			CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+			CacheCreation            struct {
+				Ephemeral1hInputTokens int `json:"ephemeral_1h_input_tokens"`
+			} `json:"cache_creation"`
+		} `json:"usage"`
+	} `json:"message"`
+}
+
+// claudeUsageEvents reads a Claude JSONL transcript. Claude writes one line
+// per content block, repeating the message's usage on each, so usage is
+// counted once per message ID.
+func claudeUsageEvents(path string) ([]usageEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []usageEvent
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	buf := make([]byte, 0, 1024*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+	for scanner.Scan() {
+		var entry claudeUsageEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		e := usageEvent{Time: parseTranscriptTime(entry.Timestamp), Kind: usageActivity}
+		switch entry.Type {
+		case "user":
+			if !entry.IsMeta && claudeIsPrompt(entry.Message.Content) {
+				e.Kind = usagePrompt
+			}
+		case "assistant":
+			var blocks []struct {
+				Type string `json:"type"`
+				Name string `json:"name"`
+			}
+			if json.Unmarshal(entry.Message.Content, &blocks) == nil {
+				for _, b := range blocks {
+					if b.Type == "tool_use" && b.Name != "" {
+						e.Tools = append(e.Tools, b.Name)
+					}
+				}
+			}
+			if entry.Message.Model != "" && entry.Message.Model != "<synthetic>" {
+				e.Model = entry.Message.Model
+			}
+			if id := entry.Message.ID; id == "" || !seen[id] {
+				seen[id] = true
+				u := entry.Message.Usage
+				e.Kind = usageReply
+				e.Input, e.Output = u.InputTokens, u.OutputTokens
+				e.CacheRead, e.CacheWrite = u.CacheReadInputTokens, u.CacheCreationInputTokens
+				e.Cost = estimateUsageCost(e.Model, TokenUsage{
+					Input: e.Input, Output: e.Output, CacheRead: e.CacheRead, CacheWrite: e.CacheWrite,
+					CacheWrite1h: u.CacheCreation.Ephemeral1hInputTokens,
+				})
+			}
+		default:
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// claudeIsPrompt reports whether user message content was typed by the user
+// rather than being tool results fed back to the model.
+func claudeIsPrompt(content json.RawMessage) bool {
+	var text string
+	if json.Unmarshal(content, &text) == nil {
+		return true
+	}
+	var blocks []struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(content, &blocks) != nil {
+		return false
+	}
+	for _, b := range blocks {
+		if b.Type == "tool_result" {
+			return false
+		}
+	}
+	return len(blocks) > 0
+}
+
+// --- Codex ---
+
+// codexUsageEvents reads a Codex rollout. token_count events carry running
+// totals, so each reply's usage is the difference from the previous total.
+func codexUsageEvents(path string) ([]usageEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []usageEvent
+	var total codexTokenUsage
+	var model string
+	scanner := bufio.NewScanner(file)
+	buf := make([]byte, 0, 1024*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+	for scanner.Scan() {
+		var record codexRecord
+		if json.Unmarshal(scanner.Bytes(), &record) != nil || len(record.Payload) == 0 {
+			continue
+		}
+		var p codexAnalyticsPayload
+		if json.Unmarshal(record.Payload, &p) != nil {
+			continue
+		}
+		e := usageEvent{Time: parseTranscriptTime(record.Timestamp), Kind: usageActivity}
+		switch {
+		case record.Type == "turn_context":
+			if p.Model != "" {
+				model = p.Model
+				e.Model = p.Model
+			}
+		case record.Type == "event_msg" && p.Type == "token_count":
+			if p.Info == nil || p.Info.TotalTokenUsage.TotalTokens == total.TotalTokens {
+				continue
+			}
+			cur := p.Info.TotalTokenUsage
+			cached := cur.CachedInputTokens - total.CachedInputTokens
+			e.Kind = usageReply
+			e.Input = cur.InputTokens - total.InputTokens - cached
+			e.CacheRead = cached
+			e.Output = cur.OutputTokens - total.OutputTokens
+			e.Cost = estimateUsageCost(model, TokenUsage{Input: e.Input, Output: e.Output, CacheRead: e.CacheRead})
+			total = cur
+		case record.Type == "event_msg" && p.Type == "user_message":
+			e.Kind = usagePrompt
+		case record.Type == "response_item":
+			switch p.Type {
+			case "function_call", "custom_tool_call":
+				if p.Name != "" {
+					e.Tools = []string{p.Name}
+				}
+			default:
+				if name := codexToolCallNames[p.Type]; name != "" {
+					e.Tools = []string{name}
+				}
+			}
+		default:
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// --- Gemini ---
+
+type geminiUsageFile struct {
+	Messages []struct {
+		Type      string `json:"type"`
+		Timestamp string `json:"timestamp"`
+		Model     string `json:"model"`
+		Tokens    struct {
+			Input  int `json:"input"`
+			Output int `json:"output"`
+			Cached int `json:"cached"`
+		} `json:"tokens"`
+		ToolCalls []struct {
+			Name string `json:"name"`
+		} `json:"toolCalls"`
+	} `json:"messages"`
+}
+
+// geminiUsageEvents reads a Gemini chat file. Gemini's input count includes
+// cached tokens.
+func geminiUsageEvents(path string) ([]usageEvent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var chat geminiUsageFile
+	if err := json.Unmarshal(data, &chat); err != nil {
+		return nil, err
+	}
+	var events []usageEvent
+	for _, m := range chat.Messages {
+		e := usageEvent{Time: parseTranscriptTime(m.Timestamp), Kind: usageActivity}
+		switch m.Type {
+		case "user":
+			e.Kind = usagePrompt
+		case "gemini":
+			e.Kind = usageReply
+			e.Model = m.Model
+			e.Input = m.Tokens.Input - m.Tokens.Cached
+			e.CacheRead = m.Tokens.Cached
+			e.Output = m.Tokens.Output
+			e.Cost = (&GeminiSessionAnalytics{InputTokens: m.Tokens.Input, OutputTokens: m.Tokens.Output}).CalculateCost(m.Model)
+			for _, tc := range m.ToolCalls {
+				if tc.Name != "" {
+					e.Tools = append(e.Tools, tc.Name)
+				}
+			}
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// --- OpenCode ---
+
+// findOpenCodeSessionFile returns storage/session/<project>/<id>.json.
+func findOpenCodeSessionFile(dataDir, sessionID string) string {
+	if sessionID == "" || strings.ContainsAny(sessionID, `/\*?[`) {
+		return ""
+	}
+	root := openCodeTranscriptSource{dir: dataDir}.Root()
+	matches, _ := filepath.Glob(filepath.Join(root, "*", sessionID+".json"))
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[0]
+}
+
+// openCodeUsageEvents reads an OpenCode session's messages. Assistant
+// messages carry OpenCode's own cost, which is used when non-zero.
+func openCodeUsageEvents(dataDir, sessionID string) ([]usageEvent, error) {
+	storage := openCodeTranscriptSource{dir: dataDir}.storage()
+	var events []usageEvent
+	for _, f := range readJSONDir(filepath.Join(storage, "message", sessionID)) {
+		var m openCodeUsageMessage
+		if json.Unmarshal(f, &m) != nil || m.ID == "" {
+			continue
+		}
+		switch m.Role {
+		case "user":
+			if m.Time.Created > 0 {
+				events = append(events, usageEvent{Time: time.UnixMilli(m.Time.Created), Kind: usagePrompt})
+			}
+		case "assistant":
+			// The pause before the reply started is the model working, so
+			// the start counts as activity and usage lands at completion
+			if m.Time.Created > 0 {
+				events = append(events, usageEvent{Time: time.UnixMilli(m.Time.Created), Kind: usageActivity, Model: m.ModelID})
+			}
+			done := m.Time.Completed
+			if done <= 0 {
+				done = m.Time.Created
+			}
+			e := usageEvent{
+				Time:       time.UnixMilli(done),
+				Kind:       usageReply,
+				Model:      m.ModelID,
+				Input:      m.Tokens.Input,
+				Output:     m.Tokens.Output + m.Tokens.Reasoning,
+				CacheRead:  m.Tokens.Cache.Read,
+				CacheWrite: m.Tokens.Cache.Write,
+				Cost:       m.Cost,
+			}
+			if e.Cost == 0 {
+				e.Cost = estimateUsageCost(m.ModelID, TokenUsage{Input: e.Input, Output: e.Output, CacheRead: e.CacheRead, CacheWrite: e.CacheWrite})
+			}
+			for _, pf := range readJSONDir(filepath.Join(storage, "part", m.ID)) {
+				var p openCodeToolPart
+				if json.Unmarshal(pf, &p) == nil && p.Type == "tool" && p.Tool != "" {
+					e.Tools = append(e.Tools, p.Tool)
+				}
+			}
+			if done > 0 {
+				events = append(events, e)
+			}
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,195 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+)
+
+// Usage report dimensions
+const (
+	UsageBySession = "session"
+	UsageByGroup   = "group"
+	UsageByTool    = "tool"
+	UsageByModel   = "model"
+	UsageByDay     = "day"
+)
+
+// UsageDimensions lists the valid UsageReport groupings.
+var UsageDimensions = []string{UsageBySession, UsageByGroup, UsageByTool, UsageByModel, UsageByDay}
+
+// UsageReportSession is one session's usage rows as input to a report.
+type UsageReportSession struct {
+	Profile  string
+	Instance *Instance
+	Rows     []*statedb.UsageRow
+}
+
+// UsageTotals are summed usage figures. Durations are in seconds so JSON and
+// CSV consumers get plain numbers.
+type UsageTotals struct {
+	Sessions         int            `json:"sessions"`
+	InputTokens      int            `json:"input_tokens"`
+	OutputTokens     int            `json:"output_tokens"`
+	CacheReadTokens  int            `json:"cache_read_input_tokens"`
+	CacheWriteTokens int            `json:"cache_creation_input_tokens"`
+	TotalTokens      int            `json:"total_tokens"`
+	EstimatedCost    float64        `json:"estimated_cost"`
+	Turns            int            `json:"turns"`
+	ToolCalls        int            `json:"tool_calls"`
+	ToolBreakdown    map[string]int `json:"tool_breakdown,omitempty"`
+	ActiveSeconds    int64          `json:"active_seconds"`
+	WaitingSeconds   int64          `json:"waiting_seconds"`
+}
+
+// UsageReportRow is the usage of one session, group, tool, model or day.
+type UsageReportRow struct {
+	Key     string `json:"key"`
+	Profile string `json:"profile,omitempty"`
+	// Set when grouping by session
+	SessionID string `json:"session_id,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Group     string `json:"group,omitempty"`
+	Tool      string `json:"tool,omitempty"`
+	UsageTotals
+}
+
+// UsageReport aggregates usage over a time window.
+type UsageReport struct {
+	By    string           `json:"by"`
+	Since *time.Time       `json:"since,omitempty"`
+	Until *time.Time       `json:"until,omitempty"`
+	Rows  []UsageReportRow `json:"rows"`
+	Total UsageTotals      `json:"total"`
+}
+
+// usageDayInWindow reports whether a YYYY-MM-DD day overlaps [since, until).
+// Zero bounds are open.
+func usageDayInWindow(day string, since, until time.Time) bool {
+	start, err := time.ParseInLocation("2006-01-02", day, time.Local)
+	if err != nil {
+		return false
+	}
+	if !since.IsZero() && !start.AddDate(0, 0, 1).After(since) {
+		return false
+	}
+	if !until.IsZero() && !start.Before(until) {
+		return false
+	}
+	return true
+}
+
+// BuildUsageReport groups session usage by one of UsageDimensions, keeping
+// only days that overlap [since, until). Rows are sorted by day for
+// UsageByDay and by cost (highest first) otherwise.
+func BuildUsageReport(sessions []UsageReportSession, by string, since, until time.Time) (*UsageReport, error) {
+	valid := false
+	for _, d := range UsageDimensions {
+		valid = valid || d == by
+	}
+	if !valid {
+		return nil, fmt.Errorf("invalid report grouping %q (expected session, group, tool, model or day)", by)
+	}
+
+	report := &UsageReport{By: by}
+	if !since.IsZero() {
+		report.Since = &since
+	}
+	if !until.IsZero() {
+		report.Until = &until
+	}
+
+	type rowKey struct{ profile, key string }
+	rows := make(map[rowKey]*UsageReportRow)
+	var order []rowKey
+	sessionsSeen := make(map[rowKey]map[string]bool)
+	totalSessions := make(map[string]bool)
+
+	for _, s := range sessions {
+		inst := s.Instance
+		sessionKey := s.Profile + "/" + inst.ID
+		for _, u := range s.Rows {
+			if !usageDayInWindow(u.Day, since, until) {
+				continue
+			}
+			var k rowKey
+			switch by {
+			case UsageBySession:
+				k = rowKey{s.Profile, inst.ID}
+			case UsageByGroup:
+				k = rowKey{s.Profile, inst.GroupPath}
+			case UsageByTool:
+				k = rowKey{"", inst.Tool}
+			case UsageByModel:
+				k = rowKey{"", u.Model}
+			case UsageByDay:
+				k = rowKey{"", u.Day}
+			}
+			r, ok := rows[k]
+			if !ok {
+				r = &UsageReportRow{Key: k.key, Profile: k.profile}
+				if by == UsageBySession {
+					r.Key = inst.Title
+					r.SessionID = inst.ID
+					r.Title = inst.Title
+					r.Group = inst.GroupPath
+					r.Tool = inst.Tool
+				}
+				rows[k] = r
+				sessionsSeen[k] = make(map[string]bool)
+				order = append(order, k)
+			}
+			r.add(u)
+			report.Total.add(u)
+			sessionsSeen[k][sessionKey] = true
+			totalSessions[sessionKey] = true
+		}
+	}
+
+	for _, k := range order {
+		r := rows[k]
+		r.Sessions = len(sessionsSeen[k])
+		report.Rows = append(report.Rows, *r)
+	}
+	report.Total.Sessions = len(totalSessions)
+	if report.Rows == nil {
+		report.Rows = []UsageReportRow{}
+	}
+
+	sort.SliceStable(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if by == UsageByDay {
+			return a.Key < b.Key
+		}
+		if a.EstimatedCost != b.EstimatedCost {
+			return a.EstimatedCost > b.EstimatedCost
+		}
+		if a.Profile != b.Profile {
+			return a.Profile < b.Profile
+		}
+		return a.Key < b.Key
+	})
+	return report, nil
+}
+
+// add accumulates one usage row into the totals.
+func (t *UsageTotals) add(u *statedb.UsageRow) {
+	t.InputTokens += u.InputTokens
+	t.OutputTokens += u.OutputTokens
+	t.CacheReadTokens += u.CacheReadTokens
+	t.CacheWriteTokens += u.CacheWriteTokens
+	t.TotalTokens += u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheWriteTokens
+	t.EstimatedCost += u.Cost
+	t.Turns += u.Turns
+	for name, n := range u.ToolCalls {
+		if t.ToolBreakdown == nil {
+			t.ToolBreakdown = make(map[string]int)
+		}
+		t.ToolBreakdown[name] += n
+		t.ToolCalls += n
+	}
+	t.ActiveSeconds += int64(u.Active / time.Second)
+	t.WaitingSeconds += int64(u.Waiting / time.Second)
+}
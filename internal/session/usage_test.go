@@ -0,0 +1,259 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+)
+
+func TestAggregateUsageTimeAccounting(t *testing.T) {
+	t0 := time.Date(2025, 10, 18, 10, 0, 0, 0, time.Local)
+	events := []usageEvent{
+		{Time: t0.Add(2 * time.Minute), Kind: usagePrompt},                                          // waited 90s
+		{Time: t0, Kind: usagePrompt},                                                               // first event: no gap
+		{Time: t0.Add(10 * time.Second), Kind: usageActivity, Model: "m1", Tools: []string{"Bash"}}, // active 10s
+		{Time: t0.Add(30 * time.Second), Kind: usageReply, Input: 100, Output: 10, Cost: 0.25},      // active 20s
+		{Time: t0.Add(140 * time.Second), Kind: usageReply, Model: "m2", Input: 50, Cost: 0.5},      // active 20s
+		{Time: t0.Add(3 * time.Hour), Kind: usagePrompt},                                            // idle gap: neither
+		{Time: t0.Add(24 * time.Hour), Kind: usageReply, Input: 1},                                  // next day, idle gap
+	}
+
+	rows := aggregateUsage(events)
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rows (day/model), got %d: %+v", len(rows), rows)
+	}
+	byKey := map[string]*statedb.UsageRow{}
+	for _, r := range rows {
+		byKey[r.Day+"/"+r.Model] = r
+	}
+
+	m1 := byKey["2025-10-18/m1"]
+	if m1 == nil || m1.Turns != 1 || m1.InputTokens != 100 || m1.Cost != 0.25 || m1.ToolCalls["Bash"] != 1 {
+		t.Fatalf("m1 row = %+v", m1)
+	}
+	if m1.Active != 30*time.Second || m1.Waiting != 90*time.Second {
+		t.Errorf("m1 active/waiting = %v/%v, want 30s/90s", m1.Active, m1.Waiting)
+	}
+	m2 := byKey["2025-10-18/m2"]
+	if m2 == nil || m2.Active != 20*time.Second || m2.Waiting != 0 {
+		t.Errorf("m2 row = %+v", m2)
+	}
+	next := byKey["2025-10-19/m2"]
+	if next == nil || next.Turns != 1 || next.Active != 0 {
+		t.Errorf("next-day row = %+v", next)
+	}
+}
+
+func TestClaudeUsageEventsCountsMessageOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	writeTestFile(t, path, `{"type":"user","timestamp":"2025-10-18T10:00:00Z","message":{"role":"user","content":"run the tests"}}
+{"type":"assistant","timestamp":"2025-10-18T10:00:05Z","message":{"id":"msg_1","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":100,"output_tokens":20,"cache_read_input_tokens":1000,"cache_creation_input_tokens":50}}}
+{"type":"assistant","timestamp":"2025-10-18T10:00:06Z","message":{"id":"msg_1","model":"claude-sonnet-4-20250514","content":[{"type":"tool_use","name":"Bash"}],"usage":{"input_tokens":100,"output_tokens":20,"cache_read_input_tokens":1000,"cache_creation_input_tokens":50}}}
+{"type":"user","timestamp":"2025-10-18T10:00:20Z","message":{"role":"user","content":[{"type":"tool_result","content":"PASS"}]}}
+{"type":"user","timestamp":"2025-10-18T10:00:21Z","isMeta":true,"message":{"role":"user","content":"<command-name>/clear</command-name>"}}
+`)
+	events, err := claudeUsageEvents(path)
+	if err != nil {
+		t.Fatalf("claudeUsageEvents: %v", err)
+	}
+	rows := aggregateUsage(events)
+	if len(rows) != 1 {
+		t.Fatalf("Expected 1 row, got %+v", rows)
+	}
+	r := rows[0]
+	if r.Turns != 1 || r.InputTokens != 100 || r.CacheReadTokens != 1000 || r.CacheWriteTokens != 50 {
+		t.Errorf("duplicate message usage counted twice: %+v", r)
+	}
+	if r.ToolCalls["Bash"] != 1 {
+		t.Errorf("ToolCalls = %v", r.ToolCalls)
+	}
+	// Tool results and meta messages are agent activity, not user prompts
+	if r.Waiting != 0 || r.Active != 21*time.Second {
+		t.Errorf("active/waiting = %v/%v, want 21s/0", r.Active, r.Waiting)
+	}
+	if want := estimateUsageCost("claude-sonnet-4-20250514", TokenUsage{Input: 100, Output: 20, CacheRead: 1000, CacheWrite: 50}); r.Cost != want {
+		t.Errorf("Cost = %f, want %f", r.Cost, want)
+	}
+}
+
+func TestSessionUsageCachesCodexRollout(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("CODEX_HOME", home)
+	id := "0199f5a1-2b3c-7d4e-8f90-123456789abc"
+	writeTestFile(t, filepath.Join(home, "sessions", "2025", "10", "18", "rollout-2025-10-18T10-00-00-"+id+".jsonl"), codexAnalyticsRollout)
+
+	db, err := statedb.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	inst := &Instance{ID: "inst-1", Tool: "codex", CodexSessionID: id}
+	rows, err := SessionUsage(db, inst, false)
+	if err != nil {
+		t.Fatalf("SessionUsage: %v", err)
+	}
+	var turns, input, cached, output int
+	for _, r := range rows {
+		turns += r.Turns
+		input += r.InputTokens
+		cached += r.CacheReadTokens
+		output += r.OutputTokens
+	}
+	// Per-reply deltas of the running totals add back up to the final totals
+	if turns != 2 || input != 11000 || cached != 14000 || output != 1300 {
+		t.Errorf("totals = turns %d, in %d, cached %d, out %d", turns, input, cached, output)
+	}
+
+	// An unchanged transcript is served from the cache
+	src, _ := db.GetUsageSource(inst.ID)
+	if src == nil {
+		t.Fatal("Expected usage source cached")
+	}
+	if err := db.ReplaceUsage(src, []*statedb.UsageRow{{Day: "2025-10-18", Turns: 99}}); err != nil {
+		t.Fatalf("ReplaceUsage: %v", err)
+	}
+	cachedRows, err := SessionUsage(db, inst, false)
+	if err != nil || len(cachedRows) != 1 || cachedRows[0].Turns != 99 {
+		t.Errorf("Expected cached rows, got %+v, %v", cachedRows, err)
+	}
+	refreshed, err := SessionUsage(db, inst, true)
+	if err != nil || len(refreshed) == 0 || refreshed[0].Turns == 99 {
+		t.Errorf("Expected refresh to re-parse, got %+v, %v", refreshed, err)
+	}
+
+	// Sessions without a transcript have no usage
+	if rows, err := SessionUsage(db, &Instance{ID: "x", Tool: "shell"}, false); err != nil || rows != nil {
+		t.Errorf("shell session usage = %+v, %v", rows, err)
+	}
+}
+
+func TestOpenCodeUsageEvents(t *testing.T) {
+	dir := t.TempDir()
+	storage := filepath.Join(dir, "storage")
+	writeTestFile(t, filepath.Join(storage, "session", "proj", "ses_1.json"), `{"id":"ses_1","directory":"/p"}`)
+	writeTestFile(t, filepath.Join(storage, "message", "ses_1", "msg_1.json"),
+		`{"id":"msg_1","role":"user","time":{"created":1760781600000}}`)
+	writeTestFile(t, filepath.Join(storage, "message", "ses_1", "msg_2.json"),
+		`{"id":"msg_2","role":"assistant","modelID":"gpt-5","time":{"created":1760781601000,"completed":1760781631000},"cost":0.1,"tokens":{"input":10,"output":5,"reasoning":5,"cache":{"read":0,"write":0}}}`)
+	writeTestFile(t, filepath.Join(storage, "part", "msg_2", "prt_1.json"), `{"id":"prt_1","type":"tool","tool":"bash"}`)
+
+	if got := findOpenCodeSessionFile(dir, "ses_1"); got != filepath.Join(storage, "session", "proj", "ses_1.json") {
+		t.Errorf("findOpenCodeSessionFile = %q", got)
+	}
+	events, err := openCodeUsageEvents(dir, "ses_1")
+	if err != nil {
+		t.Fatalf("openCodeUsageEvents: %v", err)
+	}
+	rows := aggregateUsage(events)
+	if len(rows) != 1 {
+		t.Fatalf("Expected 1 row, got %+v", rows)
+	}
+	r := rows[0]
+	if r.Model != "gpt-5" || r.Turns != 1 || r.OutputTokens != 10 || r.Cost != 0.1 || r.ToolCalls["bash"] != 1 {
+		t.Errorf("row = %+v", r)
+	}
+	if r.Active != 31*time.Second {
+		t.Errorf("Active = %v, want 31s", r.Active)
+	}
+}
+
+func TestBuildUsageReport(t *testing.T) {
+	a := &Instance{ID: "a", Title: "api", GroupPath: "work", Tool: "claude"}
+	b := &Instance{ID: "b", Title: "web", GroupPath: "work", Tool: "codex"}
+	c := &Instance{ID: "c", Title: "notes", GroupPath: "personal", Tool: "claude"}
+	sessions := []UsageReportSession{
+		{Profile: "default", Instance: a, Rows: []*statedb.UsageRow{
+			{Day: "2025-10-16", Model: "sonnet", InputTokens: 100, Cost: 1, Turns: 1, ToolCalls: map[string]int{"Bash": 2}},
+			{Day: "2025-10-18", Model: "sonnet", InputTokens: 100, Cost: 2, Turns: 2, Active: time.Minute},
+		}},
+		{Profile: "default", Instance: b, Rows: []*statedb.UsageRow{
+			{Day: "2025-10-18", Model: "gpt-5", OutputTokens: 50, Cost: 0.5, Turns: 1, ToolCalls: map[string]int{"shell": 1}},
+		}},
+		{Profile: "default", Instance: c, Rows: []*statedb.UsageRow{
+			{Day: "2025-10-17", Model: "sonnet", InputTokens: 10, Cost: 4, Turns: 1},
+		}},
+	}
+	since := time.Date(2025, 10, 17, 0, 0, 0, 0, time.Local)
+
+	report, err := BuildUsageReport(sessions, UsageByGroup, since, time.Time{})
+	if err != nil {
+		t.Fatalf("BuildUsageReport: %v", err)
+	}
+	if len(report.Rows) != 2 {
+		t.Fatalf("Expected 2 groups, got %+v", report.Rows)
+	}
+	// Sorted by cost: personal ($4) before work ($2.50, the 10-16 row is outside the window)
+	if report.Rows[0].Key != "personal" || report.Rows[1].Key != "work" {
+		t.Errorf("order = %s, %s", report.Rows[0].Key, report.Rows[1].Key)
+	}
+	work := report.Rows[1]
+	if work.Sessions != 2 || work.EstimatedCost != 2.5 || work.Turns != 3 || work.ToolCalls != 1 || work.ActiveSeconds != 60 {
+		t.Errorf("work row = %+v", work)
+	}
+	if report.Total.Sessions != 3 || report.Total.EstimatedCost != 6.5 || report.Total.TotalTokens != 160 {
+		t.Errorf("total = %+v", report.Total)
+	}
+
+	byDay, err := BuildUsageReport(sessions, UsageByDay, time.Time{}, time.Date(2025, 10, 18, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatalf("BuildUsageReport: %v", err)
+	}
+	if len(byDay.Rows) != 2 || byDay.Rows[0].Key != "2025-10-16" || byDay.Rows[1].Key != "2025-10-17" {
+		t.Errorf("day rows = %+v", byDay.Rows)
+	}
+
+	bySession, _ := BuildUsageReport(sessions, UsageBySession, time.Time{}, time.Time{})
+	if len(bySession.Rows) != 3 || bySession.Rows[0].SessionID != "c" || bySession.Rows[0].Key != "notes" {
+		t.Errorf("session rows = %+v", bySession.Rows)
+	}
+
+	if _, err := BuildUsageReport(sessions, "week", time.Time{}, time.Time{}); err == nil {
+		t.Error("Expected error for invalid grouping")
+	}
+}
+
+func TestResetStaleUsageCache(t *testing.T) {
+	withModelOverrides(t, "", "")
+	db, err := statedb.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	src := &statedb.UsageSourceRow{InstanceID: "a", Path: "/t.jsonl", Size: 1, ModTime: time.Unix(1, 0)}
+	cache := func() {
+		if err := db.ReplaceUsage(src, []*statedb.UsageRow{{InstanceID: "a", Day: "2025-10-18", Turns: 1}}); err != nil {
+			t.Fatalf("ReplaceUsage: %v", err)
+		}
+	}
+
+	// First run records the table; an unchanged table keeps the cache
+	cache()
+	if err := ResetStaleUsageCache(db); err != nil {
+		t.Fatalf("ResetStaleUsageCache: %v", err)
+	}
+	cache()
+	if err := ResetStaleUsageCache(db); err != nil {
+		t.Fatalf("ResetStaleUsageCache: %v", err)
+	}
+	if got, _ := db.GetUsageSource("a"); got == nil {
+		t.Fatal("Expected cache kept for unchanged model table")
+	}
+
+	// A price change invalidates every cached cost
+	withModelOverrides(t, "[models.default]\ninput = 1.0\n", "")
+	if err := ResetStaleUsageCache(db); err != nil {
+		t.Fatalf("ResetStaleUsageCache: %v", err)
+	}
+	if got, _ := db.GetUsageSource("a"); got != nil {
+		t.Errorf("Expected cache dropped after price change, got %+v", got)
+	}
+}
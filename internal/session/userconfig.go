@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/BurntSushi/toml"
 
@@ -108,6 +109,46 @@ type UserConfig struct {
 
 	// Vagrant defines Vagrant VM settings for vagrant mode
 	Vagrant VagrantSettings `toml:"vagrant"`
+
+	// Models overrides the built-in model table (context window sizes and
+	// pricing) and ~/.agent-deck/models.toml. Keys are model names as
+	// reported in session transcripts; "*" is a wildcard.
+	// Example:
+	// [models."claude-opus-4*"]
+	// context_limit = 1000000
+	// input = 15.0
+	Models map[string]ModelDef `toml:"models"`
+
+	// Context defines automatic context-window management
+	Context ContextSettings `toml:"context"`
+
+	// Checkpoints defines automatic git snapshots of work trees per agent turn
+	Checkpoints CheckpointSettings `toml:"checkpoints"`
+
+	// Ports defines per-session port allocation for dev servers
+	Ports PortSettings `toml:"ports"`
+
+	// Forge configures pull request lookups for launch --pr
+	Forge ForgeSettings `toml:"forge"`
+
+	// Workspaces defines named sets of repositories and directories that a
+	// session works on together (launch --workspace).
+	// Example:
+	// [workspaces.shop]
+	// repos = ["~/src/shop-api", "~/src/shop-web"]
+	// paths = ["~/src/shared-protos"]
+	Workspaces map[string]WorkspaceDef `toml:"workspaces"`
+
+	// SessionHooks runs user commands on session lifecycle events
+	// (session.created, session.deleted, ...) with a JSON payload on stdin
+	SessionHooks SessionHookSettings `toml:"session_hooks"`
+
+	// Metrics configures the standalone Prometheus /metrics listener
+	Metrics MetricsSettings `toml:"metrics"`
+
+	// Federation adds other local profiles and agent-deck web servers to
+	// the web UI
+	Federation FederationSettings `toml:"federation"`
 }
 
 // ProfileSettings defines per-profile configuration overrides.
@@ -522,6 +563,10 @@ type WorktreeSettings struct {
 	// Unknown variables like {foo} are left as-is in the path.
 	// If set, overrides DefaultLocation.
 	PathTemplate *string `toml:"path_template"`
+
+	// File copying, setup and teardown commands for worktrees. A repository
+	// can override these in .agent-deck/worktree.toml (see LoadWorktreeHooks).
+	WorktreeHookSettings
 }
 
 // Template returns the path template if set, or empty string if nil.
@@ -537,10 +582,11 @@ type GlobalSearchSettings struct {
 	// Enabled enables/disables global search feature (default: true when loaded via LoadUserConfig)
 	Enabled bool `toml:"enabled"`
 
-	// Tier controls search strategy: "auto", "instant", "balanced", "disabled"
+	// Tier controls search strategy: "auto", "instant", "balanced", "persistent", "disabled"
 	// auto: Auto-detect based on data size (recommended)
 	// instant: Force full in-memory (fast, uses more RAM)
 	// balanced: Force LRU cache mode (slower, capped RAM)
+	// persistent: SQLite FTS5 index in ~/.agent-deck/search.db (incremental, low RAM)
 	// disabled: Disable global search entirely
 	Tier string `toml:"tier"`
 
@@ -555,6 +601,10 @@ type GlobalSearchSettings struct {
 	// IndexRateLimit limits files indexed per second during background indexing
 	// Lower = less CPU impact (default: 20)
 	IndexRateLimit int `toml:"index_rate_limit"`
+
+	// Tools limits which tools' transcripts are searched
+	// ("claude", "codex", "gemini", "opencode"; default: all)
+	Tools []string `toml:"tools"`
 }
 
 // ToolDef defines a custom AI tool
@@ -591,6 +641,10 @@ type ToolDef struct {
 	// DangerousFlag is the CLI flag for dangerous mode (e.g., "--dangerously-skip-permissions")
 	DangerousFlag string `toml:"dangerous_flag"`
 
+	// AddDirFlag is the CLI flag granting access to another directory, repeated
+	// for a parent session's project and a workspace's other repos (e.g., "--add-dir")
+	AddDirFlag string `toml:"add_dir_flag"`
+
 	// OutputFormatFlag is the CLI flag for JSON output format (e.g., "--output-format json")
 	OutputFormatFlag string `toml:"output_format_flag"`
 
@@ -621,6 +675,11 @@ type ToolDef struct {
 
 	// SpinnerCharsExtra appends additional spinner characters to the built-in defaults
 	SpinnerCharsExtra []string `toml:"spinner_chars_extra"`
+
+	// CompactCommand is the in-session command that compacts the conversation
+	// (e.g., "/compact"). Used by automatic context-window management.
+	// Built-in tools have defaults; custom tools must set this to support compaction.
+	CompactCommand string `toml:"compact_command"`
 }
 
 // HTTPServerConfig defines how to auto-start an HTTP MCP server
@@ -742,6 +801,15 @@ type MaintenanceSettings struct {
 	// Enabled enables the maintenance worker (default: false)
 	// Prunes Gemini logs, cleans old backups, archives bloated sessions
 	Enabled bool `toml:"enabled"`
+
+	// BackupIntervalHours snapshots each profile's state.db into
+	// profiles/<name>/backups/ when the newest snapshot is older than this
+	// many hours (default: 0 = scheduled backups off)
+	BackupIntervalHours int `toml:"backup_interval_hours"`
+
+	// BackupRetention is how many snapshots to keep per profile, counting
+	// manual and pre-restore ones (default: 7)
+	BackupRetention int `toml:"backup_retention"`
 }
 
 // VagrantSettings defines Vagrant VM settings for vagrant mode
@@ -764,6 +832,166 @@ type VagrantSettings struct {
 	ForwardProxyEnv     *bool             `toml:"forward_proxy_env"`          // Default: true — auto-forward host proxy vars
 }
 
+// ContextSettings configures automatic context-window management.
+// The top-level fields are the global policy; [context.groups."<path>"]
+// overrides them for a group and its subgroups, and per-session overrides
+// (agent-deck session set <id> context-action ...) take precedence over both.
+//
+// Example config.toml:
+//
+//	[context]
+//	action = "compact"
+//	threshold = 80
+//
+//	[context.groups."work/long-running"]
+//	action = "fork"
+type ContextSettings struct {
+	// Action is what to do when the threshold is crossed:
+	// "off" (default), "compact" (send the tool's compact command), or
+	// "fork" (start a fresh session seeded with a summary)
+	Action string `toml:"action"`
+
+	// Threshold is the context usage percentage that triggers the action (default: 80)
+	Threshold int `toml:"threshold"`
+
+	// IdleSeconds is how long a session must be idle before acting (default: 60)
+	IdleSeconds int `toml:"idle_seconds"`
+
+	// Groups holds per-group policy overrides keyed by group path
+	Groups map[string]ContextPolicy `toml:"groups"`
+}
+
+// DefaultCheckpointKeep is how many checkpoints are kept per session when
+// [checkpoints] keep is unset.
+const DefaultCheckpointKeep = 20
+
+// CheckpointSettings configures automatic work tree checkpoints. When
+// enabled, each finished agent turn snapshots the session's work tree into
+// refs/agent-deck/checkpoints/<session>/<n> (see agent-deck session
+// checkpoints).
+type CheckpointSettings struct {
+	// Enabled turns checkpointing on (default: false)
+	Enabled bool `toml:"enabled"`
+
+	// Keep is the number of checkpoints kept per session (default: 20)
+	Keep int `toml:"keep"`
+
+	// MaxAgeDays deletes checkpoints older than this; 0 keeps them regardless of age
+	MaxAgeDays int `toml:"max_age_days"`
+}
+
+// MaxAge returns MaxAgeDays as a duration (0 = no age limit).
+func (c CheckpointSettings) MaxAge() time.Duration {
+	if c.MaxAgeDays <= 0 {
+		return 0
+	}
+	return time.Duration(c.MaxAgeDays) * 24 * time.Hour
+}
+
+// Default [ports] values
+const (
+	DefaultPortRangeStart  = 4000
+	DefaultPortRangeEnd    = 4999
+	DefaultPortsPerSession = 2
+)
+
+// PortSettings configures port allocation. When enabled, each worktree
+// session (or every session, with AllSessions) leases PerSession ports from
+// the range when it starts, exported as PORT and AGENTDECK_PORT_1..N.
+type PortSettings struct {
+	// Enabled turns allocation on (default: false)
+	Enabled bool `toml:"enabled"`
+
+	// RangeStart and RangeEnd bound the ports handed out (default: 4000-4999)
+	RangeStart int `toml:"range_start"`
+	RangeEnd   int `toml:"range_end"`
+
+	// PerSession is the number of ports leased per session (default: 2)
+	PerSession int `toml:"per_session"`
+
+	// AllSessions allocates for every session, not just worktree sessions
+	AllSessions bool `toml:"all_sessions"`
+}
+
+// ForgeSettings configures the code hosting service that launch --pr asks
+// for pull requests. The provider is detected from the remote's host
+// (github.com, gitlab.com, github.* and gitlab.* hosts) unless set.
+type ForgeSettings struct {
+	// Provider is "github" or "gitlab" (default: detected from the host)
+	Provider string `toml:"provider"`
+
+	// APIURL overrides the API base URL, e.g. https://ghe.example.com/api/v3
+	// (required for hosts other than github.com and gitlab.com)
+	APIURL string `toml:"api_url"`
+
+	// TokenEnv names the environment variable holding the API token
+	// (default: GITHUB_TOKEN or GH_TOKEN for github.com, GITLAB_TOKEN for
+	// gitlab.com; other hosts get no token unless this is set)
+	TokenEnv string `toml:"token_env"`
+
+	// Remote is the git remote pull requests are fetched from (default: origin)
+	Remote string `toml:"remote"`
+}
+
+// FederationSettings lists what a federated web server shows next to its
+// own profile: other local profiles and other agent-deck web servers.
+type FederationSettings struct {
+	// Profiles are other local profiles to include
+	Profiles []string `toml:"profiles"`
+
+	// Upstreams are other agent-deck web servers, keyed by the host name
+	// their sessions are grouped under
+	Upstreams map[string]FederationUpstream `toml:"upstreams"`
+}
+
+// FederationUpstream is another agent-deck web server to federate.
+type FederationUpstream struct {
+	// URL is the server's base URL, e.g. "https://buildbox.lan:8420"
+	URL string `toml:"url"`
+
+	// Token is a web token with the read scope (terminal:input to type);
+	// TokenEnv names an environment variable holding it instead
+	Token    string `toml:"token"`
+	TokenEnv string `toml:"token_env"`
+
+	// CAFile trusts the upstream's CA ('agent-deck web cert export')
+	CAFile string `toml:"ca_file"`
+
+	// CertFile and KeyFile are a client certificate for upstreams started
+	// with --tls-client-auth
+	CertFile string `toml:"cert_file"`
+	KeyFile  string `toml:"key_file"`
+}
+
+// MetricsSettings configures the Prometheus metrics endpoint. The web server
+// always serves /metrics; Listen adds a standalone listener for when the TUI
+// runs without it.
+type MetricsSettings struct {
+	// Listen is the address of the standalone /metrics listener, e.g.
+	// "127.0.0.1:9464" (default: empty, disabled)
+	Listen string `toml:"listen"`
+
+	// Token is required as a Bearer token by the standalone listener, and
+	// for listening on anything but loopback; TokenEnv names an environment
+	// variable holding it instead
+	Token    string `toml:"token"`
+	TokenEnv string `toml:"token_env"`
+}
+
+// WorkspaceDef is a named set of repositories edited together. A session
+// created from it runs in the first repo and gets the others, plus Paths, as
+// extra directories; with a branch, every repo gets a worktree on it.
+type WorkspaceDef struct {
+	// Repos are git repositories; the first is the session's project
+	Repos []string `toml:"repos"`
+
+	// Paths are extra directories the agent may read and edit in place
+	Paths []string `toml:"paths"`
+
+	// Group is the default group for sessions of this workspace
+	Group string `toml:"group"`
+}
+
 // PortForward defines a port forwarding rule
 type PortForward struct {
 	Guest    int    `toml:"guest"`    // Port inside VM
@@ -858,6 +1086,7 @@ func ReloadUserConfig() (*UserConfig, error) {
 	userConfigCacheMu.Lock()
 	userConfigCache = nil
 	userConfigCacheMu.Unlock()
+	clearModelOverridesCache()
 	return LoadUserConfig()
 }
 
@@ -942,6 +1171,7 @@ func ClearUserConfigCache() {
 	userConfigCacheMu.Lock()
 	userConfigCache = nil
 	userConfigCacheMu.Unlock()
+	clearModelOverridesCache()
 }
 
 // GetToolDef returns a tool definition from user config
@@ -1265,9 +1495,13 @@ func GetNotificationsSettings() NotificationsConfig {
 func GetMaintenanceSettings() MaintenanceSettings {
 	config, err := LoadUserConfig()
 	if err != nil || config == nil {
-		return MaintenanceSettings{Enabled: false}
+		return MaintenanceSettings{Enabled: false, BackupRetention: DefaultBackupRetention}
 	}
-	return config.Maintenance
+	settings := config.Maintenance
+	if settings.BackupRetention <= 0 {
+		settings.BackupRetention = DefaultBackupRetention
+	}
+	return settings
 }
 
 // GetStatusSettings returns status detection settings with defaults applied.
@@ -1279,6 +1513,123 @@ func GetStatusSettings() StatusSettings {
 	return config.Status
 }
 
+// GetContextSettings returns context-window management settings from config.
+// Defaults are applied per policy by ResolveContextPolicy.
+func GetContextSettings() ContextSettings {
+	config, err := LoadUserConfig()
+	if err != nil || config == nil {
+		return ContextSettings{}
+	}
+	return config.Context
+}
+
+// GetCheckpointSettings returns checkpoint settings with defaults applied.
+func GetCheckpointSettings() CheckpointSettings {
+	config, err := LoadUserConfig()
+	if err != nil || config == nil {
+		return CheckpointSettings{Keep: DefaultCheckpointKeep}
+	}
+	settings := config.Checkpoints
+	if settings.Keep <= 0 {
+		settings.Keep = DefaultCheckpointKeep
+	}
+	return settings
+}
+
+// GetForgeSettings returns forge settings with defaults applied
+func GetForgeSettings() ForgeSettings {
+	config, err := LoadUserConfig()
+	var settings ForgeSettings
+	if err == nil && config != nil {
+		settings = config.Forge
+	}
+	if settings.Remote == "" {
+		settings.Remote = "origin"
+	}
+	return settings
+}
+
+// GetWorkspaceDef returns the named [workspaces] definition with ~ and
+// environment variables in its paths expanded, or nil if it is not defined.
+func GetWorkspaceDef(name string) *WorkspaceDef {
+	config, err := LoadUserConfig()
+	if err != nil || config == nil {
+		return nil
+	}
+	def, ok := config.Workspaces[name]
+	if !ok {
+		return nil
+	}
+	expanded := WorkspaceDef{Group: def.Group}
+	for _, repo := range def.Repos {
+		expanded.Repos = append(expanded.Repos, ExpandPath(repo))
+	}
+	for _, path := range def.Paths {
+		expanded.Paths = append(expanded.Paths, ExpandPath(path))
+	}
+	return &expanded
+}
+
+// GetWorkspaceNames returns the sorted names of the [workspaces] definitions.
+func GetWorkspaceNames() []string {
+	config, err := LoadUserConfig()
+	if err != nil || config == nil {
+		return nil
+	}
+	names := make([]string, 0, len(config.Workspaces))
+	for name := range config.Workspaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetSessionHookSettings returns the [session_hooks] configuration.
+func GetSessionHookSettings() SessionHookSettings {
+	config, err := LoadUserConfig()
+	if err != nil || config == nil {
+		return SessionHookSettings{}
+	}
+	return config.SessionHooks
+}
+
+// GetFederationSettings returns the [federation] configuration.
+func GetFederationSettings() FederationSettings {
+	config, err := LoadUserConfig()
+	if err != nil || config == nil {
+		return FederationSettings{}
+	}
+	return config.Federation
+}
+
+// GetMetricsSettings returns the [metrics] configuration.
+func GetMetricsSettings() MetricsSettings {
+	config, err := LoadUserConfig()
+	if err != nil || config == nil {
+		return MetricsSettings{}
+	}
+	return config.Metrics
+}
+
+// GetPortSettings returns port allocation settings with defaults applied
+func GetPortSettings() PortSettings {
+	config, err := LoadUserConfig()
+	var settings PortSettings
+	if err == nil && config != nil {
+		settings = config.Ports
+	}
+	if settings.RangeStart <= 0 {
+		settings.RangeStart = DefaultPortRangeStart
+	}
+	if settings.RangeEnd < settings.RangeStart {
+		settings.RangeEnd = max(DefaultPortRangeEnd, settings.RangeStart)
+	}
+	if settings.PerSession <= 0 {
+		settings.PerSession = DefaultPortsPerSession
+	}
+	return settings
+}
+
 // GetTmuxSettings returns tmux option overrides from config
 func GetTmuxSettings() TmuxSettings {
 	config, err := LoadUserConfig()
@@ -1441,6 +1792,14 @@ auto_cleanup = true
 # Custom path template (overrides default_location if set)
 # Variables: {repo-name}, {repo-root}, {branch}, {session-id}
 # path_template = "../worktrees/{repo-name}/{branch}"
+# Prepare new worktrees and tear them down. A repository can override these
+# keys in .agent-deck/worktree.toml (paths are relative to the main worktree)
+# copy = [".env", "config/*.local.yml"]   # Copied into new worktrees
+# symlink = ["node_modules"]              # Linked instead of copied
+# post_create = ["npm install"]           # Run in the new worktree; failure removes it
+# pre_finish = ["make test"]              # Run before worktree finish; failure aborts
+# post_finish = ["docker compose -p $AGENT_DECK_BRANCH down"]
+# hook_timeout_seconds = 600
 
 # Default scope for MCP operations: "local", "global", or "user"
 # "local" writes to .mcp.json (project-only, default)
@@ -1463,6 +1822,78 @@ auto_cleanup = true
 # Override tmux options applied to every session (applied after defaults)
 # options = { "allow-passthrough" = "all", "history-limit" = "50000" }
 
+# Automatic context-window management
+# When an idle session's context usage crosses the threshold, agent-deck either
+# sends the tool's compact command or forks a fresh session seeded with a summary.
+# [context]
+# action = "compact"        # "off" (default), "compact", or "fork"
+# threshold = 80            # Context usage percentage (default: 80)
+# idle_seconds = 60         # Only act after the session has been idle this long
+# Per-group override (applies to subgroups too)
+# [context.groups."work/long-running"]
+# action = "fork"
+
+# Git checkpoints: snapshot each session's work tree when an agent finishes a
+# turn, without touching your branch or index. Browse and restore with
+# "agent-deck session checkpoints <id>" or the TUI (C).
+# [checkpoints]
+# enabled = true
+# keep = 20                 # Checkpoints kept per session
+# max_age_days = 7          # Also drop checkpoints older than this (0 = never)
+
+# Port allocation: give each worktree session its own ports so parallel dev
+# servers don't collide. Exported as PORT and AGENTDECK_PORT_1..N.
+# [ports]
+# enabled = true
+# range_start = 4000
+# range_end = 4999
+# per_session = 2
+# all_sessions = false      # true: every session, not just worktree sessions
+
+# Pull request lookups for 'launch --pr' (provider detected from the remote host)
+# [forge]
+# provider = "github"       # github or gitlab
+# api_url = "https://ghe.example.com/api/v3"
+# token_env = "GITHUB_TOKEN"
+# remote = "origin"
+
+# Workspaces: repos edited together (agent-deck launch --workspace shop -w feat -b)
+# [workspaces.shop]
+# repos = ["~/src/shop-api", "~/src/shop-web"]   # first repo is the session's project
+# paths = ["~/src/shared-protos"]                # extra directories, used in place
+# group = "shop"
+
+# Session lifecycle hooks: commands run in the background with a JSON payload
+# on stdin. Try them with "agent-deck hooks test <event> [session]".
+# [session_hooks]
+# timeout_seconds = 30
+# [session_hooks.events]
+# "session.created" = ["~/bin/tracker register"]
+# "session.deleted" = ["~/bin/archive-logs"]
+# [session_hooks.groups.work]           # Sessions in work and its subgroups
+# "session.status_changed" = ["jq -e '.session.status == \"error\"' >/dev/null && ~/bin/post-alert"]
+# [session_hooks.tools.claude]
+# "session.started" = ["logger -t agent-deck started"]
+
+# Prometheus metrics: the web server serves /metrics; this adds a listener
+# for when the TUI runs without --web (or "agent-deck web")
+# [metrics]
+# listen = "127.0.0.1:9464"
+# token_env = "AGENT_DECK_METRICS_TOKEN"   # required to listen beyond loopback
+
+# Federation: the web UI also shows other local profiles and other machines'
+# agent-deck web servers, grouped by host and profile
+# [federation]
+# profiles = ["personal"]
+# [federation.upstreams.buildbox]
+# url = "https://buildbox.lan:8420"
+# token_env = "BUILDBOX_DECK_TOKEN"      # web token with the read scope
+# ca_file = "~/.agent-deck/buildbox-ca.crt"
+
+# Model table overrides (context window sizes used for the context bar)
+# [models."claude-sonnet-4-5-20250929"]
+# context_limit = 1000000
+
 # ============================================================================
 # MCP Server Definitions
 # ============================================================================
@@ -0,0 +1,255 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+)
+
+// WorkspaceState records the repositories of a session created from a
+// [workspaces] definition. The primary repo (Repos[0]) is the session's
+// project; with a branch, its worktree is also the session's worktree.
+type WorkspaceState struct {
+	Name string `json:"name"`
+	// Branch is the branch checked out in a worktree of every repo; empty
+	// when the repos are used in place
+	Branch string          `json:"branch,omitempty"`
+	Repos  []WorkspaceRepo `json:"repos"`
+	Paths  []string        `json:"paths,omitempty"`
+}
+
+// WorkspaceRepo is one repository of a workspace session.
+type WorkspaceRepo struct {
+	RepoRoot      string `json:"repo_root"`
+	WorktreePath  string `json:"worktree_path,omitempty"`
+	BranchCreated bool   `json:"branch_created,omitempty"`
+	// Finished records a completed finish step ("merged" or "pr") so a
+	// finish retried after a failure in another repo skips this one
+	Finished string `json:"finished,omitempty"`
+	PRURL    string `json:"pr_url,omitempty"`
+}
+
+// Dir returns the directory the session works in for this repo.
+func (r WorkspaceRepo) Dir() string {
+	if r.WorktreePath != "" {
+		return r.WorktreePath
+	}
+	return r.RepoRoot
+}
+
+// Name returns the repository's directory name, used in reports.
+func (r WorkspaceRepo) Name() string {
+	return filepath.Base(r.RepoRoot)
+}
+
+// WorkspaceOptions control how a workspace session's repos are prepared.
+type WorkspaceOptions struct {
+	// Branch creates a worktree on this branch in every repo (empty: use
+	// the repos in place)
+	Branch string
+	// NewBranch requires Branch not to exist yet in any repo
+	NewBranch bool
+	// Location overrides [worktree] default_location
+	Location string
+}
+
+// ResolveWorkspaceRepos checks that every repo of a definition is a git
+// repository and returns their repo roots, in order and without duplicates.
+func ResolveWorkspaceRepos(def *WorkspaceDef) ([]string, error) {
+	if len(def.Repos) == 0 {
+		return nil, errors.New("workspace has no repos")
+	}
+	var roots []string
+	seen := map[string]bool{}
+	for _, repo := range def.Repos {
+		if !git.IsGitRepo(repo) {
+			return nil, fmt.Errorf("%s is not a git repository", repo)
+		}
+		root, err := git.GetWorktreeBaseRoot(repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get repo root of %s: %w", repo, err)
+		}
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+// CreateWorkspace prepares the repos of a workspace definition. With
+// opts.Branch, every repo gets a worktree on that branch; if any of them
+// fails, the worktrees (and branches) already created are removed again so
+// nothing is left half set up.
+func CreateWorkspace(name string, def *WorkspaceDef, opts WorkspaceOptions, progress func(string)) (*WorkspaceState, error) {
+	roots, err := ResolveWorkspaceRepos(def)
+	if err != nil {
+		return nil, err
+	}
+	state := &WorkspaceState{Name: name, Branch: opts.Branch, Paths: def.Paths}
+	if opts.Branch == "" {
+		for _, root := range roots {
+			state.Repos = append(state.Repos, WorkspaceRepo{RepoRoot: root})
+		}
+		return state, nil
+	}
+
+	if err := git.ValidateBranchName(opts.Branch); err != nil {
+		return nil, fmt.Errorf("invalid branch name: %w", err)
+	}
+	wtSettings := GetWorktreeSettings()
+	location := wtSettings.DefaultLocation
+	if opts.Location != "" {
+		location = opts.Location
+	}
+	// One path ID for all repos keeps {session-id} templates aligned
+	pathID := git.GeneratePathID()
+	for _, root := range roots {
+		if opts.NewBranch && git.BranchExists(root, opts.Branch) {
+			return nil, fmt.Errorf("branch '%s' already exists in %s (remove -b flag to use existing branch)", opts.Branch, root)
+		}
+		path := git.WorktreePath(git.WorktreePathOptions{
+			Branch:    opts.Branch,
+			Location:  location,
+			RepoDir:   root,
+			SessionID: pathID,
+			Template:  wtSettings.Template(),
+		})
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("worktree already exists at %s", path)
+		}
+		state.Repos = append(state.Repos, WorkspaceRepo{
+			RepoRoot:      root,
+			WorktreePath:  path,
+			BranchCreated: !git.BranchExists(root, opts.Branch),
+		})
+	}
+
+	for n, repo := range state.Repos {
+		if progress != nil {
+			progress(fmt.Sprintf("%s: creating worktree on %s", repo.Name(), opts.Branch))
+		}
+		err := os.MkdirAll(filepath.Dir(repo.WorktreePath), 0o755)
+		if err == nil {
+			err = CreateWorktree(repo.RepoRoot, repo.WorktreePath, opts.Branch, progress)
+		}
+		if err != nil {
+			for _, created := range state.Repos[:n] {
+				DiscardWorktree(created.RepoRoot, created.WorktreePath, opts.Branch, created.BranchCreated)
+			}
+			return nil, fmt.Errorf("%s: %w", repo.Name(), err)
+		}
+	}
+	return state, nil
+}
+
+// Primary returns the workspace's first repo, the session's project.
+func (w *WorkspaceState) Primary() WorkspaceRepo {
+	if len(w.Repos) == 0 {
+		return WorkspaceRepo{}
+	}
+	return w.Repos[0]
+}
+
+// OtherWorktrees returns the worktrees of the repos besides the primary one,
+// which is the session's own worktree. Removing a workspace session removes
+// these too.
+func (w *WorkspaceState) OtherWorktrees() []WorkspaceRepo {
+	var repos []WorkspaceRepo
+	for n, repo := range w.Repos {
+		if n > 0 && repo.WorktreePath != "" {
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
+
+// ApplyTo points a new session at the workspace: its project is the primary
+// repo (or its worktree) and the workspace is recorded for extra dirs and
+// finish.
+func (w *WorkspaceState) ApplyTo(inst *Instance) {
+	primary := w.Primary()
+	inst.ProjectPath = primary.Dir()
+	if primary.WorktreePath != "" {
+		inst.WorktreePath = primary.WorktreePath
+		inst.WorktreeRepoRoot = primary.RepoRoot
+		inst.WorktreeBranch = w.Branch
+	}
+	inst.Workspace = w
+}
+
+// ExtraDirs returns the directories besides the project path that the
+// session's tool is given access to: the parent session's project, the
+// workspace's other repos and its extra paths.
+func (i *Instance) ExtraDirs() []string {
+	var dirs []string
+	seen := map[string]bool{i.ProjectPath: true}
+	add := func(dir string) {
+		if dir != "" && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	add(i.ParentProjectPath)
+	if w := i.Workspace; w != nil {
+		for _, repo := range w.Repos {
+			add(repo.Dir())
+		}
+		for _, path := range w.Paths {
+			add(path)
+		}
+	}
+	return dirs
+}
+
+// extraDirFlags repeats flag once per extra directory, e.g. " --add-dir a
+// --add-dir b". Returns "" when there are none.
+func (i *Instance) extraDirFlags(flag string) string {
+	var b strings.Builder
+	for _, dir := range i.ExtraDirs() {
+		fmt.Fprintf(&b, " %s %s", flag, shellArg(dir))
+	}
+	return b.String()
+}
+
+// shellArg quotes s for sh when it contains anything but plain path
+// characters.
+func shellArg(s string) string {
+	safe := s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("/._-+:@%,=", r))
+	}) < 0
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// marshalWorkspace encodes a session's workspace for the tool_data blob.
+func marshalWorkspace(w *WorkspaceState) json.RawMessage {
+	if w == nil {
+		return nil
+	}
+	data, err := json.Marshal(w)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// unmarshalWorkspace decodes a session's workspace from the tool_data blob.
+func unmarshalWorkspace(data json.RawMessage) *WorkspaceState {
+	if len(data) == 0 {
+		return nil
+	}
+	var w WorkspaceState
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil
+	}
+	return &w
+}
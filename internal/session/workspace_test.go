@@ -0,0 +1,142 @@
+package session
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+)
+
+// setupWorkspaceRepos creates an api and a web repo next to each other and a
+// config.toml with a "shop" workspace of both plus a shared docs directory.
+func setupWorkspaceRepos(t *testing.T) (api, web, docs string) {
+	t.Helper()
+	api = setupHookRepo(t, "")
+	web = filepath.Join(filepath.Dir(api), "web")
+	require.NoError(t, os.MkdirAll(web, 0o755))
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+		{"commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		out, err := exec.Command("git", append([]string{"-C", web}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	docs = t.TempDir()
+
+	config := "[worktree]\ndefault_location = \"subdirectory\"\n\n" +
+		"[workspaces.shop]\nrepos = [\"" + api + "\", \"" + web + "\", \"" + api + "\"]\npaths = [\"" + docs + "\"]\ngroup = \"shop\"\n"
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".agent-deck", "config.toml"), []byte(config), 0o644))
+	ClearUserConfigCache()
+	return api, web, docs
+}
+
+func TestGetWorkspaceDef(t *testing.T) {
+	api, web, docs := setupWorkspaceRepos(t)
+
+	assert.Equal(t, []string{"shop"}, GetWorkspaceNames())
+	assert.Nil(t, GetWorkspaceDef("missing"))
+	def := GetWorkspaceDef("shop")
+	require.NotNil(t, def)
+	assert.Equal(t, "shop", def.Group)
+	assert.Equal(t, []string{docs}, def.Paths)
+
+	roots, err := ResolveWorkspaceRepos(def)
+	require.NoError(t, err)
+	assert.Equal(t, []string{api, web}, roots, "duplicates are dropped")
+
+	_, err = ResolveWorkspaceRepos(&WorkspaceDef{Repos: []string{docs}})
+	assert.ErrorContains(t, err, "not a git repository")
+}
+
+func TestCreateWorkspace_Worktrees(t *testing.T) {
+	api, web, docs := setupWorkspaceRepos(t)
+
+	ws, err := CreateWorkspace("shop", GetWorkspaceDef("shop"), WorkspaceOptions{Branch: "feat/cart", NewBranch: true}, nil)
+	require.NoError(t, err)
+	require.Len(t, ws.Repos, 2)
+	for i, root := range []string{api, web} {
+		repo := ws.Repos[i]
+		assert.Equal(t, root, repo.RepoRoot)
+		assert.Equal(t, filepath.Join(root, ".worktrees", "feat-cart"), repo.WorktreePath)
+		assert.True(t, repo.BranchCreated)
+		branch, err := git.GetCurrentBranch(repo.WorktreePath)
+		require.NoError(t, err)
+		assert.Equal(t, "feat/cart", branch)
+	}
+
+	inst := NewInstance("cart", api)
+	ws.ApplyTo(inst)
+	assert.Equal(t, ws.Repos[0].WorktreePath, inst.ProjectPath)
+	assert.True(t, inst.IsWorktree())
+	assert.Equal(t, "feat/cart", inst.WorktreeBranch)
+	assert.Equal(t, []string{ws.Repos[1].WorktreePath, docs}, inst.ExtraDirs())
+
+	// -b refuses a branch that exists in any repo
+	_, err = CreateWorkspace("shop", GetWorkspaceDef("shop"), WorkspaceOptions{Branch: "feat/cart", NewBranch: true}, nil)
+	assert.ErrorContains(t, err, "already exists")
+}
+
+func TestCreateWorkspace_RollsBackOnFailure(t *testing.T) {
+	api, web, _ := setupWorkspaceRepos(t)
+	writeHookFile(t, web, WorktreeHookFile, "post_create = [\"exit 3\"]\n")
+
+	_, err := CreateWorkspace("shop", GetWorkspaceDef("shop"), WorkspaceOptions{Branch: "feat/x"}, nil)
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "web: "), err.Error())
+	for _, root := range []string{api, web} {
+		assert.False(t, git.BranchExists(root, "feat/x"), "branch left in %s", root)
+		assert.NoDirExists(t, filepath.Join(root, ".worktrees", "feat-x"))
+	}
+}
+
+func TestCreateWorkspace_InPlace(t *testing.T) {
+	api, web, _ := setupWorkspaceRepos(t)
+
+	ws, err := CreateWorkspace("shop", GetWorkspaceDef("shop"), WorkspaceOptions{}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, ws.Branch)
+	assert.Equal(t, []WorkspaceRepo{{RepoRoot: api}, {RepoRoot: web}}, ws.Repos)
+
+	inst := NewInstance("shop", "/elsewhere")
+	ws.ApplyTo(inst)
+	assert.Equal(t, api, inst.ProjectPath)
+	assert.False(t, inst.IsWorktree())
+}
+
+func TestExtraDirFlags(t *testing.T) {
+	inst := NewInstance("multi", "/src/api")
+	inst.ParentProjectPath = "/src/parent"
+	inst.Workspace = &WorkspaceState{
+		Name:  "shop",
+		Repos: []WorkspaceRepo{{RepoRoot: "/src/api"}, {RepoRoot: "/src/web"}},
+		Paths: []string{"/src/shared protos", "/src/web"},
+	}
+	assert.Equal(t, []string{"/src/parent", "/src/web", "/src/shared protos"}, inst.ExtraDirs())
+	assert.Equal(t, " --add-dir /src/parent --add-dir /src/web --add-dir '/src/shared protos'", inst.extraDirFlags("--add-dir"))
+
+	assert.Contains(t, inst.buildClaudeExtraFlags(nil), "--add-dir /src/web --add-dir '/src/shared protos'")
+
+	inst.Tool = "gemini"
+	assert.Contains(t, inst.buildGeminiCommand("gemini"), " --include-directories '/src/parent,/src/web,/src/shared protos'")
+
+	inst.Tool = "codex"
+	inst.CodexSessionID = "abc"
+	assert.Contains(t, inst.buildCodexCommand("codex"), "codex --add-dir /src/parent --add-dir /src/web --add-dir '/src/shared protos' resume abc")
+}
+
+func TestWorkspaceToolDataRoundTrip(t *testing.T) {
+	ws := &WorkspaceState{Name: "shop", Branch: "feat", Repos: []WorkspaceRepo{{RepoRoot: "/a", WorktreePath: "/a-feat", Finished: "merged"}}}
+	assert.Equal(t, ws, unmarshalWorkspace(marshalWorkspace(ws)))
+	assert.Nil(t, marshalWorkspace(nil))
+	assert.Nil(t, unmarshalWorkspace(nil))
+}
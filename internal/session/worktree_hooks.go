@@ -0,0 +1,367 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+	"github.com/asheshgoplani/agent-deck/internal/logging"
+)
+
+var worktreeLog = logging.ForComponent(logging.CompSession)
+
+// WorktreeHookFile is the per-repository worktree setup file, relative to the
+// main worktree. Its keys are those of WorktreeHookSettings; any key it sets
+// replaces the one from the [worktree] section of config.toml.
+const WorktreeHookFile = ".agent-deck/worktree.toml"
+
+// defaultWorktreeHookTimeout bounds each hook command when
+// hook_timeout_seconds is unset. Generous, since post_create commonly runs a
+// full dependency install.
+const defaultWorktreeHookTimeout = 10 * time.Minute
+
+// worktreeHookOutputTail is how many output lines a failed command reports.
+const worktreeHookOutputTail = 10
+
+// WorktreeHookSettings prepares new worktrees and tears them down. Paths and
+// globs are relative to the main worktree; commands run with sh -c.
+type WorktreeHookSettings struct {
+	// Copy lists files or globs (e.g. ".env", "config/*.local.yml") copied
+	// from the main worktree into new worktrees. Directories are copied
+	// recursively. Existing files in the new worktree are left alone.
+	Copy []string `toml:"copy"`
+
+	// Symlink lists files or globs linked into new worktrees instead of
+	// copied (e.g. "node_modules" to share an install).
+	Symlink []string `toml:"symlink"`
+
+	// PostCreate commands run in a new worktree after files are copied.
+	// A failure removes the worktree (and its branch, if it was created for it).
+	PostCreate []string `toml:"post_create"`
+
+	// PreFinish commands run in the worktree before "worktree finish"
+	// merges it. A failure aborts the finish.
+	PreFinish []string `toml:"pre_finish"`
+
+	// PostFinish commands run in the main worktree after the worktree is
+	// removed. Failures are reported as warnings.
+	PostFinish []string `toml:"post_finish"`
+
+	// HookTimeoutSeconds limits each command (default: 600)
+	HookTimeoutSeconds int `toml:"hook_timeout_seconds"`
+}
+
+// HasSetup reports whether new worktrees need any preparation.
+func (w WorktreeHookSettings) HasSetup() bool {
+	return len(w.Copy) > 0 || len(w.Symlink) > 0 || len(w.PostCreate) > 0
+}
+
+// HookTimeout returns the per-command timeout.
+func (w WorktreeHookSettings) HookTimeout() time.Duration {
+	if w.HookTimeoutSeconds <= 0 {
+		return defaultWorktreeHookTimeout
+	}
+	return time.Duration(w.HookTimeoutSeconds) * time.Second
+}
+
+// LoadWorktreeHooks returns the worktree hooks for the repository whose main
+// worktree is repoRoot: the [worktree] section of config.toml, overridden
+// key by key by repoRoot/.agent-deck/worktree.toml when present.
+func LoadWorktreeHooks(repoRoot string) (WorktreeHookSettings, error) {
+	hooks := GetWorktreeSettings().WorktreeHookSettings
+
+	path := filepath.Join(repoRoot, WorktreeHookFile)
+	var repo WorktreeHookSettings
+	meta, err := toml.DecodeFile(path, &repo)
+	if errors.Is(err, fs.ErrNotExist) {
+		return hooks, nil
+	}
+	if err != nil {
+		return hooks, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if meta.IsDefined("copy") {
+		hooks.Copy = repo.Copy
+	}
+	if meta.IsDefined("symlink") {
+		hooks.Symlink = repo.Symlink
+	}
+	if meta.IsDefined("post_create") {
+		hooks.PostCreate = repo.PostCreate
+	}
+	if meta.IsDefined("pre_finish") {
+		hooks.PreFinish = repo.PreFinish
+	}
+	if meta.IsDefined("post_finish") {
+		hooks.PostFinish = repo.PostFinish
+	}
+	if meta.IsDefined("hook_timeout_seconds") {
+		hooks.HookTimeoutSeconds = repo.HookTimeoutSeconds
+	}
+	return hooks, nil
+}
+
+// WorktreeHookEnv describes the worktree a hook runs for. It is passed to
+// commands as AGENT_DECK_* environment variables.
+type WorktreeHookEnv struct {
+	RepoRoot     string // Main worktree
+	WorktreePath string
+	Branch       string
+	SessionID    string // Empty while the session is being created
+}
+
+func (e WorktreeHookEnv) environ(stage string) []string {
+	env := append(os.Environ(),
+		"AGENT_DECK_HOOK="+stage,
+		"AGENT_DECK_REPO_ROOT="+e.RepoRoot,
+		"AGENT_DECK_WORKTREE_PATH="+e.WorktreePath,
+		"AGENT_DECK_BRANCH="+e.Branch,
+	)
+	if e.SessionID != "" {
+		env = append(env, "AGENT_DECK_SESSION_ID="+e.SessionID)
+	}
+	return env
+}
+
+// CreateWorktree creates a git worktree for branch at worktreePath and
+// prepares it with the repository's worktree hooks (copy, symlink,
+// post_create). progress (may be nil) receives one line per step and the
+// output of setup commands. If setup fails the worktree is removed again,
+// along with the branch when this call created it, so no half-prepared
+// worktree is left behind.
+func CreateWorktree(repoRoot, worktreePath, branch string, progress func(string)) error {
+	hooks, err := LoadWorktreeHooks(repoRoot)
+	if err != nil {
+		return err
+	}
+	branchExisted := git.BranchExists(repoRoot, branch)
+	if err := git.CreateWorktree(repoRoot, worktreePath, branch); err != nil {
+		return err
+	}
+	env := WorktreeHookEnv{RepoRoot: repoRoot, WorktreePath: worktreePath, Branch: branch}
+	if err := SetupWorktree(hooks, env, progress); err != nil {
+		DiscardWorktree(repoRoot, worktreePath, branch, !branchExisted)
+		return fmt.Errorf("worktree setup failed: %w", err)
+	}
+	return nil
+}
+
+// DiscardWorktree removes a worktree whose creation could not be completed,
+// and its branch when deleteBranch is set. Errors are logged, not returned:
+// the caller is already reporting the failure that led here.
+func DiscardWorktree(repoRoot, worktreePath, branch string, deleteBranch bool) {
+	if err := git.RemoveWorktree(repoRoot, worktreePath, true); err != nil {
+		worktreeLog.Warn("worktree_discard_failed", slog.String("path", worktreePath), slog.String("error", err.Error()))
+		_ = os.RemoveAll(worktreePath)
+	}
+	_ = git.PruneWorktrees(repoRoot)
+	if deleteBranch {
+		_ = git.DeleteBranch(repoRoot, branch, true)
+	}
+}
+
+// SetupWorktree copies and links files from the main worktree into a new
+// worktree, then runs the post_create commands in it.
+func SetupWorktree(hooks WorktreeHookSettings, env WorktreeHookEnv, progress func(string)) error {
+	if progress == nil {
+		progress = func(string) {}
+	}
+	for _, pattern := range hooks.Copy {
+		if err := linkWorktreeFiles(env.RepoRoot, env.WorktreePath, pattern, false, progress); err != nil {
+			return err
+		}
+	}
+	for _, pattern := range hooks.Symlink {
+		if err := linkWorktreeFiles(env.RepoRoot, env.WorktreePath, pattern, true, progress); err != nil {
+			return err
+		}
+	}
+	return runWorktreeHooks("post_create", hooks.PostCreate, env.WorktreePath, env, hooks.HookTimeout(), progress)
+}
+
+// RunPreFinishHooks runs the pre_finish commands in the worktree. An error
+// means the finish should not go ahead.
+func RunPreFinishHooks(hooks WorktreeHookSettings, env WorktreeHookEnv, progress func(string)) error {
+	return runWorktreeHooks("pre_finish", hooks.PreFinish, env.WorktreePath, env, hooks.HookTimeout(), progress)
+}
+
+// RunPostFinishHooks runs the post_finish commands in the main worktree,
+// after the worktree itself is gone.
+func RunPostFinishHooks(hooks WorktreeHookSettings, env WorktreeHookEnv, progress func(string)) error {
+	return runWorktreeHooks("post_finish", hooks.PostFinish, env.RepoRoot, env, hooks.HookTimeout(), progress)
+}
+
+// linkWorktreeFiles copies (or symlinks) the main worktree's matches of
+// pattern into the same place in the new worktree. Patterns matching
+// nothing are skipped: optional files like .env may not exist everywhere.
+func linkWorktreeFiles(repoRoot, worktreePath, pattern string, symlink bool, progress func(string)) error {
+	if filepath.IsAbs(pattern) || strings.HasPrefix(filepath.Clean(pattern), "..") {
+		return fmt.Errorf("worktree file %q must be relative to the repository", pattern)
+	}
+	matches, err := filepath.Glob(filepath.Join(repoRoot, pattern))
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	for _, src := range matches {
+		rel, err := filepath.Rel(repoRoot, src)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(worktreePath, rel)
+		if _, err := os.Lstat(dst); err == nil {
+			continue // Tracked or already prepared; don't clobber it
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		if symlink {
+			progress("Linking " + rel)
+			if err := os.Symlink(src, dst); err != nil {
+				return fmt.Errorf("failed to link %s: %w", rel, err)
+			}
+			continue
+		}
+		progress("Copying " + rel)
+		if err := copyWorktreePath(src, dst); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", rel, err)
+		}
+	}
+	return nil
+}
+
+// copyWorktreePath copies a file, symlink or directory tree, keeping modes.
+func copyWorktreePath(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case !info.Mode().IsRegular():
+			return nil // Sockets, fifos and devices aren't worth copying
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// runWorktreeHooks runs commands one at a time in dir, stopping at the
+// first failure. Output lines are forwarded to progress.
+func runWorktreeHooks(stage string, commands []string, dir string, env WorktreeHookEnv, timeout time.Duration, progress func(string)) error {
+	if progress == nil {
+		progress = func(string) {}
+	}
+	for _, command := range commands {
+		if strings.TrimSpace(command) == "" {
+			continue
+		}
+		progress("Running " + command)
+		start := time.Now()
+		err := runWorktreeHook(stage, command, dir, env, timeout, progress)
+		worktreeLog.Info("worktree_hook",
+			slog.String("stage", stage),
+			slog.String("command", command),
+			slog.String("dir", dir),
+			slog.Duration("duration", time.Since(start)),
+			slog.Bool("ok", err == nil))
+		if err != nil {
+			return fmt.Errorf("%s command %q failed: %w", stage, command, err)
+		}
+	}
+	return nil
+}
+
+func runWorktreeHook(stage, command, dir string, env WorktreeHookEnv, timeout time.Duration, progress func(string)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var tail []string
+	err := runShellHook(ctx, command, dir, env.environ(stage), nil, func(line string) {
+		progress(line)
+		tail = append(tail, line)
+		if len(tail) > worktreeHookOutputTail {
+			tail = tail[1:]
+		}
+	})
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil && len(tail) > 0 {
+		return fmt.Errorf("%w\n%s", err, strings.Join(tail, "\n"))
+	}
+	return err
+}
+
+// runShellHook runs a hook command with sh -c in dir and passes each
+// non-empty line of its combined output to onLine. It runs in its own
+// process group so cancelling ctx also stops whatever the command spawned
+// (npm, docker compose, ...).
+func runShellHook(ctx context.Context, command, dir string, env []string, stdin io.Reader, onLine func(string)) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdin = stdin
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = 5 * time.Second
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	scanned := make(chan struct{})
+	go func() {
+		defer close(scanned)
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				onLine(line)
+			}
+		}
+		_, _ = io.Copy(io.Discard, pr) // Drain an over-long line
+	}()
+
+	err := cmd.Run()
+	pw.Close()
+	<-scanned
+	return err
+}
@@ -0,0 +1,147 @@
+package session
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+)
+
+// setupHookRepo creates a repo with one commit under a temp HOME whose
+// config.toml holds globalConfig.
+func setupHookRepo(t *testing.T, globalConfig string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".agent-deck"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".agent-deck", "config.toml"), []byte(globalConfig), 0o644))
+	ClearUserConfigCache()
+	t.Cleanup(ClearUserConfigCache)
+
+	repo := filepath.Join(t.TempDir(), "repo")
+	require.NoError(t, os.MkdirAll(repo, 0o755))
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+		{"commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	return repo
+}
+
+func writeHookFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestLoadWorktreeHooks_RepoOverridesGlobal(t *testing.T) {
+	repo := setupHookRepo(t, "[worktree]\ncopy = [\".env\"]\npost_create = [\"make setup\"]\npre_finish = [\"make test\"]\n")
+
+	hooks, err := LoadWorktreeHooks(repo)
+	require.NoError(t, err)
+	assert.Equal(t, []string{".env"}, hooks.Copy)
+	assert.Equal(t, []string{"make setup"}, hooks.PostCreate)
+	assert.Equal(t, defaultWorktreeHookTimeout, hooks.HookTimeout())
+
+	writeHookFile(t, repo, WorktreeHookFile, "post_create = [\"npm ci\"]\npre_finish = []\nhook_timeout_seconds = 30\n")
+	hooks, err = LoadWorktreeHooks(repo)
+	require.NoError(t, err)
+	assert.Equal(t, []string{".env"}, hooks.Copy, "keys the repo file doesn't set come from config.toml")
+	assert.Equal(t, []string{"npm ci"}, hooks.PostCreate)
+	assert.Empty(t, hooks.PreFinish, "an empty list in the repo file disables the global hook")
+	assert.Equal(t, 30*time.Second, hooks.HookTimeout())
+
+	writeHookFile(t, repo, WorktreeHookFile, "post_create = \"not a list\"\n")
+	_, err = LoadWorktreeHooks(repo)
+	assert.Error(t, err)
+}
+
+func TestCreateWorktree_Setup(t *testing.T) {
+	repo := setupHookRepo(t, "")
+	writeHookFile(t, repo, ".env", "SECRET=1\n")
+	writeHookFile(t, repo, "config/app.local.yml", "debug: true\n")
+	writeHookFile(t, repo, "cache/data.bin", "cached\n")
+	writeHookFile(t, repo, WorktreeHookFile, `copy = [".env", "config/*.local.yml", "missing.txt"]
+symlink = ["cache"]
+post_create = ["echo \"$AGENT_DECK_HOOK $AGENT_DECK_BRANCH\" > setup.log", "echo done"]
+`)
+
+	wt := filepath.Join(filepath.Dir(repo), "repo-feature")
+	var progress []string
+	err := CreateWorktree(repo, wt, "feature", func(line string) { progress = append(progress, line) })
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(wt, ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, "SECRET=1\n", string(data))
+	assert.FileExists(t, filepath.Join(wt, "config", "app.local.yml"))
+	link, err := os.Readlink(filepath.Join(wt, "cache"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repo, "cache"), link)
+	data, err = os.ReadFile(filepath.Join(wt, "setup.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "post_create feature\n", string(data))
+
+	assert.Contains(t, progress, "Copying .env")
+	assert.Contains(t, progress, "Linking cache")
+	assert.Contains(t, progress, "done", "command output is streamed to progress")
+}
+
+func TestCreateWorktree_SetupFailureCleansUp(t *testing.T) {
+	repo := setupHookRepo(t, "[worktree]\npost_create = [\"echo installing\", \"echo boom >&2; exit 3\"]\n")
+	wt := filepath.Join(filepath.Dir(repo), "repo-broken")
+
+	err := CreateWorktree(repo, wt, "broken", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom", "the error carries the command's output")
+	assert.NoDirExists(t, wt)
+	assert.False(t, git.BranchExists(repo, "broken"), "a branch created for the worktree is deleted")
+
+	// An existing branch survives a failed setup
+	out, gitErr := exec.Command("git", "-C", repo, "branch", "kept").CombinedOutput()
+	require.NoError(t, gitErr, string(out))
+	require.Error(t, CreateWorktree(repo, filepath.Join(filepath.Dir(repo), "repo-kept"), "kept", nil))
+	assert.True(t, git.BranchExists(repo, "kept"))
+}
+
+func TestRunWorktreeHooks_Timeout(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now()
+	err := runWorktreeHooks("pre_finish", []string{"sleep 5"}, dir, WorktreeHookEnv{}, 200*time.Millisecond, nil)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "timed out"), err.Error())
+	assert.Less(t, time.Since(start), 4*time.Second)
+}
+
+func TestFinishHooks(t *testing.T) {
+	repo := t.TempDir()
+	wt := t.TempDir()
+	hooks := WorktreeHookSettings{
+		PreFinish:  []string{"pwd > pre.txt"},
+		PostFinish: []string{"echo $AGENT_DECK_SESSION_ID > post.txt"},
+	}
+	env := WorktreeHookEnv{RepoRoot: repo, WorktreePath: wt, Branch: "b", SessionID: "sess-1"}
+
+	require.NoError(t, RunPreFinishHooks(hooks, env, nil))
+	assert.FileExists(t, filepath.Join(wt, "pre.txt"), "pre_finish runs in the worktree")
+
+	require.NoError(t, RunPostFinishHooks(hooks, env, nil))
+	data, err := os.ReadFile(filepath.Join(repo, "post.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "sess-1\n", string(data), "post_finish runs in the main worktree")
+
+	hooks.PreFinish = []string{"false"}
+	assert.Error(t, RunPreFinishHooks(hooks, env, nil))
+}
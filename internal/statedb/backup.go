@@ -0,0 +1,301 @@
+package statedb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// backuper is implemented by modernc's driver connection and exposes
+// SQLite's online backup API.
+type backuper interface {
+	NewBackup(dstURI string) (*sqlite.Backup, error)
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// --- Backup / Restore ---
+
+// Backup writes a consistent snapshot of the database to dstPath using
+// SQLite's online backup API, so it is safe while other connections (the
+// TUI, CLI commands) are reading and writing. The snapshot is written to a
+// temporary file first and renamed into place; dstPath must not exist.
+func (s *StateDB) Backup(dstPath string) error {
+	if _, err := os.Stat(dstPath); err == nil {
+		return fmt.Errorf("statedb: backup: %s already exists", dstPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0700); err != nil {
+		return fmt.Errorf("statedb: backup mkdir: %w", err)
+	}
+	tmpPath := dstPath + ".tmp"
+	_ = os.Remove(tmpPath)
+
+	err := s.withBackuper(func(b backuper) error {
+		bck, err := b.NewBackup(tmpPath)
+		if err != nil {
+			return err
+		}
+		return runBackup(bck)
+	})
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("statedb: backup: %w", err)
+	}
+	if err := setRollbackJournal(tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("statedb: backup journal mode: %w", err)
+	}
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("statedb: backup chmod: %w", err)
+	}
+	if err := os.Rename(tmpPath, dstPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("statedb: backup rename: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the database contents with the snapshot at srcPath using
+// SQLite's backup API. Snapshots written by a newer binary (higher schema
+// version) are refused. Heartbeats of running processes are kept so a live
+// TUI isn't demoted, the schema is migrated forward, and last_modified is
+// touched so other instances reload.
+func (s *StateDB) Restore(srcPath string) error {
+	version, err := ReadSchemaVersion(srcPath)
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion {
+		return &SchemaTooNewError{Version: version, Supported: SchemaVersion}
+	}
+
+	heartbeats, err := s.loadHeartbeats()
+	if err != nil {
+		return fmt.Errorf("statedb: restore: read heartbeats: %w", err)
+	}
+
+	err = s.withBackuper(func(b backuper) error {
+		bck, err := b.NewRestore(readOnlyURI(srcPath))
+		if err != nil {
+			return err
+		}
+		return runBackup(bck)
+	})
+	if err != nil {
+		return fmt.Errorf("statedb: restore: %w", err)
+	}
+
+	if err := s.Migrate(); err != nil {
+		return err
+	}
+	if err := s.replaceHeartbeats(heartbeats); err != nil {
+		return fmt.Errorf("statedb: restore: write heartbeats: %w", err)
+	}
+	return s.Touch()
+}
+
+// ReadSchemaVersion opens the database at path read-only and returns its
+// recorded schema version. It fails if the file isn't an agent-deck state
+// database.
+func ReadSchemaVersion(path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, fmt.Errorf("statedb: %w", err)
+	}
+	db, err := sql.Open("sqlite", readOnlyURI(path))
+	if err != nil {
+		return 0, fmt.Errorf("statedb: open %s: %w", path, err)
+	}
+	defer db.Close()
+
+	var value string
+	err = db.QueryRow("SELECT value FROM metadata WHERE key = 'schema_version'").Scan(&value)
+	if err != nil {
+		return 0, fmt.Errorf("statedb: %s is not an agent-deck state database: %w", path, err)
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("statedb: %s has invalid schema version %q", path, value)
+	}
+	return version, nil
+}
+
+// OpenReadOnly opens an existing database (such as a backup snapshot)
+// without creating, migrating or otherwise modifying it.
+func OpenReadOnly(path string) (*StateDB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("statedb: %w", err)
+	}
+	db, err := sql.Open("sqlite", readOnlyURI(path))
+	if err != nil {
+		return nil, fmt.Errorf("statedb: open: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("statedb: open: %w", err)
+	}
+	return &StateDB{db: db, pid: os.Getpid()}, nil
+}
+
+// setRollbackJournal switches a fresh snapshot out of WAL mode (copied from
+// the source header) so it is a single self-contained file and read-only
+// opens don't leave -wal/-shm files next to it.
+func setRollbackJournal(path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("PRAGMA journal_mode=DELETE")
+	return err
+}
+
+// readOnlyURI builds a SQLite URI that opens path without write access.
+func readOnlyURI(path string) string {
+	u := url.URL{Scheme: "file", Path: path, RawQuery: "mode=ro"}
+	return u.String()
+}
+
+// withBackuper runs fn with the driver connection of a dedicated pool
+// connection.
+func (s *StateDB) withBackuper(fn func(backuper) error) error {
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(dc any) error {
+		b, ok := dc.(backuper)
+		if !ok {
+			return fmt.Errorf("sqlite driver does not support the backup API")
+		}
+		return fn(b)
+	})
+}
+
+// runBackup copies all pages in one step, which holds a read lock on the
+// source for the duration and so yields a consistent snapshot.
+func runBackup(bck *sqlite.Backup) error {
+	for more := true; more; {
+		var err error
+		more, err = bck.Step(-1)
+		if err != nil {
+			_ = bck.Finish()
+			return err
+		}
+	}
+	return bck.Finish()
+}
+
+type heartbeatRow struct {
+	pid, started, heartbeat int64
+	primary                 int
+}
+
+func (s *StateDB) loadHeartbeats() ([]heartbeatRow, error) {
+	rows, err := s.db.Query("SELECT pid, started, heartbeat, is_primary FROM instance_heartbeats")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []heartbeatRow
+	for rows.Next() {
+		var h heartbeatRow
+		if err := rows.Scan(&h.pid, &h.started, &h.heartbeat, &h.primary); err != nil {
+			return nil, err
+		}
+		result = append(result, h)
+	}
+	return result, rows.Err()
+}
+
+func (s *StateDB) replaceHeartbeats(heartbeats []heartbeatRow) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec("DELETE FROM instance_heartbeats"); err != nil {
+		return err
+	}
+	for _, h := range heartbeats {
+		if _, err := tx.Exec(
+			"INSERT INTO instance_heartbeats (pid, started, heartbeat, is_primary) VALUES (?, ?, ?, ?)",
+			h.pid, h.started, h.heartbeat, h.primary,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// --- JSON Dump ---
+
+// Dump is a human-readable snapshot of a profile's sessions, groups and
+// metadata, meant for inspection and for diffing two backups.
+type Dump struct {
+	SchemaVersion int               `json:"schema_version"`
+	DumpedAt      time.Time         `json:"dumped_at"`
+	Metadata      map[string]string `json:"metadata"`
+	Groups        []*GroupRow       `json:"groups"`
+	Instances     []*InstanceRow    `json:"instances"`
+}
+
+// Dump reads instances, groups and metadata into a Dump. Instances are
+// ordered by group and sort order so dumps of similar states diff cleanly.
+func (s *StateDB) Dump() (*Dump, error) {
+	d := &Dump{DumpedAt: time.Now().UTC(), Metadata: map[string]string{}}
+
+	rows, err := s.db.Query("SELECT key, value FROM metadata ORDER BY key")
+	if err != nil {
+		return nil, fmt.Errorf("statedb: dump metadata: %w", err)
+	}
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("statedb: dump metadata: %w", err)
+		}
+		d.Metadata[k] = v
+	}
+	rows.Close()
+	if v, err := strconv.Atoi(d.Metadata["schema_version"]); err == nil {
+		d.SchemaVersion = v
+	}
+
+	if d.Groups, err = s.LoadGroups(); err != nil {
+		return nil, fmt.Errorf("statedb: dump groups: %w", err)
+	}
+	if d.Instances, err = s.LoadInstances(); err != nil {
+		return nil, fmt.Errorf("statedb: dump instances: %w", err)
+	}
+	sortDumpInstances(d.Instances)
+	if d.Groups == nil {
+		d.Groups = []*GroupRow{}
+	}
+	if d.Instances == nil {
+		d.Instances = []*InstanceRow{}
+	}
+	return d, nil
+}
+
+func sortDumpInstances(insts []*InstanceRow) {
+	sort.SliceStable(insts, func(i, j int) bool {
+		a, b := insts[i], insts[j]
+		if a.GroupPath != b.GroupPath {
+			return a.GroupPath < b.GroupPath
+		}
+		if a.Order != b.Order {
+			return a.Order < b.Order
+		}
+		return a.ID < b.ID
+	})
+}
@@ -0,0 +1,130 @@
+package statedb
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+	if err := db.SaveInstances([]*InstanceRow{
+		{ID: "a", Title: "Alpha", ProjectPath: "/a", GroupPath: "grp", Tool: "claude", CreatedAt: now, ToolData: json.RawMessage("{}")},
+	}); err != nil {
+		t.Fatalf("SaveInstances: %v", err)
+	}
+	if err := db.SaveGroups([]*GroupRow{{Path: "grp", Name: "Group", Expanded: true}}); err != nil {
+		t.Fatalf("SaveGroups: %v", err)
+	}
+
+	backupPath := filepath.Join(t.TempDir(), "snap.db")
+	if err := db.Backup(backupPath); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if err := db.Backup(backupPath); err == nil {
+		t.Error("Backup should refuse to overwrite an existing file")
+	}
+	if v, err := ReadSchemaVersion(backupPath); err != nil || v != SchemaVersion {
+		t.Errorf("ReadSchemaVersion = %d, %v; want %d", v, err, SchemaVersion)
+	}
+
+	// Diverge from the snapshot, with a live TUI registered
+	if err := db.SaveInstances(nil); err != nil {
+		t.Fatalf("SaveInstances: %v", err)
+	}
+	if err := db.RegisterInstance(true); err != nil {
+		t.Fatalf("RegisterInstance: %v", err)
+	}
+	before, _ := db.LastModified()
+
+	if err := db.Restore(backupPath); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	loaded, err := db.LoadInstances()
+	if err != nil {
+		t.Fatalf("LoadInstances: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].ID != "a" {
+		t.Fatalf("restored instances = %+v, want [a]", loaded)
+	}
+	if n, _ := db.AliveInstanceCount(); n != 1 {
+		t.Errorf("AliveInstanceCount after restore = %d, want 1 (live heartbeats kept)", n)
+	}
+	if after, _ := db.LastModified(); after <= before {
+		t.Error("Restore should touch last_modified so other instances reload")
+	}
+}
+
+func TestRestoreRejectsNewerSchema(t *testing.T) {
+	db := newTestDB(t)
+	backupPath := filepath.Join(t.TempDir(), "snap.db")
+	if err := db.Backup(backupPath); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+
+	snap, err := Open(backupPath)
+	if err != nil {
+		t.Fatalf("Open snapshot: %v", err)
+	}
+	if err := snap.SetMeta("schema_version", "99"); err != nil {
+		t.Fatalf("SetMeta: %v", err)
+	}
+	snap.Close()
+
+	err = db.Restore(backupPath)
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Restore of newer snapshot: err = %v, want schema version error", err)
+	}
+
+	if _, err := ReadSchemaVersion(filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Error("ReadSchemaVersion should fail for a missing file")
+	}
+}
+
+func TestDump(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Unix(1700000000, 0)
+	if err := db.SaveInstances([]*InstanceRow{
+		{ID: "b", Title: "Beta", ProjectPath: "/b", GroupPath: "z", Order: 0, Tool: "shell", CreatedAt: now, ToolData: json.RawMessage(`{"x":1}`)},
+		{ID: "a", Title: "Alpha", ProjectPath: "/a", GroupPath: "a", Order: 1, Tool: "claude", CreatedAt: now},
+	}); err != nil {
+		t.Fatalf("SaveInstances: %v", err)
+	}
+	if err := db.SetMeta("note", "hi"); err != nil {
+		t.Fatalf("SetMeta: %v", err)
+	}
+
+	backupPath := filepath.Join(t.TempDir(), "snap.db")
+	if err := db.Backup(backupPath); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	snap, err := OpenReadOnly(backupPath)
+	if err != nil {
+		t.Fatalf("OpenReadOnly: %v", err)
+	}
+	defer snap.Close()
+
+	d, err := snap.Dump()
+	if err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+	if d.SchemaVersion != SchemaVersion || d.Metadata["note"] != "hi" {
+		t.Errorf("dump metadata = %v (version %d)", d.Metadata, d.SchemaVersion)
+	}
+	if len(d.Instances) != 2 || d.Instances[0].ID != "a" {
+		t.Fatalf("dump instances should be ordered by group: %+v", d.Instances)
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	for _, want := range []string{`"project_path":"/b"`, `"tool_data":{"x":1}`, `"groups":[]`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("dump JSON missing %s: %s", want, data)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package statedb
+
+import (
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/metrics"
+)
+
+// writeDuration times StateDB write methods by operation.
+var writeDuration = metrics.NewHistogramVec("agentdeck_statedb_write_duration_seconds",
+	"Time spent in state database writes by operation.", metrics.DurationBuckets, "op")
+
+// observeWrite records a write that began at start; call it deferred.
+func observeWrite(op string, start time.Time) {
+	writeDuration.ObserveSince(start, op)
+}
@@ -77,6 +77,8 @@ type toolDataBlob struct {
 	LatestPrompt       string          `json:"latest_prompt,omitempty"`
 	LoadedMCPNames     []string        `json:"loaded_mcp_names,omitempty"`
 	ToolOptions        json.RawMessage `json:"tool_options,omitempty"`
+	ContextPolicy      json.RawMessage `json:"context_policy,omitempty"`
+	Workspace          json.RawMessage `json:"workspace,omitempty"`
 }
 
 // MigrateFromJSON reads a sessions.json file and inserts all data into the StateDB.
@@ -179,7 +181,8 @@ func MarshalToolData(
 	openCodeSessionID string, openCodeDetectedAt time.Time,
 	codexSessionID string, codexDetectedAt time.Time,
 	latestPrompt string, loadedMCPNames []string,
-	toolOptionsJSON json.RawMessage,
+	toolOptionsJSON json.RawMessage, contextPolicyJSON json.RawMessage,
+	workspaceJSON json.RawMessage,
 ) json.RawMessage {
 	td := toolDataBlob{
 		ClaudeSessionID:   claudeSessionID,
@@ -191,6 +194,8 @@ func MarshalToolData(
 		LatestPrompt:      latestPrompt,
 		LoadedMCPNames:    loadedMCPNames,
 		ToolOptions:       toolOptionsJSON,
+		ContextPolicy:     contextPolicyJSON,
+		Workspace:         workspaceJSON,
 	}
 	if !claudeDetectedAt.IsZero() {
 		td.ClaudeDetectedAt = claudeDetectedAt.Unix()
@@ -217,7 +222,8 @@ func UnmarshalToolData(data json.RawMessage) (
 	openCodeSessionID string, openCodeDetectedAt time.Time,
 	codexSessionID string, codexDetectedAt time.Time,
 	latestPrompt string, loadedMCPNames []string,
-	toolOptionsJSON json.RawMessage,
+	toolOptionsJSON json.RawMessage, contextPolicyJSON json.RawMessage,
+	workspaceJSON json.RawMessage,
 ) {
 	if len(data) == 0 {
 		return
@@ -247,5 +253,7 @@ func UnmarshalToolData(data json.RawMessage) (
 	latestPrompt = td.LatestPrompt
 	loadedMCPNames = td.LoadedMCPNames
 	toolOptionsJSON = td.ToolOptions
+	contextPolicyJSON = td.ContextPolicy
+	workspaceJSON = td.Workspace
 	return
 }
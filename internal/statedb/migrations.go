@@ -0,0 +1,378 @@
+package statedb
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migration is one numbered, forward-only schema step. Steps are applied in
+// order, each in its own transaction, and recorded with a checksum of their
+// SQL in schema_migrations. Never edit a released step; append a new one
+// and bump SchemaVersion instead.
+type migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// migrations is the schema history. The last version must equal
+// SchemaVersion.
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS instances (
+				id              TEXT PRIMARY KEY,
+				title           TEXT NOT NULL,
+				project_path    TEXT NOT NULL,
+				group_path      TEXT NOT NULL DEFAULT 'my-sessions',
+				sort_order      INTEGER NOT NULL DEFAULT 0,
+				command         TEXT NOT NULL DEFAULT '',
+				wrapper         TEXT NOT NULL DEFAULT '',
+				tool            TEXT NOT NULL DEFAULT 'shell',
+				status          TEXT NOT NULL DEFAULT 'error',
+				tmux_session    TEXT NOT NULL DEFAULT '',
+				created_at      INTEGER NOT NULL,
+				last_accessed   INTEGER NOT NULL DEFAULT 0,
+				parent_session_id TEXT NOT NULL DEFAULT '',
+				worktree_path     TEXT NOT NULL DEFAULT '',
+				worktree_repo     TEXT NOT NULL DEFAULT '',
+				worktree_branch   TEXT NOT NULL DEFAULT '',
+				tool_data       TEXT NOT NULL DEFAULT '{}',
+				acknowledged    INTEGER NOT NULL DEFAULT 0
+			);
+			CREATE TABLE IF NOT EXISTS groups (
+				path         TEXT PRIMARY KEY,
+				name         TEXT NOT NULL,
+				expanded     INTEGER NOT NULL DEFAULT 1,
+				sort_order   INTEGER NOT NULL DEFAULT 0,
+				default_path TEXT NOT NULL DEFAULT ''
+			);
+			CREATE TABLE IF NOT EXISTS instance_heartbeats (
+				pid        INTEGER PRIMARY KEY,
+				started    INTEGER NOT NULL,
+				heartbeat  INTEGER NOT NULL,
+				is_primary INTEGER NOT NULL DEFAULT 0
+			);
+		`,
+	},
+	{
+		Version: 2,
+		Name:    "session history",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS session_history (
+				id          INTEGER PRIMARY KEY AUTOINCREMENT,
+				instance_id TEXT NOT NULL,
+				ts          INTEGER NOT NULL,
+				kind        TEXT NOT NULL,
+				message     TEXT NOT NULL DEFAULT ''
+			);
+			CREATE INDEX IF NOT EXISTS idx_session_history_instance
+			ON session_history (instance_id, ts);
+		`,
+	},
+	{
+		Version: 3,
+		Name:    "usage cache",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS usage_sources (
+				instance_id TEXT PRIMARY KEY,
+				path        TEXT NOT NULL,
+				size        INTEGER NOT NULL,
+				mtime       INTEGER NOT NULL
+			);
+			CREATE TABLE IF NOT EXISTS usage_daily (
+				instance_id        TEXT NOT NULL,
+				day                TEXT NOT NULL,
+				model              TEXT NOT NULL DEFAULT '',
+				input_tokens       INTEGER NOT NULL DEFAULT 0,
+				output_tokens      INTEGER NOT NULL DEFAULT 0,
+				cache_read_tokens  INTEGER NOT NULL DEFAULT 0,
+				cache_write_tokens INTEGER NOT NULL DEFAULT 0,
+				cost               REAL NOT NULL DEFAULT 0,
+				turns              INTEGER NOT NULL DEFAULT 0,
+				tool_calls         TEXT NOT NULL DEFAULT '{}',
+				active_ms          INTEGER NOT NULL DEFAULT 0,
+				waiting_ms         INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (instance_id, day, model)
+			);
+		`,
+	},
+	{
+		Version: 4,
+		Name:    "port leases",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS port_leases (
+				port        INTEGER PRIMARY KEY,
+				instance_id TEXT NOT NULL,
+				slot        INTEGER NOT NULL,
+				leased_at   INTEGER NOT NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_port_leases_instance
+			ON port_leases (instance_id, slot);
+		`,
+	},
+	{
+		Version: 5,
+		Name:    "web tokens",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS web_tokens (
+				name         TEXT PRIMARY KEY,
+				hash         TEXT NOT NULL UNIQUE,
+				scopes       TEXT NOT NULL DEFAULT '',
+				groups       TEXT NOT NULL DEFAULT '',
+				created_at   INTEGER NOT NULL,
+				expires_at   INTEGER NOT NULL DEFAULT 0,
+				last_used_at INTEGER NOT NULL DEFAULT 0
+			);
+			CREATE TABLE IF NOT EXISTS web_audit (
+				id          INTEGER PRIMARY KEY AUTOINCREMENT,
+				ts          INTEGER NOT NULL,
+				token_name  TEXT NOT NULL,
+				action      TEXT NOT NULL,
+				method      TEXT NOT NULL DEFAULT '',
+				path        TEXT NOT NULL DEFAULT '',
+				session_id  TEXT NOT NULL DEFAULT '',
+				remote_addr TEXT NOT NULL DEFAULT '',
+				status      INTEGER NOT NULL DEFAULT 0,
+				detail      TEXT NOT NULL DEFAULT ''
+			);
+			CREATE INDEX IF NOT EXISTS idx_web_audit_token
+			ON web_audit (token_name, ts);
+		`,
+	},
+	{
+		Version: 6,
+		Name:    "web shares",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS web_shares (
+				id          TEXT PRIMARY KEY,
+				session_id  TEXT NOT NULL,
+				allow_input INTEGER NOT NULL DEFAULT 0,
+				created_at  INTEGER NOT NULL,
+				expires_at  INTEGER NOT NULL,
+				revoked_at  INTEGER NOT NULL DEFAULT 0
+			);
+			CREATE INDEX IF NOT EXISTS idx_web_shares_session
+			ON web_shares (session_id);
+			CREATE TABLE IF NOT EXISTS web_share_viewers (
+				id              INTEGER PRIMARY KEY AUTOINCREMENT,
+				share_id        TEXT NOT NULL,
+				name            TEXT NOT NULL DEFAULT '',
+				remote_addr     TEXT NOT NULL DEFAULT '',
+				user_agent      TEXT NOT NULL DEFAULT '',
+				connected_at    INTEGER NOT NULL,
+				last_seen_at    INTEGER NOT NULL,
+				disconnected_at INTEGER NOT NULL DEFAULT 0
+			);
+			CREATE INDEX IF NOT EXISTS idx_web_share_viewers_share
+			ON web_share_viewers (share_id);
+		`,
+	},
+}
+
+// Checksum identifies a step's SQL, ignoring whitespace so reformatting a
+// step doesn't count as changing it.
+func (m migration) Checksum() string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(m.SQL), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// SchemaTooNewError is returned when a database was written by a newer
+// agent-deck than this binary.
+type SchemaTooNewError struct {
+	Version   int // schema version recorded in the database
+	Supported int // highest version this binary knows
+}
+
+func (e *SchemaTooNewError) Error() string {
+	return fmt.Sprintf("statedb: database schema version %d is newer than this agent-deck supports (%d); upgrade agent-deck, or restore an older backup with 'agent-deck profile restore'", e.Version, e.Supported)
+}
+
+// AppliedMigration is a row of schema_migrations.
+type AppliedMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Migrate brings the schema up to SchemaVersion by applying pending steps
+// from the migration registry. Databases created before the registry existed
+// are adopted from their metadata schema_version. Databases written by a
+// newer agent-deck are refused with a *SchemaTooNewError, and a step whose
+// recorded checksum no longer matches the registry is reported as an error.
+func (s *StateDB) Migrate() error {
+	return s.applyMigrations(migrations)
+}
+
+// AppliedMigrations returns the recorded migration steps in order.
+func (s *StateDB) AppliedMigrations() ([]AppliedMigration, error) {
+	rows, err := s.db.Query("SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("statedb: read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+	var result []AppliedMigration
+	for rows.Next() {
+		var m AppliedMigration
+		var applied int64
+		if err := rows.Scan(&m.Version, &m.Name, &m.Checksum, &applied); err != nil {
+			return nil, fmt.Errorf("statedb: read schema_migrations: %w", err)
+		}
+		m.AppliedAt = time.Unix(applied, 0)
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+func (s *StateDB) applyMigrations(steps []migration) error {
+	if _, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS metadata (
+			key   TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			name       TEXT NOT NULL,
+			checksum   TEXT NOT NULL,
+			applied_at INTEGER NOT NULL
+		)
+	`); err != nil {
+		return fmt.Errorf("statedb: create schema_migrations: %w", err)
+	}
+
+	latest := steps[len(steps)-1].Version
+	applied, err := s.AppliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		// Pre-registry database: its metadata version says which steps the
+		// old all-in-one Migrate already created.
+		legacy, err := s.legacySchemaVersion()
+		if err != nil {
+			return err
+		}
+		if legacy > latest {
+			return &SchemaTooNewError{Version: legacy, Supported: latest}
+		}
+		if legacy > 0 {
+			if err := s.adoptMigrations(steps, legacy); err != nil {
+				return err
+			}
+			if applied, err = s.AppliedMigrations(); err != nil {
+				return err
+			}
+		}
+	}
+
+	done := make(map[int]bool, len(applied))
+	for _, a := range applied {
+		if a.Version > latest {
+			return &SchemaTooNewError{Version: a.Version, Supported: latest}
+		}
+		done[a.Version] = true
+	}
+	for _, step := range steps {
+		if !done[step.Version] {
+			continue
+		}
+		for _, a := range applied {
+			if a.Version == step.Version && a.Checksum != step.Checksum() {
+				return fmt.Errorf("statedb: migration %d (%s) was applied with checksum %.12s but this agent-deck has %.12s; the database was migrated by an incompatible build", step.Version, step.Name, a.Checksum, step.Checksum())
+			}
+		}
+	}
+
+	for _, step := range steps {
+		if done[step.Version] {
+			continue
+		}
+		if err := s.applyMigration(step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyMigration runs one step and records it in a single transaction, so a
+// failed step leaves the database at the previous version.
+func (s *StateDB) applyMigration(step migration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("statedb: begin migration %d: %w", step.Version, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(step.SQL); err != nil {
+		return fmt.Errorf("statedb: migration %d (%s): %w", step.Version, step.Name, err)
+	}
+	if err := recordMigration(tx, step); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("statedb: commit migration %d: %w", step.Version, err)
+	}
+	return nil
+}
+
+// adoptMigrations records steps up to version as applied without running
+// them.
+func (s *StateDB) adoptMigrations(steps []migration, version int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("statedb: begin adopt: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, step := range steps {
+		if step.Version > version {
+			break
+		}
+		if err := recordMigration(tx, step); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func recordMigration(tx *sql.Tx, step migration) error {
+	if _, err := tx.Exec(
+		"INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)",
+		step.Version, step.Name, step.Checksum(), time.Now().Unix(),
+	); err != nil {
+		return fmt.Errorf("statedb: record migration %d: %w", step.Version, err)
+	}
+	// schema_version mirrors the latest step for readers that predate the
+	// registry (and for ReadSchemaVersion on snapshots)
+	if _, err := tx.Exec(
+		"INSERT OR REPLACE INTO metadata (key, value) VALUES ('schema_version', ?)",
+		strconv.Itoa(step.Version),
+	); err != nil {
+		return fmt.Errorf("statedb: set schema version: %w", err)
+	}
+	return nil
+}
+
+// legacySchemaVersion reads metadata.schema_version, returning 0 when unset.
+func (s *StateDB) legacySchemaVersion() (int, error) {
+	value, err := s.GetMeta("schema_version")
+	if err != nil {
+		return 0, fmt.Errorf("statedb: read schema version: %w", err)
+	}
+	if value == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("statedb: invalid schema version %q", value)
+	}
+	return v, nil
+}
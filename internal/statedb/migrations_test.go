@@ -0,0 +1,238 @@
+package statedb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openFixture creates a database from testdata/<name>.sql without migrating.
+func openFixture(t *testing.T, name string) *StateDB {
+	t.Helper()
+	sqlText, err := os.ReadFile(filepath.Join("testdata", name+".sql"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	db, err := Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.DB().Exec(string(sqlText)); err != nil {
+		t.Fatalf("load fixture %s: %v", name, err)
+	}
+	return db
+}
+
+// schemaOf returns the whitespace-normalized DDL of every table and index.
+func schemaOf(t *testing.T, db *StateDB) map[string]string {
+	t.Helper()
+	rows, err := db.DB().Query("SELECT name, sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	defer rows.Close()
+	schema := map[string]string{}
+	for rows.Next() {
+		var name, ddl string
+		if err := rows.Scan(&name, &ddl); err != nil {
+			t.Fatalf("scan schema: %v", err)
+		}
+		ddl = strings.Replace(ddl, "IF NOT EXISTS ", "", 1)
+		schema[name] = strings.Join(strings.Fields(ddl), " ")
+	}
+	return schema
+}
+
+func TestMigrationRegistry(t *testing.T) {
+	if got := migrations[len(migrations)-1].Version; got != SchemaVersion {
+		t.Fatalf("last migration is %d, SchemaVersion is %d", got, SchemaVersion)
+	}
+	seen := map[string]int{}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d; versions must be contiguous from 1", i, m.Version)
+		}
+		if prev, ok := seen[m.Checksum()]; ok {
+			t.Errorf("migrations %d and %d have the same checksum", prev, m.Version)
+		}
+		seen[m.Checksum()] = m.Version
+	}
+
+	reformatted := migration{Version: 1, SQL: "CREATE  TABLE x (\n\ta INTEGER\n)"}
+	if reformatted.Checksum() != (migration{SQL: "CREATE TABLE x ( a INTEGER )"}).Checksum() {
+		t.Error("checksum should ignore whitespace")
+	}
+}
+
+func TestMigrate_FreshDatabase(t *testing.T) {
+	db := newTestDB(t)
+	applied, err := db.AppliedMigrations()
+	if err != nil {
+		t.Fatalf("AppliedMigrations: %v", err)
+	}
+	if len(applied) != SchemaVersion {
+		t.Fatalf("applied %d migrations, want %d", len(applied), SchemaVersion)
+	}
+	for i, a := range applied {
+		if a.Version != migrations[i].Version || a.Checksum != migrations[i].Checksum() || a.Name != migrations[i].Name {
+			t.Errorf("applied[%d] = %+v, want %d %q", i, a, migrations[i].Version, migrations[i].Name)
+		}
+	}
+	if v, _ := db.GetMeta("schema_version"); v != fmt.Sprint(SchemaVersion) {
+		t.Errorf("schema_version = %q, want %d", v, SchemaVersion)
+	}
+
+	// Re-running is a no-op
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("second Migrate: %v", err)
+	}
+	if again, _ := db.AppliedMigrations(); len(again) != len(applied) {
+		t.Errorf("second Migrate recorded %d steps, want %d", len(again), len(applied))
+	}
+}
+
+func TestMigrate_UpgradesHistoricalSchemas(t *testing.T) {
+	want := schemaOf(t, newTestDB(t))
+
+	for _, version := range []int{1, 2, 3} {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			db := openFixture(t, fmt.Sprintf("schema_v%d", version))
+			if err := db.Migrate(); err != nil {
+				t.Fatalf("Migrate: %v", err)
+			}
+
+			got := schemaOf(t, db)
+			for name, ddl := range want {
+				if got[name] != ddl {
+					t.Errorf("%s after upgrade:\n got  %s\n want %s", name, got[name], ddl)
+				}
+			}
+			if len(got) != len(want) {
+				t.Errorf("upgraded schema has %d objects, fresh has %d", len(got), len(want))
+			}
+
+			applied, err := db.AppliedMigrations()
+			if err != nil {
+				t.Fatalf("AppliedMigrations: %v", err)
+			}
+			if len(applied) != SchemaVersion {
+				t.Errorf("recorded %d migrations, want %d", len(applied), SchemaVersion)
+			}
+			if v, _ := db.GetMeta("schema_version"); v != fmt.Sprint(SchemaVersion) {
+				t.Errorf("schema_version = %q, want %d", v, SchemaVersion)
+			}
+
+			// Data written by the old version survives
+			insts, err := db.LoadInstances()
+			if err != nil || len(insts) != 1 || insts[0].ID != "inst-1" || string(insts[0].ToolData) != `{"claude_session_id":"abc"}` {
+				t.Fatalf("instances after upgrade = %+v, %v", insts, err)
+			}
+			statuses, _ := db.ReadAllStatuses()
+			if !statuses["inst-1"].Acknowledged {
+				t.Error("acknowledged flag lost in upgrade")
+			}
+			groups, err := db.LoadGroups()
+			if err != nil || len(groups) != 1 || groups[0].Name != "Work" {
+				t.Errorf("groups after upgrade = %+v, %v", groups, err)
+			}
+			history, err := db.LoadHistory("inst-1", 10)
+			if err != nil {
+				t.Fatalf("LoadHistory: %v", err)
+			}
+			if wantHistory := version >= 2; (len(history) == 1) != wantHistory {
+				t.Errorf("history rows = %d, want present=%v", len(history), wantHistory)
+			}
+			usage, err := db.LoadUsage("inst-1")
+			if err != nil {
+				t.Fatalf("LoadUsage: %v", err)
+			}
+			if wantUsage := version >= 3; (len(usage) == 1) != wantUsage {
+				t.Errorf("usage rows = %d, want present=%v", len(usage), wantUsage)
+			}
+		})
+	}
+}
+
+func TestMigrate_RefusesNewerDatabase(t *testing.T) {
+	t.Run("registry", func(t *testing.T) {
+		db := newTestDB(t)
+		if _, err := db.DB().Exec(
+			"INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, 'future', 'x', 0)",
+			SchemaVersion+1,
+		); err != nil {
+			t.Fatal(err)
+		}
+		var tooNew *SchemaTooNewError
+		if err := db.Migrate(); !errors.As(err, &tooNew) || tooNew.Version != SchemaVersion+1 {
+			t.Fatalf("Migrate = %v, want SchemaTooNewError", err)
+		}
+		if !strings.Contains(tooNew.Error(), "upgrade agent-deck") {
+			t.Errorf("error should tell the user what to do: %v", tooNew)
+		}
+	})
+
+	t.Run("legacy metadata", func(t *testing.T) {
+		db := openFixture(t, "schema_v3")
+		if err := db.SetMeta("schema_version", "9"); err != nil {
+			t.Fatal(err)
+		}
+		var tooNew *SchemaTooNewError
+		if err := db.Migrate(); !errors.As(err, &tooNew) || tooNew.Version != 9 {
+			t.Fatalf("Migrate = %v, want SchemaTooNewError for version 9", err)
+		}
+	})
+}
+
+func TestMigrate_ChecksumMismatch(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.DB().Exec("UPDATE schema_migrations SET checksum = 'tampered' WHERE version = 2"); err != nil {
+		t.Fatal(err)
+	}
+	err := db.Migrate()
+	if err == nil || !strings.Contains(err.Error(), "migration 2") {
+		t.Fatalf("Migrate = %v, want checksum error for migration 2", err)
+	}
+}
+
+func TestMigrate_FailedStepRollsBack(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	steps := []migration{
+		{Version: 1, Name: "one", SQL: "CREATE TABLE a (x INTEGER)"},
+		{Version: 2, Name: "two", SQL: "CREATE TABLE b (x INTEGER); CREATE TABLE a (x INTEGER)"},
+	}
+	err = db.applyMigrations(steps)
+	if err == nil || !strings.Contains(err.Error(), "migration 2 (two)") {
+		t.Fatalf("applyMigrations = %v, want failure in step 2", err)
+	}
+
+	applied, _ := db.AppliedMigrations()
+	if len(applied) != 1 || applied[0].Version != 1 {
+		t.Errorf("applied = %+v, want only step 1", applied)
+	}
+	if v, _ := db.GetMeta("schema_version"); v != "1" {
+		t.Errorf("schema_version = %q, want 1", v)
+	}
+	var n int
+	_ = db.DB().QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'b'").Scan(&n)
+	if n != 0 {
+		t.Error("failed step's partial changes should be rolled back")
+	}
+
+	// Fixing the step lets it apply on the next run
+	steps[1].SQL = "CREATE TABLE b (x INTEGER)"
+	if err := db.applyMigrations(steps); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if v, _ := db.GetMeta("schema_version"); v != "2" {
+		t.Errorf("schema_version after retry = %q, want 2", v)
+	}
+}
@@ -3,6 +3,7 @@ package statedb
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,8 +15,8 @@ import (
 )
 
 // SchemaVersion tracks the current database schema version.
-// Bump this when adding migrations.
-const SchemaVersion = 1
+// Bump this when appending a step to the migration registry (migrations.go).
+const SchemaVersion = 6
 
 // StateDB wraps a SQLite database for session/group persistence.
 // Thread-safe for concurrent use from multiple goroutines within one process.
@@ -27,32 +28,66 @@ type StateDB struct {
 
 // InstanceRow represents a session row in the database.
 type InstanceRow struct {
-	ID              string
-	Title           string
-	ProjectPath     string
-	GroupPath       string
-	Order           int
-	Command         string
-	Wrapper         string
-	Tool            string
-	Status          string
-	TmuxSession     string
-	CreatedAt       time.Time
-	LastAccessed    time.Time
-	ParentSessionID string
-	WorktreePath    string
-	WorktreeRepo    string
-	WorktreeBranch  string
-	ToolData        json.RawMessage // JSON blob for tool-specific data
+	ID              string          `json:"id"`
+	Title           string          `json:"title"`
+	ProjectPath     string          `json:"project_path"`
+	GroupPath       string          `json:"group_path"`
+	Order           int             `json:"order"`
+	Command         string          `json:"command"`
+	Wrapper         string          `json:"wrapper"`
+	Tool            string          `json:"tool"`
+	Status          string          `json:"status"`
+	TmuxSession     string          `json:"tmux_session"`
+	CreatedAt       time.Time       `json:"created_at"`
+	LastAccessed    time.Time       `json:"last_accessed"`
+	ParentSessionID string          `json:"parent_session_id"`
+	WorktreePath    string          `json:"worktree_path"`
+	WorktreeRepo    string          `json:"worktree_repo"`
+	WorktreeBranch  string          `json:"worktree_branch"`
+	ToolData        json.RawMessage `json:"tool_data"` // JSON blob for tool-specific data
 }
 
 // GroupRow represents a group row in the database.
 type GroupRow struct {
-	Path        string
-	Name        string
-	Expanded    bool
-	Order       int
-	DefaultPath string
+	Path        string `json:"path"`
+	Name        string `json:"name"`
+	Expanded    bool   `json:"expanded"`
+	Order       int    `json:"order"`
+	DefaultPath string `json:"default_path"`
+}
+
+// HistoryRow represents a notable event in a session's lifetime.
+type HistoryRow struct {
+	InstanceID string
+	Timestamp  time.Time
+	Kind       string
+	Message    string
+}
+
+// UsageRow is one session's usage for one day and model, derived from its
+// transcript and cached so reports don't re-parse unchanged transcripts.
+type UsageRow struct {
+	InstanceID       string
+	Day              string // YYYY-MM-DD, local time
+	Model            string
+	InputTokens      int
+	OutputTokens     int
+	CacheReadTokens  int
+	CacheWriteTokens int
+	Cost             float64
+	Turns            int
+	ToolCalls        map[string]int
+	Active           time.Duration
+	Waiting          time.Duration
+}
+
+// UsageSourceRow records which transcript a session's usage rows were
+// derived from, so they can be reused while the file is unchanged.
+type UsageSourceRow struct {
+	InstanceID string
+	Path       string
+	Size       int64
+	ModTime    time.Time
 }
 
 // StatusRow holds status + acknowledgment for a session.
@@ -127,85 +162,6 @@ func (s *StateDB) DB() *sql.DB {
 	return s.db
 }
 
-// Migrate creates tables if they don't exist and runs any pending migrations.
-func (s *StateDB) Migrate() error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return fmt.Errorf("statedb: begin migrate: %w", err)
-	}
-	defer func() { _ = tx.Rollback() }()
-
-	// metadata table
-	if _, err := tx.Exec(`
-		CREATE TABLE IF NOT EXISTS metadata (
-			key   TEXT PRIMARY KEY,
-			value TEXT NOT NULL
-		)
-	`); err != nil {
-		return fmt.Errorf("statedb: create metadata: %w", err)
-	}
-
-	// instances table
-	if _, err := tx.Exec(`
-		CREATE TABLE IF NOT EXISTS instances (
-			id              TEXT PRIMARY KEY,
-			title           TEXT NOT NULL,
-			project_path    TEXT NOT NULL,
-			group_path      TEXT NOT NULL DEFAULT 'my-sessions',
-			sort_order      INTEGER NOT NULL DEFAULT 0,
-			command         TEXT NOT NULL DEFAULT '',
-			wrapper         TEXT NOT NULL DEFAULT '',
-			tool            TEXT NOT NULL DEFAULT 'shell',
-			status          TEXT NOT NULL DEFAULT 'error',
-			tmux_session    TEXT NOT NULL DEFAULT '',
-			created_at      INTEGER NOT NULL,
-			last_accessed   INTEGER NOT NULL DEFAULT 0,
-			parent_session_id TEXT NOT NULL DEFAULT '',
-			worktree_path     TEXT NOT NULL DEFAULT '',
-			worktree_repo     TEXT NOT NULL DEFAULT '',
-			worktree_branch   TEXT NOT NULL DEFAULT '',
-			tool_data       TEXT NOT NULL DEFAULT '{}',
-			acknowledged    INTEGER NOT NULL DEFAULT 0
-		)
-	`); err != nil {
-		return fmt.Errorf("statedb: create instances: %w", err)
-	}
-
-	// groups table
-	if _, err := tx.Exec(`
-		CREATE TABLE IF NOT EXISTS groups (
-			path         TEXT PRIMARY KEY,
-			name         TEXT NOT NULL,
-			expanded     INTEGER NOT NULL DEFAULT 1,
-			sort_order   INTEGER NOT NULL DEFAULT 0,
-			default_path TEXT NOT NULL DEFAULT ''
-		)
-	`); err != nil {
-		return fmt.Errorf("statedb: create groups: %w", err)
-	}
-
-	// instance heartbeats
-	if _, err := tx.Exec(`
-		CREATE TABLE IF NOT EXISTS instance_heartbeats (
-			pid        INTEGER PRIMARY KEY,
-			started    INTEGER NOT NULL,
-			heartbeat  INTEGER NOT NULL,
-			is_primary INTEGER NOT NULL DEFAULT 0
-		)
-	`); err != nil {
-		return fmt.Errorf("statedb: create heartbeats: %w", err)
-	}
-
-	// Set schema version
-	if _, err := tx.Exec(`
-		INSERT OR REPLACE INTO metadata (key, value) VALUES ('schema_version', ?)
-	`, fmt.Sprintf("%d", SchemaVersion)); err != nil {
-		return fmt.Errorf("statedb: set schema version: %w", err)
-	}
-
-	return tx.Commit()
-}
-
 // IsEmpty returns true if the instances table has no rows.
 func (s *StateDB) IsEmpty() (bool, error) {
 	var count int
@@ -220,6 +176,7 @@ func (s *StateDB) IsEmpty() (bool, error) {
 
 // SaveInstance inserts or replaces a single instance.
 func (s *StateDB) SaveInstance(inst *InstanceRow) error {
+	defer observeWrite("save_instance", time.Now())
 	toolData := inst.ToolData
 	if len(toolData) == 0 {
 		toolData = json.RawMessage("{}")
@@ -247,6 +204,7 @@ func (s *StateDB) SaveInstance(inst *InstanceRow) error {
 // It also removes any rows from the database that are not in the provided list,
 // ensuring deleted sessions don't reappear on reload.
 func (s *StateDB) SaveInstances(insts []*InstanceRow) error {
+	defer observeWrite("save_instances", time.Now())
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
@@ -254,7 +212,11 @@ func (s *StateDB) SaveInstances(insts []*InstanceRow) error {
 	defer func() { _ = tx.Rollback() }()
 
 	// Delete rows not in the new list to prevent deleted sessions from reappearing.
+	// Their port leases go with them; leases of sessions not saved yet are kept.
 	if len(insts) == 0 {
+		if _, err := tx.Exec("DELETE FROM port_leases WHERE instance_id IN (SELECT id FROM instances)"); err != nil {
+			return err
+		}
 		if _, err := tx.Exec("DELETE FROM instances"); err != nil {
 			return err
 		}
@@ -265,8 +227,11 @@ func (s *StateDB) SaveInstances(insts []*InstanceRow) error {
 			placeholders[i] = "?"
 			args[i] = inst.ID
 		}
-		query := "DELETE FROM instances WHERE id NOT IN (" + strings.Join(placeholders, ",") + ")"
-		if _, err := tx.Exec(query, args...); err != nil {
+		notIn := "id NOT IN (" + strings.Join(placeholders, ",") + ")"
+		if _, err := tx.Exec("DELETE FROM port_leases WHERE instance_id IN (SELECT id FROM instances WHERE "+notIn+")", args...); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM instances WHERE "+notIn, args...); err != nil {
 			return err
 		}
 	}
@@ -343,15 +308,28 @@ func (s *StateDB) LoadInstances() ([]*InstanceRow, error) {
 	return result, rows.Err()
 }
 
-// DeleteInstance removes an instance by ID.
+// DeleteInstance removes an instance by ID along with its history, cached
+// usage and port leases.
 func (s *StateDB) DeleteInstance(id string) error {
-	_, err := s.db.Exec("DELETE FROM instances WHERE id = ?", id)
-	return err
+	defer observeWrite("delete_instance", time.Now())
+	for _, query := range []string{
+		"DELETE FROM instances WHERE id = ?",
+		"DELETE FROM session_history WHERE instance_id = ?",
+		"DELETE FROM usage_daily WHERE instance_id = ?",
+		"DELETE FROM usage_sources WHERE instance_id = ?",
+		"DELETE FROM port_leases WHERE instance_id = ?",
+	} {
+		if _, err := s.db.Exec(query, id); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // UpdateInstanceField updates a single column for a given instance.
 // field must be a valid column name (caller is responsible for safety).
 func (s *StateDB) UpdateInstanceField(id, field string, value any) error {
+	defer observeWrite("update_instance_field", time.Now())
 	query := fmt.Sprintf("UPDATE instances SET %s = ? WHERE id = ?", field)
 	_, err := s.db.Exec(query, value, id)
 	return err
@@ -361,6 +339,7 @@ func (s *StateDB) UpdateInstanceField(id, field string, value any) error {
 
 // SaveGroups replaces all groups in a single transaction.
 func (s *StateDB) SaveGroups(groups []*GroupRow) error {
+	defer observeWrite("save_groups", time.Now())
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
@@ -420,6 +399,7 @@ func (s *StateDB) LoadGroups() ([]*GroupRow, error) {
 
 // DeleteGroup removes a group by path.
 func (s *StateDB) DeleteGroup(path string) error {
+	defer observeWrite("delete_group", time.Now())
 	_, err := s.db.Exec("DELETE FROM groups WHERE path = ?", path)
 	return err
 }
@@ -428,6 +408,7 @@ func (s *StateDB) DeleteGroup(path string) error {
 
 // WriteStatus updates the status and tool for an instance.
 func (s *StateDB) WriteStatus(id, status, tool string) error {
+	defer observeWrite("write_status", time.Now())
 	_, err := s.db.Exec(
 		`UPDATE instances
 		 SET status = ?, tool = ?,
@@ -462,6 +443,7 @@ func (s *StateDB) ReadAllStatuses() (map[string]StatusRow, error) {
 
 // SetAcknowledged sets or clears the acknowledged flag for an instance.
 func (s *StateDB) SetAcknowledged(id string, ack bool) error {
+	defer observeWrite("set_acknowledged", time.Now())
 	v := 0
 	if ack {
 		v = 1
@@ -470,6 +452,686 @@ func (s *StateDB) SetAcknowledged(id string, ack bool) error {
 	return err
 }
 
+// --- Session History ---
+
+// AppendHistory records a history event for an instance.
+func (s *StateDB) AppendHistory(row *HistoryRow) error {
+	defer observeWrite("append_history", time.Now())
+	ts := row.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	_, err := s.db.Exec(
+		"INSERT INTO session_history (instance_id, ts, kind, message) VALUES (?, ?, ?, ?)",
+		row.InstanceID, ts.UnixNano(), row.Kind, row.Message,
+	)
+	return err
+}
+
+// LoadHistory returns the most recent history events for an instance, oldest first.
+// limit <= 0 returns all events.
+func (s *StateDB) LoadHistory(instanceID string, limit int) ([]*HistoryRow, error) {
+	query := "SELECT instance_id, ts, kind, message FROM session_history WHERE instance_id = ? ORDER BY ts DESC, id DESC"
+	args := []any{instanceID}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*HistoryRow
+	for rows.Next() {
+		r := &HistoryRow{}
+		var ts int64
+		if err := rows.Scan(&r.InstanceID, &ts, &r.Kind, &r.Message); err != nil {
+			return nil, err
+		}
+		r.Timestamp = time.Unix(0, ts)
+		result = append(result, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Reverse to chronological order
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result, nil
+}
+
+// --- Usage Cache ---
+
+// GetUsageSource returns the transcript fingerprint the cached usage of an
+// instance was derived from, or nil if none is cached.
+func (s *StateDB) GetUsageSource(instanceID string) (*UsageSourceRow, error) {
+	r := &UsageSourceRow{InstanceID: instanceID}
+	var mtime int64
+	err := s.db.QueryRow(
+		"SELECT path, size, mtime FROM usage_sources WHERE instance_id = ?", instanceID,
+	).Scan(&r.Path, &r.Size, &mtime)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.ModTime = time.Unix(0, mtime)
+	return r, nil
+}
+
+// ReplaceUsage replaces an instance's cached usage rows and records the
+// transcript they were derived from, in a single transaction.
+func (s *StateDB) ReplaceUsage(src *UsageSourceRow, rows []*UsageRow) error {
+	defer observeWrite("replace_usage", time.Now())
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec("DELETE FROM usage_daily WHERE instance_id = ?", src.InstanceID); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO usage_daily (
+			instance_id, day, model,
+			input_tokens, output_tokens, cache_read_tokens, cache_write_tokens,
+			cost, turns, tool_calls, active_ms, waiting_ms
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range rows {
+		toolCalls, err := json.Marshal(r.ToolCalls)
+		if err != nil || r.ToolCalls == nil {
+			toolCalls = []byte("{}")
+		}
+		if _, err := stmt.Exec(
+			src.InstanceID, r.Day, r.Model,
+			r.InputTokens, r.OutputTokens, r.CacheReadTokens, r.CacheWriteTokens,
+			r.Cost, r.Turns, string(toolCalls), r.Active.Milliseconds(), r.Waiting.Milliseconds(),
+		); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(
+		"INSERT OR REPLACE INTO usage_sources (instance_id, path, size, mtime) VALUES (?, ?, ?, ?)",
+		src.InstanceID, src.Path, src.Size, src.ModTime.UnixNano(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// LoadUsage returns an instance's cached usage rows ordered by day and model.
+func (s *StateDB) LoadUsage(instanceID string) ([]*UsageRow, error) {
+	rows, err := s.db.Query(`
+		SELECT day, model, input_tokens, output_tokens, cache_read_tokens, cache_write_tokens,
+		       cost, turns, tool_calls, active_ms, waiting_ms
+		FROM usage_daily WHERE instance_id = ? ORDER BY day, model
+	`, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*UsageRow
+	for rows.Next() {
+		r := &UsageRow{InstanceID: instanceID}
+		var toolCalls string
+		var activeMs, waitingMs int64
+		if err := rows.Scan(&r.Day, &r.Model, &r.InputTokens, &r.OutputTokens, &r.CacheReadTokens, &r.CacheWriteTokens,
+			&r.Cost, &r.Turns, &toolCalls, &activeMs, &waitingMs); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal([]byte(toolCalls), &r.ToolCalls)
+		r.Active = time.Duration(activeMs) * time.Millisecond
+		r.Waiting = time.Duration(waitingMs) * time.Millisecond
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// PruneUsage removes cached usage for instances not in keepIDs (sessions
+// removed by SaveInstances rather than DeleteInstance).
+func (s *StateDB) PruneUsage(keepIDs []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	where := ""
+	args := make([]any, len(keepIDs))
+	if len(keepIDs) > 0 {
+		placeholders := make([]string, len(keepIDs))
+		for i, id := range keepIDs {
+			placeholders[i] = "?"
+			args[i] = id
+		}
+		where = " WHERE instance_id NOT IN (" + strings.Join(placeholders, ",") + ")"
+	}
+	for _, table := range []string{"usage_daily", "usage_sources"} {
+		if _, err := tx.Exec("DELETE FROM "+table+where, args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// --- Port Leases ---
+
+// ErrNoFreePorts is returned by LeasePorts when the range has too few ports
+// that are neither leased nor in use.
+var ErrNoFreePorts = errors.New("statedb: no free ports in range")
+
+// orphanLeaseAge is how long a lease may exist without its instance row
+// before it is reclaimed. New sessions lease ports while starting, before
+// they are first saved, so a fresh orphan is not necessarily stale.
+const orphanLeaseAge = time.Hour
+
+// LeasePorts returns count ports reserved for an instance from [start, end],
+// leasing whatever is missing. Existing leases are kept, so calling it again
+// returns the same ports. free reports whether an unleased port can be used
+// (e.g. nothing is listening on it). Leases held by instances that no longer
+// exist are reclaimed once older than an hour.
+func (s *StateDB) LeasePorts(instanceID string, count, start, end int, free func(port int) bool) ([]int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	cutoff := time.Now().Add(-orphanLeaseAge).Unix()
+	if _, err := tx.Exec(
+		"DELETE FROM port_leases WHERE leased_at < ? AND instance_id NOT IN (SELECT id FROM instances)", cutoff,
+	); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query("SELECT port, instance_id, slot FROM port_leases")
+	if err != nil {
+		return nil, err
+	}
+	leased := make(map[int]bool)
+	mine := make(map[int]int) // slot -> port
+	for rows.Next() {
+		var port, slot int
+		var id string
+		if err := rows.Scan(&port, &id, &slot); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		leased[port] = true
+		if id == instanceID {
+			mine[slot] = port
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	next := start
+	now := time.Now().Unix()
+	for slot := 1; slot <= count; slot++ {
+		if _, ok := mine[slot]; ok {
+			continue
+		}
+		for ; next <= end; next++ {
+			if !leased[next] && (free == nil || free(next)) {
+				break
+			}
+		}
+		if next > end {
+			return nil, fmt.Errorf("%w %d-%d (%d needed)", ErrNoFreePorts, start, end, count)
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO port_leases (port, instance_id, slot, leased_at) VALUES (?, ?, ?, ?)",
+			next, instanceID, slot, now,
+		); err != nil {
+			return nil, err
+		}
+		mine[slot] = next
+		leased[next] = true
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	ports := make([]int, 0, count)
+	for slot := 1; slot <= count; slot++ {
+		ports = append(ports, mine[slot])
+	}
+	return ports, nil
+}
+
+// LoadPortLeases returns every instance's leased ports ordered by slot.
+func (s *StateDB) LoadPortLeases() (map[string][]int, error) {
+	rows, err := s.db.Query("SELECT instance_id, port FROM port_leases ORDER BY instance_id, slot")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	leases := make(map[string][]int)
+	for rows.Next() {
+		var id string
+		var port int
+		if err := rows.Scan(&id, &port); err != nil {
+			return nil, err
+		}
+		leases[id] = append(leases[id], port)
+	}
+	return leases, rows.Err()
+}
+
+// ReleasePorts drops an instance's port leases.
+func (s *StateDB) ReleasePorts(instanceID string) error {
+	_, err := s.db.Exec("DELETE FROM port_leases WHERE instance_id = ?", instanceID)
+	return err
+}
+
+// --- Web Tokens ---
+
+// ErrWebTokenExists is returned by CreateWebToken when the name is taken.
+var ErrWebTokenExists = errors.New("statedb: web token already exists")
+
+// WebTokenRow is a named web access token. Only the token's hash is stored.
+type WebTokenRow struct {
+	Name       string
+	Hash       string
+	Scopes     []string
+	Groups     []string // Empty: all groups
+	CreatedAt  time.Time
+	ExpiresAt  time.Time // Zero: never expires
+	LastUsedAt time.Time
+}
+
+// WebAuditRow is one audited web request or terminal input session.
+type WebAuditRow struct {
+	Timestamp  time.Time
+	TokenName  string
+	Action     string
+	Method     string
+	Path       string
+	SessionID  string
+	RemoteAddr string
+	Status     int
+	Detail     string
+}
+
+const webTokenColumns = "name, hash, scopes, groups, created_at, expires_at, last_used_at"
+
+// CreateWebToken stores a new token, failing with ErrWebTokenExists when
+// one with the same name exists.
+func (s *StateDB) CreateWebToken(row *WebTokenRow) error {
+	created := row.CreatedAt
+	if created.IsZero() {
+		created = time.Now()
+	}
+	var expires int64
+	if !row.ExpiresAt.IsZero() {
+		expires = row.ExpiresAt.Unix()
+	}
+	res, err := s.db.Exec(
+		"INSERT OR IGNORE INTO web_tokens ("+webTokenColumns+") VALUES (?, ?, ?, ?, ?, ?, 0)",
+		row.Name, row.Hash, strings.Join(row.Scopes, ","), strings.Join(row.Groups, ","), created.Unix(), expires,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrWebTokenExists, row.Name)
+	}
+	return nil
+}
+
+// LoadWebTokens returns all tokens ordered by name.
+func (s *StateDB) LoadWebTokens() ([]*WebTokenRow, error) {
+	rows, err := s.db.Query("SELECT " + webTokenColumns + " FROM web_tokens ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*WebTokenRow
+	for rows.Next() {
+		r, err := scanWebToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// WebTokenByHash returns the token with the given hash, or nil if none.
+func (s *StateDB) WebTokenByHash(hash string) (*WebTokenRow, error) {
+	r, err := scanWebToken(s.db.QueryRow("SELECT "+webTokenColumns+" FROM web_tokens WHERE hash = ?", hash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// CountWebTokens returns how many tokens exist, expired ones included.
+func (s *StateDB) CountWebTokens() (int, error) {
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM web_tokens").Scan(&n)
+	return n, err
+}
+
+// TouchWebToken records that a token was used at t.
+func (s *StateDB) TouchWebToken(name string, t time.Time) error {
+	_, err := s.db.Exec("UPDATE web_tokens SET last_used_at = ? WHERE name = ?", t.Unix(), name)
+	return err
+}
+
+// DeleteWebToken removes a token, reporting whether it existed.
+func (s *StateDB) DeleteWebToken(name string) (bool, error) {
+	res, err := s.db.Exec("DELETE FROM web_tokens WHERE name = ?", name)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func scanWebToken(row interface{ Scan(...any) error }) (*WebTokenRow, error) {
+	r := &WebTokenRow{}
+	var scopes, groups string
+	var created, expires, used int64
+	if err := row.Scan(&r.Name, &r.Hash, &scopes, &groups, &created, &expires, &used); err != nil {
+		return nil, err
+	}
+	r.Scopes = splitList(scopes)
+	r.Groups = splitList(groups)
+	r.CreatedAt = time.Unix(created, 0)
+	if expires > 0 {
+		r.ExpiresAt = time.Unix(expires, 0)
+	}
+	if used > 0 {
+		r.LastUsedAt = time.Unix(used, 0)
+	}
+	return r, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// AppendWebAudit records an audit entry.
+func (s *StateDB) AppendWebAudit(row *WebAuditRow) error {
+	ts := row.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	_, err := s.db.Exec(
+		"INSERT INTO web_audit (ts, token_name, action, method, path, session_id, remote_addr, status, detail) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		ts.UnixNano(), row.TokenName, row.Action, row.Method, row.Path, row.SessionID, row.RemoteAddr, row.Status, row.Detail,
+	)
+	return err
+}
+
+// LoadWebAudit returns the most recent audit entries, oldest first, for one
+// token or for all tokens when tokenName is empty. limit <= 0 returns all.
+func (s *StateDB) LoadWebAudit(tokenName string, limit int) ([]*WebAuditRow, error) {
+	query := "SELECT ts, token_name, action, method, path, session_id, remote_addr, status, detail FROM web_audit"
+	var args []any
+	if tokenName != "" {
+		query += " WHERE token_name = ?"
+		args = append(args, tokenName)
+	}
+	query += " ORDER BY ts DESC, id DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*WebAuditRow
+	for rows.Next() {
+		r := &WebAuditRow{}
+		var ts int64
+		if err := rows.Scan(&ts, &r.TokenName, &r.Action, &r.Method, &r.Path, &r.SessionID, &r.RemoteAddr, &r.Status, &r.Detail); err != nil {
+			return nil, err
+		}
+		r.Timestamp = time.Unix(0, ts)
+		result = append(result, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Reverse to chronological order
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result, nil
+}
+
+// --- Web Shares ---
+
+// WebShareRow is a share link to one session's terminal. The link itself is
+// signed by the web server; only its ID and limits are stored, so revoking
+// the row invalidates the link.
+type WebShareRow struct {
+	ID         string
+	SessionID  string
+	AllowInput bool
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	RevokedAt  time.Time // Zero: not revoked
+}
+
+// Active reports whether the share can still be used at t.
+func (r *WebShareRow) Active(t time.Time) bool {
+	return r.RevokedAt.IsZero() && t.Before(r.ExpiresAt)
+}
+
+// WebShareViewerRow is one viewer connection made through a share link.
+type WebShareViewerRow struct {
+	ID             int64
+	ShareID        string
+	Name           string
+	RemoteAddr     string
+	UserAgent      string
+	ConnectedAt    time.Time
+	LastSeenAt     time.Time
+	DisconnectedAt time.Time // Zero: still connected, or the server stopped
+}
+
+const webShareColumns = "id, session_id, allow_input, created_at, expires_at, revoked_at"
+
+const webShareViewerColumns = "id, share_id, name, remote_addr, user_agent, connected_at, last_seen_at, disconnected_at"
+
+// CreateWebShare stores a new share.
+func (s *StateDB) CreateWebShare(row *WebShareRow) error {
+	created := row.CreatedAt
+	if created.IsZero() {
+		created = time.Now()
+	}
+	input := 0
+	if row.AllowInput {
+		input = 1
+	}
+	_, err := s.db.Exec(
+		"INSERT INTO web_shares ("+webShareColumns+") VALUES (?, ?, ?, ?, ?, 0)",
+		row.ID, row.SessionID, input, created.Unix(), row.ExpiresAt.Unix(),
+	)
+	return err
+}
+
+// WebShare returns the share with the given ID, or nil if none.
+func (s *StateDB) WebShare(id string) (*WebShareRow, error) {
+	r, err := scanWebShare(s.db.QueryRow("SELECT "+webShareColumns+" FROM web_shares WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// LoadWebShares returns the shares of one session, or of all sessions when
+// sessionID is empty, oldest first.
+func (s *StateDB) LoadWebShares(sessionID string) ([]*WebShareRow, error) {
+	query := "SELECT " + webShareColumns + " FROM web_shares"
+	var args []any
+	if sessionID != "" {
+		query += " WHERE session_id = ?"
+		args = append(args, sessionID)
+	}
+	rows, err := s.db.Query(query+" ORDER BY created_at, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*WebShareRow
+	for rows.Next() {
+		r, err := scanWebShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// RevokeWebShare marks a share revoked at t, reporting whether an
+// unrevoked share with that ID existed.
+func (s *StateDB) RevokeWebShare(id string, t time.Time) (bool, error) {
+	res, err := s.db.Exec("UPDATE web_shares SET revoked_at = ? WHERE id = ? AND revoked_at = 0", t.Unix(), id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// RevokeSessionWebShares revokes every unexpired share of a session,
+// returning how many were revoked.
+func (s *StateDB) RevokeSessionWebShares(sessionID string, t time.Time) (int, error) {
+	res, err := s.db.Exec(
+		"UPDATE web_shares SET revoked_at = ? WHERE session_id = ? AND revoked_at = 0 AND expires_at > ?",
+		t.Unix(), sessionID, t.Unix(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// PruneWebShares deletes shares that expired or were revoked before t,
+// along with their viewer records.
+func (s *StateDB) PruneWebShares(t time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	const stale = "SELECT id FROM web_shares WHERE expires_at < ? OR (revoked_at > 0 AND revoked_at < ?)"
+	if _, err := tx.Exec("DELETE FROM web_share_viewers WHERE share_id IN ("+stale+")", t.Unix(), t.Unix()); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM web_shares WHERE id IN ("+stale+")", t.Unix(), t.Unix()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanWebShare(row interface{ Scan(...any) error }) (*WebShareRow, error) {
+	r := &WebShareRow{}
+	var input int
+	var created, expires, revoked int64
+	if err := row.Scan(&r.ID, &r.SessionID, &input, &created, &expires, &revoked); err != nil {
+		return nil, err
+	}
+	r.AllowInput = input != 0
+	r.CreatedAt = time.Unix(created, 0)
+	r.ExpiresAt = time.Unix(expires, 0)
+	if revoked > 0 {
+		r.RevokedAt = time.Unix(revoked, 0)
+	}
+	return r, nil
+}
+
+// AddWebShareViewer records a viewer connecting and returns its ID.
+func (s *StateDB) AddWebShareViewer(row *WebShareViewerRow) (int64, error) {
+	connected := row.ConnectedAt
+	if connected.IsZero() {
+		connected = time.Now()
+	}
+	res, err := s.db.Exec(
+		"INSERT INTO web_share_viewers (share_id, name, remote_addr, user_agent, connected_at, last_seen_at) VALUES (?, ?, ?, ?, ?, ?)",
+		row.ShareID, row.Name, row.RemoteAddr, row.UserAgent, connected.Unix(), connected.Unix(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// TouchWebShareViewer records that a viewer was still connected at t.
+func (s *StateDB) TouchWebShareViewer(id int64, t time.Time) error {
+	_, err := s.db.Exec("UPDATE web_share_viewers SET last_seen_at = ? WHERE id = ?", t.Unix(), id)
+	return err
+}
+
+// EndWebShareViewer records that a viewer disconnected at t.
+func (s *StateDB) EndWebShareViewer(id int64, t time.Time) error {
+	_, err := s.db.Exec("UPDATE web_share_viewers SET disconnected_at = ?, last_seen_at = ? WHERE id = ?", t.Unix(), t.Unix(), id)
+	return err
+}
+
+// LoadActiveWebShareViewers returns viewers that haven't disconnected and
+// were seen at or after since, oldest first. The since cutoff drops viewers
+// left behind by a web server that stopped without closing them.
+func (s *StateDB) LoadActiveWebShareViewers(since time.Time) ([]*WebShareViewerRow, error) {
+	rows, err := s.db.Query(
+		"SELECT "+webShareViewerColumns+" FROM web_share_viewers WHERE disconnected_at = 0 AND last_seen_at >= ? ORDER BY connected_at, id",
+		since.Unix(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*WebShareViewerRow
+	for rows.Next() {
+		r := &WebShareViewerRow{}
+		var connected, seen, disconnected int64
+		if err := rows.Scan(&r.ID, &r.ShareID, &r.Name, &r.RemoteAddr, &r.UserAgent, &connected, &seen, &disconnected); err != nil {
+			return nil, err
+		}
+		r.ConnectedAt = time.Unix(connected, 0)
+		r.LastSeenAt = time.Unix(seen, 0)
+		if disconnected > 0 {
+			r.DisconnectedAt = time.Unix(disconnected, 0)
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
 // --- Heartbeat ---
 
 // RegisterInstance records this process as an active TUI instance.
@@ -488,6 +1150,7 @@ func (s *StateDB) RegisterInstance(isPrimary bool) error {
 
 // Heartbeat updates the heartbeat timestamp for this process.
 func (s *StateDB) Heartbeat() error {
+	defer observeWrite("heartbeat", time.Now())
 	_, err := s.db.Exec(
 		"UPDATE instance_heartbeats SET heartbeat = ? WHERE pid = ?",
 		time.Now().Unix(), s.pid,
@@ -582,6 +1245,7 @@ func (s *StateDB) ResignPrimary() error {
 
 // SetMeta sets a key-value pair in the metadata table.
 func (s *StateDB) SetMeta(key, value string) error {
+	defer observeWrite("set_meta", time.Now())
 	_, err := s.db.Exec(
 		"INSERT OR REPLACE INTO metadata (key, value) VALUES (?, ?)",
 		key, value,
@@ -603,6 +1267,7 @@ func (s *StateDB) GetMeta(key string) (string, error) {
 
 // Touch updates a metadata timestamp that other instances can poll to detect changes.
 func (s *StateDB) Touch() error {
+	defer observeWrite("touch", time.Now())
 	return s.SetMeta("last_modified", fmt.Sprintf("%d", time.Now().UnixNano()))
 }
 
@@ -2,6 +2,7 @@ package statedb
 
 import (
 	"encoding/json"
+	"errors"
 	"path/filepath"
 	"sync"
 	"testing"
@@ -609,3 +610,328 @@ func TestGlobalSingleton(t *testing.T) {
 		t.Error("Expected nil after clearing")
 	}
 }
+
+func TestSessionHistory(t *testing.T) {
+	db := newTestDB(t)
+
+	base := time.Now().Add(-time.Hour)
+	for i, kind := range []string{"context.compact", "context.compact", "context.fork"} {
+		if err := db.AppendHistory(&HistoryRow{
+			InstanceID: "inst-1",
+			Timestamp:  base.Add(time.Duration(i) * time.Minute),
+			Kind:       kind,
+			Message:    kind,
+		}); err != nil {
+			t.Fatalf("AppendHistory: %v", err)
+		}
+	}
+	if err := db.AppendHistory(&HistoryRow{InstanceID: "inst-2", Kind: "context.compact"}); err != nil {
+		t.Fatalf("AppendHistory: %v", err)
+	}
+
+	all, err := db.LoadHistory("inst-1", 0)
+	if err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(all))
+	}
+	if all[0].Timestamp.After(all[2].Timestamp) {
+		t.Error("Expected chronological order")
+	}
+
+	// Limit keeps the most recent entries
+	recent, err := db.LoadHistory("inst-1", 1)
+	if err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	if len(recent) != 1 || recent[0].Kind != "context.fork" {
+		t.Errorf("Expected latest entry context.fork, got %+v", recent)
+	}
+
+	// Deleting the instance removes its history
+	if err := db.DeleteInstance("inst-1"); err != nil {
+		t.Fatalf("DeleteInstance: %v", err)
+	}
+	all, _ = db.LoadHistory("inst-1", 0)
+	if len(all) != 0 {
+		t.Errorf("Expected history deleted, got %d entries", len(all))
+	}
+	other, _ := db.LoadHistory("inst-2", 0)
+	if len(other) != 1 {
+		t.Errorf("Expected other instance history intact, got %d entries", len(other))
+	}
+}
+
+func TestUsageCache(t *testing.T) {
+	db := newTestDB(t)
+
+	if src, err := db.GetUsageSource("inst-1"); err != nil || src != nil {
+		t.Fatalf("GetUsageSource on empty cache = %+v, %v", src, err)
+	}
+
+	mtime := time.Now().Add(-time.Minute)
+	src := &UsageSourceRow{InstanceID: "inst-1", Path: "/tmp/a.jsonl", Size: 42, ModTime: mtime}
+	rows := []*UsageRow{
+		{Day: "2025-10-17", Model: "m1", InputTokens: 10, OutputTokens: 5, Cost: 0.5, Turns: 2,
+			ToolCalls: map[string]int{"Bash": 3}, Active: 90 * time.Second, Waiting: time.Minute},
+		{Day: "2025-10-18", Model: "m1", InputTokens: 1, Turns: 1},
+	}
+	if err := db.ReplaceUsage(src, rows); err != nil {
+		t.Fatalf("ReplaceUsage: %v", err)
+	}
+	if err := db.ReplaceUsage(&UsageSourceRow{InstanceID: "inst-2", Path: "/tmp/b.jsonl", ModTime: mtime},
+		[]*UsageRow{{Day: "2025-10-18", Turns: 1}}); err != nil {
+		t.Fatalf("ReplaceUsage: %v", err)
+	}
+
+	got, err := db.GetUsageSource("inst-1")
+	if err != nil || got == nil {
+		t.Fatalf("GetUsageSource = %+v, %v", got, err)
+	}
+	if got.Path != src.Path || got.Size != 42 || !got.ModTime.Equal(mtime) {
+		t.Errorf("GetUsageSource = %+v, want %+v", got, src)
+	}
+
+	loaded, err := db.LoadUsage("inst-1")
+	if err != nil {
+		t.Fatalf("LoadUsage: %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(loaded))
+	}
+	first := loaded[0]
+	if first.Day != "2025-10-17" || first.Cost != 0.5 || first.ToolCalls["Bash"] != 3 ||
+		first.Active != 90*time.Second || first.Waiting != time.Minute {
+		t.Errorf("Row round-trip mismatch: %+v", first)
+	}
+
+	// Replacing drops rows that are no longer produced
+	if err := db.ReplaceUsage(src, rows[:1]); err != nil {
+		t.Fatalf("ReplaceUsage: %v", err)
+	}
+	if loaded, _ = db.LoadUsage("inst-1"); len(loaded) != 1 {
+		t.Errorf("Expected 1 row after replace, got %d", len(loaded))
+	}
+
+	// Prune keeps only listed instances; DeleteInstance clears the rest
+	if err := db.PruneUsage([]string{"inst-1"}); err != nil {
+		t.Fatalf("PruneUsage: %v", err)
+	}
+	if loaded, _ = db.LoadUsage("inst-2"); len(loaded) != 0 {
+		t.Errorf("Expected inst-2 usage pruned, got %d rows", len(loaded))
+	}
+	if err := db.DeleteInstance("inst-1"); err != nil {
+		t.Fatalf("DeleteInstance: %v", err)
+	}
+	if src, _ := db.GetUsageSource("inst-1"); src != nil {
+		t.Errorf("Expected usage source deleted, got %+v", src)
+	}
+}
+
+func TestPortLeases(t *testing.T) {
+	db := newTestDB(t)
+	for _, id := range []string{"a", "b"} {
+		if err := db.SaveInstance(&InstanceRow{ID: id, Title: id, ProjectPath: "/tmp", GroupPath: "g", Tool: "shell", Status: "idle", CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("SaveInstance: %v", err)
+		}
+	}
+	busy := func(port int) bool { return port != 5001 } // Something listens on 5001
+
+	a, err := db.LeasePorts("a", 2, 5000, 5005, busy)
+	if err != nil || len(a) != 2 || a[0] != 5000 || a[1] != 5002 {
+		t.Fatalf("LeasePorts(a) = %v, %v; want [5000 5002]", a, err)
+	}
+	// Idempotent, and growing the count keeps the existing ports
+	again, err := db.LeasePorts("a", 3, 5000, 5005, busy)
+	if err != nil || len(again) != 3 || again[0] != 5000 || again[1] != 5002 || again[2] != 5003 {
+		t.Fatalf("LeasePorts(a, 3) = %v, %v", again, err)
+	}
+	b, err := db.LeasePorts("b", 2, 5000, 5005, busy)
+	if err != nil || b[0] != 5004 || b[1] != 5005 {
+		t.Fatalf("LeasePorts(b) = %v, %v; want [5004 5005]", b, err)
+	}
+	if _, err := db.LeasePorts("c", 1, 5000, 5005, busy); !errors.Is(err, ErrNoFreePorts) {
+		t.Errorf("exhausted range: err = %v, want ErrNoFreePorts", err)
+	}
+
+	leases, err := db.LoadPortLeases()
+	if err != nil || len(leases["a"]) != 3 || len(leases["b"]) != 2 {
+		t.Errorf("LoadPortLeases = %v, %v", leases, err)
+	}
+
+	if err := db.ReleasePorts("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteInstance("b"); err != nil {
+		t.Fatal(err)
+	}
+	if leases, _ := db.LoadPortLeases(); len(leases) != 0 {
+		t.Errorf("leases after release/delete = %v", leases)
+	}
+}
+
+func TestPortLeases_ReclaimOrphans(t *testing.T) {
+	db := newTestDB(t)
+	// A lease whose instance was never saved: fresh ones are kept (the session
+	// may still be starting), old ones are reclaimed
+	if _, err := db.LeasePorts("starting", 1, 6000, 6000, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.LeasePorts("other", 1, 6000, 6000, nil); !errors.Is(err, ErrNoFreePorts) {
+		t.Fatalf("fresh orphan lease should be kept, err = %v", err)
+	}
+	old := time.Now().Add(-2 * orphanLeaseAge).Unix()
+	if _, err := db.DB().Exec("UPDATE port_leases SET leased_at = ?", old); err != nil {
+		t.Fatal(err)
+	}
+	ports, err := db.LeasePorts("other", 1, 6000, 6000, nil)
+	if err != nil || ports[0] != 6000 {
+		t.Errorf("stale orphan lease not reclaimed: %v, %v", ports, err)
+	}
+}
+
+func TestSaveInstances_ReleasesRemovedLeases(t *testing.T) {
+	db := newTestDB(t)
+	row := func(id string) *InstanceRow {
+		return &InstanceRow{ID: id, Title: id, ProjectPath: "/tmp", GroupPath: "g", Tool: "shell", Status: "idle", CreatedAt: time.Now()}
+	}
+	if err := db.SaveInstances([]*InstanceRow{row("kept"), row("removed")}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"kept", "removed", "unsaved"} {
+		if _, err := db.LeasePorts(id, 1, 7000, 7010, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := db.SaveInstances([]*InstanceRow{row("kept")}); err != nil {
+		t.Fatal(err)
+	}
+	leases, err := db.LoadPortLeases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := leases["removed"]; ok {
+		t.Error("lease of a removed instance should be released")
+	}
+	if len(leases["kept"]) != 1 || len(leases["unsaved"]) != 1 {
+		t.Errorf("leases = %v, want kept and unsaved to survive", leases)
+	}
+}
+
+func TestWebTokensAndAudit(t *testing.T) {
+	db := newTestDB(t)
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := db.CreateWebToken(&WebTokenRow{Name: "viewer", Hash: "h1", Scopes: []string{"read"}, Groups: []string{"work", "ops"}, ExpiresAt: expires}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateWebToken(&WebTokenRow{Name: "viewer", Hash: "h2"}); !errors.Is(err, ErrWebTokenExists) {
+		t.Fatalf("duplicate name: err = %v, want ErrWebTokenExists", err)
+	}
+
+	tok, err := db.WebTokenByHash("h1")
+	if err != nil || tok == nil {
+		t.Fatalf("WebTokenByHash = %v, %v", tok, err)
+	}
+	if tok.Name != "viewer" || len(tok.Groups) != 2 || tok.Scopes[0] != "read" || !tok.ExpiresAt.Equal(expires) || !tok.LastUsedAt.IsZero() {
+		t.Errorf("token = %+v", tok)
+	}
+	if missing, err := db.WebTokenByHash("nope"); err != nil || missing != nil {
+		t.Errorf("unknown hash = %v, %v; want nil, nil", missing, err)
+	}
+	if err := db.TouchWebToken("viewer", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if tokens, _ := db.LoadWebTokens(); len(tokens) != 1 || tokens[0].LastUsedAt.IsZero() {
+		t.Errorf("LoadWebTokens = %v", tokens)
+	}
+
+	for _, action := range []string{"push.subscribe", "terminal.input"} {
+		if err := db.AppendWebAudit(&WebAuditRow{TokenName: "viewer", Action: action}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.AppendWebAudit(&WebAuditRow{TokenName: "ops", Action: "terminal.input"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := db.LoadWebAudit("viewer", 0)
+	if err != nil || len(entries) != 2 || entries[0].Action != "push.subscribe" {
+		t.Errorf("LoadWebAudit(viewer) = %v, %v", entries, err)
+	}
+	if entries, _ := db.LoadWebAudit("", 1); len(entries) != 1 || entries[0].TokenName != "ops" {
+		t.Errorf("LoadWebAudit(all, 1) = %v, want the latest entry", entries)
+	}
+
+	if ok, err := db.DeleteWebToken("viewer"); !ok || err != nil {
+		t.Errorf("DeleteWebToken = %v, %v", ok, err)
+	}
+	if n, _ := db.CountWebTokens(); n != 0 {
+		t.Errorf("CountWebTokens = %d after delete", n)
+	}
+	if ok, _ := db.DeleteWebToken("viewer"); ok {
+		t.Error("deleting a missing token should report false")
+	}
+}
+
+func TestWebSharesAndViewers(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().Truncate(time.Second)
+	for _, row := range []*WebShareRow{
+		{ID: "s1", SessionID: "sess-a", AllowInput: true, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{ID: "s2", SessionID: "sess-a", CreatedAt: now.Add(time.Second), ExpiresAt: now.Add(time.Hour)},
+		{ID: "old", SessionID: "sess-b", CreatedAt: now.Add(-48 * time.Hour), ExpiresAt: now.Add(-47 * time.Hour)},
+	} {
+		if err := db.CreateWebShare(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	share, err := db.WebShare("s1")
+	if err != nil || share == nil || !share.AllowInput || share.SessionID != "sess-a" || !share.ExpiresAt.Equal(now.Add(time.Hour)) || !share.Active(now) {
+		t.Fatalf("WebShare(s1) = %+v, %v", share, err)
+	}
+	if missing, err := db.WebShare("nope"); err != nil || missing != nil {
+		t.Errorf("unknown share = %v, %v; want nil, nil", missing, err)
+	}
+	if shares, _ := db.LoadWebShares("sess-a"); len(shares) != 2 || shares[0].ID != "s1" {
+		t.Errorf("LoadWebShares(sess-a) = %v", shares)
+	}
+
+	if ok, err := db.RevokeWebShare("s2", now); !ok || err != nil {
+		t.Errorf("RevokeWebShare = %v, %v", ok, err)
+	}
+	if ok, _ := db.RevokeWebShare("s2", now); ok {
+		t.Error("revoking twice should report false")
+	}
+	if share, _ := db.WebShare("s2"); share.Active(now) {
+		t.Error("revoked share should not be active")
+	}
+	if n, err := db.RevokeSessionWebShares("sess-a", now); n != 1 || err != nil {
+		t.Errorf("RevokeSessionWebShares = %d, %v; want 1", n, err)
+	}
+
+	id, err := db.AddWebShareViewer(&WebShareViewerRow{ShareID: "s1", Name: "alice", RemoteAddr: "10.0.0.2:5000", ConnectedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gone, _ := db.AddWebShareViewer(&WebShareViewerRow{ShareID: "s1", Name: "bob", ConnectedAt: now})
+	stale, _ := db.AddWebShareViewer(&WebShareViewerRow{ShareID: "s1", Name: "carol", ConnectedAt: now.Add(-time.Hour)})
+	if err := db.TouchWebShareViewer(id, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.EndWebShareViewer(gone, now); err != nil {
+		t.Fatal(err)
+	}
+	viewers, err := db.LoadActiveWebShareViewers(now.Add(-time.Minute))
+	if err != nil || len(viewers) != 1 || viewers[0].Name != "alice" || !viewers[0].LastSeenAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("LoadActiveWebShareViewers = %v, %v (stale viewer %d)", viewers, err, stale)
+	}
+
+	if err := db.PruneWebShares(now.Add(-24 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if shares, _ := db.LoadWebShares(""); len(shares) != 2 {
+		t.Errorf("after prune: %d shares, want 2", len(shares))
+	}
+}
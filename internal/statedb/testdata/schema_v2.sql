@@ -0,0 +1,57 @@
+-- state.db as written by agent-deck with schema version 2
+-- (adds session_history)
+CREATE TABLE metadata (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+CREATE TABLE instances (
+	id              TEXT PRIMARY KEY,
+	title           TEXT NOT NULL,
+	project_path    TEXT NOT NULL,
+	group_path      TEXT NOT NULL DEFAULT 'my-sessions',
+	sort_order      INTEGER NOT NULL DEFAULT 0,
+	command         TEXT NOT NULL DEFAULT '',
+	wrapper         TEXT NOT NULL DEFAULT '',
+	tool            TEXT NOT NULL DEFAULT 'shell',
+	status          TEXT NOT NULL DEFAULT 'error',
+	tmux_session    TEXT NOT NULL DEFAULT '',
+	created_at      INTEGER NOT NULL,
+	last_accessed   INTEGER NOT NULL DEFAULT 0,
+	parent_session_id TEXT NOT NULL DEFAULT '',
+	worktree_path     TEXT NOT NULL DEFAULT '',
+	worktree_repo     TEXT NOT NULL DEFAULT '',
+	worktree_branch   TEXT NOT NULL DEFAULT '',
+	tool_data       TEXT NOT NULL DEFAULT '{}',
+	acknowledged    INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE groups (
+	path         TEXT PRIMARY KEY,
+	name         TEXT NOT NULL,
+	expanded     INTEGER NOT NULL DEFAULT 1,
+	sort_order   INTEGER NOT NULL DEFAULT 0,
+	default_path TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE instance_heartbeats (
+	pid        INTEGER PRIMARY KEY,
+	started    INTEGER NOT NULL,
+	heartbeat  INTEGER NOT NULL,
+	is_primary INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO metadata (key, value) VALUES ('schema_version', '2');
+INSERT INTO metadata (key, value) VALUES ('last_modified', '1759312800000000000');
+INSERT INTO groups (path, name, expanded, sort_order) VALUES ('work', 'Work', 1, 0);
+INSERT INTO instances (id, title, project_path, group_path, tool, status, created_at, tool_data, acknowledged)
+VALUES ('inst-1', 'api', '/src/api', 'work', 'claude', 'idle', 1759312800, '{"claude_session_id":"abc"}', 1);
+
+CREATE TABLE session_history (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	instance_id TEXT NOT NULL,
+	ts          INTEGER NOT NULL,
+	kind        TEXT NOT NULL,
+	message     TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX idx_session_history_instance
+ON session_history (instance_id, ts);
+
+INSERT INTO session_history (instance_id, ts, kind, message) VALUES ('inst-1', 1759312900, 'compact', 'context at 85%');
@@ -0,0 +1,83 @@
+-- state.db as written by agent-deck with schema version 3
+-- (adds usage_sources and usage_daily)
+CREATE TABLE metadata (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+CREATE TABLE instances (
+	id              TEXT PRIMARY KEY,
+	title           TEXT NOT NULL,
+	project_path    TEXT NOT NULL,
+	group_path      TEXT NOT NULL DEFAULT 'my-sessions',
+	sort_order      INTEGER NOT NULL DEFAULT 0,
+	command         TEXT NOT NULL DEFAULT '',
+	wrapper         TEXT NOT NULL DEFAULT '',
+	tool            TEXT NOT NULL DEFAULT 'shell',
+	status          TEXT NOT NULL DEFAULT 'error',
+	tmux_session    TEXT NOT NULL DEFAULT '',
+	created_at      INTEGER NOT NULL,
+	last_accessed   INTEGER NOT NULL DEFAULT 0,
+	parent_session_id TEXT NOT NULL DEFAULT '',
+	worktree_path     TEXT NOT NULL DEFAULT '',
+	worktree_repo     TEXT NOT NULL DEFAULT '',
+	worktree_branch   TEXT NOT NULL DEFAULT '',
+	tool_data       TEXT NOT NULL DEFAULT '{}',
+	acknowledged    INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE groups (
+	path         TEXT PRIMARY KEY,
+	name         TEXT NOT NULL,
+	expanded     INTEGER NOT NULL DEFAULT 1,
+	sort_order   INTEGER NOT NULL DEFAULT 0,
+	default_path TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE instance_heartbeats (
+	pid        INTEGER PRIMARY KEY,
+	started    INTEGER NOT NULL,
+	heartbeat  INTEGER NOT NULL,
+	is_primary INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO metadata (key, value) VALUES ('schema_version', '3');
+INSERT INTO metadata (key, value) VALUES ('last_modified', '1759312800000000000');
+INSERT INTO groups (path, name, expanded, sort_order) VALUES ('work', 'Work', 1, 0);
+INSERT INTO instances (id, title, project_path, group_path, tool, status, created_at, tool_data, acknowledged)
+VALUES ('inst-1', 'api', '/src/api', 'work', 'claude', 'idle', 1759312800, '{"claude_session_id":"abc"}', 1);
+
+CREATE TABLE session_history (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	instance_id TEXT NOT NULL,
+	ts          INTEGER NOT NULL,
+	kind        TEXT NOT NULL,
+	message     TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX idx_session_history_instance
+ON session_history (instance_id, ts);
+
+INSERT INTO session_history (instance_id, ts, kind, message) VALUES ('inst-1', 1759312900, 'compact', 'context at 85%');
+
+CREATE TABLE usage_sources (
+	instance_id TEXT PRIMARY KEY,
+	path        TEXT NOT NULL,
+	size        INTEGER NOT NULL,
+	mtime       INTEGER NOT NULL
+);
+CREATE TABLE usage_daily (
+	instance_id        TEXT NOT NULL,
+	day                TEXT NOT NULL,
+	model              TEXT NOT NULL DEFAULT '',
+	input_tokens       INTEGER NOT NULL DEFAULT 0,
+	output_tokens      INTEGER NOT NULL DEFAULT 0,
+	cache_read_tokens  INTEGER NOT NULL DEFAULT 0,
+	cache_write_tokens INTEGER NOT NULL DEFAULT 0,
+	cost               REAL NOT NULL DEFAULT 0,
+	turns              INTEGER NOT NULL DEFAULT 0,
+	tool_calls         TEXT NOT NULL DEFAULT '{}',
+	active_ms          INTEGER NOT NULL DEFAULT 0,
+	waiting_ms         INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (instance_id, day, model)
+);
+
+INSERT INTO usage_sources (instance_id, path, size, mtime) VALUES ('inst-1', '/home/u/.claude/projects/-src-api/abc.jsonl', 4096, 1759312900);
+INSERT INTO usage_daily (instance_id, day, model, input_tokens, output_tokens, cost, turns)
+VALUES ('inst-1', '2025-10-01', 'claude-sonnet-4-5', 1200, 300, 0.0081, 2);
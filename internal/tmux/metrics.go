@@ -0,0 +1,29 @@
+package tmux
+
+import "github.com/asheshgoplani/agent-deck/internal/metrics"
+
+var (
+	// capturePaneCalls counts CapturePane calls by where the content came
+	// from (cache, pipe, subprocess) and how it went (ok, error, timeout).
+	capturePaneCalls = metrics.NewCounterVec("agentdeck_tmux_capture_pane",
+		"CapturePane calls by content source and result.", "source", "result")
+
+	// pipeReconnects counts control pipe reconnect attempts.
+	pipeReconnects = metrics.NewCounterVec("agentdeck_tmux_pipe_reconnects",
+		"Control pipe reconnect attempts by result (ok, failed, gave_up).", "result")
+)
+
+func init() {
+	metrics.RegisterCollector(collectPipeMetrics)
+}
+
+// collectPipeMetrics reports how many control pipes are connected.
+func collectPipeMetrics() []*metrics.Gauge {
+	g := metrics.NewGauge("agentdeck_tmux_pipes_connected", "Control mode pipes currently connected.")
+	if pm := GetPipeManager(); pm != nil {
+		g.Set(float64(pm.ConnectedCount()))
+	} else {
+		g.Set(0)
+	}
+	return []*metrics.Gauge{g}
+}
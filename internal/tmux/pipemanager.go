@@ -369,10 +369,12 @@ func (pm *PipeManager) watchPipe(sessionName string, pipe *ControlPipe) {
 
 		err := pm.Connect(sessionName)
 		if err == nil {
+			pipeReconnects.Inc("ok")
 			pipeLog.Info("pipe_reconnected", slog.String("session", sessionName))
 			return
 		}
 
+		pipeReconnects.Inc("failed")
 		pipeLog.Debug("pipe_reconnect_failed",
 			slog.String("session", sessionName),
 			slog.String("error", err.Error()),
@@ -385,6 +387,7 @@ func (pm *PipeManager) watchPipe(sessionName string, pipe *ControlPipe) {
 		}
 	}
 
+	pipeReconnects.Inc("gave_up")
 	pipeLog.Debug("pipe_reconnect_gave_up", slog.String("session", sessionName), slog.Int("max_retries", maxRetries))
 	pm.mu.Lock()
 	delete(pm.pipes, sessionName)
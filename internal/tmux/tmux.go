@@ -479,6 +479,11 @@ type Session struct {
 	// Example: {"allow-passthrough": "all", "history-limit": "50000"}
 	OptionOverrides map[string]string
 
+	// Environment holds KEY=VALUE pairs set in the session's initial shell
+	// (new-session -e, tmux 3.0+). Used for plain shells, which have no
+	// command to carry exports.
+	Environment []string
+
 	// Custom patterns for generic tool support
 	customToolName       string
 	customBusyPatterns   []string
@@ -889,7 +894,11 @@ func (s *Session) Start(command string) error {
 	}
 
 	// Create new tmux session in detached mode
-	cmd := exec.Command("tmux", "new-session", "-d", "-s", s.Name, "-c", workDir)
+	args := []string{"new-session", "-d", "-s", s.Name, "-c", workDir}
+	for _, kv := range s.Environment {
+		args = append(args, "-e", kv)
+	}
+	cmd := exec.Command("tmux", args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to create tmux session: %w (output: %s)", err, string(output))
@@ -1385,6 +1394,7 @@ func (s *Session) CapturePane() (string, error) {
 	if s.cacheContent != "" && time.Since(s.cacheTime) < 500*time.Millisecond {
 		content := s.cacheContent
 		s.cacheMu.RUnlock()
+		capturePaneCalls.Inc("cache", "ok")
 		return content, nil
 	}
 	s.cacheMu.RUnlock()
@@ -1396,6 +1406,7 @@ func (s *Session) CapturePane() (string, error) {
 		if s.cacheContent != "" && time.Since(s.cacheTime) < 500*time.Millisecond {
 			content := s.cacheContent
 			s.cacheMu.RUnlock()
+			capturePaneCalls.Inc("cache", "ok")
 			return content, nil
 		}
 		s.cacheMu.RUnlock()
@@ -1407,8 +1418,10 @@ func (s *Session) CapturePane() (string, error) {
 				s.cacheContent = content
 				s.cacheTime = time.Now()
 				s.cacheMu.Unlock()
+				capturePaneCalls.Inc("pipe", "ok")
 				return content, nil
 			}
+			capturePaneCalls.Inc("pipe", "error")
 			// Pipe failed: log it so we can verify zero subprocess usage
 			statusLog.Debug("capture_pane_subprocess_fallback", slog.String("session", s.Name))
 		}
@@ -1420,10 +1433,13 @@ func (s *Session) CapturePane() (string, error) {
 		output, err := cmd.Output()
 		if err != nil {
 			if ctx.Err() == context.DeadlineExceeded {
+				capturePaneCalls.Inc("subprocess", "timeout")
 				return "", ErrCaptureTimeout
 			}
+			capturePaneCalls.Inc("subprocess", "error")
 			return "", fmt.Errorf("failed to capture pane: %w", err)
 		}
+		capturePaneCalls.Inc("subprocess", "ok")
 
 		content := string(output)
 
@@ -316,7 +316,7 @@ func (p *AnalyticsPanel) renderEmpty() string {
 	b.WriteString("\n\n")
 	b.WriteString(dimStyle.Render("No analytics available"))
 	b.WriteString("\n")
-	b.WriteString(dimStyle.Render("(Claude/Gemini sessions only)"))
+	b.WriteString(dimStyle.Render("(Claude, Codex, Gemini and OpenCode sessions only)"))
 
 	return b.String()
 }
@@ -342,7 +342,7 @@ func (p *AnalyticsPanel) renderContextBar() string {
 	labelStyle := lipgloss.NewStyle().Foreground(ColorText).Bold(true)
 	dimStyle := lipgloss.NewStyle().Foreground(ColorTextDim)
 
-	percent := p.analytics.ContextPercent(0) // Use default 200k limit
+	percent := p.analytics.ContextPercent(0) // Limit from the model table
 	if percent > 100 {
 		percent = 100
 	}
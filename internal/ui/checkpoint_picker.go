@@ -0,0 +1,303 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+)
+
+// checkpointStatMaxLines caps the diffstat shown under the list
+const checkpointStatMaxLines = 8
+
+// CheckpointPicker lists a session's per-turn git checkpoints (newest
+// first) with a diffstat of what changed since the selected one, and
+// restores a checkpoint after confirmation.
+type CheckpointPicker struct {
+	visible       bool
+	width, height int
+
+	sessionID string
+	title     string
+	dir       string
+
+	checkpoints []git.Checkpoint // Newest first
+	loading     bool
+	err         error
+	cursor      int
+
+	stats     map[int]string // Checkpoint N -> diffstat against the work tree
+	requested map[int]bool   // Stats already being collected
+
+	confirming bool
+	restore    int // Checkpoint N to restore, taken by the parent
+	reload     bool
+	status     string
+	statusErr  bool
+}
+
+// NewCheckpointPicker creates a new checkpoint picker.
+func NewCheckpointPicker() *CheckpointPicker {
+	return &CheckpointPicker{}
+}
+
+// Show opens the picker for a session's work tree at dir. The caller loads
+// the list and delivers it with SetCheckpoints.
+func (p *CheckpointPicker) Show(sessionID, title, dir string) {
+	*p = CheckpointPicker{
+		visible:   true,
+		width:     p.width,
+		height:    p.height,
+		sessionID: sessionID,
+		title:     title,
+		dir:       dir,
+		loading:   true,
+		stats:     make(map[int]string),
+		requested: make(map[int]bool),
+	}
+}
+
+// Hide closes the picker.
+func (p *CheckpointPicker) Hide() {
+	p.visible = false
+	p.checkpoints = nil
+}
+
+// IsVisible returns whether the picker is shown.
+func (p *CheckpointPicker) IsVisible() bool {
+	return p.visible
+}
+
+// SessionID returns the session whose checkpoints are shown.
+func (p *CheckpointPicker) SessionID() string {
+	return p.sessionID
+}
+
+// Dir returns the work tree the picker is showing.
+func (p *CheckpointPicker) Dir() string {
+	return p.dir
+}
+
+// SetSize updates the dimensions for centering.
+func (p *CheckpointPicker) SetSize(w, h int) {
+	p.width = w
+	p.height = h
+}
+
+// SetCheckpoints delivers the session's checkpoints, oldest first as
+// git.ListCheckpoints returns them. Cached diffstats are dropped since the
+// work tree may have changed.
+func (p *CheckpointPicker) SetCheckpoints(sessionID string, checkpoints []git.Checkpoint, err error) {
+	if sessionID != p.sessionID {
+		return
+	}
+	p.loading = false
+	p.err = err
+	p.checkpoints = make([]git.Checkpoint, len(checkpoints))
+	for i, cp := range checkpoints {
+		p.checkpoints[len(checkpoints)-1-i] = cp
+	}
+	p.cursor = min(p.cursor, max(len(p.checkpoints)-1, 0))
+	p.stats = make(map[int]string)
+	p.requested = make(map[int]bool)
+}
+
+// SetStat delivers the diffstat of checkpoint n against the work tree.
+func (p *CheckpointPicker) SetStat(sessionID string, n int, stat string, err error) {
+	if sessionID != p.sessionID {
+		return
+	}
+	if err != nil {
+		stat = "Error: " + err.Error()
+	}
+	p.stats[n] = stat
+}
+
+// SetResult shows the outcome of a restore.
+func (p *CheckpointPicker) SetResult(sessionID, status string, err error) {
+	if sessionID != p.sessionID {
+		return
+	}
+	p.status, p.statusErr = status, err != nil
+	if err != nil {
+		p.status = "Restore failed: " + err.Error()
+	}
+}
+
+// Selected returns the checkpoint under the cursor, or nil.
+func (p *CheckpointPicker) Selected() *git.Checkpoint {
+	if p.cursor < 0 || p.cursor >= len(p.checkpoints) {
+		return nil
+	}
+	return &p.checkpoints[p.cursor]
+}
+
+// NeedsStat returns the selected checkpoint when its diffstat hasn't been
+// requested yet, marking it requested.
+func (p *CheckpointPicker) NeedsStat() (int, bool) {
+	cp := p.Selected()
+	if cp == nil || p.requested[cp.N] {
+		return 0, false
+	}
+	p.requested[cp.N] = true
+	return cp.N, true
+}
+
+// TakeRestore returns the checkpoint the user confirmed restoring (0 for
+// none) and clears it.
+func (p *CheckpointPicker) TakeRestore() int {
+	n := p.restore
+	p.restore = 0
+	return n
+}
+
+// WantsReload reports (once) that the user asked to reload the list.
+func (p *CheckpointPicker) WantsReload() bool {
+	r := p.reload
+	p.reload = false
+	return r
+}
+
+// Update handles key events.
+func (p *CheckpointPicker) Update(msg tea.KeyMsg) (*CheckpointPicker, tea.Cmd) {
+	if !p.visible {
+		return p, nil
+	}
+	if p.confirming {
+		switch msg.String() {
+		case "y", "Y":
+			if cp := p.Selected(); cp != nil {
+				p.restore = cp.N
+				p.status, p.statusErr = fmt.Sprintf("Restoring checkpoint #%d...", cp.N), false
+			}
+			p.confirming = false
+		case "n", "N", "esc":
+			p.confirming = false
+		}
+		return p, nil
+	}
+
+	switch msg.String() {
+	case "j", "down":
+		if len(p.checkpoints) > 0 {
+			p.cursor = (p.cursor + 1) % len(p.checkpoints)
+		}
+	case "k", "up":
+		if len(p.checkpoints) > 0 {
+			p.cursor = (p.cursor - 1 + len(p.checkpoints)) % len(p.checkpoints)
+		}
+	case "g", "home":
+		p.cursor = 0
+	case "G", "end":
+		p.cursor = max(len(p.checkpoints)-1, 0)
+	case "enter", "R":
+		if p.Selected() != nil {
+			p.confirming = true
+			p.status = ""
+		}
+	case "r":
+		p.reload = true
+		p.loading = true
+	case "esc", "q", "C":
+		p.Hide()
+	}
+	return p, nil
+}
+
+// View renders the picker.
+func (p *CheckpointPicker) View() string {
+	if !p.visible {
+		return ""
+	}
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(ColorAccent)
+	dimStyle := lipgloss.NewStyle().Foreground(ColorTextDim)
+	selectedStyle := lipgloss.NewStyle().Foreground(ColorAccent).Bold(true)
+	normalStyle := lipgloss.NewStyle().Foreground(ColorText)
+	footerStyle := lipgloss.NewStyle().Foreground(ColorComment).Italic(true)
+
+	dialogWidth := 72
+	if p.width > 0 && p.width < dialogWidth+10 {
+		dialogWidth = max(p.width-10, 36)
+	}
+	inner := dialogWidth - 4
+
+	var lines []string
+	lines = append(lines, titleStyle.Render("Checkpoints"))
+	lines = append(lines, dimStyle.Render(runewidth.Truncate(fmt.Sprintf("%s · %s", p.title, p.dir), inner, "…")))
+	lines = append(lines, "")
+
+	switch {
+	case p.loading && len(p.checkpoints) == 0:
+		lines = append(lines, normalStyle.Render("Loading checkpoints..."))
+	case p.err != nil:
+		lines = append(lines, lipgloss.NewStyle().Foreground(ColorRed).Render(runewidth.Truncate(p.err.Error(), inner, "…")))
+	case len(p.checkpoints) == 0:
+		lines = append(lines, normalStyle.Render("No checkpoints yet"))
+		lines = append(lines, dimStyle.Render("Enable [checkpoints] in config.toml to snapshot each turn"))
+	default:
+		listHeight := max(p.height-checkpointStatMaxLines-14, 3)
+		start := 0
+		if p.cursor >= listHeight {
+			start = p.cursor - listHeight + 1
+		}
+		end := min(start+listHeight, len(p.checkpoints))
+		for i := start; i < end; i++ {
+			cp := p.checkpoints[i]
+			short := cp.Commit
+			if len(short) > 7 {
+				short = short[:7]
+			}
+			meta := fmt.Sprintf("#%-3d %-9s %s ", cp.N, formatRelativeTime(cp.CreatedAt), short)
+			label := runewidth.Truncate(meta+cp.Message, inner-2, "…")
+			if i == p.cursor {
+				lines = append(lines, "> "+selectedStyle.Render(label))
+			} else {
+				lines = append(lines, "  "+normalStyle.Render(label))
+			}
+		}
+
+		lines = append(lines, "")
+		if cp := p.Selected(); cp != nil {
+			lines = append(lines, dimStyle.Render(fmt.Sprintf("Changes since #%d:", cp.N)))
+			stat, ok := p.stats[cp.N]
+			switch {
+			case !ok:
+				lines = append(lines, dimStyle.Render("  Loading..."))
+			case strings.TrimSpace(stat) == "":
+				lines = append(lines, dimStyle.Render("  None: the work tree matches this checkpoint"))
+			default:
+				statLines := strings.Split(strings.TrimRight(stat, "\n"), "\n")
+				if len(statLines) > checkpointStatMaxLines {
+					summary := statLines[len(statLines)-1]
+					statLines = append(statLines[:checkpointStatMaxLines-1], " ...", summary)
+				}
+				for _, l := range statLines {
+					lines = append(lines, normalStyle.Render(runewidth.Truncate(l, inner, "…")))
+				}
+			}
+		}
+	}
+
+	lines = append(lines, "")
+	switch {
+	case p.confirming:
+		if cp := p.Selected(); cp != nil {
+			lines = append(lines, lipgloss.NewStyle().Foreground(ColorYellow).Bold(true).
+				Render(fmt.Sprintf("Restore work tree to #%d? Current state is checkpointed first. (y/n)", cp.N)))
+		}
+	case p.status != "":
+		style := lipgloss.NewStyle().Foreground(ColorGreen)
+		if p.statusErr {
+			style = lipgloss.NewStyle().Foreground(ColorRed)
+		}
+		lines = append(lines, style.Render(runewidth.Truncate(p.status, inner, "…")))
+	}
+	lines = append(lines, footerStyle.Render("Enter restore | r reload | j/k navigate | Esc close"))
+
+	box := DialogBoxStyle.Width(dialogWidth).Render(strings.Join(lines, "\n"))
+	return centerInScreen(box, p.width, p.height)
+}
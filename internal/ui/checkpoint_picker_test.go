@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+)
+
+func pickerKey(s string) tea.KeyMsg {
+	switch s {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestCheckpointPicker_ListAndRestore(t *testing.T) {
+	p := NewCheckpointPicker()
+	p.SetSize(100, 40)
+	p.Show("s1", "demo", "/repo")
+	if !p.IsVisible() || !strings.Contains(p.View(), "Loading checkpoints") {
+		t.Fatal("picker should open in the loading state")
+	}
+
+	now := time.Now()
+	p.SetCheckpoints("other", []git.Checkpoint{{N: 9}}, nil)
+	if p.Selected() != nil {
+		t.Error("checkpoints for another session should be ignored")
+	}
+	p.SetCheckpoints("s1", []git.Checkpoint{
+		{N: 1, Commit: "aaaaaaaaaa", Message: "Turn ended (Stop)", CreatedAt: now.Add(-time.Hour)},
+		{N: 2, Commit: "bbbbbbbbbb", Message: "Turn ended", CreatedAt: now},
+	}, nil)
+
+	// Newest first; the selection's stat is requested once
+	if cp := p.Selected(); cp == nil || cp.N != 2 {
+		t.Fatalf("selected = %+v, want #2", cp)
+	}
+	if n, ok := p.NeedsStat(); !ok || n != 2 {
+		t.Errorf("NeedsStat = %d, %v", n, ok)
+	}
+	if _, ok := p.NeedsStat(); ok {
+		t.Error("stat should be requested only once")
+	}
+	p.SetStat("s1", 2, " a.txt | 2 +-\n 1 file changed\n", nil)
+	view := p.View()
+	for _, want := range []string{"#2", "bbbbbbb", "#1", "Turn ended (Stop)", "a.txt | 2 +-"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+
+	p.Update(pickerKey("j"))
+	if cp := p.Selected(); cp == nil || cp.N != 1 {
+		t.Fatalf("after j selected = %+v, want #1", cp)
+	}
+
+	// Restore needs confirmation
+	p.Update(pickerKey("enter"))
+	if !strings.Contains(p.View(), "Restore work tree to #1?") {
+		t.Error("enter should ask for confirmation")
+	}
+	p.Update(pickerKey("n"))
+	if p.TakeRestore() != 0 {
+		t.Error("declined restore should not be taken")
+	}
+	p.Update(pickerKey("enter"))
+	p.Update(pickerKey("y"))
+	if n := p.TakeRestore(); n != 1 {
+		t.Errorf("TakeRestore = %d, want 1", n)
+	}
+	if p.TakeRestore() != 0 {
+		t.Error("TakeRestore should clear the request")
+	}
+
+	p.Update(pickerKey("esc"))
+	if p.IsVisible() {
+		t.Error("esc should close the picker")
+	}
+}
+
+func TestCheckpointPicker_Empty(t *testing.T) {
+	p := NewCheckpointPicker()
+	p.Show("s1", "demo", "/repo")
+	p.SetCheckpoints("s1", nil, nil)
+	if !strings.Contains(p.View(), "No checkpoints yet") {
+		t.Errorf("view = %s", p.View())
+	}
+	p.Update(pickerKey("enter"))
+	if p.TakeRestore() != 0 {
+		t.Error("nothing to restore")
+	}
+	if _, ok := p.NeedsStat(); ok {
+		t.Error("no stat without a selection")
+	}
+}
@@ -18,6 +18,7 @@ const (
 	ConfirmQuitWithPool
 	ConfirmCreateDirectory
 	ConfirmInstallHooks
+	ConfirmRevokeShares
 )
 
 // ConfirmDialog handles confirmation for destructive actions
@@ -30,6 +31,10 @@ type ConfirmDialog struct {
 	height      int
 	mcpCount    int // Number of running MCPs (for quit confirmation)
 
+	// Share links being revoked (for ConfirmRevokeShares)
+	shareLinks   int
+	shareViewers int
+
 	// Pending session creation data (for ConfirmCreateDirectory)
 	pendingSessionName      string
 	pendingSessionPath      string
@@ -89,6 +94,16 @@ func (c *ConfirmDialog) ShowInstallHooks() {
 	c.targetName = ""
 }
 
+// ShowRevokeShares shows confirmation for revoking a session's web share links
+func (c *ConfirmDialog) ShowRevokeShares(sessionID, sessionName string, links, viewers int) {
+	c.visible = true
+	c.confirmType = ConfirmRevokeShares
+	c.targetID = sessionID
+	c.targetName = sessionName
+	c.shareLinks = links
+	c.shareViewers = viewers
+}
+
 // GetPendingSession returns the pending session creation data
 func (c *ConfirmDialog) GetPendingSession() (name, path, command, groupPath string, toolOptionsJSON json.RawMessage) {
 	return c.pendingSessionName, c.pendingSessionPath, c.pendingSessionCommand, c.pendingSessionGroupPath, c.pendingToolOptionsJSON
@@ -191,6 +206,29 @@ func (c *ConfirmDialog) View() string {
 			Render("(Esc to cancel)")
 		buttons = lipgloss.JoinHorizontal(lipgloss.Center, buttonYes, "  ", buttonNo, "  ", escHint)
 
+	case ConfirmRevokeShares:
+		title = "Revoke Share Links?"
+		warning = fmt.Sprintf("Revoke %d share link(s) to:\n\n  \"%s\"", c.shareLinks, c.targetName)
+		details = fmt.Sprintf("• %d viewer(s) will be disconnected\n• The links stop working right away\n• The session keeps running", c.shareViewers)
+		borderColor = ColorRed
+
+		buttonYes := lipgloss.NewStyle().
+			Foreground(ColorBg).
+			Background(ColorRed).
+			Padding(0, 2).
+			Bold(true).
+			Render("y Revoke")
+		buttonNo := lipgloss.NewStyle().
+			Foreground(ColorBg).
+			Background(ColorAccent).
+			Padding(0, 2).
+			Bold(true).
+			Render("n Cancel")
+		escHint := lipgloss.NewStyle().
+			Foreground(ColorTextDim).
+			Render("(Esc to cancel)")
+		buttons = lipgloss.JoinHorizontal(lipgloss.Center, buttonYes, "  ", buttonNo, "  ", escHint)
+
 	case ConfirmQuitWithPool:
 		title = "MCP Pool Running"
 		warning = fmt.Sprintf("%d MCP servers are running in the pool.", c.mcpCount)
@@ -0,0 +1,591 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// diffLineKind selects how a diff line is styled
+type diffLineKind int
+
+const (
+	diffLineContext diffLineKind = iota
+	diffLineHunk                 // "@@ -1,3 +1,4 @@" header
+	diffLineAdd
+	diffLineDel
+	diffLineNote // "\ No newline at end of file", binary and empty-file notes
+)
+
+// diffLine is one laid-out line of a file's diff. hunk is the index of the
+// hunk it belongs to, or -1 for notes outside any hunk.
+type diffLine struct {
+	kind diffLineKind
+	text string
+	hunk int
+}
+
+// layoutFileDiff turns a file's hunks into display lines.
+func layoutFileDiff(f *git.FileDiff) []diffLine {
+	if f == nil {
+		return nil
+	}
+	switch {
+	case f.Binary:
+		return []diffLine{{kind: diffLineNote, text: "Binary file changed", hunk: -1}}
+	case len(f.Hunks) == 0 && f.Status == "R":
+		return []diffLine{{kind: diffLineNote, text: "Renamed from " + f.OldPath + " (content unchanged)", hunk: -1}}
+	case len(f.Hunks) == 0:
+		return []diffLine{{kind: diffLineNote, text: "No content changes", hunk: -1}}
+	}
+	var lines []diffLine
+	if f.Status == "R" {
+		lines = append(lines, diffLine{kind: diffLineNote, text: "Renamed from " + f.OldPath, hunk: -1})
+	}
+	for i, h := range f.Hunks {
+		lines = append(lines, diffLine{kind: diffLineHunk, text: h.Header, hunk: i})
+		for _, l := range h.Lines {
+			kind := diffLineContext
+			switch {
+			case strings.HasPrefix(l, "+"):
+				kind = diffLineAdd
+			case strings.HasPrefix(l, "-"):
+				kind = diffLineDel
+			case strings.HasPrefix(l, `\`):
+				kind = diffLineNote
+			}
+			lines = append(lines, diffLine{kind: kind, text: strings.ReplaceAll(l, "\t", "    "), hunk: i})
+		}
+	}
+	return lines
+}
+
+// renderDiffLine styles one diff line, truncated to width. The current
+// hunk gets a marker in the gutter.
+func renderDiffLine(l diffLine, width int, current bool) string {
+	var style lipgloss.Style
+	switch l.kind {
+	case diffLineHunk:
+		style = lipgloss.NewStyle().Foreground(ColorCyan)
+	case diffLineAdd:
+		style = lipgloss.NewStyle().Foreground(ColorGreen)
+	case diffLineDel:
+		style = lipgloss.NewStyle().Foreground(ColorRed)
+	case diffLineNote:
+		style = lipgloss.NewStyle().Foreground(ColorComment).Italic(true)
+	default:
+		style = lipgloss.NewStyle().Foreground(ColorText)
+	}
+	gutter := "  "
+	if current {
+		gutter = lipgloss.NewStyle().Foreground(ColorAccent).Render("▌ ")
+	}
+	return gutter + style.Render(runewidth.Truncate(l.text, max(width-2, 1), "…"))
+}
+
+// diffStatusStyle colours a file's one-letter status
+func diffStatusStyle(status string) lipgloss.Style {
+	switch status {
+	case "A", "?":
+		return lipgloss.NewStyle().Foreground(ColorGreen)
+	case "D":
+		return lipgloss.NewStyle().Foreground(ColorRed)
+	case "R":
+		return lipgloss.NewStyle().Foreground(ColorPurple)
+	default:
+		return lipgloss.NewStyle().Foreground(ColorYellow)
+	}
+}
+
+// DiffActionKind is a change the user asked the diff viewer to make
+type DiffActionKind int
+
+const (
+	DiffActionRevertFile DiffActionKind = iota
+	DiffActionRevertHunk
+	DiffActionCommit
+)
+
+// DiffAction is a pending change for the caller to run against the work tree
+type DiffAction struct {
+	Kind      DiffActionKind
+	Dir       string
+	MergeBase string
+	File      git.FileDiff
+	Hunk      int
+	Message   string
+}
+
+// diffViewerMode is what the viewer is waiting for
+type diffViewerMode int
+
+const (
+	diffModeBrowse diffViewerMode = iota
+	diffModeConfirm
+	diffModeCommit
+)
+
+// DiffViewer is a full-screen review of everything a session's branch would
+// bring into its base branch: a file list beside a scrollable, colourised
+// unified diff, with per-file and per-hunk revert and commit.
+type DiffViewer struct {
+	visible   bool
+	width     int
+	height    int
+	sessionID string
+	title     string
+	dir       string
+	base      string
+
+	diff    *git.Diff
+	err     error
+	fileIdx int
+	hunkIdx int
+	lines   []diffLine
+	scroll  int
+
+	mode    diffViewerMode
+	confirm *DiffAction // Awaiting y/n in diffModeConfirm
+	input   textinput.Model
+	status  string
+	busy    bool
+
+	action          *DiffAction
+	reloadRequested bool
+}
+
+// NewDiffViewer creates a hidden diff viewer
+func NewDiffViewer() *DiffViewer {
+	ti := textinput.New()
+	ti.Prompt = "Commit message: "
+	ti.CharLimit = 200
+	ti.Width = 60
+	return &DiffViewer{input: ti}
+}
+
+// Show opens the viewer for a session's work tree at dir, compared against
+// base (empty for the default branch). The diff arrives via SetDiff.
+func (v *DiffViewer) Show(sessionID, title, dir, base string) {
+	if v.sessionID != sessionID || v.dir != dir || v.base != base {
+		v.diff, v.err, v.lines = nil, nil, nil
+		v.fileIdx, v.hunkIdx, v.scroll = 0, 0, 0
+	}
+	v.visible = true
+	v.sessionID = sessionID
+	v.title = title
+	v.dir = dir
+	v.base = base
+	v.mode = diffModeBrowse
+	v.confirm = nil
+	v.status = ""
+	v.busy = false
+}
+
+// Hide closes the viewer
+func (v *DiffViewer) Hide() {
+	v.visible = false
+	v.input.Blur()
+}
+
+// IsVisible returns whether the viewer is open
+func (v *DiffViewer) IsVisible() bool {
+	return v.visible
+}
+
+// SessionID returns the session being reviewed
+func (v *DiffViewer) SessionID() string {
+	return v.sessionID
+}
+
+// Dir returns the work tree being reviewed
+func (v *DiffViewer) Dir() string {
+	return v.dir
+}
+
+// Base returns the branch the work tree is compared against ("" = default)
+func (v *DiffViewer) Base() string {
+	return v.base
+}
+
+// WantsReload reports (once) whether the diff should be re-read
+func (v *DiffViewer) WantsReload() bool {
+	r := v.reloadRequested
+	v.reloadRequested = false
+	return r
+}
+
+// TakeAction returns (once) the change the user confirmed, if any
+func (v *DiffViewer) TakeAction() *DiffAction {
+	a := v.action
+	v.action = nil
+	return a
+}
+
+// SetSize sets the viewer dimensions
+func (v *DiffViewer) SetSize(width, height int) {
+	v.width, v.height = width, height
+	v.setScroll(v.scroll)
+}
+
+// SetDiff updates the diff shown for sessionID, keeping the selected file
+// when it is still part of the diff (or its neighbour when it was reverted).
+func (v *DiffViewer) SetDiff(sessionID string, d *git.Diff, err error) {
+	if sessionID != v.sessionID {
+		return
+	}
+	selected := ""
+	if f := v.currentFile(); f != nil {
+		selected = f.Path
+	}
+	v.diff, v.err = d, err
+	if d != nil {
+		v.fileIdx = max(min(v.fileIdx, len(d.Files)-1), 0)
+		for i, f := range d.Files {
+			if f.Path == selected {
+				v.fileIdx = i
+				break
+			}
+		}
+	}
+	v.selectFile(v.fileIdx, false)
+}
+
+// SetResult reports the outcome of the last action
+func (v *DiffViewer) SetResult(sessionID, status string, err error) {
+	if sessionID != v.sessionID {
+		return
+	}
+	v.busy = false
+	if err != nil {
+		v.status = "Error: " + err.Error()
+		return
+	}
+	v.status = status
+}
+
+func (v *DiffViewer) currentFile() *git.FileDiff {
+	if v.diff == nil || v.fileIdx < 0 || v.fileIdx >= len(v.diff.Files) {
+		return nil
+	}
+	return &v.diff.Files[v.fileIdx]
+}
+
+func (v *DiffViewer) contentWidth() int {
+	return max(v.width-4, 40)
+}
+
+// fileListWidth is the width of the file column, including its separator
+func (v *DiffViewer) fileListWidth() int {
+	return min(max(v.contentWidth()/4, 20), 40)
+}
+
+func (v *DiffViewer) pageHeight() int {
+	return max(v.height-5, 5) // Title, separator, status/input line, footer
+}
+
+func (v *DiffViewer) maxScroll() int {
+	return max(len(v.lines)-v.pageHeight(), 0)
+}
+
+func (v *DiffViewer) setScroll(n int) {
+	v.scroll = max(min(n, v.maxScroll()), 0)
+}
+
+// selectFile shows file i. resetScroll starts it from the top; otherwise
+// the scroll position is kept as far as the new layout allows.
+func (v *DiffViewer) selectFile(i int, resetScroll bool) {
+	v.fileIdx = i
+	v.lines = layoutFileDiff(v.currentFile())
+	if resetScroll {
+		v.scroll, v.hunkIdx = 0, 0
+	}
+	if f := v.currentFile(); f == nil || v.hunkIdx >= len(f.Hunks) {
+		v.hunkIdx = 0
+	}
+	v.setScroll(v.scroll)
+}
+
+// hunkLine returns the line index of hunk i's header
+func (v *DiffViewer) hunkLine(i int) int {
+	for n, l := range v.lines {
+		if l.kind == diffLineHunk && l.hunk == i {
+			return n
+		}
+	}
+	return 0
+}
+
+// moveFile selects the previous (-1) or next (+1) file
+func (v *DiffViewer) moveFile(dir int) {
+	if v.diff == nil || len(v.diff.Files) == 0 {
+		return
+	}
+	n := len(v.diff.Files)
+	v.selectFile((v.fileIdx+dir+n)%n, true)
+}
+
+// moveHunk selects the previous (-1) or next (+1) hunk, moving on to the
+// neighbouring file at either end.
+func (v *DiffViewer) moveHunk(dir int) {
+	f := v.currentFile()
+	if f == nil {
+		return
+	}
+	next := v.hunkIdx + dir
+	if next < 0 || next >= len(f.Hunks) {
+		if len(v.diff.Files) < 2 {
+			return
+		}
+		v.moveFile(dir)
+		if dir < 0 {
+			if nf := v.currentFile(); nf != nil && len(nf.Hunks) > 0 {
+				v.hunkIdx = len(nf.Hunks) - 1
+				v.setScroll(v.hunkLine(v.hunkIdx))
+			}
+		}
+		return
+	}
+	v.hunkIdx = next
+	v.setScroll(v.hunkLine(next))
+}
+
+// syncHunk selects the hunk at the top of the view after scrolling
+func (v *DiffViewer) syncHunk() {
+	if v.scroll < len(v.lines) && v.lines[v.scroll].hunk >= 0 {
+		v.hunkIdx = v.lines[v.scroll].hunk
+	}
+}
+
+// askConfirm stages a revert for y/n confirmation
+func (v *DiffViewer) askConfirm(kind DiffActionKind) {
+	f := v.currentFile()
+	if f == nil || v.busy {
+		return
+	}
+	a := &DiffAction{Kind: kind, Dir: v.dir, MergeBase: v.diff.MergeBase, File: *f, Hunk: v.hunkIdx}
+	if kind == DiffActionRevertHunk {
+		if f.Binary || len(f.Hunks) == 0 {
+			v.status = "No hunks to revert in " + f.Path + "; use X to revert the file"
+			return
+		}
+		v.status = fmt.Sprintf("Revert hunk %d/%d of %s? (y/n)", v.hunkIdx+1, len(f.Hunks), f.Path)
+	} else {
+		what := "Revert " + f.Path + " to " + v.diff.Base
+		if f.Status == "?" || f.Status == "A" {
+			what = "Delete " + f.Path
+		}
+		v.status = what + "? (y/n)"
+	}
+	v.confirm = a
+	v.mode = diffModeConfirm
+}
+
+// Update handles keys while the viewer is open
+func (v *DiffViewer) Update(msg tea.KeyMsg) (*DiffViewer, tea.Cmd) {
+	switch v.mode {
+	case diffModeConfirm:
+		if msg.String() == "y" || msg.String() == "Y" {
+			v.action = v.confirm
+			v.busy = true
+			v.status = "Reverting…"
+		} else {
+			v.status = ""
+		}
+		v.confirm = nil
+		v.mode = diffModeBrowse
+		return v, nil
+	case diffModeCommit:
+		switch msg.String() {
+		case "esc":
+			v.mode = diffModeBrowse
+			v.input.Blur()
+		case "enter":
+			message := strings.TrimSpace(v.input.Value())
+			if message == "" {
+				return v, nil
+			}
+			v.mode = diffModeBrowse
+			v.input.Blur()
+			v.action = &DiffAction{Kind: DiffActionCommit, Dir: v.dir, Message: message}
+			v.busy = true
+			v.status = "Committing…"
+		default:
+			var cmd tea.Cmd
+			v.input, cmd = v.input.Update(msg)
+			return v, cmd
+		}
+		return v, nil
+	}
+
+	page := v.pageHeight()
+	if !v.busy {
+		v.status = ""
+	}
+	switch msg.String() {
+	case "esc", "q", "D":
+		v.Hide()
+	case "j", "down":
+		v.setScroll(v.scroll + 1)
+		v.syncHunk()
+	case "k", "up":
+		v.setScroll(v.scroll - 1)
+		v.syncHunk()
+	case "ctrl+d":
+		v.setScroll(v.scroll + page/2)
+		v.syncHunk()
+	case "ctrl+u":
+		v.setScroll(v.scroll - page/2)
+		v.syncHunk()
+	case "pgdown", " ", "ctrl+f":
+		v.setScroll(v.scroll + page)
+		v.syncHunk()
+	case "pgup", "ctrl+b":
+		v.setScroll(v.scroll - page)
+		v.syncHunk()
+	case "g", "home":
+		v.setScroll(0)
+		v.syncHunk()
+	case "G", "end":
+		v.setScroll(v.maxScroll())
+		v.syncHunk()
+	case "tab", "J", "l", "right":
+		v.moveFile(1)
+	case "shift+tab", "K", "h", "left":
+		v.moveFile(-1)
+	case "]", "}", "n":
+		v.moveHunk(1)
+	case "[", "{", "N":
+		v.moveHunk(-1)
+	case "x":
+		v.askConfirm(DiffActionRevertHunk)
+	case "X":
+		v.askConfirm(DiffActionRevertFile)
+	case "c":
+		if v.busy || v.diff == nil {
+			return v, nil
+		}
+		v.mode = diffModeCommit
+		v.input.Placeholder = "describe the changes"
+		v.input.SetValue("")
+		return v, v.input.Focus()
+	case "r":
+		v.reloadRequested = true
+		v.status = "Reloading…"
+	}
+	return v, nil
+}
+
+// View renders the viewer
+func (v *DiffViewer) View() string {
+	if !v.visible {
+		return ""
+	}
+	var b strings.Builder
+	width := v.contentWidth()
+
+	title := "🔀 Diff: " + v.title
+	if d := v.diff; d != nil {
+		branch := d.Branch
+		if branch == "" {
+			branch = "HEAD"
+		}
+		title += fmt.Sprintf("  (%s → %s · %d files · +%d -%d)", branch, d.Base, len(d.Files), d.Added, d.Deleted)
+	}
+	b.WriteString(lipgloss.NewStyle().Foreground(ColorCyan).Bold(true).Render(runewidth.Truncate(title, width, "…")))
+	b.WriteString("\n")
+	b.WriteString(lipgloss.NewStyle().Foreground(ColorBorder).Render(strings.Repeat("─", width)))
+	b.WriteString("\n")
+
+	page := v.pageHeight()
+	dim := lipgloss.NewStyle().Foreground(ColorComment).Italic(true)
+	var body []string
+	switch {
+	case v.err != nil:
+		body = []string{lipgloss.NewStyle().Foreground(ColorRed).Render("Failed to read diff: " + v.err.Error())}
+	case v.diff == nil:
+		body = []string{dim.Render("Loading diff…")}
+	case len(v.diff.Files) == 0:
+		body = []string{dim.Render("No changes against " + v.diff.Base)}
+	default:
+		body = v.renderPanes(width, page)
+	}
+	for i := 0; i < page; i++ {
+		if i < len(body) {
+			b.WriteString(body[i])
+		}
+		b.WriteString("\n")
+	}
+
+	// Input or status line
+	switch {
+	case v.mode == diffModeCommit:
+		b.WriteString(v.input.View())
+	case v.status != "":
+		color := ColorYellow
+		if strings.HasPrefix(v.status, "Error:") {
+			color = ColorRed
+		}
+		b.WriteString(lipgloss.NewStyle().Foreground(color).Render(runewidth.Truncate(v.status, width, "…")))
+	case len(v.lines) > page:
+		b.WriteString(dim.Render(fmt.Sprintf("line %d/%d", v.scroll+1, len(v.lines))))
+	}
+	b.WriteString("\n")
+
+	hints := "j/k scroll · PgUp/PgDn · Tab/J/K file · [ ] or n/N hunk · x revert hunk · X revert file · c commit all · r reload · Esc close"
+	b.WriteString(lipgloss.NewStyle().Foreground(ColorComment).Render(runewidth.Truncate(hints, width, "…")))
+
+	return lipgloss.NewStyle().Padding(0, 2).Render(b.String())
+}
+
+// renderPanes renders the file list and the selected file's diff side by
+// side, one string per row.
+func (v *DiffViewer) renderPanes(width, page int) []string {
+	listWidth := v.fileListWidth()
+	diffWidth := width - listWidth
+
+	files := v.diff.Files
+	first := 0
+	if v.fileIdx >= page {
+		first = v.fileIdx - page + 1
+	}
+	sep := lipgloss.NewStyle().Foreground(ColorBorder).Render("│ ")
+	selStyle := lipgloss.NewStyle().Foreground(ColorBg).Background(ColorAccent).Bold(true)
+	pathStyle := lipgloss.NewStyle().Foreground(ColorText)
+	countStyle := lipgloss.NewStyle().Foreground(ColorComment)
+
+	rows := make([]string, page)
+	for i := 0; i < page; i++ {
+		var left string
+		if fi := first + i; fi < len(files) {
+			f := files[fi]
+			counts := ""
+			if f.Binary {
+				counts = "bin"
+			} else if f.Added+f.Deleted > 0 {
+				counts = fmt.Sprintf("+%d -%d", f.Added, f.Deleted)
+			}
+			// "M path/to/file.go +3 -1" within the column, minus the separator
+			pathWidth := max(listWidth-2-2-1-runewidth.StringWidth(counts), 10)
+			path := runewidth.FillRight(truncatePath(f.Path, pathWidth), pathWidth)
+			if fi == v.fileIdx {
+				left = selStyle.Render(f.Status + " " + path + " " + counts)
+			} else {
+				left = diffStatusStyle(f.Status).Render(f.Status) + " " + pathStyle.Render(path) + " " + countStyle.Render(counts)
+			}
+		}
+		rows[i] = left + strings.Repeat(" ", max(listWidth-2-lipgloss.Width(left), 0)) + sep
+	}
+
+	f := v.currentFile()
+	end := min(v.scroll+page, len(v.lines))
+	for i := v.scroll; i < end; i++ {
+		l := v.lines[i]
+		rows[i-v.scroll] += renderDiffLine(l, diffWidth, l.hunk == v.hunkIdx && f != nil && len(f.Hunks) > 1)
+	}
+	return rows
+}
@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func testDiff() *git.Diff {
+	return &git.Diff{
+		Base: "main", MergeBase: "abc123", Branch: "feature", Added: 4, Deleted: 1,
+		Files: []git.FileDiff{
+			{Path: "lib.go", Status: "M", Added: 3, Deleted: 1, Hunks: []git.Hunk{
+				{Header: "@@ -1,2 +1,2 @@", Lines: []string{" package lib", "-var a = 1", "+var a = 2"}},
+				{Header: "@@ -10 +10,3 @@", Lines: []string{" }", "+", "+func C() {}"}},
+			}},
+			{Path: "notes.txt", Status: "?", Added: 1, Hunks: []git.Hunk{
+				{Header: "@@ -0,0 +1 @@", Lines: []string{"+todo"}},
+			}},
+			{Path: "logo.png", Status: "A", Binary: true},
+		},
+	}
+}
+
+func TestLayoutFileDiff(t *testing.T) {
+	d := testDiff()
+	lines := layoutFileDiff(&d.Files[0])
+	if len(lines) != 8 {
+		t.Fatalf("lines = %d, want 8", len(lines))
+	}
+	if lines[0].kind != diffLineHunk || lines[2].kind != diffLineDel || lines[3].kind != diffLineAdd || lines[4].hunk != 1 {
+		t.Errorf("layout = %+v", lines)
+	}
+	if bin := layoutFileDiff(&d.Files[2]); len(bin) != 1 || bin[0].kind != diffLineNote {
+		t.Errorf("binary layout = %+v", bin)
+	}
+}
+
+func TestDiffViewer_NavigateAndActions(t *testing.T) {
+	v := NewDiffViewer()
+	v.SetSize(120, 30)
+	v.Show("s1", "demo", "/repo", "")
+	if !strings.Contains(v.View(), "Loading diff") {
+		t.Error("expected loading state before SetDiff")
+	}
+	v.SetDiff("s1", testDiff(), nil)
+
+	out := v.View()
+	for _, want := range []string{"feature → main", "3 files", "lib.go", "notes.txt", "+var a = 2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("view missing %q", want)
+		}
+	}
+
+	// Hunk navigation moves through the file, then on to the next one
+	v.Update(transcriptKey("]"))
+	if v.fileIdx != 0 || v.hunkIdx != 1 {
+		t.Errorf("after ] file=%d hunk=%d, want 0/1", v.fileIdx, v.hunkIdx)
+	}
+	v.Update(transcriptKey("]"))
+	if v.fileIdx != 1 || v.hunkIdx != 0 {
+		t.Errorf("after ]] file=%d hunk=%d, want 1/0", v.fileIdx, v.hunkIdx)
+	}
+	v.Update(transcriptKey("["))
+	if v.fileIdx != 0 || v.hunkIdx != 1 {
+		t.Errorf("after [ file=%d hunk=%d, want 0/1", v.fileIdx, v.hunkIdx)
+	}
+
+	// Reverting asks first; anything but y cancels
+	v.Update(transcriptKey("x"))
+	v.Update(transcriptKey("n"))
+	if v.TakeAction() != nil {
+		t.Error("cancelled revert should not produce an action")
+	}
+	v.Update(transcriptKey("x"))
+	v.Update(transcriptKey("y"))
+	a := v.TakeAction()
+	if a == nil || a.Kind != DiffActionRevertHunk || a.Hunk != 1 || a.File.Path != "lib.go" || a.MergeBase != "abc123" || a.Dir != "/repo" {
+		t.Fatalf("hunk action = %+v", a)
+	}
+	if v.TakeAction() != nil {
+		t.Error("TakeAction should only return the action once")
+	}
+	v.SetResult("s1", "Reverted hunk", nil)
+
+	// Binary files can only be reverted whole
+	v.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	if v.currentFile().Path != "logo.png" {
+		t.Fatalf("shift+tab from the first file should wrap, got %s", v.currentFile().Path)
+	}
+	v.Update(transcriptKey("x"))
+	if v.mode != diffModeBrowse || !strings.Contains(v.status, "X to revert") {
+		t.Errorf("binary hunk revert: mode=%d status=%q", v.mode, v.status)
+	}
+	v.Update(transcriptKey("X"))
+	v.Update(transcriptKey("y"))
+	if a := v.TakeAction(); a == nil || a.Kind != DiffActionRevertFile || a.File.Path != "logo.png" {
+		t.Errorf("file action = %+v", a)
+	}
+	v.SetResult("s1", "", nil)
+
+	// Commit needs a message
+	v.Update(transcriptKey("c"))
+	v.Update(transcriptKey("enter"))
+	if v.TakeAction() != nil || v.mode != diffModeCommit {
+		t.Error("empty commit message should keep the prompt open")
+	}
+	for _, r := range "wip" {
+		v.Update(transcriptKey(string(r)))
+	}
+	v.Update(transcriptKey("enter"))
+	if a := v.TakeAction(); a == nil || a.Kind != DiffActionCommit || a.Message != "wip" {
+		t.Errorf("commit action = %+v", a)
+	}
+
+	v.Update(transcriptKey("esc"))
+	if v.IsVisible() {
+		t.Error("esc should close the viewer")
+	}
+}
+
+func TestDiffViewer_KeepsSelectionOnReload(t *testing.T) {
+	v := NewDiffViewer()
+	v.SetSize(120, 30)
+	v.Show("s1", "demo", "/repo", "main")
+	v.SetDiff("s1", testDiff(), nil)
+	v.Update(transcriptKey("J"))
+	if v.currentFile().Path != "notes.txt" {
+		t.Fatalf("selected %s, want notes.txt", v.currentFile().Path)
+	}
+
+	// notes.txt was reverted: the selection stays at the same position
+	d := testDiff()
+	d.Files = append(d.Files[:1], d.Files[2:]...)
+	v.SetDiff("s1", d, nil)
+	if v.currentFile().Path != "logo.png" {
+		t.Errorf("after reload selected %s, want logo.png", v.currentFile().Path)
+	}
+
+	v.SetDiff("other", nil, nil)
+	if v.diff == nil {
+		t.Error("diffs for other sessions should be ignored")
+	}
+}
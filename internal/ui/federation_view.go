@@ -0,0 +1,243 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/asheshgoplani/agent-deck/internal/web"
+)
+
+// federationRefreshInterval is how often the open federation view reloads
+// the merged menu; upstreams are followed through their event streams.
+const federationRefreshInterval = 2 * time.Second
+
+// FederationView lists the sessions of every [federation] source: local
+// profiles and upstream agent-deck web servers, grouped by host and
+// profile, with each host's reachability. Enter attaches to a session on
+// this machine or copies the web link of a remote one.
+type FederationView struct {
+	visible       bool
+	width, height int
+
+	items   []web.MenuItem
+	sources []web.FederationSourceStatus
+	loading bool
+	err     error
+	cursor  int // Index into items; only sessions are selectable
+
+	open      *web.MenuSession // Session the user picked, taken by the parent
+	status    string
+	statusErr bool
+}
+
+// NewFederationView creates a new federation view.
+func NewFederationView() *FederationView {
+	return &FederationView{}
+}
+
+// Show opens the view. The caller loads the menu and delivers it with
+// SetSnapshot.
+func (v *FederationView) Show() {
+	*v = FederationView{visible: true, width: v.width, height: v.height, loading: true}
+}
+
+// Hide closes the view.
+func (v *FederationView) Hide() {
+	v.visible = false
+	v.items = nil
+}
+
+// IsVisible returns whether the view is shown.
+func (v *FederationView) IsVisible() bool {
+	return v.visible
+}
+
+// SetSize updates the dimensions for centering.
+func (v *FederationView) SetSize(w, h int) {
+	v.width = w
+	v.height = h
+}
+
+// SetSnapshot delivers the merged menu, keeping the cursor on the same
+// session when it is still listed.
+func (v *FederationView) SetSnapshot(snapshot *web.MenuSnapshot, err error) {
+	v.loading = false
+	v.err = err
+	if err != nil || snapshot == nil {
+		return
+	}
+	selectedID := ""
+	if sess := v.Selected(); sess != nil {
+		selectedID = sess.ID
+	}
+	v.items = snapshot.Items
+	v.sources = snapshot.Sources
+	v.cursor = -1
+	for i, item := range v.items {
+		if item.Session == nil {
+			continue
+		}
+		if v.cursor < 0 || item.Session.ID == selectedID {
+			v.cursor = i
+		}
+		if item.Session.ID == selectedID {
+			break
+		}
+	}
+}
+
+// SetStatus shows the outcome of opening a session.
+func (v *FederationView) SetStatus(status string, err error) {
+	v.status, v.statusErr = status, err != nil
+	if err != nil {
+		v.status = err.Error()
+	}
+}
+
+// Selected returns the session under the cursor, or nil.
+func (v *FederationView) Selected() *web.MenuSession {
+	if v.cursor < 0 || v.cursor >= len(v.items) {
+		return nil
+	}
+	return v.items[v.cursor].Session
+}
+
+// TakeOpen returns the session the user picked (nil for none) and clears it.
+func (v *FederationView) TakeOpen() *web.MenuSession {
+	sess := v.open
+	v.open = nil
+	return sess
+}
+
+// moveCursor moves to the next selectable session in direction dir.
+func (v *FederationView) moveCursor(dir int) {
+	n := len(v.items)
+	for step := 1; step <= n; step++ {
+		i := ((v.cursor+dir*step)%n + n) % n
+		if v.items[i].Session != nil {
+			v.cursor = i
+			return
+		}
+	}
+}
+
+// Update handles key events.
+func (v *FederationView) Update(msg tea.KeyMsg) (*FederationView, tea.Cmd) {
+	if !v.visible {
+		return v, nil
+	}
+	switch msg.String() {
+	case "j", "down":
+		v.moveCursor(1)
+	case "k", "up":
+		v.moveCursor(-1)
+	case "enter":
+		if sess := v.Selected(); sess != nil {
+			v.open = sess
+		}
+	case "esc", "q", "H":
+		v.Hide()
+	}
+	return v, nil
+}
+
+// View renders the view.
+func (v *FederationView) View() string {
+	if !v.visible {
+		return ""
+	}
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(ColorAccent)
+	dimStyle := lipgloss.NewStyle().Foreground(ColorTextDim)
+	hostStyle := lipgloss.NewStyle().Foreground(ColorCyan).Bold(true)
+	groupStyle := lipgloss.NewStyle().Foreground(ColorText)
+	selectedStyle := lipgloss.NewStyle().Foreground(ColorAccent).Bold(true)
+	errStyle := lipgloss.NewStyle().Foreground(ColorRed)
+	footerStyle := lipgloss.NewStyle().Foreground(ColorComment).Italic(true)
+
+	dialogWidth := 80
+	if v.width > 0 && v.width < dialogWidth+10 {
+		dialogWidth = max(v.width-10, 40)
+	}
+	inner := dialogWidth - 4
+
+	var lines []string
+	lines = append(lines, titleStyle.Render("Federation"))
+	reachable := 0
+	for _, src := range v.sources {
+		if src.Reachable {
+			reachable++
+		}
+	}
+	lines = append(lines, dimStyle.Render(fmt.Sprintf("%d of %d sources reachable", reachable, len(v.sources))))
+	lines = append(lines, "")
+
+	switch {
+	case v.loading && len(v.items) == 0:
+		lines = append(lines, groupStyle.Render("Loading federated sessions..."))
+	case v.err != nil:
+		lines = append(lines, errStyle.Render(runewidth.Truncate(v.err.Error(), inner, "…")))
+	default:
+		listHeight := max(v.height-12, 3)
+		start := 0
+		if v.cursor >= listHeight {
+			start = v.cursor - listHeight + 1
+		}
+		end := min(start+listHeight, len(v.items))
+		for i := start; i < end; i++ {
+			item := v.items[i]
+			indent := strings.Repeat("  ", item.Level)
+			switch {
+			case item.Group != nil && item.Level == 0:
+				// Host row: reachability and session count
+				state := fmt.Sprintf(" %d sessions", item.Group.SessionCount)
+				style := hostStyle
+				if item.Group.Unreachable {
+					state = " unreachable: " + item.Group.Error
+					style = errStyle
+				}
+				lines = append(lines, style.Render(runewidth.Truncate(item.Group.Name+state, inner, "…")))
+			case item.Group != nil:
+				label := indent + item.Group.Name
+				if item.Group.Unreachable {
+					lines = append(lines, errStyle.Render(runewidth.Truncate(label+" ("+item.Group.Error+")", inner, "…")))
+				} else {
+					lines = append(lines, groupStyle.Render(runewidth.Truncate(label, inner, "…")))
+				}
+			case item.Session != nil:
+				sess := item.Session
+				title := runewidth.Truncate(sess.Title, max(inner-len(indent)-len(sess.Tool)-5, 8), "…")
+				if i == v.cursor {
+					title = selectedStyle.Render(title)
+				} else {
+					title = groupStyle.Render(title)
+				}
+				prefix := "  "
+				if i == v.cursor {
+					prefix = "> "
+				}
+				lines = append(lines, prefix+indent+statusIndicator(sess.Status)+" "+title+" "+dimStyle.Render(sess.Tool))
+			}
+		}
+		if v.cursor < 0 {
+			lines = append(lines, dimStyle.Render("No sessions"))
+		}
+	}
+
+	lines = append(lines, "")
+	if v.status != "" {
+		style := lipgloss.NewStyle().Foreground(ColorGreen)
+		if v.statusErr {
+			style = errStyle
+		}
+		lines = append(lines, style.Render(runewidth.Truncate(v.status, inner, "…")))
+	}
+	lines = append(lines, footerStyle.Render("Enter attach (this machine) / copy web link | j/k navigate | Esc close"))
+
+	box := DialogBoxStyle.Width(dialogWidth).Render(strings.Join(lines, "\n"))
+	return centerInScreen(box, v.width, v.height)
+}
@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/asheshgoplani/agent-deck/internal/session"
+	"github.com/asheshgoplani/agent-deck/internal/web"
+)
+
+func federationViewSnapshot(t *testing.T) *web.MenuSnapshot {
+	t.Helper()
+	local := web.NewMemoryMenuData(nil)
+	local.SetSnapshot(&web.MenuSnapshot{
+		Profile:       "default",
+		TotalGroups:   1,
+		TotalSessions: 2,
+		Items: []web.MenuItem{
+			{Type: web.MenuItemTypeGroup, Path: "ops", Group: &web.MenuGroup{Name: "ops", Path: "ops", SessionCount: 2}},
+			{Type: web.MenuItemTypeSession, Level: 1, Path: "ops", Session: &web.MenuSession{
+				ID: "s1", Title: "api", Tool: "claude", Status: session.StatusRunning, GroupPath: "ops", TmuxSession: "agentdeck_api"}},
+			{Type: web.MenuItemTypeSession, Level: 1, Path: "ops", Session: &web.MenuSession{
+				ID: "s2", Title: "web", Tool: "shell", Status: session.StatusIdle, GroupPath: "ops", TmuxSession: "agentdeck_web"}},
+		},
+	})
+	fed, err := web.NewFederation(web.FederationConfig{
+		Local:     []web.LocalSource{{Profile: "default", Menu: local}},
+		Upstreams: []web.Upstream{{Name: "buildbox", URL: "http://127.0.0.1:1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := fed.LoadMenuSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snapshot
+}
+
+func TestFederationView_NavigatesSessions(t *testing.T) {
+	v := NewFederationView()
+	v.SetSize(100, 40)
+	v.Show()
+	if !v.IsVisible() || !strings.Contains(v.View(), "Loading federated sessions") {
+		t.Fatal("view should open in the loading state")
+	}
+
+	v.SetSnapshot(federationViewSnapshot(t), nil)
+	if sess := v.Selected(); sess == nil || sess.ID != "local~default~s1" {
+		t.Fatalf("the first session should be selected, got %+v", sess)
+	}
+	view := v.View()
+	for _, want := range []string{"1 of 2 sources reachable", "api", "buildbox unreachable: not connected yet"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+
+	// Group rows are skipped and the cursor wraps around
+	v.Update(pickerKey("j"))
+	if sess := v.Selected(); sess == nil || sess.Title != "web" {
+		t.Fatalf("j should select web, got %+v", sess)
+	}
+	v.Update(pickerKey("j"))
+	if sess := v.Selected(); sess == nil || sess.Title != "api" {
+		t.Fatalf("j should wrap to api, got %+v", sess)
+	}
+	v.Update(pickerKey("k"))
+
+	// A refresh keeps the selection
+	v.SetSnapshot(federationViewSnapshot(t), nil)
+	if sess := v.Selected(); sess == nil || sess.Title != "web" {
+		t.Fatalf("refresh should keep web selected, got %+v", sess)
+	}
+
+	v.Update(pickerKey("enter"))
+	if sess := v.TakeOpen(); sess == nil || sess.TmuxSession != "agentdeck_web" {
+		t.Fatalf("enter should pick web, got %+v", sess)
+	}
+	if v.TakeOpen() != nil {
+		t.Error("TakeOpen should clear the pick")
+	}
+
+	v.Update(pickerKey("esc"))
+	if v.IsVisible() {
+		t.Error("esc should close the view")
+	}
+}
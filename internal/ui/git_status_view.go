@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+)
+
+// gitPreviewMaxFiles caps the changed files listed in the preview pane
+const gitPreviewMaxFiles = 6
+
+// gitBadgeText is the compact list-row summary of a git status, e.g.
+// "±3 +120/-4 ↑2↓1". Empty when the tree is clean and in sync.
+func gitBadgeText(st *git.Status) string {
+	if st == nil {
+		return ""
+	}
+	var parts []string
+	if changed := st.Staged + st.Unstaged + st.Untracked + st.Conflicted; changed > 0 {
+		parts = append(parts, fmt.Sprintf("±%d", changed))
+	}
+	if st.LinesAdded+st.LinesDeleted > 0 {
+		parts = append(parts, fmt.Sprintf("+%d/-%d", st.LinesAdded, st.LinesDeleted))
+	}
+	if sync := aheadBehindText(st.Ahead, st.Behind); sync != "" {
+		parts = append(parts, sync)
+	}
+	return strings.Join(parts, " ")
+}
+
+// renderGitBadge renders gitBadgeText for a session row.
+func renderGitBadge(st *git.Status, selected bool) string {
+	text := gitBadgeText(st)
+	if text == "" {
+		return ""
+	}
+	style := lipgloss.NewStyle().Foreground(ColorYellow)
+	if st.Conflicted > 0 {
+		style = lipgloss.NewStyle().Foreground(ColorRed)
+	} else if !st.Dirty() {
+		style = lipgloss.NewStyle().Foreground(ColorComment)
+	}
+	if selected {
+		style = SessionStatusSelStyle
+	}
+	return style.Render(" " + text)
+}
+
+// renderGitSection renders the preview pane's git summary: branch position,
+// change counts and the most-changed files.
+func renderGitSection(st *git.Status, width int) string {
+	var b strings.Builder
+	b.WriteString(renderSectionDivider("Git", width-4))
+	b.WriteString("\n")
+
+	labelStyle := lipgloss.NewStyle().Foreground(ColorText)
+	branchStyle := lipgloss.NewStyle().Foreground(ColorCyan).Bold(true)
+	dimStyle := lipgloss.NewStyle().Foreground(ColorComment)
+	syncStyle := lipgloss.NewStyle().Foreground(ColorYellow)
+	addStyle := lipgloss.NewStyle().Foreground(ColorGreen)
+	delStyle := lipgloss.NewStyle().Foreground(ColorRed)
+
+	branch := st.Branch
+	if branch == "" {
+		branch = "(detached)"
+		if len(st.Commit) >= 7 {
+			branch = "(detached " + st.Commit[:7] + ")"
+		}
+	}
+	b.WriteString(labelStyle.Render("Branch:  "))
+	b.WriteString(branchStyle.Render(branch))
+	if st.Upstream != "" {
+		b.WriteString(dimStyle.Render(" → " + st.Upstream))
+		if sync := aheadBehindText(st.Ahead, st.Behind); sync != "" {
+			b.WriteString(" ")
+			b.WriteString(syncStyle.Render(sync))
+		}
+	}
+	b.WriteString("\n")
+
+	if st.DefaultBranch != "" {
+		b.WriteString(labelStyle.Render("Base:    "))
+		b.WriteString(dimStyle.Render(st.DefaultBranch))
+		sync := aheadBehindText(st.AheadDefault, st.BehindDefault)
+		if sync == "" {
+			sync = "even"
+		}
+		b.WriteString(" ")
+		b.WriteString(syncStyle.Render(sync))
+		b.WriteString("\n")
+	}
+
+	b.WriteString(labelStyle.Render("Changes: "))
+	if !st.Dirty() {
+		b.WriteString(lipgloss.NewStyle().Foreground(ColorGreen).Render("clean"))
+		b.WriteString("\n")
+		return b.String()
+	}
+	var counts []string
+	for _, c := range []struct {
+		n     int
+		label string
+	}{
+		{st.Conflicted, "conflicted"},
+		{st.Staged, "staged"},
+		{st.Unstaged, "unstaged"},
+		{st.Untracked, "untracked"},
+	} {
+		if c.n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", c.n, c.label))
+		}
+	}
+	b.WriteString(labelStyle.Render(strings.Join(counts, ", ")))
+	if st.LinesAdded+st.LinesDeleted > 0 {
+		b.WriteString("  ")
+		b.WriteString(addStyle.Render(fmt.Sprintf("+%d", st.LinesAdded)))
+		b.WriteString(" ")
+		b.WriteString(delStyle.Render(fmt.Sprintf("-%d", st.LinesDeleted)))
+	}
+	b.WriteString("\n")
+
+	// Changed files, largest first: "  M  path/to/file.go  +10 -2"
+	files := st.Files
+	if len(files) > gitPreviewMaxFiles {
+		files = files[:gitPreviewMaxFiles]
+	}
+	for _, f := range files {
+		stats := ""
+		switch {
+		case f.Binary:
+			stats = "bin"
+		case f.Added+f.Deleted > 0:
+			stats = fmt.Sprintf("+%d -%d", f.Added, f.Deleted)
+		}
+		pathWidth := width - 4 - 6 - runewidth.StringWidth(stats) - 2
+		b.WriteString(dimStyle.Render("  " + f.Code + "  "))
+		b.WriteString(labelStyle.Render(truncatePath(f.Path, pathWidth)))
+		if stats != "" {
+			b.WriteString("  ")
+			if f.Binary {
+				b.WriteString(dimStyle.Render(stats))
+			} else {
+				b.WriteString(addStyle.Render(fmt.Sprintf("+%d", f.Added)))
+				b.WriteString(" ")
+				b.WriteString(delStyle.Render(fmt.Sprintf("-%d", f.Deleted)))
+			}
+		}
+		b.WriteString("\n")
+	}
+	if more := len(st.Files) - len(files); more > 0 {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  … %d more", more)))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// aheadBehindText renders commit counts as "↑2↓1", omitting zero sides.
+func aheadBehindText(ahead, behind int) string {
+	s := ""
+	if ahead > 0 {
+		s += fmt.Sprintf("↑%d", ahead)
+	}
+	if behind > 0 {
+		s += fmt.Sprintf("↓%d", behind)
+	}
+	return s
+}
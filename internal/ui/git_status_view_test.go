@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+)
+
+func TestGitBadgeText(t *testing.T) {
+	tests := []struct {
+		name   string
+		status *git.Status
+		want   string
+	}{
+		{"nil", nil, ""},
+		{"clean and in sync", &git.Status{Branch: "main"}, ""},
+		{"dirty", &git.Status{Staged: 1, Unstaged: 2, LinesAdded: 120, LinesDeleted: 4}, "±3 +120/-4"},
+		{"untracked only", &git.Status{Untracked: 2}, "±2"},
+		{"ahead and behind", &git.Status{Ahead: 2, Behind: 1}, "↑2↓1"},
+		{"everything", &git.Status{Unstaged: 1, LinesAdded: 3, Ahead: 1}, "±1 +3/-0 ↑1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gitBadgeText(tt.status); got != tt.want {
+				t.Errorf("gitBadgeText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderGitSection(t *testing.T) {
+	st := &git.Status{
+		Branch:        "feature",
+		Upstream:      "origin/feature",
+		Ahead:         2,
+		DefaultBranch: "origin/main",
+		AheadDefault:  5,
+		BehindDefault: 1,
+		Staged:        1,
+		Untracked:     1,
+		LinesAdded:    10,
+		LinesDeleted:  2,
+		Files: []git.FileChange{
+			{Path: "a.go", Code: "M ", Added: 10, Deleted: 2},
+			{Path: "new.txt", Code: "??"},
+		},
+	}
+	out := renderGitSection(st, 80)
+	for _, want := range []string{"feature", "origin/feature", "↑2", "origin/main", "↑5↓1", "1 staged, 1 untracked", "a.go", "new.txt"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("section missing %q:\n%s", want, out)
+		}
+	}
+
+	clean := renderGitSection(&git.Status{Branch: "main"}, 80)
+	if !strings.Contains(clean, "clean") {
+		t.Errorf("clean section = %q", clean)
+	}
+}
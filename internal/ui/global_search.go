@@ -38,6 +38,7 @@ var (
 
 // GlobalSearchResult wraps a search result for UI display
 type GlobalSearchResult struct {
+	Tool        string // Tool that wrote the transcript ("claude", "codex", "gemini", "opencode")
 	SessionID   string
 	Summary     string
 	Snippet     string
@@ -84,7 +85,7 @@ type GlobalSearch struct {
 // NewGlobalSearch creates a new global search overlay
 func NewGlobalSearch() *GlobalSearch {
 	ti := textinput.New()
-	ti.Placeholder = "Search all Claude conversations..."
+	ti.Placeholder = "Search all conversations..."
 	ti.Focus()
 	ti.CharLimit = 100
 	ti.Width = 60
@@ -295,9 +296,18 @@ func (gs *GlobalSearch) applySearchResults(query string, searchResults []*sessio
 				content = sr.Entry.Summary
 			}
 		}
-		// Count occurrences of query in content (case-insensitive)
-		matchCount := strings.Count(strings.ToLower(content), queryLower)
+		// Count occurrences of query in content (case-insensitive).
+		// The persistent index reports matching messages directly.
+		matchCount := sr.Hits
+		if matchCount == 0 {
+			matchCount = strings.Count(strings.ToLower(content), queryLower)
+		}
+		tool := sr.Entry.Tool
+		if tool == "" {
+			tool = session.TranscriptToolClaude
+		}
 		gs.results = append(gs.results, &GlobalSearchResult{
+			Tool:       tool,
 			SessionID:  sr.Entry.SessionID,
 			Summary:    sr.Entry.Summary,
 			Snippet:    sr.Snippet,
@@ -324,6 +334,12 @@ func (gs *GlobalSearch) applySearchResults(query string, searchResults []*sessio
 		return scoreI > scoreJ
 	})
 
+	// Persistent tier queries can contain filters and FTS syntax; highlight
+	// the first plain term instead of the raw query
+	if gs.index != nil && gs.index.GetTier() == session.TierPersistent {
+		gs.query = session.SearchHighlightTerm(query)
+	}
+
 	gs.cursor = 0
 	gs.previewScroll = 0
 }
@@ -385,7 +401,10 @@ func (gs *GlobalSearch) View() string {
 		for i, result := range gs.results {
 			title := result.Summary
 			if title == "" {
-				title = result.SessionID[:8] + "..."
+				title = result.SessionID
+				if len(title) > 8 {
+					title = title[:8] + "..."
+				}
 			}
 			// Truncate to fit left pane
 			maxTitleLen := leftWidth - 12
@@ -416,7 +435,7 @@ func (gs *GlobalSearch) View() string {
 				}
 				leftPane.WriteString(lipgloss.NewStyle().
 					Foreground(ColorPurple).
-					Render(fmt.Sprintf("    %s • %d %s", dateStr, result.MatchCount, matchText)) + "\n")
+					Render(fmt.Sprintf("    %s • %s • %d %s", result.Tool, dateStr, result.MatchCount, matchText)) + "\n")
 			} else {
 				line := globalResultStyle.Render(fmt.Sprintf("%s%s", prefix, title))
 				leftPane.WriteString(line + "\n")
@@ -671,15 +690,15 @@ func (gs *GlobalSearch) highlightMatches(text, query string) string {
 
 // MarkInAgentDeck marks which results are already in Agent Deck
 func (gs *GlobalSearch) MarkInAgentDeck(instances []*session.Instance) {
-	idMap := make(map[string]string) // sessionID -> instanceID
+	idMap := make(map[string]string) // tool + sessionID -> instanceID
 	for _, inst := range instances {
-		if inst.ClaudeSessionID != "" {
-			idMap[inst.ClaudeSessionID] = inst.ID
+		if id := inst.ToolSessionID(); id != "" {
+			idMap[inst.Tool+":"+id] = inst.ID
 		}
 	}
 
 	for _, result := range gs.results {
-		if instID, ok := idMap[result.SessionID]; ok {
+		if instID, ok := idMap[result.Tool+":"+result.SessionID]; ok {
 			result.InAgentDeck = true
 			result.InstanceID = instID
 		}
@@ -119,19 +119,24 @@ func (h *HelpOverlay) View() string {
 				{"M", "Move to group"},
 				{"m", "MCP Manager (Claude/Gemini)"},
 				{"s", "Skills Manager (Claude)"},
-				{"v", "Toggle preview mode (output/stats/both)"},
+				{"v", "Toggle preview mode (output/stats/transcript/both)"},
+				{"V", "Open transcript viewer"},
+				{"D", "Review diff against base branch"},
+				{"C", "Browse/restore per-turn git checkpoints"},
 				{"u", "Mark unread"},
 				{"K / J", "Reorder up/down"},
 				{"f", "Quick fork (Claude only)"},
 				{"F", "Fork with options (Claude only)"},
 				{"c", "Copy output to clipboard"},
 				{"x", "Send output to session"},
+				{"X", "Revoke web share links"},
 			},
 		},
 		{
 			title: "WORKTREES",
 			items: [][2]string{
 				{"W", "Finish worktree (merge + cleanup)"},
+				{"W → d", "Review diff before merging"},
 				{"n → w", "Create session in worktree"},
 				{"F → w", "Fork session into worktree"},
 			},
@@ -157,6 +162,7 @@ func (h *HelpOverlay) View() string {
 			title: "OTHER",
 			items: [][2]string{
 				{"S", "Settings"},
+				{"H", "Federated hosts and profiles"},
 				{"Ctrl+R", "Reload from disk"},
 				{"i", "Import tmux sessions"},
 				{"Ctrl+Q", "Detach from session"},
@@ -10,6 +10,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -63,6 +64,12 @@ const (
 	// Prevents runaway log growth that can crash the system
 	logMaintenanceInterval = 5 * time.Minute
 
+	// contextCheckInterval - how often to evaluate automatic context-window policies
+	contextCheckInterval = 30 * time.Second
+
+	// contextNoticeTTL - how long a context-window action stays in the notification bar
+	contextNoticeTTL = 2 * time.Minute
+
 	// analyticsCacheTTL - how long analytics data remains valid before refresh
 	// Analytics don't change frequently, so 5s is a good balance between freshness and performance
 	analyticsCacheTTL = 5 * time.Second
@@ -100,9 +107,10 @@ const (
 type PreviewMode int
 
 const (
-	PreviewModeBoth      PreviewMode = iota // Show both analytics and output (default)
-	PreviewModeOutput                       // Show output only (content preview)
-	PreviewModeAnalytics                    // Show analytics only
+	PreviewModeBoth       PreviewMode = iota // Show both analytics and output (default)
+	PreviewModeOutput                        // Show output only (content preview)
+	PreviewModeAnalytics                     // Show analytics only
+	PreviewModeTranscript                    // Show the conversation transcript
 )
 
 // Responsive breakpoints for empty state content tiers
@@ -138,7 +146,7 @@ type Home struct {
 
 	// Components
 	search               *Search
-	globalSearch         *GlobalSearch              // Global session search across all Claude conversations
+	globalSearch         *GlobalSearch              // Global session search across all tools' conversations
 	globalSearchIndex    *session.GlobalSearchIndex // Search index (nil if disabled)
 	newDialog            *NewDialog
 	groupDialog          *GroupDialog          // For creating/renaming groups
@@ -163,6 +171,25 @@ type Home struct {
 	geminiAnalyticsCache   map[string]*session.GeminiSessionAnalytics // TTL cache: sessionID -> analytics (Gemini)
 	analyticsCacheTime     map[string]time.Time                       // TTL cache: sessionID -> cache timestamp
 
+	// Transcript preview mode and full-screen viewer
+	transcriptViewer     *TranscriptViewer
+	transcriptCache      map[string]*session.SessionTranscript // sessionID -> parsed transcript
+	transcriptCacheTime  map[string]time.Time                  // sessionID -> when last checked
+	transcriptFetchingID string                                // ID currently being parsed (prevents duplicates)
+
+	// Diff review of a session's changes against its base branch
+	diffViewer *DiffViewer
+
+	// Per-turn git checkpoints of the selected session
+	checkpointPicker *CheckpointPicker
+
+	// Sessions of every [federation] host and profile; upstreams are
+	// followed only while the view is open
+	federationView   *FederationView
+	federation       *web.Federation
+	federationCancel context.CancelFunc
+	federationGen    int // Ignores refreshes scheduled for an earlier opening
+
 	// State
 	cursor         int            // Selected item index in flatItems
 	viewOffset     int            // First visible item index (for scrolling)
@@ -216,10 +243,26 @@ type Home struct {
 	// Memory management: periodic cache pruning
 	lastCachePrune time.Time
 
+	// Automatic context-window management (compact/fork idle sessions near the limit)
+	contextManager   *session.ContextManager
+	lastContextCheck time.Time
+	contextChecking  bool // True while a Check is in flight (main goroutine only)
+
+	// Active web share links per session ID (main goroutine only)
+	shares         map[string]*sessionShares
+	lastShareCheck time.Time
+
 	// Hook-based status detection (Claude Code lifecycle hooks)
 	hookWatcher        *session.StatusFileWatcher
 	pendingHooksPrompt bool // True if user should be prompted to install hooks
 
+	// Live git status of every session's working directory
+	gitStatus        *session.GitStatusCollector
+	gitStatusChanged atomic.Bool // Set by the collector; republishes web state on the next tick
+
+	// Per-turn git checkpoints (opt-in via [checkpoints])
+	checkpointer *session.Checkpointer
+
 	// File watcher for external changes (auto-reload)
 	storageWatcher *StorageWatcher
 
@@ -410,6 +453,72 @@ type analyticsFetchedMsg struct {
 	err             error
 }
 
+// transcriptFetchedMsg is sent when async transcript parsing is complete.
+// Unchanged is set when the transcript file has not changed since the cached copy.
+type transcriptFetchedMsg struct {
+	sessionID  string
+	transcript *session.SessionTranscript
+	unchanged  bool
+	err        error
+}
+
+// diffFetchedMsg is sent when a session's diff against its base is collected
+type diffFetchedMsg struct {
+	sessionID string
+	diff      *git.Diff
+	err       error
+}
+
+// diffActionMsg is sent when a revert or commit from the diff viewer finishes
+type diffActionMsg struct {
+	sessionID string
+	status    string
+	err       error
+}
+
+// worktreeSetupProgressMsg carries one line of worktree setup progress for
+// the banner; progress is re-listened until the setup closes it
+type worktreeSetupProgressMsg struct {
+	progress <-chan string
+	branch   string
+	line     string
+}
+
+// checkpointsLoadedMsg delivers a session's checkpoint list to the picker
+type checkpointsLoadedMsg struct {
+	sessionID   string
+	checkpoints []git.Checkpoint
+	err         error
+}
+
+// checkpointStatMsg delivers the diffstat of a checkpoint against the work tree
+type checkpointStatMsg struct {
+	sessionID string
+	n         int
+	stat      string
+	err       error
+}
+
+// checkpointRestoredMsg is sent when a checkpoint restore finishes
+type checkpointRestoredMsg struct {
+	sessionID string
+	status    string
+	err       error
+}
+
+// federationLoadedMsg delivers the merged menu of the [federation] sources
+type federationLoadedMsg struct {
+	gen      int
+	snapshot *web.MenuSnapshot
+	err      error
+}
+
+// federationOpenedMsg reports opening a session from the federation view
+type federationOpenedMsg struct {
+	status string
+	err    error
+}
+
 // MaintenanceCompleteMsg is the exported type for sending from main.go via p.Send()
 type MaintenanceCompleteMsg struct {
 	Result session.MaintenanceResult
@@ -423,6 +532,11 @@ type maintenanceCompleteMsg struct {
 // clearMaintenanceMsg signals auto-clear of maintenance banner
 type clearMaintenanceMsg struct{}
 
+// contextCheckMsg carries the actions taken by a context-window check
+type contextCheckMsg struct {
+	results []session.ContextActionResult
+}
+
 // copyResultMsg is sent when async clipboard copy completes
 type copyResultMsg struct {
 	sessionTitle string
@@ -456,6 +570,7 @@ type worktreeFinishResultMsg struct {
 	sessionTitle string
 	targetBranch string
 	merged       bool
+	warning      error // post_finish hook failure; the finish itself succeeded
 	err          error
 }
 
@@ -535,8 +650,15 @@ func NewHomeWithProfileAndMode(profile string) *Home {
 		previewCache:         make(map[string]string),
 		previewCacheTime:     make(map[string]time.Time),
 		analyticsCache:       make(map[string]*session.SessionAnalytics),
+		contextManager:       session.NewContextManager(),
 		geminiAnalyticsCache: make(map[string]*session.GeminiSessionAnalytics),
 		analyticsCacheTime:   make(map[string]time.Time),
+		transcriptViewer:     NewTranscriptViewer(),
+		diffViewer:           NewDiffViewer(),
+		checkpointPicker:     NewCheckpointPicker(),
+		federationView:       NewFederationView(),
+		transcriptCache:      make(map[string]*session.SessionTranscript),
+		transcriptCacheTime:  make(map[string]time.Time),
 		launchingSessions:    make(map[string]time.Time),
 		resumingSessions:     make(map[string]time.Time),
 		mcpLoadingSessions:   make(map[string]time.Time),
@@ -627,21 +749,19 @@ func NewHomeWithProfileAndMode(profile string) *Home {
 	h.startLogWorkers()
 
 	// Initialize global search
-	// DISABLED: Global search opens 884+ directory watchers and loads 4.4 GB of JSONL
-	// content into memory, causing agent-deck to balloon to 6+ GB and get OOM-killed.
-	// TODO: Fix by limiting watched dirs and enforcing balanced tier for large datasets.
+	// Only project-level directories are watched, and datasets too large for memory
+	// use the persistent FTS5 index (~/.agent-deck/search.db) instead of loading
+	// transcript content, so startup cost no longer scales with history size.
 	h.globalSearch = NewGlobalSearch()
-	// claudeDir := session.GetClaudeConfigDir()
-	// userConfig, _ := session.LoadUserConfig()
-	// if userConfig != nil && userConfig.GlobalSearch.Enabled {
-	// 	globalSearchIndex, err := session.NewGlobalSearchIndex(claudeDir, userConfig.GlobalSearch)
-	// 	if err != nil {
-	// 		uiLog.Warn("global_search_init_failed", slog.String("error", err.Error()))
-	// 	} else {
-	// 		h.globalSearchIndex = globalSearchIndex
-	// 		h.globalSearch.SetIndex(globalSearchIndex)
-	// 	}
-	// }
+	if searchConfig, _ := session.LoadUserConfig(); searchConfig != nil && searchConfig.GlobalSearch.Enabled {
+		globalSearchIndex, err := session.NewGlobalSearchIndex(session.GetClaudeConfigDir(), searchConfig.GlobalSearch)
+		if err != nil {
+			uiLog.Warn("global_search_init_failed", slog.String("error", err.Error()))
+		} else if globalSearchIndex != nil {
+			h.globalSearchIndex = globalSearchIndex
+			h.globalSearch.SetIndex(globalSearchIndex)
+		}
+	}
 
 	// Initialize MCP socket pool if enabled
 	// Note: Pool initialization happens AFTER loading sessions so we can discover MCPs in use
@@ -706,6 +826,14 @@ func NewHomeWithProfileAndMode(profile string) *Home {
 		}
 	}
 
+	// Live git status per session (list badge, preview, web snapshot)
+	h.gitStatus = session.NewGitStatusCollector(func() { h.gitStatusChanged.Store(true) })
+	h.gitStatus.Start()
+
+	// Snapshot work trees at each turn end when [checkpoints] is enabled
+	h.checkpointer = session.NewCheckpointer(session.GetCheckpointSettings())
+	h.checkpointer.Start()
+
 	// Start system theme watcher if configured
 	if session.GetTheme() == "system" {
 		h.themeWatcher = NewThemeWatcher(ctx)
@@ -780,9 +908,16 @@ func (h *Home) publishWebSessionStates(instances []*session.Instance) {
 		if inst == nil {
 			continue
 		}
+		status := inst.GetStatusThreadSafe()
+		var waitingSince time.Time
+		if status == session.StatusWaiting {
+			waitingSince = inst.GetWaitingSince()
+		}
 		states[inst.ID] = web.MenuSessionState{
-			Status: inst.GetStatusThreadSafe(),
-			Tool:   inst.GetToolThreadSafe(),
+			Status:       status,
+			Tool:         inst.GetToolThreadSafe(),
+			Git:          inst.GetGitStatus(),
+			WaitingSince: waitingSince,
 		}
 	}
 	menuData.UpdateSessionStates(states, time.Now())
@@ -1063,6 +1198,21 @@ func (h *Home) syncViewport() {
 // All notification sync is now handled by syncNotificationsBackground() which runs
 // every 2s in the background worker, including during tea.Exec pauses.
 
+// checkContextWindows runs a context-window check in the background.
+// Sessions over their policy threshold are compacted or forked; the attached
+// session is skipped so the user is never interrupted mid-conversation.
+func (h *Home) checkContextWindows() tea.Cmd {
+	h.instancesMu.RLock()
+	instances := make([]*session.Instance, len(h.instances))
+	copy(instances, h.instances)
+	h.instancesMu.RUnlock()
+
+	manager := h.contextManager
+	return func() tea.Msg {
+		return contextCheckMsg{results: manager.Check(instances, h.getAttachedSessionID())}
+	}
+}
+
 // getAttachedSessionID returns the instance ID of the currently attached agentdeck session.
 // This detects which session the user is viewing, even if they switched via tmux directly.
 func (h *Home) getAttachedSessionID() string {
@@ -1215,6 +1365,42 @@ func listenForReloads(sw *StorageWatcher) tea.Cmd {
 	}
 }
 
+// listenForWorktreeSetup waits for the next line of worktree setup progress.
+// When the setup finishes (channel closed) the banner is cleared.
+func listenForWorktreeSetup(progress <-chan string, branch string) tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-progress
+		if !ok {
+			return clearMaintenanceMsg{}
+		}
+		return worktreeSetupProgressMsg{progress: progress, branch: branch, line: line}
+	}
+}
+
+// withWorktreeSetup prepares a just-created worktree (copy, symlink and
+// post_create hooks) in the background, showing progress in the banner, and
+// then runs next. If setup fails, the worktree is removed again (with its
+// branch when it was created for it) and onErr's message is delivered
+// instead, so no half-created session is left behind.
+func (h *Home) withWorktreeSetup(hooks session.WorktreeHookSettings, env session.WorktreeHookEnv, branchCreated bool, next tea.Cmd, onErr func(error) tea.Msg) tea.Cmd {
+	progress := make(chan string, 32)
+	setup := func() tea.Msg {
+		defer close(progress)
+		err := session.SetupWorktree(hooks, env, func(line string) {
+			select {
+			case progress <- line:
+			default: // The banner only shows the latest line; dropping some is fine
+			}
+		})
+		if err != nil {
+			session.DiscardWorktree(env.RepoRoot, env.WorktreePath, env.Branch, branchCreated)
+			return onErr(fmt.Errorf("worktree setup failed: %w", err))
+		}
+		return next()
+	}
+	return tea.Batch(listenForWorktreeSetup(progress, env.Branch), setup)
+}
+
 // listenForThemeChange waits for the next OS theme change.
 // MUST be re-issued in the Update handler for systemThemeMsg to keep listening.
 func listenForThemeChange(tw *ThemeWatcher) tea.Cmd {
@@ -1306,6 +1492,13 @@ func (h *Home) pruneAnalyticsCache() {
 		}
 	}
 
+	for id, t := range h.transcriptCacheTime {
+		if now.Sub(t) > maxAge && id != h.transcriptViewer.SessionID() {
+			delete(h.transcriptCache, id)
+			delete(h.transcriptCacheTime, id)
+		}
+	}
+
 	h.logActivityMu.Lock()
 	for id, t := range h.lastLogActivity {
 		if now.Sub(t) > maxAge {
@@ -1539,24 +1732,13 @@ func (h *Home) fetchAnalytics(inst *session.Instance) tea.Cmd {
 	sessionID := inst.ID
 
 	fetchTool := inst.GetToolThreadSafe()
-	if fetchTool == "claude" {
-		claudeSessionID := inst.ClaudeSessionID
+	if fetchTool == "claude" || fetchTool == "codex" || fetchTool == "opencode" {
+		toolSessionID := inst.ToolSessionID()
 		return func() tea.Msg {
-			// Get JSONL path for this session
-			jsonlPath := inst.GetJSONLPath()
-			if jsonlPath == "" {
-				// No JSONL path available - return empty analytics
-				return analyticsFetchedMsg{
-					sessionID: sessionID,
-					analytics: nil,
-					err:       nil,
-				}
-			}
-
-			// Parse the JSONL file
-			analytics, err := session.ParseSessionJSONL(jsonlPath)
+			// Parse the tool's transcript (nil if none is available yet)
+			analytics, err := session.GetSessionAnalytics(inst)
 			if err != nil {
-				uiLog.Warn("analytics_parse_failed", slog.String("session_id", sessionID), slog.String("claude_session_id", claudeSessionID), slog.String("error", err.Error()))
+				uiLog.Warn("analytics_parse_failed", slog.String("session_id", sessionID), slog.String("tool", fetchTool), slog.String("tool_session_id", toolSessionID), slog.String("error", err.Error()))
 				return analyticsFetchedMsg{
 					sessionID: sessionID,
 					analytics: nil,
@@ -1585,6 +1767,49 @@ func (h *Home) fetchAnalytics(inst *session.Instance) tea.Cmd {
 	return nil
 }
 
+// transcriptStale reports whether a session's cached transcript should be re-checked
+func (h *Home) transcriptStale(sessionID string) bool {
+	t, ok := h.transcriptCacheTime[sessionID]
+	return (!ok || time.Since(t) > analyticsCacheTTL) && h.transcriptFetchingID != sessionID
+}
+
+// fetchTranscript returns a command that parses a session's transcript in the
+// background. Parsing is skipped when the file is unchanged since the cached copy.
+func (h *Home) fetchTranscript(inst *session.Instance) tea.Cmd {
+	if inst == nil {
+		return nil
+	}
+	sessionID := inst.ID
+	var cachedMod time.Time
+	if cached := h.transcriptCache[sessionID]; cached != nil {
+		cachedMod = cached.ModTime
+	}
+	h.transcriptFetchingID = sessionID
+	return func() tea.Msg {
+		if !cachedMod.IsZero() {
+			if path := session.TranscriptPath(inst); path != "" {
+				if info, err := os.Stat(path); err == nil && info.ModTime().Equal(cachedMod) {
+					return transcriptFetchedMsg{sessionID: sessionID, unchanged: true}
+				}
+			}
+		}
+		t, err := session.LoadSessionTranscript(inst)
+		if err != nil {
+			uiLog.Warn("transcript_parse_failed", slog.String("session_id", sessionID), slog.String("error", err.Error()))
+		}
+		return transcriptFetchedMsg{sessionID: sessionID, transcript: t, err: err}
+	}
+}
+
+// hasAnalytics reports whether agent-deck can parse usage analytics for a tool
+func hasAnalytics(tool string) bool {
+	switch tool {
+	case "claude", "codex", "gemini", "opencode":
+		return true
+	}
+	return false
+}
+
 // getSelectedSession returns the currently selected session, or nil if a group is selected
 func (h *Home) getSelectedSession() *session.Instance {
 	if len(h.flatItems) == 0 || h.cursor >= len(h.flatItems) {
@@ -1737,12 +1962,22 @@ func (h *Home) backgroundStatusUpdate() {
 		}
 	}
 
+	// Attach cached git statuses and queue refreshes for stale directories
+	if h.gitStatus != nil {
+		h.gitStatus.Track(instances)
+	}
+
 	// Feed hook statuses from watcher to instances (enables hook fast path in UpdateStatus)
 	if h.hookWatcher != nil {
 		for _, inst := range instances {
 			if inst.Tool == "claude" || inst.Tool == "codex" {
 				if hs := h.hookWatcher.GetHookStatus(inst.ID); hs != nil {
 					inst.UpdateHookStatus(hs)
+					// A finished turn (Stop) likely changed files
+					if h.gitStatus != nil {
+						h.gitStatus.NoteHookStatus(inst.ID, hs)
+					}
+					h.checkpointer.NoteHookStatus(inst, hs)
 				}
 			}
 		}
@@ -1790,6 +2025,8 @@ func (h *Home) backgroundStatusUpdate() {
 			if newStatus != oldStatus {
 				statusChanged.Store(true)
 				notifLog.Debug("status_changed", slog.String("title", inst.Title), slog.String("old", string(oldStatus)), slog.String("new", string(newStatus)))
+				h.checkpointer.NoteStatusChange(inst, oldStatus, newStatus)
+				session.FireStatusChangedHook(inst, oldStatus)
 			}
 			return nil
 		})
@@ -1812,6 +2049,8 @@ func (h *Home) backgroundStatusUpdate() {
 	// Invalidate cache if status changed
 	if statusChanged.Load() {
 		h.cachedStatusCounts.valid.Store(false)
+	}
+	if gitChanged := h.gitStatusChanged.Swap(false); statusChanged.Load() || gitChanged {
 		h.publishWebSessionStates(instances)
 	}
 
@@ -2086,6 +2325,7 @@ func (h *Home) processStatusUpdate(req statusUpdateRequest) {
 			_ = inst.UpdateStatus() // Ignore errors in background worker
 			if inst.GetStatusThreadSafe() != oldStatus {
 				statusChanged = true
+				session.FireStatusChangedHook(inst, oldStatus)
 			}
 			updated[inst.ID] = true
 		}
@@ -2117,6 +2357,7 @@ func (h *Home) processStatusUpdate(req statusUpdateRequest) {
 		_ = inst.UpdateStatus() // Ignore errors in background worker
 		if inst.GetStatusThreadSafe() != oldStatus {
 			statusChanged = true
+			session.FireStatusChangedHook(inst, oldStatus)
 		}
 		remaining--
 		h.statusUpdateIndex.Store(int32((idx + 1) % instanceCount))
@@ -2147,6 +2388,10 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		h.setupWizard.SetSize(msg.Width, msg.Height)
 		h.settingsPanel.SetSize(msg.Width, msg.Height)
 		h.geminiModelDialog.SetSize(msg.Width, msg.Height)
+		h.transcriptViewer.SetSize(msg.Width, msg.Height)
+		h.diffViewer.SetSize(msg.Width, msg.Height)
+		h.checkpointPicker.SetSize(msg.Width, msg.Height)
+		h.federationView.SetSize(msg.Width, msg.Height)
 		return h, nil
 
 	case loadSessionsMsg:
@@ -2341,6 +2586,7 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			h.instancesMu.Unlock()
 			// Force save to persist the session even during reload
 			h.forceSaveInstances()
+			session.FireSessionHook(session.HookSessionCreated, msg.instance, nil)
 			// Trigger another reload to pick up the new session in the UI
 			if h.storageWatcher != nil {
 				h.storageWatcher.TriggerReload()
@@ -2384,6 +2630,7 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Save both instances AND groups (critical fix: was losing groups!)
 			// Use forceSave to bypass mtime check - new session creation MUST persist
 			h.forceSaveInstances()
+			session.FireSessionHook(session.HookSessionCreated, msg.instance, nil)
 
 			// Start fetching preview for the new session
 			return h, h.fetchPreview(msg.instance)
@@ -2407,6 +2654,7 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			h.instances = append(h.instances, msg.instance)
 			h.instancesMu.Unlock()
 			h.forceSaveInstances()
+			session.FireForkHooks(msg.instance, msg.sourceID)
 			if h.storageWatcher != nil {
 				h.storageWatcher.TriggerReload()
 			}
@@ -2451,6 +2699,7 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Save both instances AND groups
 			// Use forceSave to bypass mtime check - forked session MUST persist
 			h.forceSaveInstances()
+			session.FireForkHooks(msg.instance, msg.sourceID)
 
 			// Start fetching preview for the forked session
 			return h, h.fetchPreview(msg.instance)
@@ -2498,6 +2747,8 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		delete(h.analyticsCache, msg.deletedID)
 		delete(h.geminiAnalyticsCache, msg.deletedID)
 		delete(h.analyticsCacheTime, msg.deletedID)
+		delete(h.transcriptCache, msg.deletedID)
+		delete(h.transcriptCacheTime, msg.deletedID)
 		h.logActivityMu.Lock()
 		delete(h.lastLogActivity, msg.deletedID)
 		h.logActivityMu.Unlock()
@@ -2515,6 +2766,9 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Save both instances AND groups (critical fix: was losing groups!)
 		// Use forceSave to bypass mtime check - delete MUST persist
 		h.forceSaveInstances()
+		if deletedInstance != nil {
+			session.FireSessionHook(session.HookSessionDeleted, deletedInstance, nil)
+		}
 
 		// Show undo hint (using setError as a transient message)
 		if deletedInstance != nil {
@@ -2654,6 +2908,9 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if r.PrunedBackups > 0 {
 			parts = append(parts, fmt.Sprintf("%d backups cleaned", r.PrunedBackups))
 		}
+		if r.ProfileBackups > 0 {
+			parts = append(parts, fmt.Sprintf("%d profiles backed up", r.ProfileBackups))
+		}
 		if r.ArchivedSessions > 0 {
 			parts = append(parts, fmt.Sprintf("%d sessions archived", r.ArchivedSessions))
 		}
@@ -2671,6 +2928,42 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		h.maintenanceMsg = ""
 		return h, nil
 
+	case worktreeSetupProgressMsg:
+		h.maintenanceMsg = fmt.Sprintf("Setting up worktree %s: %s", msg.branch, msg.line)
+		h.maintenanceMsgTime = time.Now()
+		return h, listenForWorktreeSetup(msg.progress, msg.branch)
+
+	case contextCheckMsg:
+		h.contextChecking = false
+		if len(msg.results) == 0 {
+			return h, nil
+		}
+		var parts []string
+		var cmds []tea.Cmd
+		for _, r := range msg.results {
+			if h.notificationsEnabled && h.notificationManager != nil {
+				h.notificationManager.Notify(r.Instance.ID, r.Instance.Title, r.Describe(), contextNoticeTTL)
+			}
+			switch {
+			case r.Err != nil:
+				parts = append(parts, fmt.Sprintf("%s %s failed (%v)", r.Action, r.Instance.Title, r.Err))
+			case r.Forked != nil:
+				parts = append(parts, fmt.Sprintf("forked %s (%.0f%%)", r.Instance.Title, r.Percent))
+				forked := r.Forked
+				cmds = append(cmds, func() tea.Msg {
+					return sessionCreatedMsg{instance: forked}
+				})
+			default:
+				parts = append(parts, fmt.Sprintf("compacted %s (%.0f%%)", r.Instance.Title, r.Percent))
+			}
+		}
+		h.maintenanceMsg = "Context: " + strings.Join(parts, ", ")
+		h.maintenanceMsgTime = time.Now()
+		cmds = append(cmds, tea.Tick(30*time.Second, func(_ time.Time) tea.Msg {
+			return clearMaintenanceMsg{}
+		}))
+		return h, tea.Batch(cmds...)
+
 	case modelsFetchedMsg:
 		if h.geminiModelDialog != nil && h.geminiModelDialog.IsVisible() {
 			h.geminiModelDialog.HandleModelsFetched(msg)
@@ -2828,11 +3121,17 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmds = append(cmds, h.fetchPreview(inst))
 			}
 
-			// Analytics fetch (for Claude/Gemini sessions with analytics enabled)
+			// Transcript fetch (only while the preview shows it)
+			if h.previewMode == PreviewModeTranscript && h.transcriptStale(inst.ID) {
+				cmds = append(cmds, h.fetchTranscript(inst))
+			}
+
+			// Analytics fetch (for Claude/Codex/OpenCode/Gemini sessions with analytics enabled)
 			// Use TTL cache - only fetch if cache miss/expired and not already fetching
 			tickTool := inst.GetToolThreadSafe()
-			if (tickTool == "claude" || tickTool == "gemini") && h.analyticsFetchingID != inst.ID {
-				if tickTool == "claude" {
+			transcriptAnalytics := tickTool == "claude" || tickTool == "codex" || tickTool == "opencode"
+			if (transcriptAnalytics || tickTool == "gemini") && h.analyticsFetchingID != inst.ID {
+				if transcriptAnalytics {
 					cached := h.getAnalyticsForSession(inst)
 					if cached != nil {
 						// Use cached analytics
@@ -2878,6 +3177,11 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
+			// Fresh git status for the session the user is looking at
+			if h.gitStatus != nil {
+				h.gitStatus.Refresh(inst.ID)
+			}
+
 			// Worktree dirty status check (lazy, 10s TTL)
 			if inst.IsWorktree() && inst.WorktreePath != "" {
 				h.worktreeDirtyMu.Lock()
@@ -2915,6 +3219,19 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		h.previewCacheMu.Unlock()
 		return h, nil
 
+	case transcriptFetchedMsg:
+		if h.transcriptFetchingID == msg.sessionID {
+			h.transcriptFetchingID = ""
+		}
+		h.transcriptCacheTime[msg.sessionID] = time.Now()
+		if !msg.unchanged {
+			if msg.err == nil {
+				h.transcriptCache[msg.sessionID] = msg.transcript
+			}
+			h.transcriptViewer.SetTranscript(msg.sessionID, msg.transcript, msg.err)
+		}
+		return h, nil
+
 	case analyticsFetchedMsg:
 		// Async analytics parsing complete - update TTL cache
 		h.analyticsFetchingID = ""
@@ -2951,6 +3268,49 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return h, nil
 
+	case diffFetchedMsg:
+		h.diffViewer.SetDiff(msg.sessionID, msg.diff, msg.err)
+		return h, nil
+
+	case diffActionMsg:
+		h.diffViewer.SetResult(msg.sessionID, msg.status, msg.err)
+		if h.gitStatus != nil {
+			h.gitStatus.Refresh(msg.sessionID)
+		}
+		if h.diffViewer.IsVisible() && h.diffViewer.SessionID() == msg.sessionID {
+			return h, h.fetchDiff(msg.sessionID, h.diffViewer.Dir(), h.diffViewer.Base())
+		}
+		return h, nil
+
+	case checkpointsLoadedMsg:
+		h.checkpointPicker.SetCheckpoints(msg.sessionID, msg.checkpoints, msg.err)
+		return h, h.fetchCheckpointStat()
+
+	case checkpointStatMsg:
+		h.checkpointPicker.SetStat(msg.sessionID, msg.n, msg.stat, msg.err)
+		return h, nil
+
+	case checkpointRestoredMsg:
+		h.checkpointPicker.SetResult(msg.sessionID, msg.status, msg.err)
+		if h.gitStatus != nil {
+			h.gitStatus.Refresh(msg.sessionID)
+		}
+		if h.checkpointPicker.IsVisible() && h.checkpointPicker.SessionID() == msg.sessionID {
+			return h, h.fetchCheckpoints(msg.sessionID, h.checkpointPicker.Dir())
+		}
+		return h, nil
+
+	case federationLoadedMsg:
+		if msg.gen != h.federationGen || !h.federationView.IsVisible() {
+			return h, nil
+		}
+		h.federationView.SetSnapshot(msg.snapshot, msg.err)
+		return h, h.loadFederation(msg.gen, federationRefreshInterval)
+
+	case federationOpenedMsg:
+		h.federationView.SetStatus(msg.status, msg.err)
+		return h, nil
+
 	case worktreeDirtyCheckMsg:
 		// Update worktree dirty status cache
 		if msg.err == nil {
@@ -2996,6 +3356,8 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		delete(h.analyticsCache, msg.sessionID)
 		delete(h.geminiAnalyticsCache, msg.sessionID)
 		delete(h.analyticsCacheTime, msg.sessionID)
+		delete(h.transcriptCache, msg.sessionID)
+		delete(h.transcriptCacheTime, msg.sessionID)
 		h.worktreeDirtyMu.Lock()
 		delete(h.worktreeDirtyCache, msg.sessionID)
 		delete(h.worktreeDirtyCacheTs, msg.sessionID)
@@ -3016,12 +3378,23 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			uiLog.Warn("worktree_finish_delete_err", slog.String("id", msg.sessionID), slog.String("err", err.Error()))
 		}
 		h.forceSaveInstances()
+		if inst != nil {
+			session.FireSessionHook(session.HookWorktreeFinished, inst, map[string]string{
+				"branch":        inst.WorktreeBranch,
+				"target_branch": msg.targetBranch,
+				"merged":        strconv.FormatBool(msg.merged),
+			})
+			session.FireSessionHook(session.HookSessionDeleted, inst, nil)
+		}
 
 		// Show success message
 		successMsg := fmt.Sprintf("Finished worktree '%s'", msg.sessionTitle)
 		if msg.merged {
 			successMsg += fmt.Sprintf(", merged into %s", msg.targetBranch)
 		}
+		if msg.warning != nil {
+			successMsg += fmt.Sprintf(" (warning: %v)", msg.warning)
+		}
 		h.setError(fmt.Errorf("%s", successMsg))
 		return h, nil
 
@@ -3112,6 +3485,21 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+		// Automatic context-window management every 30 seconds
+		var contextCmd tea.Cmd
+		if h.contextManager != nil && !h.contextChecking && time.Since(h.lastContextCheck) >= contextCheckInterval {
+			h.lastContextCheck = time.Now()
+			h.contextChecking = true
+			contextCmd = h.checkContextWindows()
+		}
+
+		// Share links and their viewers, for the preview's Sharing section
+		var shareCmd tea.Cmd
+		if time.Since(h.lastShareCheck) >= shareCheckInterval {
+			h.lastShareCheck = time.Now()
+			shareCmd = h.loadShares()
+		}
+
 		// Full log maintenance (orphan cleanup, etc) every 5 minutes
 		if time.Since(h.lastLogMaintenance) >= logMaintenanceInterval {
 			h.lastLogMaintenance = time.Now()
@@ -3155,7 +3543,32 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			h.previewCacheMu.Unlock()
 		}
-		return h, tea.Batch(h.tick(), previewCmd)
+
+		// Keep the transcript live while it is on screen
+		var transcriptCmd tea.Cmd
+		if h.transcriptViewer.IsVisible() {
+			h.instancesMu.RLock()
+			viewed := h.instanceByID[h.transcriptViewer.SessionID()]
+			h.instancesMu.RUnlock()
+			if viewed != nil && h.transcriptStale(viewed.ID) {
+				transcriptCmd = h.fetchTranscript(viewed)
+			}
+		} else if selected != nil && h.previewMode == PreviewModeTranscript && h.transcriptStale(selected.ID) {
+			transcriptCmd = h.fetchTranscript(selected)
+		}
+		return h, tea.Batch(h.tick(), previewCmd, contextCmd, transcriptCmd, shareCmd)
+
+	case sharesLoadedMsg:
+		h.shares = msg.shares
+		return h, nil
+
+	case sharesRevokedMsg:
+		if msg.err != nil {
+			h.setError(fmt.Errorf("failed to revoke share links: %w", msg.err))
+			return h, nil
+		}
+		delete(h.shares, msg.sessionID)
+		return h, h.loadShares()
 
 	case globalSearchDebounceMsg, globalSearchResultsMsg:
 		// Route async global search messages to the global search component
@@ -3234,6 +3647,18 @@ func (h *Home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			h.helpOverlay, _ = h.helpOverlay.Update(msg)
 			return h, nil
 		}
+		if h.transcriptViewer.IsVisible() {
+			return h.handleTranscriptViewerKey(msg)
+		}
+		if h.diffViewer.IsVisible() {
+			return h.handleDiffViewerKey(msg)
+		}
+		if h.checkpointPicker.IsVisible() {
+			return h.handleCheckpointPickerKey(msg)
+		}
+		if h.federationView.IsVisible() {
+			return h.handleFederationViewKey(msg)
+		}
 		if h.search.IsVisible() {
 			return h.handleSearchKey(msg)
 		}
@@ -3318,6 +3743,253 @@ func (h *Home) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 // handleGlobalSearchKey handles keys when global search is visible
+// handleTranscriptViewerKey handles keys while the transcript viewer is open
+func (h *Home) handleTranscriptViewerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	h.transcriptViewer, cmd = h.transcriptViewer.Update(msg)
+	if h.transcriptViewer.WantsReload() {
+		h.instancesMu.RLock()
+		inst := h.instanceByID[h.transcriptViewer.SessionID()]
+		h.instancesMu.RUnlock()
+		if inst != nil && h.transcriptFetchingID != inst.ID {
+			delete(h.transcriptCache, inst.ID) // Force a full re-parse
+			return h, tea.Batch(cmd, h.fetchTranscript(inst))
+		}
+	}
+	return h, cmd
+}
+
+// openDiffViewer shows the diff review for a session's work tree at dir
+// against base ("" for the default branch) and starts collecting it.
+func (h *Home) openDiffViewer(inst *session.Instance, dir, base string) tea.Cmd {
+	h.diffViewer.SetSize(h.width, h.height)
+	h.diffViewer.Show(inst.ID, inst.Title, dir, base)
+	return h.fetchDiff(inst.ID, dir, base)
+}
+
+// fetchDiff collects a work tree's diff against its merge base in the background
+func (h *Home) fetchDiff(sessionID, dir, base string) tea.Cmd {
+	return func() tea.Msg {
+		d, err := git.GetDiff(dir, base)
+		return diffFetchedMsg{sessionID: sessionID, diff: d, err: err}
+	}
+}
+
+// handleDiffViewerKey handles keys while the diff viewer is open
+func (h *Home) handleDiffViewerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	h.diffViewer, cmd = h.diffViewer.Update(msg)
+	sid := h.diffViewer.SessionID()
+
+	if !h.diffViewer.IsVisible() {
+		// Back to the finish dialog (if that's where the review started)
+		// with a fresh dirty check, since the review may have committed
+		if h.worktreeFinishDialog.IsVisible() && h.worktreeFinishDialog.GetSessionID() == sid {
+			wtPath := h.worktreeFinishDialog.worktreePath
+			return h, func() tea.Msg {
+				dirty, err := git.HasUncommittedChanges(wtPath)
+				return worktreeDirtyCheckMsg{sessionID: sid, isDirty: dirty, err: err}
+			}
+		}
+		return h, cmd
+	}
+	if h.diffViewer.WantsReload() {
+		return h, tea.Batch(cmd, h.fetchDiff(sid, h.diffViewer.Dir(), h.diffViewer.Base()))
+	}
+	if action := h.diffViewer.TakeAction(); action != nil {
+		return h, tea.Batch(cmd, h.runDiffAction(sid, action))
+	}
+	return h, cmd
+}
+
+// runDiffAction applies a revert or commit from the diff viewer
+func (h *Home) runDiffAction(sessionID string, a *DiffAction) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		var status string
+		switch a.Kind {
+		case DiffActionRevertFile:
+			err = git.RevertFile(a.Dir, a.MergeBase, a.File)
+			status = "Reverted " + a.File.Path
+		case DiffActionRevertHunk:
+			err = git.RevertHunk(a.Dir, a.File, a.Hunk)
+			status = fmt.Sprintf("Reverted hunk %d of %s", a.Hunk+1, a.File.Path)
+		case DiffActionCommit:
+			err = git.CommitAll(a.Dir, a.Message)
+			status = "Committed: " + a.Message
+		}
+		if err != nil {
+			uiLog.Warn("diff_action_failed", slog.String("session_id", sessionID), slog.String("error", err.Error()))
+		}
+		return diffActionMsg{sessionID: sessionID, status: status, err: err}
+	}
+}
+
+// fetchCheckpoints lists a session's checkpoints in the background
+func (h *Home) fetchCheckpoints(sessionID, dir string) tea.Cmd {
+	return func() tea.Msg {
+		root, err := git.GetRepoRoot(dir)
+		if err != nil {
+			return checkpointsLoadedMsg{sessionID: sessionID, err: err}
+		}
+		list, err := git.ListCheckpoints(root, sessionID)
+		return checkpointsLoadedMsg{sessionID: sessionID, checkpoints: list, err: err}
+	}
+}
+
+// fetchCheckpointStat collects the diffstat of the picker's selected
+// checkpoint, unless it has been requested already
+func (h *Home) fetchCheckpointStat() tea.Cmd {
+	n, ok := h.checkpointPicker.NeedsStat()
+	if !ok {
+		return nil
+	}
+	sid, dir := h.checkpointPicker.SessionID(), h.checkpointPicker.Dir()
+	return func() tea.Msg {
+		stat, err := git.CheckpointDiff(dir, sid, n, true)
+		return checkpointStatMsg{sessionID: sid, n: n, stat: stat, err: err}
+	}
+}
+
+// handleCheckpointPickerKey handles keys while the checkpoint picker is open
+func (h *Home) handleCheckpointPickerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	h.checkpointPicker, cmd = h.checkpointPicker.Update(msg)
+	if !h.checkpointPicker.IsVisible() {
+		return h, cmd
+	}
+	sid, dir := h.checkpointPicker.SessionID(), h.checkpointPicker.Dir()
+	if h.checkpointPicker.WantsReload() {
+		return h, tea.Batch(cmd, h.fetchCheckpoints(sid, dir))
+	}
+	if n := h.checkpointPicker.TakeRestore(); n > 0 {
+		return h, tea.Batch(cmd, func() tea.Msg {
+			safety, err := git.RestoreCheckpoint(dir, sid, n)
+			if err != nil {
+				uiLog.Warn("checkpoint_restore_failed", slog.String("session_id", sid), slog.Int("n", n), slog.String("error", err.Error()))
+				return checkpointRestoredMsg{sessionID: sid, err: err}
+			}
+			status := fmt.Sprintf("Restored checkpoint #%d", n)
+			if safety != nil {
+				status += fmt.Sprintf(" (previous state saved as #%d)", safety.N)
+			}
+			return checkpointRestoredMsg{sessionID: sid, status: status}
+		})
+	}
+	return h, tea.Batch(cmd, h.fetchCheckpointStat())
+}
+
+// openFederationView builds the [federation] sources on first use, starts
+// following the upstreams and opens the federation view
+func (h *Home) openFederationView() tea.Cmd {
+	if h.federation == nil {
+		fed, err := web.FederationFromSettings(h.profile, web.NewSessionDataService(h.profile))
+		if err != nil {
+			h.setError(fmt.Errorf("invalid [federation] config: %w", err))
+			return nil
+		}
+		if fed == nil {
+			h.setError(fmt.Errorf("no [federation] profiles or upstreams configured"))
+			return nil
+		}
+		h.federation = fed
+	}
+	ctx, cancel := context.WithCancel(h.ctx)
+	h.federationCancel = cancel
+	h.federation.Start(ctx, nil)
+	h.federationGen++
+	h.federationView.SetSize(h.width, h.height)
+	h.federationView.Show()
+	return h.loadFederation(h.federationGen, 0)
+}
+
+// loadFederation loads the merged federated menu after delay
+func (h *Home) loadFederation(gen int, delay time.Duration) tea.Cmd {
+	fed := h.federation
+	load := func(time.Time) tea.Msg {
+		snapshot, err := fed.LoadMenuSnapshot()
+		return federationLoadedMsg{gen: gen, snapshot: snapshot, err: err}
+	}
+	if delay == 0 {
+		return func() tea.Msg { return load(time.Now()) }
+	}
+	return tea.Tick(delay, load)
+}
+
+// handleFederationViewKey handles keys while the federation view is open.
+// Sessions on this machine (any profile) are attached through tmux; remote
+// ones can't be, so their web link is copied instead.
+func (h *Home) handleFederationViewKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	h.federationView, cmd = h.federationView.Update(msg)
+	if !h.federationView.IsVisible() {
+		if h.federationCancel != nil {
+			h.federationCancel()
+			h.federationCancel = nil
+		}
+		return h, cmd
+	}
+	sess := h.federationView.TakeOpen()
+	if sess == nil {
+		return h, cmd
+	}
+	if sess.TmuxSession != "" {
+		tmuxSess := tmux.ReconnectSessionLazy(sess.TmuxSession, sess.Title, sess.ProjectPath, "", string(sess.Status))
+		if !tmuxSess.Exists() {
+			h.federationView.SetStatus("", fmt.Errorf("%s is not running", sess.Title))
+			return h, cmd
+		}
+		h.isAttaching.Store(true)
+		return h, tea.Batch(cmd, tea.Exec(attachCmd{session: tmuxSess}, func(err error) tea.Msg {
+			h.isAttaching.Store(false)
+			return statusUpdateMsg{}
+		}))
+	}
+	link, ok := h.federation.SessionURL(sess.ID)
+	if !ok {
+		h.federationView.SetStatus("", fmt.Errorf("%s has no terminal to attach to", sess.Title))
+		return h, cmd
+	}
+	return h, tea.Batch(cmd, func() tea.Msg {
+		if _, err := clipboard.Copy(link, tmux.GetTerminalInfo().SupportsOSC52); err != nil {
+			return federationOpenedMsg{err: fmt.Errorf("clipboard: %w", err)}
+		}
+		return federationOpenedMsg{status: "Copied " + link}
+	})
+}
+
+// renderTranscriptPreview renders the newest part of a session's transcript
+// for the preview pane, in at most height lines.
+func (h *Home) renderTranscriptPreview(inst *session.Instance, width, height int) string {
+	var b strings.Builder
+	b.WriteString(renderSectionDivider("Transcript", width-4))
+	b.WriteString("\n")
+	dim := lipgloss.NewStyle().Foreground(ColorText).Italic(true)
+
+	t, ok := h.transcriptCache[inst.ID]
+	switch {
+	case !ok:
+		b.WriteString(dim.Render("Loading transcript..."))
+		return b.String()
+	case t == nil || len(t.Entries) == 0:
+		b.WriteString(dim.Render("No conversation transcript for this session yet"))
+		return b.String()
+	}
+
+	lines := layoutTranscript(t, width-4, false)
+	avail := max(height-3, 1) // Divider and hint line
+	if len(lines) > avail {
+		lines = lines[len(lines)-avail:]
+	}
+	for _, l := range lines {
+		b.WriteString(renderTranscriptLine(l, ""))
+		b.WriteString("\n")
+	}
+	hint := fmt.Sprintf("%d turns · V: open viewer (search, jump to turn)", t.Turns)
+	b.WriteString(lipgloss.NewStyle().Foreground(ColorComment).Render(hint))
+	return b.String()
+}
+
 func (h *Home) handleGlobalSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "enter":
@@ -3350,7 +4022,7 @@ func (h *Home) handleGlobalSearchSelection(result *GlobalSearchResult) tea.Cmd {
 	// Check if session already exists in Agent Deck
 	h.instancesMu.RLock()
 	for _, inst := range h.instances {
-		if inst.ClaudeSessionID == result.SessionID {
+		if inst.Tool == result.Tool && inst.ToolSessionID() == result.SessionID {
 			h.instancesMu.RUnlock()
 			// Jump to existing session
 			h.jumpToSession(inst)
@@ -3359,7 +4031,7 @@ func (h *Home) handleGlobalSearchSelection(result *GlobalSearchResult) tea.Cmd {
 	}
 	h.instancesMu.RUnlock()
 
-	// Create new session with this Claude session ID
+	// Create new session resuming this conversation
 	return h.createSessionFromGlobalSearch(result)
 }
 
@@ -3383,6 +4055,9 @@ func (h *Home) jumpToSession(inst *session.Instance) {
 
 // createSessionFromGlobalSearch creates a new Agent Deck session from global search result
 func (h *Home) createSessionFromGlobalSearch(result *GlobalSearchResult) tea.Cmd {
+	if result.Tool != "" && result.Tool != session.TranscriptToolClaude {
+		return h.resumeToolSessionFromGlobalSearch(result)
+	}
 	return func() tea.Msg {
 		// Derive title from CWD or session ID
 		title := "Claude Session"
@@ -3435,6 +4110,50 @@ func (h *Home) createSessionFromGlobalSearch(result *GlobalSearchResult) tea.Cmd
 	}
 }
 
+// resumeToolSessionFromGlobalSearch creates a Codex, Gemini or OpenCode session
+// that resumes a global search result. Setting the tool's session ID makes the
+// tool's command builder emit its resume flags, as on restart.
+func (h *Home) resumeToolSessionFromGlobalSearch(result *GlobalSearchResult) tea.Cmd {
+	groupPath := h.getCurrentGroupPath()
+	return func() tea.Msg {
+		projectPath := result.CWD
+		if projectPath == "" {
+			projectPath = "."
+		}
+		title := filepath.Base(projectPath)
+		if projectPath == "." {
+			title = result.Tool + " session"
+		}
+
+		inst := session.NewInstanceWithGroupAndTool(title, projectPath, groupPath, result.Tool)
+		inst.Command = result.Tool
+
+		var err error
+		switch result.Tool {
+		case session.TranscriptToolCodex:
+			inst.CodexSessionID = result.SessionID
+			err = inst.Start()
+		case session.TranscriptToolOpenCode:
+			inst.OpenCodeSessionID = result.SessionID
+			err = inst.Start()
+		case session.TranscriptToolGemini:
+			if tag, ok := session.GeminiCheckpointTag(result.SessionID); ok {
+				// Checkpoints have no session ID; restore them inside a fresh chat
+				err = inst.StartWithMessage("/chat resume " + tag)
+			} else {
+				inst.GeminiSessionID = result.SessionID
+				err = inst.Start()
+			}
+		default:
+			err = fmt.Errorf("cannot resume %s conversations", result.Tool)
+		}
+		if err != nil {
+			return sessionCreatedMsg{err: fmt.Errorf("failed to start session: %w", err)}
+		}
+		return sessionCreatedMsg{instance: inst}
+	}
+}
+
 // getCurrentGroupPath returns the group path of the currently selected item
 func (h *Home) getCurrentGroupPath() string {
 	if h.cursor >= 0 && h.cursor < len(h.flatItems) {
@@ -3466,6 +4185,7 @@ func (h *Home) handleNewDialogKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 		// Handle worktree creation if enabled
 		var worktreePath, worktreeRepoRoot string
+		var worktreeSetup func(next tea.Cmd) tea.Cmd // Runs copy/post_create hooks before next
 		if worktreeEnabled && branchName != "" {
 			// Validate path is a git repo
 			if !git.IsGitRepo(path) {
@@ -3495,11 +4215,26 @@ func (h *Home) handleNewDialogKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				return h, nil
 			}
 
+			hooks, err := session.LoadWorktreeHooks(repoRoot)
+			if err != nil {
+				h.newDialog.SetError(err.Error())
+				return h, nil
+			}
+
 			// Create worktree
+			branchCreated := !git.BranchExists(repoRoot, branchName)
 			if err := git.CreateWorktree(repoRoot, worktreePath, branchName); err != nil {
 				h.newDialog.SetError(fmt.Sprintf("Failed to create worktree: %v", err))
 				return h, nil
 			}
+			if hooks.HasSetup() {
+				worktreeSetup = func(next tea.Cmd) tea.Cmd {
+					env := session.WorktreeHookEnv{RepoRoot: repoRoot, WorktreePath: worktreePath, Branch: branchName}
+					return h.withWorktreeSetup(hooks, env, branchCreated, next, func(err error) tea.Msg {
+						return sessionCreatedMsg{err: err}
+					})
+				}
+			}
 
 			// Store repo root for later use
 			worktreeRepoRoot = repoRoot
@@ -3528,7 +4263,11 @@ func (h *Home) handleNewDialogKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 		geminiYoloMode := h.newDialog.IsGeminiYoloMode()
 
-		return h, h.createSessionInGroupWithWorktreeAndOptions(name, path, command, groupPath, worktreePath, worktreeRepoRoot, branchName, geminiYoloMode, toolOptionsJSON)
+		create := h.createSessionInGroupWithWorktreeAndOptions(name, path, command, groupPath, worktreePath, worktreeRepoRoot, branchName, geminiYoloMode, toolOptionsJSON)
+		if worktreeSetup != nil {
+			return h, worktreeSetup(create)
+		}
+		return h, create
 
 	case "esc":
 		h.newDialog.Hide()
@@ -3877,6 +4616,10 @@ func (h *Home) handleMainKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					h.setError(fmt.Errorf("session '%s' is not a worktree", inst.Title))
 					return h, nil
 				}
+				if inst.Workspace != nil {
+					h.setError(fmt.Errorf("session '%s' spans workspace %s: finish it with 'agent-deck workspace finish'", inst.Title, inst.Workspace.Name))
+					return h, nil
+				}
 				// Determine default target branch
 				defaultBranch := "main"
 				if detected, err := git.GetDefaultBranch(inst.WorktreeRepoRoot); err == nil {
@@ -4085,11 +4828,63 @@ func (h *Home) handleMainKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return h, nil
 
+	case "X":
+		// Revoke the selected session's web share links
+		if selected := h.getSelectedSession(); selected != nil {
+			if shares := h.shares[selected.ID]; shares != nil {
+				h.confirmDialog.ShowRevokeShares(selected.ID, selected.Title, shares.links, len(shares.viewers))
+			}
+		}
+		return h, nil
+
 	case "v":
-		// Toggle preview mode (cycle: both → output-only → analytics-only → both)
-		h.previewMode = (h.previewMode + 1) % 3
+		// Toggle preview mode (cycle: both → output-only → analytics-only → transcript → both)
+		h.previewMode = (h.previewMode + 1) % 4
+		if h.previewMode == PreviewModeTranscript {
+			if selected := h.getSelectedSession(); selected != nil && h.transcriptStale(selected.ID) {
+				return h, h.fetchTranscript(selected)
+			}
+		}
+		return h, nil
+
+	case "V":
+		// Open the full-screen transcript viewer for the selected session
+		selected := h.getSelectedSession()
+		if selected == nil {
+			return h, nil
+		}
+		h.transcriptViewer.SetSize(h.width, h.height)
+		h.transcriptViewer.Show(selected.ID, selected.Title)
+		if cached, ok := h.transcriptCache[selected.ID]; ok {
+			h.transcriptViewer.SetTranscript(selected.ID, cached, nil)
+		}
+		if h.transcriptFetchingID != selected.ID {
+			return h, h.fetchTranscript(selected)
+		}
 		return h, nil
 
+	case "D":
+		// Review the selected session's changes against its base branch
+		selected := h.getSelectedSession()
+		if selected == nil {
+			return h, nil
+		}
+		return h, h.openDiffViewer(selected, selected.ReviewDir(), "")
+
+	case "C":
+		// Browse and restore the selected session's per-turn checkpoints
+		selected := h.getSelectedSession()
+		if selected == nil {
+			return h, nil
+		}
+		h.checkpointPicker.SetSize(h.width, h.height)
+		h.checkpointPicker.Show(selected.ID, selected.Title, selected.ReviewDir())
+		return h, h.fetchCheckpoints(selected.ID, selected.ReviewDir())
+
+	case "H":
+		// Browse the sessions of every [federation] host and profile
+		return h, h.openFederationView()
+
 	case "y":
 		// Toggle Gemini YOLO mode (requires restart)
 		if h.cursor < len(h.flatItems) {
@@ -4342,6 +5137,10 @@ func (h *Home) handleConfirmDialogKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					h.confirmDialog.Hide()
 					return h, h.deleteSession(inst)
 				}
+			case ConfirmRevokeShares:
+				sessionID := h.confirmDialog.GetTargetID()
+				h.confirmDialog.Hide()
+				return h, h.revokeShares(sessionID)
 			case ConfirmDeleteGroup:
 				groupPath := h.confirmDialog.GetTargetID()
 				h.groupTree.DeleteGroup(groupPath)
@@ -4424,6 +5223,13 @@ func (h *Home) performFinalShutdown(shutdownPool bool) tea.Cmd {
 		if h.hookWatcher != nil {
 			h.hookWatcher.Stop()
 		}
+		// Stop git status collection
+		if h.gitStatus != nil {
+			h.gitStatus.Stop()
+		}
+		if h.checkpointer != nil {
+			h.checkpointer.Stop()
+		}
 		// Close storage watcher
 		if h.storageWatcher != nil {
 			h.storageWatcher.Close()
@@ -4679,6 +5485,13 @@ func (h *Home) handleForkDialogKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 						return h, nil
 					}
 
+					hooks, err := session.LoadWorktreeHooks(repoRoot)
+					if err != nil {
+						h.forkDialog.SetError(err.Error())
+						return h, nil
+					}
+
+					branchCreated := !git.BranchExists(repoRoot, branchName)
 					if err := git.CreateWorktree(repoRoot, worktreePath, branchName); err != nil {
 						h.forkDialog.SetError(fmt.Sprintf("Worktree creation failed: %v", err))
 						return h, nil
@@ -4688,6 +5501,15 @@ func (h *Home) handleForkDialogKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					opts.WorktreePath = worktreePath
 					opts.WorktreeRepoRoot = repoRoot
 					opts.WorktreeBranch = branchName
+
+					if hooks.HasSetup() {
+						h.forkDialog.Hide()
+						env := session.WorktreeHookEnv{RepoRoot: repoRoot, WorktreePath: worktreePath, Branch: branchName}
+						sourceID := source.ID
+						return h, h.withWorktreeSetup(hooks, env, branchCreated, h.forkSessionCmdWithOptions(source, title, groupPath, opts), func(err error) tea.Msg {
+							return sessionForkedMsg{err: err, sourceID: sourceID}
+						})
+					}
 				}
 
 				h.forkDialog.Hide()
@@ -5200,12 +6022,20 @@ func (h *Home) deleteSession(inst *session.Instance) tea.Cmd {
 	isWorktree := inst.IsWorktree()
 	worktreePath := inst.WorktreePath
 	worktreeRepoRoot := inst.WorktreeRepoRoot
+	var workspaceWorktrees []session.WorkspaceRepo
+	if inst.Workspace != nil {
+		workspaceWorktrees = inst.Workspace.OtherWorktrees()
+	}
 	return func() tea.Msg {
 		killErr := inst.Kill()
 		if isWorktree {
 			_ = git.RemoveWorktree(worktreeRepoRoot, worktreePath, false)
 			_ = git.PruneWorktrees(worktreeRepoRoot)
 		}
+		for _, repo := range workspaceWorktrees {
+			_ = git.RemoveWorktree(repo.RepoRoot, repo.WorktreePath, false)
+			_ = git.PruneWorktrees(repo.RepoRoot)
+		}
 		return sessionDeletedMsg{deletedID: id, killErr: killErr}
 	}
 }
@@ -5590,6 +6420,18 @@ func (h *Home) View() string {
 	if h.helpOverlay.IsVisible() {
 		return h.helpOverlay.View()
 	}
+	if h.transcriptViewer.IsVisible() {
+		return h.transcriptViewer.View()
+	}
+	if h.diffViewer.IsVisible() {
+		return h.diffViewer.View()
+	}
+	if h.checkpointPicker.IsVisible() {
+		return h.checkpointPicker.View()
+	}
+	if h.federationView.IsVisible() {
+		return h.federationView.View()
+	}
 	if h.search.IsVisible() {
 		return h.search.View()
 	}
@@ -6650,6 +7492,8 @@ func (h *Home) previewModeShort() string {
 		return "Out"
 	case PreviewModeAnalytics:
 		return "Stats"
+	case PreviewModeTranscript:
+		return "Log"
 	default:
 		return "Both"
 	}
@@ -7086,10 +7930,13 @@ func (h *Home) renderSessionItem(b *strings.Builder, item session.Item, selected
 		vagrantBadge = vStyle.Render(" [Vagrant]")
 	}
 
-	// Build row: [baseIndent][selection][tree][status] [title] [tool] [yolo] [worktree] [vagrant]
+	// Git badge: changed files, line stats and ahead/behind upstream
+	gitBadge := renderGitBadge(inst.GetGitStatus(), selected)
+
+	// Build row: [baseIndent][selection][tree][status] [title] [tool] [yolo] [worktree] [git] [vagrant]
 	// Format: " ├─ ● session-name tool" or "▶└─ ● session-name tool"
 	// Sub-sessions get extra indent: "   ├─◐ sub-session tool"
-	row := fmt.Sprintf("%s%s%s %s %s%s%s%s%s", baseIndent, selectionPrefix, treeStyle.Render(treeConnector), status, title, tool, yoloBadge, worktreeBadge, vagrantBadge)
+	row := fmt.Sprintf("%s%s%s %s %s%s%s%s%s%s", baseIndent, selectionPrefix, treeStyle.Render(treeConnector), status, title, tool, yoloBadge, worktreeBadge, gitBadge, vagrantBadge)
 	b.WriteString(row)
 	b.WriteString("\n")
 }
@@ -7545,6 +8392,29 @@ func (h *Home) renderPreviewPane(width, height int) string {
 		b.WriteString("\n")
 	}
 
+	// Leased [ports]: PORT is the first, AGENTDECK_PORT_n the nth
+	if ports := selected.GetPorts(); len(ports) > 0 {
+		b.WriteString(renderSectionDivider("Ports", width-4))
+		b.WriteString("\n")
+		parts := make([]string, len(ports))
+		for n, port := range ports {
+			parts[n] = fmt.Sprint(port)
+		}
+		b.WriteString(lipgloss.NewStyle().Foreground(ColorText).Render("Ports:   "))
+		b.WriteString(lipgloss.NewStyle().Foreground(ColorCyan).Render(strings.Join(parts, " · ")))
+		b.WriteString("\n")
+	}
+
+	// Web share links ('agent-deck session share') and who is watching
+	if shares := h.shares[selected.ID]; shares != nil {
+		b.WriteString(renderSharesSection(shares, width))
+	}
+
+	// Git section (branch position, change counts, most-changed files)
+	if gitStatus := selected.GetGitStatus(); gitStatus != nil {
+		b.WriteString(renderGitSection(gitStatus, width))
+	}
+
 	// Claude-specific info (session ID and MCPs)
 	if selected.Tool == "claude" {
 		// Section divider for Claude info
@@ -7931,9 +8801,20 @@ func (h *Home) renderPreviewPane(width, height int) string {
 		return content
 	}
 
+	// Transcript mode replaces analytics and output with the conversation tail
+	if h.previewMode == PreviewModeTranscript {
+		b.WriteString(h.renderTranscriptPreview(selected, width, height-strings.Count(b.String(), "\n")))
+		content := b.String()
+		lineCount := len(strings.Split(content, "\n"))
+		for i := lineCount; i < height; i++ {
+			content += "\n"
+		}
+		return strings.TrimSuffix(content, "\n")
+	}
+
 	// Check preview settings for what to show
 	config, _ := session.LoadUserConfig()
-	showAnalytics := config != nil && config.GetShowAnalytics() && (selected.Tool == "claude" || selected.Tool == "gemini")
+	showAnalytics := config != nil && config.GetShowAnalytics() && hasAnalytics(selected.Tool)
 	showOutput := config == nil || config.GetShowOutput() // Default to true if config fails
 
 	// Apply preview mode override (v key cycles through modes)
@@ -7942,7 +8823,7 @@ func (h *Home) renderPreviewPane(width, height int) string {
 		showAnalytics = false
 		showOutput = true
 	case PreviewModeAnalytics:
-		// showAnalytics keeps its default value (only available for Claude/Gemini)
+		// showAnalytics keeps its default value (only available for tools with analytics)
 		showOutput = false
 		// PreviewModeBoth: use config settings (default)
 	}
@@ -7953,7 +8834,7 @@ func (h *Home) renderPreviewPane(width, height int) string {
 	_, isSessionForking := h.forkingSessions[selected.ID]
 	isStartingUp := isSessionLaunching || isSessionResuming || isSessionForking
 
-	// Analytics panel (for Claude/Gemini sessions with analytics enabled)
+	// Analytics panel (for sessions of tools with analytics, when enabled)
 	// Skip showing "Loading analytics..." during startup - let the launch animation take focus
 	if showAnalytics && !isStartingUp {
 		analyticsHeader := renderSectionDivider("Analytics", width-4)
@@ -8635,6 +9516,17 @@ func (h *Home) handleWorktreeFinishDialogKey(msg tea.KeyMsg) (tea.Model, tea.Cmd
 
 		return h, h.finishWorktree(inst, sid, sTitle, branch, repoRoot, wtPath, mergeEnabled, targetBranch, keepBranch)
 
+	case "diff":
+		// Review what the merge would bring in, against the chosen target
+		h.instancesMu.RLock()
+		inst := h.instanceByID[h.worktreeFinishDialog.sessionID]
+		h.instancesMu.RUnlock()
+		if inst == nil {
+			return h, nil
+		}
+		_, targetBranch, _ := h.worktreeFinishDialog.GetOptions()
+		return h, h.openDiffViewer(inst, h.worktreeFinishDialog.worktreePath, targetBranch)
+
 	case "input":
 		// Pass through to text input
 		h.worktreeFinishDialog.UpdateTargetInput(msg)
@@ -8650,6 +9542,18 @@ func (h *Home) finishWorktree(inst *session.Instance, sessionID, sessionTitle, b
 	return func() tea.Msg {
 		merged := false
 
+		// Step 0: pre_finish hooks; a failure aborts before anything changes
+		hooks, err := session.LoadWorktreeHooks(repoRoot)
+		if err != nil {
+			return worktreeFinishResultMsg{sessionID: sessionID, sessionTitle: sessionTitle, err: err}
+		}
+		hookEnv := session.WorktreeHookEnv{RepoRoot: repoRoot, WorktreePath: worktreePath, Branch: branchName, SessionID: sessionID}
+		if _, statErr := os.Stat(worktreePath); statErr == nil {
+			if err := session.RunPreFinishHooks(hooks, hookEnv, nil); err != nil {
+				return worktreeFinishResultMsg{sessionID: sessionID, sessionTitle: sessionTitle, err: err}
+			}
+		}
+
 		// Step 1: Merge (if requested)
 		if mergeEnabled {
 			// Checkout target branch in main repo
@@ -8680,6 +9584,7 @@ func (h *Home) finishWorktree(inst *session.Instance, sessionID, sessionTitle, b
 			_ = git.RemoveWorktree(repoRoot, worktreePath, false)
 		}
 		_ = git.PruneWorktrees(repoRoot)
+		_, _ = git.DeleteCheckpoints(repoRoot, sessionID)
 
 		// Step 3: Delete branch (if not keeping)
 		if !keepBranch {
@@ -8692,11 +9597,15 @@ func (h *Home) finishWorktree(inst *session.Instance, sessionID, sessionTitle, b
 			_ = inst.Kill()
 		}
 
+		// Step 5: post_finish hooks (teardown; failures are only reported)
+		warning := session.RunPostFinishHooks(hooks, hookEnv, nil)
+
 		return worktreeFinishResultMsg{
 			sessionID:    sessionID,
 			sessionTitle: sessionTitle,
 			targetBranch: targetBranch,
 			merged:       merged,
+			warning:      warning,
 		}
 	}
 }
@@ -366,6 +366,7 @@ func TestHomeSearchOpensGlobalWhenAvailable(t *testing.T) {
 		Tier:           "instant",
 		MemoryLimitMB:  100,
 		IndexRateLimit: 100,
+		Tools:          []string{"claude"},
 	}
 	index, err := session.NewGlobalSearchIndex(tmpDir, config)
 	if err != nil {
@@ -428,6 +429,7 @@ func TestHomeGlobalSearchEscape(t *testing.T) {
 		Tier:           "instant",
 		MemoryLimitMB:  100,
 		IndexRateLimit: 100,
+		Tools:          []string{"claude"},
 	}
 	index, err := session.NewGlobalSearchIndex(tmpDir, config)
 	if err != nil {
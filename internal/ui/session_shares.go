@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+	"github.com/asheshgoplani/agent-deck/internal/web"
+)
+
+// shareCheckInterval is how often active share links and their viewers are
+// reloaded from the state database.
+const shareCheckInterval = 10 * time.Second
+
+// sessionShares summarizes a session's active share links (see
+// 'agent-deck session share').
+type sessionShares struct {
+	links      int
+	allowInput bool // At least one link lets viewers type
+	expiresAt  time.Time
+	viewers    []string
+}
+
+// sharesLoadedMsg carries active share links by session ID.
+type sharesLoadedMsg struct {
+	shares map[string]*sessionShares
+}
+
+// sharesRevokedMsg reports revoking a session's share links from the TUI.
+type sharesRevokedMsg struct {
+	sessionID string
+	revoked   int
+	err       error
+}
+
+// loadShares reads active share links and viewers in the background.
+func (h *Home) loadShares() tea.Cmd {
+	db := statedb.GetGlobal()
+	if db == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		now := time.Now()
+		rows, err := db.LoadWebShares("")
+		if err != nil {
+			uiLog.Warn("share_load_failed", slog.String("error", err.Error()))
+			return nil
+		}
+		viewers, err := db.LoadActiveWebShareViewers(now.Add(-web.ShareViewerActiveWindow))
+		if err != nil {
+			uiLog.Warn("share_viewers_load_failed", slog.String("error", err.Error()))
+		}
+
+		shares := make(map[string]*sessionShares)
+		sessionOf := make(map[string]string)
+		for _, row := range rows {
+			if !row.Active(now) {
+				continue
+			}
+			s := shares[row.SessionID]
+			if s == nil {
+				s = &sessionShares{}
+				shares[row.SessionID] = s
+			}
+			s.links++
+			s.allowInput = s.allowInput || row.AllowInput
+			if row.ExpiresAt.After(s.expiresAt) {
+				s.expiresAt = row.ExpiresAt
+			}
+			sessionOf[row.ID] = row.SessionID
+		}
+		for _, v := range viewers {
+			if s := shares[sessionOf[v.ShareID]]; s != nil {
+				name := v.Name
+				if name == "" {
+					name = v.RemoteAddr
+				}
+				s.viewers = append(s.viewers, name)
+			}
+		}
+		return sharesLoadedMsg{shares: shares}
+	}
+}
+
+// revokeShares revokes every active share link of a session.
+func (h *Home) revokeShares(sessionID string) tea.Cmd {
+	db := statedb.GetGlobal()
+	if db == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		n, err := db.RevokeSessionWebShares(sessionID, time.Now())
+		if err == nil && n > 0 {
+			_ = db.AppendWebAudit(&statedb.WebAuditRow{TokenName: "tui", Action: "share.revoke", SessionID: sessionID,
+				Detail: fmt.Sprintf("%d link(s)", n)})
+		}
+		return sharesRevokedMsg{sessionID: sessionID, revoked: n, err: err}
+	}
+}
+
+// renderSharesSection shows a session's active share links and who is
+// watching through them.
+func renderSharesSection(shares *sessionShares, width int) string {
+	var b strings.Builder
+	b.WriteString(renderSectionDivider("Sharing", width-4))
+	b.WriteString("\n")
+
+	labelStyle := lipgloss.NewStyle().Foreground(ColorText)
+	hintStyle := lipgloss.NewStyle().Foreground(ColorTextDim)
+	access := "view only"
+	if shares.allowInput {
+		access = "input allowed"
+	}
+	b.WriteString(labelStyle.Render("Links:   "))
+	b.WriteString(lipgloss.NewStyle().Foreground(ColorCyan).Render(
+		fmt.Sprintf("%d active (%s, until %s)", shares.links, access, shares.expiresAt.Format("15:04"))))
+	b.WriteString("\n")
+
+	b.WriteString(labelStyle.Render("Viewers: "))
+	if len(shares.viewers) == 0 {
+		b.WriteString(hintStyle.Render("nobody watching"))
+	} else {
+		b.WriteString(lipgloss.NewStyle().Foreground(ColorYellow).Render(strings.Join(shares.viewers, ", ")))
+	}
+	b.WriteString("\n")
+
+	b.WriteString(hintStyle.Render("Revoke:  "))
+	b.WriteString(lipgloss.NewStyle().Foreground(ColorAccent).Bold(true).Render("X"))
+	b.WriteString(hintStyle.Render(" disconnect viewers + revoke links"))
+	b.WriteString("\n")
+	return b.String()
+}
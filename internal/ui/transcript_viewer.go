@@ -0,0 +1,571 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/asheshgoplani/agent-deck/internal/session"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// transcriptLineKind selects how a transcript line is styled
+type transcriptLineKind int
+
+const (
+	transcriptLineBlank transcriptLineKind = iota
+	transcriptLineTurn                     // "── Turn 3 ──" separator
+	transcriptLineUser
+	transcriptLineAssistant
+	transcriptLineTool
+	transcriptLineToolDetail
+	transcriptLineToolError
+	transcriptLineSubagent // Subagent header
+)
+
+// transcriptLine is one laid-out line of a transcript. Text is plain so it
+// can be searched and highlighted; styling is applied when rendering.
+type transcriptLine struct {
+	kind   transcriptLineKind
+	gutter string // Timestamp / icon column (plain)
+	text   string
+	turn   int
+}
+
+// transcriptGutterWidth fits "15:04:05 👤 "
+const transcriptGutterWidth = 12
+
+// transcriptToolDetailMax caps the lines shown per expanded tool input/result
+const transcriptToolDetailMax = 40
+
+// layoutTranscript lays out a transcript at the given width. Tool calls are
+// one line each unless expandTools is set.
+func layoutTranscript(t *session.SessionTranscript, width int, expandTools bool) []transcriptLine {
+	if t == nil {
+		return nil
+	}
+	textWidth := max(width-transcriptGutterWidth, 20)
+	var lines []transcriptLine
+	add := func(kind transcriptLineKind, gutter, text string, turn int) {
+		for i, l := range wrapTranscriptText(text, textWidth) {
+			if i > 0 {
+				gutter = ""
+			}
+			lines = append(lines, transcriptLine{kind: kind, gutter: gutter, text: l, turn: turn})
+		}
+	}
+
+	lastTurn, lastSubagent := -1, ""
+	for _, e := range t.Entries {
+		clock := ""
+		if !e.Timestamp.IsZero() {
+			clock = e.Timestamp.Local().Format("15:04:05")
+		}
+		if e.Turn != lastTurn && e.Kind == session.TranscriptEntryUser && e.Subagent == "" {
+			if len(lines) > 0 {
+				lines = append(lines, transcriptLine{kind: transcriptLineBlank, turn: e.Turn})
+			}
+			label := fmt.Sprintf("── Turn %d ", e.Turn)
+			if !e.Timestamp.IsZero() {
+				label += "· " + e.Timestamp.Local().Format("Jan 2 15:04") + " "
+			}
+			lines = append(lines, transcriptLine{kind: transcriptLineTurn, text: label + strings.Repeat("─", max(textWidth-runewidth.StringWidth(label), 0)), turn: e.Turn})
+			lastTurn = e.Turn
+		}
+		if e.Subagent != lastSubagent {
+			if e.Subagent != "" {
+				lines = append(lines, transcriptLine{kind: transcriptLineSubagent, gutter: clock, text: "↳ subagent " + e.Subagent, turn: e.Turn})
+			}
+			lastSubagent = e.Subagent
+		}
+		indent := ""
+		if e.Subagent != "" {
+			indent = "  "
+		}
+
+		switch e.Kind {
+		case session.TranscriptEntryUser:
+			add(transcriptLineUser, clock+" 👤", indentLines(e.Text, indent), e.Turn)
+		case session.TranscriptEntryAssistant:
+			add(transcriptLineAssistant, clock+" 🤖", indentLines(e.Text, indent), e.Turn)
+		case session.TranscriptEntryTool:
+			tc := e.Tool
+			kind := transcriptLineTool
+			if tc.IsError {
+				kind = transcriptLineToolError
+			}
+			summary := indent + "⚙ " + tc.Name
+			if tc.Summary != "" {
+				summary += "  " + tc.Summary
+			}
+			if !expandTools {
+				if tc.Result != "" {
+					mark := "→ "
+					if tc.IsError {
+						mark = "✗ "
+					}
+					summary += "  " + mark + firstTranscriptLine(tc.Result)
+				}
+				lines = append(lines, transcriptLine{kind: kind, gutter: clock, text: runewidth.Truncate(summary, textWidth, "…"), turn: e.Turn})
+				continue
+			}
+			add(kind, clock, summary, e.Turn)
+			if tc.Input != "" && tc.Input != tc.Summary {
+				add(transcriptLineToolDetail, "", indentLines(capTranscriptLines(tc.Input), indent+"    "), e.Turn)
+			}
+			if tc.Result != "" {
+				add(transcriptLineToolDetail, "", indentLines(capTranscriptLines(tc.Result), indent+"  │ "), e.Turn)
+			}
+		}
+	}
+	return lines
+}
+
+// wrapTranscriptText wraps text to width, keeping explicit line breaks.
+func wrapTranscriptText(text string, width int) []string {
+	var out []string
+	for _, l := range strings.Split(strings.ReplaceAll(text, "\t", "    "), "\n") {
+		if runewidth.StringWidth(l) <= width {
+			out = append(out, l)
+			continue
+		}
+		out = append(out, strings.Split(runewidth.Wrap(l, width), "\n")...)
+	}
+	return out
+}
+
+func indentLines(text, indent string) string {
+	if indent == "" {
+		return text
+	}
+	return indent + strings.ReplaceAll(text, "\n", "\n"+indent)
+}
+
+func capTranscriptLines(text string) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) <= transcriptToolDetailMax {
+		return text
+	}
+	return strings.Join(lines[:transcriptToolDetailMax], "\n") + fmt.Sprintf("\n… %d more lines", len(lines)-transcriptToolDetailMax)
+}
+
+func firstTranscriptLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i] + fmt.Sprintf(" … (%d lines)", strings.Count(s, "\n")+1)
+	}
+	return s
+}
+
+// renderTranscriptLine styles one line, highlighting query matches.
+func renderTranscriptLine(l transcriptLine, query string) string {
+	var style lipgloss.Style
+	switch l.kind {
+	case transcriptLineTurn:
+		style = lipgloss.NewStyle().Foreground(ColorBorder)
+	case transcriptLineUser:
+		style = lipgloss.NewStyle().Foreground(ColorGreen)
+	case transcriptLineAssistant:
+		style = lipgloss.NewStyle().Foreground(ColorText)
+	case transcriptLineTool:
+		style = lipgloss.NewStyle().Foreground(ColorYellow)
+	case transcriptLineToolError:
+		style = lipgloss.NewStyle().Foreground(ColorRed)
+	case transcriptLineToolDetail:
+		style = lipgloss.NewStyle().Foreground(ColorComment)
+	case transcriptLineSubagent:
+		style = lipgloss.NewStyle().Foreground(ColorPurple).Bold(true)
+	default:
+		return ""
+	}
+	gutter := lipgloss.NewStyle().Foreground(ColorComment).Render(runewidth.FillRight(l.gutter, transcriptGutterWidth))
+	return gutter + highlightTranscriptText(l.text, query, style)
+}
+
+// highlightTranscriptText renders text in style with case-insensitive
+// matches of query highlighted.
+func highlightTranscriptText(text, query string, style lipgloss.Style) string {
+	if query == "" {
+		return style.Render(text)
+	}
+	lower, q := strings.ToLower(text), strings.ToLower(query)
+	if len(lower) != len(text) { // Case folding changed byte offsets
+		return style.Render(text)
+	}
+	hl := lipgloss.NewStyle().Background(ColorYellow).Foreground(ColorBg).Bold(true)
+	var b strings.Builder
+	for {
+		i := strings.Index(lower, q)
+		if i < 0 {
+			b.WriteString(style.Render(text))
+			return b.String()
+		}
+		if i > 0 {
+			b.WriteString(style.Render(text[:i]))
+		}
+		b.WriteString(hl.Render(text[i : i+len(q)]))
+		text, lower = text[i+len(q):], lower[i+len(q):]
+		if text == "" {
+			return b.String()
+		}
+	}
+}
+
+// transcriptInputMode is what the viewer's input line is collecting
+type transcriptInputMode int
+
+const (
+	transcriptInputNone transcriptInputMode = iota
+	transcriptInputSearch
+	transcriptInputJump
+)
+
+// TranscriptViewer is a full-screen, scrollable view of a session's
+// conversation with search and jump-to-turn.
+type TranscriptViewer struct {
+	visible   bool
+	width     int
+	height    int
+	sessionID string
+	title     string
+
+	transcript  *session.SessionTranscript
+	err         error
+	lines       []transcriptLine
+	expandTools bool
+
+	scroll     int
+	followTail bool // Keep the newest lines in view as the transcript grows
+
+	input     textinput.Model
+	inputMode transcriptInputMode
+	query     string
+	matches   []int // Line indexes containing query
+	matchIdx  int
+	status    string
+
+	reloadRequested bool
+}
+
+// NewTranscriptViewer creates a hidden transcript viewer
+func NewTranscriptViewer() *TranscriptViewer {
+	ti := textinput.New()
+	ti.CharLimit = 100
+	ti.Width = 40
+	return &TranscriptViewer{input: ti}
+}
+
+// Show opens the viewer for a session. The transcript arrives via SetTranscript.
+func (v *TranscriptViewer) Show(sessionID, title string) {
+	if v.sessionID != sessionID {
+		v.transcript, v.err, v.lines = nil, nil, nil
+		v.query, v.matches, v.matchIdx = "", nil, 0
+	}
+	v.visible = true
+	v.sessionID = sessionID
+	v.title = title
+	v.followTail = true
+	v.inputMode = transcriptInputNone
+	v.status = ""
+}
+
+// Hide closes the viewer
+func (v *TranscriptViewer) Hide() {
+	v.visible = false
+	v.input.Blur()
+}
+
+// IsVisible returns whether the viewer is open
+func (v *TranscriptViewer) IsVisible() bool {
+	return v.visible
+}
+
+// SessionID returns the session being viewed
+func (v *TranscriptViewer) SessionID() string {
+	return v.sessionID
+}
+
+// WantsReload reports (once) whether the user asked to re-read the transcript
+func (v *TranscriptViewer) WantsReload() bool {
+	r := v.reloadRequested
+	v.reloadRequested = false
+	return r
+}
+
+// SetSize sets the viewer dimensions
+func (v *TranscriptViewer) SetSize(width, height int) {
+	if width == v.width && height == v.height {
+		return
+	}
+	v.width, v.height = width, height
+	v.relayout()
+}
+
+// SetTranscript updates the transcript shown for sessionID.
+func (v *TranscriptViewer) SetTranscript(sessionID string, t *session.SessionTranscript, err error) {
+	if sessionID != v.sessionID {
+		return
+	}
+	v.transcript, v.err = t, err
+	v.relayout()
+}
+
+func (v *TranscriptViewer) textWidth() int {
+	return max(v.width-4, 40)
+}
+
+func (v *TranscriptViewer) pageHeight() int {
+	return max(v.height-5, 5) // Title, separator, footer, input/status line
+}
+
+func (v *TranscriptViewer) maxScroll() int {
+	return max(len(v.lines)-v.pageHeight(), 0)
+}
+
+// relayout re-wraps the transcript, keeping the top line's turn in view.
+func (v *TranscriptViewer) relayout() {
+	topTurn := 0
+	if v.scroll < len(v.lines) {
+		topTurn = v.lines[v.scroll].turn
+	}
+	v.lines = layoutTranscript(v.transcript, v.textWidth(), v.expandTools)
+	v.findMatches()
+	if v.followTail {
+		v.scroll = v.maxScroll()
+		return
+	}
+	v.scroll = min(v.turnLine(topTurn), v.maxScroll())
+}
+
+func (v *TranscriptViewer) setScroll(n int) {
+	v.scroll = max(min(n, v.maxScroll()), 0)
+	v.followTail = v.scroll == v.maxScroll()
+}
+
+// turnLine returns the first line of a turn (or the closest earlier one).
+func (v *TranscriptViewer) turnLine(turn int) int {
+	best := 0
+	for i, l := range v.lines {
+		if l.kind == transcriptLineTurn {
+			if l.turn > turn {
+				break
+			}
+			best = i
+		}
+	}
+	return best
+}
+
+func (v *TranscriptViewer) findMatches() {
+	v.matches = nil
+	if v.query == "" {
+		return
+	}
+	q := strings.ToLower(v.query)
+	for i, l := range v.lines {
+		if strings.Contains(strings.ToLower(l.text), q) {
+			v.matches = append(v.matches, i)
+		}
+	}
+	if v.matchIdx >= len(v.matches) {
+		v.matchIdx = 0
+	}
+}
+
+// showMatch scrolls so match i sits a few lines below the top.
+func (v *TranscriptViewer) showMatch(i int) {
+	if len(v.matches) == 0 {
+		v.status = fmt.Sprintf("No matches for %q", v.query)
+		return
+	}
+	v.matchIdx = (i + len(v.matches)) % len(v.matches)
+	v.setScroll(v.matches[v.matchIdx] - 3)
+	v.status = fmt.Sprintf("Match %d/%d", v.matchIdx+1, len(v.matches))
+}
+
+// jumpTurn scrolls to the previous (-1) or next (+1) turn.
+func (v *TranscriptViewer) jumpTurn(dir int) {
+	if dir > 0 {
+		for i := v.scroll + 1; i < len(v.lines); i++ {
+			if v.lines[i].kind == transcriptLineTurn {
+				v.setScroll(i)
+				return
+			}
+		}
+		return
+	}
+	for i := v.scroll - 1; i >= 0; i-- {
+		if v.lines[i].kind == transcriptLineTurn {
+			v.setScroll(i)
+			return
+		}
+	}
+}
+
+// Update handles keys while the viewer is open
+func (v *TranscriptViewer) Update(msg tea.KeyMsg) (*TranscriptViewer, tea.Cmd) {
+	if v.inputMode != transcriptInputNone {
+		switch msg.String() {
+		case "esc":
+			v.inputMode = transcriptInputNone
+			v.input.Blur()
+		case "enter":
+			value := strings.TrimSpace(v.input.Value())
+			mode := v.inputMode
+			v.inputMode = transcriptInputNone
+			v.input.Blur()
+			if mode == transcriptInputSearch {
+				v.query = value
+				v.findMatches()
+				if value != "" {
+					// First match at or below the current position
+					start := 0
+					for i, line := range v.matches {
+						if line >= v.scroll {
+							start = i
+							break
+						}
+					}
+					v.showMatch(start)
+				}
+			} else if n, err := strconv.Atoi(value); err == nil {
+				if v.transcript == nil || n < 1 || n > v.transcript.Turns {
+					v.status = fmt.Sprintf("No turn %d", n)
+				} else {
+					v.setScroll(v.turnLine(n))
+					v.status = fmt.Sprintf("Turn %d/%d", n, v.transcript.Turns)
+				}
+			}
+		default:
+			var cmd tea.Cmd
+			v.input, cmd = v.input.Update(msg)
+			return v, cmd
+		}
+		return v, nil
+	}
+
+	page := v.pageHeight()
+	v.status = ""
+	switch msg.String() {
+	case "esc", "q", "V":
+		v.Hide()
+	case "j", "down":
+		v.setScroll(v.scroll + 1)
+	case "k", "up":
+		v.setScroll(v.scroll - 1)
+	case "ctrl+d":
+		v.setScroll(v.scroll + page/2)
+	case "ctrl+u":
+		v.setScroll(v.scroll - page/2)
+	case "pgdown", " ", "ctrl+f":
+		v.setScroll(v.scroll + page)
+	case "pgup", "ctrl+b":
+		v.setScroll(v.scroll - page)
+	case "g", "home":
+		v.setScroll(0)
+	case "G", "end":
+		v.setScroll(v.maxScroll())
+	case "]", "}":
+		v.jumpTurn(1)
+	case "[", "{":
+		v.jumpTurn(-1)
+	case "/":
+		v.inputMode = transcriptInputSearch
+		v.input.Prompt = "/"
+		v.input.Placeholder = "search transcript"
+		v.input.SetValue(v.query)
+		v.input.CursorEnd()
+		return v, v.input.Focus()
+	case ":":
+		v.inputMode = transcriptInputJump
+		v.input.Prompt = "Turn: "
+		v.input.Placeholder = ""
+		v.input.SetValue("")
+		return v, v.input.Focus()
+	case "n":
+		v.showMatch(v.matchIdx + 1)
+	case "N":
+		v.showMatch(v.matchIdx - 1)
+	case "x":
+		v.expandTools = !v.expandTools
+		v.relayout()
+	case "r":
+		v.reloadRequested = true
+		v.status = "Reloading…"
+	}
+	return v, nil
+}
+
+// View renders the viewer
+func (v *TranscriptViewer) View() string {
+	if !v.visible {
+		return ""
+	}
+	var b strings.Builder
+	width := v.textWidth()
+
+	title := "📜 Transcript: " + v.title
+	if t := v.transcript; t != nil {
+		title += fmt.Sprintf("  (%s · %d turns", t.Tool, t.Turns)
+		if len(t.Subagents) > 0 {
+			title += fmt.Sprintf(" · %d subagents", len(t.Subagents))
+		}
+		title += ")"
+	}
+	b.WriteString(lipgloss.NewStyle().Foreground(ColorCyan).Bold(true).Render(runewidth.Truncate(title, width, "…")))
+	b.WriteString("\n")
+	b.WriteString(lipgloss.NewStyle().Foreground(ColorBorder).Render(strings.Repeat("─", width)))
+	b.WriteString("\n")
+
+	page := v.pageHeight()
+	dim := lipgloss.NewStyle().Foreground(ColorComment).Italic(true)
+	shown := 0
+	switch {
+	case v.err != nil:
+		b.WriteString(lipgloss.NewStyle().Foreground(ColorRed).Render("Failed to read transcript: " + v.err.Error()))
+		shown = 1
+	case v.transcript == nil && v.lines == nil:
+		b.WriteString(dim.Render("Loading transcript…"))
+		shown = 1
+	case len(v.lines) == 0:
+		b.WriteString(dim.Render("No conversation yet"))
+		shown = 1
+	default:
+		end := min(v.scroll+page, len(v.lines))
+		for i := v.scroll; i < end; i++ {
+			b.WriteString(renderTranscriptLine(v.lines[i], v.query))
+			b.WriteString("\n")
+			shown++
+		}
+	}
+	for ; shown < page; shown++ {
+		b.WriteString("\n")
+	}
+
+	// Input or status line
+	switch {
+	case v.inputMode != transcriptInputNone:
+		b.WriteString(v.input.View())
+	case v.status != "":
+		b.WriteString(lipgloss.NewStyle().Foreground(ColorYellow).Render(v.status))
+	case len(v.lines) > page:
+		pos := fmt.Sprintf("line %d/%d", v.scroll+1, len(v.lines))
+		if v.scroll < len(v.lines) && v.lines[v.scroll].turn > 0 {
+			pos = fmt.Sprintf("turn %d · ", v.lines[v.scroll].turn) + pos
+		}
+		b.WriteString(dim.Render(pos))
+	}
+	b.WriteString("\n")
+
+	tools := "x expand tools"
+	if v.expandTools {
+		tools = "x collapse tools"
+	}
+	hints := "j/k scroll · PgUp/PgDn · g/G top/end · [ ] prev/next turn · : jump to turn · / search · n/N next/prev · " + tools + " · r reload · Esc close"
+	b.WriteString(lipgloss.NewStyle().Foreground(ColorComment).Render(runewidth.Truncate(hints, width, "…")))
+
+	return lipgloss.NewStyle().Padding(0, 2).Render(b.String())
+}
@@ -0,0 +1,178 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/session"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// testTranscript builds a transcript of n turns, each a prompt, a tool call
+// and a reply.
+func testTranscript(n int) *session.SessionTranscript {
+	t := &session.SessionTranscript{Tool: "claude", Turns: n}
+	start := time.Date(2025, 10, 1, 10, 0, 0, 0, time.UTC)
+	for i := 1; i <= n; i++ {
+		ts := start.Add(time.Duration(i) * time.Minute)
+		t.Entries = append(t.Entries,
+			session.TranscriptEntry{Kind: session.TranscriptEntryUser, Timestamp: ts, Text: fmt.Sprintf("prompt %d", i), Turn: i},
+			session.TranscriptEntry{Kind: session.TranscriptEntryTool, Timestamp: ts, Turn: i, Tool: &session.TranscriptToolCall{
+				Name: "Bash", Summary: "ls", Input: "{\n  \"command\": \"ls -la\"\n}", Result: "a.go\nb.go",
+			}},
+			session.TranscriptEntry{Kind: session.TranscriptEntryAssistant, Timestamp: ts, Text: fmt.Sprintf("reply %d", i), Turn: i},
+		)
+	}
+	return t
+}
+
+func transcriptKey(s string) tea.KeyMsg {
+	switch s {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestLayoutTranscript_CollapsedAndExpandedTools(t *testing.T) {
+	tr := testTranscript(1)
+
+	collapsed := layoutTranscript(tr, 80, false)
+	var toolLines []string
+	for _, l := range collapsed {
+		if l.kind == transcriptLineTool {
+			toolLines = append(toolLines, l.text)
+		}
+	}
+	if len(toolLines) != 1 || !strings.Contains(toolLines[0], "Bash  ls  → a.go") {
+		t.Errorf("collapsed tool lines = %q, want one summary line with the result", toolLines)
+	}
+	if collapsed[0].kind != transcriptLineTurn || !strings.Contains(collapsed[0].text, "Turn 1") {
+		t.Errorf("first line = %+v, want turn header", collapsed[0])
+	}
+
+	expanded := layoutTranscript(tr, 80, true)
+	if len(expanded) <= len(collapsed) {
+		t.Errorf("expanded layout has %d lines, want more than collapsed %d", len(expanded), len(collapsed))
+	}
+	var details []string
+	for _, l := range expanded {
+		if l.kind == transcriptLineToolDetail {
+			details = append(details, strings.TrimSpace(l.text))
+		}
+	}
+	joined := strings.Join(details, "\n")
+	if !strings.Contains(joined, `"command": "ls -la"`) || !strings.Contains(joined, "│ b.go") {
+		t.Errorf("expanded details missing input or result:\n%s", joined)
+	}
+}
+
+func TestLayoutTranscript_SubagentMarker(t *testing.T) {
+	tr := &session.SessionTranscript{Turns: 1, Entries: []session.TranscriptEntry{
+		{Kind: session.TranscriptEntryUser, Text: "go", Turn: 1},
+		{Kind: session.TranscriptEntryUser, Text: "find it", Subagent: "a1", Turn: 1},
+		{Kind: session.TranscriptEntryAssistant, Text: "found", Subagent: "a1", Turn: 1},
+		{Kind: session.TranscriptEntryAssistant, Text: "done", Turn: 1},
+	}}
+	lines := layoutTranscript(tr, 80, false)
+	markers := 0
+	for _, l := range lines {
+		if l.kind == transcriptLineAssistant && strings.Contains(l.text, "found") && !strings.HasPrefix(l.text, "  ") {
+			t.Errorf("subagent reply %q should be indented", l.text)
+		}
+		if l.kind == transcriptLineSubagent {
+			markers++
+			if !strings.Contains(l.text, "a1") {
+				t.Errorf("subagent marker = %q, want agent id", l.text)
+			}
+		}
+	}
+	if markers != 1 {
+		t.Errorf("got %d subagent markers, want 1", markers)
+	}
+}
+
+func TestTranscriptViewer_SearchAndJump(t *testing.T) {
+	v := NewTranscriptViewer()
+	v.SetSize(100, 20)
+	v.Show("s1", "demo")
+	v.SetTranscript("s1", testTranscript(10), nil)
+
+	if v.scroll != v.maxScroll() {
+		t.Fatalf("viewer should open at the end: scroll=%d max=%d", v.scroll, v.maxScroll())
+	}
+
+	// Jump to turn 3
+	v, _ = v.Update(transcriptKey(":"))
+	for _, r := range "3" {
+		v, _ = v.Update(transcriptKey(string(r)))
+	}
+	v, _ = v.Update(transcriptKey("enter"))
+	if top := v.lines[v.scroll]; top.kind != transcriptLineTurn || top.turn != 3 {
+		t.Errorf("after jump top line = %+v, want turn 3 header", top)
+	}
+
+	// Next/previous turn
+	v, _ = v.Update(transcriptKey("]"))
+	if got := v.lines[v.scroll].turn; got != 4 {
+		t.Errorf("after ] turn = %d, want 4", got)
+	}
+	v, _ = v.Update(transcriptKey("["))
+	if got := v.lines[v.scroll].turn; got != 3 {
+		t.Errorf("after [ turn = %d, want 3", got)
+	}
+
+	// Search from the top
+	v, _ = v.Update(transcriptKey("g"))
+	v, _ = v.Update(transcriptKey("/"))
+	for _, r := range "REPLY 7" {
+		v, _ = v.Update(transcriptKey(string(r)))
+	}
+	v, _ = v.Update(transcriptKey("enter"))
+	if len(v.matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(v.matches))
+	}
+	if !strings.Contains(v.View(), "reply 7") {
+		t.Error("match should be scrolled into view")
+	}
+
+	// Out-of-range turn leaves position alone
+	before := v.scroll
+	v, _ = v.Update(transcriptKey(":"))
+	v, _ = v.Update(transcriptKey("9"))
+	v, _ = v.Update(transcriptKey("9"))
+	v, _ = v.Update(transcriptKey("enter"))
+	if v.scroll != before || !strings.Contains(v.status, "No turn 99") {
+		t.Errorf("jump to missing turn: scroll %d→%d status %q", before, v.scroll, v.status)
+	}
+
+	v, _ = v.Update(transcriptKey("esc"))
+	if v.IsVisible() {
+		t.Error("esc should close the viewer")
+	}
+}
+
+func TestTranscriptViewer_IgnoresOtherSessions(t *testing.T) {
+	v := NewTranscriptViewer()
+	v.SetSize(100, 20)
+	v.Show("s1", "demo")
+	v.SetTranscript("s2", testTranscript(2), nil)
+	if v.transcript != nil {
+		t.Error("transcript for another session should be ignored")
+	}
+	if !strings.Contains(v.View(), "Loading transcript") {
+		t.Error("viewer should show loading state until its transcript arrives")
+	}
+}
+
+func TestHighlightTranscriptText(t *testing.T) {
+	out := highlightTranscriptText("Hello hello", "hello", lipgloss.NewStyle())
+	if strings.Count(out, "ello") != 2 {
+		t.Errorf("highlight lost text: %q", out)
+	}
+}
@@ -121,7 +121,7 @@ func (d *WorktreeFinishDialog) GetOptions() (mergeEnabled bool, targetBranch str
 }
 
 // HandleKey processes a key event and returns the action to take.
-// Returns: action string ("close", "confirm", ""), and whether the dialog handled the key.
+// Returns: action string ("close", "confirm", "diff", "input", "").
 func (d *WorktreeFinishDialog) HandleKey(key string) (action string) {
 	if d.isExecuting {
 		return "" // Block input while executing
@@ -132,6 +132,10 @@ func (d *WorktreeFinishDialog) HandleKey(key string) (action string) {
 		switch key {
 		case "y":
 			return "confirm"
+		case "d":
+			if d.errorMsg == "" {
+				return "diff"
+			}
 		case "n", "esc":
 			if d.errorMsg != "" {
 				// Error state: go back to options
@@ -203,6 +207,12 @@ func (d *WorktreeFinishDialog) HandleKey(key string) (action string) {
 		d.errorMsg = ""
 		d.step = 1
 		return ""
+
+	case "d", "ctrl+d":
+		// Review what will be merged; a plain d types into the target input
+		if key == "ctrl+d" || d.focusIndex != 1 || !d.mergeEnabled {
+			return "diff"
+		}
 	}
 
 	// Pass through to target input if focused
@@ -342,7 +352,7 @@ func (d *WorktreeFinishDialog) viewOptions(titleStyle, labelStyle, valueStyle, c
 	}
 
 	b.WriteString("\n")
-	b.WriteString(footerStyle.Render("Tab next | Space toggle | Enter confirm | Esc cancel"))
+	b.WriteString(footerStyle.Render("Tab next | Space toggle | d review diff | Enter confirm | Esc cancel"))
 
 	dialog := boxStyle.Render(b.String())
 	return lipgloss.Place(d.width, d.height, lipgloss.Center, lipgloss.Center, dialog)
@@ -403,7 +413,7 @@ func (d *WorktreeFinishDialog) viewConfirm(titleStyle, labelStyle, errStyle, foo
 	}
 
 	b.WriteString("\n")
-	b.WriteString(footerStyle.Render("y Finish | n Cancel"))
+	b.WriteString(footerStyle.Render("y Finish | d Review diff | n Cancel"))
 
 	dialog := boxStyle.Render(b.String())
 	return lipgloss.Place(d.width, d.height, lipgloss.Center, lipgloss.Center, dialog)
@@ -1,27 +1,154 @@
 package web
 
 import (
+	"context"
 	"crypto/subtle"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/logging"
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
 )
 
-func (s *Server) authorizeRequest(r *http.Request) bool {
-	if s.cfg.Token == "" {
-		return true
+// Principal names for requests that don't use a named token.
+const (
+	// flagTokenName is the --token flag's token, which grants every scope
+	flagTokenName = "--token"
+	// anonymousName is used when no token is configured at all
+	anonymousName = "anonymous"
+)
+
+// authenticate returns who the request acts as, or nil when its credentials
+// are missing or invalid. The token is read from the ?token= query parameter
+// or a Bearer Authorization header. Without a --token flag and without named
+// tokens in the store, every request is allowed.
+func (s *Server) authenticate(r *http.Request) *principal {
+	candidates := []string{
+		strings.TrimSpace(r.URL.Query().Get("token")),
+		bearerToken(r.Header.Get("Authorization")),
+	}
+	for _, secret := range candidates {
+		if secret == "" {
+			continue
+		}
+		if s.cfg.Token != "" && secureEqual(secret, s.cfg.Token) {
+			return &principal{Name: flagTokenName, Scopes: []string{ScopeAdmin}}
+		}
+		if p := s.lookupNamedToken(secret); p != nil {
+			return p
+		}
 	}
 
-	queryToken := strings.TrimSpace(r.URL.Query().Get("token"))
-	if queryToken != "" && secureEqual(queryToken, s.cfg.Token) {
-		return true
+	if s.cfg.Token == "" && !s.hasNamedTokens() {
+		return &principal{Name: anonymousName, Scopes: []string{ScopeAdmin}}
 	}
+	return nil
+}
 
-	headerToken := bearerToken(r.Header.Get("Authorization"))
-	if headerToken != "" && secureEqual(headerToken, s.cfg.Token) {
+// authorize authenticates the request and checks it holds scope, writing
+// a 401 or 403 response when it doesn't. The principal is handed to
+// withAudit through the request context.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, scope string) (*principal, bool) {
+	p := s.authenticate(r)
+	if slot, ok := r.Context().Value(auditPrincipalKey{}).(**principal); ok {
+		*slot = p
+	}
+	if p == nil {
+		writeAPIError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+		return nil, false
+	}
+	if !p.Can(scope) {
+		writeAPIError(w, http.StatusForbidden, "FORBIDDEN", "token lacks the "+scope+" scope")
+		return nil, false
+	}
+	return p, true
+}
+
+func (s *Server) lookupNamedToken(secret string) *principal {
+	if s.cfg.Tokens == nil {
+		return nil
+	}
+	tok, err := s.cfg.Tokens.LookupToken(secret)
+	if err != nil {
+		logging.ForComponent(logging.CompWeb).Warn("token_lookup_failed", slog.String("error", err.Error()))
+		return nil
+	}
+	if tok == nil || (!tok.ExpiresAt.IsZero() && time.Now().After(tok.ExpiresAt)) {
+		return nil
+	}
+	return &principal{Name: tok.Name, Scopes: tok.Scopes, Groups: tok.Groups}
+}
+
+// hasNamedTokens reports whether the store holds tokens, failing closed
+// when it can't be read.
+func (s *Server) hasNamedTokens() bool {
+	if s.cfg.Tokens == nil {
+		return false
+	}
+	has, err := s.cfg.Tokens.HasTokens()
+	if err != nil {
+		logging.ForComponent(logging.CompWeb).Warn("token_store_failed", slog.String("error", err.Error()))
 		return true
 	}
+	return has
+}
+
+// recordAudit appends an entry to the token store's audit log.
+func (s *Server) recordAudit(entry *statedb.WebAuditRow) {
+	if s.cfg.Tokens == nil {
+		return
+	}
+	if err := s.cfg.Tokens.RecordAudit(entry); err != nil {
+		logging.ForComponent(logging.CompWeb).Warn("audit_record_failed",
+			slog.String("action", entry.Action),
+			slog.String("error", err.Error()))
+	}
+}
+
+// auditPrincipalKey is the request context key of the slot authorize
+// stores the request's principal in for withAudit.
+type auditPrincipalKey struct{}
+
+// withAudit records every request that may change state (anything but
+// GET, HEAD and OPTIONS) in the audit log, keyed by the token it used.
+func (s *Server) withAudit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		var p *principal
+		r = r.WithContext(context.WithValue(r.Context(), auditPrincipalKey{}, &p))
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		name := "unauthenticated"
+		if p != nil {
+			name = p.Name
+		}
+		s.recordAudit(&statedb.WebAuditRow{
+			TokenName:  name,
+			Action:     "request",
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			RemoteAddr: r.RemoteAddr,
+			Status:     rec.status,
+		})
+	})
+}
+
+// statusRecorder remembers the status code a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
 
-	return false
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
 }
 
 func bearerToken(authHeader string) string {
@@ -0,0 +1,213 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+	"github.com/gorilla/websocket"
+)
+
+// newTokenTestServer returns a server backed by a fresh token store holding
+// tokens, whose secrets are "secret-<name>".
+func newTokenTestServer(t *testing.T, tokens ...*statedb.WebTokenRow) (*Server, *statedb.StateDB) {
+	t.Helper()
+	db, err := statedb.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open state db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for _, tok := range tokens {
+		tok.Hash = HashToken("secret-" + tok.Name)
+		if err := db.CreateWebToken(tok); err != nil {
+			t.Fatalf("create token: %v", err)
+		}
+	}
+
+	srv := NewServer(Config{
+		ListenAddr: "127.0.0.1:0",
+		Profile:    "work",
+		Tokens:     NewTokenStore(db),
+	})
+	srv.menuData = &fakeMenuDataLoader{
+		snapshot: &MenuSnapshot{
+			Profile:       "work",
+			TotalGroups:   3,
+			TotalSessions: 2,
+			Items: []MenuItem{
+				{Type: MenuItemTypeGroup, Group: &MenuGroup{Name: "clients", Path: "clients", SessionCount: 2}},
+				{Type: MenuItemTypeGroup, Group: &MenuGroup{Name: "acme", Path: "clients/acme", SessionCount: 1}},
+				{Type: MenuItemTypeSession, Session: &MenuSession{ID: "sess-acme", GroupPath: "clients/acme"}},
+				{Type: MenuItemTypeGroup, Group: &MenuGroup{Name: "beta", Path: "clients/beta", SessionCount: 1}},
+				{Type: MenuItemTypeSession, Session: &MenuSession{ID: "sess-beta", GroupPath: "clients/beta"}},
+			},
+		},
+	}
+	return srv, db
+}
+
+func serveWithToken(srv *Server, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rr, req)
+	return rr
+}
+
+func TestNamedTokensRequireAuth(t *testing.T) {
+	srv, _ := newTokenTestServer(t,
+		&statedb.WebTokenRow{Name: "viewer", Scopes: []string{ScopeRead}},
+		&statedb.WebTokenRow{Name: "old", Scopes: []string{ScopeAdmin}, ExpiresAt: time.Now().Add(-time.Minute)},
+		&statedb.WebTokenRow{Name: "typist", Scopes: []string{ScopeTerminalInput}},
+	)
+
+	cases := []struct {
+		token string
+		want  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"wrong", http.StatusUnauthorized},
+		{"secret-old", http.StatusUnauthorized},
+		{"secret-typist", http.StatusForbidden},
+		{"secret-viewer", http.StatusOK},
+	}
+	for _, tc := range cases {
+		if rr := serveWithToken(srv, http.MethodGet, "/api/menu", tc.token); rr.Code != tc.want {
+			t.Errorf("token %q: status %d, want %d", tc.token, rr.Code, tc.want)
+		}
+	}
+}
+
+func TestNoTokensAllowsAnonymousAccess(t *testing.T) {
+	srv, _ := newTokenTestServer(t)
+	if rr := serveWithToken(srv, http.MethodGet, "/api/menu", ""); rr.Code != http.StatusOK {
+		t.Fatalf("expected open access without tokens, got %d", rr.Code)
+	}
+}
+
+func TestGroupRestrictedTokenSeesOnlyItsGroups(t *testing.T) {
+	srv, _ := newTokenTestServer(t,
+		&statedb.WebTokenRow{Name: "acme", Scopes: []string{ScopeRead}, Groups: []string{"clients/acme"}},
+	)
+
+	rr := serveWithToken(srv, http.MethodGet, "/api/menu", "secret-acme")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("menu status %d", rr.Code)
+	}
+	var snapshot MenuSnapshot
+	if err := json.Unmarshal(rr.Body.Bytes(), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.TotalSessions != 1 || snapshot.TotalGroups != 2 || len(snapshot.Items) != 3 {
+		t.Fatalf("filtered snapshot = %+v", snapshot)
+	}
+	if parent := snapshot.Items[0].Group; parent == nil || parent.Path != "clients" || parent.SessionCount != 1 {
+		t.Errorf("parent group should stay, counting only visible sessions: %+v", parent)
+	}
+
+	if rr := serveWithToken(srv, http.MethodGet, "/api/session/sess-acme", "secret-acme"); rr.Code != http.StatusOK {
+		t.Errorf("own session: status %d", rr.Code)
+	}
+	if rr := serveWithToken(srv, http.MethodGet, "/api/session/sess-beta", "secret-acme"); rr.Code != http.StatusNotFound {
+		t.Errorf("other group's session: status %d, want 404", rr.Code)
+	}
+	if rr := serveWithToken(srv, http.MethodPost, "/api/push/subscribe", "secret-acme"); rr.Code != http.StatusForbidden {
+		t.Errorf("push subscribe: status %d, want 403", rr.Code)
+	}
+}
+
+func TestMutatingRequestsAreAudited(t *testing.T) {
+	srv, db := newTokenTestServer(t,
+		&statedb.WebTokenRow{Name: "viewer", Scopes: []string{ScopeRead}},
+	)
+
+	serveWithToken(srv, http.MethodGet, "/api/menu", "secret-viewer")
+	serveWithToken(srv, http.MethodPost, "/api/push/unsubscribe", "secret-viewer")
+	serveWithToken(srv, http.MethodPost, "/api/push/presence", "bogus")
+
+	entries, err := db.LoadWebAudit("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("audit entries = %d, want 2 (GETs are not audited)", len(entries))
+	}
+	first, second := entries[0], entries[1]
+	if first.TokenName != "viewer" || first.Method != http.MethodPost || first.Path != "/api/push/unsubscribe" ||
+		first.Status != http.StatusServiceUnavailable {
+		t.Errorf("first entry = %+v", first)
+	}
+	if second.TokenName != "unauthenticated" || second.Status != http.StatusUnauthorized {
+		t.Errorf("second entry = %+v", second)
+	}
+}
+
+func TestWSReadTokenCannotSendInput(t *testing.T) {
+	srv, _ := newTokenTestServer(t,
+		&statedb.WebTokenRow{Name: "viewer", Scopes: []string{ScopeRead}},
+	)
+	testServer := httptest.NewServer(srv.Handler())
+	defer testServer.Close()
+
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL(testServer.URL, "/ws/session/sess-acme?token=secret-viewer"), nil)
+	if err != nil {
+		if resp != nil {
+			t.Fatalf("dial failed with status %d: %v", resp.StatusCode, err)
+		}
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var connected wsServerMessage
+	if err := conn.ReadJSON(&connected); err != nil {
+		t.Fatal(err)
+	}
+	if connected.Event != "connected" || !connected.ReadOnly {
+		t.Fatalf("expected read-only connected status, got %+v", connected)
+	}
+	expectWSStatusEvent(t, conn, "ready")
+
+	if err := conn.WriteJSON(wsClientMessage{Type: "input", Data: "ls\r"}); err != nil {
+		t.Fatal(err)
+	}
+	var msg wsServerMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "error" || msg.Code != "FORBIDDEN" {
+		t.Fatalf("expected FORBIDDEN error, got %+v", msg)
+	}
+}
+
+func TestTerminalInputAuditRecordsSession(t *testing.T) {
+	srv, db := newTokenTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/ws/session/sess-acme", nil)
+	audit := terminalInputAudit{server: srv, principal: &principal{Name: "ops"}, request: req, sessionID: "sess-acme"}
+
+	audit.finish() // No input: nothing recorded
+	audit.record(3)
+	audit.record(2)
+	audit.finish()
+
+	entries, err := db.LoadWebAudit("ops", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Action != "terminal.input.start" || entries[1].Action != "terminal.input.end" {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if entries[1].SessionID != "sess-acme" || !strings.HasPrefix(entries[1].Detail, "5 bytes in 2 messages") {
+		t.Errorf("end entry = %+v", entries[1])
+	}
+}
@@ -0,0 +1,622 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/logging"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// FederationLocalHost is the host name of this machine's profiles in a
+	// federated menu.
+	FederationLocalHost = "local"
+	// federationIDSeparator joins host, profile and session ID into a
+	// federated session ID ("host~profile~id"). Host and profile names may
+	// not contain it.
+	federationIDSeparator = "~"
+	// menuScopeLocal is the ?scope= value that asks a federated server for
+	// its own profile only, so federating servers never nest each other.
+	menuScopeLocal = "local"
+)
+
+var (
+	// federationStreamIdleTimeout drops an upstream stream that has sent
+	// nothing, not even a keepalive, for this long.
+	federationStreamIdleTimeout = 45 * time.Second
+	federationMaxBackoff        = 30 * time.Second
+	federationMaxEventSize      = 16 << 20
+)
+
+// LocalSource is a profile on this machine shown in a federated menu.
+type LocalSource struct {
+	Profile string
+	Menu    MenuDataLoader
+}
+
+// Upstream is another agent-deck web server shown in a federated menu.
+type Upstream struct {
+	// Name is the host name sessions are namespaced under
+	Name string
+	// URL is the server's base URL, e.g. https://buildbox:8420
+	URL string
+	// Token is sent as a Bearer token (optional)
+	Token string
+	// TLS trusts the upstream's CA and carries a client certificate (optional)
+	TLS *tls.Config
+}
+
+// FederationConfig lists the sources of a federated menu. Local[0] is the
+// serving process's own profile.
+type FederationConfig struct {
+	Local     []LocalSource
+	Upstreams []Upstream
+}
+
+// FederationSourceStatus reports one host/profile of a federated menu.
+type FederationSourceStatus struct {
+	Host      string    `json:"host"`
+	Profile   string    `json:"profile,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	Reachable bool      `json:"reachable"`
+	Error     string    `json:"error,omitempty"`
+	Sessions  int       `json:"sessions"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+}
+
+// Federation merges the menus of local profiles and upstream agent-deck web
+// servers into one snapshot. Sessions and groups are namespaced under
+// "<host>/<profile>" groups and session IDs become "host~profile~id".
+// Upstream menus are followed through their /events/menu streams once
+// Start is called.
+type Federation struct {
+	local     []LocalSource
+	upstreams []*federationUpstream
+}
+
+// NewFederation validates cfg and returns a federation over its sources.
+func NewFederation(cfg FederationConfig) (*Federation, error) {
+	if len(cfg.Local) == 0 || cfg.Local[0].Menu == nil {
+		return nil, fmt.Errorf("federation needs the local profile's menu data")
+	}
+	f := &Federation{}
+	seenProfiles := make(map[string]bool)
+	for _, src := range cfg.Local {
+		if err := validateFederationName("profile", src.Profile); err != nil {
+			return nil, err
+		}
+		if seenProfiles[src.Profile] {
+			continue
+		}
+		seenProfiles[src.Profile] = true
+		f.local = append(f.local, src)
+	}
+
+	seenHosts := map[string]bool{FederationLocalHost: true}
+	for _, up := range cfg.Upstreams {
+		if err := validateFederationName("upstream", up.Name); err != nil {
+			return nil, err
+		}
+		if seenHosts[up.Name] {
+			return nil, fmt.Errorf("upstream name %q is used twice or reserved", up.Name)
+		}
+		seenHosts[up.Name] = true
+		u, err := url.Parse(strings.TrimRight(up.URL, "/"))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("upstream %s: url must be http(s)://host[:port], got %q", up.Name, up.URL)
+		}
+		f.upstreams = append(f.upstreams, newFederationUpstream(up, u))
+	}
+	return f, nil
+}
+
+func validateFederationName(kind, name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%s name is empty", kind)
+	}
+	if strings.ContainsAny(name, "/"+federationIDSeparator) {
+		return fmt.Errorf("%s name %q must not contain '/' or '%s'", kind, name, federationIDSeparator)
+	}
+	return nil
+}
+
+// LocalMenuData returns the serving profile's own, unfederated menu data.
+func (f *Federation) LocalMenuData() MenuDataLoader {
+	return f.local[0].Menu
+}
+
+// SourceCount returns how many local profiles and upstreams are federated.
+func (f *Federation) SourceCount() int {
+	return len(f.local) + len(f.upstreams)
+}
+
+// Start follows every upstream's menu stream until ctx is done, calling
+// onChange whenever an upstream's menu or reachability changes.
+func (f *Federation) Start(ctx context.Context, onChange func()) {
+	if onChange == nil {
+		onChange = func() {}
+	}
+	for _, up := range f.upstreams {
+		go up.follow(ctx, onChange)
+	}
+}
+
+// Refresh fetches every upstream's menu once (used by one-shot commands
+// and tests instead of Start).
+func (f *Federation) Refresh(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, up := range f.upstreams {
+		wg.Add(1)
+		go func(up *federationUpstream) {
+			defer wg.Done()
+			snapshot, err := up.fetchMenu(ctx)
+			if err != nil {
+				up.setError(err)
+				return
+			}
+			up.setSnapshot(snapshot)
+		}(up)
+	}
+	wg.Wait()
+}
+
+// LoadMenuSnapshot returns the merged menu of all sources.
+func (f *Federation) LoadMenuSnapshot() (*MenuSnapshot, error) {
+	merged := &MenuSnapshot{
+		Profile:     f.local[0].Profile,
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	var localParts []federationPart
+	for _, src := range f.local {
+		snapshot, err := src.Menu.LoadMenuSnapshot()
+		part := federationPart{profile: src.Profile, snapshot: snapshot}
+		status := FederationSourceStatus{Host: FederationLocalHost, Profile: src.Profile, Reachable: err == nil}
+		if err != nil {
+			part.snapshot = nil
+			part.err = err.Error()
+			status.Error = part.err
+		} else if snapshot != nil {
+			status.Sessions = snapshot.TotalSessions
+			status.UpdatedAt = snapshot.GeneratedAt
+		}
+		localParts = append(localParts, part)
+		merged.Sources = append(merged.Sources, status)
+	}
+	merged.appendFederatedHost(FederationLocalHost, "", localParts, false)
+
+	for _, up := range f.upstreams {
+		snapshot, status := up.state()
+		var parts []federationPart
+		if snapshot != nil {
+			parts = []federationPart{{profile: snapshot.Profile, snapshot: snapshot}}
+		}
+		merged.appendFederatedHost(up.cfg.Name, status.Error, parts, true)
+		merged.Sources = append(merged.Sources, status)
+	}
+
+	for i := range merged.Items {
+		merged.Items[i].Index = i
+	}
+	return merged, nil
+}
+
+// Statuses reports every source's reachability and session count.
+func (f *Federation) Statuses() []FederationSourceStatus {
+	snapshot, _ := f.LoadMenuSnapshot()
+	return snapshot.Sources
+}
+
+// remoteSession resolves a federated session ID served by an upstream to
+// the upstream and the session's ID there.
+func (f *Federation) remoteSession(id string) (*federationUpstream, string, bool) {
+	parts := strings.SplitN(id, federationIDSeparator, 3)
+	if len(parts) != 3 || parts[0] == FederationLocalHost {
+		return nil, "", false
+	}
+	for _, up := range f.upstreams {
+		if up.cfg.Name == parts[0] {
+			return up, parts[2], true
+		}
+	}
+	return nil, "", false
+}
+
+// SessionURL returns the web UI link of a federated session served by an
+// upstream, without credentials.
+func (f *Federation) SessionURL(id string) (string, bool) {
+	up, remoteID, ok := f.remoteSession(id)
+	if !ok {
+		return "", false
+	}
+	ref := *up.base
+	ref.Path = strings.TrimRight(up.base.Path, "/") + "/s/" + remoteID
+	ref.RawPath = ""
+	return ref.String(), true
+}
+
+// federatedSessionID namespaces a session ID by host and profile.
+func federatedSessionID(host, profile, id string) string {
+	return host + federationIDSeparator + profile + federationIDSeparator + id
+}
+
+// federationPart is one profile's menu on a host.
+type federationPart struct {
+	profile  string
+	snapshot *MenuSnapshot
+	err      string
+}
+
+// appendFederatedHost adds a host group, a group per profile and the
+// profiles' items re-rooted under "<host>/<profile>". hostErr marks the
+// whole host unreachable. Remote sessions lose their tmux session name:
+// their terminals are proxied, never attached locally.
+func (m *MenuSnapshot) appendFederatedHost(host, hostErr string, parts []federationPart, remote bool) {
+	hostIdx := len(m.Items)
+	m.Items = append(m.Items, MenuItem{
+		Type:  MenuItemTypeGroup,
+		Level: 0,
+		Path:  host,
+		Group: &MenuGroup{
+			Name:        host,
+			Path:        host,
+			Expanded:    true,
+			Unreachable: hostErr != "",
+			Error:       hostErr,
+			Host:        host,
+		},
+	})
+	m.TotalGroups++
+
+	hostSessions := 0
+	for _, part := range parts {
+		prefix := host + "/" + part.profile
+		profileGroup := &MenuGroup{
+			Name:        part.profile,
+			Path:        prefix,
+			Expanded:    true,
+			Unreachable: part.err != "",
+			Error:       part.err,
+			Host:        host,
+		}
+		m.Items = append(m.Items, MenuItem{Type: MenuItemTypeGroup, Level: 1, Path: prefix, Group: profileGroup})
+		m.TotalGroups++
+		if part.snapshot == nil {
+			continue
+		}
+
+		profileGroup.SessionCount = part.snapshot.TotalSessions
+		hostSessions += part.snapshot.TotalSessions
+		m.TotalGroups += part.snapshot.TotalGroups
+		m.TotalSessions += part.snapshot.TotalSessions
+		for _, item := range part.snapshot.Items {
+			item.Level += 2
+			item.Path = joinGroupPath(prefix, item.Path)
+			if item.Group != nil {
+				group := *item.Group
+				if group.Host == "" {
+					// Groups an upstream federates already carry theirs
+					group.OriginPath = group.Path
+				}
+				group.Path = joinGroupPath(prefix, group.Path)
+				group.Host = host
+				item.Group = &group
+			}
+			if item.Session != nil {
+				sess := *item.Session
+				sess.ID = federatedSessionID(host, part.profile, sess.ID)
+				if sess.ParentSessionID != "" {
+					sess.ParentSessionID = federatedSessionID(host, part.profile, sess.ParentSessionID)
+				}
+				if sess.OriginGroupPath == "" {
+					sess.OriginGroupPath = sess.GroupPath
+				}
+				sess.GroupPath = joinGroupPath(prefix, sess.GroupPath)
+				sess.Host = host
+				sess.Profile = part.profile
+				if remote {
+					sess.TmuxSession = ""
+				}
+				item.Session = &sess
+			}
+			m.Items = append(m.Items, item)
+		}
+	}
+	m.Items[hostIdx].Group.SessionCount = hostSessions
+}
+
+func joinGroupPath(prefix, path string) string {
+	if path == "" {
+		return prefix
+	}
+	return prefix + "/" + path
+}
+
+// --- Upstreams ---
+
+// federationUpstream follows one upstream server's menu.
+type federationUpstream struct {
+	cfg    Upstream
+	base   *url.URL
+	client *http.Client
+
+	mu        sync.RWMutex
+	snapshot  *MenuSnapshot
+	err       string
+	updatedAt time.Time
+}
+
+func newFederationUpstream(cfg Upstream, base *url.URL) *federationUpstream {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg.TLS
+	transport.ResponseHeaderTimeout = 10 * time.Second
+	return &federationUpstream{
+		cfg:    cfg,
+		base:   base,
+		client: &http.Client{Transport: transport},
+		err:    "not connected yet",
+	}
+}
+
+// endpoint returns the upstream URL for path, asking for its local scope.
+func (u *federationUpstream) endpoint(path string, query url.Values) string {
+	ref := *u.base
+	ref.Path = strings.TrimRight(u.base.Path, "/") + path
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("scope", menuScopeLocal)
+	ref.RawQuery = query.Encode()
+	return ref.String()
+}
+
+func (u *federationUpstream) authorize(h http.Header) {
+	if u.cfg.Token != "" {
+		h.Set("Authorization", "Bearer "+u.cfg.Token)
+	}
+}
+
+func (u *federationUpstream) state() (*MenuSnapshot, FederationSourceStatus) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	status := FederationSourceStatus{
+		Host:      u.cfg.Name,
+		URL:       u.base.String(),
+		Reachable: u.err == "",
+		Error:     u.err,
+		UpdatedAt: u.updatedAt,
+	}
+	if u.snapshot != nil {
+		status.Profile = u.snapshot.Profile
+		status.Sessions = u.snapshot.TotalSessions
+	}
+	return u.snapshot, status
+}
+
+func (u *federationUpstream) setSnapshot(snapshot *MenuSnapshot) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.snapshot = snapshot
+	u.err = ""
+	u.updatedAt = time.Now().UTC()
+}
+
+// setError marks the upstream unreachable and drops its sessions; reports
+// whether that changed anything.
+func (u *federationUpstream) setError(err error) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	changed := u.err != err.Error() || u.snapshot != nil
+	u.snapshot = nil
+	u.err = err.Error()
+	return changed
+}
+
+// fetchMenu loads the upstream's menu once.
+func (u *federationUpstream) fetchMenu(ctx context.Context) (*MenuSnapshot, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.endpoint("/api/menu", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	u.authorize(req.Header)
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, upstreamStatusError(resp)
+	}
+	var snapshot MenuSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("decode menu: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// follow keeps a /events/menu stream open, reconnecting with backoff.
+func (u *federationUpstream) follow(ctx context.Context, onChange func()) {
+	log := logging.ForComponent(logging.CompWeb)
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := u.stream(ctx, onChange)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("stream closed")
+		}
+		log.Warn("federation_upstream_disconnected",
+			slog.String("upstream", u.cfg.Name),
+			slog.String("error", err.Error()))
+		if u.setError(err) {
+			onChange()
+		}
+
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, federationMaxBackoff)
+	}
+}
+
+func (u *federationUpstream) stream(ctx context.Context, onChange func()) error {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, u.endpoint("/events/menu", nil), nil)
+	if err != nil {
+		return err
+	}
+	u.authorize(req.Header)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return upstreamStatusError(resp)
+	}
+
+	// Upstreams send a keepalive every 15s; silence means a dead link.
+	idle := time.AfterFunc(federationStreamIdleTimeout, cancel)
+	defer idle.Stop()
+	body := &idleResetReader{r: resp.Body, timer: idle, timeout: federationStreamIdleTimeout}
+
+	err = readSSE(body, func(event string, data []byte) error {
+		if event != "menu" {
+			return nil
+		}
+		var snapshot MenuSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return fmt.Errorf("decode menu event: %w", err)
+		}
+		u.setSnapshot(&snapshot)
+		onChange()
+		return nil
+	})
+	if ctx.Err() == nil && streamCtx.Err() != nil {
+		return fmt.Errorf("no data for %s", federationStreamIdleTimeout)
+	}
+	return err
+}
+
+// dialSession opens the upstream terminal websocket for sessionID.
+func (u *federationUpstream) dialSession(ctx context.Context, sessionID, viewer string) (*websocket.Conn, error) {
+	target := *u.base
+	switch target.Scheme {
+	case "https":
+		target.Scheme = "wss"
+	default:
+		target.Scheme = "ws"
+	}
+	target.Path = strings.TrimRight(u.base.Path, "/") + "/ws/session/" + url.PathEscape(sessionID)
+	if viewer != "" {
+		// Names the viewer in the upstream's presence events
+		target.RawQuery = url.Values{"viewer": {viewer}}.Encode()
+	}
+
+	dialer := websocket.Dialer{
+		HandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:  u.cfg.TLS,
+		ReadBufferSize:   4096,
+		WriteBufferSize:  4096,
+	}
+	header := http.Header{}
+	u.authorize(header)
+	conn, resp, err := dialer.DialContext(ctx, target.String(), header)
+	if err != nil {
+		if resp != nil {
+			return nil, upstreamStatusError(resp)
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// upstreamStatusError describes a non-200 upstream response.
+func upstreamStatusError(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("unauthorized: check the upstream token")
+	case http.StatusForbidden:
+		return fmt.Errorf("forbidden: the upstream token needs the read scope")
+	}
+	return fmt.Errorf("upstream returned %s", resp.Status)
+}
+
+// idleResetReader pushes timer back by timeout on every read.
+type idleResetReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *idleResetReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
+
+// readSSE parses a server-sent event stream, calling fn for every event
+// that carries data. Comments (keepalives) are skipped.
+func readSSE(r io.Reader, fn func(event string, data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), federationMaxEventSize)
+
+	event := ""
+	var data bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				name := event
+				if name == "" {
+					name = "message"
+				}
+				if err := fn(name, bytes.TrimSuffix(data.Bytes(), []byte("\n"))); err != nil {
+					return err
+				}
+			}
+			event = ""
+			data.Reset()
+		case strings.HasPrefix(line, ":"):
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			data.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil && !isClosedConnError(err) {
+		return err
+	}
+	return nil
+}
+
+func isClosedConnError(err error) bool {
+	return err == io.EOF || err == context.Canceled || strings.Contains(err.Error(), net.ErrClosed.Error())
+}
@@ -0,0 +1,54 @@
+package web
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+// FederationFromSettings returns the federated menu for the [federation]
+// config, with own as the serving profile's menu data, or nil when nothing
+// is configured.
+func FederationFromSettings(profile string, own MenuDataLoader) (*Federation, error) {
+	settings := session.GetFederationSettings()
+	if len(settings.Profiles) == 0 && len(settings.Upstreams) == 0 {
+		return nil, nil
+	}
+
+	cfg := FederationConfig{
+		Local: []LocalSource{{Profile: profile, Menu: own}},
+	}
+	for _, name := range settings.Profiles {
+		name = strings.TrimSpace(name)
+		if name == "" || name == profile {
+			continue
+		}
+		cfg.Local = append(cfg.Local, LocalSource{Profile: name, Menu: NewSessionDataService(name)})
+	}
+
+	names := make([]string, 0, len(settings.Upstreams))
+	for name := range settings.Upstreams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		up := settings.Upstreams[name]
+		token := up.Token
+		if up.TokenEnv != "" {
+			token = os.Getenv(up.TokenEnv)
+			if token == "" {
+				return nil, fmt.Errorf("upstream %s: $%s is not set", name, up.TokenEnv)
+			}
+		}
+		tlsConfig, err := ClientTLSConfig(
+			session.ExpandPath(up.CAFile), session.ExpandPath(up.CertFile), session.ExpandPath(up.KeyFile))
+		if err != nil {
+			return nil, fmt.Errorf("upstream %s: %w", name, err)
+		}
+		cfg.Upstreams = append(cfg.Upstreams, Upstream{Name: name, URL: up.URL, Token: token, TLS: tlsConfig})
+	}
+	return NewFederation(cfg)
+}
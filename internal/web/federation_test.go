@@ -0,0 +1,355 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/session"
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+	"github.com/gorilla/websocket"
+)
+
+// newUpstreamServer starts an agent-deck web server on localhost serving
+// one session in group "ops".
+func newUpstreamServer(t *testing.T, profile, token, sessionID string) (*httptest.Server, *MemoryMenuData) {
+	t.Helper()
+	menu := NewMemoryMenuData(nil)
+	menu.SetSnapshot(federationTestSnapshot(profile, sessionID, session.StatusRunning))
+	srv := NewServer(Config{ListenAddr: "127.0.0.1:0", Profile: profile, Token: token, MenuData: menu})
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts, menu
+}
+
+func federationTestSnapshot(profile, sessionID string, status session.Status) *MenuSnapshot {
+	return &MenuSnapshot{
+		Profile:       profile,
+		TotalGroups:   1,
+		TotalSessions: 1,
+		Items: []MenuItem{
+			{Type: MenuItemTypeGroup, Path: "ops", Group: &MenuGroup{Name: "ops", Path: "ops", SessionCount: 1}},
+			{Type: MenuItemTypeSession, Level: 1, Path: "ops", Session: &MenuSession{
+				ID: sessionID, Title: sessionID, Status: status, GroupPath: "ops", TmuxSession: "agentdeck_" + sessionID,
+			}},
+		},
+	}
+}
+
+func TestFederationMergesProfilesAndUpstreams(t *testing.T) {
+	buildbox, _ := newUpstreamServer(t, "work", "up-secret", "bb1")
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+
+	fed, err := NewFederation(FederationConfig{
+		Local: []LocalSource{
+			{Profile: "default", Menu: &fakeMenuDataLoader{snapshot: federationTestSnapshot("default", "me1", session.StatusWaiting)}},
+			{Profile: "personal", Menu: &fakeMenuDataLoader{snapshot: federationTestSnapshot("personal", "me2", session.StatusIdle)}},
+		},
+		Upstreams: []Upstream{
+			{Name: "buildbox", URL: buildbox.URL, Token: "up-secret"},
+			{Name: "laptop", URL: downURL},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	fed.Refresh(ctx)
+
+	snapshot, err := fed.LoadMenuSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.TotalSessions != 3 {
+		t.Fatalf("total sessions = %d, want 3", snapshot.TotalSessions)
+	}
+
+	var paths, ids []string
+	for i, item := range snapshot.Items {
+		if item.Index != i {
+			t.Errorf("item %d has index %d", i, item.Index)
+		}
+		if item.Group != nil {
+			paths = append(paths, item.Group.Path)
+		}
+		if item.Session != nil {
+			ids = append(ids, item.Session.ID)
+		}
+	}
+	wantPaths := "local local/default local/default/ops local/personal local/personal/ops buildbox buildbox/work buildbox/work/ops laptop"
+	if got := strings.Join(paths, " "); got != wantPaths {
+		t.Errorf("group paths = %s\nwant %s", got, wantPaths)
+	}
+	if got := strings.Join(ids, " "); got != "local~default~me1 local~personal~me2 buildbox~work~bb1" {
+		t.Errorf("session ids = %s", got)
+	}
+
+	remote, ok := snapshotSessionByID(snapshot, "buildbox~work~bb1")
+	if !ok || remote.Host != "buildbox" || remote.Profile != "work" || remote.GroupPath != "buildbox/work/ops" || remote.TmuxSession != "" {
+		t.Errorf("remote session = %+v", remote)
+	}
+	if local, _ := snapshotSessionByID(snapshot, "local~default~me1"); local == nil || local.TmuxSession == "" {
+		t.Errorf("local sessions keep their tmux session: %+v", local)
+	}
+
+	laptop := snapshot.Items[len(snapshot.Items)-1].Group
+	if laptop == nil || !laptop.Unreachable || laptop.Error == "" {
+		t.Errorf("unreachable upstream should be flagged: %+v", laptop)
+	}
+	if len(snapshot.Sources) != 4 || !snapshot.Sources[2].Reachable || snapshot.Sources[3].Reachable {
+		t.Errorf("sources = %+v", snapshot.Sources)
+	}
+
+	if _, _, ok := fed.remoteSession("local~default~me1"); ok {
+		t.Error("local sessions must not resolve to an upstream")
+	}
+	if up, id, ok := fed.remoteSession("buildbox~work~bb1"); !ok || up.cfg.Name != "buildbox" || id != "bb1" {
+		t.Errorf("remoteSession = %v %q %v", up, id, ok)
+	}
+}
+
+func TestFederationRejectsBadConfig(t *testing.T) {
+	own := []LocalSource{{Profile: "default", Menu: &fakeMenuDataLoader{}}}
+	for name, ups := range map[string][]Upstream{
+		"reserved name": {{Name: "local", URL: "http://127.0.0.1:1"}},
+		"separator":     {{Name: "a~b", URL: "http://127.0.0.1:1"}},
+		"bad url":       {{Name: "box", URL: "ftp://box"}},
+	} {
+		if _, err := NewFederation(FederationConfig{Local: own, Upstreams: ups}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFederationFollowsUpstreamEvents(t *testing.T) {
+	prevPoll := menuEventsPollInterval
+	menuEventsPollInterval = 20 * time.Millisecond
+	t.Cleanup(func() { menuEventsPollInterval = prevPoll })
+
+	buildbox, upstreamMenu := newUpstreamServer(t, "work", "", "bb1")
+	fed, err := NewFederation(FederationConfig{
+		Local:     []LocalSource{{Profile: "default", Menu: &fakeMenuDataLoader{snapshot: &MenuSnapshot{Profile: "default"}}}},
+		Upstreams: []Upstream{{Name: "buildbox", URL: buildbox.URL}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan struct{}, 16)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fed.Start(ctx, func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	})
+
+	waitForStatus := func(want session.Status) {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for {
+			snapshot, _ := fed.LoadMenuSnapshot()
+			if sess, ok := snapshotSessionByID(snapshot, "buildbox~work~bb1"); ok && sess.Status == want {
+				return
+			}
+			select {
+			case <-changes:
+			case <-deadline:
+				t.Fatalf("timed out waiting for upstream status %s", want)
+			}
+		}
+	}
+	waitForStatus(session.StatusRunning)
+	upstreamMenu.SetSnapshot(federationTestSnapshot("work", "bb1", session.StatusWaiting))
+	waitForStatus(session.StatusWaiting)
+}
+
+func TestFederatedServerProxiesTerminalAndScopesMenu(t *testing.T) {
+	buildbox, _ := newUpstreamServer(t, "work", "up-secret", "bb1")
+	fed, err := NewFederation(FederationConfig{
+		Local:     []LocalSource{{Profile: "default", Menu: &fakeMenuDataLoader{snapshot: federationTestSnapshot("default", "me1", session.StatusIdle)}}},
+		Upstreams: []Upstream{{Name: "buildbox", URL: buildbox.URL, Token: "up-secret"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fed.Refresh(context.Background())
+
+	srv := NewServer(Config{ListenAddr: "127.0.0.1:0", Profile: "default", ReadOnly: true, MenuData: fed})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	// Other federating servers only see this server's own profile
+	local := serveWithToken(srv, http.MethodGet, "/api/menu?scope=local", "")
+	if strings.Contains(local.Body.String(), "buildbox") || !strings.Contains(local.Body.String(), `"me1"`) {
+		t.Errorf("scope=local menu = %s", local.Body.String())
+	}
+
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL(ts.URL, "/ws/session/buildbox~work~bb1"), nil)
+	if err != nil {
+		if resp != nil {
+			t.Fatalf("dial failed with status %d: %v", resp.StatusCode, err)
+		}
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var connected wsServerMessage
+	if err := conn.ReadJSON(&connected); err != nil {
+		t.Fatal(err)
+	}
+	if connected.Event != "connected" || connected.SessionID != "buildbox~work~bb1" || connected.Profile != "work" || !connected.ReadOnly {
+		t.Fatalf("connected = %+v", connected)
+	}
+
+	if err := conn.WriteJSON(wsClientMessage{Type: "input", Data: "ls\r"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteJSON(wsClientMessage{Type: "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for !seen["pong"] {
+		var msg wsServerMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("reading proxied messages (seen %v): %v", seen, err)
+		}
+		if msg.SessionID != "buildbox~work~bb1" {
+			t.Errorf("message not rewritten to the federated id: %+v", msg)
+		}
+		seen[msg.Event+msg.Code] = true
+	}
+	if !seen["READ_ONLY"] {
+		t.Errorf("input to a read-only federated server should be refused locally, saw %v", seen)
+	}
+}
+
+func TestFederatedServerAppliesGroupRestrictedTokens(t *testing.T) {
+	other := federationTestSnapshot("personal", "me2", session.StatusIdle)
+	other.Items[0].Group.Name, other.Items[0].Group.Path, other.Items[0].Path = "home", "home", "home"
+	other.Items[1].Session.GroupPath, other.Items[1].Path = "home", "home"
+	fed, err := NewFederation(FederationConfig{Local: []LocalSource{
+		{Profile: "default", Menu: &fakeMenuDataLoader{snapshot: federationTestSnapshot("default", "me1", session.StatusIdle)}},
+		{Profile: "personal", Menu: &fakeMenuDataLoader{snapshot: other}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fed.Refresh(context.Background())
+
+	srv, _ := newTokenTestServer(t, &statedb.WebTokenRow{Name: "ops", Scopes: []string{ScopeRead}, Groups: []string{"ops"}})
+	srv.menuData, srv.federation = fed, fed
+
+	rr := serveWithToken(srv, http.MethodGet, "/api/menu", "secret-ops")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("menu status %d", rr.Code)
+	}
+	var snapshot MenuSnapshot
+	if err := json.Unmarshal(rr.Body.Bytes(), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, item := range snapshot.Items {
+		switch {
+		case item.Session != nil:
+			paths = append(paths, item.Session.ID)
+		case item.Group != nil:
+			paths = append(paths, fmt.Sprintf("%s(%d)", item.Group.Path, item.Group.SessionCount))
+		}
+	}
+	want := "local(1) local/default(1) local/default/ops(1) local~default~me1"
+	if got := strings.Join(paths, " "); got != want || snapshot.TotalSessions != 1 || snapshot.TotalGroups != 3 {
+		t.Fatalf("token limited to ops sees %q (%d sessions, %d groups), want %q", got, snapshot.TotalSessions, snapshot.TotalGroups, want)
+	}
+
+	if rr := serveWithToken(srv, http.MethodGet, "/api/session/local~default~me1", "secret-ops"); rr.Code != http.StatusOK {
+		t.Errorf("session in the token's group: status %d", rr.Code)
+	}
+	if rr := serveWithToken(srv, http.MethodGet, "/api/session/local~personal~me2", "secret-ops"); rr.Code != http.StatusNotFound {
+		t.Errorf("session in another group: status %d, want 404", rr.Code)
+	}
+}
+
+func TestFederatedServerRefusesBinaryClientFrames(t *testing.T) {
+	// The upstream records what reaches its terminal
+	menu := NewMemoryMenuData(nil)
+	menu.SetSnapshot(federationTestSnapshot("work", "bb1", session.StatusRunning))
+	upSrv := NewServer(Config{ListenAddr: "127.0.0.1:0", Profile: "work", Token: "up-secret", MenuData: menu})
+	bridge := &fakeTerminalBridge{done: make(chan struct{})}
+	upSrv.terminals.attach = func(tmuxSession, sessionID string, sink terminalSink) (terminalBridge, error) {
+		bridge.sink = sink
+		return bridge, nil
+	}
+	upstream := httptest.NewServer(upSrv.Handler())
+	defer upstream.Close()
+
+	fed, err := NewFederation(FederationConfig{
+		Local:     []LocalSource{{Profile: "default", Menu: &fakeMenuDataLoader{snapshot: federationTestSnapshot("default", "me1", session.StatusIdle)}}},
+		Upstreams: []Upstream{{Name: "buildbox", URL: upstream.URL, Token: "up-secret"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fed.Refresh(context.Background())
+	srv := NewServer(Config{ListenAddr: "127.0.0.1:0", Profile: "default", ReadOnly: true, MenuData: fed})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	for name, url := range map[string]string{
+		"proxy":    wsURL(ts.URL, "/ws/session/buildbox~work~bb1"),
+		"upstream": wsURL(upstream.URL, "/ws/session/bb1?token=up-secret"),
+	} {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("%s: dial: %v", name, err)
+		}
+		readWSMessage(t, conn, func(msg wsServerMessage) bool { return msg.Event == "connected" })
+
+		// A binary frame holding an input message must not count as input
+		if err := conn.WriteMessage(websocket.BinaryMessage, []byte(`{"type":"input","data":"rm -rf /\r"}`)); err != nil {
+			t.Fatalf("%s: send binary frame: %v", name, err)
+		}
+		if msg := readWSMessage(t, conn, func(msg wsServerMessage) bool { return msg.Type == "error" }); msg.Code != "INVALID_MESSAGE" {
+			t.Errorf("%s: binary frame answered with %+v, want INVALID_MESSAGE", name, msg)
+		}
+		// The upstream handles frames in order: once the ping is answered
+		// the binary frame would have reached the terminal
+		if err := conn.WriteJSON(wsClientMessage{Type: "ping"}); err != nil {
+			t.Fatalf("%s: send ping: %v", name, err)
+		}
+		readWSMessage(t, conn, func(msg wsServerMessage) bool { return msg.Event == "pong" })
+		_ = conn.Close()
+	}
+
+	bridge.mu.Lock()
+	defer bridge.mu.Unlock()
+	if len(bridge.input) != 0 {
+		t.Fatalf("binary frames reached the upstream terminal: %q", bridge.input)
+	}
+}
+
+func TestFederationSessionURL(t *testing.T) {
+	fed, err := NewFederation(FederationConfig{
+		Local:     []LocalSource{{Profile: "default", Menu: &fakeMenuDataLoader{snapshot: federationTestSnapshot("default", "me1", session.StatusIdle)}}},
+		Upstreams: []Upstream{{Name: "buildbox", URL: "https://buildbox:8420/deck/", Token: "up-secret"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := fed.SessionURL("buildbox~work~bb 1"); !ok || got != "https://buildbox:8420/deck/s/bb%201" {
+		t.Errorf("SessionURL = %q, %v", got, ok)
+	}
+	if _, ok := fed.SessionURL("local~default~me1"); ok {
+		t.Error("local sessions have no upstream link")
+	}
+}
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/git"
+)
+
+// sessionDiffResponse is a session's changes against the merge base with
+// its base branch, as shown by the TUI's diff review.
+type sessionDiffResponse struct {
+	Profile   string            `json:"profile"`
+	SessionID string            `json:"sessionId"`
+	Base      string            `json:"base"`
+	MergeBase string            `json:"mergeBase"`
+	Branch    string            `json:"branch,omitempty"`
+	Added     int               `json:"added"`
+	Deleted   int               `json:"deleted"`
+	Files     []sessionDiffFile `json:"files"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+type sessionDiffFile struct {
+	Path    string            `json:"path"`
+	OldPath string            `json:"oldPath,omitempty"`
+	Status  string            `json:"status"`
+	Added   int               `json:"added"`
+	Deleted int               `json:"deleted"`
+	Binary  bool              `json:"binary,omitempty"`
+	Hunks   []sessionDiffHunk `json:"hunks,omitempty"`
+}
+
+type sessionDiffHunk struct {
+	Header string   `json:"header"`
+	Lines  []string `json:"lines"`
+}
+
+// handleSessionDiff serves GET /api/session/{id}/diff[?base=branch]. The
+// base defaults to the repository's default branch.
+func (s *Server) handleSessionDiff(w http.ResponseWriter, r *http.Request, p *principal, sessionID string) {
+	profile, sess, err := s.findMenuSession(sessionID)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load session data")
+		return
+	}
+	if sess == nil || !p.AllowsSession(sess) {
+		writeAPIError(w, http.StatusNotFound, "NOT_FOUND", "session not found")
+		return
+	}
+	if upstream, remoteID, ok := s.remoteSession(sessionID); ok {
+		proxyUpstreamGET(w, r, upstream, "/api/session/"+url.PathEscape(remoteID)+"/diff")
+		return
+	}
+
+	dir := sess.WorktreePath
+	if dir == "" {
+		dir = sess.ProjectPath
+	}
+	if dir == "" || !git.IsGitRepo(dir) {
+		writeAPIError(w, http.StatusUnprocessableEntity, "NOT_A_REPOSITORY", "session directory is not a git repository")
+		return
+	}
+
+	diff, err := git.GetDiff(dir, r.URL.Query().Get("base"))
+	if errors.Is(err, git.ErrInvalidBase) {
+		writeAPIError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, "DIFF_FAILED", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, toSessionDiffResponse(profile, sessionID, diff))
+}
+
+func toSessionDiffResponse(profile, sessionID string, d *git.Diff) sessionDiffResponse {
+	resp := sessionDiffResponse{
+		Profile:   profile,
+		SessionID: sessionID,
+		Base:      d.Base,
+		MergeBase: d.MergeBase,
+		Branch:    d.Branch,
+		Added:     d.Added,
+		Deleted:   d.Deleted,
+		Files:     make([]sessionDiffFile, 0, len(d.Files)),
+		UpdatedAt: d.UpdatedAt,
+	}
+	for _, f := range d.Files {
+		file := sessionDiffFile{
+			Path:    f.Path,
+			OldPath: f.OldPath,
+			Status:  f.Status,
+			Added:   f.Added,
+			Deleted: f.Deleted,
+			Binary:  f.Binary,
+		}
+		for _, h := range f.Hunks {
+			file.Hunks = append(file.Hunks, sessionDiffHunk{Header: h.Header, Lines: h.Lines})
+		}
+		resp.Files = append(resp.Files, file)
+	}
+	return resp
+}
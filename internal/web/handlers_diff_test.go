@@ -0,0 +1,108 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func newDiffTestServer(t *testing.T, projectPath string) *Server {
+	t.Helper()
+	srv := NewServer(Config{ListenAddr: "127.0.0.1:0", Profile: "test-profile"})
+	srv.menuData = &fakeMenuDataLoader{snapshot: &MenuSnapshot{
+		Profile: "test-profile",
+		Items: []MenuItem{{
+			Type:    MenuItemTypeSession,
+			Session: &MenuSession{ID: "sess-1", Title: "demo", ProjectPath: projectPath},
+		}},
+	}}
+	return srv
+}
+
+func TestSessionDiffEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q", "-b", "main")
+	run("config", "user.email", "test@test.com")
+	run("config", "user.name", "Test User")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", ".")
+	run("commit", "-q", "-m", "init")
+	run("checkout", "-q", "-b", "feature")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newDiffTestServer(t, dir)
+	req := httptest.NewRequest(http.MethodGet, "/api/session/sess-1/diff", nil)
+	rr := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var resp sessionDiffResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Base != "main" || resp.Branch != "feature" || resp.SessionID != "sess-1" {
+		t.Errorf("response = %+v", resp)
+	}
+	if len(resp.Files) != 1 || resp.Files[0].Path != "a.txt" || resp.Files[0].Added != 1 || resp.Files[0].Deleted != 1 {
+		t.Fatalf("files = %+v", resp.Files)
+	}
+	if len(resp.Files[0].Hunks) != 1 || len(resp.Files[0].Hunks[0].Lines) != 2 {
+		t.Errorf("hunks = %+v", resp.Files[0].Hunks)
+	}
+
+	// Unknown base branches and option-shaped bases are client errors
+	for _, base := range []string{"nope", "--output=x", "-p"} {
+		req = httptest.NewRequest(http.MethodGet, "/api/session/sess-1/diff?base="+url.QueryEscape(base), nil)
+		rr = httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("base %q: expected status %d, got %d", base, http.StatusBadRequest, rr.Code)
+		}
+	}
+}
+
+func TestSessionDiffEndpointErrors(t *testing.T) {
+	srv := newDiffTestServer(t, t.TempDir())
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/session/missing/diff", http.StatusNotFound},
+		{"/api/session/sess-1/diff", http.StatusUnprocessableEntity}, // Not a repository
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		rr := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d: %s", tt.path, rr.Code, tt.want, rr.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/session/sess-1/diff", nil)
+	rr := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST = %d, want %d", rr.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -22,8 +22,8 @@ func (s *Server) handleMenuEvents(w http.ResponseWriter, r *http.Request) {
 		writeAPIError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "method not allowed")
 		return
 	}
-	if !s.authorizeRequest(r) {
-		writeAPIError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+	p, ok := s.authorize(w, r, ScopeRead)
+	if !ok {
 		return
 	}
 
@@ -33,11 +33,13 @@ func (s *Server) handleMenuEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	snapshot, err := s.menuData.LoadMenuSnapshot()
+	menuData := s.menuSource(r)
+	snapshot, err := menuData.LoadMenuSnapshot()
 	if err != nil {
 		writeAPIError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load menu data")
 		return
 	}
+	snapshot = p.filterSnapshot(snapshot)
 
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -61,12 +63,13 @@ func (s *Server) handleMenuEvents(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 	emitIfChanged := func() error {
-		nextSnapshot, err := s.menuData.LoadMenuSnapshot()
+		nextSnapshot, err := menuData.LoadMenuSnapshot()
 		if err != nil {
 			logging.ForComponent(logging.CompWeb).Error("menu_stream_refresh_failed",
 				slog.String("error", err.Error()))
 			return nil
 		}
+		nextSnapshot = p.filterSnapshot(nextSnapshot)
 
 		nextFingerprint := menuSnapshotFingerprint(nextSnapshot)
 		if nextFingerprint == lastFingerprint {
@@ -0,0 +1,144 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/logging"
+	"github.com/gorilla/websocket"
+)
+
+// proxySessionWS relays a terminal websocket to the upstream serving a
+// federated session. This server's read-only mode and the token's scopes
+// still apply: input the caller may not send never reaches the upstream.
+func (s *Server) proxySessionWS(w http.ResponseWriter, r *http.Request, p *principal, sess *MenuSession, upstream *federationUpstream, remoteID string) {
+	sessionID := sess.ID
+	viewer := strings.TrimSpace(r.URL.Query().Get("viewer"))
+	if viewer == "" {
+		viewer = p.Name
+	}
+	upConn, err := upstream.dialSession(r.Context(), remoteID, viewer)
+	if err != nil {
+		logging.ForComponent(logging.CompWeb).Warn("federation_ws_dial_failed",
+			slog.String("upstream", upstream.cfg.Name),
+			slog.String("session_id", sessionID),
+			slog.String("error", err.Error()))
+		writeAPIError(w, http.StatusBadGateway, "UPSTREAM_UNAVAILABLE", "upstream "+upstream.cfg.Name+" is unreachable: "+err.Error())
+		return
+	}
+	defer upConn.Close()
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	writer := newWSConnWriter(conn)
+	inputAllowed := !s.cfg.ReadOnly && p.Can(ScopeTerminalInput)
+	input := terminalInputAudit{server: s, principal: p, request: r, sessionID: sessionID}
+	defer input.finish()
+
+	// Upstream to browser: terminal output as-is, status messages with the
+	// federated session ID and this server's input rights.
+	go func() {
+		defer conn.Close()
+		for {
+			msgType, data, err := upConn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if msgType == websocket.TextMessage {
+				var msg wsServerMessage
+				if json.Unmarshal(data, &msg) == nil {
+					if msg.SessionID != "" {
+						msg.SessionID = sessionID
+					}
+					if msg.Event == "connected" {
+						msg.ReadOnly = msg.ReadOnly || !inputAllowed
+					}
+					if writer.WriteJSON(msg) != nil {
+						return
+					}
+					continue
+				}
+			}
+			if writer.WriteBinary(data) != nil {
+				return
+			}
+		}
+	}()
+
+	// Browser to upstream.
+	for {
+		msgType, payload, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		// Only JSON text frames are relayed, so every message the upstream
+		// acts on has been checked here
+		var msg wsClientMessage
+		if msgType != websocket.TextMessage || json.Unmarshal(payload, &msg) != nil {
+			_ = writer.WriteJSON(wsServerMessage{
+				Type:      "error",
+				Code:      "INVALID_MESSAGE",
+				Message:   "client messages must be JSON text frames",
+				SessionID: sessionID,
+				Time:      time.Now().UTC(),
+			})
+			continue
+		}
+		if msg.Type == "input" || msg.Type == "lock" {
+			if !inputAllowed {
+				code, message := "READ_ONLY", "input is disabled in read-only mode"
+				if !s.cfg.ReadOnly {
+					code, message = "FORBIDDEN", "token lacks the "+ScopeTerminalInput+" scope"
+				}
+				_ = writer.WriteJSON(wsServerMessage{
+					Type:      "error",
+					Code:      code,
+					Message:   message,
+					SessionID: sessionID,
+					Time:      time.Now().UTC(),
+				})
+				continue
+			}
+			if msg.Type == "input" {
+				input.record(len(msg.Data))
+			}
+		}
+		_ = upConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if err := upConn.WriteMessage(msgType, payload); err != nil {
+			return
+		}
+	}
+}
+
+// proxyUpstreamGET relays a GET request for a federated session to its
+// upstream, keeping the request's query.
+func proxyUpstreamGET(w http.ResponseWriter, r *http.Request, upstream *federationUpstream, path string) {
+	query := r.URL.Query()
+	query.Del("token") // This server's credentials stay here
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.endpoint(path, query), nil)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to build upstream request")
+		return
+	}
+	upstream.authorize(req.Header)
+	resp, err := upstream.client.Do(req)
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, "UPSTREAM_UNAVAILABLE", "upstream "+upstream.cfg.Name+" is unreachable: "+err.Error())
+		return
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
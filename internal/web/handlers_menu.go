@@ -28,18 +28,18 @@ func (s *Server) handleMenu(w http.ResponseWriter, r *http.Request) {
 		writeAPIError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "method not allowed")
 		return
 	}
-	if !s.authorizeRequest(r) {
-		writeAPIError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+	p, ok := s.authorize(w, r, ScopeRead)
+	if !ok {
 		return
 	}
 
-	snapshot, err := s.menuData.LoadMenuSnapshot()
+	snapshot, err := s.menuSource(r).LoadMenuSnapshot()
 	if err != nil {
 		writeAPIError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load menu data")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, snapshot)
+	writeJSON(w, http.StatusOK, p.filterSnapshot(snapshot))
 }
 
 func (s *Server) handleSessionByID(w http.ResponseWriter, r *http.Request) {
@@ -47,8 +47,8 @@ func (s *Server) handleSessionByID(w http.ResponseWriter, r *http.Request) {
 		writeAPIError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "method not allowed")
 		return
 	}
-	if !s.authorizeRequest(r) {
-		writeAPIError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+	p, ok := s.authorize(w, r, ScopeRead)
+	if !ok {
 		return
 	}
 
@@ -58,6 +58,10 @@ func (s *Server) handleSessionByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	sessionID := strings.TrimPrefix(r.URL.Path, prefix)
+	if id, ok := strings.CutSuffix(sessionID, "/diff"); ok && id != "" && !strings.Contains(id, "/") {
+		s.handleSessionDiff(w, r, p, id)
+		return
+	}
 	if sessionID == "" || strings.Contains(sessionID, "/") {
 		writeAPIError(w, http.StatusBadRequest, "INVALID_REQUEST", "session id is required")
 		return
@@ -73,7 +77,7 @@ func (s *Server) handleSessionByID(w http.ResponseWriter, r *http.Request) {
 		if item.Type != MenuItemTypeSession || item.Session == nil {
 			continue
 		}
-		if item.Session.ID != sessionID {
+		if item.Session.ID != sessionID || !p.AllowsSession(item.Session) {
 			continue
 		}
 
@@ -90,6 +94,21 @@ func (s *Server) handleSessionByID(w http.ResponseWriter, r *http.Request) {
 	writeAPIError(w, http.StatusNotFound, "NOT_FOUND", "session not found")
 }
 
+// findMenuSession returns the profile and session with the given ID from the
+// current menu snapshot (nil when it doesn't exist).
+func (s *Server) findMenuSession(sessionID string) (string, *MenuSession, error) {
+	snapshot, err := s.menuData.LoadMenuSnapshot()
+	if err != nil {
+		return "", nil, err
+	}
+	for _, item := range snapshot.Items {
+		if item.Type == MenuItemTypeSession && item.Session != nil && item.Session.ID == sessionID {
+			return snapshot.Profile, item.Session, nil
+		}
+	}
+	return snapshot.Profile, nil, nil
+}
+
 func writeJSON(w http.ResponseWriter, status int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,141 @@
+package web
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/logging"
+	"github.com/asheshgoplani/agent-deck/internal/metrics"
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+// pushDeliveries counts web push deliveries by kind (transition, test) and
+// result (sent, expired, failed, suppressed).
+var pushDeliveries = metrics.NewCounterVec("agentdeck_push_deliveries",
+	"Web push deliveries by kind and result.", "kind", "result")
+
+// recordPushDelivery counts one Send result. Subscriptions the push service
+// reports as gone are counted as expired.
+func recordPushDelivery(kind string, statusCode int, err error) {
+	switch {
+	case err == nil:
+		pushDeliveries.Inc(kind, "sent")
+	case statusCode == http.StatusGone || statusCode == http.StatusNotFound:
+		pushDeliveries.Inc(kind, "expired")
+	default:
+		pushDeliveries.Inc(kind, "failed")
+	}
+}
+
+// handleMetrics serves the process metrics plus per-session gauges, for
+// tokens with the read scope. Group-restricted tokens only see their
+// groups' sessions.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "method not allowed")
+		return
+	}
+	p, ok := s.authorize(w, r, ScopeRead)
+	if !ok {
+		return
+	}
+	serveMetrics(w, r, s.menuData, p)
+}
+
+// NewMetricsHandler returns a /metrics handler for the standalone metrics
+// listener used when the web server isn't running. With a token, scrapes
+// must send it as a Bearer Authorization header.
+func NewMetricsHandler(menuData MenuDataLoader, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if token != "" && !secureEqual(bearerToken(r.Header.Get("Authorization")), token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="agent-deck metrics"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		serveMetrics(w, r, menuData, nil)
+	})
+	return withRecover(mux)
+}
+
+// NewMetricsServer returns an HTTP server for NewMetricsHandler on the
+// [metrics] listen address. Metrics name sessions and their costs, so an
+// address other machines can reach needs a token.
+func NewMetricsServer(settings session.MetricsSettings, menuData MenuDataLoader) (*http.Server, error) {
+	token := settings.Token
+	if settings.TokenEnv != "" {
+		token = os.Getenv(settings.TokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("metrics: $%s is not set", settings.TokenEnv)
+		}
+	}
+	if token == "" && !isLoopbackAddr(settings.Listen) {
+		return nil, fmt.Errorf("metrics: refusing to listen on %s without a token; use a loopback address or set [metrics] token", settings.Listen)
+	}
+	return &http.Server{
+		Addr:              settings.Listen,
+		Handler:           NewMetricsHandler(menuData, token),
+		ReadHeaderTimeout: 5 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}, nil
+}
+
+// isLoopbackAddr reports whether a listen address only accepts connections
+// from this machine. An empty host listens on every interface.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func serveMetrics(w http.ResponseWriter, r *http.Request, menuData MenuDataLoader, p *principal) {
+	var snapshot *MenuSnapshot
+	if menuData != nil {
+		var err error
+		if snapshot, err = menuData.LoadMenuSnapshot(); err != nil {
+			logging.ForComponent(logging.CompWeb).Warn("metrics_snapshot_failed", slog.String("error", err.Error()))
+		}
+	}
+	if p != nil {
+		snapshot = p.filterSnapshot(snapshot)
+	}
+	metrics.Default.ServeHTTP(w, r, sessionGauges(snapshot, time.Now())...)
+}
+
+// sessionGauges reports session counts by status, tool and group, and how
+// long each waiting session has been waiting.
+func sessionGauges(snapshot *MenuSnapshot, now time.Time) []*metrics.Gauge {
+	sessions := metrics.NewGauge("agentdeck_sessions",
+		"Sessions by status, tool and group.", "status", "tool", "group")
+	waiting := metrics.NewGauge("agentdeck_session_waiting_seconds",
+		"Seconds each waiting session has been waiting for input.", "session_id", "title", "tool", "group")
+	if snapshot == nil {
+		return []*metrics.Gauge{sessions, waiting}
+	}
+
+	for _, item := range snapshot.Items {
+		if item.Type != MenuItemTypeSession || item.Session == nil {
+			continue
+		}
+		sess := item.Session
+		sessions.Add(1, string(sess.Status), sess.Tool, sess.GroupPath)
+		if !sess.WaitingSince.IsZero() {
+			waiting.Set(now.Sub(sess.WaitingSince).Seconds(), sess.ID, sess.Title, sess.Tool, sess.GroupPath)
+		}
+	}
+	return []*metrics.Gauge{sessions, waiting}
+}
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/session"
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+)
+
+func TestMetricsEndpointReportsSessions(t *testing.T) {
+	srv, _ := newTokenTestServer(t,
+		&statedb.WebTokenRow{Name: "prom", Scopes: []string{ScopeRead}},
+		&statedb.WebTokenRow{Name: "acme", Scopes: []string{ScopeRead}, Groups: []string{"clients/acme"}},
+	)
+	snapshot := srv.menuData.(*fakeMenuDataLoader).snapshot
+	snapshot.Items[2].Session.Status = session.StatusWaiting
+	snapshot.Items[2].Session.Tool = "claude"
+	snapshot.Items[2].Session.Title = "acme api"
+	snapshot.Items[2].Session.WaitingSince = time.Now().Add(-90 * time.Second)
+	snapshot.Items[4].Session.Status = session.StatusRunning
+	snapshot.Items[4].Session.Tool = "codex"
+
+	if rr := serveWithToken(srv, http.MethodGet, "/metrics", ""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated scrape: status %d, want 401", rr.Code)
+	}
+
+	rr := serveWithToken(srv, http.MethodGet, "/metrics", "secret-prom")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("scrape status %d", rr.Code)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		`agentdeck_sessions{status="waiting",tool="claude",group="clients/acme"} 1`,
+		`agentdeck_sessions{status="running",tool="codex",group="clients/beta"} 1`,
+		`agentdeck_session_waiting_seconds{session_id="sess-acme",title="acme api",tool="claude",group="clients/acme"} 90`,
+		"# TYPE agentdeck_tmux_capture_pane_total counter",
+		"# TYPE agentdeck_statedb_write_duration_seconds histogram",
+		"# TYPE agentdeck_mcp_pool_servers gauge",
+		"# TYPE agentdeck_push_deliveries_total counter",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in scrape:\n%s", want, body)
+		}
+	}
+
+	rr = serveWithToken(srv, http.MethodGet, "/metrics", "secret-acme")
+	if strings.Contains(rr.Body.String(), `group="clients/beta"`) {
+		t.Errorf("group-restricted token sees other groups' sessions:\n%s", rr.Body.String())
+	}
+}
+
+func TestStandaloneMetricsHandler(t *testing.T) {
+	handler := NewMetricsHandler(&fakeMenuDataLoader{snapshot: &MenuSnapshot{}}, "")
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d", rr.Code)
+	}
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "application/openmetrics-text") {
+		t.Errorf("content type = %q", rr.Header().Get("Content-Type"))
+	}
+	if !strings.HasSuffix(rr.Body.String(), "# EOF\n") {
+		t.Errorf("OpenMetrics body should end with # EOF")
+	}
+}
+
+func TestStandaloneMetricsRequiresTokenBeyondLoopback(t *testing.T) {
+	menu := &fakeMenuDataLoader{snapshot: &MenuSnapshot{}}
+	for _, addr := range []string{"127.0.0.1:9464", "localhost:9464", "[::1]:9464"} {
+		if _, err := NewMetricsServer(session.MetricsSettings{Listen: addr}, menu); err != nil {
+			t.Errorf("%s without a token: %v", addr, err)
+		}
+	}
+	for _, addr := range []string{":9464", "0.0.0.0:9464", "192.168.1.5:9464"} {
+		if _, err := NewMetricsServer(session.MetricsSettings{Listen: addr}, menu); err == nil {
+			t.Errorf("%s without a token should be refused", addr)
+		}
+	}
+	t.Setenv("METRICS_TEST_TOKEN", "")
+	if _, err := NewMetricsServer(session.MetricsSettings{Listen: ":9464", TokenEnv: "METRICS_TEST_TOKEN"}, menu); err == nil {
+		t.Error("an unset token_env should be refused")
+	}
+
+	t.Setenv("METRICS_TEST_TOKEN", "scrape-secret")
+	server, err := NewMetricsServer(session.MetricsSettings{Listen: ":9464", TokenEnv: "METRICS_TEST_TOKEN"}, menu)
+	if err != nil {
+		t.Fatalf("with a token: %v", err)
+	}
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "scrape-secret": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		server.Handler.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("token %q: status %d, want %d", token, rr.Code, want)
+		}
+	}
+}
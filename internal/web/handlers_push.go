@@ -32,8 +32,8 @@ func (s *Server) handlePushConfig(w http.ResponseWriter, r *http.Request) {
 		writeAPIError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "method not allowed")
 		return
 	}
-	if !s.authorizeRequest(r) {
-		writeAPIError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+	_, ok := s.authorize(w, r, ScopeRead)
+	if !ok {
 		return
 	}
 
@@ -59,8 +59,13 @@ func (s *Server) handlePushSubscribe(w http.ResponseWriter, r *http.Request) {
 		writeAPIError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "method not allowed")
 		return
 	}
-	if !s.authorizeRequest(r) {
-		writeAPIError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+	p, ok := s.authorize(w, r, ScopeRead)
+	if !ok {
+		return
+	}
+	if len(p.Groups) > 0 {
+		// Notifications cover every session of the profile
+		writeAPIError(w, http.StatusForbidden, "FORBIDDEN", "push notifications are not available to group-restricted tokens")
 		return
 	}
 	if s.push == nil || !s.push.Enabled() {
@@ -95,8 +100,8 @@ func (s *Server) handlePushUnsubscribe(w http.ResponseWriter, r *http.Request) {
 		writeAPIError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "method not allowed")
 		return
 	}
-	if !s.authorizeRequest(r) {
-		writeAPIError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+	_, ok := s.authorize(w, r, ScopeRead)
+	if !ok {
 		return
 	}
 	if s.push == nil || !s.push.Enabled() {
@@ -128,8 +133,8 @@ func (s *Server) handlePushPresence(w http.ResponseWriter, r *http.Request) {
 		writeAPIError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "method not allowed")
 		return
 	}
-	if !s.authorizeRequest(r) {
-		writeAPIError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+	_, ok := s.authorize(w, r, ScopeRead)
+	if !ok {
 		return
 	}
 	if s.push == nil || !s.push.Enabled() {
@@ -3,6 +3,7 @@ package web
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/url"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/asheshgoplani/agent-deck/internal/logging"
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
 	"github.com/gorilla/websocket"
 )
 
@@ -21,14 +23,19 @@ type wsClientMessage struct {
 }
 
 type wsServerMessage struct {
-	Type      string    `json:"type"` // status, error
-	Event     string    `json:"event,omitempty"`
-	Code      string    `json:"code,omitempty"`
-	Message   string    `json:"message,omitempty"`
-	SessionID string    `json:"sessionId,omitempty"`
-	Profile   string    `json:"profile,omitempty"`
-	ReadOnly  bool      `json:"readOnly,omitempty"`
-	Time      time.Time `json:"time,omitempty"`
+	Type       string     `json:"type"` // status, error
+	Event      string     `json:"event,omitempty"`
+	Code       string     `json:"code,omitempty"`
+	Message    string     `json:"message,omitempty"`
+	SessionID  string     `json:"sessionId,omitempty"`
+	Profile    string     `json:"profile,omitempty"`
+	ReadOnly   bool       `json:"readOnly,omitempty"`
+	ViewerID   string     `json:"viewerId,omitempty"`   // this connection, among a shared terminal's viewers
+	Viewers    []wsViewer `json:"viewers,omitempty"`    // everyone watching (presence events)
+	LockHolder string     `json:"lockHolder,omitempty"` // viewer ID holding the input lock
+	Cols       int        `json:"cols,omitempty"`
+	Rows       int        `json:"rows,omitempty"`
+	Time       time.Time  `json:"time,omitempty"`
 }
 
 var wsUpgrader = websocket.Upgrader{
@@ -57,13 +64,22 @@ func (s *Server) handleSessionWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !s.authorizeRequest(r) {
-		writeAPIError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+	const prefix = "/ws/session/"
+	sessionID := strings.TrimPrefix(r.URL.Path, prefix)
+
+	// A share link grants access to its one session instead of a token
+	var p *principal
+	var share *statedb.WebShareRow
+	var ok bool
+	if r.URL.Query().Has("share") {
+		p, share, ok = s.authorizeShare(w, r, sessionID)
+	} else {
+		p, ok = s.authorize(w, r, ScopeRead)
+	}
+	if !ok {
 		return
 	}
 
-	const prefix = "/ws/session/"
-	sessionID := strings.TrimPrefix(r.URL.Path, prefix)
 	if sessionID == "" || strings.Contains(sessionID, "/") {
 		writeAPIError(w, http.StatusBadRequest, "INVALID_REQUEST", "session id is required")
 		return
@@ -75,11 +91,19 @@ func (s *Server) handleSessionWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if share != nil && s.federation != nil {
+		// Shares name the session by its ID in this profile
+		sessionID = federatedSessionID(FederationLocalHost, s.cfg.Profile, sessionID)
+	}
 	menuSession, found := snapshotSessionByID(snapshot, sessionID)
-	if !found {
+	if !found || !p.AllowsSession(menuSession) {
 		writeAPIError(w, http.StatusNotFound, "NOT_FOUND", "session not found")
 		return
 	}
+	if upstream, remoteID, ok := s.remoteSession(sessionID); ok {
+		s.proxySessionWS(w, r, p, menuSession, upstream, remoteID)
+		return
+	}
 
 	conn, err := wsUpgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -88,13 +112,31 @@ func (s *Server) handleSessionWS(w http.ResponseWriter, r *http.Request) {
 	defer conn.Close()
 
 	writer := newWSConnWriter(conn)
+	if share != nil {
+		viewer := s.trackShareViewer(share, r, func(reason string) {
+			_ = writer.WriteJSON(wsServerMessage{
+				Type:      "status",
+				Event:     "share_ended",
+				Message:   reason,
+				SessionID: sessionID,
+				Time:      time.Now().UTC(),
+			})
+			_ = conn.Close()
+		})
+		defer viewer.stop()
+	}
+	inputAllowed := !s.cfg.ReadOnly && p.Can(ScopeTerminalInput)
+	input := terminalInputAudit{server: s, principal: p, request: r, sessionID: sessionID}
+	defer input.finish()
+	viewer := s.terminals.newViewer(r.URL.Query().Get("viewer"), p, inputAllowed, writer, func() { _ = conn.Close() })
 
 	_ = writer.WriteJSON(wsServerMessage{
 		Type:      "status",
 		Event:     "connected",
 		SessionID: sessionID,
 		Profile:   snapshot.Profile,
-		ReadOnly:  s.cfg.ReadOnly,
+		ReadOnly:  !inputAllowed,
+		ViewerID:  viewer.id,
 		Time:      time.Now().UTC(),
 	})
 	_ = writer.WriteJSON(wsServerMessage{
@@ -104,9 +146,9 @@ func (s *Server) handleSessionWS(w http.ResponseWriter, r *http.Request) {
 		Time:      time.Now().UTC(),
 	})
 
-	var bridge *tmuxPTYBridge
+	var terminal *sharedTerminal
 	if menuSession.TmuxSession != "" {
-		bridge, err = newTmuxPTYBridge(menuSession.TmuxSession, sessionID, writer)
+		terminal, err = s.terminals.join(sessionID, menuSession.TmuxSession, viewer)
 		if err != nil {
 			logging.ForComponent(logging.CompWeb).Error("terminal_attach_failed",
 				slog.String("session_id", sessionID),
@@ -126,7 +168,7 @@ func (s *Server) handleSessionWS(w http.ResponseWriter, r *http.Request) {
 				Time:      time.Now().UTC(),
 			})
 		} else {
-			defer bridge.Close()
+			defer terminal.leave(viewer)
 			_ = writer.WriteJSON(wsServerMessage{
 				Type:      "status",
 				Event:     "terminal_attached",
@@ -136,8 +178,32 @@ func (s *Server) handleSessionWS(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// canType reports whether this connection may type, telling the client
+	// why not otherwise
+	canType := func() bool {
+		code, message := "", ""
+		switch {
+		case s.cfg.ReadOnly:
+			code, message = "READ_ONLY", "input is disabled in read-only mode"
+		case !p.Can(ScopeTerminalInput):
+			code, message = "FORBIDDEN", "token lacks the "+ScopeTerminalInput+" scope"
+		case terminal == nil:
+			code, message = "NO_TERMINAL_BRIDGE", "terminal bridge is not attached"
+		default:
+			return true
+		}
+		_ = writer.WriteJSON(wsServerMessage{
+			Type:      "error",
+			Code:      code,
+			Message:   message,
+			SessionID: sessionID,
+			Time:      time.Now().UTC(),
+		})
+		return false
+	}
+
 	for {
-		_, payload, err := conn.ReadMessage()
+		msgType, payload, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(
 				err,
@@ -152,6 +218,18 @@ func (s *Server) handleSessionWS(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Client messages are JSON text frames; binary ones are refused so
+		// they can't slip past a proxy that only inspects text
+		if msgType != websocket.TextMessage {
+			_ = writer.WriteJSON(wsServerMessage{
+				Type:      "error",
+				Code:      "INVALID_MESSAGE",
+				Message:   "client messages must be text frames",
+				SessionID: sessionID,
+				Time:      time.Now().UTC(),
+			})
+			continue
+		}
 		var msg wsClientMessage
 		if err := json.Unmarshal(payload, &msg); err != nil {
 			_ = writer.WriteJSON(wsServerMessage{
@@ -173,37 +251,47 @@ func (s *Server) handleSessionWS(w http.ResponseWriter, r *http.Request) {
 				Time:      time.Now().UTC(),
 			})
 		case "input":
-			if s.cfg.ReadOnly {
-				_ = writer.WriteJSON(wsServerMessage{
-					Type:      "error",
-					Code:      "READ_ONLY",
-					Message:   "input is disabled in read-only mode",
-					SessionID: sessionID,
-					Time:      time.Now().UTC(),
-				})
+			if !canType() {
 				continue
 			}
-			if bridge == nil {
+			if err := terminal.writeInput(viewer, msg.Data); err != nil {
+				code, message := "INPUT_WRITE_FAILED", "failed to send input to terminal"
+				var locked *errInputLocked
+				switch {
+				case errors.As(err, &locked):
+					code, message = "INPUT_LOCKED", err.Error()
+				case errors.Is(err, errInputLockRequired):
+					code, message = "INPUT_LOCK_REQUIRED", err.Error()
+				}
 				_ = writer.WriteJSON(wsServerMessage{
 					Type:      "error",
-					Code:      "NO_TERMINAL_BRIDGE",
-					Message:   "terminal bridge is not attached",
+					Code:      code,
+					Message:   message,
 					SessionID: sessionID,
 					Time:      time.Now().UTC(),
 				})
 				continue
 			}
-			if err := bridge.WriteInput(msg.Data); err != nil {
+			input.record(len(msg.Data))
+		case "lock":
+			if !canType() {
+				continue
+			}
+			if err := terminal.lock(viewer); err != nil {
 				_ = writer.WriteJSON(wsServerMessage{
 					Type:      "error",
-					Code:      "INPUT_WRITE_FAILED",
-					Message:   "failed to send input to terminal",
+					Code:      "INPUT_LOCKED",
+					Message:   err.Error(),
 					SessionID: sessionID,
 					Time:      time.Now().UTC(),
 				})
 			}
+		case "unlock":
+			if terminal != nil {
+				terminal.unlock(viewer)
+			}
 		case "resize":
-			if bridge == nil {
+			if terminal == nil {
 				_ = writer.WriteJSON(wsServerMessage{
 					Type:      "error",
 					Code:      "NO_TERMINAL_BRIDGE",
@@ -213,7 +301,7 @@ func (s *Server) handleSessionWS(w http.ResponseWriter, r *http.Request) {
 				})
 				continue
 			}
-			if err := bridge.Resize(msg.Cols, msg.Rows); err != nil {
+			if err := terminal.resize(viewer, msg.Cols, msg.Rows); err != nil {
 				_ = writer.WriteJSON(wsServerMessage{
 					Type:      "error",
 					Code:      "RESIZE_FAILED",
@@ -226,7 +314,7 @@ func (s *Server) handleSessionWS(w http.ResponseWriter, r *http.Request) {
 			_ = writer.WriteJSON(wsServerMessage{
 				Type:      "error",
 				Code:      "UNSUPPORTED_MESSAGE",
-				Message:   "supported message types: ping,input,resize",
+				Message:   "supported message types: ping,input,lock,unlock,resize",
 				SessionID: sessionID,
 				Time:      time.Now().UTC(),
 			})
@@ -248,3 +336,46 @@ func snapshotSessionByID(snapshot *MenuSnapshot, sessionID string) (*MenuSession
 	}
 	return nil, false
 }
+
+// terminalInputAudit records a websocket connection's terminal input in the
+// audit log: once when the first input is sent, and once with totals when
+// the connection closes.
+type terminalInputAudit struct {
+	server    *Server
+	principal *principal
+	request   *http.Request
+	sessionID string
+
+	started  time.Time
+	messages int
+	bytes    int
+}
+
+func (a *terminalInputAudit) record(n int) {
+	if a.messages == 0 {
+		a.started = time.Now()
+		a.write("terminal.input.start", "")
+	}
+	a.messages++
+	a.bytes += n
+}
+
+func (a *terminalInputAudit) finish() {
+	if a.messages == 0 {
+		return
+	}
+	a.write("terminal.input.end", fmt.Sprintf("%d bytes in %d messages over %s",
+		a.bytes, a.messages, time.Since(a.started).Round(time.Second)))
+}
+
+func (a *terminalInputAudit) write(action, detail string) {
+	a.server.recordAudit(&statedb.WebAuditRow{
+		TokenName:  a.principal.Name,
+		Action:     action,
+		Method:     a.request.Method,
+		Path:       a.request.URL.Path,
+		SessionID:  a.sessionID,
+		RemoteAddr: a.request.RemoteAddr,
+		Detail:     detail,
+	})
+}
@@ -83,6 +83,76 @@ func TestWSEndpointTmuxBridgeIntegration(t *testing.T) {
 	}
 }
 
+func TestWSEndpointSharesOneTmuxAttach(t *testing.T) {
+	requireTmuxForWebIntegration(t)
+
+	sessionName := fmt.Sprintf("agentdeck_web_share_%d", time.Now().UnixNano())
+	if output, err := exec.Command("tmux", "new-session", "-d", "-s", sessionName).CombinedOutput(); err != nil {
+		t.Skipf("tmux new-session unavailable: %v (%s)", err, strings.TrimSpace(string(output)))
+	}
+	defer func() {
+		_ = exec.Command("tmux", "kill-session", "-t", sessionName).Run()
+	}()
+
+	srv := NewServer(Config{
+		ListenAddr: "127.0.0.1:0",
+		Profile:    "work",
+	})
+	srv.menuData = &fakeMenuDataLoader{
+		snapshot: &MenuSnapshot{
+			Profile: "work",
+			Items: []MenuItem{
+				{
+					Type: MenuItemTypeSession,
+					Session: &MenuSession{
+						ID:          "sess-it",
+						TmuxSession: sessionName,
+					},
+				},
+			},
+		},
+	}
+
+	testServer := httptest.NewServer(srv.Handler())
+	defer testServer.Close()
+
+	var conns []*websocket.Conn
+	for i := 0; i < 2; i++ {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL(testServer.URL, "/ws/session/sess-it"), nil)
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer conn.Close()
+		waitForStatusOrSkipOnAttachFailure(t, conn, "terminal_attached")
+		conns = append(conns, conn)
+	}
+
+	output, err := exec.Command("tmux", "list-clients", "-t", sessionName).Output()
+	if err != nil {
+		t.Fatalf("tmux list-clients: %v", err)
+	}
+	if clients := strings.Count(strings.TrimSpace(string(output)), "\n") + 1; clients != 1 {
+		t.Fatalf("expected one tmux client for two viewers, got %d:\n%s", clients, output)
+	}
+
+	// Another viewer is watching, so typing needs the input lock
+	if err := conns[0].WriteJSON(wsClientMessage{Type: "lock"}); err != nil {
+		t.Fatalf("failed to send lock message: %v", err)
+	}
+	marker := fmt.Sprintf("ADWEB_SHARED_%d", time.Now().UnixNano())
+	if err := conns[0].WriteJSON(wsClientMessage{
+		Type: "input",
+		Data: fmt.Sprintf("printf '%s\\n'\r", marker),
+	}); err != nil {
+		t.Fatalf("failed to send input message: %v", err)
+	}
+	for i, conn := range conns {
+		if received, err := readBinaryUntilContains(conn, marker, 8*time.Second); err != nil {
+			t.Fatalf("viewer %d did not observe marker: %v\nstream_excerpt=%q", i, err, trimForError(received, 350))
+		}
+	}
+}
+
 func requireTmuxForWebIntegration(t *testing.T) {
 	t.Helper()
 
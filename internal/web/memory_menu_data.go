@@ -5,13 +5,17 @@ import (
 	"sync"
 	"time"
 
+	"github.com/asheshgoplani/agent-deck/internal/git"
 	"github.com/asheshgoplani/agent-deck/internal/session"
 )
 
-// MenuSessionState is a lightweight status/tool update for one session.
+// MenuSessionState is a lightweight status/tool/git update for one session.
 type MenuSessionState struct {
 	Status session.Status
 	Tool   string
+	Git    *git.Status
+	// WaitingSince is when the session started waiting (zero unless waiting)
+	WaitingSince time.Time
 }
 
 // MemoryMenuData is an in-memory menu snapshot store used by web mode.
@@ -61,7 +65,7 @@ func (m *MemoryMenuData) SetSnapshot(snapshot *MenuSnapshot) {
 	m.mu.Unlock()
 }
 
-// UpdateSessionStates updates status/tool fields in-place for existing sessions.
+// UpdateSessionStates updates status/tool/git fields in-place for existing sessions.
 func (m *MemoryMenuData) UpdateSessionStates(states map[string]MenuSessionState, generatedAt time.Time) {
 	if m == nil || len(states) == 0 {
 		return
@@ -88,6 +92,8 @@ func (m *MemoryMenuData) UpdateSessionStates(states map[string]MenuSessionState,
 		if state.Tool != "" {
 			item.Session.Tool = state.Tool
 		}
+		item.Session.Git = toMenuGitStatus(state.Git)
+		item.Session.WaitingSince = state.WaitingSince
 	}
 
 	if generatedAt.IsZero() {
@@ -103,6 +109,7 @@ func cloneMenuSnapshot(snapshot *MenuSnapshot) *MenuSnapshot {
 
 	cloned := *snapshot
 	cloned.Items = make([]MenuItem, len(snapshot.Items))
+	cloned.Sources = append([]FederationSourceStatus(nil), snapshot.Sources...)
 
 	for i, item := range snapshot.Items {
 		cloned.Items[i] = item
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/asheshgoplani/agent-deck/internal/git"
 	"github.com/asheshgoplani/agent-deck/internal/session"
 )
 
@@ -102,3 +103,35 @@ func TestMemoryMenuData_UpdateSessionStates(t *testing.T) {
 		t.Fatalf("generatedAt = %s, want %s", snapshot.GeneratedAt, ts)
 	}
 }
+
+func TestMemoryMenuData_UpdateSessionStatesGit(t *testing.T) {
+	store := NewMemoryMenuData(nil)
+	store.SetSnapshot(&MenuSnapshot{
+		Items: []MenuItem{
+			{Type: MenuItemTypeSession, Session: &MenuSession{ID: "sess-1", Status: session.StatusIdle}},
+		},
+	})
+
+	store.UpdateSessionStates(map[string]MenuSessionState{
+		"sess-1": {
+			Status: session.StatusRunning,
+			Git:    &git.Status{Branch: "feature", Ahead: 2, Unstaged: 3, LinesAdded: 40, LinesDeleted: 5},
+		},
+	}, time.Time{})
+
+	snapshot, err := store.LoadMenuSnapshot()
+	if err != nil {
+		t.Fatalf("LoadMenuSnapshot() error = %v", err)
+	}
+	got := snapshot.Items[0].Session.Git
+	if got == nil || got.Branch != "feature" || got.Ahead != 2 || got.Unstaged != 3 || got.LinesAdded != 40 || got.LinesDeleted != 5 {
+		t.Fatalf("git = %+v", got)
+	}
+
+	// A session that left its repository drops the git summary
+	store.UpdateSessionStates(map[string]MenuSessionState{"sess-1": {Status: session.StatusRunning}}, time.Time{})
+	snapshot, _ = store.LoadMenuSnapshot()
+	if snapshot.Items[0].Session.Git != nil {
+		t.Fatalf("git = %+v, want nil", snapshot.Items[0].Session.Git)
+	}
+}
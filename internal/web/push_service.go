@@ -598,9 +598,11 @@ func (p *pushService) notifySubscribers(ctx context.Context, tr pushTransition)
 				slog.String("status", tr.Status),
 				slog.String("reason", "focused_state"),
 				slog.String("state", focusStateForLog(sub)))
+			pushDeliveries.Inc("transition", "suppressed")
 			continue
 		}
 		statusCode, err := p.sender.Send(payload, sub)
+		recordPushDelivery("transition", statusCode, err)
 		if err == nil {
 			pushLog.Debug("push_sent",
 				slog.String("endpoint", endpointForLog(sub.Endpoint)),
@@ -661,9 +663,11 @@ func (p *pushService) sendTestPush(ctx context.Context) {
 				slog.String("endpoint", endpointForLog(sub.Endpoint)),
 				slog.String("reason", "focused_state"),
 				slog.String("state", focusStateForLog(sub)))
+			pushDeliveries.Inc("test", "suppressed")
 			continue
 		}
 		statusCode, err := p.sender.Send(payload, sub)
+		recordPushDelivery("test", statusCode, err)
 		if err == nil {
 			pushLog.Debug("push_test_sent",
 				slog.String("endpoint", endpointForLog(sub.Endpoint)),
@@ -2,6 +2,7 @@ package web
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,11 +18,19 @@ import (
 
 // Config defines runtime options for the web server.
 type Config struct {
-	ListenAddr          string
-	Profile             string
-	ReadOnly            bool
-	Token               string
-	MenuData            MenuDataLoader
+	ListenAddr string
+	Profile    string
+	ReadOnly   bool
+	Token      string
+	// Tokens holds named, scoped access tokens and the audit log (optional)
+	Tokens TokenStore
+	// Shares holds share links to single session terminals (optional)
+	Shares ShareStore
+	// TerminalSize sizes terminals watched by several viewers (default smallest)
+	TerminalSize TerminalSizePolicy
+	MenuData     MenuDataLoader
+	// TLS serves HTTPS when set (see BuildTLSConfig)
+	TLS                 *tls.Config
 	PushVAPIDPublicKey  string
 	PushVAPIDPrivateKey string
 	PushVAPIDSubject    string
@@ -42,6 +51,10 @@ type Server struct {
 	baseCtx     context.Context
 	cancelBase  context.CancelFunc
 	hookWatcher *session.StatusFileWatcher
+	// federation is set when MenuData is a *Federation
+	federation *Federation
+	// terminals shares one tmux attach among a session's websocket viewers
+	terminals *terminalHub
 
 	menuSubscribersMu sync.Mutex
 	menuSubscribers   map[chan struct{}]struct{}
@@ -61,8 +74,10 @@ func NewServer(cfg Config) *Server {
 	s := &Server{
 		cfg:             cfg,
 		menuData:        menuData,
+		terminals:       newTerminalHub(cfg.TerminalSize),
 		menuSubscribers: make(map[chan struct{}]struct{}),
 	}
+	s.federation, _ = menuData.(*Federation)
 	s.baseCtx, s.cancelBase = context.WithCancel(context.Background())
 	webLog := logging.ForComponent(logging.CompWeb)
 	if pushSvc, err := newPushService(cfg, menuData); err != nil {
@@ -74,6 +89,7 @@ func NewServer(cfg Config) *Server {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", s.handleIndex)
 	mux.HandleFunc("/s/", s.handleIndex)
+	mux.HandleFunc("/share/", s.handleSharePage)
 	mux.HandleFunc("/manifest.webmanifest", s.handleManifest)
 	mux.HandleFunc("/sw.js", s.handleServiceWorker)
 	mux.Handle("/static/", http.StripPrefix("/static/", s.staticFileServer()))
@@ -87,11 +103,13 @@ func NewServer(cfg Config) *Server {
 			"ok":       true,
 			"profile":  cfg.Profile,
 			"readOnly": cfg.ReadOnly,
+			"tls":      cfg.TLS != nil,
 			"time":     time.Now().UTC().Format(time.RFC3339),
 		}
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(resp)
 	})
+	mux.HandleFunc("/metrics", s.handleMetrics)
 	mux.HandleFunc("/api/menu", s.handleMenu)
 	mux.HandleFunc("/api/session/", s.handleSessionByID)
 	mux.HandleFunc("/api/push/config", s.handlePushConfig)
@@ -101,7 +119,7 @@ func NewServer(cfg Config) *Server {
 	mux.HandleFunc("/events/menu", s.handleMenuEvents)
 	mux.HandleFunc("/ws/session/", s.handleSessionWS)
 
-	handler := withRecover(mux)
+	handler := withRecover(s.withAudit(mux))
 
 	s.httpServer = &http.Server{
 		Addr:              cfg.ListenAddr,
@@ -109,6 +127,7 @@ func NewServer(cfg Config) *Server {
 		BaseContext:       func(_ net.Listener) context.Context { return s.baseCtx },
 		ReadHeaderTimeout: 5 * time.Second,
 		IdleTimeout:       60 * time.Second,
+		TLSConfig:         cfg.TLS,
 	}
 
 	return s
@@ -119,6 +138,14 @@ func (s *Server) Addr() string {
 	return s.httpServer.Addr
 }
 
+// URL returns the base URL the server is reachable at.
+func (s *Server) URL() string {
+	if s.cfg.TLS != nil {
+		return "https://" + s.httpServer.Addr
+	}
+	return "http://" + s.httpServer.Addr
+}
+
 // Handler returns the configured HTTP handler (used by tests).
 func (s *Server) Handler() http.Handler {
 	return s.httpServer.Handler
@@ -143,7 +170,21 @@ func (s *Server) Start() error {
 	if s.push != nil {
 		s.push.Start(s.baseCtx)
 	}
-	err := s.httpServer.ListenAndServe()
+	if s.federation != nil {
+		s.federation.Start(s.baseCtx, func() {
+			s.notifyMenuChanged()
+			if s.push != nil {
+				s.push.TriggerSync()
+			}
+		})
+	}
+	var err error
+	if s.cfg.TLS != nil {
+		// Certificates come from TLSConfig
+		err = s.httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = s.httpServer.ListenAndServe()
+	}
 	if s.hookWatcher != nil {
 		s.hookWatcher.Stop()
 		s.hookWatcher = nil
@@ -198,7 +239,25 @@ func withRecover(next http.Handler) http.Handler {
 }
 
 func (s *Server) String() string {
-	return fmt.Sprintf("web-server(addr=%s, profile=%s, readOnly=%t)", s.cfg.ListenAddr, s.cfg.Profile, s.cfg.ReadOnly)
+	return fmt.Sprintf("web-server(addr=%s, profile=%s, readOnly=%t, tls=%t)", s.cfg.ListenAddr, s.cfg.Profile, s.cfg.ReadOnly, s.cfg.TLS != nil)
+}
+
+// menuSource returns the menu data a request reads: for ?scope=local a
+// federated server answers with its own profile only (other federating
+// servers ask for that), otherwise with everything it serves.
+func (s *Server) menuSource(r *http.Request) MenuDataLoader {
+	if s.federation != nil && r.URL.Query().Get("scope") == menuScopeLocal {
+		return s.federation.LocalMenuData()
+	}
+	return s.menuData
+}
+
+// remoteSession resolves a federated session ID served by an upstream.
+func (s *Server) remoteSession(id string) (*federationUpstream, string, bool) {
+	if s.federation == nil {
+		return nil, "", false
+	}
+	return s.federation.remoteSession(id)
 }
 
 func (s *Server) subscribeMenuChanges() chan struct{} {
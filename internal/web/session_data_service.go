@@ -6,8 +6,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/asheshgoplani/agent-deck/internal/git"
 	"github.com/asheshgoplani/agent-deck/internal/session"
 	"github.com/asheshgoplani/agent-deck/internal/tmux"
 )
@@ -24,6 +26,8 @@ type MenuSnapshot struct {
 	TotalGroups   int        `json:"totalGroups"`
 	TotalSessions int        `json:"totalSessions"`
 	Items         []MenuItem `json:"items"`
+	// Sources lists the hosts and profiles of a federated menu
+	Sources []FederationSourceStatus `json:"sources,omitempty"`
 }
 
 // MenuItem represents one row in the flattened navigation list.
@@ -47,6 +51,13 @@ type MenuGroup struct {
 	Expanded     bool   `json:"expanded"`
 	Order        int    `json:"order"`
 	SessionCount int    `json:"sessionCount"`
+	// Unreachable marks a federated host or profile whose menu can't be loaded
+	Unreachable bool   `json:"unreachable,omitempty"`
+	Error       string `json:"error,omitempty"`
+	// Host marks a federated group, and OriginPath is its path in its own
+	// profile (empty for the host and profile groups federation adds)
+	Host       string `json:"host,omitempty"`
+	OriginPath string `json:"originPath,omitempty"`
 }
 
 // MenuSession contains metadata for a session item.
@@ -57,11 +68,36 @@ type MenuSession struct {
 	Status          session.Status `json:"status"`
 	GroupPath       string         `json:"groupPath"`
 	ProjectPath     string         `json:"projectPath"`
+	WorktreePath    string         `json:"worktreePath,omitempty"`
 	ParentSessionID string         `json:"parentSessionId,omitempty"`
 	Order           int            `json:"order"`
 	TmuxSession     string         `json:"tmuxSession,omitempty"`
 	CreatedAt       time.Time      `json:"createdAt"`
 	LastAccessedAt  time.Time      `json:"lastAccessedAt,omitempty"`
+	WaitingSince    time.Time      `json:"waitingSince,omitempty"`
+	Git             *MenuGitStatus `json:"git,omitempty"`
+	// Host and Profile say where a federated session lives, and
+	// OriginGroupPath is its group path in that profile
+	Host            string `json:"host,omitempty"`
+	Profile         string `json:"profile,omitempty"`
+	OriginGroupPath string `json:"originGroupPath,omitempty"`
+}
+
+// MenuGitStatus summarizes the git state of a session's working directory.
+type MenuGitStatus struct {
+	Branch        string `json:"branch,omitempty"`
+	Upstream      string `json:"upstream,omitempty"`
+	Ahead         int    `json:"ahead"`
+	Behind        int    `json:"behind"`
+	DefaultBranch string `json:"defaultBranch,omitempty"`
+	AheadDefault  int    `json:"aheadDefault"`
+	BehindDefault int    `json:"behindDefault"`
+	Staged        int    `json:"staged"`
+	Unstaged      int    `json:"unstaged"`
+	Untracked     int    `json:"untracked"`
+	Conflicted    int    `json:"conflicted"`
+	LinesAdded    int    `json:"linesAdded"`
+	LinesDeleted  int    `json:"linesDeleted"`
 }
 
 type storageLoader interface {
@@ -78,6 +114,9 @@ type SessionDataService struct {
 	now              func() time.Time
 	refreshLiveState bool
 	loadHookStatuses func() map[string]*session.HookStatus
+
+	gitStatusOnce sync.Once
+	gitStatus     *session.GitStatusCollector // Started on the first live refresh
 }
 
 // NewSessionDataService creates a SessionDataService for a profile.
@@ -134,19 +173,48 @@ func toMenuSession(inst *session.Instance) *MenuSession {
 	if tmuxSess := inst.GetTmuxSession(); tmuxSess != nil {
 		tmuxName = tmuxSess.Name
 	}
+	status := inst.GetStatusThreadSafe()
+	var waitingSince time.Time
+	if status == session.StatusWaiting {
+		waitingSince = inst.GetWaitingSince()
+	}
 
 	return &MenuSession{
 		ID:              inst.ID,
 		Title:           inst.Title,
 		Tool:            inst.GetToolThreadSafe(),
-		Status:          inst.GetStatusThreadSafe(),
+		Status:          status,
 		GroupPath:       inst.GroupPath,
 		ProjectPath:     inst.ProjectPath,
+		WorktreePath:    inst.WorktreePath,
 		ParentSessionID: inst.ParentSessionID,
 		Order:           inst.Order,
 		TmuxSession:     tmuxName,
 		CreatedAt:       inst.CreatedAt,
 		LastAccessedAt:  inst.LastAccessedAt,
+		WaitingSince:    waitingSince,
+		Git:             toMenuGitStatus(inst.GetGitStatus()),
+	}
+}
+
+func toMenuGitStatus(st *git.Status) *MenuGitStatus {
+	if st == nil {
+		return nil
+	}
+	return &MenuGitStatus{
+		Branch:        st.Branch,
+		Upstream:      st.Upstream,
+		Ahead:         st.Ahead,
+		Behind:        st.Behind,
+		DefaultBranch: st.DefaultBranch,
+		AheadDefault:  st.AheadDefault,
+		BehindDefault: st.BehindDefault,
+		Staged:        st.Staged,
+		Unstaged:      st.Unstaged,
+		Untracked:     st.Untracked,
+		Conflicted:    st.Conflicted,
+		LinesAdded:    st.LinesAdded,
+		LinesDeleted:  st.LinesDeleted,
 	}
 }
 
@@ -242,4 +310,12 @@ func (s *SessionDataService) refreshStatuses(instances []*session.Instance) {
 		}
 		_ = inst.UpdateStatus()
 	}
+
+	// Git statuses are collected in the background; each snapshot carries the
+	// latest cached ones.
+	s.gitStatusOnce.Do(func() {
+		s.gitStatus = session.NewGitStatusCollector(nil)
+		s.gitStatus.Start()
+	})
+	s.gitStatus.Track(instances)
 }
@@ -0,0 +1,349 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/logging"
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+)
+
+// shareTokenPrefix makes share link tokens recognizable, like tokenPrefix.
+const shareTokenPrefix = "ads_"
+
+// Metadata keys in the profile's state database.
+const (
+	// shareKeyMeta holds the key share links are signed with
+	shareKeyMeta = "web_share_key"
+	// ShareBaseURLMeta holds the URL the web server was last started on,
+	// which share links default to
+	ShareBaseURLMeta = "web_share_base_url"
+)
+
+// ShareViewerActiveWindow is how recently a viewer must have been seen to
+// count as watching. Servers refresh connected viewers more often than this.
+const ShareViewerActiveWindow = time.Minute
+
+// shareCheckInterval is how often a share viewer's connection refreshes its
+// last-seen time and rechecks that the share wasn't revoked.
+var shareCheckInterval = 15 * time.Second
+
+// Share link errors.
+var (
+	// ErrShareInvalid is returned for malformed or badly signed tokens
+	ErrShareInvalid = errors.New("invalid share link")
+	// ErrShareEnded is returned for expired or revoked shares
+	ErrShareEnded = errors.New("share link expired or was revoked")
+)
+
+// shareClaims are the signed contents of a share token.
+type shareClaims struct {
+	ID         string `json:"id"`
+	SessionID  string `json:"sid"`
+	AllowInput bool   `json:"in,omitempty"`
+	ExpiresAt  int64  `json:"exp"`
+}
+
+// ShareStore holds a profile's share links and their viewers.
+type ShareStore interface {
+	// ShareKey returns the signing key, or nil when no share was created yet.
+	ShareKey() ([]byte, error)
+	// LookupShare returns the share with id, or nil if there is none.
+	LookupShare(id string) (*statedb.WebShareRow, error)
+	// AddViewer records a viewer connecting and returns its ID.
+	AddViewer(viewer *statedb.WebShareViewerRow) (int64, error)
+	// TouchViewer records that a viewer is still connected.
+	TouchViewer(id int64, t time.Time) error
+	// EndViewer records that a viewer disconnected.
+	EndViewer(id int64, t time.Time) error
+}
+
+// NewShareStore returns a ShareStore backed by a profile's state database.
+func NewShareStore(db *statedb.StateDB) ShareStore {
+	return &stateDBShareStore{db: db}
+}
+
+type stateDBShareStore struct {
+	db *statedb.StateDB
+}
+
+func (s *stateDBShareStore) ShareKey() ([]byte, error) {
+	return loadShareKey(s.db)
+}
+
+func (s *stateDBShareStore) LookupShare(id string) (*statedb.WebShareRow, error) {
+	return s.db.WebShare(id)
+}
+
+func (s *stateDBShareStore) AddViewer(viewer *statedb.WebShareViewerRow) (int64, error) {
+	return s.db.AddWebShareViewer(viewer)
+}
+
+func (s *stateDBShareStore) TouchViewer(id int64, t time.Time) error {
+	return s.db.TouchWebShareViewer(id, t)
+}
+
+func (s *stateDBShareStore) EndViewer(id int64, t time.Time) error {
+	return s.db.EndWebShareViewer(id, t)
+}
+
+func loadShareKey(db *statedb.StateDB) ([]byte, error) {
+	value, err := db.GetMeta(shareKeyMeta)
+	if err != nil || value == "" {
+		return nil, err
+	}
+	return hex.DecodeString(value)
+}
+
+// CreateShare stores a share link to one session's terminal, valid for ttl,
+// and returns its signed token. The profile's signing key is generated on
+// first use.
+func CreateShare(db *statedb.StateDB, sessionID string, ttl time.Duration, allowInput bool) (string, *statedb.WebShareRow, error) {
+	if ttl <= 0 {
+		return "", nil, errors.New("share ttl must be positive")
+	}
+	key, err := loadShareKey(db)
+	if err != nil {
+		return "", nil, fmt.Errorf("load share key: %w", err)
+	}
+	if key == nil {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return "", nil, fmt.Errorf("generate share key: %w", err)
+		}
+		if err := db.SetMeta(shareKeyMeta, hex.EncodeToString(key)); err != nil {
+			return "", nil, fmt.Errorf("save share key: %w", err)
+		}
+	}
+
+	idBytes := make([]byte, 9)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", nil, fmt.Errorf("generate share id: %w", err)
+	}
+	now := time.Now()
+	row := &statedb.WebShareRow{
+		ID:         base64.RawURLEncoding.EncodeToString(idBytes),
+		SessionID:  sessionID,
+		AllowInput: allowInput,
+		CreatedAt:  now,
+		// Stored with second precision, like the signed expiry
+		ExpiresAt: now.Add(ttl).Truncate(time.Second),
+	}
+	if err := db.CreateWebShare(row); err != nil {
+		return "", nil, fmt.Errorf("save share: %w", err)
+	}
+	token := signShare(key, shareClaims{
+		ID:         row.ID,
+		SessionID:  row.SessionID,
+		AllowInput: row.AllowInput,
+		ExpiresAt:  row.ExpiresAt.Unix(),
+	})
+	return token, row, nil
+}
+
+// ShareURL returns the viewer page URL for a share token.
+func ShareURL(baseURL, token string) string {
+	return strings.TrimRight(baseURL, "/") + "/share/" + token
+}
+
+func signShare(key []byte, claims shareClaims) string {
+	payload, _ := json.Marshal(claims)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return shareTokenPrefix + encoded + "." + base64.RawURLEncoding.EncodeToString(shareSignature(key, encoded))
+}
+
+func shareSignature(key []byte, encodedPayload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encodedPayload))
+	return mac.Sum(nil)
+}
+
+// parseShareToken checks a token's signature and returns its claims.
+func parseShareToken(key []byte, token string) (*shareClaims, error) {
+	rest, ok := strings.CutPrefix(token, shareTokenPrefix)
+	if !ok || len(key) == 0 {
+		return nil, ErrShareInvalid
+	}
+	encoded, sig, ok := strings.Cut(rest, ".")
+	if !ok {
+		return nil, ErrShareInvalid
+	}
+	gotSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotSig, shareSignature(key, encoded)) {
+		return nil, ErrShareInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrShareInvalid
+	}
+	var claims shareClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.ID == "" || claims.SessionID == "" {
+		return nil, ErrShareInvalid
+	}
+	return &claims, nil
+}
+
+// resolveShare validates a share token: its signature, its expiry and that
+// the share still exists unrevoked with the signed limits.
+func (s *Server) resolveShare(token string) (*statedb.WebShareRow, error) {
+	if s.cfg.Shares == nil {
+		return nil, ErrShareInvalid
+	}
+	key, err := s.cfg.Shares.ShareKey()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := parseShareToken(key, token)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, ErrShareEnded
+	}
+	share, err := s.cfg.Shares.LookupShare(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if share == nil || share.SessionID != claims.SessionID || share.AllowInput != claims.AllowInput {
+		return nil, ErrShareInvalid
+	}
+	if !share.Active(now) {
+		return nil, ErrShareEnded
+	}
+	return share, nil
+}
+
+// authorizeShare checks the ?share= token of a terminal websocket request
+// for sessionID, writing an error response when it doesn't grant access.
+// The principal it returns can watch the session, and type into it when
+// the share allows input.
+func (s *Server) authorizeShare(w http.ResponseWriter, r *http.Request, sessionID string) (*principal, *statedb.WebShareRow, bool) {
+	share, err := s.resolveShare(r.URL.Query().Get("share"))
+	switch {
+	case errors.Is(err, ErrShareEnded):
+		writeAPIError(w, http.StatusGone, "SHARE_ENDED", err.Error())
+		return nil, nil, false
+	case errors.Is(err, ErrShareInvalid):
+		writeAPIError(w, http.StatusUnauthorized, "UNAUTHORIZED", err.Error())
+		return nil, nil, false
+	case err != nil:
+		logging.ForComponent(logging.CompWeb).Warn("share_lookup_failed", slog.String("error", err.Error()))
+		writeAPIError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to check share link")
+		return nil, nil, false
+	case share.SessionID != sessionID:
+		writeAPIError(w, http.StatusForbidden, "FORBIDDEN", "share link is for another session")
+		return nil, nil, false
+	}
+	scopes := []string{ScopeRead}
+	if share.AllowInput {
+		scopes = append(scopes, ScopeTerminalInput)
+	}
+	return &principal{Name: "share:" + share.ID, Scopes: scopes}, share, true
+}
+
+// handleSharePage serves the viewer page of a share link.
+func (s *Server) handleSharePage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	token := strings.TrimPrefix(r.URL.Path, "/share/")
+	if _, err := s.resolveShare(token); err != nil {
+		w.Header().Set("Cache-Control", "no-store")
+		switch {
+		case errors.Is(err, ErrShareEnded):
+			http.Error(w, "This share link has expired or was revoked.", http.StatusGone)
+		case errors.Is(err, ErrShareInvalid):
+			http.Error(w, "This share link is not valid.", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to check share link.", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := serveEmbeddedFile(w, "static/share.html", "text/html; charset=utf-8", map[string]string{
+		"Cache-Control":   "no-store",
+		"Referrer-Policy": "no-referrer",
+	}); err != nil {
+		http.Error(w, "viewer unavailable", http.StatusInternalServerError)
+	}
+}
+
+// shareViewer tracks one connection made through a share link: it is
+// recorded in the share store, refreshed while connected and closed when
+// the share expires or is revoked.
+type shareViewer struct {
+	server *Server
+	share  *statedb.WebShareRow
+	id     int64
+	done   chan struct{}
+}
+
+// trackShareViewer records the viewer behind r and watches its share until
+// stop is called, calling end with the reason when the share ends first.
+func (s *Server) trackShareViewer(share *statedb.WebShareRow, r *http.Request, end func(reason string)) *shareViewer {
+	v := &shareViewer{server: s, share: share, done: make(chan struct{})}
+	interval := shareCheckInterval
+	id, err := s.cfg.Shares.AddViewer(&statedb.WebShareViewerRow{
+		ShareID:     share.ID,
+		Name:        strings.TrimSpace(r.URL.Query().Get("viewer")),
+		RemoteAddr:  r.RemoteAddr,
+		UserAgent:   r.UserAgent(),
+		ConnectedAt: time.Now(),
+	})
+	if err != nil {
+		logging.ForComponent(logging.CompWeb).Warn("share_viewer_record_failed",
+			slog.String("share_id", share.ID),
+			slog.String("error", err.Error()))
+	}
+	v.id = id
+	go v.watch(interval, end)
+	return v
+}
+
+func (v *shareViewer) watch(interval time.Duration, end func(reason string)) {
+	expiry := time.NewTimer(time.Until(v.share.ExpiresAt))
+	defer expiry.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-v.done:
+			return
+		case <-v.server.baseCtx.Done():
+			return
+		case <-expiry.C:
+			end("share link expired")
+			return
+		case now := <-ticker.C:
+			if v.id != 0 {
+				_ = v.server.cfg.Shares.TouchViewer(v.id, now)
+			}
+			share, err := v.server.cfg.Shares.LookupShare(v.share.ID)
+			if err == nil && (share == nil || !share.Active(now)) {
+				end("share link was revoked")
+				return
+			}
+		}
+	}
+}
+
+// stop records the viewer as disconnected.
+func (v *shareViewer) stop() {
+	close(v.done)
+	if v.id != 0 {
+		_ = v.server.cfg.Shares.EndViewer(v.id, time.Now())
+	}
+}
@@ -0,0 +1,167 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+	"github.com/gorilla/websocket"
+)
+
+func newShareTestServer(t *testing.T) (*Server, *statedb.StateDB, *httptest.Server) {
+	t.Helper()
+	// A named token exists, so everything but share links needs one
+	srv, db := newTokenTestServer(t, &statedb.WebTokenRow{Name: "owner", Scopes: []string{ScopeAdmin}})
+	srv.cfg.Shares = NewShareStore(db)
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return srv, db, ts
+}
+
+func dialShare(t *testing.T, ts *httptest.Server, sessionID, token string) *websocket.Conn {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL(ts.URL, "/ws/session/"+sessionID+"?viewer=alice&share="+token), nil)
+	if err != nil {
+		if resp != nil {
+			t.Fatalf("dial failed with status %d: %v", resp.StatusCode, err)
+		}
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestShareTokenSignature(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	token := signShare(key, shareClaims{ID: "abc", SessionID: "sess-acme", ExpiresAt: 42})
+
+	claims, err := parseShareToken(key, token)
+	if err != nil || claims.ID != "abc" || claims.SessionID != "sess-acme" || claims.AllowInput {
+		t.Fatalf("parseShareToken = %+v, %v", claims, err)
+	}
+
+	// Granting input by editing the payload breaks the signature
+	forged := signShare([]byte("another key, same length........"), shareClaims{ID: "abc", SessionID: "sess-acme", AllowInput: true, ExpiresAt: 42})
+	payload, _, _ := strings.Cut(forged, ".")
+	_, sig, _ := strings.Cut(token, ".")
+	for _, bad := range []string{forged, payload + "." + sig, strings.TrimPrefix(token, shareTokenPrefix), "", "ads_x"} {
+		if _, err := parseShareToken(key, bad); err != ErrShareInvalid {
+			t.Errorf("parseShareToken(%q) err = %v, want ErrShareInvalid", bad, err)
+		}
+	}
+}
+
+func TestSharePageAndScope(t *testing.T) {
+	srv, db, _ := newShareTestServer(t)
+	token, _, err := CreateShare(db, "sess-acme", time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := serveWithToken(srv, http.MethodGet, "/share/"+token, "")
+	if page.Code != http.StatusOK || !strings.Contains(page.Body.String(), "/static/share.js") {
+		t.Fatalf("share page = %d %s", page.Code, page.Body.String())
+	}
+	if rr := serveWithToken(srv, http.MethodGet, "/share/"+token+"x", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("tampered link: status %d, want 404", rr.Code)
+	}
+	// A share link is not an access token
+	if rr := serveWithToken(srv, http.MethodGet, "/api/menu?share="+token, ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("menu with share token: status %d, want 401", rr.Code)
+	}
+	if rr := serveWithToken(srv, http.MethodGet, "/ws/session/sess-beta?share="+token, ""); rr.Code != http.StatusForbidden {
+		t.Errorf("share for another session: status %d, want 403", rr.Code)
+	}
+
+	expired, row, err := CreateShare(db, "sess-acme", time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.RevokeWebShare(row.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if rr := serveWithToken(srv, http.MethodGet, "/share/"+expired, ""); rr.Code != http.StatusGone {
+		t.Errorf("revoked link page: status %d, want 410", rr.Code)
+	}
+}
+
+func TestShareViewerIsReadOnlyAndTracked(t *testing.T) {
+	_, db, ts := newShareTestServer(t)
+	token, share, err := CreateShare(db, "sess-acme", time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := dialShare(t, ts, "sess-acme", token)
+	var connected wsServerMessage
+	if err := conn.ReadJSON(&connected); err != nil {
+		t.Fatal(err)
+	}
+	if connected.Event != "connected" || !connected.ReadOnly {
+		t.Fatalf("expected read-only connected status, got %+v", connected)
+	}
+	expectWSStatusEvent(t, conn, "ready")
+
+	if err := conn.WriteJSON(wsClientMessage{Type: "input", Data: "ls\r"}); err != nil {
+		t.Fatal(err)
+	}
+	var msg wsServerMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "error" || msg.Code != "FORBIDDEN" {
+		t.Fatalf("expected FORBIDDEN error, got %+v", msg)
+	}
+
+	viewers, err := db.LoadActiveWebShareViewers(time.Now().Add(-time.Minute))
+	if err != nil || len(viewers) != 1 || viewers[0].ShareID != share.ID || viewers[0].Name != "alice" {
+		t.Fatalf("active viewers = %v, %v", viewers, err)
+	}
+
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		viewers, _ := db.LoadActiveWebShareViewers(time.Now().Add(-time.Minute))
+		if len(viewers) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("viewer still active after disconnecting")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRevokingShareDisconnectsViewers(t *testing.T) {
+	prev := shareCheckInterval
+	shareCheckInterval = 20 * time.Millisecond
+	t.Cleanup(func() { shareCheckInterval = prev })
+
+	_, db, ts := newShareTestServer(t)
+	token, share, err := CreateShare(db, "sess-acme", time.Hour, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := dialShare(t, ts, "sess-acme", token)
+	var connected wsServerMessage
+	if err := conn.ReadJSON(&connected); err != nil {
+		t.Fatal(err)
+	}
+	if connected.ReadOnly {
+		t.Fatalf("--allow-input share should allow input, got %+v", connected)
+	}
+	expectWSStatusEvent(t, conn, "ready")
+
+	if _, err := db.RevokeWebShare(share.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	expectWSStatusEvent(t, conn, "share_ended")
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Fatal("connection should be closed after the share is revoked")
+	}
+}
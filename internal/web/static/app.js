@@ -28,6 +28,9 @@
     resizeTimer: null,
     readOnly: false,
     lastReadOnlyBlockAt: 0,
+    viewerId: "",
+    viewers: [],
+    lockHolder: "",
     connectionPhase: "idle",
     connectionDetail: "",
     groupExpandedByPath: new Map(),
@@ -879,6 +882,13 @@
     row.appendChild(marker)
     row.appendChild(name)
     row.appendChild(count)
+    if (item.group.unreachable) {
+      const offline = document.createElement("span")
+      offline.className = "group-unreachable"
+      offline.textContent = "unreachable"
+      offline.title = item.group.error || "unreachable"
+      row.appendChild(offline)
+    }
     btn.appendChild(row)
     return btn
   }
@@ -917,10 +927,55 @@
     row.appendChild(status)
     row.appendChild(title)
     row.appendChild(tool)
+
+    const gitText = gitBadgeText(session.git)
+    if (gitText) {
+      const git = document.createElement("span")
+      git.className = `git-badge${session.git.conflicted > 0 ? " conflicted" : ""}`
+      git.textContent = gitText
+      git.title = gitBadgeTitle(session.git)
+      row.appendChild(git)
+    }
+
     btn.appendChild(row)
     return btn
   }
 
+  // Compact git summary matching the TUI badge, e.g. "±3 +120/-4 ↑2↓1"
+  function gitBadgeText(git) {
+    if (!git) {
+      return ""
+    }
+    const parts = []
+    const changed = (git.staged || 0) + (git.unstaged || 0) + (git.untracked || 0) + (git.conflicted || 0)
+    if (changed > 0) {
+      parts.push(`±${changed}`)
+    }
+    if ((git.linesAdded || 0) + (git.linesDeleted || 0) > 0) {
+      parts.push(`+${git.linesAdded || 0}/-${git.linesDeleted || 0}`)
+    }
+    let sync = ""
+    if (git.ahead > 0) {
+      sync += `↑${git.ahead}`
+    }
+    if (git.behind > 0) {
+      sync += `↓${git.behind}`
+    }
+    if (sync) {
+      parts.push(sync)
+    }
+    return parts.join(" ")
+  }
+
+  function gitBadgeTitle(git) {
+    const lines = [`branch ${git.branch || "(detached)"}${git.upstream ? ` → ${git.upstream}` : ""}`]
+    if (git.defaultBranch) {
+      lines.push(`vs ${git.defaultBranch}: ${git.aheadDefault || 0} ahead, ${git.behindDefault || 0} behind`)
+    }
+    lines.push(`${git.staged || 0} staged, ${git.unstaged || 0} unstaged, ${git.untracked || 0} untracked`)
+    return lines.join("\n")
+  }
+
   function findSessionById(sessionId) {
     if (!sessionId || !state.snapshot || !Array.isArray(state.snapshot.items)) {
       return null
@@ -994,6 +1049,21 @@
     modeBanner.hidden = true
     modeBanner.textContent = "READ-ONLY MODE: input is disabled"
 
+    const presence = document.createElement("div")
+    presence.className = "terminal-presence"
+    presence.hidden = true
+
+    const presenceText = document.createElement("span")
+    presenceText.className = "terminal-presence-text"
+
+    const lockButton = document.createElement("button")
+    lockButton.type = "button"
+    lockButton.className = "terminal-lock-button"
+    lockButton.addEventListener("click", toggleInputLock)
+
+    presence.appendChild(presenceText)
+    presence.appendChild(lockButton)
+
     const canvas = document.createElement("div")
     canvas.className = "terminal-canvas"
 
@@ -1002,6 +1072,7 @@
 
     shell.appendChild(info)
     shell.appendChild(modeBanner)
+    shell.appendChild(presence)
     shell.appendChild(canvas)
     shell.appendChild(events)
     terminalRoot.appendChild(shell)
@@ -1011,6 +1082,9 @@
       shell,
       info,
       modeBanner,
+      presence,
+      presenceText,
+      lockButton,
       canvas,
       events,
       terminal: null,
@@ -1035,6 +1109,7 @@
       ui.fallbackPre = pre
       state.terminalUI = ui
       applyReadOnlyModeToTerminal()
+      renderPresence()
       return
     }
 
@@ -1084,6 +1159,7 @@
 
     state.terminalUI = ui
     applyReadOnlyModeToTerminal()
+    renderPresence()
     terminal.writeln("Connecting to terminal...")
     terminal.focus()
   }
@@ -1102,6 +1178,56 @@
     }
   }
 
+  // renderPresence shows who else watches the terminal and who holds the
+  // input lock. A lone viewer sees nothing: typing takes the free lock as
+  // long as nobody else could type.
+  function renderPresence() {
+    const ui = state.terminalUI
+    if (!ui || !ui.presence) {
+      return
+    }
+
+    const others = state.viewers.filter((v) => v.id !== state.viewerId)
+    ui.presence.hidden = others.length === 0 && !state.lockHolder
+    if (ui.presence.hidden) {
+      return
+    }
+
+    const names = state.viewers.map((v) =>
+      v.id === state.viewerId ? "you" : v.name || v.id,
+    )
+    let lock = "input free"
+    if (state.lockHolder === state.viewerId) {
+      lock = "you have input"
+    } else if (state.lockHolder) {
+      lock = `${viewerName(state.lockHolder)} has input`
+    }
+    ui.presenceText.textContent = `Watching: ${names.join(", ")} · ${lock}`
+
+    ui.lockButton.hidden = state.readOnly
+    ui.lockButton.textContent =
+      state.lockHolder === state.viewerId ? "Release input" : "Take input"
+    ui.lockButton.disabled =
+      !!state.lockHolder && state.lockHolder !== state.viewerId
+  }
+
+  function otherTypists() {
+    return state.viewers.filter((v) => v.id !== state.viewerId && v.canInput)
+  }
+
+  function viewerName(id) {
+    const viewer = state.viewers.find((v) => v.id === id)
+    return (viewer && viewer.name) || id
+  }
+
+  function toggleInputLock() {
+    if (!state.ws || state.ws.readyState !== WebSocket.OPEN) {
+      return
+    }
+    const type = state.lockHolder === state.viewerId ? "unlock" : "lock"
+    state.ws.send(JSON.stringify({ type }))
+  }
+
   function installTerminalTouchScroll(ui) {
     if (!ui || !ui.terminal || !ui.canvas) {
       return null
@@ -1209,6 +1335,23 @@
       }
       return
     }
+    if (state.lockHolder && state.lockHolder !== state.viewerId) {
+      const now = Date.now()
+      if (now - state.lastReadOnlyBlockAt > 1200) {
+        state.lastReadOnlyBlockAt = now
+        addTerminalEvent(`input locked by ${viewerName(state.lockHolder)}`)
+      }
+      return
+    }
+    if (!state.lockHolder && otherTypists().length > 0) {
+      // With others able to type, input needs the lock taken explicitly
+      const now = Date.now()
+      if (now - state.lastReadOnlyBlockAt > 1200) {
+        state.lastReadOnlyBlockAt = now
+        addTerminalEvent("others are watching: take input first")
+      }
+      return
+    }
 
     state.ws.send(
       JSON.stringify({
@@ -1358,6 +1501,10 @@
     state.wsSessionId = sessionId
     state.terminalAttached = false
     state.wsReconnectEnabled = true
+    state.viewerId = ""
+    state.viewers = []
+    state.lockHolder = ""
+    renderPresence()
 
     const ws = new WebSocket(wsURLForSession(sessionId))
     ws.binaryType = "arraybuffer"
@@ -1431,8 +1578,18 @@
         addTerminalEvent(`status:${payload.event || "unknown"}`)
         if (payload.event === "connected") {
           state.readOnly = !!payload.readOnly
+          state.viewerId = payload.viewerId || ""
           applyReadOnlyModeToTerminal()
           setConnectionState("connected", "session connected")
+        } else if (payload.event === "presence") {
+          state.viewers = payload.viewers || []
+          state.lockHolder = payload.lockHolder || ""
+          renderPresence()
+        } else if (payload.event === "input_lock") {
+          state.lockHolder = payload.lockHolder || ""
+          renderPresence()
+        } else if (payload.event === "terminal_resized") {
+          addTerminalEvent(`size:${payload.cols}x${payload.rows}`)
         } else if (payload.event === "ready") {
           setConnectionState("connected", "ready")
         } else if (payload.event === "pong") {
@@ -1457,6 +1614,13 @@
 
       if (payload.type === "error") {
         addTerminalEvent(`error:${payload.code || "unknown"}`)
+        if (
+          payload.code === "INPUT_LOCKED" ||
+          payload.code === "INPUT_LOCK_REQUIRED"
+        ) {
+          // Someone else is typing or could; the presence bar has the lock
+          return
+        }
         setConnectionState("error", payload.code || "terminal error")
 
         if (
@@ -0,0 +1,181 @@
+(function () {
+  // Share link viewer: one session's terminal, reached through a signed
+  // /share/<token> link instead of an access token.
+  const VIEWER_NAME_KEY = "agentdeck.share.viewer"
+  const token = decodeURIComponent(location.pathname.slice("/share/".length))
+  const claims = parseClaims(token)
+  const stateEl = document.getElementById("share-state")
+  const modeEl = document.getElementById("share-mode")
+  const root = document.getElementById("share-terminal")
+  const lockButton = document.getElementById("share-lock")
+
+  let readOnly = true
+  let ended = false
+  let viewerId = ""
+  let viewers = []
+  let lockHolder = ""
+
+  function parseClaims(raw) {
+    try {
+      const payload = raw.replace(/^ads_/, "").split(".")[0]
+      const json = atob(payload.replace(/-/g, "+").replace(/_/g, "/"))
+      return JSON.parse(json)
+    } catch (_err) {
+      return null
+    }
+  }
+
+  function setState(text) {
+    stateEl.textContent = text
+  }
+
+  // showPresence tells the viewer who else is watching and who is typing.
+  // Typing takes the input lock on its own only while nobody else could
+  // type; otherwise the lock button has to be used first.
+  function showPresence() {
+    const others = viewers.filter((v) => v.id !== viewerId).map((v) => v.name || v.id)
+    let text = others.length > 0 ? `watching with ${others.join(", ")}` : "watching"
+    if (lockHolder && lockHolder !== viewerId) {
+      const holder = viewers.find((v) => v.id === lockHolder)
+      text += ` · ${(holder && holder.name) || lockHolder} is typing`
+    }
+    setState(text)
+
+    lockButton.hidden = readOnly || (!lockHolder && !needsLock())
+    lockButton.textContent = lockHolder === viewerId ? "Release input" : "Take input"
+    lockButton.disabled = !!lockHolder && lockHolder !== viewerId
+  }
+
+  function needsLock() {
+    return viewers.some((v) => v.id !== viewerId && v.canInput)
+  }
+
+  function viewerName() {
+    let name = localStorage.getItem(VIEWER_NAME_KEY)
+    if (name === null) {
+      name = (window.prompt("Your name (shown to the session owner):") || "").trim()
+      localStorage.setItem(VIEWER_NAME_KEY, name)
+    }
+    return name
+  }
+
+  function updateExpiry() {
+    if (ended || !claims || !claims.exp) {
+      return
+    }
+    const minutes = Math.max(0, Math.round((claims.exp * 1000 - Date.now()) / 60000))
+    modeEl.textContent = `${readOnly ? "view only" : "view and type"} · expires in ${minutes} min`
+  }
+
+  if (!claims || !claims.sid) {
+    setState("invalid link")
+    return
+  }
+
+  const terminal = new window.Terminal({
+    convertEol: false,
+    cursorBlink: false,
+    disableStdin: true,
+    fontFamily: "IBM Plex Mono, Menlo, Consolas, monospace",
+    fontSize: 13,
+    scrollback: 10000,
+    theme: {
+      background: "#0a1220",
+      foreground: "#d9e2ec",
+      cursor: "#9ecbff",
+    },
+  })
+  const fitAddon = new window.FitAddon.FitAddon()
+  terminal.loadAddon(fitAddon)
+  terminal.open(root)
+  fitAddon.fit()
+
+  const scheme = location.protocol === "https:" ? "wss:" : "ws:"
+  const query = new URLSearchParams({ share: token, viewer: viewerName() })
+  const ws = new WebSocket(
+    `${scheme}//${location.host}/ws/session/${encodeURIComponent(claims.sid)}?${query}`,
+  )
+  ws.binaryType = "arraybuffer"
+  const decoder = new TextDecoder()
+
+  function sendResize() {
+    if (ws.readyState === WebSocket.OPEN) {
+      ws.send(JSON.stringify({ type: "resize", cols: terminal.cols, rows: terminal.rows }))
+    }
+  }
+
+  ws.addEventListener("message", (event) => {
+    if (event.data instanceof ArrayBuffer) {
+      terminal.write(decoder.decode(new Uint8Array(event.data), { stream: true }))
+      return
+    }
+    let msg
+    try {
+      msg = JSON.parse(event.data)
+    } catch (_err) {
+      return
+    }
+    if (msg.type === "error") {
+      if (msg.code === "INPUT_LOCKED" || msg.code === "INPUT_LOCK_REQUIRED") {
+        return
+      }
+      setState(msg.message || msg.code || "error")
+      return
+    }
+    switch (msg.event) {
+      case "connected":
+        readOnly = !!msg.readOnly
+        viewerId = msg.viewerId || ""
+        terminal.options.disableStdin = readOnly
+        updateExpiry()
+        setState("connected")
+        break
+      case "terminal_attached":
+        showPresence()
+        sendResize()
+        break
+      case "presence":
+        viewers = msg.viewers || []
+        lockHolder = msg.lockHolder || ""
+        showPresence()
+        break
+      case "input_lock":
+        lockHolder = msg.lockHolder || ""
+        showPresence()
+        break
+      case "session_closed":
+        setState("session closed")
+        break
+      case "share_ended":
+        ended = true
+        modeEl.textContent = msg.message || "share ended"
+        setState("ended")
+        break
+    }
+  })
+
+  ws.addEventListener("close", () => {
+    if (!ended) {
+      setState("disconnected")
+    }
+  })
+
+  terminal.onData((data) => {
+    const mayType = lockHolder ? lockHolder === viewerId : !needsLock()
+    if (!readOnly && mayType && ws.readyState === WebSocket.OPEN) {
+      ws.send(JSON.stringify({ type: "input", data }))
+    }
+  })
+
+  lockButton.addEventListener("click", () => {
+    if (ws.readyState === WebSocket.OPEN) {
+      ws.send(JSON.stringify({ type: lockHolder === viewerId ? "unlock" : "lock" }))
+    }
+  })
+
+  window.addEventListener("resize", () => {
+    fitAddon.fit()
+    sendResize()
+  })
+  setInterval(updateExpiry, 30000)
+})()
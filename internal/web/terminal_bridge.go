@@ -40,10 +40,17 @@ func (w *wsConnWriter) WriteBinary(data []byte) error {
 	return w.conn.WriteMessage(websocket.BinaryMessage, data)
 }
 
+// terminalSink receives a terminal bridge's output and status events: a
+// single websocket, or a sharedTerminal fanning out to all its viewers.
+type terminalSink interface {
+	WriteJSON(v any) error
+	WriteBinary(data []byte) error
+}
+
 type tmuxPTYBridge struct {
 	tmuxSession string
 	sessionID   string
-	writer      *wsConnWriter
+	writer      terminalSink
 
 	cmd  *exec.Cmd
 	ptmx *os.File
@@ -52,7 +59,7 @@ type tmuxPTYBridge struct {
 	done      chan struct{}
 }
 
-func newTmuxPTYBridge(tmuxSession, sessionID string, writer *wsConnWriter) (*tmuxPTYBridge, error) {
+func newTmuxPTYBridge(tmuxSession, sessionID string, writer terminalSink) (*tmuxPTYBridge, error) {
 	if tmuxSession == "" {
 		return nil, fmt.Errorf("tmux session name is required")
 	}
@@ -149,6 +156,31 @@ func (b *tmuxPTYBridge) Resize(cols, rows int) error {
 	return nil
 }
 
+// Redraw asks tmux to repaint this bridge's client, so a viewer joining a
+// running bridge sees the whole screen rather than only later changes.
+func (b *tmuxPTYBridge) Redraw() error {
+	if b == nil || b.cmd == nil || b.cmd.Process == nil {
+		return fmt.Errorf("bridge not initialized")
+	}
+	output, err := tmuxCommand("list-clients", "-t", b.tmuxSession, "-F", "#{client_pid} #{client_name}").Output()
+	if err != nil {
+		return fmt.Errorf("tmux list-clients: %w", err)
+	}
+	pid := fmt.Sprint(b.cmd.Process.Pid)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == pid {
+			return tmuxCommand("refresh-client", "-t", fields[1]).Run()
+		}
+	}
+	return fmt.Errorf("tmux client for pid %s not found", pid)
+}
+
+// Done is closed once the bridge stops streaming output.
+func (b *tmuxPTYBridge) Done() <-chan struct{} {
+	return b.done
+}
+
 func (b *tmuxPTYBridge) Close() {
 	if b == nil {
 		return
@@ -0,0 +1,530 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/asheshgoplani/agent-deck/internal/logging"
+)
+
+// TerminalSizePolicy picks the size of a terminal watched by several viewers.
+type TerminalSizePolicy string
+
+const (
+	// TerminalSizeSmallest fits every viewer, like tmux's own default.
+	TerminalSizeSmallest TerminalSizePolicy = "smallest"
+	// TerminalSizeLargest uses the biggest viewer; smaller viewers scroll.
+	TerminalSizeLargest TerminalSizePolicy = "largest"
+	// TerminalSizeOwner follows the input lock holder, or else the viewer
+	// that connected first.
+	TerminalSizeOwner TerminalSizePolicy = "owner"
+)
+
+// ParseTerminalSizePolicy validates a --terminal-size value ("" means smallest).
+func ParseTerminalSizePolicy(value string) (TerminalSizePolicy, error) {
+	switch policy := TerminalSizePolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return TerminalSizeSmallest, nil
+	case TerminalSizeSmallest, TerminalSizeLargest, TerminalSizeOwner:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid terminal size policy %q (use smallest, largest or owner)", value)
+	}
+}
+
+// maxViewerNameLen bounds the display names viewers pick for themselves.
+const maxViewerNameLen = 64
+
+// terminalLockIdleTimeout releases an input lock whose holder stopped typing.
+const terminalLockIdleTimeout = 2 * time.Minute
+
+// terminalBridge is the attached terminal a sharedTerminal fans out.
+type terminalBridge interface {
+	WriteInput(data string) error
+	Resize(cols, rows int) error
+	Redraw() error
+	Done() <-chan struct{}
+	Close()
+}
+
+// terminalAttachFunc attaches to a tmux session, streaming to sink.
+type terminalAttachFunc func(tmuxSession, sessionID string, sink terminalSink) (terminalBridge, error)
+
+func attachTmuxTerminal(tmuxSession, sessionID string, sink terminalSink) (terminalBridge, error) {
+	return newTmuxPTYBridge(tmuxSession, sessionID, sink)
+}
+
+// terminalHub keeps one shared terminal per session, so every websocket
+// watching a session reads the same tmux attach.
+type terminalHub struct {
+	policy   TerminalSizePolicy
+	attach   terminalAttachFunc
+	lockIdle time.Duration
+
+	mu         sync.Mutex
+	terminals  map[string]*sharedTerminal
+	nextViewer int
+}
+
+func newTerminalHub(policy TerminalSizePolicy) *terminalHub {
+	if policy == "" {
+		policy = TerminalSizeSmallest
+	}
+	return &terminalHub{
+		policy:    policy,
+		attach:    attachTmuxTerminal,
+		lockIdle:  terminalLockIdleTimeout,
+		terminals: make(map[string]*sharedTerminal),
+	}
+}
+
+// terminalViewer is one websocket watching a shared terminal.
+type terminalViewer struct {
+	id          string
+	name        string
+	principal   string
+	canInput    bool
+	connectedAt time.Time
+	writer      *wsConnWriter
+	// disconnect closes the viewer's websocket when writing to it fails
+	disconnect func()
+
+	cols, rows int
+}
+
+// wsViewer describes a connected viewer in presence events.
+type wsViewer struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Principal   string    `json:"principal,omitempty"`
+	CanInput    bool      `json:"canInput"`
+	ConnectedAt time.Time `json:"connectedAt"`
+}
+
+// newViewer creates a viewer with a connection-unique ID. name is the label
+// the client asked for; it defaults to the principal's name.
+func (h *terminalHub) newViewer(name string, p *principal, canInput bool, writer *wsConnWriter, disconnect func()) *terminalViewer {
+	h.mu.Lock()
+	h.nextViewer++
+	id := fmt.Sprintf("v%d", h.nextViewer)
+	h.mu.Unlock()
+
+	name = strings.TrimSpace(name)
+	for utf8.RuneCountInString(name) > maxViewerNameLen {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" {
+		name = p.Name
+	}
+	return &terminalViewer{
+		id:          id,
+		name:        name,
+		principal:   p.Name,
+		canInput:    canInput,
+		connectedAt: time.Now().UTC(),
+		writer:      writer,
+		disconnect:  disconnect,
+	}
+}
+
+// join adds a viewer to the session's shared terminal, attaching to tmux
+// if nobody is watching yet. The attach runs outside h.mu: viewers of the
+// same session wait for it, other sessions don't.
+func (h *terminalHub) join(sessionID, tmuxSession string, v *terminalViewer) (*sharedTerminal, error) {
+	h.mu.Lock()
+	t := h.terminals[sessionID]
+	if t == nil {
+		// The first viewer is in place before attaching so it gets the
+		// screen tmux draws on attach
+		t = &sharedTerminal{hub: h, sessionID: sessionID, viewers: []*terminalViewer{v}, ready: make(chan struct{})}
+		h.terminals[sessionID] = t
+		h.mu.Unlock()
+
+		bridge, err := h.attach(tmuxSession, sessionID, t)
+		if err != nil {
+			h.drop(t)
+			t.attachErr = err
+			close(t.ready)
+			return nil, err
+		}
+		t.bridge = bridge
+		close(t.ready)
+		go t.watchBridge()
+
+		t.ctrl.Lock()
+		defer t.ctrl.Unlock()
+		t.broadcastPresence()
+		return t, nil
+	}
+	t.mu.Lock()
+	t.viewers = append(t.viewers, v)
+	t.mu.Unlock()
+	h.mu.Unlock()
+
+	<-t.ready
+	if t.attachErr != nil {
+		return nil, t.attachErr
+	}
+	t.ctrl.Lock()
+	defer t.ctrl.Unlock()
+	t.broadcastPresence()
+	if err := t.bridge.Redraw(); err != nil {
+		logging.ForComponent(logging.CompWeb).Debug("terminal_redraw_failed",
+			slog.String("session_id", sessionID),
+			slog.String("error", err.Error()))
+	}
+	return t, nil
+}
+
+// viewerCount returns how many viewers watch a session's shared terminal.
+func (h *terminalHub) viewerCount(sessionID string) int {
+	h.mu.Lock()
+	t := h.terminals[sessionID]
+	h.mu.Unlock()
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.viewers)
+}
+
+// drop forgets a shared terminal once it has no viewers or its bridge ended.
+func (h *terminalHub) drop(t *sharedTerminal) {
+	h.mu.Lock()
+	if h.terminals[t.sessionID] == t {
+		delete(h.terminals, t.sessionID)
+	}
+	h.mu.Unlock()
+}
+
+// sharedTerminal fans one terminal bridge out to every viewer of a session.
+// Only the holder of the input lock may type, and the terminal size follows
+// the hub's TerminalSizePolicy.
+type sharedTerminal struct {
+	hub       *terminalHub
+	sessionID string
+	// ready is closed once the first viewer's attach finished; bridge and
+	// attachErr are set before
+	ready     chan struct{}
+	bridge    terminalBridge
+	attachErr error
+
+	// ctrl serializes presence, lock and size changes so every viewer sees
+	// their events in the order they happened
+	ctrl       sync.Mutex
+	mu         sync.Mutex
+	viewers    []*terminalViewer // in connection order
+	lockHolder *terminalViewer
+	lockActive time.Time   // Last lock or input by the holder
+	lockTimer  *time.Timer // Pending idle check, nil when none
+	cols, rows int
+}
+
+// WriteBinary sends terminal output to every viewer. A viewer that cannot
+// keep up is disconnected rather than stalling the others.
+func (t *sharedTerminal) WriteBinary(data []byte) error {
+	for _, v := range t.snapshotViewers() {
+		if err := v.writer.WriteBinary(data); err != nil {
+			v.disconnect()
+		}
+	}
+	return nil
+}
+
+// WriteJSON sends a status or error message to every viewer.
+func (t *sharedTerminal) WriteJSON(msg any) error {
+	for _, v := range t.snapshotViewers() {
+		_ = v.writer.WriteJSON(msg)
+	}
+	return nil
+}
+
+func (t *sharedTerminal) snapshotViewers() []*terminalViewer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*terminalViewer(nil), t.viewers...)
+}
+
+func (t *sharedTerminal) watchBridge() {
+	<-t.bridge.Done()
+	t.hub.drop(t)
+}
+
+// leave removes a viewer, releasing its input lock, and closes the bridge
+// when the last viewer is gone.
+func (t *sharedTerminal) leave(v *terminalViewer) {
+	t.ctrl.Lock()
+	defer t.ctrl.Unlock()
+	t.hub.mu.Lock()
+	t.mu.Lock()
+	for i, other := range t.viewers {
+		if other == v {
+			t.viewers = append(t.viewers[:i], t.viewers[i+1:]...)
+			break
+		}
+	}
+	released := t.lockHolder == v
+	if released {
+		t.lockHolder = nil
+	}
+	empty := len(t.viewers) == 0
+	t.mu.Unlock()
+	if empty && t.hub.terminals[t.sessionID] == t {
+		delete(t.hub.terminals, t.sessionID)
+	}
+	t.hub.mu.Unlock()
+
+	if empty {
+		t.bridge.Close()
+		return
+	}
+	if released {
+		t.broadcastLock()
+	}
+	t.broadcastPresence()
+	_ = t.applySize()
+}
+
+// errInputLocked reports that another viewer holds the input lock.
+type errInputLocked struct {
+	holder string
+}
+
+func (e *errInputLocked) Error() string {
+	return "input lock is held by " + e.holder
+}
+
+// errInputLockRequired is returned for input from a viewer without the lock
+// while other viewers could type too.
+var errInputLockRequired = errors.New("take the input lock before typing: others are watching")
+
+// lock gives v the input lock if nobody else holds it.
+func (t *sharedTerminal) lock(v *terminalViewer) error {
+	t.ctrl.Lock()
+	defer t.ctrl.Unlock()
+	return t.takeLock(v)
+}
+
+// takeLock gives v the input lock if nobody else holds it and marks the
+// lock active. t.ctrl must be held.
+func (t *sharedTerminal) takeLock(v *terminalViewer) error {
+	t.mu.Lock()
+	holder := t.lockHolder
+	if holder != nil && holder != v {
+		t.mu.Unlock()
+		return &errInputLocked{holder: holder.name}
+	}
+	t.lockHolder = v
+	t.lockActive = time.Now()
+	if t.lockTimer == nil {
+		t.lockTimer = time.AfterFunc(t.hub.lockIdle, t.releaseIdleLock)
+	}
+	t.mu.Unlock()
+
+	if holder == nil {
+		t.broadcastLock()
+		_ = t.applySize()
+	}
+	return nil
+}
+
+// releaseIdleLock releases the input lock once its holder has been idle
+// for the hub's lockIdle, so a forgotten tab doesn't keep others out.
+func (t *sharedTerminal) releaseIdleLock() {
+	t.ctrl.Lock()
+	defer t.ctrl.Unlock()
+	t.mu.Lock()
+	if t.lockHolder == nil {
+		t.lockTimer = nil
+		t.mu.Unlock()
+		return
+	}
+	if wait := t.hub.lockIdle - time.Since(t.lockActive); wait > 0 {
+		t.lockTimer = time.AfterFunc(wait, t.releaseIdleLock)
+		t.mu.Unlock()
+		return
+	}
+	t.lockHolder = nil
+	t.lockTimer = nil
+	t.mu.Unlock()
+
+	t.broadcastLock()
+	_ = t.applySize()
+}
+
+// unlock releases the input lock if v holds it.
+func (t *sharedTerminal) unlock(v *terminalViewer) {
+	t.ctrl.Lock()
+	defer t.ctrl.Unlock()
+	t.mu.Lock()
+	if t.lockHolder != v {
+		t.mu.Unlock()
+		return
+	}
+	t.lockHolder = nil
+	t.mu.Unlock()
+
+	t.broadcastLock()
+	_ = t.applySize()
+}
+
+// writeInput types into the terminal for v. A viewer who is the only one
+// able to type takes the free lock implicitly; with others who could type
+// watching, v has to take it explicitly first.
+func (t *sharedTerminal) writeInput(v *terminalViewer, data string) error {
+	if data == "" {
+		return nil
+	}
+	t.ctrl.Lock()
+	t.mu.Lock()
+	holder := t.lockHolder
+	contended := false
+	for _, other := range t.viewers {
+		if other != v && other.canInput {
+			contended = true
+			break
+		}
+	}
+	t.mu.Unlock()
+	var err error
+	switch {
+	case holder != nil && holder != v:
+		err = &errInputLocked{holder: holder.name}
+	case holder == nil && contended:
+		err = errInputLockRequired
+	default:
+		err = t.takeLock(v)
+	}
+	t.ctrl.Unlock()
+	if err != nil {
+		return err
+	}
+	return t.bridge.WriteInput(data)
+}
+
+// resize records v's window size and re-applies the size policy.
+func (t *sharedTerminal) resize(v *terminalViewer, cols, rows int) error {
+	if cols <= 0 || rows <= 0 {
+		return fmt.Errorf("invalid dimensions: cols=%d rows=%d", cols, rows)
+	}
+	t.ctrl.Lock()
+	defer t.ctrl.Unlock()
+	t.mu.Lock()
+	v.cols, v.rows = cols, rows
+	t.mu.Unlock()
+	return t.applySize()
+}
+
+// applySize resizes the bridge to what the size policy picks from the
+// viewers' window sizes, telling viewers when it changes. t.ctrl must be
+// held.
+func (t *sharedTerminal) applySize() error {
+	t.mu.Lock()
+	cols, rows := t.negotiatedSize()
+	if cols == 0 || (cols == t.cols && rows == t.rows) {
+		t.mu.Unlock()
+		return nil
+	}
+	t.cols, t.rows = cols, rows
+	t.mu.Unlock()
+
+	if err := t.bridge.Resize(cols, rows); err != nil {
+		return err
+	}
+	return t.WriteJSON(wsServerMessage{
+		Type:      "status",
+		Event:     "terminal_resized",
+		SessionID: t.sessionID,
+		Cols:      cols,
+		Rows:      rows,
+		Time:      time.Now().UTC(),
+	})
+}
+
+// negotiatedSize applies the size policy; t.mu must be held. Viewers that
+// have not reported a size yet are ignored.
+func (t *sharedTerminal) negotiatedSize() (int, int) {
+	var cols, rows int
+	if t.hub.policy == TerminalSizeOwner {
+		owner := t.lockHolder
+		if owner == nil || owner.cols == 0 {
+			for _, v := range t.viewers {
+				if v.cols > 0 {
+					owner = v
+					break
+				}
+			}
+		}
+		if owner != nil {
+			cols, rows = owner.cols, owner.rows
+		}
+		return cols, rows
+	}
+	for _, v := range t.viewers {
+		if v.cols == 0 {
+			continue
+		}
+		if cols == 0 {
+			cols, rows = v.cols, v.rows
+			continue
+		}
+		if t.hub.policy == TerminalSizeLargest {
+			cols, rows = max(cols, v.cols), max(rows, v.rows)
+		} else {
+			cols, rows = min(cols, v.cols), min(rows, v.rows)
+		}
+	}
+	return cols, rows
+}
+
+func (t *sharedTerminal) broadcastPresence() {
+	t.mu.Lock()
+	viewers := make([]wsViewer, 0, len(t.viewers))
+	for _, v := range t.viewers {
+		viewers = append(viewers, wsViewer{
+			ID:          v.id,
+			Name:        v.name,
+			Principal:   v.principal,
+			CanInput:    v.canInput,
+			ConnectedAt: v.connectedAt,
+		})
+	}
+	holder := ""
+	if t.lockHolder != nil {
+		holder = t.lockHolder.id
+	}
+	t.mu.Unlock()
+
+	_ = t.WriteJSON(wsServerMessage{
+		Type:       "status",
+		Event:      "presence",
+		SessionID:  t.sessionID,
+		Viewers:    viewers,
+		LockHolder: holder,
+		Time:       time.Now().UTC(),
+	})
+}
+
+func (t *sharedTerminal) broadcastLock() {
+	t.mu.Lock()
+	msg := wsServerMessage{
+		Type:      "status",
+		Event:     "input_lock",
+		SessionID: t.sessionID,
+		Time:      time.Now().UTC(),
+	}
+	if t.lockHolder != nil {
+		msg.LockHolder = t.lockHolder.id
+		msg.Message = t.lockHolder.name
+	}
+	t.mu.Unlock()
+
+	_ = t.WriteJSON(msg)
+}
@@ -0,0 +1,411 @@
+package web
+
+import (
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+	"github.com/gorilla/websocket"
+)
+
+// fakeTerminalBridge records what a shared terminal sends to tmux.
+type fakeTerminalBridge struct {
+	sink terminalSink
+
+	mu      sync.Mutex
+	input   []string
+	sizes   [][2]int
+	redraws int
+	closed  bool
+	done    chan struct{}
+}
+
+func (b *fakeTerminalBridge) WriteInput(data string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.input = append(b.input, data)
+	return nil
+}
+
+func (b *fakeTerminalBridge) Resize(cols, rows int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sizes = append(b.sizes, [2]int{cols, rows})
+	return nil
+}
+
+func (b *fakeTerminalBridge) Redraw() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.redraws++
+	return nil
+}
+
+func (b *fakeTerminalBridge) Done() <-chan struct{} { return b.done }
+
+func (b *fakeTerminalBridge) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		close(b.done)
+	}
+}
+
+func (b *fakeTerminalBridge) lastSize() [2]int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.sizes) == 0 {
+		return [2]int{}
+	}
+	return b.sizes[len(b.sizes)-1]
+}
+
+// newSharedTerminalTestServer serves one tmux-backed session whose terminal
+// is a fakeTerminalBridge; attaches counts how often it was attached.
+func newSharedTerminalTestServer(t *testing.T, policy TerminalSizePolicy) (*httptest.Server, *Server, func() []*fakeTerminalBridge) {
+	t.Helper()
+
+	srv := NewServer(Config{ListenAddr: "127.0.0.1:0", Profile: "work", TerminalSize: policy})
+	srv.menuData = &fakeMenuDataLoader{
+		snapshot: &MenuSnapshot{
+			Profile: "work",
+			Items: []MenuItem{{
+				Type:    MenuItemTypeSession,
+				Session: &MenuSession{ID: "sess-shared", TmuxSession: "agentdeck_shared"},
+			}},
+		},
+	}
+	var mu sync.Mutex
+	var bridges []*fakeTerminalBridge
+	srv.terminals.attach = func(tmuxSession, sessionID string, sink terminalSink) (terminalBridge, error) {
+		b := &fakeTerminalBridge{sink: sink, done: make(chan struct{})}
+		mu.Lock()
+		bridges = append(bridges, b)
+		mu.Unlock()
+		return b, nil
+	}
+
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts, srv, func() []*fakeTerminalBridge {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*fakeTerminalBridge(nil), bridges...)
+	}
+}
+
+// dialSharedViewer connects a named viewer and waits until it is attached,
+// returning its viewer ID.
+func dialSharedViewer(t *testing.T, ts *httptest.Server, name string) (*websocket.Conn, string) {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL(ts.URL, "/ws/session/sess-shared?viewer="+name), nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", name, err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	connected := readWSMessage(t, conn, func(msg wsServerMessage) bool { return msg.Event == "connected" })
+	if connected.ViewerID == "" {
+		t.Fatalf("connected event has no viewer ID: %+v", connected)
+	}
+	readWSMessage(t, conn, func(msg wsServerMessage) bool { return msg.Event == "terminal_attached" })
+	return conn, connected.ViewerID
+}
+
+// readWSMessage reads control messages until one matches.
+func readWSMessage(t *testing.T, conn *websocket.Conn, match func(wsServerMessage) bool) wsServerMessage {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	defer func() { _ = conn.SetReadDeadline(time.Time{}) }()
+	for {
+		var msg wsServerMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for ws message: %v", err)
+		}
+		if match(msg) {
+			return msg
+		}
+	}
+}
+
+func TestSharedTerminalFansOutOneAttach(t *testing.T) {
+	ts, srv, bridges := newSharedTerminalTestServer(t, TerminalSizeSmallest)
+
+	alice, aliceID := dialSharedViewer(t, ts, "alice")
+	bob, bobID := dialSharedViewer(t, ts, "bob")
+
+	if got := len(bridges()); got != 1 {
+		t.Fatalf("expected a single attach for two viewers, got %d", got)
+	}
+	bridge := bridges()[0]
+	bridge.mu.Lock()
+	redraws := bridge.redraws
+	bridge.mu.Unlock()
+	if redraws != 1 {
+		t.Fatalf("expected the second viewer to trigger a redraw, got %d", redraws)
+	}
+
+	presence := readWSMessage(t, alice, func(msg wsServerMessage) bool {
+		return msg.Event == "presence" && len(msg.Viewers) == 2
+	})
+	if presence.Viewers[0].ID != aliceID || presence.Viewers[1].ID != bobID || presence.Viewers[1].Name != "bob" {
+		t.Fatalf("unexpected presence: %+v", presence.Viewers)
+	}
+
+	// Output reaches every viewer
+	if err := bridge.sink.WriteBinary([]byte("hello")); err != nil {
+		t.Fatalf("write output: %v", err)
+	}
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+		for {
+			msgType, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("read output: %v", err)
+			}
+			if msgType == websocket.BinaryMessage {
+				if string(data) != "hello" {
+					t.Fatalf("unexpected output %q", data)
+				}
+				break
+			}
+		}
+	}
+
+	_ = bob.Close()
+	readWSMessage(t, alice, func(msg wsServerMessage) bool {
+		return msg.Event == "presence" && len(msg.Viewers) == 1
+	})
+	_ = alice.Close()
+	select {
+	case <-bridge.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("bridge not closed after the last viewer left")
+	}
+	if n := srv.terminals.viewerCount("sess-shared"); n != 0 {
+		t.Fatalf("expected no viewers left, got %d", n)
+	}
+}
+
+func TestSharedTerminalInputLock(t *testing.T) {
+	ts, _, bridges := newSharedTerminalTestServer(t, TerminalSizeSmallest)
+
+	alice, aliceID := dialSharedViewer(t, ts, "alice")
+	bob, bobID := dialSharedViewer(t, ts, "bob")
+
+	// With someone else able to type, a stray keypress doesn't take the lock
+	if err := alice.WriteJSON(wsClientMessage{Type: "input", Data: "x"}); err != nil {
+		t.Fatalf("send input: %v", err)
+	}
+	if msg := readWSMessage(t, alice, func(msg wsServerMessage) bool { return msg.Type == "error" }); msg.Code != "INPUT_LOCK_REQUIRED" {
+		t.Fatalf("expected INPUT_LOCK_REQUIRED, got %+v", msg)
+	}
+
+	// Taking the lock is explicit, and everyone hears about it
+	if err := alice.WriteJSON(wsClientMessage{Type: "lock"}); err != nil {
+		t.Fatalf("send lock: %v", err)
+	}
+	if err := alice.WriteJSON(wsClientMessage{Type: "input", Data: "ls\r"}); err != nil {
+		t.Fatalf("send input: %v", err)
+	}
+	locked := readWSMessage(t, bob, func(msg wsServerMessage) bool { return msg.Event == "input_lock" })
+	if locked.LockHolder != aliceID || locked.Message != "alice" {
+		t.Fatalf("expected alice to hold the lock, got %+v", locked)
+	}
+
+	if err := bob.WriteJSON(wsClientMessage{Type: "input", Data: "rm -rf /\r"}); err != nil {
+		t.Fatalf("send input: %v", err)
+	}
+	rejected := readWSMessage(t, bob, func(msg wsServerMessage) bool { return msg.Type == "error" })
+	if rejected.Code != "INPUT_LOCKED" {
+		t.Fatalf("expected INPUT_LOCKED, got %+v", rejected)
+	}
+	if err := bob.WriteJSON(wsClientMessage{Type: "lock"}); err != nil {
+		t.Fatalf("send lock: %v", err)
+	}
+	if msg := readWSMessage(t, bob, func(msg wsServerMessage) bool { return msg.Type == "error" }); msg.Code != "INPUT_LOCKED" {
+		t.Fatalf("expected INPUT_LOCKED for lock, got %+v", msg)
+	}
+
+	// Releasing hands the lock over
+	if err := alice.WriteJSON(wsClientMessage{Type: "unlock"}); err != nil {
+		t.Fatalf("send unlock: %v", err)
+	}
+	if msg := readWSMessage(t, bob, func(msg wsServerMessage) bool { return msg.Event == "input_lock" }); msg.LockHolder != "" {
+		t.Fatalf("expected a released lock, got %+v", msg)
+	}
+	if err := bob.WriteJSON(wsClientMessage{Type: "lock"}); err != nil {
+		t.Fatalf("send lock: %v", err)
+	}
+	readWSMessage(t, alice, func(msg wsServerMessage) bool { return msg.Event == "input_lock" && msg.LockHolder == bobID })
+
+	// Leaving releases the lock
+	_ = bob.Close()
+	if msg := readWSMessage(t, alice, func(msg wsServerMessage) bool { return msg.Event == "input_lock" }); msg.LockHolder != "" {
+		t.Fatalf("expected the lock released when bob left, got %+v", msg)
+	}
+
+	bridge := bridges()[0]
+	bridge.mu.Lock()
+	defer bridge.mu.Unlock()
+	if len(bridge.input) != 1 || bridge.input[0] != "ls\r" {
+		t.Fatalf("only alice's input should reach the terminal, got %q", bridge.input)
+	}
+}
+
+func TestSharedTerminalLoneViewerTypesWithoutLocking(t *testing.T) {
+	ts, srv, bridges := newSharedTerminalTestServer(t, TerminalSizeSmallest)
+	srv.terminals.lockIdle = 100 * time.Millisecond
+
+	alice, aliceID := dialSharedViewer(t, ts, "alice")
+	if err := alice.WriteJSON(wsClientMessage{Type: "input", Data: "ls\r"}); err != nil {
+		t.Fatalf("send input: %v", err)
+	}
+	readWSMessage(t, alice, func(msg wsServerMessage) bool { return msg.Event == "input_lock" && msg.LockHolder == aliceID })
+
+	// An idle holder loses the lock, so a forgotten tab can't keep others out
+	readWSMessage(t, alice, func(msg wsServerMessage) bool { return msg.Event == "input_lock" && msg.LockHolder == "" })
+
+	bridge := bridges()[0]
+	bridge.mu.Lock()
+	defer bridge.mu.Unlock()
+	if len(bridge.input) != 1 || bridge.input[0] != "ls\r" {
+		t.Fatalf("expected alice's input to reach the terminal, got %q", bridge.input)
+	}
+}
+
+func TestTerminalHubAttachesOutsideTheHubLock(t *testing.T) {
+	ts, srv, bridges := newSharedTerminalTestServer(t, TerminalSizeSmallest)
+	loader := srv.menuData.(*fakeMenuDataLoader)
+	loader.snapshot.Items = append(loader.snapshot.Items, MenuItem{
+		Type:    MenuItemTypeSession,
+		Session: &MenuSession{ID: "sess-slow", TmuxSession: "agentdeck_slow"},
+	})
+	fastAttach := srv.terminals.attach
+	release := make(chan struct{})
+	var slowAttaches atomic.Int32
+	srv.terminals.attach = func(tmuxSession, sessionID string, sink terminalSink) (terminalBridge, error) {
+		if sessionID == "sess-slow" {
+			slowAttaches.Add(1)
+			<-release
+		}
+		return fastAttach(tmuxSession, sessionID, sink)
+	}
+
+	dialSlow := func(name string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL(ts.URL, "/ws/session/sess-slow?viewer="+name), nil)
+		if err != nil {
+			t.Fatalf("dial %s: %v", name, err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+	first := dialSlow("first")
+	second := dialSlow("second")
+
+	// While the slow attach is spawning, other sessions still join
+	dialSharedViewer(t, ts, "alice")
+	if n := srv.terminals.viewerCount("sess-shared"); n != 1 {
+		t.Fatalf("expected one viewer of sess-shared, got %d", n)
+	}
+
+	close(release)
+	for _, conn := range []*websocket.Conn{first, second} {
+		readWSMessage(t, conn, func(msg wsServerMessage) bool { return msg.Event == "terminal_attached" })
+	}
+	if n := slowAttaches.Load(); n != 1 {
+		t.Fatalf("expected one attach for two viewers of sess-slow, got %d", n)
+	}
+	if n := len(bridges()); n != 2 {
+		t.Fatalf("expected one bridge per session, got %d", n)
+	}
+}
+
+func TestSharedTerminalReadOnlyViewerCannotLock(t *testing.T) {
+	srv, _ := newTokenTestServer(t, &statedb.WebTokenRow{Name: "viewer", Scopes: []string{ScopeRead}})
+	srv.menuData = &fakeMenuDataLoader{
+		snapshot: &MenuSnapshot{
+			Profile: "work",
+			Items: []MenuItem{{
+				Type:    MenuItemTypeSession,
+				Session: &MenuSession{ID: "sess-shared", TmuxSession: "agentdeck_shared"},
+			}},
+		},
+	}
+	srv.terminals.attach = func(tmuxSession, sessionID string, sink terminalSink) (terminalBridge, error) {
+		return &fakeTerminalBridge{sink: sink, done: make(chan struct{})}, nil
+	}
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL(ts.URL, "/ws/session/sess-shared?token=secret-viewer"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	readWSMessage(t, conn, func(msg wsServerMessage) bool { return msg.Event == "terminal_attached" })
+
+	if err := conn.WriteJSON(wsClientMessage{Type: "lock"}); err != nil {
+		t.Fatalf("send lock: %v", err)
+	}
+	if msg := readWSMessage(t, conn, func(msg wsServerMessage) bool { return msg.Type == "error" }); msg.Code != "FORBIDDEN" {
+		t.Fatalf("expected FORBIDDEN, got %+v", msg)
+	}
+}
+
+func TestSharedTerminalSizePolicies(t *testing.T) {
+	tests := []struct {
+		policy TerminalSizePolicy
+		want   [2]int
+	}{
+		{TerminalSizeSmallest, [2]int{80, 24}},
+		{TerminalSizeLargest, [2]int{200, 50}},
+		// Bob holds the input lock, so his window wins
+		{TerminalSizeOwner, [2]int{120, 40}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			ts, _, bridges := newSharedTerminalTestServer(t, tt.policy)
+			alice, _ := dialSharedViewer(t, ts, "alice")
+			bob, _ := dialSharedViewer(t, ts, "bob")
+			carol, _ := dialSharedViewer(t, ts, "carol")
+
+			_ = bob.WriteJSON(wsClientMessage{Type: "lock"})
+			readWSMessage(t, alice, func(msg wsServerMessage) bool { return msg.Event == "input_lock" })
+			sizes := map[*websocket.Conn][2]int{alice: {200, 24}, bob: {120, 40}, carol: {80, 50}}
+			for conn, size := range sizes {
+				_ = conn.WriteJSON(wsClientMessage{Type: "resize", Cols: size[0], Rows: size[1]})
+			}
+
+			deadline := time.Now().Add(3 * time.Second)
+			for bridges()[0].lastSize() != tt.want {
+				if time.Now().After(deadline) {
+					t.Fatalf("terminal size = %v, want %v", bridges()[0].lastSize(), tt.want)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestParseTerminalSizePolicy(t *testing.T) {
+	for value, want := range map[string]TerminalSizePolicy{
+		"":         TerminalSizeSmallest,
+		"smallest": TerminalSizeSmallest,
+		"Largest":  TerminalSizeLargest,
+		" owner ":  TerminalSizeOwner,
+	} {
+		got, err := ParseTerminalSizePolicy(value)
+		if err != nil || got != want {
+			t.Fatalf("ParseTerminalSizePolicy(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseTerminalSizePolicy("biggest"); err == nil {
+		t.Fatal("expected an error for an unknown policy")
+	}
+}
@@ -0,0 +1,416 @@
+package web
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/session"
+)
+
+// Generated TLS material lives in the profile directory, next to the VAPID
+// keys: a CA (installed on clients once) and a server certificate it signs.
+const (
+	webTLSDirName      = "web_tls"
+	webCACertFileName  = "ca.crt"
+	webCAKeyFileName   = "ca.key"
+	webServerCertName  = "server.crt"
+	webServerKeyName   = "server.key"
+	webCAValidity      = 10 * 365 * 24 * time.Hour
+	webServerValidity  = 397 * 24 * time.Hour // Apple platforms reject longer-lived server certificates
+	webServerRenewSoon = 30 * 24 * time.Hour
+)
+
+// TLSOptions selects how web mode serves HTTPS.
+type TLSOptions struct {
+	// CertFile and KeyFile are a certificate to serve instead of the
+	// generated one
+	CertFile string
+	KeyFile  string
+	// Hosts are extra DNS names or IPs for the generated server certificate
+	Hosts []string
+	// ClientAuth requires clients to present a certificate
+	ClientAuth bool
+	// ClientCAFile is a PEM bundle client certificates are verified against
+	// (default: the profile's CA, see IssueWebClientCert)
+	ClientCAFile string
+}
+
+// WebCA is a profile's certificate authority for web mode.
+type WebCA struct {
+	Cert    *x509.Certificate
+	CertPEM []byte
+	Key     crypto.Signer
+	// Path is the CA certificate file
+	Path string
+}
+
+// webTLSDir returns the profile's directory for generated TLS material.
+func webTLSDir(profile string) (string, error) {
+	profileDir, err := session.GetProfileDir(session.GetEffectiveProfile(profile))
+	if err != nil {
+		return "", fmt.Errorf("resolve profile dir: %w", err)
+	}
+	return filepath.Join(profileDir, webTLSDirName), nil
+}
+
+// BuildTLSConfig returns the TLS configuration for web mode. Without
+// CertFile/KeyFile it serves a server certificate signed by the profile's
+// CA, creating both as needed; generated reports whether anything new was
+// created.
+func BuildTLSConfig(profile string, opts TLSOptions) (cfg *tls.Config, generated bool, err error) {
+	certFile, keyFile := opts.CertFile, opts.KeyFile
+	if (certFile == "") != (keyFile == "") {
+		return nil, false, errors.New("--tls-cert and --tls-key must be used together")
+	}
+	if certFile == "" {
+		certFile, keyFile, generated, err = EnsureWebServerCert(profile, opts.Hosts)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, false, fmt.Errorf("load TLS certificate: %w", err)
+	}
+
+	cfg = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if !opts.ClientAuth && opts.ClientCAFile == "" {
+		return cfg, generated, nil
+	}
+
+	pool := x509.NewCertPool()
+	if opts.ClientCAFile != "" {
+		raw, err := os.ReadFile(opts.ClientCAFile)
+		if err != nil {
+			return nil, false, fmt.Errorf("read client CA: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(raw) {
+			return nil, false, fmt.Errorf("client CA %s contains no PEM certificates", opts.ClientCAFile)
+		}
+	} else {
+		ca, caGenerated, err := EnsureWebCA(profile)
+		if err != nil {
+			return nil, false, err
+		}
+		generated = generated || caGenerated
+		pool.AddCert(ca.Cert)
+	}
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	cfg.ClientCAs = pool
+	return cfg, generated, nil
+}
+
+// ClientTLSConfig returns the TLS settings for connecting to another
+// agent-deck web server: caFile adds its CA to the system roots, and
+// certFile/keyFile present a client certificate. Returns nil when all are
+// empty.
+func ClientTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("cert_file and key_file must be used together")
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		raw, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(raw) {
+			return nil, fmt.Errorf("CA %s contains no PEM certificates", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// EnsureWebCA returns the profile's web CA, generating it on first use.
+func EnsureWebCA(profile string) (ca *WebCA, generated bool, err error) {
+	dir, err := webTLSDir(profile)
+	if err != nil {
+		return nil, false, err
+	}
+	certPath := filepath.Join(dir, webCACertFileName)
+	keyPath := filepath.Join(dir, webCAKeyFileName)
+
+	if ca, err := loadWebCA(certPath, keyPath); err == nil {
+		return ca, false, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, false, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, false, fmt.Errorf("generate CA key: %w", err)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          newCertSerial(),
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("agent-deck %s CA", session.GetEffectiveProfile(profile)), Organization: []string{"agent-deck"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(webCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, false, fmt.Errorf("create CA certificate: %w", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM, err := marshalECKeyPEM(key)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := writeTLSFile(keyPath, keyPEM, 0o600); err != nil {
+		return nil, false, err
+	}
+	if err := writeTLSFile(certPath, certPEM, 0o644); err != nil {
+		return nil, false, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, false, fmt.Errorf("parse CA certificate: %w", err)
+	}
+	return &WebCA{Cert: cert, CertPEM: certPEM, Key: key, Path: certPath}, true, nil
+}
+
+// EnsureWebServerCert returns the paths of the profile's server certificate
+// and key, (re)issuing them from the profile CA when they are missing,
+// expire within 30 days, were signed by another CA or don't cover every
+// requested host. localhost, the loopback addresses and the machine's
+// hostname are always included.
+func EnsureWebServerCert(profile string, hosts []string) (certPath, keyPath string, generated bool, err error) {
+	ca, caGenerated, err := EnsureWebCA(profile)
+	if err != nil {
+		return "", "", false, err
+	}
+	dir := filepath.Dir(ca.Path)
+	certPath = filepath.Join(dir, webServerCertName)
+	keyPath = filepath.Join(dir, webServerKeyName)
+
+	dnsNames, ips := webServerNames(hosts)
+	if !caGenerated && serverCertUsable(certPath, keyPath, ca, dnsNames, ips) {
+		return certPath, keyPath, false, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", false, fmt.Errorf("generate server key: %w", err)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: newCertSerial(),
+		Subject:      pkix.Name{CommonName: "agent-deck web", Organization: []string{"agent-deck"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(webServerValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, key.Public(), ca.Key)
+	if err != nil {
+		return "", "", false, fmt.Errorf("create server certificate: %w", err)
+	}
+	keyPEM, err := marshalECKeyPEM(key)
+	if err != nil {
+		return "", "", false, err
+	}
+	if err := writeTLSFile(keyPath, keyPEM, 0o600); err != nil {
+		return "", "", false, err
+	}
+	if err := writeTLSFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		return "", "", false, err
+	}
+	return certPath, keyPath, true, nil
+}
+
+// IssueWebClientCert issues a client certificate named name from the
+// profile's CA, for use with client-certificate authentication.
+func IssueWebClientCert(profile, name string, validity time.Duration) (certPEM, keyPEM []byte, err error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil, errors.New("client name is required")
+	}
+	if validity <= 0 {
+		return nil, nil, errors.New("validity must be positive")
+	}
+	ca, _, err := EnsureWebCA(profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate client key: %w", err)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: newCertSerial(),
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"agent-deck"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, key.Public(), ca.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create client certificate: %w", err)
+	}
+	keyPEM, err = marshalECKeyPEM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM, nil
+}
+
+// webServerNames splits hosts into DNS names and IPs, adding the defaults.
+func webServerNames(hosts []string) ([]string, []net.IP) {
+	names := []string{"localhost"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		names = append(names, hostname)
+	}
+	ips := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+
+	seen := make(map[string]bool)
+	var dnsNames []string
+	for _, h := range append(names, hosts...) {
+		h = strings.TrimSpace(h)
+		if h == "" || seen[h] {
+			continue
+		}
+		seen[h] = true
+		if ip := net.ParseIP(h); ip != nil {
+			ips = append(ips, ip)
+			continue
+		}
+		dnsNames = append(dnsNames, h)
+	}
+	return dnsNames, ips
+}
+
+// serverCertUsable reports whether the stored server certificate can be
+// served as is.
+func serverCertUsable(certPath, keyPath string, ca *WebCA, dnsNames []string, ips []net.IP) bool {
+	pair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil || len(pair.Certificate) == 0 {
+		return false
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return false
+	}
+	if time.Until(cert.NotAfter) < webServerRenewSoon || cert.CheckSignatureFrom(ca.Cert) != nil {
+		return false
+	}
+	for _, name := range dnsNames {
+		if cert.VerifyHostname(name) != nil {
+			return false
+		}
+	}
+	for _, ip := range ips {
+		found := false
+		for _, certIP := range cert.IPAddresses {
+			if certIP.Equal(ip) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func loadWebCA(certPath, keyPath string) (*WebCA, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, os.ErrNotExist
+		}
+		return nil, fmt.Errorf("read CA certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read CA key: %w", err)
+	}
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, errors.New("CA files are not valid PEM")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse CA certificate: %w", err)
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse CA key: %w", err)
+	}
+	if !key.PublicKey.Equal(cert.PublicKey) {
+		return nil, errors.New("CA key does not match CA certificate")
+	}
+	return &WebCA{Cert: cert, CertPEM: certPEM, Key: key, Path: certPath}, nil
+}
+
+func marshalECKeyPEM(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+func newCertSerial() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return big.NewInt(time.Now().UnixNano())
+	}
+	return serial
+}
+
+func writeTLSFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("mkdir tls dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("rename %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEnsureWebServerCertReusesAndReissues(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	ca, generated, err := EnsureWebCA("test-profile")
+	if err != nil || !generated {
+		t.Fatalf("EnsureWebCA = %v, generated %t", err, generated)
+	}
+	if again, generated, err := EnsureWebCA("test-profile"); err != nil || generated || !again.Cert.Equal(ca.Cert) {
+		t.Fatalf("second EnsureWebCA should reuse the CA (generated %t, err %v)", generated, err)
+	}
+
+	certPath, keyPath, generated, err := EnsureWebServerCert("test-profile", []string{"deck.lan", "192.168.1.20"})
+	if err != nil || !generated {
+		t.Fatalf("EnsureWebServerCert = %v, generated %t", err, generated)
+	}
+	pair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "deck.lan", "192.168.1.20"} {
+		if err := cert.VerifyHostname(host); err != nil {
+			t.Errorf("server certificate doesn't cover %s: %v", host, err)
+		}
+	}
+	if err := cert.CheckSignatureFrom(ca.Cert); err != nil {
+		t.Errorf("server certificate not signed by the profile CA: %v", err)
+	}
+
+	if _, _, generated, err := EnsureWebServerCert("test-profile", []string{"deck.lan"}); err != nil || generated {
+		t.Errorf("covered hosts should reuse the certificate (generated %t, err %v)", generated, err)
+	}
+	if _, _, generated, err := EnsureWebServerCert("test-profile", []string{"other.lan"}); err != nil || !generated {
+		t.Errorf("a new host should reissue the certificate (generated %t, err %v)", generated, err)
+	}
+}
+
+func TestBuildTLSConfigClientAuth(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cfg, _, err := BuildTLSConfig("test-profile", TLSOptions{ClientAuth: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatalf("ClientAuth = %v", cfg.ClientAuth)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	ts.TLS = cfg
+	ts.StartTLS()
+	defer ts.Close()
+
+	ca, _, err := EnsureWebCA("test-profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+	client := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs, ServerName: "localhost"},
+		}}
+	}
+
+	if _, err := client().Get(ts.URL); err == nil {
+		t.Error("request without a client certificate should fail")
+	}
+
+	certPEM, keyPEM, err := IssueWebClientCert("test-profile", "phone", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client(clientCert).Get(ts.URL)
+	if err != nil {
+		t.Fatalf("request with an issued client certificate failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("status = %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,211 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asheshgoplani/agent-deck/internal/statedb"
+)
+
+// Scopes a web access token can grant.
+const (
+	// ScopeRead allows viewing the menu, sessions, diffs and terminals
+	ScopeRead = "read"
+	// ScopeTerminalInput allows typing into session terminals
+	ScopeTerminalInput = "terminal:input"
+	// ScopeAdmin grants every scope
+	ScopeAdmin = "admin"
+)
+
+// TokenScopes lists the known scopes.
+var TokenScopes = []string{ScopeRead, ScopeTerminalInput, ScopeAdmin}
+
+// IsTokenScope reports whether scope is a known scope.
+func IsTokenScope(scope string) bool {
+	for _, s := range TokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenPrefix makes agent-deck web tokens recognizable, e.g. in secret scanners.
+const tokenPrefix = "adw_"
+
+// tokenTouchInterval limits how often a token's last use is written back.
+const tokenTouchInterval = time.Minute
+
+// GenerateToken returns a new random token secret.
+func GenerateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return tokenPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken returns the hash a token secret is stored and looked up by.
+// Tokens are random 256-bit values, so a plain SHA-256 is enough.
+func HashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenStore holds the named access tokens of a profile and its web audit
+// log.
+type TokenStore interface {
+	// HasTokens reports whether any token exists. Once one does, requests
+	// without a valid token are refused.
+	HasTokens() (bool, error)
+	// LookupToken returns the token for a secret, or nil if there is none.
+	LookupToken(secret string) (*statedb.WebTokenRow, error)
+	// RecordAudit appends an audit log entry.
+	RecordAudit(entry *statedb.WebAuditRow) error
+}
+
+// NewTokenStore returns a TokenStore backed by a profile's state database.
+func NewTokenStore(db *statedb.StateDB) TokenStore {
+	return &stateDBTokenStore{db: db}
+}
+
+type stateDBTokenStore struct {
+	db *statedb.StateDB
+}
+
+func (s *stateDBTokenStore) HasTokens() (bool, error) {
+	n, err := s.db.CountWebTokens()
+	return n > 0, err
+}
+
+func (s *stateDBTokenStore) LookupToken(secret string) (*statedb.WebTokenRow, error) {
+	tok, err := s.db.WebTokenByHash(HashToken(secret))
+	if err != nil || tok == nil {
+		return nil, err
+	}
+	if now := time.Now(); now.Sub(tok.LastUsedAt) >= tokenTouchInterval {
+		_ = s.db.TouchWebToken(tok.Name, now)
+	}
+	return tok, nil
+}
+
+func (s *stateDBTokenStore) RecordAudit(entry *statedb.WebAuditRow) error {
+	return s.db.AppendWebAudit(entry)
+}
+
+// principal is who a request acts as: a named token with its scopes and
+// group restrictions.
+type principal struct {
+	// Name is the token name recorded in the audit log
+	Name   string
+	Scopes []string
+	// Groups restricts access to sessions in these groups and their
+	// subgroups; empty means all groups
+	Groups []string
+}
+
+// Can reports whether the principal holds scope.
+func (p *principal) Can(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsGroup reports whether sessions in groupPath are visible.
+func (p *principal) AllowsGroup(groupPath string) bool {
+	if len(p.Groups) == 0 {
+		return true
+	}
+	for _, g := range p.Groups {
+		if groupPath == g || strings.HasPrefix(groupPath, g+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsSession reports whether sess is visible. Group restrictions apply
+// to the session's group in its own profile, not the path federation
+// re-roots it under.
+func (p *principal) AllowsSession(sess *MenuSession) bool {
+	if sess.OriginGroupPath != "" {
+		return p.AllowsGroup(sess.OriginGroupPath)
+	}
+	return p.AllowsGroup(sess.GroupPath)
+}
+
+// filterSnapshot returns the part of snapshot the principal may see: its
+// sessions, and the groups leading to them.
+func (p *principal) filterSnapshot(snapshot *MenuSnapshot) *MenuSnapshot {
+	if snapshot == nil || len(p.Groups) == 0 {
+		return snapshot
+	}
+	filtered := *snapshot
+	filtered.Items = make([]MenuItem, 0, len(snapshot.Items))
+	filtered.TotalGroups = 0
+	filtered.TotalSessions = 0
+	for _, item := range snapshot.Items {
+		switch {
+		case item.Type == MenuItemTypeSession && item.Session != nil:
+			if !p.AllowsSession(item.Session) {
+				continue
+			}
+			filtered.TotalSessions++
+		case item.Type == MenuItemTypeGroup && item.Group != nil:
+			path := item.Group.Path
+			if item.Group.Host != "" {
+				path = item.Group.OriginPath
+			}
+			// Federation's host and profile groups are kept for now and
+			// dropped below when they hold no visible session
+			if path != "" && !p.AllowsGroup(path) && !p.isGroupAncestor(path) {
+				continue
+			}
+		}
+		filtered.Items = append(filtered.Items, item)
+	}
+
+	// Parent groups of allowed groups hold sessions this principal can't
+	// see; count only the visible ones
+	items := make([]MenuItem, 0, len(filtered.Items))
+	for _, item := range filtered.Items {
+		if item.Type != MenuItemTypeGroup || item.Group == nil {
+			items = append(items, item)
+			continue
+		}
+		group := *item.Group
+		group.SessionCount = 0
+		for _, other := range filtered.Items {
+			if other.Type == MenuItemTypeSession && other.Session != nil &&
+				(other.Session.GroupPath == group.Path || strings.HasPrefix(other.Session.GroupPath, group.Path+"/")) {
+				group.SessionCount++
+			}
+		}
+		if group.Host != "" && group.OriginPath == "" && group.SessionCount == 0 {
+			continue
+		}
+		item.Group = &group
+		items = append(items, item)
+		filtered.TotalGroups++
+	}
+	filtered.Items = items
+	return &filtered
+}
+
+// isGroupAncestor reports whether groupPath is a parent of an allowed group.
+func (p *principal) isGroupAncestor(groupPath string) bool {
+	for _, g := range p.Groups {
+		if strings.HasPrefix(g, groupPath+"/") {
+			return true
+		}
+	}
+	return false
+}